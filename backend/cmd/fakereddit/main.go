@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/fakereddit"
+)
+
+func main() {
+	wopts := fakereddit.DefaultOptions()
+	sopts := fakereddit.DefaultServerOptions()
+	defaultSubs := strings.Join(wopts.SubredditNames, ",")
+	if env := os.Getenv("DEFAULT_SUBREDDITS"); env != "" {
+		defaultSubs = env
+	}
+
+	addr := flag.String("addr", ":8081", "Listen address")
+	seed := flag.Int64("seed", wopts.Seed, "Seed of the synthetic world and fault injection")
+	subNames := flag.String("subreddit-names", defaultSubs, "Comma-separated subreddits that always exist (default: DEFAULT_SUBREDDITS)")
+	flag.IntVar(&wopts.Subreddits, "subreddits", wopts.Subreddits, "Additional generated subreddits")
+	flag.IntVar(&wopts.Users, "users", wopts.Users, "Generated users")
+	flag.IntVar(&wopts.PostsPerSub, "posts-per-sub", wopts.PostsPerSub, "Average posts per subreddit")
+	flag.IntVar(&wopts.CommentsPerPost, "comments-per-post", wopts.CommentsPerPost, "Average comments per post")
+	flag.IntVar(&wopts.PrivateSubs, "private", wopts.PrivateSubs, "Generated subreddits that answer 403 private")
+	flag.IntVar(&wopts.BannedSubs, "banned", wopts.BannedSubs, "Generated subreddits that answer 404 banned")
+	flag.Float64Var(&wopts.NSFWFraction, "nsfw", wopts.NSFWFraction, "Share of subreddits marked over_18")
+	flag.IntVar(&sopts.Quota, "quota", sopts.Quota, "Requests per window and token (0 disables rate limiting)")
+	flag.DurationVar(&sopts.Window, "window", sopts.Window, "Rate-limit window")
+	flag.DurationVar(&sopts.TokenTTL, "token-ttl", sopts.TokenTTL, "Lifetime of issued access tokens")
+	flag.Float64Var(&sopts.ErrorRate, "error-rate", 0, "Share of API requests answered with a 500/502/503")
+	flag.Float64Var(&sopts.ThrottleRate, "throttle-rate", 0, "Share of API requests answered with a 429")
+	flag.DurationVar(&sopts.Latency, "latency", 0, "Delay added to every response")
+	flag.StringVar(&sopts.ClientID, "client-id", os.Getenv("REDDIT_CLIENT_ID"), "Required OAuth client ID (default: REDDIT_CLIENT_ID; empty accepts any)")
+	flag.StringVar(&sopts.ClientSecret, "client-secret", os.Getenv("REDDIT_CLIENT_SECRET"), "Required OAuth client secret (default: REDDIT_CLIENT_SECRET)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: fakereddit [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Serves the Reddit API endpoints the crawler uses from a seeded synthetic world.\n")
+		fmt.Fprintf(os.Stderr, "Point the crawler at it with REDDIT_API_BASE_URL=http://localhost:8081.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	wopts.Seed, sopts.Seed = *seed, *seed
+	wopts.SubredditNames = strings.Split(*subNames, ",")
+
+	start := time.Now()
+	world := fakereddit.NewWorld(wopts)
+	posts, comments := world.Counts()
+	log.Printf("🌍 Generated %d subreddits, %d users, %d posts and %d comments in %v",
+		len(world.Subreddits), len(world.Users), posts, comments, time.Since(start).Round(time.Millisecond))
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           fakereddit.NewServer(world, sopts),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("🚀 Fake Reddit listening on %s (stats at /_fake/stats)", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server error: %v", err)
+	}
+}
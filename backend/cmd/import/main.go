@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/importer"
+)
+
+func main() {
+	subreddits := flag.String("subreddits", "", "Comma-separated subreddits to import (default: all)")
+	after := flag.String("after", "", "Import records created on or after this date (YYYY-MM-DD or RFC 3339)")
+	before := flag.String("before", "", "Import records created before this date (YYYY-MM-DD or RFC 3339)")
+	batch := flag.Int("batch", 1000, "Lines between checkpoints")
+	progress := flag.Duration("progress", 10*time.Second, "Throughput report interval (0 to disable)")
+	noResume := flag.Bool("no-resume", false, "Ignore existing checkpoints without clearing them")
+	restart := flag.Bool("restart", false, "Clear the checkpoints of the given files and import them from the start")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: import [flags] FILE...\n\n")
+		fmt.Fprintf(os.Stderr, "Imports Reddit dump files (RS_*.zst submissions, RC_*.zst comments or plain NDJSON).\n")
+		fmt.Fprintf(os.Stderr, "Import submissions before the comments that reply to them.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	from, err := parseDate(*after)
+	if err != nil {
+		log.Fatalf("Invalid -after: %v", err)
+	}
+	to, err := parseDate(*before)
+	if err != nil {
+		log.Fatalf("Invalid -before: %v", err)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		log.Fatal("-after must be before -before")
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	q := db.New(conn)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	im := importer.New(q, importer.Options{
+		Filter:           importer.NewFilter(strings.Split(*subreddits, ","), from, to),
+		BatchSize:        *batch,
+		Resume:           !*noResume,
+		ProgressInterval: *progress,
+	})
+
+	var total importer.Stats
+	for _, path := range files {
+		if *restart {
+			if err := importer.ResetCheckpoint(ctx, q, filepath.Base(path)); err != nil {
+				log.Fatalf("Failed to reset checkpoint for %s: %v", path, err)
+			}
+		}
+		log.Printf("📂 Importing %s", path)
+		stats, err := im.ImportFile(ctx, path)
+		printStats(path, stats)
+		total.Posts += stats.Posts
+		total.Comments += stats.Comments
+		total.Filtered += stats.Filtered
+		total.Skipped += stats.Skipped
+		total.Elapsed += stats.Elapsed
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("🛑 Interrupted; rerun the same command to resume")
+				os.Exit(130)
+			}
+			log.Fatalf("❌ Import of %s failed: %v", path, err)
+		}
+	}
+	log.Printf("✅ Done: %d posts, %d comments, %d filtered, %d skipped in %s",
+		total.Posts, total.Comments, total.Filtered, total.Skipped, total.Elapsed.Round(time.Second))
+	log.Printf("Run the precalculation to add the imported data to the graph")
+}
+
+func printStats(path string, s importer.Stats) {
+	if s.Elapsed <= 0 {
+		return
+	}
+	secs := s.Elapsed.Seconds()
+	log.Printf("📊 %s: %d lines, %d posts, %d comments, %d filtered, %d skipped in %s (%.0f records/s, %.1f MB/s)",
+		filepath.Base(path), s.Lines, s.Posts, s.Comments, s.Filtered, s.Skipped, s.Elapsed.Round(time.Millisecond),
+		float64(s.Imported())/secs, float64(s.Bytes)/secs/(1<<20))
+}
+
+// parseDate accepts a calendar date (midnight UTC) or an RFC 3339 timestamp.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/onnwee/reddit-cluster-map/backend/internal/api"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/errorreporting"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/graphstore"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/server"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/tracing"
@@ -63,7 +64,14 @@ func main() {
 		log.Fatalf("❌ Server start failed: %v", err)
 	}
 
-	router := api.NewRouter(queries)
+	// Keep the graph in memory for traversal queries, reloading new versions
+	var graphStore *graphstore.Store
+	if cfg.GraphMemoryEnabled {
+		graphStore = graphstore.New(graphstore.DBSource(queries))
+		go graphStore.Run(ctx, cfg.GraphMemoryPollInterval)
+	}
+
+	router := api.NewRouter(queries, graphStore)
 
 	logger.Info("Server running", "address", ":8000", "url", "http://localhost:8000")
 	log.Fatal(http.ListenAndServe(":8000", router))
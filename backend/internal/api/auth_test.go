@@ -143,7 +143,7 @@ func TestAdminEndpointsRequireAuth(t *testing.T) {
 	var q *db.Queries
 
 	// Create router
-	router := NewRouter(q)
+	router := NewRouter(q, nil)
 
 	// Test admin endpoints without authentication
 	adminEndpoints := []struct {
@@ -183,7 +183,7 @@ func TestAdminEndpointsWithAuth(t *testing.T) {
 	var q *db.Queries
 
 	// Create router
-	router := NewRouter(q)
+	router := NewRouter(q, nil)
 
 	// Test that admin endpoints accept valid token
 	// Note: These may return 500 or other errors due to nil queries,
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// breakerReportTTL is how long a crawler's breaker report is trusted. Crawlers
+// report every 15 seconds, so anything older belongs to a stopped process.
+const breakerReportTTL = 2 * time.Minute
+
+// BreakerStateResponse is the last reported state of one Reddit endpoint breaker.
+type BreakerStateResponse struct {
+	Instance      string  `json:"instance"`
+	Endpoint      string  `json:"endpoint"`
+	State         string  `json:"state"`
+	FailureCount  int     `json:"failure_count"`
+	LastFailureAt *string `json:"last_failure_at,omitempty"`
+	RetryAt       *string `json:"retry_at,omitempty"`
+	UpdatedAt     string  `json:"updated_at"`
+	Stale         bool    `json:"stale"`
+}
+
+// CrawlerBreakersResponse lists breaker states across crawler processes.
+// Paused is true when a live crawler has an open breaker and is therefore not claiming jobs.
+type CrawlerBreakersResponse struct {
+	Breakers []BreakerStateResponse `json:"breakers"`
+	Paused   bool                   `json:"paused"`
+}
+
+// GetCrawlerBreakers returns the Reddit circuit breaker states reported by crawler processes.
+func (h *AdminHandler) GetCrawlerBreakers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rows, err := h.q.DB().QueryContext(ctx, `
+		SELECT instance, endpoint, state, failure_count, last_failure_at, retry_at, updated_at
+		FROM crawler_circuit_breakers
+		ORDER BY instance, endpoint`)
+	if err != nil {
+		http.Error(w, "Failed to fetch circuit breaker states", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	resp := CrawlerBreakersResponse{Breakers: []BreakerStateResponse{}}
+	for rows.Next() {
+		var (
+			b                    BreakerStateResponse
+			lastFailure, retryAt sql.NullTime
+			updatedAt            time.Time
+		)
+		if err := rows.Scan(&b.Instance, &b.Endpoint, &b.State, &b.FailureCount, &lastFailure, &retryAt, &updatedAt); err != nil {
+			http.Error(w, "Failed to fetch circuit breaker states", http.StatusInternalServerError)
+			return
+		}
+		if lastFailure.Valid {
+			s := lastFailure.Time.Format(time.RFC3339)
+			b.LastFailureAt = &s
+		}
+		if retryAt.Valid {
+			s := retryAt.Time.Format(time.RFC3339)
+			b.RetryAt = &s
+		}
+		b.UpdatedAt = updatedAt.Format(time.RFC3339)
+		b.Stale = now.Sub(updatedAt) > breakerReportTTL
+		if !b.Stale && b.State == "open" && retryAt.Valid && retryAt.Time.After(now) {
+			resp.Paused = true
+		}
+		resp.Breakers = append(resp.Breakers, b)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to fetch circuit breaker states", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/secrets"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// credentialLabelPattern restricts labels to names that are safe as metric labels.
+var credentialLabelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// CredentialStateResponse is one crawler process's view of a pooled credential.
+type CredentialStateResponse struct {
+	Instance       string   `json:"instance"`
+	Status         string   `json:"status"`
+	InFlight       int      `json:"in_flight"`
+	RequestsTotal  int64    `json:"requests_total"`
+	QuotaRemaining *float64 `json:"quota_remaining,omitempty"`
+	QuotaUsed      *int     `json:"quota_used,omitempty"`
+	QuotaResetAt   *string  `json:"quota_reset_at,omitempty"`
+	TokenExpiresAt *string  `json:"token_expires_at,omitempty"`
+	LastError      string   `json:"last_error,omitempty"`
+	UpdatedAt      string   `json:"updated_at"`
+	Stale          bool     `json:"stale"`
+}
+
+// CredentialResponse describes a Reddit OAuth credential. Stored credentials have an
+// ID; the credential configured through the environment does not and cannot be
+// changed through the API. The client secret is never returned.
+type CredentialResponse struct {
+	ID       *int32                    `json:"id,omitempty"`
+	Label    string                    `json:"label"`
+	ClientID string                    `json:"client_id"`
+	Enabled  bool                      `json:"enabled"`
+	Source   string                    `json:"source"`
+	States   []CredentialStateResponse `json:"states"`
+}
+
+// ListCredentials returns the stored credentials and the state crawler processes
+// reported for every pooled credential, including the env credential.
+func (h *AdminHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rows, err := h.q.DB().QueryContext(ctx, `SELECT id, label, client_id, enabled FROM reddit_credentials ORDER BY label`)
+	if err != nil {
+		http.Error(w, "Failed to fetch credentials", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	creds := []CredentialResponse{}
+	byLabel := make(map[string]int)
+	for rows.Next() {
+		var (
+			c  CredentialResponse
+			id int32
+		)
+		if err := rows.Scan(&id, &c.Label, &c.ClientID, &c.Enabled); err != nil {
+			http.Error(w, "Failed to fetch credentials", http.StatusInternalServerError)
+			return
+		}
+		c.ID = &id
+		c.ClientID = secrets.Mask(c.ClientID)
+		c.Source = "database"
+		c.States = []CredentialStateResponse{}
+		byLabel[c.Label] = len(creds)
+		creds = append(creds, c)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to fetch credentials", http.StatusInternalServerError)
+		return
+	}
+
+	states, err := h.q.DB().QueryContext(ctx, `
+		SELECT instance, credential, status, in_flight, requests_total, quota_remaining, quota_used,
+		       quota_reset_at, token_expires_at, COALESCE(last_error, ''), updated_at
+		FROM reddit_credential_states
+		ORDER BY credential, instance`)
+	if err != nil {
+		http.Error(w, "Failed to fetch credential states", http.StatusInternalServerError)
+		return
+	}
+	defer states.Close()
+
+	now := time.Now()
+	for states.Next() {
+		var (
+			s                 CredentialStateResponse
+			label             string
+			remaining         sql.NullFloat64
+			used              sql.NullInt32
+			resetAt, expireAt sql.NullTime
+			updatedAt         time.Time
+		)
+		if err := states.Scan(&s.Instance, &label, &s.Status, &s.InFlight, &s.RequestsTotal, &remaining, &used,
+			&resetAt, &expireAt, &s.LastError, &updatedAt); err != nil {
+			http.Error(w, "Failed to fetch credential states", http.StatusInternalServerError)
+			return
+		}
+		if remaining.Valid {
+			s.QuotaRemaining = &remaining.Float64
+		}
+		if used.Valid {
+			u := int(used.Int32)
+			s.QuotaUsed = &u
+		}
+		if resetAt.Valid {
+			t := resetAt.Time.Format(time.RFC3339)
+			s.QuotaResetAt = &t
+		}
+		if expireAt.Valid {
+			t := expireAt.Time.Format(time.RFC3339)
+			s.TokenExpiresAt = &t
+		}
+		s.UpdatedAt = updatedAt.Format(time.RFC3339)
+		s.Stale = now.Sub(updatedAt) > breakerReportTTL
+
+		i, ok := byLabel[label]
+		if !ok {
+			// Only the env credential is pooled without a stored row.
+			if label != crawler.EnvCredentialLabel {
+				continue
+			}
+			byLabel[label] = len(creds)
+			i = len(creds)
+			creds = append(creds, CredentialResponse{Label: label, Enabled: true, Source: "environment", States: []CredentialStateResponse{}})
+		}
+		creds[i].States = append(creds[i].States, s)
+	}
+	if err := states.Err(); err != nil {
+		http.Error(w, "Failed to fetch credential states", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"credentials": creds})
+}
+
+// AddCredential stores a new credential. Crawlers add it to their pools on their next sync.
+func (h *AdminHandler) AddCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req struct {
+		Label        string `json:"label"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Label = strings.ToLower(strings.TrimSpace(req.Label))
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	req.ClientSecret = strings.TrimSpace(req.ClientSecret)
+	if !credentialLabelPattern.MatchString(req.Label) || req.Label == crawler.EnvCredentialLabel {
+		http.Error(w, "Invalid label: use lowercase letters, digits, '-' or '_' (\"env\" is reserved)", http.StatusBadRequest)
+		return
+	}
+	if req.ClientID == "" || req.ClientSecret == "" {
+		http.Error(w, "client_id and client_secret are required", http.StatusBadRequest)
+		return
+	}
+
+	key, err := secrets.ParseKey(config.Load().RedditCredentialsKey)
+	if err != nil {
+		log.Printf("⚠️ Refusing to store credential %q: REDDIT_CREDENTIALS_KEY: %v", req.Label, err)
+		http.Error(w, "Credential storage is not configured (REDDIT_CREDENTIALS_KEY)", http.StatusServiceUnavailable)
+		return
+	}
+	sealed, err := secrets.Seal(key, req.ClientSecret, req.Label)
+	if err != nil {
+		http.Error(w, "Failed to store credential", http.StatusInternalServerError)
+		return
+	}
+
+	var id int32
+	err = h.q.DB().QueryRowContext(ctx,
+		`INSERT INTO reddit_credentials (label, client_id, client_secret_sealed) VALUES ($1, $2, $3) RETURNING id`,
+		req.Label, req.ClientID, sealed).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		http.Error(w, "A credential with this label already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to store credential", http.StatusInternalServerError)
+		return
+	}
+
+	h.logCredentialAction(r, "add_reddit_credential", req.Label, map[string]interface{}{
+		"label": req.Label, "client_id": secrets.Mask(req.ClientID),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(CredentialResponse{
+		ID: &id, Label: req.Label, ClientID: secrets.Mask(req.ClientID), Enabled: true,
+		Source: "database", States: []CredentialStateResponse{},
+	})
+}
+
+// UpdateCredential enables or disables a stored credential. Disabled credentials
+// finish their in-flight requests and then leave the crawler pools.
+func (h *AdminHandler) UpdateCredential(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid credential ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "Invalid request body: enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	var c CredentialResponse
+	err = h.q.DB().QueryRowContext(ctx,
+		`UPDATE reddit_credentials SET enabled = $2, updated_at = now() WHERE id = $1 RETURNING label, client_id, enabled`,
+		int32(id), *req.Enabled).Scan(&c.Label, &c.ClientID, &c.Enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Credential not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update credential", http.StatusInternalServerError)
+		return
+	}
+	id32 := int32(id)
+	c.ID = &id32
+	c.ClientID = secrets.Mask(c.ClientID)
+	c.Source = "database"
+	c.States = []CredentialStateResponse{}
+
+	h.logCredentialAction(r, "update_reddit_credential", c.Label, map[string]interface{}{
+		"label": c.Label, "enabled": c.Enabled,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+func (h *AdminHandler) logCredentialAction(r *http.Request, action, label string, details map[string]interface{}) {
+	userID := getUserIDFromRequest(r)
+	ipAddr := getIPFromRequest(r)
+	detailsJSON, _ := json.Marshal(details)
+	_ = h.q.LogAdminAction(r.Context(), db.LogAdminActionParams{
+		Action:       action,
+		ResourceType: "reddit_credential",
+		ResourceID:   sql.NullString{String: label, Valid: true},
+		UserID:       userID,
+		Details:      pqtype.NullRawMessage{RawMessage: detailsJSON, Valid: true},
+		IpAddress:    sql.NullString{String: ipAddr, Valid: ipAddr != ""},
+	})
+}
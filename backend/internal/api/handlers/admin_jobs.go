@@ -3,10 +3,13 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
 	"github.com/sqlc-dev/pqtype"
 )
@@ -25,21 +28,26 @@ type JobStatsResponse struct {
 	RunningCount   int64 `json:"running_count"`
 	FailedCount    int64 `json:"failed_count"`
 	CompletedCount int64 `json:"completed_count"`
+	TerminalCount  int64 `json:"terminal_count"`
 	TotalCount     int64 `json:"total_count"`
 }
 
 // JobResponse represents a single crawl job with subreddit name
 type JobResponse struct {
-	ID            int32   `json:"id"`
-	SubredditID   int32   `json:"subreddit_id"`
-	SubredditName string  `json:"subreddit_name"`
-	Status        string  `json:"status"`
-	Retries       *int32  `json:"retries"`
-	Priority      *int32  `json:"priority"`
-	LastAttempt   *string `json:"last_attempt"`
-	EnqueuedBy    *string `json:"enqueued_by"`
-	CreatedAt     *string `json:"created_at"`
-	UpdatedAt     *string `json:"updated_at"`
+	ID            int32           `json:"id"`
+	JobType       string          `json:"job_type"`
+	SubredditID   int32           `json:"subreddit_id"`
+	SubredditName string          `json:"subreddit_name"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Status        string          `json:"status"`
+	Retries       *int32          `json:"retries"`
+	Priority      *int32          `json:"priority"`
+	LastAttempt   *string         `json:"last_attempt"`
+	EnqueuedBy    *string         `json:"enqueued_by"`
+	CreatedAt     *string         `json:"created_at"`
+	UpdatedAt     *string         `json:"updated_at"`
+	FailureReason *string         `json:"failure_reason,omitempty"`
+	LastError     *string         `json:"last_error,omitempty"`
 }
 
 // GetJobStats returns statistics about crawl jobs
@@ -57,6 +65,7 @@ func (h *AdminJobsHandler) GetJobStats(w http.ResponseWriter, r *http.Request) {
 		RunningCount:   stats.RunningCount,
 		FailedCount:    stats.FailedCount,
 		CompletedCount: stats.CompletedCount,
+		TerminalCount:  stats.TerminalCount,
 		TotalCount:     stats.TotalCount,
 	}
 
@@ -74,6 +83,12 @@ func (h *AdminJobsHandler) ListJobsByStatus(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	jobType := r.URL.Query().Get("type")
+	if jobType != "" && !crawler.IsValidJobType(jobType) {
+		http.Error(w, "Invalid type parameter", http.StatusBadRequest)
+		return
+	}
+
 	limit := 100
 	offset := 0
 
@@ -93,6 +108,7 @@ func (h *AdminJobsHandler) ListJobsByStatus(w http.ResponseWriter, r *http.Reque
 		Status:  status,
 		Column2: int32(limit),
 		Column3: int32(offset),
+		Column4: jobType,
 	})
 	if err != nil {
 		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
@@ -104,10 +120,14 @@ func (h *AdminJobsHandler) ListJobsByStatus(w http.ResponseWriter, r *http.Reque
 	for _, job := range jobs {
 		jr := JobResponse{
 			ID:            job.ID,
+			JobType:       job.JobType,
 			SubredditID:   job.SubredditID,
 			SubredditName: job.SubredditName,
 			Status:        job.Status,
 		}
+		if job.JobType != crawler.JobTypeSubreddit && len(job.Payload) > 0 {
+			jr.Payload = job.Payload
+		}
 		if job.Retries.Valid {
 			retries := job.Retries.Int32
 			jr.Retries = &retries
@@ -131,6 +151,12 @@ func (h *AdminJobsHandler) ListJobsByStatus(w http.ResponseWriter, r *http.Reque
 			updatedAt := job.UpdatedAt.Time.Format("2006-01-02T15:04:05Z")
 			jr.UpdatedAt = &updatedAt
 		}
+		if job.FailureReason.Valid {
+			jr.FailureReason = &job.FailureReason.String
+		}
+		if job.LastError.Valid {
+			jr.LastError = &job.LastError.String
+		}
 		response = append(response, jr)
 	}
 
@@ -164,6 +190,7 @@ func (h *AdminJobsHandler) UpdateJobStatus(w http.ResponseWriter, r *http.Reques
 		"crawling": true,
 		"success":  true,
 		"failed":   true,
+		"terminal": true,
 	}
 	if !validStatuses[req.Status] {
 		http.Error(w, "Invalid status value", http.StatusBadRequest)
@@ -266,7 +293,11 @@ func (h *AdminJobsHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
 
 	// Retry the job (reset status to queued and retries to 0)
 	err = h.q.RetryCrawlJob(ctx, int32(jobID))
-	if err != nil {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		http.Error(w, "An identical job is already queued", http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to retry job", http.StatusInternalServerError)
 		return
 	}
@@ -310,6 +341,7 @@ func (h *AdminJobsHandler) BulkUpdateJobStatus(w http.ResponseWriter, r *http.Re
 		"crawling": true,
 		"success":  true,
 		"failed":   true,
+		"terminal": true,
 	}
 	if !validStatuses[req.Status] {
 		http.Error(w, "Invalid status value", http.StatusBadRequest)
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// ListingPlanResponse describes the listings crawled for a subreddit.
+// Custom is false when the subreddit follows the default plan.
+type ListingPlanResponse struct {
+	Subreddit string `json:"subreddit"`
+	Plan      string `json:"plan"`
+	MaxPosts  int    `json:"max_posts"`
+	Custom    bool   `json:"custom"`
+}
+
+// GetListingPlan returns the effective listing plan for a subreddit.
+func (h *AdminHandler) GetListingPlan(w http.ResponseWriter, r *http.Request) {
+	h.writeListingPlan(w, r, mux.Vars(r)["name"])
+}
+
+// UpdateListingPlan sets or clears a subreddit's own listing plan.
+// An empty plan makes the subreddit follow the default plan again.
+func (h *AdminHandler) UpdateListingPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := mux.Vars(r)["name"]
+
+	var req struct {
+		Plan string `json:"plan"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.q.GetSubreddit(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Subreddit not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to fetch subreddit", http.StatusInternalServerError)
+		return
+	}
+
+	if err := crawler.SetListingPlan(ctx, h.q, sub.ID, req.Plan); err != nil {
+		http.Error(w, "Invalid listing plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := getUserIDFromRequest(r)
+	ipAddr := getIPFromRequest(r)
+	detailsJSON, _ := json.Marshal(map[string]interface{}{"subreddit": sub.Name, "plan": req.Plan})
+	_ = h.q.LogAdminAction(ctx, db.LogAdminActionParams{
+		Action:       "update_listing_plan",
+		ResourceType: "subreddit",
+		ResourceID:   sql.NullString{String: sub.Name, Valid: true},
+		UserID:       userID,
+		Details:      pqtype.NullRawMessage{RawMessage: detailsJSON, Valid: true},
+		IpAddress:    sql.NullString{String: ipAddr, Valid: ipAddr != ""},
+	})
+
+	h.writeListingPlan(w, r, sub.Name)
+}
+
+func (h *AdminHandler) writeListingPlan(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	sub, err := h.q.GetSubreddit(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Subreddit not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to fetch subreddit", http.StatusInternalServerError)
+		return
+	}
+
+	var stored sql.NullString
+	if err := h.q.DB().QueryRowContext(ctx, `SELECT listing_plan FROM subreddits WHERE id = $1`, sub.ID).Scan(&stored); err != nil {
+		http.Error(w, "Failed to fetch listing plan", http.StatusInternalServerError)
+		return
+	}
+	plan, err := crawler.LoadListingPlan(ctx, h.q, sub.ID)
+	custom := stored.Valid && stored.String != "" && err == nil
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListingPlanResponse{
+		Subreddit: sub.Name,
+		Plan:      plan.String(),
+		MaxPosts:  plan.MaxPosts,
+		Custom:    custom,
+	})
+}
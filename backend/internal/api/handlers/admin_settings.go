@@ -4,11 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/admin"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/graph"
 	"github.com/sqlc-dev/pqtype"
 )
 
@@ -34,6 +39,16 @@ type SettingsResponse struct {
 	CommentsPerPost    int     `json:"comments_per_post_in_graph"`
 	MaxAuthorLinks     int     `json:"max_author_content_links"`
 	MaxPostsPerSub     int     `json:"max_posts_per_sub"`
+	// Discovery policy
+	DiscoveryMaxHops        int      `json:"discovery_max_hops"`
+	DiscoveryAllowPatterns  []string `json:"discovery_allow_patterns"`
+	DiscoveryDenyPatterns   []string `json:"discovery_deny_patterns"`
+	DiscoveryNSFWPolicy     string   `json:"discovery_nsfw_policy"`
+	DiscoveryMinSubscribers int      `json:"discovery_min_subscribers"`
+	// Community detection
+	CommunityAlgorithm  string  `json:"community_algorithm"`
+	CommunityResolution float64 `json:"community_resolution"`
+	CommunitySeed       int64   `json:"community_seed"`
 }
 
 // GetSettings returns all configurable settings
@@ -58,6 +73,9 @@ func (h *AdminSettingsHandler) GetSettings(w http.ResponseWriter, r *http.Reques
 	maxAuthorLinks := getIntSetting(ctx, h.q, "max_author_content_links", 3)
 	maxPostsPerSub := getIntSetting(ctx, h.q, "max_posts_per_sub", 25)
 
+	discovery := crawler.LoadDiscoveryPolicy(ctx, h.q)
+	community := graph.LoadCommunityParams(ctx, h.q)
+
 	response := SettingsResponse{
 		CrawlerEnabled:     crawlerEnabled,
 		PrecalcEnabled:     precalcEnabled,
@@ -71,6 +89,16 @@ func (h *AdminSettingsHandler) GetSettings(w http.ResponseWriter, r *http.Reques
 		CommentsPerPost:    commentsPerPost,
 		MaxAuthorLinks:     maxAuthorLinks,
 		MaxPostsPerSub:     maxPostsPerSub,
+
+		DiscoveryMaxHops:        discovery.MaxHops,
+		DiscoveryAllowPatterns:  discovery.AllowPatterns,
+		DiscoveryDenyPatterns:   discovery.DenyPatterns,
+		DiscoveryNSFWPolicy:     discovery.NSFW,
+		DiscoveryMinSubscribers: discovery.MinSubscribers,
+
+		CommunityAlgorithm:  community.Algorithm,
+		CommunityResolution: community.Resolution,
+		CommunitySeed:       community.Seed,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -87,6 +115,18 @@ func (h *AdminSettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Validate the discovery policy and community detection before changing anything.
+	discovery, err := parseDiscoverySettings(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	community, err := parseCommunitySettings(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	userID := getUserIDFromRequest(r)
 	ipAddr := getIPFromRequest(r)
 	changes := make(map[string]interface{})
@@ -179,6 +219,14 @@ func (h *AdminSettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Req
 		changes["max_posts_per_sub"] = int(val)
 	}
 
+	for _, d := range append(discovery, community...) {
+		if err := admin.Set(ctx, h.q, d.key, d.stored); err != nil {
+			http.Error(w, "Failed to update "+d.key+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		changes[d.key] = d.value
+	}
+
 	// Log the action if any changes were made
 	if len(changes) > 0 {
 		detailsJSON, _ := json.Marshal(changes)
@@ -196,6 +244,92 @@ func (h *AdminSettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Req
 	h.GetSettings(w, r)
 }
 
+// validatedSetting is a validated discovery policy or community detection update.
+type validatedSetting struct {
+	key    string
+	stored string      // value written to service_settings
+	value  interface{} // value recorded in the audit log
+}
+
+// parseDiscoverySettings validates the discovery policy fields of a settings update.
+// Unlike the other settings, invalid discovery values are rejected rather than ignored.
+func parseDiscoverySettings(req map[string]interface{}) ([]validatedSetting, error) {
+	var out []validatedSetting
+	for _, key := range []string{crawler.SettingDiscoveryMaxHops, crawler.SettingDiscoveryMinSubscribers} {
+		raw, ok := req[key]
+		if !ok {
+			continue
+		}
+		val, ok := raw.(float64)
+		if !ok || val < 0 || val != float64(int(val)) {
+			return nil, fmt.Errorf("%s must be a non-negative integer", key)
+		}
+		out = append(out, validatedSetting{key: key, stored: intToString(int(val)), value: int(val)})
+	}
+	if raw, ok := req[crawler.SettingDiscoveryNSFWPolicy]; ok {
+		val, _ := raw.(string)
+		val = strings.ToLower(strings.TrimSpace(val))
+		if val != crawler.NSFWAllow && val != crawler.NSFWDeny {
+			return nil, fmt.Errorf("%s must be %q or %q", crawler.SettingDiscoveryNSFWPolicy, crawler.NSFWAllow, crawler.NSFWDeny)
+		}
+		out = append(out, validatedSetting{key: crawler.SettingDiscoveryNSFWPolicy, stored: val, value: val})
+	}
+	for _, key := range []string{crawler.SettingDiscoveryAllowPatterns, crawler.SettingDiscoveryDenyPatterns} {
+		raw, ok := req[key]
+		if !ok {
+			continue
+		}
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of regular expressions", key)
+		}
+		patterns := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of regular expressions", key)
+			}
+			patterns = append(patterns, s)
+		}
+		patterns, err := crawler.ValidateDiscoveryPatterns(patterns)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		stored, _ := json.Marshal(patterns)
+		out = append(out, validatedSetting{key: key, stored: string(stored), value: patterns})
+	}
+	return out, nil
+}
+
+// parseCommunitySettings validates the community detection fields of a settings
+// update. Like the discovery policy, invalid values are rejected.
+func parseCommunitySettings(req map[string]interface{}) ([]validatedSetting, error) {
+	var out []validatedSetting
+	if raw, ok := req[graph.SettingCommunityAlgorithm]; ok {
+		val, _ := raw.(string)
+		val = strings.ToLower(strings.TrimSpace(val))
+		if val != graph.AlgorithmLouvain && val != graph.AlgorithmLeiden {
+			return nil, fmt.Errorf("%s must be %q or %q", graph.SettingCommunityAlgorithm, graph.AlgorithmLouvain, graph.AlgorithmLeiden)
+		}
+		out = append(out, validatedSetting{key: graph.SettingCommunityAlgorithm, stored: val, value: val})
+	}
+	if raw, ok := req[graph.SettingCommunityResolution]; ok {
+		val, ok := raw.(float64)
+		if !ok || !(val > 0) || math.IsInf(val, 0) {
+			return nil, fmt.Errorf("%s must be a positive number", graph.SettingCommunityResolution)
+		}
+		out = append(out, validatedSetting{key: graph.SettingCommunityResolution, stored: floatToString(val), value: val})
+	}
+	if raw, ok := req[graph.SettingCommunitySeed]; ok {
+		val, ok := raw.(float64)
+		if !ok || val != math.Trunc(val) || math.Abs(val) > 1<<53 {
+			return nil, fmt.Errorf("%s must be an integer", graph.SettingCommunitySeed)
+		}
+		out = append(out, validatedSetting{key: graph.SettingCommunitySeed, stored: strconv.FormatInt(int64(val), 10), value: int64(val)})
+	}
+	return out, nil
+}
+
 // GetAuditLog returns the audit log entries
 func (h *AdminSettingsHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/graph"
+)
+
+func TestParseDiscoverySettings(t *testing.T) {
+	var req map[string]interface{}
+	body := `{"discovery_max_hops": 2, "discovery_nsfw_policy": "Deny", "discovery_deny_patterns": ["^nsfw", " porn "], "crawler_rps": 2}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseDiscoverySettings(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored := map[string]string{}
+	for _, d := range got {
+		stored[d.key] = d.stored
+	}
+	want := map[string]string{
+		crawler.SettingDiscoveryMaxHops:      "2",
+		crawler.SettingDiscoveryNSFWPolicy:   "deny",
+		crawler.SettingDiscoveryDenyPatterns: `["^nsfw","porn"]`,
+	}
+	if len(stored) != len(want) {
+		t.Fatalf("expected %d settings, got %v", len(want), stored)
+	}
+	for k, v := range want {
+		if stored[k] != v {
+			t.Errorf("%s = %q, want %q", k, stored[k], v)
+		}
+	}
+}
+
+func TestParseDiscoverySettingsRejectsInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{"discovery_max_hops": -1}`,
+		`{"discovery_min_subscribers": 1.5}`,
+		`{"discovery_nsfw_policy": "maybe"}`,
+		`{"discovery_allow_patterns": "golang"}`,
+		`{"discovery_allow_patterns": ["("]}`,
+	} {
+		var req map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseDiscoverySettings(req); err == nil {
+			t.Errorf("%s: expected error", body)
+		}
+	}
+}
+
+func TestParseCommunitySettings(t *testing.T) {
+	var req map[string]interface{}
+	body := `{"community_algorithm": " Leiden", "community_resolution": 0.5, "community_seed": 7, "discovery_max_hops": 2}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseCommunitySettings(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored := map[string]string{}
+	for _, s := range got {
+		stored[s.key] = s.stored
+	}
+	want := map[string]string{
+		graph.SettingCommunityAlgorithm:  "leiden",
+		graph.SettingCommunityResolution: "0.5",
+		graph.SettingCommunitySeed:       "7",
+	}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("stored %v, want %v", stored, want)
+	}
+
+	for _, body := range []string{
+		`{"community_algorithm": "infomap"}`,
+		`{"community_resolution": 0}`,
+		`{"community_resolution": "1"}`,
+		`{"community_seed": 1.5}`,
+	} {
+		var req map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseCommunitySettings(req); err == nil {
+			t.Errorf("%s: expected error", body)
+		}
+	}
+}
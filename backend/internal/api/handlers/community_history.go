@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
+)
+
+// CommunityHistoryStore abstracts the community lineage queries for testability.
+type CommunityHistoryStore interface {
+	GetCommunity(ctx context.Context, id int32) (db.GraphCommunity, error)
+	ListCommunityEvents(ctx context.Context, communityID int32) ([]db.CommunityEvent, error)
+}
+
+// CommunityEvent is a step in the lineage of a community. For a split,
+// community_id split off related_id; for a merge, community_id merged into
+// related_id.
+type CommunityEvent struct {
+	RunID       int32     `json:"run_id"`
+	At          time.Time `json:"at"`
+	Event       string    `json:"event"`
+	CommunityID int32     `json:"community_id"`
+	RelatedID   *int32    `json:"related_id,omitempty"`
+	Jaccard     *float64  `json:"jaccard,omitempty"`
+	Size        int32     `json:"size"`
+}
+
+// CommunityHistoryResponse is the body of GET /api/communities/{id}/history.
+type CommunityHistoryResponse struct {
+	CommunityID int32            `json:"community_id"`
+	Current     bool             `json:"current"` // whether the community exists in the latest run
+	Label       string           `json:"label,omitempty"`
+	Size        int32            `json:"size,omitempty"`
+	Events      []CommunityEvent `json:"events"`
+}
+
+// GetCommunityHistory handles GET /api/communities/{id}/history with the
+// lineage events of a community across precalculation runs, including those of
+// communities that split off from it or merged into it. Communities that no
+// longer exist keep their history.
+func GetCommunityHistory(q CommunityHistoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id64, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+		if err != nil || id64 <= 0 {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("invalid community ID"))
+			return
+		}
+		id := int32(id64)
+
+		resp := CommunityHistoryResponse{CommunityID: id, Events: []CommunityEvent{}}
+		comm, err := q.GetCommunity(ctx, id)
+		switch {
+		case err == nil:
+			resp.Current, resp.Label, resp.Size = true, comm.Label, comm.Size
+		case !errors.Is(err, sql.ErrNoRows):
+			logger.ErrorContext(ctx, "Failed to fetch community", "error", err, "community_id", id)
+			apierr.WriteErrorWithContext(w, r, apierr.SystemDatabase("failed to fetch community"))
+			return
+		}
+
+		events, err := q.ListCommunityEvents(ctx, id)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to fetch community events", "error", err, "community_id", id)
+			apierr.WriteErrorWithContext(w, r, apierr.SystemDatabase("failed to fetch community history"))
+			return
+		}
+		if !resp.Current && len(events) == 0 {
+			apierr.WriteErrorWithContext(w, r, apierr.ResourceNotFound("community"))
+			return
+		}
+		for _, e := range events {
+			ev := CommunityEvent{RunID: e.RunID, At: e.CreatedAt, Event: e.Event, CommunityID: e.CommunityID, Size: e.Size}
+			if e.RelatedID.Valid {
+				ev.RelatedID = &e.RelatedID.Int32
+			}
+			if e.Jaccard.Valid {
+				ev.Jaccard = &e.Jaccard.Float64
+			}
+			resp.Events = append(resp.Events, ev)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// mockCommunityHistory implements CommunityHistoryStore.
+type mockCommunityHistory struct {
+	mockCommunityDataReader
+	events []db.CommunityEvent
+}
+
+func (m *mockCommunityHistory) ListCommunityEvents(ctx context.Context, communityID int32) ([]db.CommunityEvent, error) {
+	var out []db.CommunityEvent
+	for _, e := range m.events {
+		if e.CommunityID == communityID || (e.RelatedID.Valid && e.RelatedID.Int32 == communityID) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func getCommunityHistory(t *testing.T, m *mockCommunityHistory, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/communities/"+id+"/history", nil), map[string]string{"id": id})
+	rr := httptest.NewRecorder()
+	GetCommunityHistory(m).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGetCommunityHistory(t *testing.T) {
+	m := &mockCommunityHistory{
+		mockCommunityDataReader: mockCommunityDataReader{communities: []db.GraphCommunity{{ID: 3, Label: "programming", Size: 40}}},
+		events: []db.CommunityEvent{
+			{RunID: 1, CommunityID: 3, Event: "birth", Size: 30},
+			{RunID: 1, CommunityID: 4, Event: "birth", Size: 10},
+			{RunID: 2, CommunityID: 3, Event: "continue", Jaccard: sql.NullFloat64{Float64: 0.75, Valid: true}, Size: 40},
+			{RunID: 2, CommunityID: 4, Event: "merge", RelatedID: sql.NullInt32{Int32: 3, Valid: true}, Jaccard: sql.NullFloat64{Float64: 0.25, Valid: true}, Size: 10},
+		},
+	}
+
+	rr := getCommunityHistory(t, m, "3")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	var resp CommunityHistoryResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Current || resp.Label != "programming" || len(resp.Events) != 3 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	merge := resp.Events[2]
+	if merge.Event != "merge" || merge.CommunityID != 4 || merge.RelatedID == nil || *merge.RelatedID != 3 || *merge.Jaccard != 0.25 {
+		t.Errorf("unexpected merge event %+v", merge)
+	}
+
+	// A community that no longer exists keeps its history
+	rr = getCommunityHistory(t, m, "4")
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %v", rr.Code, err)
+	}
+	if resp.Current || len(resp.Events) != 2 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestGetCommunityHistory_NotFound(t *testing.T) {
+	m := &mockCommunityHistory{}
+	if rr := getCommunityHistory(t, m, "9"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+	if rr := getCommunityHistory(t, m, "abc"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
@@ -13,8 +15,18 @@ import (
 	"github.com/onnwee/reddit-cluster-map/backend/internal/middleware"
 )
 
+// CrawlRequest queues a crawl. Type defaults to "subreddit"; the other job types
+// read their own fields: username for "user", post_id (or a permalink in url) for
+// "post", and query with optional subreddit, sort and time for "search".
 type CrawlRequest struct {
+	Type      string `json:"type"`
 	Subreddit string `json:"subreddit"`
+	Username  string `json:"username"`
+	PostID    string `json:"post_id"`
+	URL       string `json:"url"`
+	Query     string `json:"query"`
+	Sort      string `json:"sort"`
+	Time      string `json:"time"`
 }
 
 // CrawlQueue abstracts the queries used by PostCrawl for testability.
@@ -22,6 +34,8 @@ type CrawlQueue interface {
 	EnsureSubreddit(ctx context.Context, p db.EnsureSubredditParams) (int32, error)
 	CrawlJobExists(ctx context.Context, subredditID int32) (bool, error)
 	EnqueueCrawlJob(ctx context.Context, p db.EnqueueCrawlJobParams) error
+	EnqueueTypedCrawlJob(ctx context.Context, p db.EnqueueTypedCrawlJobParams) (int32, error)
+	MarkSubredditSeeds(ctx context.Context, names []string) error
 }
 
 func PostCrawl(q CrawlQueue) http.HandlerFunc {
@@ -42,6 +56,12 @@ func PostCrawl(q CrawlQueue) http.HandlerFunc {
 			return
 		}
 
+		req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+		if req.Type != "" && req.Type != crawler.JobTypeSubreddit {
+			enqueueTypedCrawl(w, r, q, req)
+			return
+		}
+
 		// Sanitize and validate subreddit name
 		req.Subreddit = sanitizer.SanitizeString(req.Subreddit, 21)
 		if req.Subreddit == "" {
@@ -66,6 +86,11 @@ func PostCrawl(q CrawlQueue) http.HandlerFunc {
 			return
 		}
 
+		// Subreddits requested through the API are discovery seeds.
+		if err := q.MarkSubredditSeeds(r.Context(), []string{req.Subreddit}); err != nil {
+			log.Printf("⚠️ Failed to mark %s as a seed: %v", req.Subreddit, err)
+		}
+
 		// Ensure a job exists; if already queued/crawling, do nothing.
 		if exists, err := q.CrawlJobExists(r.Context(), subreddit); err == nil {
 			if !exists {
@@ -87,3 +112,40 @@ func PostCrawl(q CrawlQueue) http.HandlerFunc {
 		w.Write([]byte("Seeded and crawler started.\n"))
 	}
 }
+
+// enqueueTypedCrawl queues a user, post or search job. A request for a job that is
+// already queued or crawling is answered with 409 and leaves the queue unchanged.
+func enqueueTypedCrawl(w http.ResponseWriter, r *http.Request, q CrawlQueue, req CrawlRequest) {
+	if !crawler.IsValidJobType(req.Type) {
+		apierr.WriteErrorWithContext(w, r, apierr.ValidationInvalidValue("type", "Must be one of: "+strings.Join(crawler.JobTypes, ", ")))
+		return
+	}
+	postID := req.PostID
+	if postID == "" {
+		postID = req.URL
+	}
+	payload := crawler.JobPayload{
+		Username:   req.Username,
+		PostID:     postID,
+		Query:      req.Query,
+		Subreddit:  req.Subreddit,
+		Sort:       req.Sort,
+		TimeFilter: req.Time,
+	}
+	params, err := crawler.NewTypedJobParams(req.Type, payload, 1, "api")
+	if err != nil {
+		apierr.WriteErrorWithContext(w, r, apierr.ValidationInvalidFormat(err.Error()))
+		return
+	}
+	if _, err := q.EnqueueTypedCrawlJob(r.Context(), params); errors.Is(err, sql.ErrNoRows) {
+		apierr.WriteErrorWithContext(w, r, apierr.ResourceConflict("A "+req.Type+" crawl for "+params.DedupeKey.String+" is already queued"))
+		return
+	} else if err != nil {
+		log.Printf("❌ Failed to enqueue %s job %s: %v", req.Type, params.DedupeKey.String, err)
+		apierr.WriteErrorWithContext(w, r, apierr.CrawlQueueFailed(""))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("Queued " + req.Type + " crawl.\n"))
+}
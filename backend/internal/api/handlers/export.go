@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,12 +56,18 @@ func ExportGraph(q ExportDataReader) http.HandlerFunc {
 
 		// Parse type filter
 		_, allowedList, typeKey, allowAll := parseTypes(r.URL.Query().Get("types"))
+		lf, err := parseLinkFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
 
 		span.SetAttributes(
 			attribute.String("format", format),
 			attribute.Int("max_nodes", maxNodes),
 			attribute.Int("max_links", maxLinks),
 			attribute.String("type_filter", typeKey),
+			attribute.String("link_filter", lf.key()),
 		)
 
 		// Set timeout
@@ -78,6 +87,9 @@ func ExportGraph(q ExportDataReader) http.HandlerFunc {
 			allRows, err := q.GetPrecalculatedGraphDataCappedAll(ctx, db.GetPrecalculatedGraphDataCappedAllParams{
 				Limit:   int32(maxNodes),
 				Limit_2: int32(maxLinks),
+				Column3: lf.MinWeight,
+				Column4: lf.Types,
+				Column5: lf.MaxPValue,
 			})
 			if err != nil {
 				logger.ErrorContext(ctx, "Failed to fetch export data", "error", err)
@@ -95,6 +107,9 @@ func ExportGraph(q ExportDataReader) http.HandlerFunc {
 					Column1: allowedList,
 					Limit:   int32(maxNodes),
 					Limit_2: int32(maxLinks),
+					Column4: lf.MinWeight,
+					Column5: lf.Types,
+					Column6: lf.MaxPValue,
 				})
 				if err != nil {
 					logger.ErrorContext(ctx, "Failed to fetch filtered export data", "error", err)
@@ -126,6 +141,8 @@ type exportRow struct {
 	Type     string
 	Source   string
 	Target   string
+	Weight   string // links only
+	LinkType string
 }
 
 func convertToExportRows(rows []db.GetPrecalculatedGraphDataCappedAllRow) []exportRow {
@@ -139,6 +156,8 @@ func convertToExportRows(rows []db.GetPrecalculatedGraphDataCappedAllRow) []expo
 			Type:     r.Type.String,
 			Source:   toString(r.Source),
 			Target:   toString(r.Target),
+			Weight:   formatLinkWeight(r.Weight),
+			LinkType: r.LinkType.String,
 		}
 	}
 	return result
@@ -155,11 +174,21 @@ func convertToExportRowsFiltered(rows []db.GetPrecalculatedGraphDataCappedFilter
 			Type:     r.Type.String,
 			Source:   toString(r.Source),
 			Target:   toString(r.Target),
+			Weight:   formatLinkWeight(r.Weight),
+			LinkType: r.LinkType.String,
 		}
 	}
 	return result
 }
 
+// formatLinkWeight renders a link weight for export, "" on node rows.
+func formatLinkWeight(w sql.NullFloat64) string {
+	if !w.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(w.Float64, 'f', -1, 64)
+}
+
 func exportJSON(w http.ResponseWriter, rows []exportRow) {
 	// Separate nodes and links for cleaner JSON structure
 	var nodes []map[string]interface{}
@@ -181,6 +210,12 @@ func exportJSON(w http.ResponseWriter, rows []exportRow) {
 				"source": row.Source,
 				"target": row.Target,
 			}
+			if w, err := strconv.ParseFloat(row.Weight, 64); err == nil {
+				link["weight"] = w
+			}
+			if row.LinkType != "" {
+				link["type"] = row.LinkType
+			}
 			links = append(links, link)
 		}
 	}
@@ -203,7 +238,7 @@ func exportCSV(w http.ResponseWriter, rows []exportRow) {
 	defer writer.Flush()
 
 	// Write header
-	if err := writer.Write([]string{"data_type", "id", "name", "val", "type", "source", "target"}); err != nil {
+	if err := writer.Write([]string{"data_type", "id", "name", "val", "type", "source", "target", "weight", "link_type"}); err != nil {
 		logger.Error("Failed to write CSV header", "error", err)
 		return
 	}
@@ -218,6 +253,8 @@ func exportCSV(w http.ResponseWriter, rows []exportRow) {
 			row.Type,
 			row.Source,
 			row.Target,
+			row.Weight,
+			row.LinkType,
 		}
 		if err := writer.Write(record); err != nil {
 			logger.Error("Failed to write CSV row", "error", err)
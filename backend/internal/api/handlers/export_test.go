@@ -148,7 +148,7 @@ func TestExportGraph(t *testing.T) {
 
 					// Check header
 					header := records[0]
-					expectedHeaders := []string{"data_type", "id", "name", "val", "type", "source", "target"}
+					expectedHeaders := []string{"data_type", "id", "name", "val", "type", "source", "target", "weight", "link_type"}
 					if len(header) != len(expectedHeaders) {
 						t.Errorf("expected %d headers, got %d", len(expectedHeaders), len(header))
 					}
@@ -77,11 +77,17 @@ type GraphNode struct {
 	X    *float64 `json:"x,omitempty"`
 	Y    *float64 `json:"y,omitempty"`
 	Z    *float64 `json:"z,omitempty"`
+	// Size is the size_by measure of the node, when requested
+	Size *float64 `json:"size,omitempty"`
+	// rank orders nodes under max_nodes with sort_by
+	rank float64
 }
 
 type GraphLink struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Weight float64 `json:"weight"`
+	Type   string  `json:"type,omitempty"` // link type, see db.LinkTypes
 }
 
 type GraphResponse struct {
@@ -166,6 +172,16 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 	fallback := r.URL.Query().Get("fallback")
 	allowFallback := fallback == "" || fallback == "1" || strings.EqualFold(fallback, "true")
 	allowedTypes, allowedList, typeKey, allowAll := parseTypes(r.URL.Query().Get("types"))
+	lf, err := parseLinkFilter(r.URL.Query())
+	if err != nil {
+		apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams(err.Error()))
+		return
+	}
+	opts, err := parseNodeOptions(r.URL.Query())
+	if err != nil {
+		apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams(err.Error()))
+		return
+	}
 	withPos := func() bool {
 		v := strings.TrimSpace(r.URL.Query().Get("with_positions"))
 		return v == "1" || strings.EqualFold(v, "true")
@@ -179,7 +195,7 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 	pageSizeParam := r.URL.Query().Get("page_size")
 	if cursorParam != "" || pageSizeParam != "" {
 		// Use pagination path
-		h.getGraphDataPaginated(w, r, ctx, cursorParam, pageSizeParam, withPos, allowAll, allowedTypes)
+		h.getGraphDataPaginated(w, r, ctx, cursorParam, pageSizeParam, withPos, allowAll, allowedTypes, lf)
 		return
 	}
 
@@ -189,17 +205,21 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 		attribute.Int("max_links", maxLinks),
 		attribute.Bool("with_positions", withPos),
 		attribute.String("type_filter", typeKey),
+		attribute.String("link_filter", lf.key()),
+		attribute.String("node_options", opts.key()),
 		attribute.Bool("ndjson", useNDJSON),
 	)
+	// Responses differ by link filter and node options too
+	filterKey := typeKey + lf.key() + opts.key()
 
 	if !allowAll && len(allowedTypes) == 0 {
 		span.SetAttributes(attribute.String("result", "empty_filter"))
-		writeCachedEmpty(w, h, maxNodes, maxLinks, typeKey, withPos)
+		writeCachedEmpty(w, h, maxNodes, maxLinks, filterKey, withPos)
 		return
 	}
 
 	// Check cache first
-	key := cacheKey(maxNodes, maxLinks, typeKey, withPos)
+	key := cacheKey(maxNodes, maxLinks, filterKey, withPos)
 	if cachedData, found := h.cache.Get(key); found {
 		metrics.APICacheHits.WithLabelValues("graph").Inc()
 		span.SetAttributes(attribute.Bool("cache_hit", true))
@@ -211,7 +231,7 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 	span.SetAttributes(attribute.Bool("cache_hit", false))
 
 	// Try precalculated tables (capped) first
-	rows, err := fetchPrecalcCapped(ctx, h.queries, maxNodes, maxLinks, allowAll, allowedList)
+	rows, err := fetchPrecalcCapped(ctx, h.queries, maxNodes, maxLinks, allowAll, allowedList, lf, opts.SortBy)
 	if err != nil {
 		// Check if this was a timeout/cancellation
 		if ctx.Err() == context.DeadlineExceeded || err == context.DeadlineExceeded {
@@ -271,16 +291,17 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 						gn.Z = &z
 					}
 				}
+				opts.apply(&gn, row)
 				nodes[row.ID] = gn
 			case "link":
 				src := toString(row.Source)
 				tgt := toString(row.Target)
 				if src != "" && tgt != "" {
-					links = append(links, GraphLink{Source: src, Target: tgt})
+					links = append(links, GraphLink{Source: src, Target: tgt, Weight: row.Weight.Float64, Type: row.LinkType.String})
 				}
 			}
 		}
-		resp := capGraph(nodes, links, maxNodes, maxLinks)
+		resp := capGraph(nodes, links, maxNodes, maxLinks, opts.SortBy != "")
 		
 		// Handle NDJSON streaming vs regular JSON
 		if useNDJSON {
@@ -312,7 +333,7 @@ func (h *Handler) GetGraphData(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	handleLegacyGraph(ctx, w, r, h, maxNodes, maxLinks, allowAll, allowedTypes, typeKey, withPos, useNDJSON)
+	handleLegacyGraph(ctx, w, r, h, maxNodes, maxLinks, allowAll, allowedTypes, filterKey, withPos, useNDJSON, lf)
 }
 
 func toString(v interface{}) string {
@@ -342,8 +363,8 @@ func atoiSafe(s string) int {
 }
 
 // capGraph selects up to maxNodes by weight and filters links accordingly.
-// Weight prefers higher Val and degree.
-func capGraph(nodes map[string]GraphNode, links []GraphLink, maxNodes, maxLinks int) GraphResponse {
+// Weight prefers higher Val and degree, or is the node's rank with byRank.
+func capGraph(nodes map[string]GraphNode, links []GraphLink, maxNodes, maxLinks int, byRank bool) GraphResponse {
 	if maxNodes <= 0 {
 		maxNodes = 20000
 	}
@@ -363,6 +384,12 @@ func capGraph(nodes map[string]GraphNode, links []GraphLink, maxNodes, maxLinks
 	}
 	// weight = max(Val, degree)
 	sort.Slice(list, func(i, j int) bool {
+		if byRank {
+			if list[i].rank == list[j].rank {
+				return list[i].ID < list[j].ID
+			}
+			return list[i].rank > list[j].rank
+		}
 		wi := list[i].Val
 		if di := deg[list[i].ID]; di > wi {
 			wi = di
@@ -416,7 +443,7 @@ func parseIntDefault(s string, def int) int {
 	return def
 }
 
-func handleLegacyGraph(ctx context.Context, w http.ResponseWriter, r *http.Request, h *Handler, maxNodes, maxLinks int, allowAll bool, allowedTypes map[string]struct{}, typeKey string, withPos bool, useNDJSON bool) {
+func handleLegacyGraph(ctx context.Context, w http.ResponseWriter, r *http.Request, h *Handler, maxNodes, maxLinks int, allowAll bool, allowedTypes map[string]struct{}, typeKey string, withPos bool, useNDJSON bool, lf linkFilter) {
 	cacheKeyStr := cacheKey(maxNodes, maxLinks, typeKey, withPos)
 	data, err := h.queries.GetGraphData(ctx)
 	if err != nil {
@@ -444,7 +471,16 @@ func handleLegacyGraph(ctx context.Context, w http.ResponseWriter, r *http.Reque
 				n.Type = t
 				nodes[n.ID] = n
 			}
-			response = capGraph(nodes, response.Links, maxNodes, maxLinks)
+			links := response.Links
+			if lf.key() != "" {
+				links = make([]GraphLink, 0, len(response.Links))
+				for _, l := range response.Links {
+					if lf.allows(l) {
+						links = append(links, l)
+					}
+				}
+			}
+			response = capGraph(nodes, links, maxNodes, maxLinks, false)
 		} else {
 			// Unknown legacy format; return empty
 			response = GraphResponse{Nodes: []GraphNode{}, Links: []GraphLink{}}
@@ -485,10 +521,19 @@ type preRow struct {
 	PosZ     sql.NullFloat64
 	Source   interface{}
 	Target   interface{}
+	Weight   sql.NullFloat64
+	LinkType sql.NullString
+	// Node centrality
+	Pagerank       sql.NullFloat64
+	WeightedDegree sql.NullFloat64
+	Betweenness    sql.NullFloat64
+	Kcore          sql.NullInt32
 }
 
-// fetchPrecalcCapped runs a DB-level capped selection of precalculated graph data.
-func fetchPrecalcCapped(ctx context.Context, q GraphDataReader, maxNodes, maxLinks int, allowAll bool, allowedTypes []string) ([]preRow, error) {
+// fetchPrecalcCapped runs a DB-level capped selection of precalculated graph data,
+// keeping the nodes ranking highest by sortBy (val when empty) and the heaviest
+// links that pass the link filter.
+func fetchPrecalcCapped(ctx context.Context, q GraphDataReader, maxNodes, maxLinks int, allowAll bool, allowedTypes []string, lf linkFilter, sortBy string) ([]preRow, error) {
 	if maxNodes <= 0 {
 		maxNodes = 20000
 	}
@@ -499,13 +544,18 @@ func fetchPrecalcCapped(ctx context.Context, q GraphDataReader, maxNodes, maxLin
 		allRows, err := q.GetPrecalculatedGraphDataCappedAll(ctx, db.GetPrecalculatedGraphDataCappedAllParams{
 			Limit:   int32(maxNodes),
 			Limit_2: int32(maxLinks),
+			Column3: lf.MinWeight,
+			Column4: lf.Types,
+			Column5: lf.MaxPValue,
+			Column6: sortBy,
 		})
 		if err != nil {
 			return nil, err
 		}
 		out := make([]preRow, len(allRows))
 		for i, r := range allRows {
-			out[i] = preRow{DataType: r.DataType, ID: r.ID, Name: r.Name, Val: r.Val, Type: r.Type, PosX: r.PosX, PosY: r.PosY, PosZ: r.PosZ, Source: r.Source, Target: r.Target}
+			out[i] = preRow{DataType: r.DataType, ID: r.ID, Name: r.Name, Val: r.Val, Type: r.Type, PosX: r.PosX, PosY: r.PosY, PosZ: r.PosZ, Source: r.Source, Target: r.Target, Weight: r.Weight, LinkType: r.LinkType,
+				Pagerank: r.Pagerank, WeightedDegree: r.WeightedDegree, Betweenness: r.Betweenness, Kcore: r.Kcore}
 		}
 		return out, nil
 	}
@@ -515,13 +565,18 @@ func fetchPrecalcCapped(ctx context.Context, q GraphDataReader, maxNodes, maxLin
 		Column1: arr,
 		Limit:   int32(maxNodes),
 		Limit_2: int32(maxLinks),
+		Column4: lf.MinWeight,
+		Column5: lf.Types,
+		Column6: lf.MaxPValue,
+		Column7: sortBy,
 	})
 	if err != nil {
 		return nil, err
 	}
 	out := make([]preRow, len(filteredRows))
 	for i, r := range filteredRows {
-		out[i] = preRow{DataType: r.DataType, ID: r.ID, Name: r.Name, Val: r.Val, Type: r.Type, PosX: r.PosX, PosY: r.PosY, PosZ: r.PosZ, Source: r.Source, Target: r.Target}
+		out[i] = preRow{DataType: r.DataType, ID: r.ID, Name: r.Name, Val: r.Val, Type: r.Type, PosX: r.PosX, PosY: r.PosY, PosZ: r.PosZ, Source: r.Source, Target: r.Target, Weight: r.Weight, LinkType: r.LinkType,
+			Pagerank: r.Pagerank, WeightedDegree: r.WeightedDegree, Betweenness: r.Betweenness, Kcore: r.Kcore}
 	}
 	return out, nil
 }
@@ -941,6 +996,12 @@ func (h *Handler) GetGraphRegion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lf, err := parseLinkFilter(query)
+	if err != nil {
+		apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams(err.Error()))
+		return
+	}
+
 	// Parse additional parameters
 	maxNodes := parseIntDefault(query.Get("max_nodes"), 10000)
 	maxLinks := parseIntDefault(query.Get("max_links"), 50000)
@@ -977,7 +1038,7 @@ func (h *Handler) GetGraphRegion(w http.ResponseWriter, r *http.Request) {
 		strconv.FormatFloat(yMax, 'g', 17, 64) + ":" +
 		strconv.FormatFloat(zMin, 'g', 17, 64) + ":" +
 		strconv.FormatFloat(zMax, 'g', 17, 64) + ":" +
-		strconv.Itoa(maxNodes) + ":" + strconv.Itoa(maxLinks)
+		strconv.Itoa(maxNodes) + ":" + strconv.Itoa(maxLinks) + lf.key()
 
 	// Check cache first
 	if cachedData, found := h.cache.Get(key); found {
@@ -1017,13 +1078,16 @@ func (h *Handler) GetGraphRegion(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch links for nodes in bounding box
 	linksRows, err := h.queries.GetLinksForNodesInBoundingBox(ctx, db.GetLinksForNodesInBoundingBoxParams{
-		PosX:   sql.NullFloat64{Float64: xMin, Valid: true},
-		PosX_2: sql.NullFloat64{Float64: xMax, Valid: true},
-		PosY:   sql.NullFloat64{Float64: yMin, Valid: true},
-		PosY_2: sql.NullFloat64{Float64: yMax, Valid: true},
-		PosZ:   sql.NullFloat64{Float64: zMin, Valid: true},
-		PosZ_2: sql.NullFloat64{Float64: zMax, Valid: true},
-		Limit:  int32(maxLinks),
+		PosX:     sql.NullFloat64{Float64: xMin, Valid: true},
+		PosX_2:   sql.NullFloat64{Float64: xMax, Valid: true},
+		PosY:     sql.NullFloat64{Float64: yMin, Valid: true},
+		PosY_2:   sql.NullFloat64{Float64: yMax, Valid: true},
+		PosZ:     sql.NullFloat64{Float64: zMin, Valid: true},
+		PosZ_2:   sql.NullFloat64{Float64: zMax, Valid: true},
+		Limit:    int32(maxLinks),
+		Column8:  lf.MinWeight,
+		Column9:  lf.Types,
+		Column10: lf.MaxPValue,
 	})
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded || err == context.DeadlineExceeded {
@@ -1075,7 +1139,7 @@ func (h *Handler) GetGraphRegion(w http.ResponseWriter, r *http.Request) {
 		src := toString(row.Source)
 		tgt := toString(row.Target)
 		if src != "" && tgt != "" {
-			links = append(links, GraphLink{Source: src, Target: tgt})
+			links = append(links, GraphLink{Source: src, Target: tgt, Weight: row.Weight, Type: row.LinkType})
 		}
 	}
 
@@ -1102,7 +1166,7 @@ func (h *Handler) GetGraphRegion(w http.ResponseWriter, r *http.Request) {
 }
 
 // getGraphDataPaginated handles paginated graph data requests
-func (h *Handler) getGraphDataPaginated(w http.ResponseWriter, r *http.Request, ctx context.Context, cursorParam, pageSizeParam string, withPos, allowAll bool, allowedTypes map[string]struct{}) {
+func (h *Handler) getGraphDataPaginated(w http.ResponseWriter, r *http.Request, ctx context.Context, cursorParam, pageSizeParam string, withPos, allowAll bool, allowedTypes map[string]struct{}, lf linkFilter) {
 	ctx, span := tracing.StartSpan(ctx, "handlers.getGraphDataPaginated")
 	defer span.End()
 	
@@ -1231,6 +1295,9 @@ func (h *Handler) getGraphDataPaginated(w http.ResponseWriter, r *http.Request,
 		linkRows, err := h.queries.GetLinksForPaginatedNodes(ctx, db.GetLinksForPaginatedNodesParams{
 			Column1: nodeIDs,
 			Limit:   int32(maxLinksForPage),
+			Column3: lf.MinWeight,
+			Column4: lf.Types,
+			Column5: lf.MaxPValue,
 		})
 		
 		if err != nil {
@@ -1241,7 +1308,7 @@ func (h *Handler) getGraphDataPaginated(w http.ResponseWriter, r *http.Request,
 				src := toString(lr.Source)
 				tgt := toString(lr.Target)
 				if src != "" && tgt != "" {
-					links = append(links, GraphLink{Source: src, Target: tgt})
+					links = append(links, GraphLink{Source: src, Target: tgt, Weight: lr.Weight, Type: lr.LinkType})
 				}
 			}
 		}
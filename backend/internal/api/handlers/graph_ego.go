@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// EgoNetworkStore abstracts the graph queries of ego networks for testability.
+type EgoNetworkStore interface {
+	GraphPathStore
+	ListGraphLinksAmongFiltered(ctx context.Context, arg db.ListGraphLinksAmongFilteredParams) ([]db.ListGraphLinksAmongRow, error)
+}
+
+const (
+	defaultEgoDepth    = 2
+	maxEgoDepth        = 4
+	defaultEgoMaxNodes = 500
+	maxEgoMaxNodes     = 20000
+)
+
+// GetEgoNetwork handles GET /api/graph/ego/{id}?depth=&max_nodes=&max_links=
+// with the subgraph induced by the nodes within depth hops of a node. Each hop
+// adds the nodes with the strongest links to the ones already in, until
+// max_nodes. types restricts the added nodes and the link filter params the
+// links followed and returned, as for /api/graph, whose JSON and NDJSON
+// formats the response uses. Stored positions are included when present.
+func GetEgoNetwork(q EgoNetworkStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(mux.Vars(r)["id"])
+		if id == "" {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("node ID is required"))
+			return
+		}
+		query := r.URL.Query()
+		depth := parseIntDefault(query.Get("depth"), defaultEgoDepth)
+		if depth < 1 || depth > maxEgoDepth {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("depth must be between 1 and "+strconv.Itoa(maxEgoDepth)))
+			return
+		}
+		maxNodes := parseIntDefault(query.Get("max_nodes"), defaultEgoMaxNodes)
+		if maxNodes < 1 {
+			maxNodes = 1
+		}
+		if maxNodes > maxEgoMaxNodes {
+			maxNodes = maxEgoMaxNodes
+		}
+		maxLinks := parseIntDefault(query.Get("max_links"), 50000)
+		lf, err := parseLinkFilter(query)
+		if err != nil {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams(err.Error()))
+			return
+		}
+		_, nodeTypes, _, allowAll := parseTypes(query.Get("types"))
+		if allowAll {
+			nodeTypes = nil
+		}
+
+		timeout := config.Load().GraphQueryTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		center, err := q.ListGraphNodesByIDs(ctx, []string{id})
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to fetch node")
+			return
+		}
+		if len(center) == 0 {
+			apierr.WriteErrorWithContext(w, r, apierr.ResourceNotFound("node"))
+			return
+		}
+
+		ids, err := expandEgoNetwork(ctx, q, id, depth, maxNodes, nodeTypes, lf)
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to expand ego network")
+			return
+		}
+		rows, err := q.ListGraphNodesByIDs(ctx, ids)
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to fetch ego network nodes")
+			return
+		}
+		links, err := q.ListGraphLinksAmongFiltered(ctx, db.ListGraphLinksAmongFilteredParams{
+			IDs:       ids,
+			MinWeight: lf.MinWeight,
+			LinkTypes: lf.Types,
+			MaxPValue: lf.MaxPValue,
+		})
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to fetch ego network links")
+			return
+		}
+
+		// Rank nodes in the order they were added, the ego first
+		order := make(map[string]int, len(ids))
+		for i, n := range ids {
+			order[n] = i
+		}
+		nodes := make(map[string]GraphNode, len(rows))
+		for _, n := range rows {
+			gn := GraphNode{ID: n.ID, Name: n.Name, Val: atoiSafe(n.Val), Type: n.Type, rank: float64(len(ids) - order[n.ID])}
+			if n.PosX.Valid && n.PosY.Valid && n.PosZ.Valid {
+				gn.X, gn.Y, gn.Z = &n.PosX.Float64, &n.PosY.Float64, &n.PosZ.Float64
+			}
+			nodes[n.ID] = gn
+		}
+		gl := make([]GraphLink, 0, len(links))
+		for _, l := range links {
+			gl = append(gl, GraphLink{Source: l.Source, Target: l.Target, Weight: l.Weight, Type: l.LinkType})
+		}
+		resp := capGraph(nodes, gl, maxNodes, maxLinks, true)
+		useNDJSON := strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+		writeGraphResponse(w, resp, useNDJSON, "", nil)
+	}
+}
+
+// expandEgoNetwork returns the ego and the nodes added around it, in order.
+// Each hop loads the links of the previous hop's nodes and adds their unseen
+// neighbors by the weight of their strongest link, heaviest first, until
+// maxNodes.
+func expandEgoNetwork(ctx context.Context, q GraphPathStore, id string, depth, maxNodes int, nodeTypes []string, lf linkFilter) ([]string, error) {
+	ids := []string{id}
+	seen := map[string]bool{id: true}
+	frontier := []string{id}
+	for d := 0; d < depth && len(frontier) > 0 && len(ids) < maxNodes; d++ {
+		best := map[string]float64{}
+		for start := 0; start < len(frontier); start += pathBatchSize {
+			end := min(start+pathBatchSize, len(frontier))
+			rows, err := q.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{
+				IDs:       frontier[start:end],
+				NodeTypes: nodeTypes,
+				MinWeight: lf.MinWeight,
+				LinkTypes: lf.Types,
+				MaxPValue: lf.MaxPValue,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range rows {
+				for _, other := range []string{n.Source, n.Target} {
+					if seen[other] {
+						continue
+					}
+					if w, ok := best[other]; !ok || n.Weight > w {
+						best[other] = n.Weight
+					}
+				}
+			}
+		}
+		next := make([]string, 0, len(best))
+		for n := range best {
+			next = append(next, n)
+		}
+		sort.Slice(next, func(i, j int) bool {
+			if best[next[i]] != best[next[j]] {
+				return best[next[i]] > best[next[j]]
+			}
+			return next[i] < next[j]
+		})
+		if room := maxNodes - len(ids); len(next) > room {
+			next = next[:room]
+		}
+		for _, n := range next {
+			seen[n] = true
+		}
+		ids = append(ids, next...)
+		frontier = next
+	}
+	return ids, nil
+}
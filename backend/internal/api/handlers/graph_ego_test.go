@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// mockEgoStore implements EgoNetworkStore.
+type mockEgoStore struct {
+	mockPathStore
+}
+
+func (m *mockEgoStore) ListGraphLinksAmongFiltered(ctx context.Context, arg db.ListGraphLinksAmongFilteredParams) ([]db.ListGraphLinksAmongRow, error) {
+	var out []db.ListGraphLinksAmongRow
+	for _, l := range m.links {
+		if containsString(arg.IDs, l.Source) && containsString(arg.IDs, l.Target) && l.Weight >= arg.MinWeight {
+			out = append(out, db.ListGraphLinksAmongRow{Source: l.Source, Target: l.Target, Weight: l.Weight, LinkType: l.LinkType})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Weight > out[j].Weight })
+	return out, nil
+}
+
+func newMockEgoStore() *mockEgoStore {
+	m := &mockEgoStore{mockPathStore: *newMockPathStore()}
+	// subreddit_1 also links to two small subreddits, one through a weak link
+	m.nodes = append(m.nodes,
+		db.GraphNodeSummary{ID: "subreddit_4", Name: "gamedev", Val: "50", Type: "subreddit"},
+		db.GraphNodeSummary{ID: "subreddit_5", Name: "emacs", Val: "20", Type: "subreddit"},
+	)
+	m.nodes[0].PosX = sql.NullFloat64{Float64: 1, Valid: true}
+	m.nodes[0].PosY = sql.NullFloat64{Float64: 2, Valid: true}
+	m.nodes[0].PosZ = sql.NullFloat64{Float64: 3, Valid: true}
+	m.links = append(m.links,
+		db.GraphNeighbor{Source: "subreddit_1", Target: "subreddit_4", Weight: 8, LinkType: "subreddit_overlap"},
+		db.GraphNeighbor{Source: "subreddit_5", Target: "subreddit_1", Weight: 1, LinkType: "subreddit_overlap"},
+	)
+	return m
+}
+
+func getEgoNetwork(t *testing.T, m *mockEgoStore, id, query string) (*httptest.ResponseRecorder, GraphResponse) {
+	t.Helper()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/graph/ego/"+id+"?"+query, nil), map[string]string{"id": id})
+	rr := httptest.NewRecorder()
+	GetEgoNetwork(m).ServeHTTP(rr, req)
+	var resp GraphResponse
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rr, resp
+}
+
+func nodeIDs(nodes []GraphNode) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.ID
+	}
+	return out
+}
+
+func TestGetEgoNetwork(t *testing.T) {
+	m := newMockEgoStore()
+
+	// One hop: the ego first, then its neighbors by link strength
+	rr, resp := getEgoNetwork(t, m, "subreddit_1", "depth=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	want := []string{"subreddit_1", "subreddit_3", "subreddit_4", "user_1", "subreddit_5"}
+	if got := nodeIDs(resp.Nodes); !reflect.DeepEqual(got, want) {
+		t.Fatalf("nodes = %v, want %v", got, want)
+	}
+	if n := resp.Nodes[0]; n.X == nil || *n.X != 1 || *n.Z != 3 {
+		t.Errorf("ego position missing: %+v", n)
+	}
+	if resp.Nodes[1].X != nil {
+		t.Errorf("unexpected position on %s", resp.Nodes[1].ID)
+	}
+	// The induced subgraph has every link among the nodes, heaviest first
+	if len(resp.Links) != 4 || resp.Links[0].Weight != 40 {
+		t.Fatalf("links = %+v", resp.Links)
+	}
+
+	// Two hops reach rust; max_nodes keeps the strongest neighbors
+	_, resp = getEgoNetwork(t, m, "subreddit_1", "depth=2&max_nodes=3")
+	if got := nodeIDs(resp.Nodes); !reflect.DeepEqual(got, []string{"subreddit_1", "subreddit_3", "subreddit_4"}) {
+		t.Fatalf("capped nodes = %v", got)
+	}
+	_, resp = getEgoNetwork(t, m, "subreddit_1", "depth=2&types=subreddit&min_weight=2")
+	if got := nodeIDs(resp.Nodes); !reflect.DeepEqual(got, []string{"subreddit_1", "subreddit_3", "subreddit_4", "subreddit_2"}) {
+		t.Fatalf("filtered nodes = %v", got)
+	}
+	for _, l := range resp.Links {
+		if l.Weight < 2 {
+			t.Errorf("link below min_weight: %+v", l)
+		}
+	}
+}
+
+func TestGetEgoNetwork_NDJSON(t *testing.T) {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/graph/ego/subreddit_1?depth=1", nil), map[string]string{"id": "subreddit_1"})
+	req.Header.Set("Accept", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	GetEgoNetwork(newMockEgoStore()).ServeHTTP(rr, req)
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("content type %q", ct)
+	}
+	counts := map[string]int{}
+	sc := bufio.NewScanner(rr.Body)
+	for sc.Scan() {
+		var env NDJSONEnvelope
+		if err := json.Unmarshal(sc.Bytes(), &env); err != nil {
+			t.Fatal(err)
+		}
+		counts[env.Type]++
+	}
+	if counts["node"] != 5 || counts["link"] != 4 || counts["meta"] != 1 {
+		t.Fatalf("envelopes = %v", counts)
+	}
+}
+
+func TestGetEgoNetwork_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		id, query string
+		want      int
+	}{
+		{"subreddit_1", "depth=0", http.StatusBadRequest},
+		{"subreddit_1", "depth=9", http.StatusBadRequest},
+		{"subreddit_1", "min_weight=-1", http.StatusBadRequest},
+		{"subreddit_99", "", http.StatusNotFound},
+	} {
+		if rr, _ := getEgoNetwork(t, newMockEgoStore(), tc.id, tc.query); rr.Code != tc.want {
+			t.Errorf("%s?%s: status %d, want %d", tc.id, tc.query, rr.Code, tc.want)
+		}
+	}
+}
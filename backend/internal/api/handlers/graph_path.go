@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/graph"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
+)
+
+// GraphPathStore abstracts the graph queries of path searches for testability.
+type GraphPathStore interface {
+	ListGraphNodesByIDs(ctx context.Context, ids []string) ([]db.GraphNodeSummary, error)
+	ListGraphNeighbors(ctx context.Context, arg db.ListGraphNeighborsParams) ([]db.GraphNeighbor, error)
+}
+
+const (
+	defaultPathK       = 3
+	maxPathK           = 10
+	defaultPathMaxHops = 4
+	maxPathMaxHops     = 8
+	// pathNodeBudget caps the nodes loaded around the endpoints of a search
+	pathNodeBudget = 200000
+	// pathBatchSize is the number of nodes whose links are loaded per query
+	pathBatchSize = 5000
+)
+
+// GraphPath is one path of a path search, with the nodes and links in order.
+type GraphPath struct {
+	Hops  int         `json:"hops"`
+	Cost  float64     `json:"cost"`
+	Nodes []GraphNode `json:"nodes"`
+	Links []GraphLink `json:"links"`
+}
+
+// GraphPathResponse is the body of GET /api/graph/path.
+type GraphPathResponse struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	K        int         `json:"k"`
+	MaxHops  int         `json:"max_hops"`
+	Weighted bool        `json:"weighted"`
+	Paths    []GraphPath `json:"paths"`
+	// Truncated is set when the search hit the node budget before covering
+	// every path within max_hops, so shorter paths may exist
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GetGraphPath handles GET /api/graph/path?from=&to=&k=&max_hops=&weighted=
+// with the k shortest paths between two graph nodes over graph_links. types
+// restricts the intermediate nodes, and the link filter params restrict the
+// links, as for /api/graph.
+func GetGraphPath(q GraphPathStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		from := strings.TrimSpace(query.Get("from"))
+		to := strings.TrimSpace(query.Get("to"))
+		if from == "" || to == "" {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("from and to are required"))
+			return
+		}
+		if from == to {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("from and to must differ"))
+			return
+		}
+		k := parseIntDefault(query.Get("k"), defaultPathK)
+		if k < 1 || k > maxPathK {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("k must be between 1 and "+strconv.Itoa(maxPathK)))
+			return
+		}
+		maxHops := parseIntDefault(query.Get("max_hops"), defaultPathMaxHops)
+		if maxHops < 1 || maxHops > maxPathMaxHops {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams("max_hops must be between 1 and "+strconv.Itoa(maxPathMaxHops)))
+			return
+		}
+		weighted := query.Get("weighted") == "1" || strings.EqualFold(query.Get("weighted"), "true")
+		lf, err := parseLinkFilter(query)
+		if err != nil {
+			apierr.WriteErrorWithContext(w, r, apierr.GraphInvalidParams(err.Error()))
+			return
+		}
+		_, nodeTypes, _, allowAll := parseTypes(query.Get("types"))
+		if allowAll {
+			nodeTypes = nil
+		}
+
+		timeout := config.Load().GraphQueryTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		ends, err := q.ListGraphNodesByIDs(ctx, []string{from, to})
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to fetch path endpoints")
+			return
+		}
+		if len(ends) < 2 {
+			apierr.WriteErrorWithContext(w, r, apierr.ResourceNotFound("node"))
+			return
+		}
+
+		g, truncated, err := loadPathGraph(ctx, q, from, to, maxHops, nodeTypes, lf)
+		if err != nil {
+			writePathError(ctx, w, r, err, "Failed to fetch graph links")
+			return
+		}
+		paths, err := g.KShortestPaths(ctx, from, to, graph.PathOptions{K: k, MaxHops: maxHops, Weighted: weighted})
+		if err != nil {
+			writePathError(ctx, w, r, err, "Path search failed")
+			return
+		}
+
+		resp := GraphPathResponse{From: from, To: to, K: k, MaxHops: maxHops, Weighted: weighted, Paths: []GraphPath{}, Truncated: truncated}
+		var ids []string
+		seen := map[string]bool{}
+		for _, p := range paths {
+			for _, id := range p.Nodes {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+		nodes := map[string]GraphNode{}
+		if len(ids) > 0 {
+			rows, err := q.ListGraphNodesByIDs(ctx, ids)
+			if err != nil {
+				writePathError(ctx, w, r, err, "Failed to fetch path nodes")
+				return
+			}
+			for _, n := range rows {
+				nodes[n.ID] = GraphNode{ID: n.ID, Name: n.Name, Val: atoiSafe(n.Val), Type: n.Type}
+			}
+		}
+		for _, p := range paths {
+			gp := GraphPath{Hops: len(p.Edges), Cost: p.Cost, Nodes: make([]GraphNode, 0, len(p.Nodes)), Links: make([]GraphLink, 0, len(p.Edges))}
+			for _, id := range p.Nodes {
+				n, ok := nodes[id]
+				if !ok {
+					n = GraphNode{ID: id, Name: id}
+				}
+				gp.Nodes = append(gp.Nodes, n)
+			}
+			for _, e := range p.Edges {
+				gp.Links = append(gp.Links, GraphLink{Source: e.Source, Target: e.Target, Weight: e.Weight, Type: e.Type})
+			}
+			resp.Paths = append(resp.Paths, gp)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func writePathError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+		logger.WarnContext(ctx, "path search timed out", "error", err)
+		apierr.WriteErrorWithContext(w, r, apierr.GraphTimeout("Path search timeout - try fewer max_hops or a node type filter"))
+		return
+	}
+	logger.ErrorContext(ctx, msg, "error", err)
+	apierr.WriteErrorWithContext(w, r, apierr.GraphQueryFailed(msg))
+}
+
+// loadPathGraph loads the links around both endpoints, one breadth-first layer
+// at a time from the end with the smaller frontier. Once the layers loaded
+// from the two ends add up to maxHops, every path of at most maxHops links is
+// in the graph. It stops early when a frontier runs out, and reports truncated
+// when pathNodeBudget nodes are loaded first.
+func loadPathGraph(ctx context.Context, q GraphPathStore, from, to string, maxHops int, nodeTypes []string, lf linkFilter) (*graph.PathGraph, bool, error) {
+	g := graph.NewPathGraph()
+	g.AddNode(from)
+	g.AddNode(to)
+	loaded := map[string]bool{}
+	type side struct {
+		visited  map[string]bool
+		frontier []string
+		depth    int
+	}
+	a := &side{visited: map[string]bool{from: true}, frontier: []string{from}}
+	b := &side{visited: map[string]bool{to: true}, frontier: []string{to}}
+	for a.depth+b.depth < maxHops {
+		s := a
+		if len(b.frontier) < len(a.frontier) {
+			s = b
+		}
+		if len(s.frontier) == 0 {
+			return g, false, nil
+		}
+		var pending []string
+		for _, id := range s.frontier {
+			if !loaded[id] {
+				pending = append(pending, id)
+			}
+		}
+		for start := 0; start < len(pending); start += pathBatchSize {
+			end := min(start+pathBatchSize, len(pending))
+			rows, err := q.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{
+				IDs:       pending[start:end],
+				NodeTypes: nodeTypes,
+				Endpoints: []string{from, to},
+				MinWeight: lf.MinWeight,
+				LinkTypes: lf.Types,
+				MaxPValue: lf.MaxPValue,
+			})
+			if err != nil {
+				return nil, false, err
+			}
+			for _, n := range rows {
+				g.AddLink(graph.PathEdge{Source: n.Source, Target: n.Target, Weight: n.Weight, Type: n.LinkType})
+			}
+			if g.Len() > pathNodeBudget {
+				return g, true, nil
+			}
+		}
+		for _, id := range pending {
+			loaded[id] = true
+		}
+		var next []string
+		for _, id := range s.frontier {
+			for _, n := range g.Neighbors(id) {
+				if !s.visited[n] {
+					s.visited[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		s.frontier = next
+		s.depth++
+	}
+	return g, false, nil
+}
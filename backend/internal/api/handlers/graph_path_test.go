@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// mockPathStore implements GraphPathStore over in-memory nodes and links.
+type mockPathStore struct {
+	nodes []db.GraphNodeSummary
+	links []db.GraphNeighbor
+}
+
+func (m *mockPathStore) ListGraphNodesByIDs(ctx context.Context, ids []string) ([]db.GraphNodeSummary, error) {
+	var out []db.GraphNodeSummary
+	for _, n := range m.nodes {
+		if containsString(ids, n.ID) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockPathStore) ListGraphNeighbors(ctx context.Context, arg db.ListGraphNeighborsParams) ([]db.GraphNeighbor, error) {
+	typeOf := map[string]string{}
+	for _, n := range m.nodes {
+		typeOf[n.ID] = n.Type
+	}
+	var out []db.GraphNeighbor
+	for _, l := range m.links {
+		var other string
+		switch {
+		case containsString(arg.IDs, l.Source):
+			other = l.Target
+		case containsString(arg.IDs, l.Target):
+			other = l.Source
+		default:
+			continue
+		}
+		if len(arg.NodeTypes) > 0 && !containsString(arg.NodeTypes, typeOf[other]) && !containsString(arg.Endpoints, other) {
+			continue
+		}
+		if l.Weight < arg.MinWeight || (len(arg.LinkTypes) > 0 && !containsString(arg.LinkTypes, l.LinkType)) {
+			continue
+		}
+		l.OtherType = typeOf[other]
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func newMockPathStore() *mockPathStore {
+	// golang and rust share a user, and are linked through the programming hub
+	return &mockPathStore{
+		nodes: []db.GraphNodeSummary{
+			{ID: "subreddit_1", Name: "golang", Val: "500", Type: "subreddit"},
+			{ID: "subreddit_2", Name: "rust", Val: "400", Type: "subreddit"},
+			{ID: "subreddit_3", Name: "programming", Val: "900", Type: "subreddit"},
+			{ID: "user_1", Name: "gopher", Val: "12", Type: "user"},
+		},
+		links: []db.GraphNeighbor{
+			{Source: "user_1", Target: "subreddit_1", Weight: 5, LinkType: "user_activity"},
+			{Source: "user_1", Target: "subreddit_2", Weight: 3, LinkType: "user_activity"},
+			{Source: "subreddit_1", Target: "subreddit_3", Weight: 40, LinkType: "subreddit_overlap"},
+			{Source: "subreddit_3", Target: "subreddit_2", Weight: 30, LinkType: "subreddit_overlap"},
+		},
+	}
+}
+
+func getGraphPath(t *testing.T, m *mockPathStore, query string) (*httptest.ResponseRecorder, GraphPathResponse) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	GetGraphPath(m).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/graph/path?"+query, nil))
+	var resp GraphPathResponse
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rr, resp
+}
+
+func TestGetGraphPath(t *testing.T) {
+	m := newMockPathStore()
+	rr, resp := getGraphPath(t, m, "from=subreddit_1&to=subreddit_2&k=2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if len(resp.Paths) != 2 {
+		t.Fatalf("got %d paths, want 2", len(resp.Paths))
+	}
+	// Both take two hops; the tie goes to the smaller node IDs
+	p := resp.Paths[0]
+	if p.Hops != 2 || p.Nodes[1].ID != "subreddit_3" || p.Nodes[1].Name != "programming" || p.Nodes[1].Val != 900 {
+		t.Fatalf("unexpected first path %+v", p)
+	}
+	if l := p.Links[1]; l.Source != "subreddit_3" || l.Target != "subreddit_2" || l.Weight != 30 || l.Type != "subreddit_overlap" {
+		t.Errorf("unexpected link %+v", l)
+	}
+	if resp.Paths[1].Nodes[1].ID != "user_1" {
+		t.Errorf("second path = %+v", resp.Paths[1].Nodes)
+	}
+
+	// Subreddit-only paths skip the user
+	_, resp = getGraphPath(t, m, "from=subreddit_1&to=subreddit_2&k=3&types=subreddit")
+	if len(resp.Paths) != 1 || resp.Paths[0].Nodes[1].ID != "subreddit_3" {
+		t.Fatalf("subreddit paths = %+v", resp.Paths)
+	}
+
+	// Link filters apply to every hop
+	_, resp = getGraphPath(t, m, "from=subreddit_1&to=subreddit_2&link_types=user_activity")
+	if len(resp.Paths) != 1 || resp.Paths[0].Nodes[1].ID != "user_1" {
+		t.Fatalf("filtered paths = %+v", resp.Paths)
+	}
+
+	// Weighted paths cost 1/weight per link
+	_, resp = getGraphPath(t, m, "from=subreddit_1&to=subreddit_2&k=1&weighted=true")
+	if len(resp.Paths) != 1 || math.Abs(resp.Paths[0].Cost-(1.0/40+1.0/30)) > 1e-9 {
+		t.Fatalf("weighted paths = %+v", resp.Paths)
+	}
+
+	// One hop is not enough
+	_, resp = getGraphPath(t, m, "from=subreddit_1&to=subreddit_2&max_hops=1")
+	if len(resp.Paths) != 0 {
+		t.Fatalf("expected no path within one hop, got %+v", resp.Paths)
+	}
+}
+
+func TestGetGraphPath_Errors(t *testing.T) {
+	for query, want := range map[string]int{
+		"from=subreddit_1":                               http.StatusBadRequest,
+		"from=subreddit_1&to=subreddit_1":                http.StatusBadRequest,
+		"from=subreddit_1&to=subreddit_2&k=0":            http.StatusBadRequest,
+		"from=subreddit_1&to=subreddit_2&max_hops=20":    http.StatusBadRequest,
+		"from=subreddit_1&to=subreddit_2&link_types=x":   http.StatusBadRequest,
+		"from=subreddit_1&to=subreddit_99":               http.StatusNotFound,
+		"from=subreddit_1&to=subreddit_2&max_hops=2&k=1": http.StatusOK,
+	} {
+		if rr, _ := getGraphPath(t, newMockPathStore(), query); rr.Code != want {
+			t.Errorf("%s: status %d, want %d", query, rr.Code, want)
+		}
+	}
+}
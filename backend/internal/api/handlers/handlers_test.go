@@ -17,8 +17,10 @@ import (
 type queriesAdapter struct{ f *fakeMemory }
 
 type fakeMemory struct {
-	subs []db.Subreddit
-	jobs []db.EnqueueCrawlJobParams
+	subs  []db.Subreddit
+	jobs  []db.EnqueueCrawlJobParams
+	typed []db.EnqueueTypedCrawlJobParams
+	seeds []string
 }
 
 func (qa *queriesAdapter) ListSubreddits(ctx context.Context, p db.ListSubredditsParams) ([]db.Subreddit, error) {
@@ -42,6 +44,20 @@ func (qa *queriesAdapter) EnqueueCrawlJob(ctx context.Context, p db.EnqueueCrawl
 	qa.f.jobs = append(qa.f.jobs, p)
 	return nil
 }
+func (qa *queriesAdapter) EnqueueTypedCrawlJob(ctx context.Context, p db.EnqueueTypedCrawlJobParams) (int32, error) {
+	for _, j := range qa.f.typed {
+		if j.JobType == p.JobType && j.DedupeKey == p.DedupeKey {
+			return 0, sql.ErrNoRows
+		}
+	}
+	qa.f.typed = append(qa.f.typed, p)
+	return int32(len(qa.f.typed)), nil
+}
+
+func (qa *queriesAdapter) MarkSubredditSeeds(ctx context.Context, names []string) error {
+	qa.f.seeds = append(qa.f.seeds, names...)
+	return nil
+}
 
 func TestGetSubreddits_Pagination(t *testing.T) {
 	qa := &queriesAdapter{f: &fakeMemory{subs: []db.Subreddit{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}}}
@@ -73,6 +89,64 @@ func TestPostCrawl_EnqueuesJob(t *testing.T) {
 	if len(qa.f.jobs) != 1 {
 		t.Fatalf("expected 1 job enqueued, got %d", len(qa.f.jobs))
 	}
+	if len(qa.f.seeds) != 1 || qa.f.seeds[0] != "golang" {
+		t.Fatalf("expected golang to be marked as a seed, got %v", qa.f.seeds)
+	}
+}
+
+func TestPostCrawl_EnqueuesTypedJob(t *testing.T) {
+	qa := &queriesAdapter{f: &fakeMemory{}}
+	rr := httptest.NewRecorder()
+	body := bytes.NewBufferString(`{"type":"post","url":"https://www.reddit.com/r/golang/comments/Abc123/title/"}`)
+	req := httptest.NewRequest(http.MethodPost, "/crawl", body)
+	req.Header.Set("Content-Type", "application/json")
+	PostCrawl(qa)(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(qa.f.jobs) != 0 || len(qa.f.typed) != 1 {
+		t.Fatalf("expected 1 typed job only, got %d subreddit and %d typed", len(qa.f.jobs), len(qa.f.typed))
+	}
+	job := qa.f.typed[0]
+	if job.JobType != "post" || job.DedupeKey.String != "abc123" || string(job.Payload) != `{"post_id":"abc123"}` {
+		t.Fatalf("unexpected job: type=%q key=%q payload=%s", job.JobType, job.DedupeKey.String, job.Payload)
+	}
+}
+
+func TestPostCrawl_TypedJobAlreadyQueued(t *testing.T) {
+	qa := &queriesAdapter{f: &fakeMemory{}}
+	for i, want := range []int{http.StatusAccepted, http.StatusConflict} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/crawl", bytes.NewBufferString(`{"type":"user","username":"Spez"}`))
+		req.Header.Set("Content-Type", "application/json")
+		PostCrawl(qa)(rr, req)
+		if rr.Code != want {
+			t.Fatalf("request %d: expected %d, got %d: %s", i+1, want, rr.Code, rr.Body.String())
+		}
+	}
+	if len(qa.f.typed) != 1 {
+		t.Fatalf("expected the duplicate to be dropped, got %d typed jobs", len(qa.f.typed))
+	}
+}
+
+func TestPostCrawl_RejectsInvalidTypedJob(t *testing.T) {
+	for _, body := range []string{
+		`{"type":"comment"}`,
+		`{"type":"user","username":"x"}`,
+		`{"type":"search","query":"  "}`,
+	} {
+		qa := &queriesAdapter{f: &fakeMemory{}}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/crawl", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		PostCrawl(qa)(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rr.Code)
+		}
+		if len(qa.f.typed) != 0 {
+			t.Errorf("%s: expected nothing enqueued", body)
+		}
+	}
 }
 
 type fakeGraphQueries struct{ data [][]byte }
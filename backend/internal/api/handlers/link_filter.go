@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// linkFilter restricts the links of a graph response by weight and link type,
+// from the min_weight and link_types query parameters, and to the backbone with
+// backbone=true.
+type linkFilter struct {
+	MinWeight float64
+	Types     []string // sorted; empty allows every type
+	// Backbone mode keeps links whose backbone p-value is at most MaxPValue;
+	// untested links always pass. 0 disables it.
+	MaxPValue float64
+}
+
+// parseLinkFilter reads min_weight and link_types (comma separated), and backbone
+// with its alpha (default BACKBONE_ALPHA). Unknown link types, negative or
+// non-finite weights and alphas outside (0, 1] are rejected.
+func parseLinkFilter(q url.Values) (linkFilter, error) {
+	var f linkFilter
+	if v := strings.TrimSpace(q.Get("min_weight")); v != "" {
+		w, err := strconv.ParseFloat(v, 64)
+		if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return f, fmt.Errorf("min_weight must be a non-negative number")
+		}
+		f.MinWeight = w
+	}
+	seen := map[string]bool{}
+	for _, p := range strings.Split(q.Get("link_types"), ",") {
+		t := strings.ToLower(strings.TrimSpace(p))
+		if t == "" || seen[t] {
+			continue
+		}
+		if !db.IsLinkType(t) {
+			return f, fmt.Errorf("unknown link type %q (valid: %s)", t, strings.Join(db.LinkTypes, ", "))
+		}
+		seen[t] = true
+		f.Types = append(f.Types, t)
+	}
+	sort.Strings(f.Types)
+
+	if v := strings.TrimSpace(q.Get("backbone")); v == "1" || strings.EqualFold(v, "true") {
+		f.MaxPValue = config.Load().BackboneAlpha
+		if a := strings.TrimSpace(q.Get("alpha")); a != "" {
+			p, err := strconv.ParseFloat(a, 64)
+			if err != nil || !(p > 0 && p <= 1) {
+				return f, fmt.Errorf("alpha must be in (0, 1]")
+			}
+			f.MaxPValue = p
+		}
+		if !(f.MaxPValue > 0 && f.MaxPValue <= 1) {
+			f.MaxPValue = 0.05
+		}
+	}
+	return f, nil
+}
+
+// key is the cache key suffix of the filter, "" when it allows every link.
+func (f linkFilter) key() string {
+	if f.MinWeight <= 0 && len(f.Types) == 0 && f.MaxPValue <= 0 {
+		return ""
+	}
+	k := ":w" + strconv.FormatFloat(f.MinWeight, 'g', -1, 64) + ":lt" + strings.Join(f.Types, ",")
+	if f.MaxPValue > 0 {
+		k += ":bb" + strconv.FormatFloat(f.MaxPValue, 'g', -1, 64)
+	}
+	return k
+}
+
+// allows reports whether a link passes the filter. The database applies the same
+// filter; this covers links that do not come from graph_links, which have no
+// backbone p-values.
+func (f linkFilter) allows(l GraphLink) bool {
+	if l.Weight < f.MinWeight {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	i := sort.SearchStrings(f.Types, l.Type)
+	return i < len(f.Types) && f.Types[i] == l.Type
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/cache"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestParseLinkFilter(t *testing.T) {
+	t.Setenv("BACKBONE_ALPHA", "")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	tests := []struct {
+		query   string
+		want    linkFilter
+		key     string
+		wantErr bool
+	}{
+		{query: "", want: linkFilter{}, key: ""},
+		{query: "min_weight=2.5", want: linkFilter{MinWeight: 2.5}, key: ":w2.5:lt"},
+		{query: "link_types=Reply,mention,reply", want: linkFilter{Types: []string{"mention", "reply"}}, key: ":w0:ltmention,reply"},
+		{query: "link_types=follows", wantErr: true},
+		{query: "min_weight=-1", wantErr: true},
+		{query: "min_weight=NaN", wantErr: true},
+		{query: "backbone=true", want: linkFilter{MaxPValue: 0.05}, key: ":w0:lt:bb0.05"},
+		{query: "backbone=1&alpha=0.01&min_weight=1", want: linkFilter{MinWeight: 1, MaxPValue: 0.01}, key: ":w1:lt:bb0.01"},
+		{query: "alpha=0.01", want: linkFilter{}, key: ""},
+		{query: "backbone=true&alpha=0", wantErr: true},
+		{query: "backbone=true&alpha=1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := parseLinkFilter(q)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) || got.key() != tt.key {
+			t.Errorf("%q: got %+v (key %q, err %v), want %+v (key %q)", tt.query, got, got.key(), err, tt.want, tt.key)
+		}
+	}
+}
+
+// mockWeightedGraphReader returns typed links and records the link filter it was queried with.
+type mockWeightedGraphReader struct {
+	MockGraphDataReader
+	capped    db.GetPrecalculatedGraphDataCappedAllParams
+	region    db.GetLinksForNodesInBoundingBoxParams
+	paginated db.GetLinksForPaginatedNodesParams
+}
+
+func (m *mockWeightedGraphReader) GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg db.GetPrecalculatedGraphDataCappedAllParams) ([]db.GetPrecalculatedGraphDataCappedAllRow, error) {
+	m.capped = arg
+	return []db.GetPrecalculatedGraphDataCappedAllRow{
+		{DataType: "node", ID: "subreddit_1", Name: "golang", Val: "10", Type: sql.NullString{String: "subreddit", Valid: true}},
+		{DataType: "node", ID: "subreddit_2", Name: "rust", Val: "8", Type: sql.NullString{String: "subreddit", Valid: true}},
+		{DataType: "link", Source: "subreddit_1", Target: "subreddit_2", Weight: sql.NullFloat64{Float64: 3, Valid: true}, LinkType: sql.NullString{String: db.LinkTypeMention, Valid: true}},
+	}, nil
+}
+
+func (m *mockWeightedGraphReader) GetLinksForNodesInBoundingBox(ctx context.Context, arg db.GetLinksForNodesInBoundingBoxParams) ([]db.GetLinksForNodesInBoundingBoxRow, error) {
+	m.region = arg
+	return []db.GetLinksForNodesInBoundingBoxRow{{ID: 1, Source: "user_1", Target: "subreddit_1", Weight: 7, LinkType: db.LinkTypeUserActivity}}, nil
+}
+
+func (m *mockWeightedGraphReader) GetPaginatedGraphNodes(ctx context.Context, arg db.GetPaginatedGraphNodesParams) ([]db.GetPaginatedGraphNodesRow, error) {
+	return []db.GetPaginatedGraphNodesRow{{ID: "user_1", Name: "alice"}, {ID: "subreddit_1", Name: "golang"}}, nil
+}
+
+func (m *mockWeightedGraphReader) GetLinksForPaginatedNodes(ctx context.Context, arg db.GetLinksForPaginatedNodesParams) ([]db.GetLinksForPaginatedNodesRow, error) {
+	m.paginated = arg
+	return []db.GetLinksForPaginatedNodesRow{{ID: 1, Source: "user_1", Target: "subreddit_1", Weight: 7, LinkType: db.LinkTypeUserActivity}}, nil
+}
+
+func TestGetGraphData_LinkFilter(t *testing.T) {
+	mock := &mockWeightedGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+
+	rr := httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?min_weight=2&link_types=mention,subreddit_overlap", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if mock.capped.Column3 != 2 || !reflect.DeepEqual(mock.capped.Column4, []string{"mention", "subreddit_overlap"}) {
+		t.Errorf("link filter not passed to the query: %+v", mock.capped)
+	}
+	var resp GraphResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Links) != 1 || resp.Links[0].Weight != 3 || resp.Links[0].Type != db.LinkTypeMention {
+		t.Errorf("unexpected links %+v", resp.Links)
+	}
+
+	rr = httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?link_types=follows", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown link type: status %d, want 400", rr.Code)
+	}
+}
+
+func TestGetGraphData_LinkFilterCacheKey(t *testing.T) {
+	mock := &mockWeightedGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+
+	h.GetGraphData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph", nil))
+	h.GetGraphData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph?min_weight=5", nil))
+	if mock.capped.Column3 != 5 {
+		t.Errorf("filtered request was served from the unfiltered cache entry")
+	}
+}
+
+func TestGetGraphRegion_LinkFilter(t *testing.T) {
+	mock := &mockWeightedGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+
+	rr := httptest.NewRecorder()
+	h.GetGraphRegion(rr, httptest.NewRequest(http.MethodGet, "/api/graph/region?x_min=0&x_max=1&y_min=0&y_max=1&z_min=0&z_max=1&min_weight=4&link_types=user_activity", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if mock.region.Column8 != 4 || !reflect.DeepEqual(mock.region.Column9, []string{"user_activity"}) {
+		t.Errorf("link filter not passed to the query: %+v", mock.region)
+	}
+	var resp GraphResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Links) != 1 || resp.Links[0].Weight != 7 || resp.Links[0].Type != db.LinkTypeUserActivity {
+		t.Errorf("unexpected links %+v", resp.Links)
+	}
+}
+
+func TestGetGraphData_PaginatedLinkFilter(t *testing.T) {
+	mock := &mockWeightedGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+
+	rr := httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?page_size=10&min_weight=1.5&link_types=user_activity", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if mock.paginated.Column3 != 1.5 || !reflect.DeepEqual(mock.paginated.Column4, []string{"user_activity"}) {
+		t.Errorf("link filter not passed to the query: %+v", mock.paginated)
+	}
+	var resp PaginatedGraphResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Links) != 1 || resp.Links[0].Type != db.LinkTypeUserActivity {
+		t.Errorf("unexpected links %+v", resp.Links)
+	}
+}
+
+func TestGetGraphData_Backbone(t *testing.T) {
+	t.Setenv("BACKBONE_ALPHA", "0.1")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	mock := &mockWeightedGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+	h.GetGraphData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph", nil))
+	if mock.capped.Column5 != 0 {
+		t.Errorf("backbone mode should be off by default, got alpha %v", mock.capped.Column5)
+	}
+	h.GetGraphData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph?backbone=true", nil))
+	if mock.capped.Column5 != 0.1 {
+		t.Errorf("expected the configured alpha, got %v", mock.capped.Column5)
+	}
+	h.GetGraphData(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph?backbone=true&alpha=0.01", nil))
+	if mock.capped.Column5 != 0.01 {
+		t.Errorf("expected alpha 0.01, got %v", mock.capped.Column5)
+	}
+
+	rr := httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?backbone=true&alpha=2", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid alpha: status %d, want 400", rr.Code)
+	}
+}
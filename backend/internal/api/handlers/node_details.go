@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
@@ -25,6 +26,22 @@ type NodeDetailsReader interface {
 	GetUser(ctx context.Context, username string) (db.User, error)
 }
 
+// SubredditHistoryReader is implemented by readers that track content history
+// (*db.Queries does). Node details of subreddits include it when available.
+type SubredditHistoryReader interface {
+	GetSubredditContentHistory(ctx context.Context, subredditID int32, since time.Time) (db.SubredditContentHistory, error)
+}
+
+// SubredditGrowthReader is implemented by readers that store subreddit metadata
+// snapshots (*db.Queries does). Node details of subreddits include growth rates
+// when available.
+type SubredditGrowthReader interface {
+	GetSubredditGrowth(ctx context.Context, subredditID int32, since time.Time) (db.SubredditGrowth, bool, error)
+}
+
+// scoreTrendDays is how far back the score trend of a subreddit reaches.
+const scoreTrendDays = 30
+
 // NodeDetailResponse represents the detailed information about a node.
 type NodeDetailResponse struct {
 	ID        string                `json:"id"`
@@ -37,6 +54,16 @@ type NodeDetailResponse struct {
 	Degree    int                   `json:"degree"`
 	Neighbors []NeighborInfo        `json:"neighbors"`
 	Stats     *NodeStats            `json:"stats,omitempty"`
+	// Centrality is computed during precalculation; absent until the first run
+	Centrality *NodeCentrality `json:"centrality,omitempty"`
+}
+
+// NodeCentrality is the structural importance of a node in the precalculated graph.
+type NodeCentrality struct {
+	PageRank       float64 `json:"pagerank"`
+	WeightedDegree float64 `json:"weighted_degree"`
+	Betweenness    float64 `json:"betweenness"` // approximate, normalized to [0, 1]
+	KCore          int32   `json:"kcore"`
 }
 
 // NeighborInfo represents information about a neighboring node.
@@ -54,11 +81,27 @@ type NodeStats struct {
 	Subscribers  *int32  `json:"subscribers,omitempty"`
 	Title        *string `json:"title,omitempty"`
 	Description  *string `json:"description,omitempty"`
+	// Content history observed by recrawls
+	RemovalRate  *float64          `json:"removal_rate,omitempty"`  // share of stored posts and comments removed by moderators or Reddit
+	DeletionRate *float64          `json:"deletion_rate,omitempty"` // share deleted by their authors
+	RecentEdits  *int64            `json:"recent_edits,omitempty"`  // edits observed in the trend window
+	ScoreTrend   []ScoreTrendPoint `json:"score_trend,omitempty"`   // daily average post score snapshots
+	// Subscriber growth from metadata snapshots
+	GrowthRate7d        *float64 `json:"growth_rate_7d,omitempty"` // relative subscriber change per 7 days, 0.1 for +10%
+	GrowthRate30d       *float64 `json:"growth_rate_30d,omitempty"`
+	SubscriberChange30d *int64   `json:"subscriber_change_30d,omitempty"`
 	
 	// User-specific fields (can be extended later)
 	// Currently we just have basic user info from the users table
 }
 
+// ScoreTrendPoint is the average score of a subreddit's post snapshots on one day.
+type ScoreTrendPoint struct {
+	Date      string  `json:"date"`
+	AvgScore  float64 `json:"avg_score"`
+	Snapshots int64   `json:"snapshots"`
+}
+
 // GetNodeDetails handles GET /api/nodes/{id} for detailed node information.
 func GetNodeDetails(q NodeDetailsReader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -150,6 +193,14 @@ func GetNodeDetails(q NodeDetailsReader) http.HandlerFunc {
 		if nodeDetails.PosZ.Valid {
 			response.PosZ = &nodeDetails.PosZ.Float64
 		}
+		if nodeDetails.Pagerank.Valid {
+			response.Centrality = &NodeCentrality{
+				PageRank:       nodeDetails.Pagerank.Float64,
+				WeightedDegree: nodeDetails.WeightedDegree.Float64,
+				Betweenness:    nodeDetails.Betweenness.Float64,
+				KCore:          nodeDetails.Kcore.Int32,
+			}
+		}
 
 		// Fetch type-specific stats
 		if nodeDetails.Type.Valid {
@@ -198,6 +249,17 @@ func fetchNodeStats(ctx context.Context, q NodeDetailsReader, nodeType, nodeID,
 		if sub.Description.Valid {
 			stats.Description = &sub.Description.String
 		}
+		if hr, ok := q.(SubredditHistoryReader); ok {
+			h, err := hr.GetSubredditContentHistory(ctx, sub.ID, time.Now().AddDate(0, 0, -scoreTrendDays))
+			if err != nil {
+				logger.WarnContext(ctx, "Failed to fetch subreddit content history", "error", err, "subreddit", nodeName)
+			} else {
+				addContentHistory(stats, h)
+			}
+		}
+		if gr, ok := q.(SubredditGrowthReader); ok {
+			addGrowth(ctx, gr, stats, sub.ID)
+		}
 		return stats, nil
 		
 	case "user":
@@ -209,3 +271,42 @@ func fetchNodeStats(ctx context.Context, q NodeDetailsReader, nodeType, nodeID,
 		return nil, nil
 	}
 }
+
+// addContentHistory adds a subreddit's removal rates and score trend to its stats.
+func addContentHistory(stats *NodeStats, h db.SubredditContentHistory) {
+	if total := h.Posts + h.Comments; total > 0 {
+		removal := float64(h.RemovedPosts+h.RemovedComments) / float64(total)
+		deletion := float64(h.DeletedPosts+h.DeletedComments) / float64(total)
+		stats.RemovalRate, stats.DeletionRate = &removal, &deletion
+	}
+	stats.RecentEdits = &h.Edits
+	for _, p := range h.ScoreTrend {
+		stats.ScoreTrend = append(stats.ScoreTrend, ScoreTrendPoint{
+			Date:      p.Day.Format("2006-01-02"),
+			AvgScore:  p.AvgScore,
+			Snapshots: p.Snapshots,
+		})
+	}
+}
+
+// addGrowth adds a subreddit's subscriber growth over the last 7 and 30 days. The
+// rates are scaled to the full 7 and 30 days when the snapshots span less.
+func addGrowth(ctx context.Context, gr SubredditGrowthReader, stats *NodeStats, subredditID int32) {
+	const week, month = 7 * 24 * time.Hour, 30 * 24 * time.Hour
+	now := time.Now()
+	if g, ok, err := gr.GetSubredditGrowth(ctx, subredditID, now.Add(-week)); err != nil {
+		logger.WarnContext(ctx, "Failed to fetch subreddit growth", "error", err, "subreddit_id", subredditID)
+		return
+	} else if rate, hasRate := g.RateOver(week); ok && hasRate {
+		stats.GrowthRate7d = &rate
+	}
+	if g, ok, err := gr.GetSubredditGrowth(ctx, subredditID, now.Add(-month)); err != nil {
+		logger.WarnContext(ctx, "Failed to fetch subreddit growth", "error", err, "subreddit_id", subredditID)
+	} else if ok {
+		change := g.Change()
+		stats.SubscriberChange30d = &change
+		if rate, hasRate := g.RateOver(month); hasRate {
+			stats.GrowthRate30d = &rate
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
@@ -239,3 +240,85 @@ func TestGetNodeDetails_DegreeAccuracy(t *testing.T) {
 		t.Errorf("expected degree 2 (len of neighbors), got %d", resp.Degree)
 	}
 }
+
+// mockHistoryReader adds subreddit content history to mockNodeDetailsReader.
+type mockHistoryReader struct {
+	mockNodeDetailsReader
+	history db.SubredditContentHistory
+	since   time.Time
+}
+
+func (m *mockHistoryReader) GetSubredditContentHistory(ctx context.Context, subredditID int32, since time.Time) (db.SubredditContentHistory, error) {
+	m.since = since
+	return m.history, nil
+}
+
+func TestGetNodeDetails_SubredditContentHistory(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock := &mockHistoryReader{
+		mockNodeDetailsReader: mockNodeDetailsReader{
+			nodeDetails: db.GetNodeDetailsRow{ID: "subreddit_1", Name: "golang", Type: sql.NullString{String: "subreddit", Valid: true}},
+			subreddit:   db.Subreddit{ID: 1, Name: "golang"},
+		},
+		history: db.SubredditContentHistory{
+			Posts: 40, Comments: 60, RemovedPosts: 2, RemovedComments: 3, DeletedComments: 10, Edits: 4,
+			ScoreTrend: []db.ScoreTrendPoint{{Day: day, AvgScore: 12.5, Snapshots: 8}},
+		},
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/nodes/subreddit_1", nil), map[string]string{"id": "subreddit_1"})
+	rr := httptest.NewRecorder()
+	GetNodeDetails(mock).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	var resp NodeDetailResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	s := resp.Stats
+	if s == nil || s.RemovalRate == nil || s.DeletionRate == nil || s.RecentEdits == nil {
+		t.Fatalf("expected content history in stats, got %+v", s)
+	}
+	if *s.RemovalRate != 0.05 || *s.DeletionRate != 0.1 || *s.RecentEdits != 4 {
+		t.Errorf("removal %v, deletion %v, edits %v", *s.RemovalRate, *s.DeletionRate, *s.RecentEdits)
+	}
+	if len(s.ScoreTrend) != 1 || s.ScoreTrend[0].Date != "2024-05-01" || s.ScoreTrend[0].AvgScore != 12.5 {
+		t.Errorf("unexpected score trend %+v", s.ScoreTrend)
+	}
+	if age := time.Since(mock.since); age < 29*24*time.Hour || age > 31*24*time.Hour {
+		t.Errorf("trend window starts %v ago, want 30 days", age)
+	}
+}
+
+func TestGetNodeDetails_Centrality(t *testing.T) {
+	get := func(row db.GetNodeDetailsRow) NodeDetailResponse {
+		t.Helper()
+		mock := &mockNodeDetailsReader{nodeDetails: row}
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/nodes/user_1", nil), map[string]string{"id": "user_1"})
+		rr := httptest.NewRecorder()
+		GetNodeDetails(mock).ServeHTTP(rr, req)
+		var resp NodeDetailResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("status %d: %v", rr.Code, err)
+		}
+		return resp
+	}
+
+	resp := get(db.GetNodeDetailsRow{
+		ID: "user_1", Name: "alice",
+		Pagerank:       sql.NullFloat64{Float64: 0.02, Valid: true},
+		WeightedDegree: sql.NullFloat64{Float64: 12, Valid: true},
+		Betweenness:    sql.NullFloat64{Float64: 0.25, Valid: true},
+		Kcore:          sql.NullInt32{Int32: 3, Valid: true},
+	})
+	want := NodeCentrality{PageRank: 0.02, WeightedDegree: 12, Betweenness: 0.25, KCore: 3}
+	if resp.Centrality == nil || *resp.Centrality != want {
+		t.Errorf("centrality = %+v, want %+v", resp.Centrality, want)
+	}
+
+	// Not computed yet
+	if resp := get(db.GetNodeDetailsRow{ID: "user_1", Name: "alice"}); resp.Centrality != nil {
+		t.Errorf("expected no centrality, got %+v", resp.Centrality)
+	}
+}
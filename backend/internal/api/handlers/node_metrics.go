@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Node measures usable as sort_by and size_by in /api/graph: the activity
+// count val, and the centrality computed during precalculation.
+var nodeMetrics = []string{"val", "pagerank", "weighted_degree", "betweenness", "kcore"}
+
+// nodeOptions selects the order in which nodes are kept under max_nodes and
+// the measure returned as each node's size. "" means val.
+type nodeOptions struct {
+	SortBy string
+	SizeBy string
+}
+
+// parseNodeOptions reads sort_by and size_by, rejecting unknown measures.
+func parseNodeOptions(q url.Values) (nodeOptions, error) {
+	var o nodeOptions
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"sort_by", &o.SortBy}, {"size_by", &o.SizeBy}} {
+		v := strings.ToLower(strings.TrimSpace(q.Get(p.name)))
+		if v == "" || v == "val" {
+			continue
+		}
+		if !isNodeMetric(v) {
+			return o, fmt.Errorf("unknown %s %q (valid: %s)", p.name, v, strings.Join(nodeMetrics, ", "))
+		}
+		*p.dst = v
+	}
+	return o, nil
+}
+
+func isNodeMetric(v string) bool {
+	for _, m := range nodeMetrics {
+		if m == v {
+			return true
+		}
+	}
+	return false
+}
+
+// key is the cache key suffix of the options, "" for the defaults.
+func (o nodeOptions) key() string {
+	if o.SortBy == "" && o.SizeBy == "" {
+		return ""
+	}
+	return ":sort" + o.SortBy + ":size" + o.SizeBy
+}
+
+// nodeMetric returns a centrality measure of a node row, and false when it has
+// not been computed yet.
+func nodeMetric(r preRow, metric string) (float64, bool) {
+	switch metric {
+	case "pagerank":
+		return r.Pagerank.Float64, r.Pagerank.Valid
+	case "weighted_degree":
+		return r.WeightedDegree.Float64, r.WeightedDegree.Valid
+	case "betweenness":
+		return r.Betweenness.Float64, r.Betweenness.Valid
+	case "kcore":
+		return float64(r.Kcore.Int32), r.Kcore.Valid
+	}
+	return 0, false
+}
+
+// apply sets the selection rank and the size of a node built from r.
+// Nodes whose measure is missing rank last.
+func (o nodeOptions) apply(gn *GraphNode, r preRow) {
+	if o.SortBy != "" {
+		gn.rank = -1
+		if v, ok := nodeMetric(r, o.SortBy); ok {
+			gn.rank = v
+		}
+	}
+	if o.SizeBy != "" {
+		if v, ok := nodeMetric(r, o.SizeBy); ok {
+			gn.Size = &v
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/cache"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestParseNodeOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    nodeOptions
+		key     string
+		wantErr bool
+	}{
+		{query: "", want: nodeOptions{}, key: ""},
+		{query: "sort_by=val&size_by=VAL", want: nodeOptions{}, key: ""},
+		{query: "sort_by=PageRank", want: nodeOptions{SortBy: "pagerank"}, key: ":sortpagerank:size"},
+		{query: "sort_by=kcore&size_by=betweenness", want: nodeOptions{SortBy: "kcore", SizeBy: "betweenness"}, key: ":sortkcore:sizebetweenness"},
+		{query: "size_by=weighted_degree", want: nodeOptions{SizeBy: "weighted_degree"}, key: ":sort:sizeweighted_degree"},
+		{query: "sort_by=closeness", wantErr: true},
+		{query: "size_by=degree", wantErr: true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := parseNodeOptions(q)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || got != tt.want || got.key() != tt.key {
+			t.Errorf("%q: got %+v (key %q, err %v), want %+v (key %q)", tt.query, got, got.key(), err, tt.want, tt.key)
+		}
+	}
+}
+
+// mockCentralityGraphReader returns nodes with centrality and records the order requested.
+type mockCentralityGraphReader struct {
+	MockGraphDataReader
+	capped db.GetPrecalculatedGraphDataCappedAllParams
+}
+
+func (m *mockCentralityGraphReader) GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg db.GetPrecalculatedGraphDataCappedAllParams) ([]db.GetPrecalculatedGraphDataCappedAllRow, error) {
+	m.capped = arg
+	node := func(id string, val string, pr float64, core int32) db.GetPrecalculatedGraphDataCappedAllRow {
+		return db.GetPrecalculatedGraphDataCappedAllRow{
+			DataType: "node", ID: id, Name: id, Val: val, Type: sql.NullString{String: "subreddit", Valid: true},
+			Pagerank: sql.NullFloat64{Float64: pr, Valid: true}, WeightedDegree: sql.NullFloat64{Float64: 2, Valid: true},
+			Betweenness: sql.NullFloat64{Valid: true}, Kcore: sql.NullInt32{Int32: core, Valid: true},
+		}
+	}
+	return []db.GetPrecalculatedGraphDataCappedAllRow{
+		node("subreddit_1", "100", 0.1, 1),
+		node("subreddit_2", "5", 0.6, 3),
+		node("subreddit_3", "50", 0.3, 2),
+		{DataType: "node", ID: "subreddit_4", Name: "new", Val: "80", Type: sql.NullString{String: "subreddit", Valid: true}},
+	}, nil
+}
+
+func TestGetGraphData_SortAndSizeByCentrality(t *testing.T) {
+	mock := &mockCentralityGraphReader{}
+	h := NewHandler(mock, cache.NewMockCache())
+
+	rr := httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?sort_by=pagerank&size_by=kcore&max_nodes=3", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if mock.capped.Column6 != "pagerank" {
+		t.Errorf("sort order not passed to the query: %+v", mock.capped)
+	}
+	var resp GraphResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, n := range resp.Nodes {
+		ids = append(ids, n.ID)
+	}
+	if len(ids) != 3 || ids[0] != "subreddit_2" || ids[1] != "subreddit_3" || ids[2] != "subreddit_1" {
+		t.Fatalf("nodes not kept by pagerank: %v", ids)
+	}
+	if s := resp.Nodes[0].Size; s == nil || *s != 3 {
+		t.Errorf("size_by=kcore: got size %v", s)
+	}
+
+	// By default the heaviest nodes are kept and no size is returned
+	rr = httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?max_nodes=3", nil))
+	resp = GraphResponse{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if mock.capped.Column6 != "" || len(resp.Nodes) != 3 || resp.Nodes[0].ID != "subreddit_1" || resp.Nodes[1].ID != "subreddit_4" || resp.Nodes[0].Size != nil {
+		t.Errorf("unexpected default response %+v", resp.Nodes)
+	}
+
+	rr = httptest.NewRecorder()
+	h.GetGraphData(rr, httptest.NewRequest(http.MethodGet, "/api/graph?size_by=eigenvector", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown size_by: status %d, want 400", rr.Code)
+	}
+}
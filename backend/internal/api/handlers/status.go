@@ -10,18 +10,24 @@ import (
 
 type queueItem struct {
 	ID            int32  `json:"id"`
+	JobType       string `json:"job_type"`
 	SubredditID   int32  `json:"subreddit_id"`
 	SubredditName string `json:"subreddit_name"`
-	Status        string `json:"status"`
-	Priority      int32  `json:"priority"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
+	// Target identifies user, post and search jobs, which have no subreddit.
+	Target    string `json:"target,omitempty"`
+	Status    string `json:"status"`
+	Priority  int32  `json:"priority"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	// FailureReason is the reason of the previous failed attempt for requeued jobs.
+	FailureReason *string `json:"failure_reason,omitempty"`
 }
 
 func GetCrawlStatus(q *db.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const qstr = `SELECT cj.id, cj.subreddit_id, s.name AS subreddit_name, cj.status, cj.priority, cj.created_at::text, cj.updated_at::text
-                       FROM crawl_jobs cj JOIN subreddits s ON s.id = cj.subreddit_id
+		const qstr = `SELECT cj.id, cj.job_type, COALESCE(cj.subreddit_id, 0), COALESCE(s.name, '') AS subreddit_name, COALESCE(cj.dedupe_key, ''),
+                              cj.status, cj.priority, cj.created_at::text, cj.updated_at::text, cj.failure_reason
+                       FROM crawl_jobs cj LEFT JOIN subreddits s ON s.id = cj.subreddit_id
                        WHERE cj.status IN ('queued','crawling')
                        ORDER BY cj.priority DESC, cj.created_at ASC`
 		rows, err := q.DB().QueryContext(r.Context(), qstr)
@@ -33,10 +39,14 @@ func GetCrawlStatus(q *db.Queries) http.HandlerFunc {
 		var out []queueItem
 		for rows.Next() {
 			var it queueItem
-			if err := rows.Scan(&it.ID, &it.SubredditID, &it.SubredditName, &it.Status, &it.Priority, &it.CreatedAt, &it.UpdatedAt); err != nil {
+			var reason sql.NullString
+			if err := rows.Scan(&it.ID, &it.JobType, &it.SubredditID, &it.SubredditName, &it.Target, &it.Status, &it.Priority, &it.CreatedAt, &it.UpdatedAt, &reason); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+			if reason.Valid {
+				it.FailureReason = &reason.String
+			}
 			out = append(out, it)
 		}
 		if err := rows.Err(); err != nil {
@@ -44,23 +54,41 @@ func GetCrawlStatus(q *db.Queries) http.HandlerFunc {
 			return
 		}
 		// Summary counts across all jobs
-		var qd, cr, su, fa sql.NullInt64
+		var qd, cr, su, fa, te sql.NullInt64
 		const csql = `SELECT
                         COALESCE(SUM(CASE WHEN status='queued' THEN 1 ELSE 0 END),0),
                         COALESCE(SUM(CASE WHEN status='crawling' THEN 1 ELSE 0 END),0),
                         COALESCE(SUM(CASE WHEN status='success' THEN 1 ELSE 0 END),0),
-                        COALESCE(SUM(CASE WHEN status='failed' THEN 1 ELSE 0 END),0)
+                        COALESCE(SUM(CASE WHEN status='failed' THEN 1 ELSE 0 END),0),
+                        COALESCE(SUM(CASE WHEN status='terminal' THEN 1 ELSE 0 END),0)
                     FROM crawl_jobs`
-		_ = q.DB().QueryRowContext(r.Context(), csql).Scan(&qd, &cr, &su, &fa)
+		_ = q.DB().QueryRowContext(r.Context(), csql).Scan(&qd, &cr, &su, &fa, &te)
 
 		counts := map[string]int64{
 			"queued":   qd.Int64,
 			"crawling": cr.Int64,
 			"success":  su.Int64,
 			"failed":   fa.Int64,
+			"terminal": te.Int64,
+		}
+
+		// Breakdown of failed and terminal jobs by their recorded failure reason
+		failureReasons := map[string]int64{}
+		const fsql = `SELECT failure_reason, COUNT(*) FROM crawl_jobs
+                      WHERE status IN ('failed','terminal') AND failure_reason IS NOT NULL
+                      GROUP BY failure_reason`
+		if frows, err := q.DB().QueryContext(r.Context(), fsql); err == nil {
+			for frows.Next() {
+				var reason string
+				var n int64
+				if err := frows.Scan(&reason, &n); err == nil {
+					failureReasons[reason] = n
+				}
+			}
+			frows.Close()
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"queue": out, "counts": counts})
+		_ = json.NewEncoder(w).Encode(map[string]any{"queue": out, "counts": counts, "failure_reasons": failureReasons})
 	}
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/apierr"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
+)
+
+// SubredditHistoryStore abstracts the subreddit snapshot queries for testability.
+type SubredditHistoryStore interface {
+	GetSubreddit(ctx context.Context, name string) (db.Subreddit, error)
+	ListSubredditSnapshots(ctx context.Context, subredditID int32, since time.Time) ([]db.SubredditSnapshot, error)
+	GetSubredditGrowth(ctx context.Context, subredditID int32, since time.Time) (db.SubredditGrowth, bool, error)
+}
+
+// GrowthRanker ranks subreddits by subscriber growth.
+type GrowthRanker interface {
+	ListFastestGrowingSubreddits(ctx context.Context, since time.Time, minSubscribers int32, limit int32) ([]db.SubredditGrowth, error)
+}
+
+// SubredditSnapshotPoint is one crawl's view of a subreddit.
+type SubredditSnapshotPoint struct {
+	ObservedAt    time.Time  `json:"observed_at"`
+	Subscribers   *int32     `json:"subscribers,omitempty"`
+	ActiveUsers   *int32     `json:"active_users,omitempty"`
+	Over18        bool       `json:"over18"`
+	SubredditType string     `json:"subreddit_type,omitempty"`
+	CreatedUTC    *time.Time `json:"created_utc,omitempty"`
+	Language      string     `json:"language,omitempty"`
+}
+
+// GrowthStats is the subscriber change of a subreddit over a window.
+type GrowthStats struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Start        int32     `json:"start"`
+	End          int32     `json:"end"`
+	Change       int64     `json:"change"`
+	Rate         float64   `json:"rate"` // relative change, 0.1 for +10%
+	ChangePerDay float64   `json:"change_per_day"`
+}
+
+// SubredditHistoryResponse is the body of GET /api/subreddits/{name}/history.
+type SubredditHistoryResponse struct {
+	Subreddit string                   `json:"subreddit"`
+	Days      int                      `json:"days"`
+	Snapshots []SubredditSnapshotPoint `json:"snapshots"`
+	Growth    *GrowthStats             `json:"growth,omitempty"`
+}
+
+// GrowingNode is a subreddit node in the fastest growing ranking.
+type GrowingNode struct {
+	ID          string  `json:"id"` // graph node ID
+	Name        string  `json:"name"`
+	Subscribers int32   `json:"subscribers"`
+	Change      int64   `json:"change"`
+	Rate        float64 `json:"rate"`
+}
+
+func newGrowthStats(g db.SubredditGrowth) *GrowthStats {
+	s := &GrowthStats{From: g.From, To: g.To, Start: g.Start, End: g.End, Change: g.Change(), Rate: g.Rate()}
+	if days := g.To.Sub(g.From).Hours() / 24; days > 0 {
+		s.ChangePerDay = float64(s.Change) / days
+	}
+	return s
+}
+
+// parseDays reads a window in days, bounded to [1, 3650].
+func parseDays(v string, def int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > 3650 {
+		return 3650
+	}
+	return n
+}
+
+// GetSubredditHistory handles GET /api/subreddits/{name}/history?days=90 with
+// the metadata snapshots of a subreddit and its subscriber growth.
+func GetSubredditHistory(q SubredditHistoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		name := mux.Vars(r)["name"]
+		days := parseDays(r.URL.Query().Get("days"), 90)
+		since := time.Now().AddDate(0, 0, -days)
+
+		sub, err := q.GetSubreddit(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			apierr.WriteErrorWithContext(w, r, apierr.ResourceNotFound("subreddit"))
+			return
+		} else if err != nil {
+			logger.ErrorContext(ctx, "Failed to fetch subreddit", "error", err, "subreddit", name)
+			apierr.WriteErrorWithContext(w, r, apierr.SystemDatabase("failed to fetch subreddit"))
+			return
+		}
+
+		snaps, err := q.ListSubredditSnapshots(ctx, sub.ID, since)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to fetch subreddit snapshots", "error", err, "subreddit", name)
+			apierr.WriteErrorWithContext(w, r, apierr.SystemDatabase("failed to fetch subreddit history"))
+			return
+		}
+		resp := SubredditHistoryResponse{Subreddit: sub.Name, Days: days, Snapshots: make([]SubredditSnapshotPoint, 0, len(snaps))}
+		for _, s := range snaps {
+			p := SubredditSnapshotPoint{ObservedAt: s.ObservedAt, Over18: s.Over18, SubredditType: s.SubredditType.String, Language: s.Language.String}
+			if s.Subscribers.Valid {
+				p.Subscribers = &s.Subscribers.Int32
+			}
+			if s.ActiveUsers.Valid {
+				p.ActiveUsers = &s.ActiveUsers.Int32
+			}
+			if s.CreatedUTC.Valid {
+				p.CreatedUTC = &s.CreatedUTC.Time
+			}
+			resp.Snapshots = append(resp.Snapshots, p)
+		}
+
+		if g, ok, err := q.GetSubredditGrowth(ctx, sub.ID, since); err != nil {
+			logger.WarnContext(ctx, "Failed to compute subreddit growth", "error", err, "subreddit", name)
+		} else if ok {
+			resp.Growth = newGrowthStats(g)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// GetFastestGrowing handles GET /api/graph/rankings/fastest-growing with the
+// subreddit nodes that gained the largest share of subscribers in the last
+// days (default 7). Rates are scaled from the span a subreddit was observed
+// for to the whole window. min_subscribers (default 100) leaves out tiny
+// subreddits, whose relative growth is noise.
+func GetFastestGrowing(q GrowthRanker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		days := parseDays(r.URL.Query().Get("days"), 7)
+		limit := parseIntDefault(r.URL.Query().Get("limit"), 20)
+		if limit <= 0 || limit > 500 {
+			limit = 20
+		}
+		minSubs := parseIntDefault(r.URL.Query().Get("min_subscribers"), 100)
+		if minSubs < 0 {
+			minSubs = 0
+		}
+
+		rows, err := q.ListFastestGrowingSubreddits(ctx, time.Now().AddDate(0, 0, -days), int32(minSubs), int32(limit))
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to rank subreddits by growth", "error", err)
+			apierr.WriteErrorWithContext(w, r, apierr.SystemDatabase("failed to rank subreddits"))
+			return
+		}
+		nodes := make([]GrowingNode, 0, len(rows))
+		for _, g := range rows {
+			rate, _ := g.RateOver(time.Duration(days) * 24 * time.Hour)
+			nodes = append(nodes, GrowingNode{
+				ID:          fmt.Sprintf("subreddit_%d", g.SubredditID),
+				Name:        g.Name,
+				Subscribers: g.End,
+				Change:      g.Change(),
+				Rate:        rate,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"days": days, "nodes": nodes})
+	}
+}
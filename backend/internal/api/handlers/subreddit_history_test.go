@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// mockSubredditHistory implements SubredditHistoryStore and GrowthRanker.
+type mockSubredditHistory struct {
+	mockNodeDetailsReader
+	snapshots []db.SubredditSnapshot
+	growth    db.SubredditGrowth
+	hasGrowth bool
+	ranking   []db.SubredditGrowth
+	since     time.Time
+	minSubs   int32
+	limit     int32
+}
+
+func (m *mockSubredditHistory) ListSubredditSnapshots(ctx context.Context, subredditID int32, since time.Time) ([]db.SubredditSnapshot, error) {
+	m.since = since
+	return m.snapshots, nil
+}
+
+func (m *mockSubredditHistory) GetSubredditGrowth(ctx context.Context, subredditID int32, since time.Time) (db.SubredditGrowth, bool, error) {
+	return m.growth, m.hasGrowth, nil
+}
+
+func (m *mockSubredditHistory) ListFastestGrowingSubreddits(ctx context.Context, since time.Time, minSubscribers int32, limit int32) ([]db.SubredditGrowth, error) {
+	m.since, m.minSubs, m.limit = since, minSubscribers, limit
+	return m.ranking, nil
+}
+
+func TestGetSubredditHistory(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(48 * time.Hour)
+	mock := &mockSubredditHistory{
+		mockNodeDetailsReader: mockNodeDetailsReader{subreddit: db.Subreddit{ID: 1, Name: "golang"}},
+		snapshots: []db.SubredditSnapshot{
+			{SubredditID: 1, ObservedAt: t0, Subscribers: sql.NullInt32{Int32: 1000, Valid: true}, SubredditType: sql.NullString{String: "public", Valid: true}},
+			{SubredditID: 1, ObservedAt: t1, Subscribers: sql.NullInt32{Int32: 1100, Valid: true}, ActiveUsers: sql.NullInt32{Int32: 40, Valid: true}},
+		},
+		growth:    db.SubredditGrowth{SubredditID: 1, Name: "golang", From: t0, To: t1, Start: 1000, End: 1100},
+		hasGrowth: true,
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/subreddits/golang/history?days=30", nil), map[string]string{"name": "golang"})
+	rr := httptest.NewRecorder()
+	GetSubredditHistory(mock).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	var resp SubredditHistoryResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Days != 30 || time.Since(mock.since) < 29*24*time.Hour {
+		t.Errorf("window: days %d, since %v", resp.Days, mock.since)
+	}
+	if len(resp.Snapshots) != 2 || *resp.Snapshots[1].ActiveUsers != 40 || resp.Snapshots[0].SubredditType != "public" {
+		t.Errorf("unexpected snapshots %+v", resp.Snapshots)
+	}
+	g := resp.Growth
+	if g == nil || g.Change != 100 || g.Rate != 0.1 || g.ChangePerDay != 50 {
+		t.Errorf("unexpected growth %+v", g)
+	}
+}
+
+func TestGetSubredditHistory_NotFound(t *testing.T) {
+	mock := &mockSubredditHistory{mockNodeDetailsReader: mockNodeDetailsReader{subredditErr: sql.ErrNoRows}}
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/subreddits/nope/history", nil), map[string]string{"name": "nope"})
+	rr := httptest.NewRecorder()
+	GetSubredditHistory(mock).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rr.Code)
+	}
+}
+
+func TestGetFastestGrowing(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock := &mockSubredditHistory{ranking: []db.SubredditGrowth{
+		// +50% in the 3.5 days observed is +100% over the 7 day window.
+		{SubredditID: 7, Name: "rising", From: t0, To: t0.Add(84 * time.Hour), Start: 200, End: 300},
+		{SubredditID: 3, Name: "steady", From: t0, To: t0.AddDate(0, 0, 7), Start: 1000, End: 1100},
+	}}
+	rr := httptest.NewRecorder()
+	GetFastestGrowing(mock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/graph/rankings/fastest-growing?limit=5&min_subscribers=50", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	var resp struct {
+		Days  int           `json:"days"`
+		Nodes []GrowingNode `json:"nodes"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if mock.limit != 5 || mock.minSubs != 50 || resp.Days != 7 {
+		t.Errorf("limit %d, min_subscribers %d, days %d", mock.limit, mock.minSubs, resp.Days)
+	}
+	if len(resp.Nodes) != 2 || resp.Nodes[0].ID != "subreddit_7" || resp.Nodes[0].Rate != 1 || resp.Nodes[0].Subscribers != 300 {
+		t.Errorf("unexpected ranking %+v", resp.Nodes)
+	}
+}
+
+func TestGetNodeDetails_SubredditGrowth(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		span      time.Duration
+		wantRates bool
+		want7d    float64
+		want30d   float64
+	}{
+		// +25% in 3.5 days is +50% per week and +214% per 30 days.
+		{name: "scaled to the window", span: 84 * time.Hour, wantRates: true, want7d: 0.5, want30d: 0.25 * 30 / 3.5},
+		{name: "span too short", span: 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockSubredditHistory{
+				mockNodeDetailsReader: mockNodeDetailsReader{
+					nodeDetails: db.GetNodeDetailsRow{ID: "subreddit_1", Name: "golang", Type: sql.NullString{String: "subreddit", Valid: true}},
+					subreddit:   db.Subreddit{ID: 1, Name: "golang"},
+				},
+				growth:    db.SubredditGrowth{From: t0, To: t0.Add(tt.span), Start: 400, End: 500},
+				hasGrowth: true,
+			}
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/nodes/subreddit_1", nil), map[string]string{"id": "subreddit_1"})
+			rr := httptest.NewRecorder()
+			GetNodeDetails(mock).ServeHTTP(rr, req)
+			var resp NodeDetailResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			s := resp.Stats
+			if s == nil || s.SubscriberChange30d == nil || *s.SubscriberChange30d != 100 {
+				t.Fatalf("expected a subscriber change of 100, got %+v", s)
+			}
+			if !tt.wantRates {
+				if s.GrowthRate7d != nil || s.GrowthRate30d != nil {
+					t.Errorf("rates extrapolated from %v: %v, %v", tt.span, s.GrowthRate7d, s.GrowthRate30d)
+				}
+				return
+			}
+			if s.GrowthRate7d == nil || s.GrowthRate30d == nil {
+				t.Fatalf("expected growth rates, got %+v", s)
+			}
+			if math.Abs(*s.GrowthRate7d-tt.want7d) > 1e-9 || math.Abs(*s.GrowthRate30d-tt.want30d) > 1e-9 {
+				t.Errorf("growth rates %v, %v; want %v, %v", *s.GrowthRate7d, *s.GrowthRate30d, tt.want7d, tt.want30d)
+			}
+		})
+	}
+}
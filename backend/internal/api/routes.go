@@ -11,12 +11,15 @@ import (
 	"github.com/onnwee/reddit-cluster-map/backend/internal/cache"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/graphstore"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func NewRouter(q *db.Queries) *mux.Router {
+// NewRouter builds the API router. Graph traversal queries are answered from
+// mem when it has a graph loaded; mem may be nil.
+func NewRouter(q *db.Queries, mem *graphstore.Store) *mux.Router {
 	// Create the root router. All routes below are relative to this router.
 	r := mux.NewRouter()
 
@@ -104,8 +107,11 @@ func NewRouter(q *db.Queries) *mux.Router {
 	// Crawl jobs list: GET /jobs?limit=&offset=
 	r.HandleFunc("/jobs", handlers.GetCrawlJobs(q)).Methods("GET")
 
+	// Neighbor, path, ego and region queries read the in-memory graph when loaded
+	graphReader := graphstore.NewReader(q, mem)
+
 	// Graph data for the frontend: GET /api/graph
-	graphHandler := handlers.NewHandler(q, graphCache)
+	graphHandler := handlers.NewHandler(graphReader, graphCache)
 	r.Handle("/api/graph", middleware.Gzip(middleware.ETag(http.HandlerFunc(graphHandler.GetGraphData)))).Methods("GET")
 
 	// Tiered graph endpoints for overview and drill-down
@@ -115,6 +121,12 @@ func NewRouter(q *db.Queries) *mux.Router {
 	// Edge bundles endpoint with gzip and ETag: GET /api/graph/bundles
 	r.Handle("/api/graph/bundles", middleware.Gzip(middleware.ETag(http.HandlerFunc(graphHandler.GetEdgeBundles)))).Methods("GET")
 
+	// K shortest paths between two nodes: GET /api/graph/path?from=&to=&k=&max_hops=
+	r.Handle("/api/graph/path", middleware.Gzip(http.HandlerFunc(handlers.GetGraphPath(graphReader)))).Methods("GET")
+
+	// Neighborhood of a node out to N hops: GET /api/graph/ego/{id}?depth=&max_nodes=
+	r.Handle("/api/graph/ego/{id}", middleware.Gzip(middleware.ETag(http.HandlerFunc(handlers.GetEgoNetwork(graphReader))))).Methods("GET")
+
 	// Graph versioning endpoints
 	versionHandler := handlers.NewVersionHandler(q, graphCache)
 	r.Handle("/api/graph/version", middleware.Gzip(http.HandlerFunc(versionHandler.GetCurrentVersion))).Methods("GET")
@@ -125,9 +137,14 @@ func NewRouter(q *db.Queries) *mux.Router {
 	r.Handle("/api/search", searchHandler).Methods("GET")
 
 	// Node details endpoint: GET /api/nodes/{id}
-	nodeDetailsHandler := middleware.Gzip(middleware.ETag(http.HandlerFunc(handlers.GetNodeDetails(q))))
+	nodeDetailsHandler := middleware.Gzip(middleware.ETag(http.HandlerFunc(handlers.GetNodeDetails(graphReader))))
 	r.Handle("/api/nodes/{id}", nodeDetailsHandler).Methods("GET")
 
+	// Subreddit metadata snapshots and subscriber growth: GET /api/subreddits/{name}/history?days=
+	r.Handle("/api/subreddits/{name}/history", middleware.Gzip(http.HandlerFunc(handlers.GetSubredditHistory(q)))).Methods("GET")
+	// Subreddit nodes ranked by subscriber growth: GET /api/graph/rankings/fastest-growing?days=&limit=
+	r.Handle("/api/graph/rankings/fastest-growing", middleware.Gzip(http.HandlerFunc(handlers.GetFastestGrowing(q)))).Methods("GET")
+
 	// Export endpoint with gzip and ETag: GET /api/export?format=json|csv
 	exportHandler := middleware.Gzip(middleware.ETag(http.HandlerFunc(handlers.ExportGraph(q))))
 	r.Handle("/api/export", exportHandler).Methods("GET")
@@ -136,6 +153,8 @@ func NewRouter(q *db.Queries) *mux.Router {
 	communityHandler := handlers.NewCommunityHandler(q, graphCache)
 	r.Handle("/api/communities", middleware.Gzip(middleware.ETag(http.HandlerFunc(communityHandler.GetCommunities)))).Methods("GET")
 	r.Handle("/api/communities/{id}", middleware.Gzip(middleware.ETag(http.HandlerFunc(communityHandler.GetCommunityByID)))).Methods("GET")
+	// Community lineage across precalc runs: GET /api/communities/{id}/history
+	r.Handle("/api/communities/{id}/history", middleware.Gzip(http.HandlerFunc(handlers.GetCommunityHistory(q)))).Methods("GET")
 
 	// Alias for drill-down - same as /api/communities/{id} but matches tiered API convention
 	r.Handle("/api/graph/community/{id}", middleware.Gzip(middleware.ETag(http.HandlerFunc(communityHandler.GetCommunityByID)))).Methods("GET")
@@ -170,6 +189,15 @@ func NewRouter(q *db.Queries) *mux.Router {
 	// Services endpoints gated by adminOnly
 	r.Handle("/api/admin/services", adminOnly(http.HandlerFunc(admin.GetServices))).Methods("GET")
 	r.Handle("/api/admin/services", adminOnly(http.HandlerFunc(admin.UpdateServices))).Methods("POST")
+	// Reddit circuit breaker states reported by crawler processes
+	r.Handle("/api/admin/crawler/breakers", adminOnly(http.HandlerFunc(admin.GetCrawlerBreakers))).Methods("GET")
+	// Pool of Reddit OAuth credentials used by crawlers
+	r.Handle("/api/admin/crawler/credentials", adminOnly(http.HandlerFunc(admin.ListCredentials))).Methods("GET")
+	r.Handle("/api/admin/crawler/credentials", adminOnly(http.HandlerFunc(admin.AddCredential))).Methods("POST")
+	r.Handle("/api/admin/crawler/credentials/{id}", adminOnly(http.HandlerFunc(admin.UpdateCredential))).Methods("PUT")
+	// Per-subreddit listing plans (which sorts/time filters the crawler follows)
+	r.Handle("/api/admin/subreddits/{name}/listing-plan", adminOnly(http.HandlerFunc(admin.GetListingPlan))).Methods("GET")
+	r.Handle("/api/admin/subreddits/{name}/listing-plan", adminOnly(http.HandlerFunc(admin.UpdateListingPlan))).Methods("PUT")
 	// User token refresh endpoint (admin-only for security)
 	r.Handle("/api/auth/refresh", adminOnly(http.HandlerFunc(auth.RefreshUserToken))).Methods("POST")
 
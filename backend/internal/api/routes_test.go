@@ -11,7 +11,7 @@ import (
 // This test only validates route registration; handler functionality
 // is comprehensively tested in the handlers package.
 func TestSearchEndpointRegistered(t *testing.T) {
-	router := NewRouter(nil)
+	router := NewRouter(nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
 	rr := httptest.NewRecorder()
@@ -28,7 +28,7 @@ func TestSearchEndpointRegistered(t *testing.T) {
 // This test only validates route registration; handler functionality
 // is comprehensively tested in the handlers package.
 func TestExportEndpointRegistered(t *testing.T) {
-	router := NewRouter(nil)
+	router := NewRouter(nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
 	rr := httptest.NewRecorder()
@@ -45,7 +45,7 @@ func TestExportEndpointRegistered(t *testing.T) {
 // Note: With nil queries, the handler will panic and be recovered, but the middleware
 // behavior can still be validated.
 func TestGraphEndpointCompression(t *testing.T) {
-	router := NewRouter(nil)
+	router := NewRouter(nil, nil)
 
 	tests := []struct {
 		name           string
@@ -22,6 +22,20 @@ const (
 	StateHalfOpen
 )
 
+// String returns the lowercase name of the state ("closed", "open", "half-open").
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
 // CircuitBreaker implements a circuit breaker pattern
 type CircuitBreaker struct {
 	mu              sync.RWMutex
@@ -164,3 +178,37 @@ func (cb *CircuitBreaker) GetState() State {
 	defer cb.mu.RUnlock()
 	return cb.state
 }
+
+// Snapshot is a point-in-time view of a circuit breaker.
+type Snapshot struct {
+	Name            string
+	State           State
+	FailureCount    int
+	LastFailureTime time.Time // zero if the breaker has never failed
+	RetryAt         time.Time // when an open breaker will let a probe through; zero unless open
+}
+
+// Snapshot returns the current state of the breaker without changing it.
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	s := Snapshot{
+		Name:            cb.name,
+		State:           cb.state,
+		FailureCount:    cb.failureCount,
+		LastFailureTime: cb.lastFailureTime,
+	}
+	if cb.state == StateOpen {
+		s.RetryAt = cb.lastFailureTime.Add(cb.timeout)
+	}
+	return s
+}
+
+// IsOpen reports whether the breaker is open and still rejecting calls, i.e. its
+// timeout has not yet elapsed. An open breaker whose timeout has passed will
+// admit the next call as a half-open probe, so it is not reported as open.
+func (cb *CircuitBreaker) IsOpen() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.state == StateOpen && time.Since(cb.lastFailureTime) <= cb.timeout
+}
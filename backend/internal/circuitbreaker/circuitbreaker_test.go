@@ -132,3 +132,43 @@ func TestCircuitBreakerReopensOnFailureInHalfOpen(t *testing.T) {
 		t.Errorf("Expected state to be Open after failure in half-open, got %v", cb.GetState())
 	}
 }
+
+func TestCircuitBreakerSnapshotAndIsOpen(t *testing.T) {
+	cb := New(Config{
+		Name:             "test",
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		Timeout:          50 * time.Millisecond,
+	})
+
+	testErr := errors.New("test error")
+
+	if cb.IsOpen() {
+		t.Error("Expected new breaker not to be open")
+	}
+
+	cb.Call(func() error { return testErr })
+	cb.Call(func() error { return testErr })
+
+	if !cb.IsOpen() {
+		t.Error("Expected breaker to be open after reaching the failure threshold")
+	}
+	snap := cb.Snapshot()
+	if snap.State != StateOpen || snap.State.String() != "open" {
+		t.Errorf("Expected open snapshot, got %v", snap.State)
+	}
+	if snap.RetryAt.IsZero() || snap.RetryAt.Before(snap.LastFailureTime) {
+		t.Errorf("Expected RetryAt after last failure, got %v (last failure %v)", snap.RetryAt, snap.LastFailureTime)
+	}
+
+	// Once the timeout elapses the breaker admits probes, so it no longer counts as open.
+	time.Sleep(60 * time.Millisecond)
+	if cb.IsOpen() {
+		t.Error("Expected breaker past its timeout not to report open")
+	}
+
+	cb.Call(func() error { return nil })
+	if snap := cb.Snapshot(); snap.State != StateClosed || !snap.RetryAt.IsZero() {
+		t.Errorf("Expected closed snapshot without RetryAt, got %+v", snap)
+	}
+}
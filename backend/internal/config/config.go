@@ -24,11 +24,58 @@ type Config struct {
 	MaxPostsPerSub     int
 	PostsSort          string
 	PostsTimeFilter    string
+	// Leave posts and comments last seen deleted or removed out of the graph
+	PrecalcExcludeRemoved bool
+	// Score of subreddit relationships that drives subreddit links: overlap, jaccard,
+	// cosine, pmi, npmi or lift. Pairs scoring below PrecalcSimilarityMin get no link.
+	PrecalcSimilarityMetric string
+	PrecalcSimilarityMin    float64
+	// Backbone extraction of subreddit links: disparity, hypergeometric or none.
+	// Links with a p-value above BackboneAlpha are pruned in backbone mode.
+	BackboneMethod string
+	BackboneAlpha  float64
+	// Community detection defaults, overridden by the admin settings: louvain or
+	// leiden, and Leiden's resolution and seed
+	CommunityAlgorithm  string
+	CommunityResolution float64
+	CommunitySeed       int64
+	// Communities of consecutive runs whose members overlap with at least this
+	// Jaccard index are the same community; merges and splits are found by
+	// containment
+	CommunityMatchThreshold float64
+	// Sources sampled for approximate betweenness centrality; 0 skips betweenness
+	CentralitySamples int
+	// In-memory graph of the API server, reloaded when the graph version changes
+	GraphMemoryEnabled      bool
+	GraphMemoryPollInterval time.Duration
+	// Subreddit listing plan: comma-separated sort[:time][@pages] entries; empty means PostsSort/PostsTimeFilter
+	CrawlListingPlan        string
+	CrawlMaxPagesPerListing int // default number of `after` pages followed per listing (0 = until MaxPostsPerSub)
+	// Incremental crawling with per-subreddit watermarks
+	CrawlIncremental            bool          // fetch only posts newer than the watermark when one exists
+	CrawlActivityWindow         time.Duration // posts younger than this get their comments refreshed
+	CrawlCommentRefreshInterval time.Duration // minimum time between comment refreshes of a post
+	CrawlFullRecrawlInterval    time.Duration // run the full listing plan again after this long
+	// Subreddit discovery policy defaults; the admin settings API overrides them
+	DiscoveryMaxHops        int    // subreddits farther than this from a seed are not crawled
+	DiscoveryAllow          string // comma-separated name regexes; when set, a name must match one
+	DiscoveryDeny           string // comma-separated name regexes that exclude a subreddit
+	DiscoveryNSFW           string // "allow" or "deny"
+	DiscoveryMinSubscribers int    // discovered subreddits below this are not crawled
 	// Reddit OAuth (user-auth) configuration
 	RedditClientID     string
 	RedditClientSecret string
 	RedditRedirectURI  string
 	RedditScopes       string
+	// Reddit endpoints; point them at cmd/fakereddit for offline pipelines
+	RedditAPIBaseURL    string // OAuth API, e.g. https://oauth.reddit.com
+	RedditAuthBaseURL   string // token endpoint host, e.g. https://www.reddit.com
+	RedditPublicBaseURL string // unauthenticated fallback, e.g. https://old.reddit.com
+	// HTTP cassettes: record real Reddit responses or replay them offline
+	RedditCassetteMode string // "", "record" or "replay"
+	RedditCassetteDir  string // directory holding the cassette files
+	// Base64 AES-256 key sealing the client secrets stored in reddit_credentials
+	RedditCredentialsKey string
 	// Crawler scheduling
 	StaleDays             int
 	ResetCrawlingAfterMin int
@@ -46,12 +93,27 @@ type Config struct {
 	// Crawler rate limiting (Reddit API)
 	CrawlerRPS       float64 // requests per second to Reddit API
 	CrawlerBurstSize int     // burst size for crawler rate limit
+	// Adaptive crawler rate limiting driven by Reddit's X-Ratelimit-* headers
+	CrawlerAdaptiveRateLimit bool    // retune the request rate from response headers
+	CrawlerMinRPS            float64 // lower bound for the adaptive rate
+	CrawlerMaxRPS            float64 // upper bound for the adaptive rate
+	// Crawler worker pool
+	CrawlerWorkers           int           // number of concurrent crawl workers per process
+	CrawlerLeaseTimeout      time.Duration // how long a claimed job stays leased without a heartbeat
+	CrawlerHeartbeatInterval time.Duration // how often workers renew their job lease
+	CrawlerPollInterval      time.Duration // idle wait between claim attempts when the queue is empty
+	// Reddit circuit breakers (one per endpoint class)
+	CrawlerBreakerFailureThreshold int           // consecutive upstream failures before a breaker opens
+	CrawlerBreakerSuccessThreshold int           // successful probes needed to close a half-open breaker
+	CrawlerBreakerTimeout          time.Duration // how long a breaker stays open before probing again
 	// Layout computation settings
 	LayoutMaxNodes   int     // maximum nodes to include in layout computation
 	LayoutIterations int     // number of force-directed iterations
 	LayoutBatchSize  int     // batch size for position updates
 	LayoutEpsilon    float64 // minimum distance threshold for position updates (0 = update all)
 	LayoutTheta      float64 // Barnes-Hut theta parameter (0.0 = exact, 0.8 = standard approximation)
+	LayoutSeed       int64   // seed for the initial positions; a seed always gives the same layout
+	LayoutWorkers    int     // goroutines computing layout forces (0 = GOMAXPROCS)
 	// Observability settings
 	LogLevel          string  // log level: debug, info, warn, error
 	OTELEnabled       bool    // enable OpenTelemetry tracing
@@ -92,6 +154,7 @@ func Load() *Config {
 		PostsPerSubInGraph:    utils.GetEnvAsInt("POSTS_PER_SUB_IN_GRAPH", 10),
 		CommentsPerPost:       utils.GetEnvAsInt("COMMENTS_PER_POST_IN_GRAPH", 50),
 		MaxAuthorLinks:        utils.GetEnvAsInt("MAX_AUTHOR_CONTENT_LINKS", 3),
+		PrecalcExcludeRemoved: utils.GetEnvAsBool("PRECALC_EXCLUDE_REMOVED", false),
 		MaxPostsPerSub:        utils.GetEnvAsInt("MAX_POSTS_PER_SUB", 25),
 		PostsSort:             strings.ToLower(strings.TrimSpace(os.Getenv("POSTS_SORT"))),
 		PostsTimeFilter:       strings.ToLower(strings.TrimSpace(os.Getenv("POSTS_TIME_FILTER"))),
@@ -99,10 +162,47 @@ func Load() *Config {
 		RedditClientSecret:    strings.TrimSpace(os.Getenv("REDDIT_CLIENT_SECRET")),
 		RedditRedirectURI:     strings.TrimSpace(os.Getenv("REDDIT_REDIRECT_URI")),
 		RedditScopes:          strings.TrimSpace(os.Getenv("REDDIT_SCOPES")),
+		RedditAPIBaseURL:      strings.TrimRight(strings.TrimSpace(os.Getenv("REDDIT_API_BASE_URL")), "/"),
+		RedditAuthBaseURL:     strings.TrimRight(strings.TrimSpace(os.Getenv("REDDIT_AUTH_BASE_URL")), "/"),
+		RedditPublicBaseURL:   strings.TrimRight(strings.TrimSpace(os.Getenv("REDDIT_PUBLIC_BASE_URL")), "/"),
+		RedditCassetteMode:    strings.ToLower(strings.TrimSpace(os.Getenv("REDDIT_CASSETTE_MODE"))),
+		RedditCassetteDir:     strings.TrimSpace(os.Getenv("REDDIT_CASSETTE_DIR")),
+		RedditCredentialsKey:  strings.TrimSpace(os.Getenv("REDDIT_CREDENTIALS_KEY")),
 		StaleDays:             utils.GetEnvAsInt("STALE_DAYS", 30),
 		ResetCrawlingAfterMin: utils.GetEnvAsInt("RESET_CRAWLING_AFTER_MIN", 15),
 		DisableAPIGraphJob:    utils.GetEnvAsBool("DISABLE_API_GRAPH_JOB", false),
 		AdminAPIToken:         strings.TrimSpace(os.Getenv("ADMIN_API_TOKEN")),
+		// Similarity: raw overlap counts, every pair linked
+		PrecalcSimilarityMetric: strings.ToLower(strings.TrimSpace(os.Getenv("PRECALC_SIMILARITY_METRIC"))),
+		PrecalcSimilarityMin:    utils.GetEnvAsFloat("PRECALC_SIMILARITY_MIN", 0),
+		// Backbone: disparity filter at the 5% level
+		BackboneMethod: strings.ToLower(strings.TrimSpace(os.Getenv("BACKBONE_METHOD"))),
+		BackboneAlpha:  utils.GetEnvAsFloat("BACKBONE_ALPHA", 0.05),
+		// Communities: Louvain, as before Leiden was added
+		CommunityAlgorithm:  strings.ToLower(strings.TrimSpace(os.Getenv("COMMUNITY_ALGORITHM"))),
+		CommunityResolution: utils.GetEnvAsFloat("COMMUNITY_RESOLUTION", 1),
+		CommunitySeed:       int64(utils.GetEnvAsInt("COMMUNITY_SEED", 1)),
+		// Community identity: a third of the combined members in common
+		CommunityMatchThreshold: utils.GetEnvAsFloat("COMMUNITY_MATCH_THRESHOLD", 0.3),
+		// Centrality: betweenness from 256 random sources
+		CentralitySamples: utils.GetEnvAsInt("CENTRALITY_BETWEENNESS_SAMPLES", 256),
+		// In-memory graph: loaded by the API, version checked every 30s
+		GraphMemoryEnabled:      utils.GetEnvAsBool("GRAPH_MEMORY_ENABLED", true),
+		GraphMemoryPollInterval: time.Duration(utils.GetEnvAsInt("GRAPH_MEMORY_POLL_SEC", 30)) * time.Second,
+		// Listing plan: pages are followed until MaxPostsPerSub by default, as before plans
+		CrawlListingPlan:        strings.ToLower(strings.TrimSpace(os.Getenv("CRAWL_LISTING_PLAN"))),
+		CrawlMaxPagesPerListing: utils.GetEnvAsInt("CRAWL_MAX_PAGES_PER_LISTING", 0),
+		// Incremental crawls: refresh comments of posts from the last 2 days hourly, full plan weekly
+		CrawlIncremental:            utils.GetEnvAsBool("CRAWL_INCREMENTAL", true),
+		CrawlActivityWindow:         time.Duration(utils.GetEnvAsInt("CRAWL_ACTIVITY_WINDOW_HOURS", 48)) * time.Hour,
+		CrawlCommentRefreshInterval: time.Duration(utils.GetEnvAsInt("CRAWL_COMMENT_REFRESH_MIN", 60)) * time.Minute,
+		CrawlFullRecrawlInterval:    time.Duration(utils.GetEnvAsInt("CRAWL_FULL_RECRAWL_HOURS", 168)) * time.Hour,
+		// Discovery: up to 3 hops from the seeds, no name filters, NSFW allowed
+		DiscoveryMaxHops:        utils.GetEnvAsInt("DISCOVERY_MAX_HOPS", 3),
+		DiscoveryAllow:          strings.TrimSpace(os.Getenv("DISCOVERY_ALLOW")),
+		DiscoveryDeny:           strings.TrimSpace(os.Getenv("DISCOVERY_DENY")),
+		DiscoveryNSFW:           strings.ToLower(strings.TrimSpace(os.Getenv("DISCOVERY_NSFW"))),
+		DiscoveryMinSubscribers: utils.GetEnvAsInt("DISCOVERY_MIN_SUBSCRIBERS", 0),
 		// Security settings with sensible defaults
 		RateLimitGlobal:      utils.GetEnvAsFloat("RATE_LIMIT_GLOBAL", 100.0),
 		RateLimitGlobalBurst: utils.GetEnvAsInt("RATE_LIMIT_GLOBAL_BURST", 200),
@@ -112,12 +212,27 @@ func Load() *Config {
 		// Crawler rate limiting: default to ~1.66 rps (60 requests per minute)
 		CrawlerRPS:       utils.GetEnvAsFloat("CRAWLER_RPS", 1.66),
 		CrawlerBurstSize: utils.GetEnvAsInt("CRAWLER_BURST_SIZE", 1),
+		// Adaptive rate: follow Reddit's remaining quota between 0.2 and 3 rps
+		CrawlerAdaptiveRateLimit: utils.GetEnvAsBool("CRAWLER_ADAPTIVE_RATE_LIMIT", true),
+		CrawlerMinRPS:            utils.GetEnvAsFloat("CRAWLER_MIN_RPS", 0.2),
+		CrawlerMaxRPS:            utils.GetEnvAsFloat("CRAWLER_MAX_RPS", 3.0),
+		// Crawler worker pool: a single worker by default, 5 minute leases renewed every minute
+		CrawlerWorkers:           utils.GetEnvAsInt("CRAWLER_WORKERS", 1),
+		CrawlerLeaseTimeout:      time.Duration(utils.GetEnvAsInt("CRAWLER_LEASE_TIMEOUT_SEC", 300)) * time.Second,
+		CrawlerHeartbeatInterval: time.Duration(utils.GetEnvAsInt("CRAWLER_HEARTBEAT_INTERVAL_SEC", 60)) * time.Second,
+		CrawlerPollInterval:      time.Duration(utils.GetEnvAsInt("CRAWLER_POLL_INTERVAL_MS", 5000)) * time.Millisecond,
+		// Reddit circuit breakers: open after 5 consecutive failures, probe again after a minute
+		CrawlerBreakerFailureThreshold: utils.GetEnvAsInt("CRAWLER_BREAKER_FAILURE_THRESHOLD", 5),
+		CrawlerBreakerSuccessThreshold: utils.GetEnvAsInt("CRAWLER_BREAKER_SUCCESS_THRESHOLD", 2),
+		CrawlerBreakerTimeout:          time.Duration(utils.GetEnvAsInt("CRAWLER_BREAKER_TIMEOUT_SEC", 60)) * time.Second,
 		// Layout computation: sensible defaults for force-directed layout
 		LayoutMaxNodes:   utils.GetEnvAsInt("LAYOUT_MAX_NODES", 5000),
 		LayoutIterations: utils.GetEnvAsInt("LAYOUT_ITERATIONS", 400),
 		LayoutBatchSize:  utils.GetEnvAsInt("LAYOUT_BATCH_SIZE", 5000),
 		LayoutEpsilon:    utils.GetEnvAsFloat("LAYOUT_EPSILON", 0.0),
 		LayoutTheta:      utils.GetEnvAsFloat("LAYOUT_THETA", 0.8),
+		LayoutSeed:       int64(utils.GetEnvAsInt("LAYOUT_SEED", 1)),
+		LayoutWorkers:    utils.GetEnvAsInt("LAYOUT_WORKERS", 0),
 		// Observability settings
 		LogLevel:          strings.ToLower(strings.TrimSpace(os.Getenv("LOG_LEVEL"))),
 		OTELEnabled:       utils.GetEnvAsBool("OTEL_ENABLED", false),
@@ -140,6 +255,53 @@ func Load() *Config {
 	if cached.PostsTimeFilter == "" {
 		cached.PostsTimeFilter = "day"
 	}
+	if cached.CrawlMaxPagesPerListing < 0 {
+		cached.CrawlMaxPagesPerListing = 0
+	}
+	if cached.DiscoveryMaxHops < 0 {
+		cached.DiscoveryMaxHops = 0
+	}
+	if cached.DiscoveryNSFW != "deny" {
+		cached.DiscoveryNSFW = "allow"
+	}
+	if cached.CrawlerWorkers < 1 {
+		cached.CrawlerWorkers = 1
+	}
+	// A custom API base (e.g. a fake Reddit) serves the token and public endpoints
+	// too unless they are set separately.
+	if cached.RedditAPIBaseURL == "" {
+		cached.RedditAPIBaseURL = "https://oauth.reddit.com"
+		if cached.RedditAuthBaseURL == "" {
+			cached.RedditAuthBaseURL = "https://www.reddit.com"
+		}
+		if cached.RedditPublicBaseURL == "" {
+			cached.RedditPublicBaseURL = "https://old.reddit.com"
+		}
+	}
+	if cached.RedditAuthBaseURL == "" {
+		cached.RedditAuthBaseURL = cached.RedditAPIBaseURL
+	}
+	if cached.RedditPublicBaseURL == "" {
+		cached.RedditPublicBaseURL = cached.RedditAPIBaseURL
+	}
+	if cached.RedditCassetteMode == "off" {
+		cached.RedditCassetteMode = ""
+	}
+	if cached.RedditCassetteMode != "" && cached.RedditCassetteDir == "" {
+		cached.RedditCassetteDir = "cassettes"
+	}
+	// Replayed token requests never reach Reddit, so placeholder credentials will do.
+	if cached.RedditCassetteMode == "replay" {
+		if cached.RedditClientID == "" {
+			cached.RedditClientID = "cassette-replay"
+		}
+		if cached.RedditClientSecret == "" {
+			cached.RedditClientSecret = "cassette-replay"
+		}
+	}
+	if cached.CrawlerHeartbeatInterval <= 0 || cached.CrawlerHeartbeatInterval >= cached.CrawlerLeaseTimeout {
+		cached.CrawlerHeartbeatInterval = cached.CrawlerLeaseTimeout / 3
+	}
 	if cached.LogLevel == "" {
 		cached.LogLevel = "info"
 	}
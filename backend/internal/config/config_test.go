@@ -36,6 +36,8 @@ func TestLayoutConfigDefaults(t *testing.T) {
 	os.Unsetenv("LAYOUT_ITERATIONS")
 	os.Unsetenv("LAYOUT_BATCH_SIZE")
 	os.Unsetenv("LAYOUT_EPSILON")
+	os.Unsetenv("LAYOUT_SEED")
+	os.Unsetenv("LAYOUT_WORKERS")
 
 	ResetForTest() // Clear cached config
 	cfg := Load()
@@ -53,6 +55,12 @@ func TestLayoutConfigDefaults(t *testing.T) {
 	if cfg.LayoutEpsilon != 0.0 {
 		t.Errorf("expected LayoutEpsilon=0.0, got %f", cfg.LayoutEpsilon)
 	}
+	if cfg.LayoutSeed != 1 {
+		t.Errorf("expected LayoutSeed=1, got %d", cfg.LayoutSeed)
+	}
+	if cfg.LayoutWorkers != 0 {
+		t.Errorf("expected LayoutWorkers=0, got %d", cfg.LayoutWorkers)
+	}
 }
 
 func TestLayoutConfigCustom(t *testing.T) {
@@ -86,3 +94,24 @@ func TestLayoutConfigCustom(t *testing.T) {
 	os.Unsetenv("LAYOUT_EPSILON")
 	ResetForTest()
 }
+
+func TestRedditBaseURLs(t *testing.T) {
+	t.Setenv("REDDIT_API_BASE_URL", "")
+	t.Setenv("REDDIT_AUTH_BASE_URL", "")
+	t.Setenv("REDDIT_PUBLIC_BASE_URL", "")
+	ResetForTest()
+	cfg := Load()
+	if cfg.RedditAPIBaseURL != "https://oauth.reddit.com" || cfg.RedditAuthBaseURL != "https://www.reddit.com" || cfg.RedditPublicBaseURL != "https://old.reddit.com" {
+		t.Errorf("unexpected default bases: %s %s %s", cfg.RedditAPIBaseURL, cfg.RedditAuthBaseURL, cfg.RedditPublicBaseURL)
+	}
+
+	// A custom API base also serves tokens and public requests unless overridden.
+	t.Setenv("REDDIT_API_BASE_URL", "http://localhost:8081/")
+	t.Setenv("REDDIT_PUBLIC_BASE_URL", "http://localhost:9000")
+	ResetForTest()
+	defer ResetForTest()
+	cfg = Load()
+	if cfg.RedditAPIBaseURL != "http://localhost:8081" || cfg.RedditAuthBaseURL != "http://localhost:8081" || cfg.RedditPublicBaseURL != "http://localhost:9000" {
+		t.Errorf("unexpected custom bases: %s %s %s", cfg.RedditAPIBaseURL, cfg.RedditAuthBaseURL, cfg.RedditPublicBaseURL)
+	}
+}
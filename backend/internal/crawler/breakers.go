@@ -0,0 +1,170 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Endpoint classes that get their own circuit breaker. An outage of one class of
+// Reddit endpoints (e.g. comment trees) should not stop requests to the others.
+const (
+	EndpointAbout    = "about"
+	EndpointListing  = "listing"
+	EndpointComments = "comments"
+	EndpointUser     = "user"
+	EndpointToken    = "token"
+)
+
+// endpointClasses lists the breaker classes in display order.
+var endpointClasses = []string{EndpointAbout, EndpointListing, EndpointComments, EndpointUser, EndpointToken}
+
+// errUpstreamUnavailable marks a response that should count against a breaker
+// (5xx or 429 after retries) while still being handed back to the caller.
+var errUpstreamUnavailable = errors.New("reddit upstream unavailable")
+
+// redditBreakers holds one circuit breaker per endpoint class.
+var redditBreakers = newRedditBreakers(config.Load())
+
+func newRedditBreakers(cfg *config.Config) map[string]*circuitbreaker.CircuitBreaker {
+	m := make(map[string]*circuitbreaker.CircuitBreaker, len(endpointClasses))
+	for _, class := range endpointClasses {
+		m[class] = circuitbreaker.New(circuitbreaker.Config{
+			Name:             "reddit_" + class,
+			FailureThreshold: cfg.CrawlerBreakerFailureThreshold,
+			SuccessThreshold: cfg.CrawlerBreakerSuccessThreshold,
+			Timeout:          cfg.CrawlerBreakerTimeout,
+		})
+	}
+	return m
+}
+
+// classifyEndpoint maps a Reddit URL to its breaker class.
+func classifyEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return EndpointListing
+	}
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(u.Path, "/api/v1/access_token"):
+		return EndpointToken
+	case segs[0] == "comments" || strings.HasPrefix(u.Path, "/api/morechildren"):
+		return EndpointComments
+	case segs[0] == "user" || segs[0] == "u" || strings.HasPrefix(segs[0], "search"):
+		return EndpointUser
+	case segs[0] == "r" && len(segs) >= 3 && segs[2] == "comments":
+		return EndpointComments
+	case segs[0] == "r" && len(segs) >= 3 && strings.HasPrefix(segs[2], "about"):
+		return EndpointAbout
+	default:
+		return EndpointListing
+	}
+}
+
+// isUpstreamFailure reports whether a response status indicates that Reddit itself
+// is unhealthy. Client errors such as 403/404 are about a single resource and do
+// not trip breakers.
+func isUpstreamFailure(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// doWithBreaker runs a Reddit request through the breaker for its endpoint class.
+// When the breaker is open the request is not sent and the returned error wraps
+// circuitbreaker.ErrCircuitOpen; otherwise the response is returned as-is, even
+// when it counted as a failure.
+func doWithBreaker(class string, do func() (*http.Response, error)) (*http.Response, error) {
+	cb, ok := redditBreakers[class]
+	if !ok {
+		return do()
+	}
+	var resp *http.Response
+	err := cb.Call(func() error {
+		r, err := do()
+		resp = r
+		if err != nil {
+			return err
+		}
+		if isUpstreamFailure(r.StatusCode) {
+			return errUpstreamUnavailable
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, circuitbreaker.ErrCircuitOpen):
+		return nil, fmt.Errorf("reddit %s endpoints unavailable: %w", class, err)
+	case errors.Is(err, errUpstreamUnavailable):
+		return resp, nil
+	default:
+		return resp, err
+	}
+}
+
+// openBreakers returns the endpoint classes whose breakers are currently rejecting requests.
+func openBreakers() []string {
+	var open []string
+	for _, class := range endpointClasses {
+		if cb := redditBreakers[class]; cb != nil && cb.IsOpen() {
+			open = append(open, class)
+		}
+	}
+	return open
+}
+
+// BreakerSnapshots returns the state of every Reddit endpoint breaker, keyed by class.
+func BreakerSnapshots() map[string]circuitbreaker.Snapshot {
+	out := make(map[string]circuitbreaker.Snapshot, len(redditBreakers))
+	for class, cb := range redditBreakers {
+		out[class] = cb.Snapshot()
+	}
+	return out
+}
+
+// ReportBreakerStates persists this process's breaker states so the API server,
+// which runs in a separate process, can expose them. Rows from crawler processes
+// that stopped reporting more than a day ago are removed.
+func ReportBreakerStates(ctx context.Context, q *db.Queries, instance string) error {
+	const upsert = `INSERT INTO crawler_circuit_breakers
+                  (instance, endpoint, state, failure_count, last_failure_at, retry_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, now())
+              ON CONFLICT (instance, endpoint) DO UPDATE SET
+                  state = EXCLUDED.state,
+                  failure_count = EXCLUDED.failure_count,
+                  last_failure_at = EXCLUDED.last_failure_at,
+                  retry_at = EXCLUDED.retry_at,
+                  updated_at = now()`
+	for class, snap := range BreakerSnapshots() {
+		if _, err := q.DB().ExecContext(ctx, upsert, instance, class, snap.State.String(), snap.FailureCount,
+			nullTime(snap.LastFailureTime), nullTime(snap.RetryAt)); err != nil {
+			return err
+		}
+	}
+	_, err := q.DB().ExecContext(ctx, `DELETE FROM crawler_circuit_breakers WHERE updated_at < now() - interval '1 day'`)
+	return err
+}
+
+// nullTime converts a zero time to NULL.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// logBreakerPause logs when claiming is paused or resumed because of open breakers.
+func logBreakerPause(paused bool, open []string) {
+	if paused {
+		log.Printf("⏸️ Pausing job claims: Reddit circuit breaker open for %s", strings.Join(open, ", "))
+	} else {
+		log.Printf("▶️ Resuming job claims: Reddit circuit breakers closed")
+	}
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+// withTestBreakers swaps in fresh breakers that open after two failures.
+func withTestBreakers(t *testing.T) {
+	t.Helper()
+	old := redditBreakers
+	redditBreakers = newRedditBreakers(&config.Config{
+		CrawlerBreakerFailureThreshold: 2,
+		CrawlerBreakerSuccessThreshold: 1,
+		CrawlerBreakerTimeout:          time.Hour,
+	})
+	t.Cleanup(func() { redditBreakers = old })
+}
+
+func TestClassifyEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"https://oauth.reddit.com/r/golang/about":                    EndpointAbout,
+		"https://oauth.reddit.com/r/golang/about.json":               EndpointAbout,
+		"https://oauth.reddit.com/r/golang/top?limit=25&t=day":       EndpointListing,
+		"https://oauth.reddit.com/r/golang/new?limit=25":             EndpointListing,
+		"https://oauth.reddit.com/comments/abc123?limit=100":         EndpointComments,
+		"https://oauth.reddit.com/r/golang/comments/abc123":          EndpointComments,
+		"https://oauth.reddit.com/api/morechildren?link_id=t3_abc":   EndpointComments,
+		"https://oauth.reddit.com/user/spez/submitted.json?limit=10": EndpointUser,
+		"https://old.reddit.com/user/spez/.json?limit=10":            EndpointUser,
+		"https://oauth.reddit.com/search.json?q=author%3Aspez":       EndpointUser,
+		"https://www.reddit.com/api/v1/access_token":                 EndpointToken,
+	}
+	for u, want := range cases {
+		if got := classifyEndpoint(u); got != want {
+			t.Errorf("classifyEndpoint(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestDoWithBreaker_OpensOnUpstreamFailures(t *testing.T) {
+	withTestBreakers(t)
+
+	calls := 0
+	unavailable := func() (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := doWithBreaker(EndpointListing, unavailable)
+		if err != nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: expected 503 response to be passed through, got resp=%v err=%v", i, resp, err)
+		}
+	}
+
+	_, err := doWithBreaker(EndpointListing, unavailable)
+	if !errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen once the breaker tripped, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected no request while open, got %d calls", calls)
+	}
+	if open := openBreakers(); len(open) != 1 || open[0] != EndpointListing {
+		t.Errorf("expected only the listing breaker open, got %v", open)
+	}
+
+	// Other endpoint classes are unaffected.
+	if _, err := doWithBreaker(EndpointAbout, func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}); err != nil {
+		t.Errorf("expected about endpoint to stay available, got %v", err)
+	}
+}
+
+func TestDoWithBreaker_ClientErrorsDoNotTrip(t *testing.T) {
+	withTestBreakers(t)
+
+	for i := 0; i < 5; i++ {
+		resp, err := doWithBreaker(EndpointAbout, func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+		})
+		if err != nil || resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 passthrough, got resp=%v err=%v", resp, err)
+		}
+	}
+	if open := openBreakers(); len(open) != 0 {
+		t.Errorf("expected no open breakers after client errors, got %v", open)
+	}
+}
@@ -0,0 +1,281 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+// Cassette modes. In record mode every Reddit response is saved to the cassette
+// directory; in replay mode requests are answered from it and never reach the
+// network.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// cassetteHeaders are the response headers kept in a cassette. Everything else,
+// including cookies, is dropped.
+var cassetteHeaders = []string{
+	"Content-Type",
+	"Location",
+	"Retry-After",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Used",
+	"X-Ratelimit-Reset",
+}
+
+// scrubbedToken replaces OAuth tokens in recorded token responses.
+const scrubbedToken = "cassette-token"
+
+// cassetteEntry is one recorded request/response pair, stored as a JSON file.
+type cassetteEntry struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        string            `json:"body"`
+}
+
+// cassetteTransport records or replays HTTP exchanges. Identical requests are
+// numbered in the order they are made, so a replay returns the same sequence of
+// responses as the recording; once a sequence is exhausted its last response is
+// repeated.
+type cassetteTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper // used in record mode
+
+	mu     sync.Mutex
+	seen   map[string]int             // requests made per key in this process
+	loaded map[string][]cassetteEntry // replay: recordings per key
+}
+
+func newCassetteTransport(mode, dir string, next http.RoundTripper) (*cassetteTransport, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q (want %q or %q)", mode, CassetteRecord, CassetteReplay)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &cassetteTransport{
+		mode:   mode,
+		dir:    dir,
+		next:   next,
+		seen:   make(map[string]int),
+		loaded: make(map[string][]cassetteEntry),
+	}, nil
+}
+
+// newHTTPClient builds the crawler's HTTP client, recording or replaying Reddit
+// traffic when REDDIT_CASSETTE_MODE is set.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	client := &http.Client{Timeout: cfg.HTTPTimeout}
+	if cfg.RedditCassetteMode == "" {
+		return client
+	}
+	t, err := newCassetteTransport(cfg.RedditCassetteMode, cfg.RedditCassetteDir, nil)
+	if err != nil {
+		log.Printf("⚠️ Ignoring REDDIT_CASSETTE_MODE: %v", err)
+		return client
+	}
+	log.Printf("📼 Reddit HTTP cassettes: %s mode, directory %s", t.mode, t.dir)
+	client.Transport = t
+	return client
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	key := cassetteKey(req.Method, req.URL.String(), reqBody)
+
+	t.mu.Lock()
+	n := t.seen[key]
+	t.seen[key] = n + 1
+	t.mu.Unlock()
+
+	if t.mode == CassetteReplay {
+		entry, err := t.lookup(key, n)
+		if err != nil {
+			return nil, fmt.Errorf("cassette: %s %s: %w", req.Method, req.URL, err)
+		}
+		return entry.response(req), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := cassetteEntry{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		Header:      make(map[string]string),
+		Body:        scrubCassetteBody(req.URL.Path, body),
+	}
+	for _, h := range cassetteHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			entry.Header[h] = v
+		}
+	}
+	if err := t.save(key, n, entry); err != nil {
+		log.Printf("⚠️ Failed to record %s %s: %v", req.Method, req.URL, err)
+	}
+	return resp, nil
+}
+
+// lookup returns the n-th recording for key, or the last one if fewer were made.
+func (t *cassetteTransport) lookup(key string, n int) (cassetteEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries, ok := t.loaded[key]
+	if !ok {
+		var err error
+		if entries, err = t.load(key); err != nil {
+			return cassetteEntry{}, err
+		}
+		t.loaded[key] = entries
+	}
+	if len(entries) == 0 {
+		return cassetteEntry{}, fmt.Errorf("no recording in %s", t.dir)
+	}
+	if n >= len(entries) {
+		n = len(entries) - 1
+	}
+	return entries[n], nil
+}
+
+// load reads every recording of key, in request order.
+func (t *cassetteTransport) load(key string) ([]cassetteEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(t.dir, key+".*.json"))
+	if err != nil {
+		return nil, err
+	}
+	seq := func(p string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), key+"."), ".json"))
+		return n
+	}
+	sort.Slice(paths, func(i, j int) bool { return seq(paths[i]) < seq(paths[j]) })
+
+	entries := make([]cassetteEntry, 0, len(paths))
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var e cassetteEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (t *cassetteTransport) save(key string, n int, e cassetteEntry) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(e); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.dir, fmt.Sprintf("%s.%d.json", key, n)), buf.Bytes(), 0o644)
+}
+
+func (e cassetteEntry) response(req *http.Request) *http.Response {
+	h := make(http.Header, len(e.Header))
+	for k, v := range e.Header {
+		h.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+var cassetteSlugChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// cassetteKey names the recordings of a request: a readable slug of the method,
+// host and path followed by a hash of the full request.
+func cassetteKey(method, rawURL string, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(method + " " + rawURL + "\n"))
+	sum.Write(body)
+	hash := hex.EncodeToString(sum.Sum(nil))[:12]
+
+	slug := rawURL
+	if i := strings.Index(slug, "://"); i >= 0 {
+		slug = slug[i+3:]
+	}
+	if i := strings.IndexByte(slug, '?'); i >= 0 {
+		slug = slug[:i]
+	}
+	slug = strings.Trim(cassetteSlugChars.ReplaceAllString(slug, "_"), "_")
+	if len(slug) > 80 {
+		slug = slug[:80]
+	}
+	return strings.ToLower(method) + "_" + slug + "_" + hash
+}
+
+// scrubCassetteBody removes OAuth tokens from token endpoint responses.
+func scrubCassetteBody(path string, body []byte) string {
+	if !strings.HasSuffix(path, "/access_token") {
+		return string(body)
+	}
+	var token map[string]any
+	if err := json.Unmarshal(body, &token); err != nil {
+		return string(body)
+	}
+	for _, field := range []string{"access_token", "refresh_token", "id_token"} {
+		if _, ok := token[field]; ok {
+			token[field] = scrubbedToken
+		}
+	}
+	b, err := json.Marshal(token)
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
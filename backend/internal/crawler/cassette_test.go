@@ -0,0 +1,231 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// handlerTransport serves requests from an http.Handler without a network.
+type handlerTransport struct{ h http.Handler }
+
+func (ht handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	ht.h.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+// offlineTransport fails every request; replays must not fall through to it.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("network access in replay: " + r.URL.String())
+}
+
+// replayCassette points the crawler's HTTP client at a cassette directory and
+// gives the env credential placeholder secrets, restoring both after the test.
+func replayCassette(t *testing.T, dir string) {
+	t.Helper()
+	tr, err := newCassetteTransport(CassetteReplay, dir, offlineTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldClient, oldTokens, oldCreds := httpClient, globalTokenManager, redditCredentials
+	httpClient = &http.Client{Transport: tr}
+	globalTokenManager = &tokenManager{clientID: "cassette-replay", clientSecret: "cassette-replay"}
+	redditCredentials = newCredentialPool()
+	t.Cleanup(func() {
+		globalTokenManager.stop()
+		httpClient, globalTokenManager, redditCredentials = oldClient, oldTokens, oldCreds
+	})
+}
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		io.WriteString(w, `{"access_token":"live-token","expires_in":3600}`)
+	})
+	mux.HandleFunc("/r/golang/about.json", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Ratelimit-Remaining", "99")
+		if calls == 1 {
+			io.WriteString(w, `{"first":true}`)
+			return
+		}
+		io.WriteString(w, `{"second":true}`)
+	})
+
+	rec, err := newCassetteTransport(CassetteRecord, dir, handlerTransport{mux})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec}
+	token := func(c *http.Client) string {
+		resp, err := c.Post("https://www.reddit.com/api/v1/access_token", "application/x-www-form-urlencoded", strings.NewReader("grant_type=client_credentials"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.Header.Get("Set-Cookie") != "" && c != client {
+			t.Error("replayed response carries a cookie")
+		}
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	about := func(c *http.Client) string {
+		resp, err := c.Get("https://oauth.reddit.com/r/golang/about.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	if got := token(client); !strings.Contains(got, "live-token") {
+		t.Errorf("recording should pass the live response through, got %s", got)
+	}
+	about(client)
+	about(client)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("expected 3 cassette files, got %v", files)
+	}
+	for _, f := range files {
+		b, _ := os.ReadFile(f)
+		if strings.Contains(string(b), "live-token") || strings.Contains(string(b), "session=secret") {
+			t.Errorf("%s contains a secret:\n%s", f, b)
+		}
+	}
+
+	play, err := newCassetteTransport(CassetteReplay, dir, offlineTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := &http.Client{Transport: play}
+	if got := token(replay); !strings.Contains(got, scrubbedToken) {
+		t.Errorf("replayed token = %s", got)
+	}
+	// Repeated requests replay in recorded order, then stick to the last response.
+	for i, want := range []string{`{"first":true}`, `{"second":true}`, `{"second":true}`} {
+		if got := about(replay); got != want {
+			t.Errorf("replay %d = %s, want %s", i, got, want)
+		}
+	}
+
+	if _, err := replay.Get("https://oauth.reddit.com/r/rust/about.json"); err == nil || !strings.Contains(err.Error(), "no recording") {
+		t.Errorf("expected a missing recording error, got %v", err)
+	}
+}
+
+func TestNewHTTPClientCassetteMode(t *testing.T) {
+	t.Setenv("REDDIT_CASSETTE_MODE", "replay")
+	t.Setenv("REDDIT_CLIENT_ID", "")
+	t.Setenv("REDDIT_CLIENT_SECRET", "")
+	config.ResetForTest()
+	defer config.ResetForTest()
+
+	cfg := config.Load()
+	if cfg.RedditCassetteDir != "cassettes" || cfg.RedditClientID == "" || cfg.RedditClientSecret == "" {
+		t.Errorf("unexpected replay config: dir %q, client ID %q", cfg.RedditCassetteDir, cfg.RedditClientID)
+	}
+	if tr, ok := newHTTPClient(cfg).Transport.(*cassetteTransport); !ok || tr.mode != CassetteReplay {
+		t.Errorf("expected a replay transport, got %T", newHTTPClient(cfg).Transport)
+	}
+
+	cfg.RedditCassetteMode = "rewind"
+	if tr := newHTTPClient(cfg).Transport; tr != nil {
+		t.Errorf("unknown mode should leave the default transport, got %T", tr)
+	}
+}
+
+func TestReplayPostThreadOffline(t *testing.T) {
+	replayCassette(t, "testdata/cassettes/post_thread")
+
+	page, err := fetchListingPage("t3_abc123", "https://oauth.reddit.com/api/info?id=t3_abc123&raw_json=1")
+	if err != nil {
+		t.Fatalf("fetch post: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].Subreddit != "golang" || page.Posts[0].Author != "gopher_one" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if globalTokenManager.expiry().IsZero() {
+		t.Error("expected the token to be fetched from the cassette")
+	}
+
+	t.Setenv("MAX_COMMENTS_PER_POST", "100")
+	comments, err := CrawlComments("abc123")
+	if err != nil {
+		t.Fatalf("fetch comments: %v", err)
+	}
+	// The removed comment is returned marked, so recrawls can record the removal.
+	if len(comments) != 3 || comments[1].ParentID != "t1_c1" || comments[1].Depth != 1 || comments[2].Removed != RemovalRemoved {
+		t.Errorf("unexpected comments %+v", comments)
+	}
+}
+
+func TestIntegration_HandleJobReplaysPostThread(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	ctx := context.Background()
+
+	replayCassette(t, "testdata/cassettes/post_thread")
+	t.Setenv("MAX_COMMENTS_PER_POST", "100")
+
+	if err := EnqueueTypedJob(ctx, q, JobTypePost, JobPayload{PostID: "abc123"}, 0, "test"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	var id int32
+	if err := conn.QueryRowContext(ctx, `SELECT id FROM crawl_jobs WHERE job_type = 'post' AND dedupe_key = 'abc123'`).Scan(&id); err != nil {
+		t.Fatalf("find job: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM crawl_jobs WHERE id = $1`, id)
+		conn.Exec(`DELETE FROM posts WHERE id = 'abc123'`)
+	})
+
+	// Lease the job as ClaimNextJob would; only the holder can complete it
+	if _, err := conn.ExecContext(ctx, `UPDATE crawl_jobs SET status = 'crawling', lease_owner = 'cassette-worker' WHERE id = $1`, id); err != nil {
+		t.Fatalf("lease job: %v", err)
+	}
+	job := decodeJob(db.CrawlJob{ID: id}, JobTypePost, []byte(`{"post_id":"abc123"}`))
+	job.LeaseOwner = "cassette-worker"
+	if err := handleJob(ctx, q, job); err != nil {
+		t.Fatalf("handleJob: %v", err)
+	}
+
+	var status string
+	var comments int
+	if err := conn.QueryRowContext(ctx, `SELECT status FROM crawl_jobs WHERE id = $1`, id).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.QueryRowContext(ctx, `SELECT count(*) FROM comments WHERE post_id = 'abc123'`).Scan(&comments); err != nil {
+		t.Fatal(err)
+	}
+	if status != "success" || comments != 2 {
+		t.Errorf("job status %q with %d comments stored, want success with 2", status, comments)
+	}
+}
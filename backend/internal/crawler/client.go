@@ -2,34 +2,86 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/httpx"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 )
 
-var httpClient = &http.Client{Timeout: config.Load().HTTPTimeout}
+var httpClient = newHTTPClient(config.Load())
+
+// redditAPIURL returns the URL of an OAuth API path such as "/r/golang/about".
+func redditAPIURL(path string) string { return config.Load().RedditAPIBaseURL + path }
+
+// redditAuthURL returns the URL of a path on the host serving OAuth tokens.
+func redditAuthURL(path string) string { return config.Load().RedditAuthBaseURL + path }
+
+// redditPublicURL returns the URL of a path on the unauthenticated site.
+func redditPublicURL(path string) string { return config.Load().RedditPublicBaseURL + path }
 
 // authenticatedGet issues a GET with OAuth Bearer token and Reddit-compliant User-Agent.
-// It uses DoWithRetryFactory which applies light retries and a pre-attempt hook
-// wired to waitForRateLimit() so we never exceed our global pacing.
+// Each request is scheduled on one credential of the pool and paced by that
+// credential's limiter through the pre-attempt hook of DoWithRetryFactoryObs; the
+// observer feeds Reddit's X-Ratelimit-* headers back into the same limiter, since
+// Reddit accounts quota per OAuth app. A credential whose token request is rejected
+// is taken out of rotation and the request moves to another one. Requests go
+// through the circuit breaker for the URL's endpoint class.
 var authenticatedGet = func(url string) (*http.Response, error) {
-	token, err := getAccessToken()
-	if err != nil {
-		return nil, err
+	for {
+		cred, err := redditCredentials.acquire(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		token, err := cred.tokenSource().getAccessToken()
+		if errors.Is(err, errCredentialRejected) {
+			redditCredentials.revoke(cred, err, time.Now())
+			redditCredentials.release(cred)
+			continue
+		}
+		if err != nil {
+			redditCredentials.release(cred)
+			return nil, err
+		}
+		resp, err := getWithCredential(url, cred, token)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			// The token expired or was revoked early; fetch a fresh one next time.
+			cred.tokenSource().invalidate()
+		} else if err == nil {
+			redditCredentials.succeeded(cred)
+		}
+		redditCredentials.release(cred)
+		return resp, err
 	}
+}
+
+func getWithCredential(url string, cred *credential, token string) (*http.Response, error) {
 	ua := config.Load().UserAgent
+	limiter := cred.rateLimiter()
 	build := func() (*http.Request, error) {
 		req, _ := http.NewRequest("GET", url, nil)
 		req.Header.Set("Authorization", "Bearer "+token)
 		req.Header.Set("User-Agent", ua)
 		return req, nil
 	}
-	pre := func(ctx context.Context, attempt int) error { waitForRateLimit(); return nil }
-	return httpx.DoWithRetryFactory(httpClient, build, pre)
+	pre := func(ctx context.Context, attempt int) error {
+		metrics.CrawlerRateLimitWaits.Inc()
+		return limiter.Wait(ctx)
+	}
+	obs := func(info httpx.AttemptInfo) {
+		if info.Header != nil {
+			limiter.Observe(info.Header, time.Now())
+		}
+	}
+	return doWithBreaker(classifyEndpoint(url), func() (*http.Response, error) {
+		return httpx.DoWithRetryFactoryObs(httpClient, build, pre, obs)
+	})
 }
 
-// unauthenticatedGet performs a GET without OAuth, but with Reddit-compliant User-Agent and retries.
+// unauthenticatedGet performs a GET without OAuth, but with Reddit-compliant User-Agent,
+// retries and the endpoint-class circuit breaker.
 var unauthenticatedGet = func(url string) (*http.Response, error) {
 	ua := config.Load().UserAgent
 	build := func() (*http.Request, error) {
@@ -38,5 +90,7 @@ var unauthenticatedGet = func(url string) (*http.Response, error) {
 		return req, nil
 	}
 	pre := func(ctx context.Context, attempt int) error { waitForRateLimit(); return nil }
-	return httpx.DoWithRetryFactory(httpClient, build, pre)
+	return doWithBreaker(classifyEndpoint(url), func() (*http.Response, error) {
+		return httpx.DoWithRetryFactoryObs(httpClient, build, pre, observeAttempt)
+	})
 }
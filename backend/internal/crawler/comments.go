@@ -4,11 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/utils"
 )
 
+// moreChildrenBatchSize is the maximum number of comment IDs Reddit resolves per
+// /api/morechildren request.
+const moreChildrenBatchSize = 100
+
 // Comment holds comment data from a Reddit thread
 type Comment struct {
 	ID        string    `json:"id"`
@@ -18,77 +25,243 @@ type Comment struct {
 	ParentID  string    `json:"parent_id"`
 	Depth     int       `json:"depth"`
 	Score     int       `json:"score"`
+	Removed   string    `json:"removed,omitempty"` // RemovalDeleted or RemovalRemoved for placeholder comments
+}
+
+// moreStub is a `kind: "more"` placeholder for comments Reddit left out of a listing.
+type moreStub struct {
+	ParentID string
+	Depth    int      // depth of the comments the stub stands for
+	Children []string // comment IDs resolvable through /api/morechildren
+	Count    int      // total comments hidden behind the stub, including descendants
+}
+
+// CommentExpansion records how much of a truncated thread was resolved through
+// /api/morechildren.
+type CommentExpansion struct {
+	Requests int // morechildren requests issued
+	Expanded int // comments fetched through morechildren
+	Skipped  int // comments left unresolved (budget, depth, "continue this thread", errors)
 }
 
+// CrawlComments fetches a post's comment tree, expanding truncated branches.
 func CrawlComments(postID string) ([]Comment, error) {
+	comments, _, err := CrawlCommentsExpanded(postID)
+	return comments, err
+}
+
+// CrawlCommentsExpanded fetches a post's comment tree and resolves `more` stubs
+// through /api/morechildren in batches, staying within MAX_COMMENTS_PER_POST,
+// MAX_COMMENT_DEPTH and MAX_MORECHILDREN_REQUESTS. Every request goes through
+// authenticatedGet and therefore the global rate limiter.
+func CrawlCommentsExpanded(postID string) ([]Comment, CommentExpansion, error) {
+	var exp CommentExpansion
 	limit := utils.GetEnvAsInt("MAX_COMMENTS_PER_POST", 100)
 	maxDepth := utils.GetEnvAsInt("MAX_COMMENT_DEPTH", 4)
-	url := fmt.Sprintf("https://oauth.reddit.com/comments/%s?limit=%d", postID, limit)
+	maxRequests := utils.GetEnvAsInt("MAX_MORECHILDREN_REQUESTS", 5)
+	url := redditAPIURL(fmt.Sprintf("/comments/%s?limit=%d", postID, limit))
 
 	resp, err := authenticatedGet(url)
 	if err != nil {
 		log.Printf("⚠️ Failed to fetch comments for post %s: %v", postID, err)
-		return nil, err
+		return nil, exp, err
 	}
 	defer resp.Body.Close()
 
 	var data []interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		log.Printf("⚠️ Failed to decode comments for post %s: %v", postID, err)
-		return nil, err
+		return nil, exp, err
 	}
 
 	if len(data) < 2 {
-		return nil, fmt.Errorf("unexpected comments response")
+		return nil, exp, fmt.Errorf("unexpected comments response")
 	}
 
 	commentData := data[1].(map[string]interface{})["data"].(map[string]interface{})
 	children := commentData["children"].([]interface{})
 
-	comments := parseCommentsWithLimit(children, 0, maxDepth)
-	log.Printf("🧮 Total parsed comments for post %s: %d", postID, len(comments))
-	return comments, nil
+	var stubs []moreStub
+	comments := parseCommentTree(children, 0, maxDepth, &stubs)
+	comments, exp = expandMoreChildren(postID, comments, stubs, limit, maxDepth, maxRequests)
+
+	metrics.CrawlerCommentsExpanded.Add(float64(exp.Expanded))
+	metrics.CrawlerCommentsSkipped.Add(float64(exp.Skipped))
+	log.Printf("🧮 Total parsed comments for post %s: %d (expanded %d via %d morechildren requests, skipped %d)",
+		postID, len(comments), exp.Expanded, exp.Requests, exp.Skipped)
+	return comments, exp, nil
 }
 
+// expandMoreChildren resolves stubs breadth-first until the comment budget or the
+// request budget runs out. Stubs found in morechildren responses are queued too.
+// Whatever cannot be resolved is counted as skipped.
+func expandMoreChildren(postID string, comments []Comment, stubs []moreStub, limit, maxDepth, maxRequests int) ([]Comment, CommentExpansion) {
+	var exp CommentExpansion
+	seen := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		seen[c.ID] = true
+	}
+
+	var pending []string        // IDs waiting to be requested, in discovery order
+	depthOf := map[string]int{} // stub depth per pending ID, used when a thing lacks its own depth
+	queued := map[string]bool{}
+	enqueue := func(stubs []moreStub) {
+		for _, s := range stubs {
+			if s.Depth > maxDepth {
+				exp.Skipped += s.Count
+				continue
+			}
+			if len(s.Children) == 0 {
+				// "Continue this thread" stubs can only be resolved by refetching the subtree.
+				exp.Skipped += s.Count
+				continue
+			}
+			for _, id := range s.Children {
+				if seen[id] || queued[id] {
+					continue
+				}
+				queued[id] = true
+				pending = append(pending, id)
+				depthOf[id] = s.Depth
+			}
+		}
+	}
+	enqueue(stubs)
+
+	for len(pending) > 0 && len(comments) < limit && exp.Requests < maxRequests {
+		n := moreChildrenBatchSize
+		if room := limit - len(comments); room < n {
+			n = room
+		}
+		if n > len(pending) {
+			n = len(pending)
+		}
+		batch := pending[:n]
+		pending = pending[n:]
+
+		things, err := fetchMoreChildren(postID, batch)
+		exp.Requests++
+		if err != nil {
+			log.Printf("⚠️ Failed to expand comments for post %s: %v", postID, err)
+			exp.Skipped += len(batch)
+			break
+		}
+
+		var nested []moreStub
+		for _, t := range things {
+			tm, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			data, _ := tm["data"].(map[string]interface{})
+			id, _ := data["id"].(string)
+			depth := depthOf[id]
+			if d, ok := data["depth"].(float64); ok {
+				depth = int(d)
+			}
+			switch tm["kind"] {
+			case "more":
+				nested = append(nested, stubFromData(data, depth))
+			case "t1":
+				if seen[id] {
+					continue
+				}
+				if depth > maxDepth {
+					exp.Skipped++
+					continue
+				}
+				seen[id] = true
+				if c, ok := commentFromData(data, depth); ok {
+					if len(comments) >= limit {
+						exp.Skipped++
+						continue
+					}
+					comments = append(comments, c)
+					exp.Expanded++
+				}
+			}
+		}
+		enqueue(nested)
+	}
+
+	exp.Skipped += len(pending)
+	return comments, exp
+}
+
+// fetchMoreChildren requests one batch of comment IDs for a post and returns the
+// flat list of things (t1 comments and nested more stubs).
+func fetchMoreChildren(postID string, ids []string) ([]interface{}, error) {
+	params := url.Values{}
+	params.Set("api_type", "json")
+	params.Set("link_id", "t3_"+postID)
+	params.Set("children", strings.Join(ids, ","))
+	params.Set("limit_children", "false")
+	params.Set("raw_json", "1")
+
+	resp, err := authenticatedGet(redditAPIURL("/api/morechildren?" + params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("morechildren request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		JSON struct {
+			Errors []interface{} `json:"errors"`
+			Data   struct {
+				Things []interface{} `json:"things"`
+			} `json:"data"`
+		} `json:"json"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if len(body.JSON.Errors) > 0 {
+		return nil, fmt.Errorf("morechildren returned errors: %v", body.JSON.Errors)
+	}
+	return body.JSON.Data.Things, nil
+}
+
+// parseCommentsWithLimit walks a comment listing down to maxDepth, ignoring `more` stubs.
 func parseCommentsWithLimit(children []interface{}, depth int, maxDepth int) []Comment {
+	return parseCommentTree(children, depth, maxDepth, nil)
+}
+
+// parseCommentTree walks a comment listing down to maxDepth. When stubs is non-nil,
+// `more` placeholders encountered along the way are appended to it.
+func parseCommentTree(children []interface{}, depth int, maxDepth int, stubs *[]moreStub) []Comment {
 	if depth > maxDepth {
 		return nil
 	}
 	var comments []Comment
 	for _, c := range children {
 		kind, ok := c.(map[string]interface{})["kind"].(string)
-		if !ok || kind != "t1" {
+		if !ok {
 			continue
 		}
 		data, ok := c.(map[string]interface{})["data"].(map[string]interface{})
 		if !ok {
 			continue
 		}
-		author, _ := data["author"].(string)
-		body, _ := data["body"].(string)
-		id, _ := data["id"].(string)
-		parentID, _ := data["parent_id"].(string)
-		if utils.IsValidAuthor(author) && body != "" {
-			var created time.Time
-			if createdUTC, ok := data["created_utc"].(float64); ok {
-				created = time.Unix(int64(createdUTC), 0)
-			} else {
-				created = time.Now()
+		if kind == "more" {
+			if stubs != nil {
+				*stubs = append(*stubs, stubFromData(data, depth))
 			}
-			comments = append(comments, Comment{
-				ID:        id,
-				Author:    author,
-				Body:      body,
-				Depth:     depth,
-				ParentID:  parentID,
-				CreatedAt: created,
-			})
+			continue
+		}
+		if kind != "t1" {
+			continue
+		}
+		if comment, ok := commentFromData(data, depth); ok {
+			comments = append(comments, comment)
 		}
 		if repliesRaw, ok := data["replies"]; ok {
 			if repliesMap, ok := repliesRaw.(map[string]interface{}); ok {
 				if repliesData, ok := repliesMap["data"].(map[string]interface{}); ok {
 					if nestedChildren, ok := repliesData["children"].([]interface{}); ok {
-						nested := parseCommentsWithLimit(nestedChildren, depth+1, maxDepth)
+						nested := parseCommentTree(nestedChildren, depth+1, maxDepth, stubs)
 						comments = append(comments, nested...)
 					}
 				}
@@ -97,3 +270,54 @@ func parseCommentsWithLimit(children []interface{}, depth int, maxDepth int) []C
 	}
 	return comments
 }
+
+// commentFromData converts a t1 data object into a Comment, skipping deleted
+// authors and empty bodies. Deleted and removed comments are kept with Removed
+// set, so recrawls can record the transition of comments already stored.
+func commentFromData(data map[string]interface{}, depth int) (Comment, bool) {
+	author, _ := data["author"].(string)
+	body, _ := data["body"].(string)
+	id, _ := data["id"].(string)
+	parentID, _ := data["parent_id"].(string)
+	removed := commentRemovalState(body)
+	if (!utils.IsValidAuthor(author) && removed == "") || body == "" {
+		return Comment{}, false
+	}
+	score, _ := data["score"].(float64)
+	var created time.Time
+	if createdUTC, ok := data["created_utc"].(float64); ok {
+		created = time.Unix(int64(createdUTC), 0)
+	} else {
+		created = time.Now()
+	}
+	return Comment{
+		ID:        id,
+		Author:    author,
+		Body:      body,
+		Depth:     depth,
+		ParentID:  parentID,
+		CreatedAt: created,
+		Score:     int(score),
+		Removed:   removed,
+	}, true
+}
+
+// stubFromData converts a `more` data object into a moreStub.
+func stubFromData(data map[string]interface{}, depth int) moreStub {
+	s := moreStub{Depth: depth}
+	s.ParentID, _ = data["parent_id"].(string)
+	if count, ok := data["count"].(float64); ok {
+		s.Count = int(count)
+	}
+	if ids, ok := data["children"].([]interface{}); ok {
+		for _, id := range ids {
+			if sid, ok := id.(string); ok && sid != "" {
+				s.Children = append(s.Children, sid)
+			}
+		}
+	}
+	if s.Count < len(s.Children) {
+		s.Count = len(s.Children)
+	}
+	return s
+}
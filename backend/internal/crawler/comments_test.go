@@ -1,6 +1,13 @@
 package crawler
 
-import "testing"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestParseCommentsWithLimit_Depth0(t *testing.T) {
 	var children []interface{}
@@ -49,3 +56,106 @@ func TestParseCommentsWithLimit_StopsAtMaxDepth(t *testing.T) {
 		t.Fatalf("expected 2 at maxDepth=1, got %d", len(out1))
 	}
 }
+
+func TestParseCommentTree_CollectsMoreStubs(t *testing.T) {
+	children := []interface{}{
+		map[string]interface{}{
+			"kind": "t1",
+			"data": map[string]interface{}{"id": "c1", "author": "user1", "body": "hi", "parent_id": "t3_p"},
+		},
+		map[string]interface{}{
+			"kind": "more",
+			"data": map[string]interface{}{"parent_id": "t3_p", "count": float64(5), "children": []interface{}{"c2", "c3"}},
+		},
+	}
+	var stubs []moreStub
+	out := parseCommentTree(children, 0, 2, &stubs)
+	if len(out) != 1 {
+		t.Fatalf("expected 1 comment, got %d", len(out))
+	}
+	if len(stubs) != 1 || stubs[0].Count != 5 || len(stubs[0].Children) != 2 {
+		t.Fatalf("unexpected stubs: %+v", stubs)
+	}
+}
+
+func TestExpandMoreChildren_BatchesAndBudgets(t *testing.T) {
+	var requests [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/morechildren" {
+			w.WriteHeader(404)
+			return
+		}
+		ids := strings.Split(r.URL.Query().Get("children"), ",")
+		requests = append(requests, ids)
+		var things []interface{}
+		for _, id := range ids {
+			things = append(things, map[string]interface{}{
+				"kind": "t1",
+				"data": map[string]interface{}{"id": id, "author": "a_" + id, "body": "b", "parent_id": "t3_p", "depth": float64(0)},
+			})
+		}
+		// The first response also uncovers a nested stub one level down.
+		if len(requests) == 1 {
+			things = append(things, map[string]interface{}{
+				"kind": "more",
+				"data": map[string]interface{}{"parent_id": "t1_" + ids[0], "depth": float64(1), "count": float64(1), "children": []interface{}{"n1"}},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"json": map[string]interface{}{"errors": []interface{}{}, "data": map[string]interface{}{"things": things}}})
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		return http.Get(strings.Replace(u, "https://oauth.reddit.com", server.URL, 1))
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	existing := []Comment{{ID: "c1", Author: "u1", Body: "b"}}
+	ids := make([]string, 0, 150)
+	for i := 0; i < 150; i++ {
+		ids = append(ids, fmt.Sprintf("m%d", i))
+	}
+	stubs := []moreStub{
+		{ParentID: "t3_p", Depth: 0, Children: append([]string{"c1"}, ids...), Count: 151},
+		{ParentID: "t1_c1", Depth: 1, Count: 7}, // "continue this thread"
+		{ParentID: "t1_deep", Depth: 5, Count: 3, Children: []string{"d1"}},
+	}
+
+	out, exp := expandMoreChildren("p", existing, stubs, 120, 2, 5)
+	if len(out) != 120 {
+		t.Fatalf("expected comment budget of 120 to be filled, got %d", len(out))
+	}
+	if exp.Requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", exp.Requests)
+	}
+	if len(requests[0]) != moreChildrenBatchSize {
+		t.Fatalf("expected first batch of %d, got %d", moreChildrenBatchSize, len(requests[0]))
+	}
+	if exp.Expanded != 119 {
+		t.Fatalf("expected 119 expanded, got %d", exp.Expanded)
+	}
+	// 7 (continue thread) + 3 (too deep) + 31 pending m-ids + 1 nested stub
+	if exp.Skipped != 42 {
+		t.Fatalf("expected 42 skipped, got %d", exp.Skipped)
+	}
+}
+
+func TestExpandMoreChildren_RequestBudget(t *testing.T) {
+	calls := 0
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		calls++
+		return nil, fmt.Errorf("should not be called")
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	stubs := []moreStub{{ParentID: "t3_p", Children: []string{"a", "b"}, Count: 2}}
+	out, exp := expandMoreChildren("p", nil, stubs, 100, 4, 0)
+	if calls != 0 || len(out) != 0 {
+		t.Fatalf("expected no requests with zero budget, got %d calls", calls)
+	}
+	if exp.Skipped != 2 {
+		t.Fatalf("expected 2 skipped, got %d", exp.Skipped)
+	}
+}
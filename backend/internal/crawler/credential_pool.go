@@ -0,0 +1,430 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/secrets"
+)
+
+// Credential statuses reported to the admin API.
+const (
+	CredentialActive    = "active"
+	CredentialExhausted = "exhausted"
+	CredentialDraining  = "draining"
+	CredentialRevoked   = "revoked"
+)
+
+// EnvCredentialLabel names the credential configured through REDDIT_CLIENT_ID and
+// REDDIT_CLIENT_SECRET. Credentials stored in the database cannot use it.
+const EnvCredentialLabel = "env"
+
+// revokedRecheckInterval is how long a credential rejected by Reddit is left out
+// before one request tries it again, in case the rejection was transient.
+const revokedRecheckInterval = 15 * time.Minute
+
+// errNoCredentials is returned when every credential is revoked or draining.
+var errNoCredentials = errors.New("no usable Reddit OAuth credentials")
+
+// credential is one Reddit OAuth app with its own token, rate limiter and quota.
+// The env credential leaves tokens and limiter nil and uses the package-level
+// token manager and limiter, which tests and RotateOAuthCredentials replace.
+type credential struct {
+	label   string
+	tokens  *tokenManager
+	limiter *AdaptiveLimiter
+
+	// Guarded by credentialPool.mu.
+	inFlight  int
+	requests  int64
+	draining  bool
+	revokedAt time.Time
+	lastError string
+}
+
+func (c *credential) tokenSource() *tokenManager {
+	if c.tokens != nil {
+		return c.tokens
+	}
+	return globalTokenManager
+}
+
+func (c *credential) rateLimiter() *AdaptiveLimiter {
+	if c.limiter != nil {
+		return c.limiter
+	}
+	return getLimiter()
+}
+
+// configured reports whether the credential has a client ID and secret.
+func (c *credential) configured() bool {
+	tm := c.tokenSource()
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.clientID != "" && tm.clientSecret != ""
+}
+
+// credentialPool schedules requests across credentials: each request goes to the
+// usable credential with the fewest requests in flight, rotating between ties.
+// Credentials whose quota is exhausted are only used when all of them are, and
+// then the one that resets first is chosen.
+type credentialPool struct {
+	mu    sync.Mutex
+	creds []*credential
+	next  int
+}
+
+func newCredentialPool() *credentialPool {
+	return &credentialPool{creds: []*credential{{label: EnvCredentialLabel}}}
+}
+
+// redditCredentials is the pool used by authenticatedGet.
+var redditCredentials = newCredentialPool()
+
+// credentialState is what the pool reads from a credential's token manager and
+// limiter. It is read under their own locks before the pool lock is taken, so a
+// credential that is busy refreshing its token cannot hold up the others.
+type credentialState struct {
+	configured  bool
+	pausedUntil time.Time
+}
+
+// states reads the state of every pooled credential.
+func (p *credentialPool) states(now time.Time) map[*credential]credentialState {
+	p.mu.Lock()
+	creds := append([]*credential(nil), p.creds...)
+	p.mu.Unlock()
+	out := make(map[*credential]credentialState, len(creds))
+	for _, c := range creds {
+		out[c] = credentialState{configured: c.configured(), pausedUntil: c.rateLimiter().PausedUntil(now)}
+	}
+	return out
+}
+
+// acquire picks a credential for one request and counts it as in flight. The
+// caller must release it.
+func (p *credentialPool) acquire(now time.Time) (*credential, error) {
+	states := p.states(now)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		best, earliest *credential
+		earliestReset  time.Time
+	)
+	n := len(p.creds)
+	for i := 0; i < n; i++ {
+		c := p.creds[(p.next+i)%n]
+		st, ok := states[c]
+		// A credential added since the states were read waits for the next request
+		if !ok || c.draining || !st.configured {
+			continue
+		}
+		if !c.revokedAt.IsZero() && now.Sub(c.revokedAt) < revokedRecheckInterval {
+			continue
+		}
+		if until := st.pausedUntil; !until.IsZero() {
+			if earliest == nil || until.Before(earliestReset) {
+				earliest, earliestReset = c, until
+			}
+			continue
+		}
+		if best == nil || c.inFlight < best.inFlight {
+			best = c
+		}
+	}
+	if best == nil {
+		best = earliest
+	}
+	if best == nil {
+		return nil, errNoCredentials
+	}
+	if n > 0 {
+		p.next = (p.next + 1) % n
+	}
+	best.inFlight++
+	best.requests++
+	metrics.CrawlerCredentialRequests.WithLabelValues(best.label).Inc()
+	return best, nil
+}
+
+// release marks a request as finished. A draining credential leaves the pool once
+// its last request has finished.
+func (p *credentialPool) release(c *credential) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c.inFlight > 0 {
+		c.inFlight--
+	}
+	if c.draining && c.inFlight == 0 {
+		p.removeLocked(c)
+	}
+}
+
+// revoke takes a credential out of rotation after Reddit rejected it.
+func (p *credentialPool) revoke(c *credential, err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c.revokedAt.IsZero() {
+		log.Printf("⚠️ Reddit rejected OAuth credential %q; taking it out of rotation", c.label)
+	}
+	c.revokedAt = now
+	c.lastError = err.Error()
+}
+
+// succeeded clears a previous rejection once a credential works again.
+func (p *credentialPool) succeeded(c *credential) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !c.revokedAt.IsZero() {
+		log.Printf("✓ OAuth credential %q accepted again", c.label)
+	}
+	c.revokedAt = time.Time{}
+	c.lastError = ""
+}
+
+// restore puts a credential back into rotation after its secret was replaced.
+func (p *credentialPool) restore(label string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.creds {
+		if c.label == label {
+			c.revokedAt = time.Time{}
+			c.lastError = ""
+		}
+	}
+}
+
+func (p *credentialPool) removeLocked(c *credential) {
+	for i, other := range p.creds {
+		if other == c {
+			p.creds = append(p.creds[:i], p.creds[i+1:]...)
+			break
+		}
+	}
+	if c.tokens != nil {
+		c.tokens.stop()
+	}
+	if c.limiter != nil {
+		c.limiter.forgetMetrics()
+	}
+	if p.next >= len(p.creds) {
+		p.next = 0
+	}
+	log.Printf("🔌 OAuth credential %q removed from the pool", c.label)
+}
+
+// storedCredential is one enabled row of reddit_credentials.
+type storedCredential struct {
+	Label        string
+	ClientID     string
+	ClientSecret string
+}
+
+// sync makes the pool match the enabled stored credentials. New credentials get
+// their own token manager and a limiter built from the crawler rate settings;
+// changed secrets replace the cached token; credentials that were disabled or
+// deleted drain. The env credential is never touched.
+func (p *credentialPool) sync(stored []storedCredential, cfg *config.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	want := make(map[string]storedCredential, len(stored))
+	for _, s := range stored {
+		if s.Label != EnvCredentialLabel {
+			want[s.Label] = s
+		}
+	}
+
+	for _, c := range append([]*credential(nil), p.creds...) {
+		if c.label == EnvCredentialLabel {
+			continue
+		}
+		s, ok := want[c.label]
+		if !ok {
+			if !c.draining {
+				log.Printf("🔌 Draining OAuth credential %q", c.label)
+				c.draining = true
+			}
+			if c.inFlight == 0 {
+				p.removeLocked(c)
+			}
+			continue
+		}
+		delete(want, c.label)
+		c.draining = false
+		tm := c.tokens
+		tm.mu.Lock()
+		if tm.clientID != s.ClientID || tm.clientSecret != s.ClientSecret {
+			tm.clientID, tm.clientSecret = s.ClientID, s.ClientSecret
+			tm.accessToken, tm.tokenExpiry = "", time.Time{}
+			c.revokedAt = time.Time{}
+			c.lastError = ""
+			log.Printf("🔄 OAuth credential %q updated (client_id: %s)", c.label, secrets.Mask(s.ClientID))
+		}
+		tm.mu.Unlock()
+	}
+
+	labels := make([]string, 0, len(want))
+	for label := range want {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		s := want[label]
+		limiter := NewAdaptiveLimiter(label, cfg.CrawlerRPS, cfg.CrawlerBurstSize, cfg.CrawlerAdaptiveRateLimit, cfg.CrawlerMinRPS, cfg.CrawlerMaxRPS)
+		p.creds = append(p.creds, &credential{
+			label:   label,
+			limiter: limiter,
+			tokens:  &tokenManager{clientID: s.ClientID, clientSecret: s.ClientSecret, limiter: limiter},
+		})
+		log.Printf("➕ OAuth credential %q added to the pool (client_id: %s)", label, secrets.Mask(s.ClientID))
+	}
+}
+
+// CredentialSnapshot is a point-in-time view of one pooled credential.
+type CredentialSnapshot struct {
+	Label       string
+	Status      string
+	InFlight    int
+	Requests    int64
+	Quota       QuotaState
+	TokenExpiry time.Time
+	LastError   string
+}
+
+// snapshots returns the state of every credential in the pool. Like acquire, it
+// reads token and limiter state outside the pool lock.
+func (p *credentialPool) snapshots(now time.Time) []CredentialSnapshot {
+	type pooled struct {
+		c        *credential
+		revoked  bool
+		draining bool
+		snap     CredentialSnapshot
+	}
+	p.mu.Lock()
+	list := make([]pooled, 0, len(p.creds))
+	for _, c := range p.creds {
+		list = append(list, pooled{c: c, revoked: !c.revokedAt.IsZero(), draining: c.draining, snap: CredentialSnapshot{
+			Label:     c.label,
+			InFlight:  c.inFlight,
+			Requests:  c.requests,
+			LastError: c.lastError,
+		}})
+	}
+	p.mu.Unlock()
+
+	out := make([]CredentialSnapshot, 0, len(list))
+	for _, e := range list {
+		if e.c.label == EnvCredentialLabel && !e.c.configured() {
+			continue
+		}
+		limiter := e.c.rateLimiter()
+		snap := e.snap
+		snap.Status = CredentialActive
+		switch {
+		case e.revoked:
+			snap.Status = CredentialRevoked
+		case e.draining:
+			snap.Status = CredentialDraining
+		case !limiter.PausedUntil(now).IsZero():
+			snap.Status = CredentialExhausted
+		}
+		snap.Quota = limiter.Quota()
+		snap.TokenExpiry = e.c.tokenSource().expiry()
+		out = append(out, snap)
+	}
+	return out
+}
+
+// SyncCredentials reloads the enabled credentials from reddit_credentials into the
+// pool, so credentials added or disabled through the admin API take effect without
+// a restart. Stored secrets are sealed with REDDIT_CREDENTIALS_KEY; if any cannot be
+// opened the pool is left unchanged.
+func SyncCredentials(ctx context.Context, q *db.Queries) error {
+	rows, err := q.DB().QueryContext(ctx, `SELECT label, client_id, client_secret_sealed FROM reddit_credentials WHERE enabled ORDER BY label`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var stored []storedCredential
+	for rows.Next() {
+		var s storedCredential
+		if err := rows.Scan(&s.Label, &s.ClientID, &s.ClientSecret); err != nil {
+			return err
+		}
+		stored = append(stored, s)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cfg := config.Load()
+	if len(stored) > 0 {
+		key, err := secrets.ParseKey(cfg.RedditCredentialsKey)
+		if err != nil {
+			return fmt.Errorf("REDDIT_CREDENTIALS_KEY: %w", err)
+		}
+		for i := range stored {
+			secret, err := secrets.Open(key, stored[i].ClientSecret, stored[i].Label)
+			if err != nil {
+				return fmt.Errorf("credential %q: %w", stored[i].Label, err)
+			}
+			stored[i].ClientSecret = secret
+		}
+	}
+	redditCredentials.sync(stored, cfg)
+	return nil
+}
+
+// ReportCredentialStates persists this process's view of the credential pool for
+// the admin API. Rows for credentials that left the pool are removed, as are rows
+// from crawler processes that stopped reporting more than a day ago.
+func ReportCredentialStates(ctx context.Context, q *db.Queries, instance string) error {
+	const upsert = `INSERT INTO reddit_credential_states
+                  (instance, credential, status, in_flight, requests_total, quota_remaining, quota_used, quota_reset_at, token_expires_at, last_error, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now())
+              ON CONFLICT (instance, credential) DO UPDATE SET
+                  status = EXCLUDED.status,
+                  in_flight = EXCLUDED.in_flight,
+                  requests_total = EXCLUDED.requests_total,
+                  quota_remaining = EXCLUDED.quota_remaining,
+                  quota_used = EXCLUDED.quota_used,
+                  quota_reset_at = EXCLUDED.quota_reset_at,
+                  token_expires_at = EXCLUDED.token_expires_at,
+                  last_error = EXCLUDED.last_error,
+                  updated_at = now()`
+	snaps := redditCredentials.snapshots(time.Now())
+	labels := make([]string, 0, len(snaps))
+	for _, s := range snaps {
+		var remaining, used any
+		if s.Quota.Known {
+			remaining, used = s.Quota.Remaining, s.Quota.Used
+		}
+		var lastError any
+		if s.LastError != "" {
+			lastError = s.LastError
+		}
+		if _, err := q.DB().ExecContext(ctx, upsert, instance, s.Label, s.Status, s.InFlight, s.Requests,
+			remaining, used, nullTime(s.Quota.ResetAt), nullTime(s.TokenExpiry), lastError); err != nil {
+			return err
+		}
+		labels = append(labels, s.Label)
+	}
+	if _, err := q.DB().ExecContext(ctx, `DELETE FROM reddit_credential_states WHERE instance = $1 AND NOT (credential = ANY($2))`,
+		instance, pq.Array(labels)); err != nil {
+		return err
+	}
+	_, err := q.DB().ExecContext(ctx, `DELETE FROM reddit_credential_states WHERE updated_at < now() - interval '1 day'`)
+	return err
+}
@@ -0,0 +1,233 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+func testCredential(label string) *credential {
+	limiter := NewAdaptiveLimiter(label, 10, 1, true, 0.2, 10)
+	return &credential{
+		label:   label,
+		limiter: limiter,
+		tokens:  &tokenManager{clientID: label + "-id", clientSecret: label + "-secret", limiter: limiter},
+	}
+}
+
+func exhaust(c *credential, now time.Time, reset string) {
+	h := http.Header{}
+	h.Set("X-Ratelimit-Remaining", "0")
+	h.Set("X-Ratelimit-Used", "600")
+	h.Set("X-Ratelimit-Reset", reset)
+	c.limiter.Observe(h, now)
+}
+
+func TestCredentialPoolSpreadsRequests(t *testing.T) {
+	a, b := testCredential("a"), testCredential("b")
+	p := &credentialPool{creds: []*credential{a, b}}
+	now := time.Now()
+
+	first, err := p.acquire(now)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	second, err := p.acquire(now)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if first == second {
+		t.Fatalf("expected requests on different credentials, both went to %q", first.label)
+	}
+
+	// With one request in flight each, releasing b makes it the least busy.
+	p.release(b)
+	next, _ := p.acquire(now)
+	if next != b {
+		t.Errorf("expected least busy credential b, got %q", next.label)
+	}
+}
+
+func TestCredentialPoolSkipsExhaustedAndRevoked(t *testing.T) {
+	a, b, c := testCredential("a"), testCredential("b"), testCredential("c")
+	p := &credentialPool{creds: []*credential{a, b, c}}
+	now := time.Now()
+
+	exhaust(a, now, "300")
+	p.revoke(b, errCredentialRejected, now)
+	for i := 0; i < 3; i++ {
+		got, err := p.acquire(now)
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		if got != c {
+			t.Fatalf("expected only c to be scheduled, got %q", got.label)
+		}
+		p.release(got)
+	}
+
+	// When every usable credential is exhausted, the one resetting first is used.
+	exhaust(c, now, "60")
+	got, err := p.acquire(now)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got != c {
+		t.Errorf("expected c (resets first), got %q", got.label)
+	}
+	p.release(got)
+
+	// A revoked credential is retried after the recheck interval.
+	p.succeeded(c)
+	later := now.Add(revokedRecheckInterval + time.Minute)
+	exhaust(c, later, "600")
+	exhaust(a, later, "600")
+	got, err = p.acquire(later)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got != b {
+		t.Errorf("expected revoked credential b to be rechecked, got %q", got.label)
+	}
+}
+
+func TestCredentialPoolNoUsableCredentials(t *testing.T) {
+	a := testCredential("a")
+	p := &credentialPool{creds: []*credential{a}}
+	p.revoke(a, errCredentialRejected, time.Now())
+	if _, err := p.acquire(time.Now()); !errors.Is(err, errNoCredentials) {
+		t.Errorf("expected errNoCredentials, got %v", err)
+	}
+}
+
+func TestCredentialPoolSyncDrainsRemovedCredentials(t *testing.T) {
+	config.ResetForTest()
+	cfg := config.Load()
+	p := &credentialPool{}
+
+	p.sync([]storedCredential{
+		{Label: "a", ClientID: "a-id", ClientSecret: "a-secret"},
+		{Label: "b", ClientID: "b-id", ClientSecret: "b-secret"},
+		{Label: EnvCredentialLabel, ClientID: "ignored", ClientSecret: "ignored"},
+	}, cfg)
+	if len(p.creds) != 2 {
+		t.Fatalf("expected 2 pooled credentials, got %d", len(p.creds))
+	}
+	a := p.creds[0]
+	if a.label != "a" || a.limiter == nil || a.tokens.limiter != a.limiter {
+		t.Fatalf("credential a not set up with its own limiter: %+v", a)
+	}
+
+	// A request in flight on a keeps it in the pool while it drains.
+	inFlight, _ := p.acquire(time.Now())
+	if inFlight != a {
+		t.Fatalf("expected first request on a, got %q", inFlight.label)
+	}
+	p.sync([]storedCredential{{Label: "b", ClientID: "b-id", ClientSecret: "b-secret"}}, cfg)
+	if len(p.creds) != 2 || !a.draining {
+		t.Fatalf("expected a to drain while in flight, pool=%d draining=%v", len(p.creds), a.draining)
+	}
+	for i := 0; i < 3; i++ {
+		got, _ := p.acquire(time.Now())
+		if got == a {
+			t.Fatal("draining credential must not receive new requests")
+		}
+		p.release(got)
+	}
+	p.release(a)
+	if len(p.creds) != 1 || p.creds[0].label != "b" {
+		t.Fatalf("expected only b after a drained, got %d credentials", len(p.creds))
+	}
+
+	// A changed secret drops the cached token.
+	b := p.creds[0]
+	b.tokens.accessToken = "cached"
+	p.sync([]storedCredential{{Label: "b", ClientID: "b-id", ClientSecret: "rotated"}}, cfg)
+	if b.tokens.clientSecret != "rotated" || b.tokens.accessToken != "" {
+		t.Errorf("expected rotated secret and cleared token, got secret=%q token=%q", b.tokens.clientSecret, b.tokens.accessToken)
+	}
+}
+
+func TestCredentialSnapshotsStatus(t *testing.T) {
+	a, b, c := testCredential("a"), testCredential("b"), testCredential("c")
+	p := &credentialPool{creds: []*credential{a, b, c}}
+	now := time.Now()
+	exhaust(a, now, "120")
+	p.revoke(b, errCredentialRejected, now)
+
+	want := map[string]string{"a": CredentialExhausted, "b": CredentialRevoked, "c": CredentialActive}
+	for _, s := range p.snapshots(now) {
+		if s.Status != want[s.Label] {
+			t.Errorf("credential %q: expected status %q, got %q", s.Label, want[s.Label], s.Status)
+		}
+	}
+}
+
+func TestCredentialPoolNotBlockedByTokenRefresh(t *testing.T) {
+	var calls atomic.Int32
+	started, unblock := make(chan struct{}, 1), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-unblock
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "fresh", "expires_in": 3600}`))
+	}))
+	defer srv.Close()
+	t.Setenv("REDDIT_AUTH_BASE_URL", srv.URL)
+	config.ResetForTest()
+	defer config.ResetForTest()
+
+	a, b := testCredential("a"), testCredential("b")
+	defer a.tokens.stop()
+	p := &credentialPool{creds: []*credential{a, b}}
+
+	// Several requests on a wait for one slow token request
+	var wg sync.WaitGroup
+	tokens := make([]string, 3)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], _ = a.tokens.getAccessToken()
+		}()
+	}
+	<-started
+
+	// Meanwhile the pool keeps scheduling and reporting
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 4; i++ {
+			c, err := p.acquire(time.Now())
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			p.release(c)
+		}
+		p.snapshots(time.Now())
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("acquire blocked behind a token refresh")
+	}
+
+	close(unblock)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected one shared token request, got %d", n)
+	}
+	for i, tok := range tokens {
+		if tok != "fresh" {
+			t.Errorf("caller %d got token %q", i, tok)
+		}
+	}
+}
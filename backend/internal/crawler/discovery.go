@@ -2,18 +2,24 @@ package crawler
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 	"log"
+	"sync"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/utils"
 )
 
 var seenUsers = struct {
-	m map[string]bool
+	mu sync.Mutex
+	m  map[string]bool
 }{m: make(map[string]bool)}
 
-// ShouldFetchForUser checks if a user has already been processed.
+// ShouldFetchForUser checks if a user has already been queued by this process.
+// It is safe for concurrent use by crawler workers.
 func ShouldFetchForUser(username string) bool {
+	seenUsers.mu.Lock()
+	defer seenUsers.mu.Unlock()
 	if seenUsers.m[username] {
 		return false
 	}
@@ -21,15 +27,15 @@ func ShouldFetchForUser(username string) bool {
 	return true
 }
 
-func FetchAndQueueUserSubreddits(ctx context.Context, q *db.Queries, username string, config FetchUserSubredditsConfig) {
-	if !config.Enabled || !ShouldFetchForUser(username) {
-		return
-	}
-
+// fetchAndQueueUserSubreddits scans a user's recent posts and comments and queues
+// crawl jobs for the subreddits they were active in that the discovery policy
+// allows, up to config.MaxEnqueue subreddits without a job. It returns how many
+// jobs were queued.
+func fetchAndQueueUserSubreddits(ctx context.Context, q *db.Queries, username string, config FetchUserSubredditsConfig, policy DiscoveryPolicy, from discoverySource) (int, error) {
 	subs, err := FetchRecentUserSubreddits(username, config.Limit)
 	if err != nil {
 		log.Printf("⚠️ Failed to fetch subs for u/%s: %v", username, err)
-		return
+		return 0, err
 	}
 
 	shuffled := subs // already sufficiently random for now; utils.ShuffleStrings could be used
@@ -37,31 +43,12 @@ func FetchAndQueueUserSubreddits(ctx context.Context, q *db.Queries, username st
 	total := len(shuffled)
 
 	for _, sub := range shuffled {
-		// Get or create subreddit
-		subredditID, err := q.EnsureSubreddit(ctx, db.EnsureSubredditParams{
-			Name:        sub,
-			Title:       sql.NullString{String: sub, Valid: true},
-			Description: sql.NullString{String: "", Valid: true},
-			Subscribers: sql.NullInt32{Int32: 0, Valid: true},
-		})
-		if err != nil {
-			log.Printf("⚠️ Failed to upsert subreddit r/%s: %v", sub, err)
-			continue
-		}
-
-		exists, err := q.CrawlJobExists(ctx, subredditID)
+		queued, err := discoverSubreddit(ctx, q, policy, sub, from)
 		if err != nil {
-			log.Printf("⚠️ Failed to check if job exists for r/%s: %v", sub, err)
-			continue
-		}
-		if exists {
+			log.Printf("⚠️ Failed to enqueue r/%s: %v", sub, err)
 			continue
 		}
-
-		if err := q.EnqueueCrawlJob(ctx, db.EnqueueCrawlJobParams{
-			SubredditID: subredditID,
-			EnqueuedBy:  sql.NullString{String: "system", Valid: true},
-		}); err == nil {
+		if queued {
 			count++
 			if count >= config.MaxEnqueue {
 				break
@@ -70,16 +57,55 @@ func FetchAndQueueUserSubreddits(ctx context.Context, q *db.Queries, username st
 	}
 
 	log.Printf("📬 Enqueued %d/%d new subs from u/%s", count, total, username)
+	return count, nil
 }
 
-// FetchAndQueueUserSubredditsForAuthors processes a list of authors and queues subs for each.
-func FetchAndQueueUserSubredditsForAuthors(ctx context.Context, q *db.Queries, authors []string, config FetchUserSubredditsConfig) {
-	total := len(authors)
-	processed := 0
+// userSubredditsConfig reads the user discovery settings from the environment.
+func userSubredditsConfig() FetchUserSubredditsConfig {
+	return FetchUserSubredditsConfig{
+		Limit:      utils.GetEnvAsInt("USER_SUB_FETCH_LIMIT", 30),
+		MaxEnqueue: utils.GetEnvAsInt("USER_SUB_ENQUEUE_MAX", 10),
+		Enabled:    utils.GetEnvAsBool("FETCH_USER_SUBREDDITS", true),
+	}
+}
 
+// EnqueueUserJobs queues a user job for each author, so that their histories are
+// scanned by the worker pool with retries instead of inline in the current job.
+// Authors already queued by this process are skipped without a database round trip.
+// hop is the distance from a seed of the subreddit the authors were seen in.
+func EnqueueUserJobs(ctx context.Context, q *db.Queries, authors []string, hop int, enqueuedBy string) int {
+	queued := 0
+	priority := policyFor(JobTypeUser).Priority
 	for _, author := range authors {
-		FetchAndQueueUserSubreddits(ctx, q, author, config)
-		processed++
-		log.Printf("👥 Processed %d/%d users", processed, total)
+		if !ShouldFetchForUser(author) {
+			continue
+		}
+		if err := EnqueueTypedJob(ctx, q, JobTypeUser, JobPayload{Username: author, Hop: hop}, priority, enqueuedBy); err != nil {
+			log.Printf("⚠️ Failed to enqueue user job for u/%s: %v", author, err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// handleUserJob runs a user job: it queues the subreddits from the user's history.
+func handleUserJob(ctx context.Context, q *db.Queries, job Job) error {
+	if _, err := job.Payload.Normalize(JobTypeUser); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidJob, err)
+	}
+	cfg := userSubredditsConfig()
+	if !cfg.Enabled {
+		log.Printf("ℹ️ User discovery disabled; skipping u/%s", job.Payload.Username)
+		return nil
+	}
+	if err := q.UpsertUser(ctx, job.Payload.Username); err != nil {
+		log.Printf("⚠️ Failed to upsert user %s: %v", job.Payload.Username, err)
 	}
+	_, err := fetchAndQueueUserSubreddits(ctx, q, job.Payload.Username, cfg, LoadDiscoveryPolicy(ctx, q), discoverySource{
+		Hop:        job.Payload.Hop + 1,
+		JobID:      job.ID,
+		EnqueuedBy: "system",
+	})
+	return err
 }
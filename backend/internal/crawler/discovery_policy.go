@@ -0,0 +1,310 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/admin"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
+)
+
+// Service setting keys of the discovery policy. Stored values override the
+// DISCOVERY_* environment defaults; pattern lists are stored as JSON arrays.
+const (
+	SettingDiscoveryMaxHops        = "discovery_max_hops"
+	SettingDiscoveryAllowPatterns  = "discovery_allow_patterns"
+	SettingDiscoveryDenyPatterns   = "discovery_deny_patterns"
+	SettingDiscoveryNSFWPolicy     = "discovery_nsfw_policy"
+	SettingDiscoveryMinSubscribers = "discovery_min_subscribers"
+)
+
+// NSFW policies.
+const (
+	NSFWAllow = "allow"
+	NSFWDeny  = "deny"
+)
+
+// Reasons a subreddit is excluded by the discovery policy.
+const (
+	ExcludedMaxHops        = "max_hops"
+	ExcludedDenied         = "denied"
+	ExcludedNotAllowed     = "not_allowed"
+	ExcludedNSFW           = "nsfw"
+	ExcludedMinSubscribers = "min_subscribers"
+)
+
+// errExcludedByPolicy fails a subreddit job permanently when the subreddit turns out
+// to be outside the discovery policy.
+var errExcludedByPolicy = errors.New("subreddit excluded by discovery policy")
+
+// DiscoveryPolicy bounds which discovered subreddits are crawled. Seeds (hop 0) are
+// always crawled; every other subreddit must be within MaxHops of a seed, match an
+// allow pattern when any are set, match no deny pattern, and pass the NSFW and
+// subscriber checks once its metadata is known.
+type DiscoveryPolicy struct {
+	MaxHops        int
+	AllowPatterns  []string
+	DenyPatterns   []string
+	NSFW           string
+	MinSubscribers int
+
+	allow, deny []*regexp.Regexp
+}
+
+// NewDiscoveryPolicy validates and compiles a policy. Patterns are matched
+// case-insensitively against subreddit names.
+func NewDiscoveryPolicy(maxHops int, allow, deny []string, nsfw string, minSubscribers int) (DiscoveryPolicy, error) {
+	p := DiscoveryPolicy{MaxHops: maxHops, NSFW: strings.ToLower(strings.TrimSpace(nsfw)), MinSubscribers: minSubscribers}
+	if p.MaxHops < 0 {
+		return DiscoveryPolicy{}, fmt.Errorf("max hops must not be negative")
+	}
+	if p.MinSubscribers < 0 {
+		return DiscoveryPolicy{}, fmt.Errorf("minimum subscribers must not be negative")
+	}
+	if p.NSFW == "" {
+		p.NSFW = NSFWAllow
+	}
+	if p.NSFW != NSFWAllow && p.NSFW != NSFWDeny {
+		return DiscoveryPolicy{}, fmt.Errorf("unknown NSFW policy %q (use %q or %q)", nsfw, NSFWAllow, NSFWDeny)
+	}
+	var err error
+	if p.AllowPatterns, p.allow, err = compilePatterns(allow); err != nil {
+		return DiscoveryPolicy{}, err
+	}
+	if p.DenyPatterns, p.deny, err = compilePatterns(deny); err != nil {
+		return DiscoveryPolicy{}, err
+	}
+	return p, nil
+}
+
+// ValidateDiscoveryPatterns checks that every pattern compiles and returns the
+// non-empty patterns, trimmed.
+func ValidateDiscoveryPatterns(patterns []string) ([]string, error) {
+	kept, _, err := compilePatterns(patterns)
+	return kept, err
+}
+
+func compilePatterns(patterns []string) ([]string, []*regexp.Regexp, error) {
+	kept := []string{}
+	var compiled []*regexp.Regexp
+	for _, s := range patterns {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern %q: %v", s, err)
+		}
+		kept = append(kept, s)
+		compiled = append(compiled, re)
+	}
+	return kept, compiled, nil
+}
+
+// defaultDiscoveryPolicy builds the policy from the environment configuration.
+// Invalid patterns are logged and ignored.
+func defaultDiscoveryPolicy(cfg *config.Config) DiscoveryPolicy {
+	allow, deny := splitPatterns(cfg.DiscoveryAllow), splitPatterns(cfg.DiscoveryDeny)
+	p, err := NewDiscoveryPolicy(cfg.DiscoveryMaxHops, allow, deny, cfg.DiscoveryNSFW, cfg.DiscoveryMinSubscribers)
+	if err != nil {
+		log.Printf("⚠️ Invalid discovery policy in environment, ignoring name patterns: %v", err)
+		p, _ = NewDiscoveryPolicy(cfg.DiscoveryMaxHops, nil, nil, cfg.DiscoveryNSFW, cfg.DiscoveryMinSubscribers)
+	}
+	return p
+}
+
+func splitPatterns(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// LoadDiscoveryPolicy returns the environment policy overridden by the settings
+// stored through the admin API. A stored value that cannot be used is logged and
+// the default kept.
+func LoadDiscoveryPolicy(ctx context.Context, q *db.Queries) DiscoveryPolicy {
+	def := defaultDiscoveryPolicy(config.Load())
+	maxHops, minSubs, nsfw := def.MaxHops, def.MinSubscribers, def.NSFW
+	allow, deny := def.AllowPatterns, def.DenyPatterns
+
+	if v, _ := admin.Get(ctx, q, SettingDiscoveryMaxHops); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxHops = n
+		}
+	}
+	if v, _ := admin.Get(ctx, q, SettingDiscoveryMinSubscribers); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			minSubs = n
+		}
+	}
+	if v, _ := admin.Get(ctx, q, SettingDiscoveryNSFWPolicy); v != "" {
+		nsfw = v
+	}
+	if v, _ := admin.Get(ctx, q, SettingDiscoveryAllowPatterns); v != "" {
+		if err := json.Unmarshal([]byte(v), &allow); err != nil {
+			log.Printf("⚠️ Ignoring stored %s: %v", SettingDiscoveryAllowPatterns, err)
+			allow = def.AllowPatterns
+		}
+	}
+	if v, _ := admin.Get(ctx, q, SettingDiscoveryDenyPatterns); v != "" {
+		if err := json.Unmarshal([]byte(v), &deny); err != nil {
+			log.Printf("⚠️ Ignoring stored %s: %v", SettingDiscoveryDenyPatterns, err)
+			deny = def.DenyPatterns
+		}
+	}
+
+	p, err := NewDiscoveryPolicy(maxHops, allow, deny, nsfw, minSubs)
+	if err != nil {
+		log.Printf("⚠️ Invalid stored discovery policy, using defaults: %v", err)
+		return def
+	}
+	return p
+}
+
+// CheckName returns why a subreddit found hop steps from a seed is excluded, or ""
+// if its distance and name are allowed.
+func (p DiscoveryPolicy) CheckName(name string, hop int) string {
+	if hop <= 0 {
+		return ""
+	}
+	if hop > p.MaxHops {
+		return ExcludedMaxHops
+	}
+	return p.checkPatterns(name)
+}
+
+func (p DiscoveryPolicy) checkPatterns(name string) string {
+	for _, re := range p.deny {
+		if re.MatchString(name) {
+			return ExcludedDenied
+		}
+	}
+	if len(p.allow) == 0 {
+		return ""
+	}
+	for _, re := range p.allow {
+		if re.MatchString(name) {
+			return ""
+		}
+	}
+	return ExcludedNotAllowed
+}
+
+// CheckInfo returns why a discovered subreddit with the given metadata is excluded,
+// or "" if it may be crawled.
+func (p DiscoveryPolicy) CheckInfo(over18 bool, subscribers int) string {
+	if over18 && p.NSFW == NSFWDeny {
+		return ExcludedNSFW
+	}
+	if subscribers < p.MinSubscribers {
+		return ExcludedMinSubscribers
+	}
+	return ""
+}
+
+// discoverySource describes how a subreddit was reached: the job that found it and
+// the subreddit's resulting distance from a seed.
+type discoverySource struct {
+	Hop        int
+	JobID      int32
+	EnqueuedBy string
+}
+
+// originHop returns the distance from which a subreddit's discoveries are counted:
+// they are one hop farther. A subreddit whose distance is unknown (crawled before
+// distances were recorded) counts as a seed.
+func originHop(hop sql.NullInt32) int {
+	if !hop.Valid || hop.Int32 < 0 {
+		return 0
+	}
+	return int(hop.Int32)
+}
+
+// subredditHop returns the recorded hop distance of a subreddit.
+func subredditHop(ctx context.Context, q *db.Queries, subredditID int32) (sql.NullInt32, error) {
+	var hop sql.NullInt32
+	err := q.DB().QueryRowContext(ctx, `SELECT hop_distance FROM subreddits WHERE id = $1`, subredditID).Scan(&hop)
+	return hop, err
+}
+
+// recordDiscovery stores how a subreddit was reached unless it is already known to
+// be at the same distance or closer to a seed.
+func recordDiscovery(ctx context.Context, q *db.Queries, subredditID int32, from discoverySource) error {
+	const stmt = `UPDATE subreddits SET hop_distance = $2, discovered_by_job_id = $3
+                  WHERE id = $1 AND (hop_distance IS NULL OR hop_distance > $2)`
+	var jobID any
+	if from.JobID > 0 {
+		jobID = from.JobID
+	}
+	_, err := q.DB().ExecContext(ctx, stmt, subredditID, from.Hop, jobID)
+	return err
+}
+
+// ensureSubredditByName creates a subreddit row with placeholder metadata.
+func ensureSubredditByName(ctx context.Context, q *db.Queries, name string) (int32, error) {
+	return q.EnsureSubreddit(ctx, db.EnsureSubredditParams{
+		Name:        name,
+		Title:       sql.NullString{String: name, Valid: true},
+		Description: sql.NullString{String: "", Valid: true},
+		Subscribers: sql.NullInt32{Int32: 0, Valid: true},
+	})
+}
+
+// discoverSubreddit queues a subreddit found by a crawl if the discovery policy
+// allows its distance and name. Excluded subreddits are not stored. It reports
+// whether a new crawl job was queued.
+func discoverSubreddit(ctx context.Context, q *db.Queries, policy DiscoveryPolicy, name string, from discoverySource) (bool, error) {
+	if reason := policy.CheckName(name, from.Hop); reason != "" {
+		metrics.CrawlerDiscoveryExcluded.WithLabelValues(reason).Inc()
+		return false, nil
+	}
+	id, err := ensureSubredditByName(ctx, q, name)
+	if err != nil {
+		return false, err
+	}
+	if err := recordDiscovery(ctx, q, id, from); err != nil {
+		log.Printf("⚠️ Failed to record discovery of r/%s: %v", name, err)
+	}
+	exists, err := q.CrawlJobExists(ctx, id)
+	if err != nil || exists {
+		return false, err
+	}
+	if err := EnsureJob(ctx, q, id, from.EnqueuedBy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkSubredditPolicy stops the crawl of a discovered subreddit that the current
+// policy excludes. Seeds always pass; the distance of subreddits crawled before
+// distances were recorded is not checked.
+func checkSubredditPolicy(policy DiscoveryPolicy, name string, hop sql.NullInt32, info *SubredditInfo) error {
+	if hop.Valid && hop.Int32 == 0 {
+		return nil
+	}
+	var reason string
+	if hop.Valid {
+		reason = policy.CheckName(name, int(hop.Int32))
+	} else {
+		reason = policy.checkPatterns(name)
+	}
+	if reason == "" && info != nil {
+		reason = policy.CheckInfo(info.Over18, info.Subscribers)
+	}
+	if reason == "" {
+		return nil
+	}
+	metrics.CrawlerDiscoveryExcluded.WithLabelValues(reason).Inc()
+	return fmt.Errorf("%w: r/%s (%s)", errExcludedByPolicy, name, reason)
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+func TestDiscoveryPolicyCheckName(t *testing.T) {
+	p, err := NewDiscoveryPolicy(2, []string{"^golang", "rust$"}, []string{"nsfw", "^golang_?jobs$"}, "", 0)
+	if err != nil {
+		t.Fatalf("NewDiscoveryPolicy: %v", err)
+	}
+	tests := []struct {
+		name string
+		hop  int
+		want string
+	}{
+		{"anything", 0, ""}, // seeds are always allowed
+		{"GolangNews", 1, ""},
+		{"learnrust", 2, ""},
+		{"golang", 3, ExcludedMaxHops},
+		{"golangjobs", 1, ExcludedDenied},
+		{"golang_nsfw", 1, ExcludedDenied},
+		{"python", 1, ExcludedNotAllowed},
+	}
+	for _, tt := range tests {
+		if got := p.CheckName(tt.name, tt.hop); got != tt.want {
+			t.Errorf("CheckName(%q, %d) = %q, want %q", tt.name, tt.hop, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoveryPolicyCheckInfo(t *testing.T) {
+	p, err := NewDiscoveryPolicy(3, nil, nil, "DENY", 1000)
+	if err != nil {
+		t.Fatalf("NewDiscoveryPolicy: %v", err)
+	}
+	if got := p.CheckInfo(true, 5000); got != ExcludedNSFW {
+		t.Errorf("NSFW subreddit: got %q", got)
+	}
+	if got := p.CheckInfo(false, 999); got != ExcludedMinSubscribers {
+		t.Errorf("small subreddit: got %q", got)
+	}
+	if got := p.CheckInfo(false, 1000); got != "" {
+		t.Errorf("allowed subreddit: got %q", got)
+	}
+}
+
+func TestNewDiscoveryPolicyRejectsInvalid(t *testing.T) {
+	if _, err := NewDiscoveryPolicy(-1, nil, nil, "", 0); err == nil {
+		t.Error("expected error for negative max hops")
+	}
+	if _, err := NewDiscoveryPolicy(1, nil, nil, "sometimes", 0); err == nil {
+		t.Error("expected error for unknown NSFW policy")
+	}
+	if _, err := NewDiscoveryPolicy(1, []string{"("}, nil, "", 0); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	kept, err := ValidateDiscoveryPatterns([]string{" ^a ", "", "b"})
+	if err != nil || len(kept) != 2 || kept[0] != "^a" {
+		t.Errorf("ValidateDiscoveryPatterns = %v, %v", kept, err)
+	}
+}
+
+func TestDefaultDiscoveryPolicyFromEnv(t *testing.T) {
+	t.Setenv("DISCOVERY_MAX_HOPS", "1")
+	t.Setenv("DISCOVERY_DENY", "^porn, nsfw")
+	t.Setenv("DISCOVERY_NSFW", "deny")
+	t.Setenv("DISCOVERY_MIN_SUBSCRIBERS", "50")
+	config.ResetForTest()
+	defer config.ResetForTest()
+
+	p := defaultDiscoveryPolicy(config.Load())
+	if p.MaxHops != 1 || p.NSFW != NSFWDeny || p.MinSubscribers != 50 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	if len(p.DenyPatterns) != 2 || p.CheckName("some_nsfw_sub", 1) != ExcludedDenied {
+		t.Errorf("deny patterns not applied: %v", p.DenyPatterns)
+	}
+}
+
+func TestCheckSubredditPolicy(t *testing.T) {
+	p, _ := NewDiscoveryPolicy(1, nil, []string{"^banned"}, NSFWDeny, 100)
+	nsfw := &SubredditInfo{Over18: true, Subscribers: 500}
+	small := &SubredditInfo{Subscribers: 10}
+
+	// Seeds are crawled whatever the policy says.
+	if err := checkSubredditPolicy(p, "banned_sub", sql.NullInt32{Int32: 0, Valid: true}, nsfw); err != nil {
+		t.Errorf("seed excluded: %v", err)
+	}
+
+	err := checkSubredditPolicy(p, "far", sql.NullInt32{Int32: 2, Valid: true}, &SubredditInfo{Subscribers: 500})
+	if !errors.Is(err, errExcludedByPolicy) || !IsTerminalFailure(err) || FailureReason(err) != ReasonExcluded {
+		t.Errorf("expected terminal exclusion beyond max hops, got %v", err)
+	}
+	if err := checkSubredditPolicy(p, "nsfw", sql.NullInt32{Int32: 1, Valid: true}, nsfw); !errors.Is(err, errExcludedByPolicy) {
+		t.Errorf("expected NSFW exclusion, got %v", err)
+	}
+
+	// Subreddits crawled before distances were recorded skip the hop check only.
+	if err := checkSubredditPolicy(p, "legacy", sql.NullInt32{}, &SubredditInfo{Subscribers: 500}); err != nil {
+		t.Errorf("legacy subreddit excluded: %v", err)
+	}
+	if err := checkSubredditPolicy(p, "legacy", sql.NullInt32{}, small); !errors.Is(err, errExcludedByPolicy) {
+		t.Errorf("expected small legacy subreddit to be excluded, got %v", err)
+	}
+}
+
+func TestOriginHop(t *testing.T) {
+	if got := originHop(sql.NullInt32{}); got != 0 {
+		t.Errorf("unknown hop: got %d", got)
+	}
+	if got := originHop(sql.NullInt32{Int32: 2, Valid: true}); got != 2 {
+		t.Errorf("hop 2: got %d", got)
+	}
+}
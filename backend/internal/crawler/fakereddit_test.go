@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/fakereddit"
+)
+
+// useFakeReddit points the crawler at a fake Reddit server for the test.
+func useFakeReddit(t *testing.T, w *fakereddit.World, opts fakereddit.ServerOptions) {
+	t.Helper()
+	srv := httptest.NewServer(fakereddit.NewServer(w, opts))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("REDDIT_API_BASE_URL", srv.URL)
+	t.Setenv("REDDIT_CLIENT_ID", opts.ClientID)
+	t.Setenv("REDDIT_CLIENT_SECRET", opts.ClientSecret)
+	t.Setenv("CRAWLER_RPS", "1000")
+	t.Setenv("CRAWLER_BURST_SIZE", "1000")
+	config.ResetForTest()
+	ResetLimiterForTest()
+
+	oldClient, oldTokens, oldCreds := httpClient, globalTokenManager, redditCredentials
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+	globalTokenManager = &tokenManager{clientID: opts.ClientID, clientSecret: opts.ClientSecret}
+	redditCredentials = newCredentialPool()
+	t.Cleanup(func() {
+		globalTokenManager.stop()
+		httpClient, globalTokenManager, redditCredentials = oldClient, oldTokens, oldCreds
+		config.ResetForTest()
+		ResetLimiterForTest()
+	})
+}
+
+func TestCrawlAgainstFakeReddit(t *testing.T) {
+	wopts := fakereddit.DefaultOptions()
+	wopts.Subreddits = 3
+	wopts.Users = 50
+	wopts.PostsPerSub = 300
+	wopts.CommentsPerPost = 20
+	world := fakereddit.NewWorld(wopts)
+	sopts := fakereddit.DefaultServerOptions()
+	sopts.ClientID, sopts.ClientSecret = "fake-id", "fake-secret"
+	useFakeReddit(t, world, sopts)
+
+	info, posts, err := CrawlSubreddit("technology")
+	if err != nil {
+		t.Fatalf("crawl subreddit: %v", err)
+	}
+	if info.Title != world.Subreddit("technology").Title || len(posts) == 0 {
+		t.Fatalf("unexpected crawl result %+v with %d posts", info, len(posts))
+	}
+	for _, p := range posts {
+		if world.Post(p.ID) == nil {
+			t.Fatalf("post %s is not in the fake world", p.ID)
+		}
+	}
+
+	var busiest *fakereddit.Post
+	for _, p := range world.Subreddit("technology").Posts {
+		if busiest == nil || len(p.Comments) > len(busiest.Comments) {
+			busiest = p
+		}
+	}
+	t.Setenv("MAX_COMMENTS_PER_POST", "1000")
+	t.Setenv("MAX_COMMENT_DEPTH", "50")
+	t.Setenv("MAX_MORECHILDREN_REQUESTS", "20")
+	comments, _, err := CrawlCommentsExpanded(busiest.ID)
+	if err != nil {
+		t.Fatalf("crawl comments: %v", err)
+	}
+	if len(comments) != len(busiest.Comments) {
+		t.Errorf("crawled %d comments, post has %d", len(comments), len(busiest.Comments))
+	}
+
+	subs, err := FetchUserSubreddits(busiest.Author, 100)
+	if err != nil || len(subs) == 0 {
+		t.Errorf("user subreddits: %v %v", subs, err)
+	}
+}
+
+func TestCrawlAndStoreCommentsStopsOnOpenBreaker(t *testing.T) {
+	world := fakereddit.NewWorld(fakereddit.DefaultOptions())
+	sopts := fakereddit.DefaultServerOptions()
+	sopts.ClientID, sopts.ClientSecret = "fake-id", "fake-secret"
+	useFakeReddit(t, world, sopts)
+	withTestBreakers(t)
+
+	for i := 0; i < 2; i++ {
+		doWithBreaker(EndpointComments, func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+		})
+	}
+
+	// The posts are never read from the database: the job stops at the first fetch.
+	posts := []Post{
+		{ID: "abc123", Permalink: "/r/technology/comments/abc123/first/"},
+		{ID: "def456", Permalink: "/r/technology/comments/def456/second/"},
+	}
+	inserted := map[string]bool{"abc123": true, "def456": true}
+	err := crawlAndStoreComments(context.Background(), nil, 1, posts, 5, inserted)
+	if !errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen so the job is released, got %v", err)
+	}
+}
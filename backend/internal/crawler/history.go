@@ -0,0 +1,246 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
+)
+
+// Removal states stored in posts.removed_state and comments.removed_state.
+const (
+	RemovalDeleted = "deleted" // deleted by its author
+	RemovalRemoved = "removed" // removed by moderators, admins or Reddit's filters
+)
+
+// Content types of the history tables.
+const (
+	contentPost    = "post"
+	contentComment = "comment"
+)
+
+// Events recorded in content_events.
+const (
+	eventEdited   = "edited"
+	eventRestored = "restored"
+)
+
+// removalState classifies a post as Reddit returned it; "" means the post is live.
+func (p Post) removalState() string {
+	switch {
+	case p.RemovedByCategory == "deleted" || p.Selftext == "[deleted]":
+		return RemovalDeleted
+	case p.RemovedByCategory != "" || p.Selftext == "[removed]":
+		return RemovalRemoved
+	}
+	return ""
+}
+
+// commentRemovalState classifies a comment body; "" means the comment is live.
+func commentRemovalState(body string) string {
+	switch strings.TrimSpace(body) {
+	case "[deleted]":
+		return RemovalDeleted
+	case "[removed]":
+		return RemovalRemoved
+	}
+	return ""
+}
+
+// contentState is what the database holds for a post or comment.
+type contentState struct {
+	title   string
+	text    string // selftext or body
+	score   sql.NullInt32
+	removed string
+}
+
+// contentObservation is a post or comment as the current crawl saw it.
+type contentObservation struct {
+	kind        string
+	id          string
+	subredditID int32
+	title       string // posts only
+	text        string
+	score       int
+	removed     string
+}
+
+func postObservation(p Post, subredditID int32) contentObservation {
+	return contentObservation{kind: contentPost, id: p.ID, subredditID: subredditID, title: p.Title, text: p.Selftext, score: p.Score, removed: p.removalState()}
+}
+
+func commentObservation(c Comment, subredditID int32) contentObservation {
+	return contentObservation{kind: contentComment, id: c.ID, subredditID: subredditID, text: c.Body, score: c.Score, removed: c.Removed}
+}
+
+// contentEvent is one row of content_events.
+type contentEvent struct {
+	event    string // edited, deleted, removed or restored
+	field    string
+	oldValue string
+	newValue string
+	diff     string
+}
+
+// compareContent lists the events between the stored state and a new observation
+// and reports whether the score changed. Edits are only detected while the content
+// is live on both sides; a removal keeps the last live text in the database.
+func compareContent(prev contentState, obs contentObservation) ([]contentEvent, bool) {
+	scoreChanged := !prev.score.Valid || int(prev.score.Int32) != obs.score
+	switch {
+	case prev.removed != obs.removed && obs.removed != "":
+		return []contentEvent{{event: obs.removed}}, scoreChanged
+	case prev.removed != obs.removed:
+		return []contentEvent{{event: eventRestored}}, scoreChanged
+	case obs.removed != "":
+		return nil, scoreChanged
+	}
+
+	var events []contentEvent
+	edited := func(field, old, new string) {
+		if strings.TrimSpace(old) == strings.TrimSpace(new) {
+			return
+		}
+		events = append(events, contentEvent{event: eventEdited, field: field, oldValue: old, newValue: new, diff: lineDiff(old, new)})
+	}
+	if obs.kind == contentPost {
+		edited("title", prev.title, obs.title)
+		edited("selftext", prev.text, obs.text)
+	} else {
+		edited("body", prev.text, obs.text)
+	}
+	return events, scoreChanged
+}
+
+// lineDiff shows an edit as the changed lines of old (-) and new (+), after an
+// @@ header locating them, with the unchanged lines before and after left out.
+func lineDiff(old, new string) string {
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", pre+1, len(a)-pre-suf, pre+1, len(b)-pre-suf)
+	for _, l := range a[pre : len(a)-suf] {
+		sb.WriteString("-" + l + "\n")
+	}
+	for _, l := range b[pre : len(b)-suf] {
+		sb.WriteString("+" + l + "\n")
+	}
+	return sb.String()
+}
+
+// historyTables maps content types to their tables.
+var historyTables = map[string]string{contentPost: "posts", contentComment: "comments"}
+
+// loadContentStates returns the stored state of the given posts or comments that
+// exist in the database.
+func loadContentStates(ctx context.Context, q *db.Queries, kind string, ids []string) (map[string]contentState, error) {
+	states := make(map[string]contentState, len(ids))
+	if len(ids) == 0 {
+		return states, nil
+	}
+	stmt := `SELECT id, COALESCE(title, ''), COALESCE(selftext, ''), score, COALESCE(removed_state, '') FROM posts WHERE id = ANY($1)`
+	if kind == contentComment {
+		stmt = `SELECT id, '', COALESCE(body, ''), score, COALESCE(removed_state, '') FROM comments WHERE id = ANY($1)`
+	}
+	rows, err := q.DB().QueryContext(ctx, stmt, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var s contentState
+		if err := rows.Scan(&id, &s.title, &s.text, &s.score, &s.removed); err != nil {
+			return nil, err
+		}
+		states[id] = s
+	}
+	return states, rows.Err()
+}
+
+// recordObservation records what changed on content that is already stored:
+// a score snapshot when the score moved, and edit or removal events. Removed
+// content is only marked, so the stored text keeps its last live version; live
+// content is upserted by the caller afterwards.
+func recordObservation(ctx context.Context, q *db.Queries, obs contentObservation, prev contentState) error {
+	events, scoreChanged := compareContent(prev, obs)
+	if scoreChanged {
+		if err := insertScoreSnapshot(ctx, q, obs); err != nil {
+			return err
+		}
+	}
+	table := historyTables[obs.kind]
+	for _, e := range events {
+		const ins = `INSERT INTO content_events (content_type, content_id, subreddit_id, event, field, old_value, new_value, diff)
+                     VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))`
+		if _, err := q.DB().ExecContext(ctx, ins, obs.kind, obs.id, obs.subredditID, e.event, e.field, e.oldValue, e.newValue, e.diff); err != nil {
+			return err
+		}
+		metrics.CrawlerContentEvents.WithLabelValues(obs.kind, e.event).Inc()
+
+		var stmt string
+		args := []any{obs.id}
+		switch e.event {
+		case eventEdited:
+			stmt = `UPDATE ` + table + ` SET edited_at = now() WHERE id = $1`
+		case eventRestored:
+			stmt = `UPDATE ` + table + ` SET removed_state = NULL, removed_at = NULL WHERE id = $1`
+		default:
+			stmt = `UPDATE ` + table + ` SET removed_state = $2, removed_at = now(), score = $3, last_seen = now() WHERE id = $1`
+			args = append(args, e.event, obs.score)
+		}
+		if _, err := q.DB().ExecContext(ctx, stmt, args...); err != nil {
+			return err
+		}
+	}
+	if obs.removed != "" && len(events) == 0 && scoreChanged {
+		_, err := q.DB().ExecContext(ctx, `UPDATE `+table+` SET score = $2, last_seen = now() WHERE id = $1`, obs.id, obs.score)
+		return err
+	}
+	return nil
+}
+
+// recordFirstObservation records the first score snapshot of newly stored
+// content and marks it when it was already removed the first time it was seen.
+func recordFirstObservation(ctx context.Context, q *db.Queries, obs contentObservation) error {
+	if err := insertScoreSnapshot(ctx, q, obs); err != nil {
+		return err
+	}
+	if obs.removed == "" {
+		return nil
+	}
+	_, err := q.DB().ExecContext(ctx, `UPDATE `+historyTables[obs.kind]+` SET removed_state = $2, removed_at = now() WHERE id = $1`, obs.id, obs.removed)
+	return err
+}
+
+func insertScoreSnapshot(ctx context.Context, q *db.Queries, obs contentObservation) error {
+	const ins = `INSERT INTO content_score_snapshots (content_type, content_id, subreddit_id, score)
+                 VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	_, err := q.DB().ExecContext(ctx, ins, obs.kind, obs.id, obs.subredditID, obs.score)
+	return err
+}
+
+// recordFirstCommentObservation records the first observation of a comment just
+// stored, if it had not been stored before.
+func recordFirstCommentObservation(ctx context.Context, q *db.Queries, fresh map[string]contentObservation, id string) {
+	obs, ok := fresh[id]
+	if !ok {
+		return
+	}
+	if err := recordFirstObservation(ctx, q, obs); err != nil {
+		log.Printf("⚠️ Failed to record history of comment %s: %v", id, err)
+	}
+}
@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestRemovalState(t *testing.T) {
+	cases := []struct {
+		post Post
+		want string
+	}{
+		{Post{Author: "a", Selftext: "hello"}, ""},
+		{Post{Author: "[deleted]", Selftext: "[deleted]"}, RemovalDeleted},
+		{Post{Author: "[deleted]", RemovedByCategory: "deleted"}, RemovalDeleted},
+		{Post{Author: "a", Selftext: "[removed]"}, RemovalRemoved},
+		{Post{Author: "a", RemovedByCategory: "moderator"}, RemovalRemoved},
+	}
+	for _, tc := range cases {
+		if got := tc.post.removalState(); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.post, got, tc.want)
+		}
+	}
+	if commentRemovalState("[removed]") != RemovalRemoved || commentRemovalState("[deleted]") != RemovalDeleted || commentRemovalState("fine") != "" {
+		t.Error("unexpected comment removal states")
+	}
+}
+
+func TestCommentFromDataKeepsRemovedPlaceholders(t *testing.T) {
+	c, ok := commentFromData(map[string]interface{}{"id": "c1", "author": "[deleted]", "body": "[removed]", "score": float64(7)}, 0)
+	if !ok || c.Removed != RemovalRemoved || c.Score != 7 {
+		t.Errorf("got %+v, %v", c, ok)
+	}
+	if _, ok := commentFromData(map[string]interface{}{"id": "c2", "author": "[deleted]", "body": "text kept after account deletion"}, 0); ok {
+		t.Error("comments of deleted accounts without a placeholder body should still be skipped")
+	}
+}
+
+func TestCompareContent(t *testing.T) {
+	live := contentState{title: "Title", text: "line one\nline two", score: sql.NullInt32{Int32: 5, Valid: true}}
+	obs := contentObservation{kind: contentPost, title: "Title", text: "line one\nline two", score: 5}
+
+	if events, scoreChanged := compareContent(live, obs); len(events) != 0 || scoreChanged {
+		t.Errorf("unchanged content: %+v, score changed %v", events, scoreChanged)
+	}
+
+	edited := obs
+	edited.text = "line one\nline 2\nline three"
+	edited.score = 9
+	events, scoreChanged := compareContent(live, edited)
+	if !scoreChanged || len(events) != 1 || events[0].event != eventEdited || events[0].field != "selftext" {
+		t.Fatalf("edit: %+v, score changed %v", events, scoreChanged)
+	}
+	if want := "@@ -2,1 +2,2 @@\n-line two\n+line 2\n+line three\n"; events[0].diff != want {
+		t.Errorf("diff = %q, want %q", events[0].diff, want)
+	}
+
+	removed := obs
+	removed.text, removed.removed = "[removed]", RemovalRemoved
+	if events, _ := compareContent(live, removed); len(events) != 1 || events[0].event != RemovalRemoved {
+		t.Errorf("removal: %+v", events)
+	}
+	// Still removed on the next crawl: no new event, and the placeholder is not an edit.
+	stillRemoved := live
+	stillRemoved.removed = RemovalRemoved
+	if events, _ := compareContent(stillRemoved, removed); len(events) != 0 {
+		t.Errorf("repeated removal: %+v", events)
+	}
+	if events, _ := compareContent(stillRemoved, obs); len(events) != 1 || events[0].event != eventRestored {
+		t.Errorf("restore: %+v", events)
+	}
+}
+
+func TestIntegration_RecordObservation(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	ctx := context.Background()
+
+	var subID, userID int32
+	if err := conn.QueryRowContext(ctx, `INSERT INTO subreddits (name) VALUES ('history_test_sub') ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id`).Scan(&subID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.UpsertUser(ctx, "history_test_user"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := q.GetUser(ctx, "history_test_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID = user.ID
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM content_events WHERE content_id = 'histp1'`)
+		conn.Exec(`DELETE FROM content_score_snapshots WHERE content_id = 'histp1'`)
+		conn.Exec(`DELETE FROM posts WHERE id = 'histp1'`)
+	})
+
+	post := Post{ID: "histp1", Author: "history_test_user", Title: "t", Selftext: "first", Score: 1}
+	if err := q.UpsertPost(ctx, ToUpsertPostParams(post, subID, userID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordFirstObservation(ctx, q, postObservation(post, subID)); err != nil {
+		t.Fatal(err)
+	}
+
+	post.Selftext, post.Score = "[removed]", 3
+	states, err := loadContentStates(ctx, q, contentPost, []string{"histp1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordObservation(ctx, q, postObservation(post, subID), states["histp1"]); err != nil {
+		t.Fatal(err)
+	}
+
+	var removedState, selftext string
+	var score, snapshots, events int
+	conn.QueryRowContext(ctx, `SELECT removed_state, selftext, score FROM posts WHERE id = 'histp1'`).Scan(&removedState, &selftext, &score)
+	conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM content_score_snapshots WHERE content_id = 'histp1'`).Scan(&snapshots)
+	conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM content_events WHERE content_id = 'histp1' AND event = 'removed'`).Scan(&events)
+	if removedState != RemovalRemoved || selftext != "first" || score != 3 {
+		t.Errorf("post row: state %q, selftext %q, score %d", removedState, selftext, score)
+	}
+	if snapshots != 2 || events != 1 {
+		t.Errorf("%d snapshots and %d removal events, want 2 and 1", snapshots, events)
+	}
+}
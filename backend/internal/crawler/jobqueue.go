@@ -3,14 +3,21 @@ package crawler
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/redditapi"
 )
 
+// JobStatusTerminal marks a job that failed permanently (e.g. a private or banned
+// subreddit). Terminal jobs are never requeued automatically; only an admin retry
+// moves them back to the queue.
+const JobStatusTerminal = "terminal"
+
 // EnsureJob enqueues a job for subredditID if absent, or resets to queued if failed/stale.
 func EnsureJob(ctx context.Context, q *db.Queries, subredditID int32, enqueuedBy string) error {
 	// Try insert queued; ON CONFLICT DO NOTHING is already in EnqueueCrawlJob.
@@ -22,7 +29,7 @@ func EnsureJob(ctx context.Context, q *db.Queries, subredditID int32, enqueuedBy
 
 // PromoteNext attempts to pick the oldest queued job by creation time.
 func PromoteNext(ctx context.Context, q *db.Queries) (db.CrawlJob, error) {
-	const qstr = `SELECT id, subreddit_id, status, retries, last_attempt, duration_ms, enqueued_by, created_at, updated_at, priority
+	const qstr = `SELECT id, COALESCE(subreddit_id, 0), status, retries, last_attempt, duration_ms, enqueued_by, created_at, updated_at, priority
                   FROM crawl_jobs
                   WHERE status='queued'
                   ORDER BY priority DESC, created_at ASC
@@ -46,8 +53,12 @@ func ResetIncompleteJobs(ctx context.Context, q *db.Queries, olderThan time.Dura
 }
 
 // RequeueStaleSubreddits enqueues subs with last_seen older than ttl.
+// Subreddits whose job ended in the terminal state are skipped.
 func RequeueStaleSubreddits(ctx context.Context, q *db.Queries, ttl time.Duration) error {
-	const sel = `SELECT id FROM subreddits WHERE last_seen < now() - $1::interval ORDER BY created_at ASC`
+	const sel = `SELECT s.id FROM subreddits s
+                 WHERE s.last_seen < now() - $1::interval
+                   AND NOT EXISTS (SELECT 1 FROM crawl_jobs cj WHERE cj.subreddit_id = s.id AND cj.status = 'terminal')
+                 ORDER BY s.created_at ASC`
 	rows, err := q.DB().QueryContext(ctx, sel, ttl.String())
 	if err != nil {
 		return err
@@ -72,35 +83,50 @@ func BumpPriority(ctx context.Context, q *db.Queries, subredditID int32, delta i
 	return err
 }
 
-// ClaimNextJob selects the highest-priority queued job and marks it crawling atomically.
-// Updated to respect visibility timeout.
-func ClaimNextJob(ctx context.Context, q *db.Queries) (db.CrawlJob, error) {
+// ClaimNextJob selects the highest-priority visible job and leases it to workerID atomically.
+// A job is visible when it is queued and its visible_at has passed, or when it is crawling
+// under a lease whose holder stopped sending heartbeats (e.g. a crashed crawler process).
+// While crawling, visible_at is the lease expiry; holders extend it with RenewJobLease.
+func ClaimNextJob(ctx context.Context, q *db.Queries, workerID string, lease time.Duration) (Job, error) {
 	rawDB := q.DB()
 	sqlDB, ok := rawDB.(*sql.DB)
 	if !ok {
-		return db.CrawlJob{}, fmt.Errorf("underlying DB does not support transactions")
+		return Job{}, fmt.Errorf("underlying DB does not support transactions")
 	}
 	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return db.CrawlJob{}, err
+		return Job{}, err
 	}
 	defer func() {
 		if err != nil {
 			_ = tx.Rollback()
 		}
 	}()
-	var j db.CrawlJob
-	// Select job that is visible (respecting visibility timeout)
-	sel := `SELECT id, subreddit_id, status, retries, last_attempt, duration_ms, enqueued_by, created_at, updated_at
+	var (
+		j       db.CrawlJob
+		jobType string
+		payload []byte
+	)
+	leased := true
+	// Select job that is visible (respecting visibility timeout and expired leases)
+	sel := `SELECT id, COALESCE(subreddit_id, 0), status, retries, last_attempt, duration_ms, enqueued_by, created_at, updated_at, job_type, payload
             FROM crawl_jobs
-            WHERE status='queued' AND (visible_at IS NULL OR visible_at <= now())
+            WHERE (status='queued' AND (visible_at IS NULL OR visible_at <= now()))
+               OR (status='crawling' AND lease_owner IS NOT NULL AND visible_at < now())
             ORDER BY priority DESC, created_at ASC
             FOR UPDATE SKIP LOCKED
             LIMIT 1`
 	row := tx.QueryRowContext(ctx, sel)
-	if err = row.Scan(&j.ID, &j.SubredditID, &j.Status, &j.Retries, &j.LastAttempt, &j.DurationMs, &j.EnqueuedBy, &j.CreatedAt, &j.UpdatedAt); err != nil {
-		// Fallback if visibility_at column is missing (backward compatibility)
+	if err = row.Scan(&j.ID, &j.SubredditID, &j.Status, &j.Retries, &j.LastAttempt, &j.DurationMs, &j.EnqueuedBy, &j.CreatedAt, &j.UpdatedAt, &jobType, &payload); err != nil {
+		// Fallback if lease/visibility columns are missing (backward compatibility)
 		if pqErr, ok := err.(*pq.Error); ok && string(pqErr.Code) == "42703" {
+			// The failed statement aborted the transaction; start over without leases
+			// (such old schemas only hold subreddit jobs).
+			_ = tx.Rollback()
+			if tx, err = sqlDB.BeginTx(ctx, &sql.TxOptions{}); err != nil {
+				return Job{}, err
+			}
+			leased = false
 			sel = `SELECT id, subreddit_id, status, retries, last_attempt, duration_ms, enqueued_by, created_at, updated_at
                    FROM crawl_jobs
                    WHERE status='queued'
@@ -111,36 +137,91 @@ func ClaimNextJob(ctx context.Context, q *db.Queries) (db.CrawlJob, error) {
 			if err = row.Scan(&j.ID, &j.SubredditID, &j.Status, &j.Retries, &j.LastAttempt, &j.DurationMs, &j.EnqueuedBy, &j.CreatedAt, &j.UpdatedAt); err != nil {
 				if err == sql.ErrNoRows {
 					_ = tx.Rollback()
-					return db.CrawlJob{}, sql.ErrNoRows
+					return Job{}, sql.ErrNoRows
 				}
 				_ = tx.Rollback()
-				return db.CrawlJob{}, err
+				return Job{}, err
 			}
 		} else {
 			if err == sql.ErrNoRows {
 				_ = tx.Rollback()
-				return db.CrawlJob{}, sql.ErrNoRows
+				return Job{}, sql.ErrNoRows
 			}
 			_ = tx.Rollback()
-			return db.CrawlJob{}, err
+			return Job{}, err
 		}
 	}
-	if _, err = tx.ExecContext(ctx, `UPDATE crawl_jobs SET status='crawling', last_attempt=now(), updated_at=now() WHERE id=$1`, j.ID); err != nil {
+	if leased {
+		_, err = tx.ExecContext(ctx, `UPDATE crawl_jobs
+              SET status='crawling',
+                  lease_owner=$2,
+                  heartbeat_at=now(),
+                  visible_at=now() + $3::interval,
+                  last_attempt=now(),
+                  updated_at=now()
+              WHERE id=$1`, j.ID, workerID, lease.String())
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE crawl_jobs SET status='crawling', last_attempt=now(), updated_at=now() WHERE id=$1`, j.ID)
+	}
+	if err != nil {
 		_ = tx.Rollback()
-		return db.CrawlJob{}, err
+		return Job{}, err
 	}
 	if err = tx.Commit(); err != nil {
-		return db.CrawlJob{}, err
+		return Job{}, err
 	}
 	j.Status = "crawling"
-	return j, nil
+	job := decodeJob(j, jobType, payload)
+	if leased {
+		job.LeaseOwner = workerID
+	}
+	return job, nil
+}
+
+// RenewJobLease extends workerID's lease on a crawling job by lease from now.
+// It returns false when the lease is no longer held, e.g. because it expired and
+// another worker reclaimed the job; the caller should abandon the job in that case.
+func RenewJobLease(ctx context.Context, q *db.Queries, jobID int32, workerID string, lease time.Duration) (bool, error) {
+	const stmt = `UPDATE crawl_jobs
+              SET visible_at = now() + $3::interval,
+                  heartbeat_at = now(),
+                  updated_at = now()
+              WHERE id = $1 AND lease_owner = $2 AND status = 'crawling'`
+	res, err := q.DB().ExecContext(ctx, stmt, jobID, workerID, lease.String())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// errLeaseLost is returned when a job finished after its lease expired and another
+// worker reclaimed it; the job's status then belongs to the new holder.
+var errLeaseLost = errors.New("job lease lost")
+
+// ReleaseJob hands a crawling job held by workerID back to the queue so it can be
+// claimed immediately by another worker. Used when a worker shuts down mid-job.
+func ReleaseJob(ctx context.Context, q *db.Queries, jobID int32, workerID string) error {
+	const stmt = `UPDATE crawl_jobs
+              SET status = 'queued',
+                  visible_at = now(),
+                  updated_at = now()
+              WHERE id = $1 AND lease_owner = $2 AND status = 'crawling'`
+	_, err := q.DB().ExecContext(ctx, stmt, jobID, workerID)
+	return err
 }
 
 // CalculateRetryDelay calculates the next retry delay with exponential backoff and jitter
 func CalculateRetryDelay(retryCount int32) time.Duration {
 	// Base delay: 1 minute
-	baseDelay := 1 * time.Minute
+	return retryDelayFrom(1*time.Minute, retryCount)
+}
 
+// retryDelayFrom is CalculateRetryDelay with the base delay of a job type's retry policy.
+func retryDelayFrom(baseDelay time.Duration, retryCount int32) time.Duration {
 	// Exponential backoff: 2^retryCount * baseDelay
 	// Capped at 24 hours
 	maxDelay := 24 * time.Hour
@@ -170,7 +251,93 @@ func MarkJobFailedWithRetry(ctx context.Context, q *db.Queries, jobID int32, ret
 	return err
 }
 
-// RequeueRetryableJobs finds failed jobs ready to retry and requeues them
+// FailureReason maps a crawl error to a stable reason string. Reddit API errors use
+// their classified type; other errors are either transport failures or internal ones.
+func FailureReason(err error) string {
+	if apiErr, ok := redditapi.AsAPIError(err); ok {
+		return apiErr.Reason()
+	}
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, errInvalidJob) {
+		return ReasonInvalidJob
+	}
+	if errors.Is(err, errExcludedByPolicy) {
+		return ReasonExcluded
+	}
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) {
+		return redditapi.ReasonNetwork
+	}
+	return redditapi.ReasonUnknown
+}
+
+// ReasonInvalidJob is the failure reason of jobs whose payload is invalid.
+const ReasonInvalidJob = "invalid_job"
+
+// ReasonExcluded is the failure reason of subreddit jobs stopped by the discovery
+// policy.
+const ReasonExcluded = "excluded"
+
+// IsTerminalFailure reports whether err says the subreddit is private, banned or
+// quarantined, or is an invalid job or a subreddit excluded by the discovery policy,
+// for which retrying would not help. Other Reddit errors, including bare 403s and
+// 400s, are retried with backoff.
+func IsTerminalFailure(err error) bool {
+	if errors.Is(err, errInvalidJob) || errors.Is(err, errExcludedByPolicy) {
+		return true
+	}
+	apiErr, ok := redditapi.AsAPIError(err)
+	return ok && redditapi.IsUnavailableSubreddit(apiErr)
+}
+
+// RecordJobFailure stores the failure reason for a job. Terminal failures move the
+// job to the terminal state; everything else is marked failed and scheduled for retry
+// with exponential backoff from the base delay of the job type. It returns the
+// recorded reason.
+func RecordJobFailure(ctx context.Context, q *db.Queries, jobID int32, cause error) (string, error) {
+	reason := FailureReason(cause)
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	if IsTerminalFailure(cause) {
+		const stmt = `UPDATE crawl_jobs
+              SET status = 'terminal',
+                  failure_reason = $2,
+                  last_error = $3,
+                  retries = retries + 1,
+                  next_retry_at = NULL,
+                  updated_at = now()
+              WHERE id = $1`
+		_, err := q.DB().ExecContext(ctx, stmt, jobID, reason, msg)
+		return reason, err
+	}
+
+	var (
+		retryCount int32
+		jobType    string
+	)
+	if err := q.DB().QueryRowContext(ctx, `SELECT COALESCE(retry_count, 0), job_type FROM crawl_jobs WHERE id = $1`, jobID).Scan(&retryCount, &jobType); err != nil {
+		return reason, err
+	}
+	const stmt = `UPDATE crawl_jobs
+              SET status = 'failed',
+                  failure_reason = $2,
+                  last_error = $3,
+                  retries = retries + 1,
+                  retry_count = retry_count + 1,
+                  next_retry_at = $4,
+                  updated_at = now()
+              WHERE id = $1`
+	_, err := q.DB().ExecContext(ctx, stmt, jobID, reason, msg, time.Now().Add(retryDelayFrom(policyFor(jobType).RetryBase, retryCount)))
+	return reason, err
+}
+
+// RequeueRetryableJobs finds failed jobs ready to retry and requeues them. A typed
+// job is not retried once a newer job with the same identity was enqueued, or while
+// another one is active; the dedupe index allows only one active job per identity.
 func RequeueRetryableJobs(ctx context.Context, q *db.Queries) error {
 	const stmt = `UPDATE crawl_jobs
               SET status = 'queued',
@@ -179,7 +346,13 @@ func RequeueRetryableJobs(ctx context.Context, q *db.Queries) error {
               WHERE status = 'failed' 
                 AND next_retry_at IS NOT NULL 
                 AND next_retry_at <= now()
-                AND (retry_count < max_retries OR max_retries IS NULL)`
+                AND (retry_count < max_retries OR max_retries IS NULL)
+                AND NOT EXISTS (
+                    SELECT 1 FROM crawl_jobs newer
+                    WHERE newer.job_type = crawl_jobs.job_type
+                      AND newer.dedupe_key = crawl_jobs.dedupe_key
+                      AND newer.id <> crawl_jobs.id
+                      AND (newer.id > crawl_jobs.id OR newer.status IN ('queued', 'crawling')))`
 	_, err := q.DB().ExecContext(ctx, stmt)
 	return err
 }
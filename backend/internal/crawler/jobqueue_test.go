@@ -1,8 +1,13 @@
 package crawler
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/redditapi"
 )
 
 func TestCalculateRetryDelay(t *testing.T) {
@@ -53,3 +58,34 @@ func TestCalculateRetryDelay_Capped(t *testing.T) {
 		}
 	}
 }
+
+func TestFailureReasonAndTerminal(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		reason   string
+		terminal bool
+	}{
+		{"private", fmt.Errorf("wrap: %w", &redditapi.APIError{Type: redditapi.ErrorPrivateSubreddit}), redditapi.ReasonPrivate, true},
+		{"banned", &redditapi.APIError{Type: redditapi.ErrorBannedSubreddit}, redditapi.ReasonBanned, true},
+		{"quarantined", &redditapi.APIError{Type: redditapi.ErrorQuarantined}, redditapi.ReasonQuarantined, true},
+		{"not found", &redditapi.APIError{Type: redditapi.ErrorNotFound}, redditapi.ReasonNotFound, false},
+		{"forbidden", &redditapi.APIError{Type: redditapi.ErrorForbidden, StatusCode: 403}, redditapi.ReasonForbidden, false},
+		{"bad request", &redditapi.APIError{Type: redditapi.ErrorBadRequest, StatusCode: 400}, redditapi.ReasonBadRequest, false},
+		{"rate limited", &redditapi.APIError{Type: redditapi.ErrorRateLimited, Retryable: true}, redditapi.ReasonRateLimited, false},
+		{"server error", &redditapi.APIError{Type: redditapi.ErrorServerError, Retryable: true}, redditapi.ReasonServerError, false},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, redditapi.ReasonNetwork, false},
+		{"internal", errors.New("db down"), redditapi.ReasonUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailureReason(tt.err); got != tt.reason {
+				t.Errorf("FailureReason() = %q, want %q", got, tt.reason)
+			}
+			if got := IsTerminalFailure(tt.err); got != tt.terminal {
+				t.Errorf("IsTerminalFailure() = %v, want %v", got, tt.terminal)
+			}
+		})
+	}
+}
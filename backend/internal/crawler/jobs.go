@@ -3,10 +3,14 @@ package crawler
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
@@ -14,6 +18,7 @@ import (
 	"github.com/onnwee/reddit-cluster-map/backend/internal/utils"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -23,28 +28,37 @@ var (
 	DefaultSubs          = utils.GetEnvAsSlice("DEFAULT_SUBREDDITS", []string{"AskReddit", "worldnews", "technology", "funny", "gaming"}, ",")
 )
 
-func handleJob(ctx context.Context, q *db.Queries, job db.CrawlJob) error {
+// jobHandlers maps each job type to the function that runs it. Handlers return an
+// error to fail the attempt; handleJob records the outcome.
+var jobHandlers = map[string]func(ctx context.Context, q *db.Queries, job Job) error{
+	JobTypeSubreddit: handleSubredditJob,
+	JobTypeUser:      handleUserJob,
+	JobTypePost:      handlePostJob,
+	JobTypeSearch:    handleSearchJob,
+}
+
+func handleJob(ctx context.Context, q *db.Queries, job Job) error {
 	ctx, span := tracing.StartSpan(ctx, "crawler.handleJob")
 	defer span.End()
 
 	span.SetAttributes(
 		attribute.Int("job_id", int(job.ID)),
-		attribute.Int("subreddit_id", int(job.SubredditID)),
+		attribute.String("job_type", job.Type),
 	)
 
 	startTime := time.Now()
 	var jobStatus string
 	defer func() {
 		duration := time.Since(startTime).Seconds()
-		metrics.CrawlerJobDuration.WithLabelValues(jobStatus).Observe(duration)
-		metrics.CrawlerJobsTotal.WithLabelValues(jobStatus).Inc()
+		metrics.CrawlerJobDuration.WithLabelValues(job.Type, jobStatus).Observe(duration)
+		metrics.CrawlerJobsTotal.WithLabelValues(job.Type, jobStatus).Inc()
 		span.SetAttributes(
 			attribute.String("job_status", jobStatus),
 			attribute.Float64("duration_seconds", duration),
 		)
 	}()
 
-	logger.InfoContext(ctx, "Starting crawl job", "job_id", job.ID)
+	logger.InfoContext(ctx, "Starting crawl job", "job_id", job.ID, "job_type", job.Type)
 
 	// Update job status to crawling
 	if err := q.MarkCrawlJobStarted(ctx, job.ID); err != nil {
@@ -53,29 +67,103 @@ func handleJob(ctx context.Context, q *db.Queries, job db.CrawlJob) error {
 		return err
 	}
 
+	handler, ok := jobHandlers[job.Type]
+	if !ok {
+		handler = func(context.Context, *db.Queries, Job) error {
+			return fmt.Errorf("%w: unknown job type %q", errInvalidJob, job.Type)
+		}
+	}
+	if err := handler(ctx, q, job); err != nil {
+		// Update job status to failed (or terminal for private, banned or quarantined subreddits)
+		jobStatus = markJobFailed(ctx, q, job, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "crawl job failed")
+		return err
+	}
+
+	log.Printf("🎉 Completed %s job #%d in %v", job.Type, job.ID, time.Since(startTime))
+
+	// Update job status to success, as long as this worker still holds the lease
+	n, err := q.MarkCrawlJobSuccess(ctx, db.MarkCrawlJobSuccessParams{
+		ID:         job.ID,
+		LeaseOwner: sql.NullString{String: job.LeaseOwner, Valid: job.LeaseOwner != ""},
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to update job status to success: %v", err)
+		jobStatus = "failed"
+		return err
+	}
+	if n == 0 {
+		log.Printf("⚠️ Job #%d finished after its lease was lost; leaving it to its new holder", job.ID)
+		metrics.CrawlerLeasesLost.Inc()
+		jobStatus = "interrupted"
+		return errLeaseLost
+	}
+
+	jobStatus = "success"
+	return nil
+}
+
+// handleSubredditJob crawls a subreddit: its metadata, the posts of its listing plan
+// (or only the posts newer than its watermark) and their comments.
+func handleSubredditJob(ctx context.Context, q *db.Queries, job Job) error {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int("subreddit_id", int(job.SubredditID)))
+
 	// Get subreddit name from ID
 	subreddit, err := q.GetSubredditByID(ctx, job.SubredditID)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to get subreddit", "error", err, "subreddit_id", job.SubredditID)
-		// Update job status to failed
-		_ = q.MarkCrawlJobFailed(ctx, job.ID)
-		jobStatus = "failed"
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "failed to get subreddit")
 		return err
 	}
 
 	span.SetAttributes(attribute.String("subreddit", subreddit.Name))
 	logger.InfoContext(ctx, "Crawling subreddit", "subreddit", subreddit.Name)
 
-	info, posts, err := CrawlSubreddit(subreddit.Name)
+	plan, err := LoadListingPlan(ctx, q, job.SubredditID)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to load listing plan, using default", "error", err, "subreddit", subreddit.Name)
+	}
+	span.SetAttributes(attribute.String("listing_plan", plan.String()))
+
+	cfg := config.Load()
+	wm, hasWatermark, err := LoadWatermark(ctx, q, job.SubredditID)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to load watermark, running full crawl", "error", err, "subreddit", subreddit.Name)
+	}
+	incremental := useIncrementalCrawl(cfg, wm, hasWatermark, time.Now())
+	crawlMode := "full"
+	if incremental {
+		crawlMode = "incremental"
+	}
+	span.SetAttributes(attribute.String("crawl_mode", crawlMode))
+	metrics.CrawlerCrawlsByMode.WithLabelValues(crawlMode).Inc()
+
+	hop, err := subredditHop(ctx, q, job.SubredditID)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to load hop distance", "error", err, "subreddit", subreddit.Name)
+	}
+	policy := LoadDiscoveryPolicy(ctx, q)
+
+	name := strings.ToLower(strings.TrimSpace(subreddit.Name))
+	info, err := fetchSubredditAbout(name)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to crawl subreddit", "error", err, "subreddit", subreddit.Name)
+		return err
+	}
+	if err := checkSubredditPolicy(policy, subreddit.Name, hop, info); err != nil {
+		logger.InfoContext(ctx, "Subreddit excluded by discovery policy", "error", err, "subreddit", subreddit.Name)
+		return err
+	}
+
+	var posts []Post
+	if incremental {
+		posts, err = crawlPostsSince(ctx, name, wm, plan.MaxPosts, cfg.CrawlMaxPagesPerListing)
+	} else {
+		posts, err = crawlPlanListings(ctx, name, plan)
+	}
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to crawl subreddit", "error", err, "subreddit", subreddit.Name)
-		// Update job status to failed
-		_ = q.MarkCrawlJobFailed(ctx, job.ID)
-		jobStatus = "failed"
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "crawl failed")
 		return err
 	}
 
@@ -98,52 +186,104 @@ func handleJob(ctx context.Context, q *db.Queries, job db.CrawlJob) error {
 	})
 	if err != nil {
 		logger.WarnContext(ctx, "Failed to upsert subreddit", "error", err, "subreddit", subreddit.Name)
-		// Update job status to failed
-		_ = q.MarkCrawlJobFailed(ctx, job.ID)
-		jobStatus = "failed"
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "upsert failed")
 		return err
 	}
 	logger.DebugContext(ctx, "Updated subreddit info", "subreddit", subreddit.Name)
-
-	if len(posts) > MaxPostsPerSubreddit {
-		logger.DebugContext(ctx, "Limiting posts", "from", len(posts), "to", MaxPostsPerSubreddit)
-		posts = posts[:MaxPostsPerSubreddit]
+	if err := q.InsertSubredditSnapshot(ctx, info.snapshot(job.SubredditID)); err != nil {
+		logger.WarnContext(ctx, "Failed to store subreddit snapshot", "error", err, "subreddit", subreddit.Name)
 	}
 
 	insertedPosts, err := crawlAndStorePosts(ctx, q, job.SubredditID, posts)
 	if err != nil {
 		log.Printf("⚠️ Failed to crawl and store posts: %v", err)
-		// Update job status to failed
-		_ = q.MarkCrawlJobFailed(ctx, job.ID)
-		jobStatus = "failed"
 		return err
 	}
 	log.Printf("✅ Stored %d posts", len(insertedPosts))
 
-	if err := crawlAndStoreComments(ctx, q, job.SubredditID, posts, utils.GetEnvAsInt("MAX_COMMENT_DEPTH", 5), insertedPosts); err != nil {
+	commentPosts := posts
+	if incremental {
+		commentPosts = withActivePosts(ctx, q, job.SubredditID, posts, insertedPosts, cfg)
+	}
+
+	if err := crawlAndStoreComments(ctx, q, job.SubredditID, commentPosts, utils.GetEnvAsInt("MAX_COMMENT_DEPTH", 5), insertedPosts); err != nil {
 		log.Printf("⚠️ Failed to crawl and store comments: %v", err)
-		// Update job status to failed
-		_ = q.MarkCrawlJobFailed(ctx, job.ID)
-		jobStatus = "failed"
 		return err
 	}
 
-	enqueueLinkedSubreddits(ctx, q, posts)
+	enqueueLinkedSubreddits(ctx, q, policy, posts, discoverySource{
+		Hop:        originHop(hop) + 1,
+		JobID:      job.ID,
+		EnqueuedBy: "crawler",
+	})
 
-	duration := time.Since(startTime)
-	log.Printf("🎉 Completed crawl job #%d for r/%s in %v", job.ID, subreddit.Name, duration)
+	now := time.Now()
+	wm.Advance(posts, now)
+	if incremental {
+		wm.LastIncrementalAt = now
+	} else {
+		wm.LastFullCrawlAt = now
+	}
+	if err := SaveWatermark(ctx, q, wm); err != nil {
+		logger.WarnContext(ctx, "Failed to save watermark", "error", err, "subreddit", subreddit.Name)
+	}
+	log.Printf("✅ Crawled r/%s", subreddit.Name)
+	return nil
+}
 
-	// Update job status to success
-	if err := q.MarkCrawlJobSuccess(ctx, job.ID); err != nil {
-		log.Printf("⚠️ Failed to update job status to success: %v", err)
-		jobStatus = "failed"
-		return err
+// useIncrementalCrawl reports whether a job should fetch only posts newer than the
+// subreddit's watermark. The full listing plan still runs for subreddits without a
+// watermark and periodically, so that older posts climbing the top listings are seen.
+func useIncrementalCrawl(cfg *config.Config, wm Watermark, hasWatermark bool, now time.Time) bool {
+	if !cfg.CrawlIncremental || !hasWatermark || wm.NewestFullname == "" || wm.LastFullCrawlAt.IsZero() {
+		return false
 	}
+	return now.Sub(wm.LastFullCrawlAt) < cfg.CrawlFullRecrawlInterval
+}
 
-	jobStatus = "success"
-	return nil
+// withActivePosts adds stored posts still inside the activity window to the posts
+// whose comments are refreshed on an incremental crawl. Those posts are already in
+// the database, so they are marked as inserted for crawlAndStoreComments.
+func withActivePosts(ctx context.Context, q *db.Queries, subredditID int32, posts []Post, insertedPosts map[string]bool, cfg *config.Config) []Post {
+	active, err := ActivePostsForCommentRefresh(ctx, q, subredditID, cfg.CrawlActivityWindow, cfg.CrawlCommentRefreshInterval, cfg.MaxPostsPerSub)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to load active posts for comment refresh", "error", err, "subreddit_id", subredditID)
+		return posts
+	}
+	out := posts
+	for _, p := range active {
+		if insertedPosts[p.ID] {
+			continue
+		}
+		insertedPosts[p.ID] = true
+		out = append(out, p)
+	}
+	log.Printf("🔁 Refreshing comments on %d active posts", len(out)-len(posts))
+	return out
+}
+
+// markJobFailed records a failed attempt with its classified reason and returns the
+// job status label for metrics ("failed" or "terminal"). It does nothing if the job
+// context was cancelled: a cancelled job is either being released on shutdown or was
+// reclaimed by another worker after its lease expired, so its status belongs to someone else.
+// Likewise a job stopped by an open circuit breaker is released by the worker without
+// counting as an attempt.
+func markJobFailed(ctx context.Context, q *db.Queries, job Job, cause error) string {
+	if ctx.Err() != nil {
+		return "interrupted"
+	}
+	if errors.Is(cause, circuitbreaker.ErrCircuitOpen) {
+		return "deferred"
+	}
+	reason, err := RecordJobFailure(ctx, q, job.ID, cause)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to record job failure", "error", err, "job_id", job.ID)
+	}
+	metrics.CrawlerJobFailures.WithLabelValues(job.Type, reason).Inc()
+	if IsTerminalFailure(cause) {
+		logger.InfoContext(ctx, "Job moved to terminal state", "job_id", job.ID, "reason", reason)
+		return JobStatusTerminal
+	}
+	return "failed"
 }
 
 func crawlAndStorePosts(ctx context.Context, q *db.Queries, subredditID int32, posts []Post) (map[string]bool, error) {
@@ -151,7 +291,29 @@ func crawlAndStorePosts(ctx context.Context, q *db.Queries, subredditID int32, p
 	skippedPosts := 0
 	insertedCount := 0
 
+	ids := make([]string, 0, len(posts))
 	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	stored, err := loadContentStates(ctx, q, contentPost, ids)
+	if err != nil {
+		log.Printf("⚠️ Failed to load stored posts for history: %v", err)
+		stored = nil
+	}
+
+	for _, post := range posts {
+		obs := postObservation(post, subredditID)
+		prev, seen := stored[post.ID]
+		if seen {
+			if err := recordObservation(ctx, q, obs, prev); err != nil {
+				log.Printf("⚠️ Failed to record history of post %s: %v", post.ID, err)
+			}
+			// A removed post keeps its last live version; its comments are still refreshed.
+			if obs.removed != "" {
+				insertedPosts[post.ID] = true
+				continue
+			}
+		}
 		if post.Author == "" || post.Author == "[deleted]" {
 			skippedPosts++
 			continue
@@ -180,6 +342,14 @@ func crawlAndStorePosts(ctx context.Context, q *db.Queries, subredditID int32, p
 			insertedPosts[post.ID] = true
 			insertedCount++
 			metrics.CrawlerPostsProcessed.Inc()
+			if err := q.ReplacePostSubredditRefs(ctx, post.ID, subredditID, post.SubredditRefs()); err != nil {
+				log.Printf("⚠️ Failed to store subreddit references of post %s: %v", post.ID, err)
+			}
+			if !seen {
+				if err := recordFirstObservation(ctx, q, obs); err != nil {
+					log.Printf("⚠️ Failed to record history of post %s: %v", post.ID, err)
+				}
+			}
 		}
 	}
 
@@ -211,21 +381,57 @@ func crawlAndStoreComments(
 			continue
 		}
 
-		comments, err := CrawlComments(postID)
+		comments, exp, err := CrawlCommentsExpanded(postID)
 		if err != nil {
+			// An open breaker fails every remaining post too: stop so the job is released and retried.
+			if errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+				return fmt.Errorf("fetch comments for %s: %w", postID, err)
+			}
 			log.Printf("⚠️ Failed to fetch comments for %s: %v", post.Permalink, err)
 			continue
 		}
 
-		log.Printf("💬 Post: %s — %d comments", post.Title, len(comments))
+		log.Printf("💬 Post: %s — %d comments (%d expanded, %d unresolved)", post.Title, len(comments), exp.Expanded, exp.Skipped)
+		if err := MarkCommentsChecked(ctx, q, postID, len(comments)); err != nil {
+			log.Printf("⚠️ Failed to record comment check for post %s: %v", postID, err)
+		}
 		totalComments += len(comments)
 
 		inserted := map[string]bool{}
 		pending := map[string]db.UpsertCommentParams{}
+		fresh := map[string]contentObservation{} // comments stored for the first time
+
+		ids := make([]string, 0, len(comments))
+		for _, c := range comments {
+			ids = append(ids, c.ID)
+		}
+		stored, err := loadContentStates(ctx, q, contentComment, ids)
+		if err != nil {
+			log.Printf("⚠️ Failed to load stored comments of post %s for history: %v", postID, err)
+			stored = nil
+		}
 
 		// First pass
 		for _, c := range comments {
-			if !utils.IsValidAuthor(c.Author) || c.Depth > maxDepth {
+			if c.Depth > maxDepth {
+				skippedThisPost++
+				continue
+			}
+			obs := commentObservation(c, subredditID)
+			prev, seen := stored[c.ID]
+			if seen {
+				if err := recordObservation(ctx, q, obs, prev); err != nil {
+					log.Printf("⚠️ Failed to record history of comment %s: %v", c.ID, err)
+				}
+				// A removed comment keeps its last live version but still anchors its replies.
+				if obs.removed != "" {
+					inserted[c.ID] = true
+					continue
+				}
+			} else {
+				fresh[c.ID] = obs
+			}
+			if !utils.IsValidAuthor(c.Author) {
 				skippedThisPost++
 				continue
 			}
@@ -255,6 +461,7 @@ func crawlAndStoreComments(
 					inserted[c.ID] = true
 					insertedThisPost++
 					metrics.CrawlerCommentsProcessed.Inc()
+					recordFirstCommentObservation(ctx, q, fresh, c.ID)
 				} else {
 					log.Printf("⚠️ Failed to insert comment %s: %v", c.ID, err)
 					skippedThisPost++
@@ -271,6 +478,7 @@ func crawlAndStoreComments(
 					inserted[id] = true
 					insertedThisPost++
 					metrics.CrawlerCommentsProcessed.Inc()
+					recordFirstCommentObservation(ctx, q, fresh, id)
 				} else {
 					log.Printf("⚠️ Second pass failed for comment %s: %v", id, err)
 					skippedThisPost++
@@ -291,40 +499,30 @@ func crawlAndStoreComments(
 	}
 	log.Printf("👥 Found %d unique authors to process", len(authors))
 
-	FetchAndQueueUserSubredditsForAuthors(ctx, q, authors, FetchUserSubredditsConfig{
-		Limit:      utils.GetEnvAsInt("USER_SUB_FETCH_LIMIT", 30),
-		MaxEnqueue: utils.GetEnvAsInt("USER_SUB_ENQUEUE_MAX", 10),
-		Enabled:    utils.GetEnvAsBool("FETCH_USER_SUBREDDITS", true),
-	})
+	if userSubredditsConfig().Enabled {
+		hop, err := subredditHop(ctx, q, subredditID)
+		if err != nil {
+			log.Printf("⚠️ Failed to load hop distance of subreddit %d: %v", subredditID, err)
+		}
+		queued := EnqueueUserJobs(ctx, q, authors, originHop(hop), "crawler")
+		log.Printf("👥 Queued %d user jobs", queued)
+	}
 
 	return nil
 }
 
-func enqueueLinkedSubreddits(ctx context.Context, q *db.Queries, posts []Post) {
+// enqueueLinkedSubreddits queues the subreddits mentioned in posts that the
+// discovery policy allows.
+func enqueueLinkedSubreddits(ctx context.Context, q *db.Queries, policy DiscoveryPolicy, posts []Post, from discoverySource) {
 	linked := extractMentionedSubreddits(posts)
 	log.Printf("🔗 Found %d linked subreddits", len(linked))
 
 	enqueuedCount := 0
 	for _, sub := range linked {
-		// First get or create the subreddit
-		subreddit, err := q.EnsureSubreddit(ctx, db.EnsureSubredditParams{
-			Name:        sub,
-			Title:       sql.NullString{String: sub, Valid: true},
-			Description: sql.NullString{String: "", Valid: true},
-			Subscribers: sql.NullInt32{Int32: 0, Valid: true},
-		})
+		queued, err := discoverSubreddit(ctx, q, policy, sub, from)
 		if err != nil {
-			log.Printf("⚠️ Failed to ensure subreddit %s: %v", sub, err)
-			continue
-		}
-
-		// Then enqueue the crawl job
-		if err := q.EnqueueCrawlJob(ctx, db.EnqueueCrawlJobParams{
-			SubredditID: subreddit,
-			EnqueuedBy:  sql.NullString{String: "crawler", Valid: true},
-		}); err != nil {
 			log.Printf("⚠️ Failed to enqueue %s: %v", sub, err)
-		} else {
+		} else if queued {
 			enqueuedCount++
 		}
 	}
@@ -0,0 +1,197 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/utils"
+)
+
+// Crawl job types. Subreddit jobs are keyed by subreddit_id; the other types carry
+// their parameters in the job payload and are deduplicated by a normalized key.
+const (
+	JobTypeSubreddit = "subreddit"
+	JobTypeUser      = "user"
+	JobTypePost      = "post"
+	JobTypeSearch    = "search"
+)
+
+// JobTypes lists the job types in display order.
+var JobTypes = []string{JobTypeSubreddit, JobTypeUser, JobTypePost, JobTypeSearch}
+
+// JobPayload holds the parameters of a non-subreddit job. Only the fields used by
+// the job's type are set.
+type JobPayload struct {
+	Username   string `json:"username,omitempty"`  // user: whose history to scan
+	PostID     string `json:"post_id,omitempty"`   // post: base36 ID without the t3_ prefix
+	Query      string `json:"query,omitempty"`     // search: keywords
+	Subreddit  string `json:"subreddit,omitempty"` // search: restrict to one subreddit
+	Sort       string `json:"sort,omitempty"`      // search: relevance, hot, top, new, comments
+	TimeFilter string `json:"time,omitempty"`      // search: hour ... all
+	Hop        int    `json:"hop,omitempty"`       // distance from a seed of the subreddit that led to the job
+}
+
+// Job is a claimed crawl job with its type and decoded payload.
+type Job struct {
+	db.CrawlJob
+	Type    string
+	Payload JobPayload
+	// LeaseOwner is the worker holding the job's lease; only it may complete the job.
+	LeaseOwner string
+}
+
+// jobPolicy is the per-type retry policy. Retries back off exponentially from
+// RetryBase; jobs enqueued by discovery get Priority so that they yield to
+// subreddit crawls.
+type jobPolicy struct {
+	MaxRetries int32
+	RetryBase  time.Duration
+	Priority   int32
+}
+
+var jobPolicies = map[string]jobPolicy{
+	JobTypeSubreddit: {MaxRetries: 3, RetryBase: time.Minute},
+	JobTypeUser:      {MaxRetries: 2, RetryBase: 5 * time.Minute, Priority: -1},
+	JobTypePost:      {MaxRetries: 3, RetryBase: time.Minute},
+	JobTypeSearch:    {MaxRetries: 2, RetryBase: 2 * time.Minute},
+}
+
+func policyFor(jobType string) jobPolicy {
+	if p, ok := jobPolicies[jobType]; ok {
+		return p
+	}
+	return jobPolicies[JobTypeSubreddit]
+}
+
+// IsValidJobType reports whether t is a known job type.
+func IsValidJobType(t string) bool {
+	_, ok := jobPolicies[t]
+	return ok
+}
+
+var (
+	usernamePattern      = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+	postIDPattern        = regexp.MustCompile(`^[a-z0-9]{1,12}$`)
+	subredditNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,21}$`)
+	validSearchSorts     = map[string]bool{"relevance": true, "hot": true, "top": true, "new": true, "comments": true}
+)
+
+const maxSearchQueryLen = 512
+
+// Normalize validates the payload for jobType, fills in defaults and returns the
+// job's dedupe key. Usernames may carry a u/ prefix and post IDs may be given as a
+// fullname (t3_...) or a permalink. The hop distance is kept but is not part of the key.
+func (p *JobPayload) Normalize(jobType string) (string, error) {
+	hop := p.Hop
+	if hop < 0 {
+		hop = 0
+	}
+	switch jobType {
+	case JobTypeUser:
+		name := strings.TrimSpace(p.Username)
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "/"), "u/")
+		if !usernamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid username %q", p.Username)
+		}
+		*p = JobPayload{Username: name, Hop: hop}
+		return strings.ToLower(name), nil
+	case JobTypePost:
+		id := strings.TrimSpace(p.PostID)
+		if strings.Contains(id, "/comments/") {
+			id = utils.ExtractPostID(id)
+		}
+		id = strings.ToLower(strings.TrimPrefix(id, "t3_"))
+		if !postIDPattern.MatchString(id) {
+			return "", fmt.Errorf("invalid post ID %q", p.PostID)
+		}
+		*p = JobPayload{PostID: id, Hop: hop}
+		return id, nil
+	case JobTypeSearch:
+		query := strings.Join(strings.Fields(p.Query), " ")
+		if query == "" || len(query) > maxSearchQueryLen {
+			return "", fmt.Errorf("search query must be 1-%d characters", maxSearchQueryLen)
+		}
+		sub := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(p.Subreddit), "/"), "r/")
+		if sub != "" && !subredditNamePattern.MatchString(sub) {
+			return "", fmt.Errorf("invalid subreddit %q", p.Subreddit)
+		}
+		sort := strings.ToLower(strings.TrimSpace(p.Sort))
+		if sort == "" {
+			sort = "relevance"
+		}
+		if !validSearchSorts[sort] {
+			return "", fmt.Errorf("unknown search sort %q", p.Sort)
+		}
+		tf := strings.ToLower(strings.TrimSpace(p.TimeFilter))
+		if tf == "" {
+			tf = "all"
+		}
+		if !validTimeFilters[tf] {
+			return "", fmt.Errorf("unknown time filter %q", p.TimeFilter)
+		}
+		*p = JobPayload{Query: query, Subreddit: sub, Sort: sort, TimeFilter: tf, Hop: hop}
+		return strings.ToLower(strings.Join([]string{query, sub, sort, tf}, "|")), nil
+	case JobTypeSubreddit:
+		return "", fmt.Errorf("subreddit jobs are enqueued by subreddit ID")
+	default:
+		return "", fmt.Errorf("unknown job type %q", jobType)
+	}
+}
+
+// NewTypedJobParams builds the enqueue parameters for a user, post or search job.
+// The payload is normalized; an invalid payload is rejected.
+func NewTypedJobParams(jobType string, payload JobPayload, priority int32, enqueuedBy string) (db.EnqueueTypedCrawlJobParams, error) {
+	key, err := payload.Normalize(jobType)
+	if err != nil {
+		return db.EnqueueTypedCrawlJobParams{}, err
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return db.EnqueueTypedCrawlJobParams{}, err
+	}
+	return db.EnqueueTypedCrawlJobParams{
+		JobType:    jobType,
+		DedupeKey:  sql.NullString{String: key, Valid: true},
+		Payload:    raw,
+		Priority:   sql.NullInt32{Int32: priority, Valid: true},
+		MaxRetries: sql.NullInt32{Int32: policyFor(jobType).MaxRetries, Valid: true},
+		EnqueuedBy: sql.NullString{String: enqueuedBy, Valid: enqueuedBy != ""},
+	}, nil
+}
+
+// EnqueueTypedJob queues a user, post or search job unless an identical one is
+// already queued or crawling.
+func EnqueueTypedJob(ctx context.Context, q *db.Queries, jobType string, payload JobPayload, priority int32, enqueuedBy string) error {
+	params, err := NewTypedJobParams(jobType, payload, priority, enqueuedBy)
+	if err != nil {
+		return err
+	}
+	if _, err := q.EnqueueTypedCrawlJob(ctx, params); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// errInvalidJob marks a job whose payload cannot be processed. Such jobs fail
+// permanently instead of being retried.
+var errInvalidJob = errors.New("invalid crawl job")
+
+// decodeJob fills in a claimed job's type and payload. An undecodable payload is
+// left empty; the job's handler then rejects it as invalid.
+func decodeJob(j db.CrawlJob, jobType string, payload []byte) Job {
+	job := Job{CrawlJob: j, Type: jobType}
+	if job.Type == "" {
+		job.Type = JobTypeSubreddit
+	}
+	if len(payload) > 0 {
+		_ = json.Unmarshal(payload, &job.Payload)
+	}
+	return job
+}
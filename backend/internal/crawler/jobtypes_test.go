@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestJobPayloadNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobType string
+		in      JobPayload
+		want    JobPayload
+		key     string
+		wantErr bool
+	}{
+		{name: "user prefix", jobType: JobTypeUser, in: JobPayload{Username: " /u/Some_User "}, want: JobPayload{Username: "Some_User"}, key: "some_user"},
+		{name: "user keeps hop", jobType: JobTypeUser, in: JobPayload{Username: "someone", Hop: 2}, want: JobPayload{Username: "someone", Hop: 2}, key: "someone"},
+		{name: "user too short", jobType: JobTypeUser, in: JobPayload{Username: "ab"}, wantErr: true},
+		{name: "post fullname", jobType: JobTypePost, in: JobPayload{PostID: "t3_Abc12"}, want: JobPayload{PostID: "abc12"}, key: "abc12"},
+		{name: "post permalink", jobType: JobTypePost, in: JobPayload{PostID: "https://www.reddit.com/r/golang/comments/xyz9/some_title/"}, want: JobPayload{PostID: "xyz9"}, key: "xyz9"},
+		{name: "post invalid", jobType: JobTypePost, in: JobPayload{PostID: "not a post"}, wantErr: true},
+		{
+			name: "search defaults", jobType: JobTypeSearch,
+			in:   JobPayload{Query: "  Graph   Theory ", Subreddit: "r/Math", Username: "ignored"},
+			want: JobPayload{Query: "Graph Theory", Subreddit: "Math", Sort: "relevance", TimeFilter: "all"},
+			key:  "graph theory|math|relevance|all",
+		},
+		{name: "search bad sort", jobType: JobTypeSearch, in: JobPayload{Query: "go", Sort: "random"}, wantErr: true},
+		{name: "search bad time", jobType: JobTypeSearch, in: JobPayload{Query: "go", TimeFilter: "decade"}, wantErr: true},
+		{name: "search too long", jobType: JobTypeSearch, in: JobPayload{Query: strings.Repeat("a", maxSearchQueryLen+1)}, wantErr: true},
+		{name: "subreddit", jobType: JobTypeSubreddit, in: JobPayload{Subreddit: "golang"}, wantErr: true},
+		{name: "unknown", jobType: "comment", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.in
+			key, err := p.Normalize(tt.jobType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got key %q", key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != tt.key {
+				t.Errorf("key = %q, want %q", key, tt.key)
+			}
+			if p != tt.want {
+				t.Errorf("payload = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTypedJobParamsUsesPolicy(t *testing.T) {
+	params, err := NewTypedJobParams(JobTypeUser, JobPayload{Username: "u/Spez"}, policyFor(JobTypeUser).Priority, "crawler")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.JobType != JobTypeUser || params.DedupeKey.String != "spez" {
+		t.Errorf("unexpected type/key: %q %q", params.JobType, params.DedupeKey.String)
+	}
+	if params.MaxRetries.Int32 != 2 || params.Priority.Int32 != -1 {
+		t.Errorf("expected user policy (2 retries, priority -1), got %d retries, priority %d", params.MaxRetries.Int32, params.Priority.Int32)
+	}
+	if string(params.Payload) != `{"username":"Spez"}` {
+		t.Errorf("unexpected payload %s", params.Payload)
+	}
+}
+
+func TestDecodeJob(t *testing.T) {
+	base := db.CrawlJob{ID: 7}
+
+	job := decodeJob(base, JobTypeSearch, json.RawMessage(`{"query":"go","sort":"new","time":"week"}`))
+	if job.ID != 7 || job.Type != JobTypeSearch {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if job.Payload.Query != "go" || job.Payload.Sort != "new" || job.Payload.TimeFilter != "week" {
+		t.Errorf("unexpected payload %+v", job.Payload)
+	}
+
+	// Rows claimed before the job_type column existed are subreddit jobs.
+	if job := decodeJob(base, "", nil); job.Type != JobTypeSubreddit {
+		t.Errorf("expected subreddit default, got %q", job.Type)
+	}
+
+	// A corrupt payload decodes empty and is rejected by the handler.
+	job = decodeJob(base, JobTypeUser, json.RawMessage(`{not json`))
+	if _, err := job.Payload.Normalize(JobTypeUser); err == nil {
+		t.Error("expected corrupt payload to fail validation")
+	}
+}
+
+func TestInvalidJobIsTerminal(t *testing.T) {
+	err := fmt.Errorf("%w: missing username", errInvalidJob)
+	if !IsTerminalFailure(err) {
+		t.Error("expected invalid job to be terminal")
+	}
+	if got := FailureReason(err); got != ReasonInvalidJob {
+		t.Errorf("FailureReason = %q, want %q", got, ReasonInvalidJob)
+	}
+}
+
+func TestRetryDelayPerType(t *testing.T) {
+	for _, jobType := range JobTypes {
+		base := policyFor(jobType).RetryBase
+		delay := retryDelayFrom(base, 1)
+		if delay < 2*base || delay > time.Duration(float64(2*base)*1.2) {
+			t.Errorf("%s: delay %v outside [%v, %v]", jobType, delay, 2*base, time.Duration(float64(2*base)*1.2))
+		}
+	}
+	if policyFor("unknown") != policyFor(JobTypeSubreddit) {
+		t.Error("unknown types should fall back to the subreddit policy")
+	}
+}
+
+func TestSearchURL(t *testing.T) {
+	p := JobPayload{Query: "graph theory", Sort: "top", TimeFilter: "year"}
+	got := searchURL(p, 50)
+	want := "https://oauth.reddit.com/search?limit=50&q=graph+theory&raw_json=1&sort=top&t=year&type=link"
+	if got != want {
+		t.Errorf("searchURL = %q, want %q", got, want)
+	}
+
+	p.Subreddit = "Math"
+	got = searchURL(p, 50)
+	if !strings.HasPrefix(got, "https://oauth.reddit.com/r/math/search?") || !strings.Contains(got, "restrict_sr=1") {
+		t.Errorf("unexpected restricted search URL %q", got)
+	}
+}
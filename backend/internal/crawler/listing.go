@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// validSorts are the subreddit listings the crawler knows how to follow.
+var validSorts = map[string]bool{"hot": true, "new": true, "top": true, "rising": true, "controversial": true}
+
+// validTimeFilters are the `t` values Reddit accepts for top and controversial.
+var validTimeFilters = map[string]bool{"hour": true, "day": true, "week": true, "month": true, "year": true, "all": true}
+
+// ListingSpec is one subreddit listing to crawl, e.g. top posts of the month.
+type ListingSpec struct {
+	Sort       string // hot, new, top, rising, controversial
+	TimeFilter string // only used by top and controversial
+	MaxPages   int    // how many `after` pages to follow; 0 means no page cap
+}
+
+// String renders the spec in plan syntax: sort[:time][@pages].
+func (s ListingSpec) String() string {
+	out := s.Sort
+	if s.usesTimeFilter() && s.TimeFilter != "" {
+		out += ":" + s.TimeFilter
+	}
+	if s.MaxPages > 0 {
+		out += "@" + strconv.Itoa(s.MaxPages)
+	}
+	return out
+}
+
+// withinPageBudget reports whether page n (from 0) may be fetched under a budget of
+// maxPages; a budget of 0 or less only stops at the post budget.
+func withinPageBudget(n, maxPages int) bool {
+	return maxPages <= 0 || n < maxPages
+}
+
+func (s ListingSpec) usesTimeFilter() bool {
+	return s.Sort == "top" || s.Sort == "controversial"
+}
+
+// ListingPlan is the set of listings crawled for a subreddit. Posts are deduplicated
+// across listings and the whole plan shares one post budget.
+type ListingPlan struct {
+	Listings []ListingSpec
+	MaxPosts int
+}
+
+// String renders the plan in the syntax accepted by ParseListingPlan.
+func (p ListingPlan) String() string {
+	parts := make([]string, len(p.Listings))
+	for i, l := range p.Listings {
+		parts[i] = l.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseListingPlan parses a comma-separated plan such as "hot,new@2,top:month@3,rising".
+// Each entry is sort[:time][@pages]; the time filter defaults to defaultTime for top
+// and controversial, and the page count defaults to defaultPages (0 for no page cap).
+func ParseListingPlan(s string, defaultTime string, defaultPages int) ([]ListingSpec, error) {
+	var specs []ListingSpec
+	seen := make(map[string]bool)
+	for _, raw := range strings.Split(s, ",") {
+		entry := strings.ToLower(strings.TrimSpace(raw))
+		if entry == "" {
+			continue
+		}
+		spec := ListingSpec{MaxPages: defaultPages}
+		if i := strings.Index(entry, "@"); i >= 0 {
+			pages, err := strconv.Atoi(entry[i+1:])
+			if err != nil || pages < 1 {
+				return nil, fmt.Errorf("invalid page count in listing %q", raw)
+			}
+			spec.MaxPages = pages
+			entry = entry[:i]
+		}
+		if i := strings.Index(entry, ":"); i >= 0 {
+			spec.TimeFilter = entry[i+1:]
+			entry = entry[:i]
+		}
+		spec.Sort = entry
+		if !validSorts[spec.Sort] {
+			return nil, fmt.Errorf("unknown listing sort %q", spec.Sort)
+		}
+		if spec.usesTimeFilter() {
+			if spec.TimeFilter == "" {
+				spec.TimeFilter = defaultTime
+			}
+			if !validTimeFilters[spec.TimeFilter] {
+				return nil, fmt.Errorf("unknown time filter %q for listing %q", spec.TimeFilter, raw)
+			}
+		} else {
+			spec.TimeFilter = ""
+		}
+		key := spec.Sort + ":" + spec.TimeFilter
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("listing plan %q has no listings", s)
+	}
+	return specs, nil
+}
+
+// DefaultListingPlan builds the plan used for subreddits without their own plan.
+// CRAWL_LISTING_PLAN takes precedence; otherwise POSTS_SORT and POSTS_TIME_FILTER
+// describe a single listing.
+func DefaultListingPlan(cfg *config.Config) ListingPlan {
+	plan := ListingPlan{MaxPosts: cfg.MaxPostsPerSub}
+	if cfg.CrawlListingPlan != "" {
+		specs, err := ParseListingPlan(cfg.CrawlListingPlan, cfg.PostsTimeFilter, cfg.CrawlMaxPagesPerListing)
+		if err == nil {
+			plan.Listings = specs
+			return plan
+		}
+	}
+	specs, err := ParseListingPlan(cfg.PostsSort, cfg.PostsTimeFilter, cfg.CrawlMaxPagesPerListing)
+	if err != nil {
+		specs = []ListingSpec{{Sort: "top", TimeFilter: "day", MaxPages: cfg.CrawlMaxPagesPerListing}}
+	}
+	plan.Listings = specs
+	return plan
+}
+
+// LoadListingPlan returns the listing plan for a subreddit: its own plan when one is
+// stored, the default plan otherwise. An unparsable stored plan falls back to the default.
+func LoadListingPlan(ctx context.Context, q *db.Queries, subredditID int32) (ListingPlan, error) {
+	cfg := config.Load()
+	plan := DefaultListingPlan(cfg)
+	var stored sql.NullString
+	err := q.DB().QueryRowContext(ctx, `SELECT listing_plan FROM subreddits WHERE id = $1`, subredditID).Scan(&stored)
+	if err != nil {
+		return plan, err
+	}
+	if !stored.Valid || strings.TrimSpace(stored.String) == "" {
+		return plan, nil
+	}
+	specs, err := ParseListingPlan(stored.String, cfg.PostsTimeFilter, cfg.CrawlMaxPagesPerListing)
+	if err != nil {
+		return plan, err
+	}
+	plan.Listings = specs
+	return plan, nil
+}
+
+// SetListingPlan stores a subreddit's own listing plan. An empty plan clears it so the
+// subreddit follows the default plan again. The plan is validated before it is stored.
+func SetListingPlan(ctx context.Context, q *db.Queries, subredditID int32, plan string) error {
+	plan = strings.TrimSpace(plan)
+	stored := sql.NullString{}
+	if plan != "" {
+		cfg := config.Load()
+		specs, err := ParseListingPlan(plan, cfg.PostsTimeFilter, cfg.CrawlMaxPagesPerListing)
+		if err != nil {
+			return err
+		}
+		stored = sql.NullString{String: ListingPlan{Listings: specs}.String(), Valid: true}
+	}
+	_, err := q.DB().ExecContext(ctx, `UPDATE subreddits SET listing_plan = $2 WHERE id = $1`, subredditID, stored)
+	return err
+}
@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+func TestParseListingPlan(t *testing.T) {
+	specs, err := ParseListingPlan("hot, new@2, top:month@3, rising, top:week, TOP:month", "day", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ListingSpec{
+		{Sort: "hot", MaxPages: 5},
+		{Sort: "new", MaxPages: 2},
+		{Sort: "top", TimeFilter: "month", MaxPages: 3},
+		{Sort: "rising", MaxPages: 5},
+		{Sort: "top", TimeFilter: "week", MaxPages: 5},
+	}
+	if len(specs) != len(want) {
+		t.Fatalf("expected %d listings, got %d: %+v", len(want), len(specs), specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Errorf("listing %d = %+v, want %+v", i, specs[i], want[i])
+		}
+	}
+
+	specs, err = ParseListingPlan("top", "year", 1)
+	if err != nil || specs[0].TimeFilter != "year" {
+		t.Fatalf("expected default time filter, got %+v (err %v)", specs, err)
+	}
+
+	for _, bad := range []string{"", "best", "top:decade", "hot@0", "new@x"} {
+		if _, err := ParseListingPlan(bad, "day", 1); err == nil {
+			t.Errorf("expected error for plan %q", bad)
+		}
+	}
+}
+
+func TestDefaultListingPlan(t *testing.T) {
+	cfg := &config.Config{MaxPostsPerSub: 25, PostsSort: "new", PostsTimeFilter: "day", CrawlMaxPagesPerListing: 2}
+	plan := DefaultListingPlan(cfg)
+	if plan.String() != "new@2" || plan.MaxPosts != 25 {
+		t.Fatalf("unexpected default plan %q (max %d)", plan, plan.MaxPosts)
+	}
+
+	cfg.CrawlListingPlan = "hot,top:month"
+	if got := DefaultListingPlan(cfg).String(); got != "hot@2,top:month@2" {
+		t.Fatalf("unexpected configured plan %q", got)
+	}
+}
+
+func TestCrawlSubredditWithPlan_DedupesAcrossListings(t *testing.T) {
+	oldDelay := listingPageDelay
+	listingPageDelay = 0
+	defer func() { listingPageDelay = oldDelay }()
+
+	page := func(ids []string, next string) map[string]interface{} {
+		var children []map[string]interface{}
+		for _, id := range ids {
+			children = append(children, map[string]interface{}{"data": map[string]interface{}{"id": id, "title": id, "author": "u"}})
+		}
+		return map[string]interface{}{"data": map[string]interface{}{"children": children, "after": next}}
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/r/test/about":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"title": "t"}})
+		case "/r/test/hot":
+			if r.URL.Query().Get("after") == "" {
+				json.NewEncoder(w).Encode(page([]string{"a", "b"}, "t3_b"))
+			} else {
+				json.NewEncoder(w).Encode(page([]string{"c"}, "t3_c"))
+			}
+		case "/r/test/top":
+			if r.URL.Query().Get("t") != "month" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(page([]string{"b", "d", "e"}, ""))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		return http.Get(strings.Replace(u, "https://oauth.reddit.com", server.URL, 1))
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	plan := ListingPlan{
+		Listings: []ListingSpec{{Sort: "hot", MaxPages: 1}, {Sort: "top", TimeFilter: "month", MaxPages: 2}, {Sort: "new", MaxPages: 1}},
+		MaxPosts: 4,
+	}
+	_, posts, err := CrawlSubredditWithPlan("test", plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	if strings.Join(ids, ",") != "a,b,d,e" {
+		t.Fatalf("unexpected posts %v", ids)
+	}
+	for _, u := range requested {
+		if strings.Contains(u, "after=") || strings.HasPrefix(u, "/r/test/new") {
+			t.Errorf("unexpected request %s beyond page or post budget", u)
+		}
+	}
+}
+
+func TestCrawlListing_StopsWhenCancelled(t *testing.T) {
+	oldDelay := listingPageDelay
+	listingPageDelay = time.Hour
+	defer func() { listingPageDelay = oldDelay }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"children": []map[string]interface{}{{"data": map[string]interface{}{"id": "a", "title": "a", "author": "u"}}},
+			"after":    "t3_a",
+		}})
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		return http.Get(strings.Replace(u, "https://oauth.reddit.com", server.URL, 1))
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	// The first page needs no delay; the wait before the second ends with the job
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	posts, err := crawlListing(ctx, "test", ListingSpec{Sort: "new", MaxPages: 3}, 100, map[string]bool{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(posts) != 1 || time.Since(start) > time.Minute {
+		t.Errorf("got %d posts after %v, want the first page without waiting out the delay", len(posts), time.Since(start))
+	}
+}
+
+func TestUncappedPageBudget(t *testing.T) {
+	specs, err := ParseListingPlan("hot,new@2", "day", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if specs[0].MaxPages != 0 || specs[0].String() != "hot" {
+		t.Errorf("hot listing = %+v (%s), want no page cap", specs[0], specs[0])
+	}
+	if !withinPageBudget(1000, specs[0].MaxPages) {
+		t.Error("an uncapped listing should keep paging")
+	}
+	if withinPageBudget(2, specs[1].MaxPages) || !withinPageBudget(1, specs[1].MaxPages) {
+		t.Error("new@2 should fetch exactly two pages")
+	}
+}
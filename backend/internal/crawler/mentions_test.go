@@ -1,6 +1,12 @@
 package crawler
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
 
 func TestExtractMentionedSubreddits_TitleAndSelftext(t *testing.T) {
 	posts := []Post{
@@ -24,3 +30,20 @@ func TestExtractMentionedSubreddits_TitleAndSelftext(t *testing.T) {
 		}
 	}
 }
+
+func TestPostSubredditRefs(t *testing.T) {
+	var p Post
+	raw := `{"subreddit": "golang", "title": "Moved from /r/Rust to /r/golang", "selftext": "see /r/rust and /r/programming",
+		"crosspost_parent_list": [{"subreddit": "Programming"}]}`
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := []db.PostSubredditRef{
+		{Target: "rust", Kind: db.LinkTypeMention},
+		{Target: "programming", Kind: db.LinkTypeMention},
+		{Target: "programming", Kind: db.LinkTypeCrosspost},
+	}
+	if got := p.SubredditRefs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SubredditRefs() = %v, want %v", got, want)
+	}
+}
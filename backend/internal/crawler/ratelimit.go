@@ -2,28 +2,214 @@ package crawler
 
 import (
 	"context"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/httpx"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 )
 
+// Smoothing factors for rate changes. Slowing down reacts faster than speeding up
+// so that a shrinking quota is respected before it runs out.
+const (
+	rateIncreaseFactor = 0.2
+	rateDecreaseFactor = 0.5
+	// quotaSafetyMargin keeps a share of the remaining quota unused to absorb
+	// requests already in flight and clock skew.
+	quotaSafetyMargin = 0.9
+)
+
+// AdaptiveLimiter paces Reddit requests. It starts at the configured CrawlerRPS and,
+// when adaptive mode is on, retunes itself from the X-Ratelimit-* headers Reddit
+// returns: the target rate is the remaining quota spread over the time left until
+// the window resets, clamped to [minRPS, maxRPS]. When the quota is exhausted all
+// callers wait until the reset.
+type AdaptiveLimiter struct {
+	label    string // credential label used in metrics
+	limiter  *rate.Limiter
+	adaptive bool
+	minRPS   float64
+	maxRPS   float64
+
+	mu         sync.Mutex
+	pauseUntil time.Time
+	quota      QuotaState
+}
+
+// QuotaState is the Reddit quota reported by the most recent response headers.
+type QuotaState struct {
+	Known     bool
+	Remaining float64
+	Used      int
+	ResetAt   time.Time
+}
+
+// NewAdaptiveLimiter creates a limiter for the credential label starting at rps
+// with the given burst.
+func NewAdaptiveLimiter(label string, rps float64, burst int, adaptive bool, minRPS, maxRPS float64) *AdaptiveLimiter {
+	if maxRPS < rps {
+		maxRPS = rps
+	}
+	if minRPS <= 0 || minRPS > rps {
+		minRPS = rps
+	}
+	metrics.CrawlerRateLimitRPS.WithLabelValues(label).Set(rps)
+	return &AdaptiveLimiter{
+		label:    label,
+		limiter:  rate.NewLimiter(rate.Limit(rps), burst),
+		adaptive: adaptive,
+		minRPS:   minRPS,
+		maxRPS:   maxRPS,
+	}
+}
+
+// Wait blocks until the quota window has reset (if it was exhausted) and a token
+// is available.
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	pause := time.Until(l.pauseUntil)
+	l.mu.Unlock()
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return l.limiter.Wait(ctx)
+}
+
+// Limit returns the current request rate in requests per second.
+func (l *AdaptiveLimiter) Limit() float64 {
+	return float64(l.limiter.Limit())
+}
+
+// Quota returns the quota reported by the most recent response.
+func (l *AdaptiveLimiter) Quota() QuotaState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.quota
+}
+
+// PausedUntil returns when an exhausted quota resets, or the zero time when the
+// limiter is not paused.
+func (l *AdaptiveLimiter) PausedUntil(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pauseUntil.After(now) {
+		return l.pauseUntil
+	}
+	return time.Time{}
+}
+
+// Observe feeds the limiter with the rate limit headers of a Reddit response.
+// Responses without the headers are ignored.
+func (l *AdaptiveLimiter) Observe(h http.Header, now time.Time) {
+	remaining, used, reset, ok := parseRateLimitHeaders(h)
+	if !ok {
+		return
+	}
+	metrics.RedditRateLimitRemaining.WithLabelValues(l.label).Set(remaining)
+	metrics.RedditRateLimitUsed.WithLabelValues(l.label).Set(float64(used))
+	metrics.RedditRateLimitResetSeconds.WithLabelValues(l.label).Set(reset.Seconds())
+	l.mu.Lock()
+	l.quota = QuotaState{Known: true, Remaining: remaining, Used: used, ResetAt: now.Add(reset)}
+	l.mu.Unlock()
+	if !l.adaptive {
+		return
+	}
+
+	if remaining < 1 {
+		l.mu.Lock()
+		if until := now.Add(reset); until.After(l.pauseUntil) {
+			l.pauseUntil = until
+			metrics.RedditRateLimitExhausted.WithLabelValues(l.label).Inc()
+		}
+		l.mu.Unlock()
+		return
+	}
+
+	target := l.maxRPS
+	if reset > 0 {
+		target = remaining * quotaSafetyMargin / reset.Seconds()
+	}
+	current := l.Limit()
+	next := current
+	if target < current {
+		next = current + rateDecreaseFactor*(target-current)
+	} else {
+		next = current + rateIncreaseFactor*(target-current)
+	}
+	if next < l.minRPS {
+		next = l.minRPS
+	}
+	if next > l.maxRPS {
+		next = l.maxRPS
+	}
+	l.limiter.SetLimitAt(now, rate.Limit(next))
+	metrics.CrawlerRateLimitRPS.WithLabelValues(l.label).Set(next)
+}
+
+// observeAttempt is the httpx.Observer that feeds the shared limiter.
+func observeAttempt(info httpx.AttemptInfo) {
+	if info.Header != nil {
+		getLimiter().Observe(info.Header, time.Now())
+	}
+}
+
+// forgetMetrics drops the limiter's gauges once its credential leaves the pool, so
+// dashboards do not keep showing the last quota it saw.
+func (l *AdaptiveLimiter) forgetMetrics() {
+	metrics.CrawlerRateLimitRPS.DeleteLabelValues(l.label)
+	metrics.RedditRateLimitRemaining.DeleteLabelValues(l.label)
+	metrics.RedditRateLimitUsed.DeleteLabelValues(l.label)
+	metrics.RedditRateLimitResetSeconds.DeleteLabelValues(l.label)
+}
+
+// parseRateLimitHeaders reads X-Ratelimit-Remaining, X-Ratelimit-Used and
+// X-Ratelimit-Reset. Remaining is fractional on Reddit (e.g. "598.0").
+func parseRateLimitHeaders(h http.Header) (remaining float64, used int, reset time.Duration, ok bool) {
+	rem := strings.TrimSpace(h.Get("X-Ratelimit-Remaining"))
+	rst := strings.TrimSpace(h.Get("X-Ratelimit-Reset"))
+	if rem == "" || rst == "" {
+		return 0, 0, 0, false
+	}
+	remaining, err := strconv.ParseFloat(rem, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	resetSecs, err := strconv.ParseFloat(rst, 64)
+	if err != nil || resetSecs < 0 {
+		return 0, 0, 0, false
+	}
+	if u, err := strconv.ParseFloat(strings.TrimSpace(h.Get("X-Ratelimit-Used")), 64); err == nil {
+		used = int(u)
+	}
+	return remaining, used, time.Duration(resetSecs * float64(time.Second)), true
+}
+
 var (
-	limiter     *rate.Limiter
+	limiter     *AdaptiveLimiter
 	limiterOnce sync.Once
 )
 
 // initLimiter creates the rate limiter based on config
 func initLimiter() {
 	cfg := config.Load()
-	// Create token bucket rate limiter with configured RPS and burst
-	limiter = rate.NewLimiter(rate.Limit(cfg.CrawlerRPS), cfg.CrawlerBurstSize)
+	// Token bucket starting at the configured RPS and burst; adaptive mode retunes it
+	limiter = NewAdaptiveLimiter(EnvCredentialLabel, cfg.CrawlerRPS, cfg.CrawlerBurstSize, cfg.CrawlerAdaptiveRateLimit, cfg.CrawlerMinRPS, cfg.CrawlerMaxRPS)
 }
 
 // getLimiter returns the singleton rate limiter instance
-func getLimiter() *rate.Limiter {
+func getLimiter() *AdaptiveLimiter {
 	limiterOnce.Do(initLimiter)
 	return limiter
 }
@@ -1,11 +1,16 @@
 package crawler
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 )
 
 func TestRateLimiterDefault(t *testing.T) {
@@ -97,3 +102,100 @@ func TestRateLimiterEnforcesRate(t *testing.T) {
 		t.Errorf("Rate limit too slow: expected <=%v, got %v", expectedMax, elapsed)
 	}
 }
+
+func rateLimitHeader(remaining, used, reset string) http.Header {
+	h := http.Header{}
+	h.Set("X-Ratelimit-Remaining", remaining)
+	h.Set("X-Ratelimit-Used", used)
+	h.Set("X-Ratelimit-Reset", reset)
+	return h
+}
+
+func TestAdaptiveLimiter_FollowsQuota(t *testing.T) {
+	l := NewAdaptiveLimiter("test", 1.0, 1, true, 0.1, 5.0)
+	now := time.Now()
+
+	// Plenty of quota: 500 requests left for 100s allows ~4.5 rps; speed up gradually.
+	l.Observe(rateLimitHeader("500.0", "100", "100"), now)
+	if got := l.Limit(); got <= 1.0 || got >= 4.5 {
+		t.Fatalf("expected a gradual speed-up, got %.2f rps", got)
+	}
+	for i := 0; i < 50; i++ {
+		l.Observe(rateLimitHeader("500.0", "100", "100"), now)
+	}
+	if got := l.Limit(); got < 4.0 || got > 5.0 {
+		t.Fatalf("expected rate to approach the quota rate, got %.2f rps", got)
+	}
+
+	// Quota nearly gone: 10 requests for 100s allows 0.09 rps; slow down towards the floor.
+	for i := 0; i < 20; i++ {
+		l.Observe(rateLimitHeader("10", "590", "100"), now)
+	}
+	if got := l.Limit(); got != 0.1 {
+		t.Fatalf("expected rate clamped to minimum 0.1, got %.2f rps", got)
+	}
+
+	// Headers missing: no change.
+	l.Observe(http.Header{}, now)
+	if got := l.Limit(); got != 0.1 {
+		t.Fatalf("expected rate unchanged without headers, got %.2f rps", got)
+	}
+}
+
+func TestAdaptiveLimiter_PausesUntilReset(t *testing.T) {
+	l := NewAdaptiveLimiter("test", 100, 1, true, 1, 100)
+	l.Observe(rateLimitHeader("0", "600", "0.15"), time.Now())
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected to wait for the quota reset, waited %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Observe(rateLimitHeader("0", "600", "10"), time.Now())
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("expected cancelled wait to return an error")
+	}
+}
+
+func TestAdaptiveLimiter_DisabledIgnoresHeaders(t *testing.T) {
+	l := NewAdaptiveLimiter("test", 1.0, 1, false, 0.1, 5.0)
+	l.Observe(rateLimitHeader("0", "600", "60"), time.Now())
+	l.Observe(rateLimitHeader("500", "100", "100"), time.Now())
+	if got := l.Limit(); got != 1.0 {
+		t.Fatalf("expected fixed rate when adaptive mode is off, got %.2f", got)
+	}
+	start := time.Now()
+	_ = l.Wait(context.Background())
+	if time.Since(start) > 50*time.Millisecond {
+		t.Fatal("expected no pause when adaptive mode is off")
+	}
+}
+
+func TestAdaptiveLimiter_QuotaGaugesPerCredential(t *testing.T) {
+	a := NewAdaptiveLimiter("gauge-a", 1.0, 1, true, 0.1, 5.0)
+	b := NewAdaptiveLimiter("gauge-b", 1.0, 1, true, 0.1, 5.0)
+	now := time.Now()
+	a.Observe(rateLimitHeader("500.0", "100", "100"), now)
+	b.Observe(rateLimitHeader("20.0", "580", "30"), now)
+
+	if got := testutil.ToFloat64(metrics.RedditRateLimitRemaining.WithLabelValues("gauge-a")); got != 500 {
+		t.Errorf("remaining{gauge-a} = %v, want 500", got)
+	}
+	if got := testutil.ToFloat64(metrics.RedditRateLimitRemaining.WithLabelValues("gauge-b")); got != 20 {
+		t.Errorf("remaining{gauge-b} = %v, want 20", got)
+	}
+
+	before := testutil.CollectAndCount(metrics.RedditRateLimitRemaining)
+	b.forgetMetrics()
+	if after := testutil.CollectAndCount(metrics.RedditRateLimitRemaining); after != before-1 {
+		t.Errorf("series after forgetting gauge-b = %d, want %d", after, before-1)
+	}
+	if got := testutil.ToFloat64(metrics.RedditRateLimitRemaining.WithLabelValues("gauge-a")); got != 500 {
+		t.Errorf("remaining{gauge-a} = %v after removing gauge-b, want 500", got)
+	}
+}
@@ -1,6 +1,8 @@
 package crawler
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,6 +12,8 @@ import (
 	"time"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/redditapi"
 )
 
 // SubredditInfo holds metadata about a subreddit
@@ -17,6 +21,31 @@ type SubredditInfo struct {
 	Title       string `json:"title"`
 	Description string `json:"public_description"`
 	Subscribers int    `json:"subscribers"`
+	Over18      bool   `json:"over18"`
+
+	// Stored in subreddit_snapshots on each crawl.
+	ActiveUsers   *int    `json:"active_user_count"` // nil when Reddit hides it
+	SubredditType string  `json:"subreddit_type"`
+	CreatedUTC    float64 `json:"created_utc"`
+	Language      string  `json:"lang"`
+}
+
+// snapshot converts the /about data into a subreddit_snapshots row.
+func (info *SubredditInfo) snapshot(subredditID int32) db.SubredditSnapshot {
+	s := db.SubredditSnapshot{
+		SubredditID:   subredditID,
+		Subscribers:   sql.NullInt32{Int32: int32(info.Subscribers), Valid: info.Subscribers >= 0},
+		Over18:        info.Over18,
+		SubredditType: sql.NullString{String: info.SubredditType, Valid: info.SubredditType != ""},
+		Language:      sql.NullString{String: info.Language, Valid: info.Language != ""},
+	}
+	if info.ActiveUsers != nil {
+		s.ActiveUsers = sql.NullInt32{Int32: int32(*info.ActiveUsers), Valid: true}
+	}
+	if info.CreatedUTC > 0 {
+		s.CreatedUTC = sql.NullTime{Time: time.Unix(int64(info.CreatedUTC), 0).UTC(), Valid: true}
+	}
+	return s
 }
 
 // FetchUserSubredditsConfig holds configurable options for subreddit discovery.
@@ -30,6 +59,7 @@ type FetchUserSubredditsConfig struct {
 type Post struct {
 	ID         string    `json:"id"`
 	Title      string    `json:"title"`
+	Subreddit  string    `json:"subreddit"`
 	Author     string    `json:"author"`
 	Permalink  string    `json:"permalink"`
 	Score      int       `json:"score"`
@@ -39,24 +69,77 @@ type Post struct {
 	CreatedAt  time.Time `json:"-"`
 	IsSelf     bool      `json:"is_self"`
 	Selftext   string    `json:"selftext"`
+	// RemovedByCategory is set by Reddit on removed posts: "deleted" when the
+	// author deleted it, otherwise who removed it ("moderator", "reddit", ...).
+	RemovedByCategory string `json:"removed_by_category"`
+	// CrosspostParentList holds the original post of a crosspost.
+	CrosspostParentList []struct {
+		Subreddit string `json:"subreddit"`
+	} `json:"crosspost_parent_list"`
 }
 
 var subredditMentionRegex = regexp.MustCompile(`(?i)/r/([a-zA-Z0-9_]+)`)
 
+// listingPageDelay spaces out consecutive pages of the same listing.
+var listingPageDelay = 1 * time.Second
+
+// CrawlSubreddit fetches subreddit metadata and posts using the default listing plan.
 func CrawlSubreddit(subreddit string) (*SubredditInfo, []Post, error) {
+	return CrawlSubredditWithPlan(subreddit, DefaultListingPlan(config.Load()))
+}
+
+// CrawlSubredditWithPlan fetches subreddit metadata and then each listing of the plan
+// in order, following `after` cursors up to the listing's page budget. Posts seen in an
+// earlier listing are skipped, and crawling stops once the plan's post budget is reached.
+func CrawlSubredditWithPlan(subreddit string, plan ListingPlan) (*SubredditInfo, []Post, error) {
 	subreddit = strings.ToLower(strings.TrimSpace(subreddit))
 
-	aboutURL := fmt.Sprintf("https://oauth.reddit.com/r/%s/about", subreddit)
+	info, err := fetchSubredditAbout(subreddit)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, err := crawlPlanListings(context.Background(), subreddit, plan)
+	return info, posts, err
+}
+
+// crawlPlanListings fetches the posts of each listing of the plan; see
+// CrawlSubredditWithPlan.
+func crawlPlanListings(ctx context.Context, subreddit string, plan ListingPlan) ([]Post, error) {
+	var (
+		allPosts []Post
+		err      error
+	)
+	seen := make(map[string]bool)
+	for _, listing := range plan.Listings {
+		if len(allPosts) >= plan.MaxPosts {
+			break
+		}
+		before := len(allPosts)
+		allPosts, err = crawlListing(ctx, subreddit, listing, plan.MaxPosts, seen, allPosts)
+		if err != nil {
+			return allPosts, err
+		}
+		log.Printf("📄 r/%s %s: %d new posts", subreddit, listing, len(allPosts)-before)
+	}
+
+	log.Printf("📥 Fetched %d posts from r/%s (plan %s)", len(allPosts), subreddit, plan)
+	return allPosts, nil
+}
+
+// fetchSubredditAbout fetches a subreddit's metadata. Non-200 responses are
+// returned as classified Reddit API errors.
+func fetchSubredditAbout(subreddit string) (*SubredditInfo, error) {
+	aboutURL := redditAPIURL(fmt.Sprintf("/r/%s/about", subreddit))
 	resp, err := authenticatedGet(aboutURL)
 	if err != nil {
 		log.Printf("⚠️ Failed to fetch subreddit %s: %v", subreddit, err)
-		return nil, nil, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("⚠️ Non-200 status for subreddit %s: %d", subreddit, resp.StatusCode)
-		return nil, nil, fmt.Errorf("failed to fetch subreddit: %s", resp.Status)
+		return nil, fmt.Errorf("failed to fetch subreddit r/%s: %w", subreddit, redditapi.ClassifyError(resp))
 	}
 
 	var aboutWrapper struct {
@@ -64,82 +147,104 @@ func CrawlSubreddit(subreddit string) (*SubredditInfo, []Post, error) {
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&aboutWrapper); err != nil {
 		log.Printf("⚠️ Failed to decode subreddit %s response: %v", subreddit, err)
-		return nil, nil, err
+		return nil, err
 	}
+	return &aboutWrapper.Data, nil
+}
 
-	// Get targets and filters from centralized config
-	cfg := config.Load()
-	targetPosts := cfg.MaxPostsPerSub
-	sortMode := cfg.PostsSort         // top, hot, new, rising
-	timeFilter := cfg.PostsTimeFilter // hour, day, week, month, year, all (for top)
-	var allPosts []Post
+// crawlListing follows one listing through its `after` cursor, appending posts not yet
+// in seen until the page budget runs out or posts reaches maxPosts. It stops with
+// ctx's error when the job is cancelled between pages.
+func crawlListing(ctx context.Context, subreddit string, listing ListingSpec, maxPosts int, seen map[string]bool, posts []Post) ([]Post, error) {
 	var after string
-
-	// Keep fetching posts until we reach the target or run out of posts
-	for len(allPosts) < targetPosts {
-		remaining := targetPosts - len(allPosts)
-		if remaining <= 0 {
-			break
+	for n := 0; withinPageBudget(n, listing.MaxPages) && len(posts) < maxPosts; n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return posts, ctx.Err()
+			case <-time.After(listingPageDelay):
+			}
 		}
-		limit := remaining
+		limit := maxPosts - len(posts)
 		if limit > 100 {
 			limit = 100
 		}
-		base := fmt.Sprintf("https://oauth.reddit.com/r/%s/%s?limit=%d", subreddit, sortMode, limit)
-		if sortMode == "top" || sortMode == "controversial" {
-			base += "&t=" + timeFilter
+		postsURL := redditAPIURL(fmt.Sprintf("/r/%s/%s?limit=%d", subreddit, listing.Sort, limit))
+		if listing.usesTimeFilter() && listing.TimeFilter != "" {
+			postsURL += "&t=" + listing.TimeFilter
 		}
-		postsURL := base
 		if after != "" {
 			postsURL += "&after=" + after
 		}
 
-		resp, err = authenticatedGet(postsURL)
+		page, err := fetchListingPage(subreddit, postsURL)
 		if err != nil {
-			log.Printf("⚠️ Failed to fetch posts for subreddit %s: %v", subreddit, err)
-			return &aboutWrapper.Data, allPosts, err
-		}
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("⚠️ Non-200 status for posts r/%s: %d", subreddit, resp.StatusCode)
-			resp.Body.Close()
-			return &aboutWrapper.Data, allPosts, fmt.Errorf("failed to fetch posts: %s", resp.Status)
+			return posts, err
 		}
 
-		var postsWrapper struct {
-			Data struct {
-				Children []struct {
-					Data Post `json:"data"`
-				} `json:"children"`
-				After string `json:"after"`
-			} `json:"data"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&postsWrapper); err != nil {
-			log.Printf("⚠️ Failed to decode posts for subreddit %s: %v", subreddit, err)
-			return &aboutWrapper.Data, allPosts, err
-		}
-		resp.Body.Close()
-
-		for _, child := range postsWrapper.Data.Children {
-			p := child.Data
-			if p.CreatedUTC > 0 {
-				p.CreatedAt = time.Unix(int64(p.CreatedUTC), 0)
+		for _, p := range page.Posts {
+			if seen[p.ID] {
+				continue
 			}
-			allPosts = append(allPosts, p)
-			if len(allPosts) >= targetPosts {
+			seen[p.ID] = true
+			posts = append(posts, p)
+			if len(posts) >= maxPosts {
 				break
 			}
 		}
 
-		if postsWrapper.Data.After == "" || len(postsWrapper.Data.Children) == 0 {
+		if page.After == "" || len(page.Posts) == 0 {
 			break
 		}
-		after = postsWrapper.Data.After
+		after = page.After
+	}
+	return posts, nil
+}
+
+// listingPage is one page of a subreddit listing with its pagination cursors.
+type listingPage struct {
+	Posts  []Post
+	After  string
+	Before string
+}
+
+// fetchListingPage fetches and decodes one listing page. Non-200 responses are
+// returned as classified Reddit API errors.
+func fetchListingPage(subreddit, postsURL string) (listingPage, error) {
+	resp, err := authenticatedGet(postsURL)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch posts for subreddit %s: %v", subreddit, err)
+		return listingPage{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("⚠️ Non-200 status for posts r/%s: %d", subreddit, resp.StatusCode)
+		return listingPage{}, fmt.Errorf("failed to fetch posts for r/%s: %w", subreddit, redditapi.ClassifyError(resp))
+	}
 
-		time.Sleep(1 * time.Second)
+	var postsWrapper struct {
+		Data struct {
+			Children []struct {
+				Data Post `json:"data"`
+			} `json:"children"`
+			After  string `json:"after"`
+			Before string `json:"before"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&postsWrapper); err != nil {
+		log.Printf("⚠️ Failed to decode posts for subreddit %s: %v", subreddit, err)
+		return listingPage{}, err
 	}
 
-	log.Printf("📥 Fetched %d posts from r/%s", len(allPosts), subreddit)
-	return &aboutWrapper.Data, allPosts, nil
+	page := listingPage{After: postsWrapper.Data.After, Before: postsWrapper.Data.Before}
+	for _, child := range postsWrapper.Data.Children {
+		p := child.Data
+		if p.CreatedUTC > 0 {
+			p.CreatedAt = time.Unix(int64(p.CreatedUTC), 0)
+		}
+		page.Posts = append(page.Posts, p)
+	}
+	return page, nil
 }
 
 func extractMentionedSubreddits(posts []Post) []string {
@@ -164,3 +269,28 @@ func extractMentionedSubreddits(posts []Post) []string {
 	}
 	return results
 }
+
+// SubredditRefs lists the other subreddits a post mentions in its title or
+// selftext and the subreddit it was crossposted from, by lowercase name.
+func (p Post) SubredditRefs() []db.PostSubredditRef {
+	own := strings.ToLower(p.Subreddit)
+	seen := make(map[db.PostSubredditRef]bool)
+	var refs []db.PostSubredditRef
+	add := func(name, kind string) {
+		r := db.PostSubredditRef{Target: strings.ToLower(name), Kind: kind}
+		if r.Target == "" || r.Target == own || seen[r] {
+			return
+		}
+		seen[r] = true
+		refs = append(refs, r)
+	}
+	for _, text := range []string{p.Title, p.Selftext} {
+		for _, m := range subredditMentionRegex.FindAllStringSubmatch(text, -1) {
+			add(m[1], db.LinkTypeMention)
+		}
+	}
+	for _, parent := range p.CrosspostParentList {
+		add(parent.Subreddit, db.LinkTypeCrosspost)
+	}
+	return refs
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/redditapi"
 )
 
 // fakeAccessToken avoids hitting Reddit OAuth in tests
@@ -67,3 +68,52 @@ func TestCrawlSubreddit_Pagination(t *testing.T) {
 		t.Fatalf("expected 3 posts, got %d", len(posts))
 	}
 }
+
+func TestCrawlSubreddit_ClassifiesPrivate(t *testing.T) {
+	config.ResetForTest()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"reason": "private", "message": "Forbidden", "error": 403}`))
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		u = strings.Replace(u, "https://oauth.reddit.com", server.URL, 1)
+		return http.Get(u)
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	_, _, err := CrawlSubreddit("secret")
+	if err == nil {
+		t.Fatal("expected error for private subreddit")
+	}
+	if got := FailureReason(err); got != redditapi.ReasonPrivate {
+		t.Errorf("FailureReason() = %q, want %q", got, redditapi.ReasonPrivate)
+	}
+	if !IsTerminalFailure(err) {
+		t.Error("expected private subreddit to be a terminal failure")
+	}
+}
+
+func TestSubredditInfoSnapshot(t *testing.T) {
+	var info SubredditInfo
+	body := `{"subscribers": 1200, "over18": true, "active_user_count": 35, "subreddit_type": "restricted", "created_utc": 1257894000.0, "lang": "de"}`
+	if err := json.Unmarshal([]byte(body), &info); err != nil {
+		t.Fatal(err)
+	}
+	s := info.snapshot(9)
+	if s.SubredditID != 9 || s.Subscribers.Int32 != 1200 || !s.Over18 || s.ActiveUsers.Int32 != 35 ||
+		s.SubredditType.String != "restricted" || s.Language.String != "de" || s.CreatedUTC.Time.Year() != 2009 {
+		t.Errorf("unexpected snapshot %+v", s)
+	}
+
+	// Reddit reports active_user_count as null for some subreddits.
+	var hidden SubredditInfo
+	if err := json.Unmarshal([]byte(`{"subscribers": 5, "active_user_count": null}`), &hidden); err != nil {
+		t.Fatal(err)
+	}
+	if s := hidden.snapshot(9); s.ActiveUsers.Valid || s.CreatedUTC.Valid || s.Language.Valid {
+		t.Errorf("expected unknown fields to be NULL, got %+v", s)
+	}
+}
@@ -3,6 +3,7 @@ package crawler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -16,18 +17,36 @@ import (
 	"github.com/onnwee/reddit-cluster-map/backend/internal/secrets"
 )
 
-// tokenManager handles OAuth token lifecycle with proactive refresh.
+// tokenManager handles OAuth token lifecycle with proactive refresh. mu is only
+// held to read or swap state, never across a token request, so a slow refresh
+// does not block readers of the credentials or the cached token.
 type tokenManager struct {
 	mu           sync.RWMutex
 	accessToken  string
 	tokenExpiry  time.Time
 	refreshTimer *time.Timer
+	refreshing   *tokenRefresh // the token request in flight, if any
 
 	// credentials - can be updated for rotation
 	clientID     string
 	clientSecret string
+
+	// limiter paces token requests; nil uses the global crawler limiter.
+	limiter *AdaptiveLimiter
+}
+
+// tokenRefresh is one token request shared by every caller that needs a token
+// while it is in flight.
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
 }
 
+// errCredentialRejected marks a token request refused because Reddit no longer
+// accepts the client ID/secret (revoked or deleted app).
+var errCredentialRejected = errors.New("reddit rejected OAuth credentials")
+
 var globalTokenManager = &tokenManager{}
 
 // ValidateOAuthCredentials validates OAuth credentials at startup.
@@ -39,7 +58,11 @@ func ValidateOAuthCredentials() error {
 // RotateOAuthCredentials allows updating OAuth credentials at runtime.
 // This enables zero-downtime credential rotation.
 func RotateOAuthCredentials(newClientID, newClientSecret string) error {
-	return globalTokenManager.rotateCredentials(newClientID, newClientSecret)
+	if err := globalTokenManager.rotateCredentials(newClientID, newClientSecret); err != nil {
+		return err
+	}
+	redditCredentials.restore(EnvCredentialLabel)
+	return nil
 }
 
 // initTokenManager initializes the token manager with credentials from config.
@@ -63,33 +86,45 @@ func initTokenManager() error {
 }
 
 // getAccessToken returns a valid access token, refreshing if necessary.
+// Concurrent callers share a single token request.
 func (tm *tokenManager) getAccessToken() (string, error) {
-	tm.mu.RLock()
+	tm.mu.Lock()
 	// Check if we have a valid token (with 60s buffer)
 	if tm.accessToken != "" && time.Now().Add(60*time.Second).Before(tm.tokenExpiry) {
 		token := tm.accessToken
-		tm.mu.RUnlock()
+		tm.mu.Unlock()
 		return token, nil
 	}
-	tm.mu.RUnlock()
+	if call := tm.refreshing; call != nil {
+		tm.mu.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+	call := &tokenRefresh{done: make(chan struct{})}
+	tm.refreshing = call
+	clientID, clientSecret := tm.clientID, tm.clientSecret
+	tm.mu.Unlock()
 
-	// Need to refresh - acquire write lock
-	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	token, expiresIn, err := tm.requestToken(clientID, clientSecret)
 
-	// Double-check after acquiring write lock (another goroutine may have refreshed)
-	if tm.accessToken != "" && time.Now().Add(60*time.Second).Before(tm.tokenExpiry) {
-		return tm.accessToken, nil
+	tm.mu.Lock()
+	tm.refreshing = nil
+	// Credentials rotated during the request make its token stale; the next
+	// call fetches one for the new credentials.
+	if err == nil && tm.clientID == clientID && tm.clientSecret == clientSecret {
+		tm.storeTokenLocked(token, expiresIn)
 	}
-
-	// Fetch new token
-	return tm.refreshTokenLocked()
+	tm.mu.Unlock()
+	call.token, call.err = token, err
+	close(call.done)
+	return token, err
 }
 
-// refreshTokenLocked fetches a new access token. Must be called with write lock held.
-func (tm *tokenManager) refreshTokenLocked() (string, error) {
-	if tm.clientID == "" || tm.clientSecret == "" {
-		return "", fmt.Errorf("OAuth credentials not initialized")
+// requestToken fetches a new access token for the given credentials and
+// returns it with its lifetime. It must not be called with mu held.
+func (tm *tokenManager) requestToken(clientID, clientSecret string) (string, time.Duration, error) {
+	if clientID == "" || clientSecret == "" {
+		return "", 0, fmt.Errorf("OAuth credentials not initialized")
 	}
 
 	data := url.Values{}
@@ -98,8 +133,8 @@ func (tm *tokenManager) refreshTokenLocked() (string, error) {
 
 	ua := config.Load().UserAgent
 	build := func() (*http.Request, error) {
-		req, _ := http.NewRequest("POST", "https://www.reddit.com/api/v1/access_token", strings.NewReader(data.Encode()))
-		req.SetBasicAuth(tm.clientID, tm.clientSecret)
+		req, _ := http.NewRequest("POST", redditAuthURL("/api/v1/access_token"), strings.NewReader(data.Encode()))
+		req.SetBasicAuth(clientID, clientSecret)
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		req.Header.Set("User-Agent", ua)
 		return req, nil
@@ -107,21 +142,30 @@ func (tm *tokenManager) refreshTokenLocked() (string, error) {
 
 	// Token requests also respect the global pacing to avoid burst traffic during retries.
 	pre := func(ctx context.Context, attempt int) error {
+		if tm.limiter != nil {
+			return tm.limiter.Wait(ctx)
+		}
 		waitForRateLimit()
 		return nil
 	}
 
-	resp, err := httpx.DoWithRetryFactory(httpClient, build, pre)
+	resp, err := doWithBreaker(EndpointToken, func() (*http.Response, error) {
+		return httpx.DoWithRetryFactory(httpClient, build, pre)
+	})
 	if err != nil {
 		// Don't log secrets in error messages
 		log.Printf("⚠️ Failed to request access token: %v", err)
-		return "", err
+		return "", 0, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		log.Printf("⚠️ Token request rejected with status: %s", resp.Status)
+		return "", 0, fmt.Errorf("token request failed: %s: %w", resp.Status, errCredentialRejected)
+	}
 	if resp.StatusCode != 200 {
 		log.Printf("⚠️ Token request failed with status: %s", resp.Status)
-		return "", fmt.Errorf("token request failed: %s", resp.Status)
+		return "", 0, fmt.Errorf("token request failed: %s", resp.Status)
 	}
 
 	var tokenResp struct {
@@ -131,16 +175,21 @@ func (tm *tokenManager) refreshTokenLocked() (string, error) {
 
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
 		log.Printf("⚠️ Failed to decode token response: %v", err)
-		return "", err
+		return "", 0, err
 	}
 
 	if tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("received empty access token")
+		return "", 0, fmt.Errorf("received empty access token")
 	}
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
+}
 
+// storeTokenLocked caches a token and schedules its proactive refresh. Must be
+// called with the write lock held.
+func (tm *tokenManager) storeTokenLocked(token string, expiresIn time.Duration) {
 	// Store token with safety buffer (renew 60s before expiry)
-	tm.accessToken = tokenResp.AccessToken
-	expiryDuration := time.Duration(tokenResp.ExpiresIn) * time.Second
+	tm.accessToken = token
+	expiryDuration := expiresIn
 	if expiryDuration > 120*time.Second {
 		expiryDuration -= 60 * time.Second // Renew 60s early
 	} else {
@@ -148,16 +197,13 @@ func (tm *tokenManager) refreshTokenLocked() (string, error) {
 	}
 	tm.tokenExpiry = time.Now().Add(expiryDuration)
 
-	// Schedule proactive refresh
+	// Schedule proactive refresh; by then the cached token counts as expired
 	if tm.refreshTimer != nil {
 		tm.refreshTimer.Stop()
 	}
 	tm.refreshTimer = time.AfterFunc(expiryDuration, func() {
-		tm.mu.Lock()
-		defer tm.mu.Unlock()
-		// Proactively refresh token
 		log.Printf("🔄 Proactively refreshing OAuth token")
-		if _, err := tm.refreshTokenLocked(); err != nil {
+		if _, err := tm.getAccessToken(); err != nil {
 			log.Printf("⚠️ Proactive token refresh failed: %v", err)
 		} else {
 			log.Printf("✓ Token refreshed successfully")
@@ -165,7 +211,6 @@ func (tm *tokenManager) refreshTokenLocked() (string, error) {
 	})
 
 	log.Printf("✓ Obtained access token (expires in %v)", expiryDuration)
-	return tm.accessToken, nil
 }
 
 // rotateCredentials allows updating credentials without downtime.
@@ -175,29 +220,49 @@ func (tm *tokenManager) rotateCredentials(newClientID, newClientSecret string) e
 		return fmt.Errorf("new credentials cannot be empty")
 	}
 
+	// Get a token with the new credentials before switching to them, so a
+	// failure leaves the old ones in place
+	token, expiresIn, err := tm.requestToken(newClientID, newClientSecret)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate with new credentials: %w", err)
+	}
+
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-
 	oldID := tm.clientID
-	oldSecret := tm.clientSecret
 	tm.clientID = newClientID
 	tm.clientSecret = newClientSecret
-
-	// Immediately try to get a token with new credentials
-	_, err := tm.refreshTokenLocked()
-	if err != nil {
-		// Rollback on failure
-		tm.clientID = oldID
-		tm.clientSecret = oldSecret
-		return fmt.Errorf("failed to authenticate with new credentials: %w", err)
-	}
+	tm.storeTokenLocked(token, expiresIn)
 
 	log.Printf("✓ Credentials rotated successfully (old: %s, new: %s)",
 		secrets.Mask(oldID), secrets.Mask(newClientID))
 	return nil
 }
 
-// getAccessToken is the package-level function used by the rest of the crawler.
-func getAccessToken() (string, error) {
-	return globalTokenManager.getAccessToken()
+// invalidate drops the cached token so the next request fetches a new one.
+func (tm *tokenManager) invalidate() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.accessToken = ""
+	tm.tokenExpiry = time.Time{}
+}
+
+// expiry returns when the cached token is renewed, or the zero time without a token.
+func (tm *tokenManager) expiry() time.Time {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if tm.accessToken == "" {
+		return time.Time{}
+	}
+	return tm.tokenExpiry
+}
+
+// stop cancels the proactive refresh of a credential that left the pool.
+func (tm *tokenManager) stop() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.refreshTimer != nil {
+		tm.refreshTimer.Stop()
+		tm.refreshTimer = nil
+	}
 }
@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/redditapi"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/utils"
+)
+
+// handlePostJob crawls a single post thread: the post and its comment tree. The
+// post's subreddit is created if needed and queued for its own crawl.
+func handlePostJob(ctx context.Context, q *db.Queries, job Job) error {
+	if _, err := job.Payload.Normalize(JobTypePost); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidJob, err)
+	}
+	postID := job.Payload.PostID
+
+	page, err := fetchListingPage("t3_"+postID, redditAPIURL("/api/info?id=t3_"+postID+"&raw_json=1"))
+	if err != nil {
+		return err
+	}
+	if len(page.Posts) == 0 || page.Posts[0].Subreddit == "" {
+		return fmt.Errorf("post %s: %w", postID, &redditapi.APIError{
+			Type: redditapi.ErrorNotFound, StatusCode: 404, Message: "post not found",
+		})
+	}
+	post := page.Posts[0]
+
+	from := discoverySource{Hop: job.Payload.Hop + 1, JobID: job.ID, EnqueuedBy: "post"}
+	subredditID, err := ensureDiscoveredSubreddit(ctx, q, LoadDiscoveryPolicy(ctx, q), post.Subreddit, from)
+	if err != nil {
+		return err
+	}
+	inserted, err := crawlAndStorePosts(ctx, q, subredditID, []Post{post})
+	if err != nil {
+		return err
+	}
+	if err := crawlAndStoreComments(ctx, q, subredditID, []Post{post}, utils.GetEnvAsInt("MAX_COMMENT_DEPTH", 5), inserted); err != nil {
+		return err
+	}
+	log.Printf("🧵 Crawled thread t3_%s in r/%s", postID, post.Subreddit)
+	return nil
+}
+
+// handleSearchJob runs a keyword search, stores the matching posts and queues the
+// subreddits they were posted in.
+func handleSearchJob(ctx context.Context, q *db.Queries, job Job) error {
+	if _, err := job.Payload.Normalize(JobTypeSearch); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidJob, err)
+	}
+	p := job.Payload
+
+	limit := config.Load().MaxPostsPerSub
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	page, err := fetchListingPage("search:"+p.Query, searchURL(p, limit))
+	if err != nil {
+		return err
+	}
+
+	bySubreddit := make(map[string][]Post)
+	for _, post := range page.Posts {
+		if post.Subreddit == "" {
+			continue
+		}
+		name := strings.ToLower(post.Subreddit)
+		bySubreddit[name] = append(bySubreddit[name], post)
+	}
+	names := make([]string, 0, len(bySubreddit))
+	for name := range bySubreddit {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policy := LoadDiscoveryPolicy(ctx, q)
+	from := discoverySource{Hop: p.Hop + 1, JobID: job.ID, EnqueuedBy: "search"}
+	stored := 0
+	for _, name := range names {
+		subredditID, err := ensureDiscoveredSubreddit(ctx, q, policy, name, from)
+		if err != nil {
+			log.Printf("⚠️ Failed to ensure subreddit r/%s: %v", name, err)
+			continue
+		}
+		inserted, err := crawlAndStorePosts(ctx, q, subredditID, bySubreddit[name])
+		if err != nil {
+			return err
+		}
+		stored += len(inserted)
+	}
+	log.Printf("🔎 Search %q: %d posts stored across %d subreddits", p.Query, stored, len(names))
+	return nil
+}
+
+// searchURL builds the Reddit search URL for a normalized search payload.
+func searchURL(p JobPayload, limit int) string {
+	v := url.Values{}
+	v.Set("q", p.Query)
+	v.Set("sort", p.Sort)
+	v.Set("t", p.TimeFilter)
+	v.Set("limit", fmt.Sprintf("%d", limit))
+	v.Set("type", "link")
+	v.Set("raw_json", "1")
+	if p.Subreddit != "" {
+		v.Set("restrict_sr", "1")
+		return redditAPIURL(fmt.Sprintf("/r/%s/search?%s", strings.ToLower(p.Subreddit), v.Encode()))
+	}
+	return redditAPIURL("/search?" + v.Encode())
+}
+
+// ensureDiscoveredSubreddit creates a subreddit seen by a post or search job, so
+// that the job's posts can be stored. Only if the discovery policy allows it is the
+// subreddit's distance recorded and a crawl queued.
+func ensureDiscoveredSubreddit(ctx context.Context, q *db.Queries, policy DiscoveryPolicy, name string, from discoverySource) (int32, error) {
+	id, err := ensureSubredditByName(ctx, q, name)
+	if err != nil {
+		return 0, err
+	}
+	if reason := policy.CheckName(name, from.Hop); reason != "" {
+		metrics.CrawlerDiscoveryExcluded.WithLabelValues(reason).Inc()
+		return id, nil
+	}
+	if err := recordDiscovery(ctx, q, id, from); err != nil {
+		log.Printf("⚠️ Failed to record discovery of r/%s: %v", name, err)
+	}
+	if err := EnsureJob(ctx, q, id, from.EnqueuedBy); err != nil {
+		log.Printf("⚠️ Failed to enqueue r/%s: %v", name, err)
+	}
+	return id, nil
+}
@@ -12,8 +12,8 @@ import (
 // FetchUserSubreddits fetches the list of subreddits a user has posted or commented in.
 func FetchUserSubreddits(username string, limit int) ([]string, error) {
 	endpoints := []string{
-		fmt.Sprintf("https://oauth.reddit.com/user/%s/submitted.json?limit=%d", username, limit),
-		fmt.Sprintf("https://oauth.reddit.com/user/%s/comments.json?limit=%d", username, limit),
+		redditAPIURL(fmt.Sprintf("/user/%s/submitted.json?limit=%d", username, limit)),
+		redditAPIURL(fmt.Sprintf("/user/%s/comments.json?limit=%d", username, limit)),
 	}
 
 	subreddits := make(map[string]bool)
@@ -64,7 +64,7 @@ func FetchUserSubreddits(username string, limit int) ([]string, error) {
 func FetchRecentUserSubreddits(username string, limit int) ([]string, error) {
 	uname := strings.TrimSpace(username)
 	// Attempt 1: OAuth user listing
-	u1 := fmt.Sprintf("https://oauth.reddit.com/user/%s/.json?limit=%d&raw_json=1", url.PathEscape(uname), limit)
+	u1 := redditAPIURL(fmt.Sprintf("/user/%s/.json?limit=%d&raw_json=1", url.PathEscape(uname), limit))
 	if subs, ok, err := tryUserListingOAuth(u1); err == nil && ok {
 		return subs, nil
 	} else if err != nil && !ok {
@@ -78,15 +78,15 @@ func FetchRecentUserSubreddits(username string, limit int) ([]string, error) {
 	q.Set("limit", fmt.Sprintf("%d", limit))
 	q.Set("sort", "new")
 	q.Set("type", "link")
-	u2 := "https://oauth.reddit.com/search.json?" + q.Encode()
+	u2 := redditAPIURL("/search.json?" + q.Encode())
 	if subs, ok, err := trySearchOAuth(u2); err == nil && ok {
 		return subs, nil
 	} else if err != nil && !ok {
 		return nil, err
 	}
 
-	// Attempt 3: Public old.reddit.com listing
-	u3 := fmt.Sprintf("https://old.reddit.com/user/%s/.json?limit=%d&raw_json=1", url.PathEscape(uname), limit)
+	// Attempt 3: Public (old.reddit.com) listing
+	u3 := redditPublicURL(fmt.Sprintf("/user/%s/.json?limit=%d&raw_json=1", url.PathEscape(uname), limit))
 	if subs, ok, err := tryUserListingPublic(u3); err == nil && ok {
 		return subs, nil
 	} else if err != nil && !ok {
@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Watermark remembers the newest post the crawler has seen in a subreddit, so later
+// jobs can ask Reddit only for posts newer than it.
+type Watermark struct {
+	SubredditID       int32
+	NewestFullname    string    // fullname (t3_...) of the newest post seen
+	NewestCreatedAt   time.Time // creation time of that post
+	NewestSeenAt      time.Time // when the crawler first saw that post
+	LastFullCrawlAt   time.Time // last crawl that ran the full listing plan
+	LastIncrementalAt time.Time // last crawl that only fetched newer posts
+}
+
+// LoadWatermark returns the stored watermark for a subreddit. The boolean is false
+// when the subreddit has never been crawled with watermarks.
+func LoadWatermark(ctx context.Context, q *db.Queries, subredditID int32) (Watermark, bool, error) {
+	const sel = `SELECT newest_post_fullname, newest_post_created_at, newest_post_seen_at, last_full_crawl_at, last_incremental_crawl_at
+                 FROM subreddit_watermarks WHERE subreddit_id = $1`
+	var (
+		fullname                                 sql.NullString
+		created, seen, fullCrawl, incrementalRun sql.NullTime
+	)
+	err := q.DB().QueryRowContext(ctx, sel, subredditID).Scan(&fullname, &created, &seen, &fullCrawl, &incrementalRun)
+	if err == sql.ErrNoRows {
+		return Watermark{SubredditID: subredditID}, false, nil
+	}
+	if err != nil {
+		return Watermark{SubredditID: subredditID}, false, err
+	}
+	return Watermark{
+		SubredditID:       subredditID,
+		NewestFullname:    fullname.String,
+		NewestCreatedAt:   created.Time,
+		NewestSeenAt:      seen.Time,
+		LastFullCrawlAt:   fullCrawl.Time,
+		LastIncrementalAt: incrementalRun.Time,
+	}, true, nil
+}
+
+// Advance moves the watermark to the newest of posts, if it is newer than the
+// current one. It reports whether the watermark moved.
+func (w *Watermark) Advance(posts []Post, now time.Time) bool {
+	moved := false
+	for _, p := range posts {
+		if p.ID == "" || p.CreatedAt.IsZero() {
+			continue
+		}
+		if !p.CreatedAt.After(w.NewestCreatedAt) {
+			continue
+		}
+		w.NewestFullname = "t3_" + p.ID
+		w.NewestCreatedAt = p.CreatedAt
+		w.NewestSeenAt = now
+		moved = true
+	}
+	return moved
+}
+
+// SaveWatermark upserts a subreddit's watermark. Watermarks live in their own table
+// so that recording them does not bump subreddits.updated_at and trigger needless
+// incremental precalculation.
+func SaveWatermark(ctx context.Context, q *db.Queries, w Watermark) error {
+	const stmt = `INSERT INTO subreddit_watermarks
+                  (subreddit_id, newest_post_fullname, newest_post_created_at, newest_post_seen_at, last_full_crawl_at, last_incremental_crawl_at, updated_at)
+                  VALUES ($1, $2, $3, $4, $5, $6, now())
+                  ON CONFLICT (subreddit_id) DO UPDATE SET
+                    newest_post_fullname = EXCLUDED.newest_post_fullname,
+                    newest_post_created_at = EXCLUDED.newest_post_created_at,
+                    newest_post_seen_at = EXCLUDED.newest_post_seen_at,
+                    last_full_crawl_at = EXCLUDED.last_full_crawl_at,
+                    last_incremental_crawl_at = EXCLUDED.last_incremental_crawl_at,
+                    updated_at = now()`
+	_, err := q.DB().ExecContext(ctx, stmt, w.SubredditID,
+		sql.NullString{String: w.NewestFullname, Valid: w.NewestFullname != ""},
+		nullTime(w.NewestCreatedAt), nullTime(w.NewestSeenAt),
+		nullTime(w.LastFullCrawlAt), nullTime(w.LastIncrementalAt))
+	return err
+}
+
+// MarkCommentsChecked records when a post's comment tree was last fetched.
+func MarkCommentsChecked(ctx context.Context, q *db.Queries, postID string, comments int) error {
+	const stmt = `INSERT INTO post_comment_checks (post_id, checked_at, comment_count)
+                  VALUES ($1, now(), $2)
+                  ON CONFLICT (post_id) DO UPDATE SET checked_at = now(), comment_count = EXCLUDED.comment_count`
+	_, err := q.DB().ExecContext(ctx, stmt, postID, comments)
+	return err
+}
+
+// ActivePostsForCommentRefresh returns stored posts of a subreddit that were created
+// inside the activity window and whose comments were last checked more than
+// refreshEvery ago (or never), newest first.
+func ActivePostsForCommentRefresh(ctx context.Context, q *db.Queries, subredditID int32, window, refreshEvery time.Duration, limit int) ([]Post, error) {
+	const sel = `SELECT p.id, COALESCE(p.title, ''), COALESCE(p.permalink, ''), p.created_at
+                 FROM posts p
+                 LEFT JOIN post_comment_checks c ON c.post_id = p.id
+                 WHERE p.subreddit_id = $1
+                   AND p.created_at > now() - $2::interval
+                   AND (c.checked_at IS NULL OR c.checked_at < now() - $3::interval)
+                 ORDER BY p.created_at DESC
+                 LIMIT $4`
+	rows, err := q.DB().QueryContext(ctx, sel, subredditID, window.String(), refreshEvery.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var posts []Post
+	for rows.Next() {
+		var (
+			p       Post
+			created sql.NullTime
+		)
+		if err := rows.Scan(&p.ID, &p.Title, &p.Permalink, &created); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = created.Time
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// CrawlSubredditSince fetches subreddit metadata and the posts newer than the
+// watermark. It walks /new through Reddit's `before` cursor starting at the
+// watermark post, up to maxPages pages and maxPosts posts. When the watermark post
+// has been deleted Reddit returns an empty page for it, so an empty first page is
+// double-checked against the newest page of /new using creation times.
+func CrawlSubredditSince(subreddit string, wm Watermark, maxPosts, maxPages int) (*SubredditInfo, []Post, error) {
+	subreddit = strings.ToLower(strings.TrimSpace(subreddit))
+
+	info, err := fetchSubredditAbout(subreddit)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, err := crawlPostsSince(context.Background(), subreddit, wm, maxPosts, maxPages)
+	return info, posts, err
+}
+
+// crawlPostsSince fetches the posts newer than the watermark; see
+// CrawlSubredditSince. It stops with ctx's error when the job is cancelled
+// between pages.
+func crawlPostsSince(ctx context.Context, subreddit string, wm Watermark, maxPosts, maxPages int) ([]Post, error) {
+	var posts []Post
+	seen := make(map[string]bool)
+	before := wm.NewestFullname
+	for n := 0; withinPageBudget(n, maxPages) && len(posts) < maxPosts; n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return posts, ctx.Err()
+			case <-time.After(listingPageDelay):
+			}
+		}
+		limit := maxPosts - len(posts)
+		if limit > 100 {
+			limit = 100
+		}
+		postsURL := redditAPIURL(fmt.Sprintf("/r/%s/new?limit=%d&before=%s", subreddit, limit, before))
+		page, err := fetchListingPage(subreddit, postsURL)
+		if err != nil {
+			return posts, err
+		}
+
+		if n == 0 && len(page.Posts) == 0 {
+			newest, err := fetchListingPage(subreddit, redditAPIURL(fmt.Sprintf("/r/%s/new?limit=%d", subreddit, limit)))
+			if err != nil {
+				return posts, err
+			}
+			for _, p := range newest.Posts {
+				if p.CreatedAt.After(wm.NewestCreatedAt) && !seen[p.ID] {
+					seen[p.ID] = true
+					posts = append(posts, p)
+				}
+			}
+			break
+		}
+
+		for _, p := range page.Posts {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			posts = append(posts, p)
+			if len(posts) >= maxPosts {
+				break
+			}
+		}
+
+		// Pages are newest first; the next page of newer posts starts before the first item.
+		next := page.Before
+		if next == "" && len(page.Posts) == limit {
+			next = "t3_" + page.Posts[0].ID
+		}
+		if next == "" || len(page.Posts) == 0 {
+			break
+		}
+		before = next
+	}
+
+	log.Printf("📥 Fetched %d new posts from r/%s since %s", len(posts), subreddit, wm.NewestFullname)
+	return posts, nil
+}
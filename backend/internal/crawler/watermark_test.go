@@ -0,0 +1,158 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+)
+
+func TestUseIncrementalCrawl(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{CrawlIncremental: true, CrawlFullRecrawlInterval: 24 * time.Hour}
+	wm := Watermark{NewestFullname: "t3_abc", LastFullCrawlAt: now.Add(-time.Hour)}
+
+	if !useIncrementalCrawl(cfg, wm, true, now) {
+		t.Error("expected incremental crawl with a recent full crawl")
+	}
+	if useIncrementalCrawl(cfg, wm, false, now) {
+		t.Error("expected full crawl without a watermark")
+	}
+	if useIncrementalCrawl(cfg, Watermark{LastFullCrawlAt: wm.LastFullCrawlAt}, true, now) {
+		t.Error("expected full crawl when the watermark has no post")
+	}
+	if useIncrementalCrawl(cfg, wm, true, now.Add(48*time.Hour)) {
+		t.Error("expected full crawl once the recrawl interval passed")
+	}
+	cfg.CrawlIncremental = false
+	if useIncrementalCrawl(cfg, wm, true, now) {
+		t.Error("expected full crawl when incremental crawling is disabled")
+	}
+}
+
+func TestWatermarkAdvance(t *testing.T) {
+	base := time.Unix(1730000000, 0)
+	wm := Watermark{NewestFullname: "t3_old", NewestCreatedAt: base}
+	now := base.Add(time.Hour)
+
+	if wm.Advance([]Post{{ID: "older", CreatedAt: base.Add(-time.Minute)}}, now) {
+		t.Fatal("watermark must not move backwards")
+	}
+	moved := wm.Advance([]Post{
+		{ID: "b", CreatedAt: base.Add(2 * time.Minute)},
+		{ID: "c", CreatedAt: base.Add(5 * time.Minute)},
+		{ID: "a", CreatedAt: base.Add(time.Minute)},
+	}, now)
+	if !moved || wm.NewestFullname != "t3_c" || !wm.NewestSeenAt.Equal(now) {
+		t.Fatalf("unexpected watermark %+v", wm)
+	}
+}
+
+func TestCrawlSubredditSince(t *testing.T) {
+	oldDelay := listingPageDelay
+	listingPageDelay = 0
+	defer func() { listingPageDelay = oldDelay }()
+
+	base := time.Unix(1730000000, 0)
+	post := func(id string, age time.Duration) map[string]interface{} {
+		return map[string]interface{}{"data": map[string]interface{}{"id": id, "author": "u", "created_utc": float64(base.Add(age).Unix())}}
+	}
+	listing := func(before string, children ...map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"data": map[string]interface{}{"children": children, "before": before}}
+	}
+	deleted := false
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/r/test/about":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"title": "t"}})
+		case "/r/test/new":
+			before := r.URL.Query().Get("before")
+			switch {
+			case before == "t3_w" && !deleted:
+				json.NewEncoder(w).Encode(listing("t3_n2", post("n2", 2*time.Minute), post("n1", time.Minute)))
+			case before == "t3_n2":
+				json.NewEncoder(w).Encode(listing("", post("n3", 3*time.Minute)))
+			case before == "":
+				json.NewEncoder(w).Encode(listing("", post("n3", 3*time.Minute), post("n2", 2*time.Minute), post("w", 0), post("x", -time.Minute)))
+			default:
+				json.NewEncoder(w).Encode(listing(""))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		return http.Get(strings.Replace(u, "https://oauth.reddit.com", server.URL, 1))
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	wm := Watermark{NewestFullname: "t3_w", NewestCreatedAt: base}
+	_, posts, err := CrawlSubredditSince("test", wm, 10, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := postIDs(posts); got != "n2,n1,n3" {
+		t.Fatalf("unexpected posts %s", got)
+	}
+
+	// A deleted watermark post yields an empty before-page; fall back to creation times.
+	deleted = true
+	_, posts, err = CrawlSubredditSince("test", wm, 10, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := postIDs(posts); got != "n3,n2" {
+		t.Fatalf("unexpected fallback posts %s", got)
+	}
+}
+
+func TestCrawlPostsSince_StopsWhenCancelled(t *testing.T) {
+	oldDelay := listingPageDelay
+	listingPageDelay = time.Hour
+	defer func() { listingPageDelay = oldDelay }()
+
+	// Every page is full and points at a newer one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"children": []map[string]interface{}{{"data": map[string]interface{}{"id": "n1", "author": "u"}}},
+			"before":   "t3_n1",
+		}})
+	}))
+	defer server.Close()
+
+	oldAuth := authenticatedGet
+	authenticatedGet = func(u string) (*http.Response, error) {
+		return http.Get(strings.Replace(u, "https://oauth.reddit.com", server.URL, 1))
+	}
+	defer func() { authenticatedGet = oldAuth }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	posts, err := crawlPostsSince(ctx, "test", Watermark{NewestFullname: "t3_w"}, 10, 3)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(posts) != 1 || time.Since(start) > time.Minute {
+		t.Errorf("got %d posts after %v, want the first page without waiting out the delay", len(posts), time.Since(start))
+	}
+}
+
+func postIDs(posts []Post) string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	return strings.Join(ids, ",")
+}
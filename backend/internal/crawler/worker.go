@@ -3,16 +3,19 @@ package crawler
 import (
 	"context"
 	"database/sql"
-
+	"errors"
 	"fmt"
 	"log"
-
+	"os"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq" // Import the postgres driver
 	"github.com/onnwee/reddit-cluster-map/backend/internal/admin"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
 )
 
 // checkAndRequeueStaleSubreddits checks for subreddits that haven't been crawled in 7 days
@@ -43,33 +46,118 @@ func checkAndRequeueStaleSubreddits(ctx context.Context, q *db.Queries) error {
 	return nil
 }
 
-// Crawler represents a Reddit crawler instance
+// jobLeaser abstracts the lease operations of the crawl job queue so the worker
+// pool can be exercised without a database.
+type jobLeaser interface {
+	Claim(ctx context.Context, workerID string) (Job, error)
+	Renew(ctx context.Context, jobID int32, workerID string) (bool, error)
+	Release(ctx context.Context, jobID int32, workerID string) error
+}
+
+// dbLeaser implements jobLeaser on top of the crawl_jobs table.
+type dbLeaser struct {
+	q     *db.Queries
+	lease time.Duration
+}
+
+func (l dbLeaser) Claim(ctx context.Context, workerID string) (Job, error) {
+	return ClaimNextJob(ctx, l.q, workerID, l.lease)
+}
+
+func (l dbLeaser) Renew(ctx context.Context, jobID int32, workerID string) (bool, error) {
+	return RenewJobLease(ctx, l.q, jobID, workerID, l.lease)
+}
+
+func (l dbLeaser) Release(ctx context.Context, jobID int32, workerID string) error {
+	return ReleaseJob(ctx, l.q, jobID, workerID)
+}
+
+// Crawler represents a Reddit crawler instance.
+// It runs a pool of workers that each claim one job at a time from the shared
+// queue, so several crawler processes can safely use the same database.
 type Crawler struct {
-	queries *db.Queries
-	stop    chan struct{}
+	queries  *db.Queries
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	id        string // process identity used as the prefix of worker lease owners
+	workers   int
+	heartbeat time.Duration
+	poll      time.Duration
+
+	leaser  jobLeaser
+	handle  func(ctx context.Context, job Job) error
+	enabled func(ctx context.Context) bool
+	// openBreakers lists endpoint classes whose circuit breakers are open; while
+	// any are, workers stop claiming jobs. Nil disables the check.
+	openBreakers func() []string
+
+	pauseMu sync.Mutex
+	paused  bool
 }
 
 // NewCrawler creates a new crawler instance
 func NewCrawler(q *db.Queries) *Crawler {
-	return &Crawler{
-		queries: q,
-		stop:    make(chan struct{}),
+	cfg := config.Load()
+	c := &Crawler{
+		queries:   q,
+		stop:      make(chan struct{}),
+		id:        processIdentity(),
+		workers:   cfg.CrawlerWorkers,
+		heartbeat: cfg.CrawlerHeartbeatInterval,
+		poll:      cfg.CrawlerPollInterval,
+		leaser:    dbLeaser{q: q, lease: cfg.CrawlerLeaseTimeout},
+
+		openBreakers: openBreakers,
 	}
+	c.handle = func(ctx context.Context, job Job) error { return handleJob(ctx, q, job) }
+	c.enabled = func(ctx context.Context) bool {
+		// Check if disabled via admin flag
+		enabled, _ := admin.GetBool(ctx, q, "crawler_enabled", true)
+		return enabled
+	}
+	return c
+}
+
+// processIdentity returns a host/pid based identifier that is unique across crawler processes.
+func processIdentity() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "crawler"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
 }
 
 // Start begins the crawler process
 func (c *Crawler) Start(ctx context.Context) {
-	// Main crawler loop: wakes up on a short interval, grabs one queued job, processes it.
-	log.Println("🚀 Starting crawler...")
+	log.Printf("🚀 Starting crawler with %d worker(s) (id=%s)...", c.workers, c.id)
 	// On start, reset stale in-progress jobs (e.g., container restarts)
 	cfg := config.Load()
 	_ = ResetIncompleteJobs(ctx, c.queries, time.Duration(cfg.ResetCrawlingAfterMin)*time.Minute)
-	ticker := time.NewTicker(5 * time.Second)
+	// Discovery distances are counted from the default subreddits.
+	if err := c.queries.MarkSubredditSeeds(ctx, DefaultSubs); err != nil {
+		log.Printf("⚠️ Failed to mark default subreddits as seeds: %v", err)
+	}
+	// Load pooled OAuth credentials before the workers start sending requests.
+	if err := SyncCredentials(ctx, c.queries); err != nil {
+		log.Printf("⚠️ Failed to load OAuth credential pool: %v", err)
+	}
 	staleTicker := time.NewTicker(6 * time.Hour)
 	maintenanceTicker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+	breakerTicker := time.NewTicker(15 * time.Second)
 	defer staleTicker.Stop()
 	defer maintenanceTicker.Stop()
+	defer breakerTicker.Stop()
+
+	// Workers share a context that is cancelled on shutdown so in-flight jobs are released.
+	workCtx, cancelWork := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	c.startWorkers(workCtx, &wg)
+	defer func() {
+		cancelWork()
+		wg.Wait()
+		log.Println("🛑 All crawler workers stopped")
+	}()
 
 	for {
 		select {
@@ -79,16 +167,18 @@ func (c *Crawler) Start(ctx context.Context) {
 		case <-c.stop:
 			log.Println("🛑 Crawler stopped by signal")
 			return
-		case <-ticker.C:
-			// Check if disabled via admin flag
-			enabled, _ := admin.GetBool(ctx, c.queries, "crawler_enabled", true)
-			if !enabled {
-				// skip quietly; avoid noisy logs every tick
-				continue
+		case <-breakerTicker.C:
+			// Publish breaker states for the admin API, which runs in another process.
+			if err := ReportBreakerStates(ctx, c.queries, c.id); err != nil {
+				log.Printf("⚠️ Failed to report circuit breaker states: %v", err)
+			}
+			// Pick up credentials added or disabled through the admin API and
+			// publish per-credential quota state.
+			if err := SyncCredentials(ctx, c.queries); err != nil {
+				log.Printf("⚠️ Failed to sync OAuth credential pool: %v", err)
 			}
-			// Pull the next queued job, if any, and process it end-to-end.
-			if err := c.processNextJob(ctx); err != nil {
-				log.Printf("⚠️ Error processing job: %v", err)
+			if err := ReportCredentialStates(ctx, c.queries, c.id); err != nil {
+				log.Printf("⚠️ Failed to report OAuth credential states: %v", err)
 			}
 		case <-maintenanceTicker.C:
 			// Requeue jobs that are ready to retry
@@ -112,18 +202,144 @@ func (c *Crawler) Start(ctx context.Context) {
 
 // Stop gracefully stops the crawler
 func (c *Crawler) Stop() {
-	close(c.stop)
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// startWorkers launches the worker pool; each worker exits when ctx is cancelled.
+func (c *Crawler) startWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	for i := 0; i < c.workers; i++ {
+		workerID := fmt.Sprintf("%s/%d", c.id, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.runWorker(ctx, workerID)
+		}()
+	}
+}
+
+// runWorker claims and processes jobs until ctx is cancelled, idling for the
+// poll interval whenever the queue is empty, the crawler is disabled, or a Reddit
+// circuit breaker is open.
+func (c *Crawler) runWorker(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		if !c.enabled(ctx) {
+			// skip quietly; avoid noisy logs every tick
+			sleepCtx(ctx, c.poll)
+			continue
+		}
+		if c.claimsPaused() {
+			sleepCtx(ctx, c.poll)
+			continue
+		}
+		job, err := c.leaser.Claim(ctx, workerID)
+		if err != nil {
+			if err != sql.ErrNoRows && ctx.Err() == nil {
+				log.Printf("⚠️ Worker %s failed to claim next job: %v", workerID, err)
+			}
+			sleepCtx(ctx, c.poll)
+			continue
+		}
+		c.processJob(ctx, workerID, job)
+	}
 }
 
-// processNextJob handles a single crawl job
-func (c *Crawler) processNextJob(ctx context.Context) error {
-	job, err := ClaimNextJob(ctx, c.queries)
+// claimsPaused reports whether any Reddit circuit breaker is open, logging once
+// per transition rather than on every poll.
+func (c *Crawler) claimsPaused() bool {
+	if c.openBreakers == nil {
+		return false
+	}
+	open := c.openBreakers()
+	paused := len(open) > 0
+	c.pauseMu.Lock()
+	changed := paused != c.paused
+	c.paused = paused
+	c.pauseMu.Unlock()
+	if changed {
+		logBreakerPause(paused, open)
+	}
+	return paused
+}
+
+// processJob runs a claimed job while a heartbeat keeps its lease alive.
+// If the lease is lost the job context is cancelled; if the worker is shutting
+// down, or the job stopped because a Reddit circuit breaker opened, the job is
+// released back to the queue instead of being marked failed.
+func (c *Crawler) processJob(ctx context.Context, workerID string, job Job) {
+	metrics.CrawlerWorkersBusy.Inc()
+	defer metrics.CrawlerWorkersBusy.Dec()
+
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+
+	hbDone := make(chan struct{})
+	go func() {
+		defer close(hbDone)
+		c.heartbeatLoop(jobCtx, cancelJob, workerID, job.ID)
+	}()
+
+	err := c.handle(jobCtx, job)
+	cancelJob()
+	<-hbDone
+
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown: hand the job back so another worker can pick it up right away.
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if rerr := c.leaser.Release(releaseCtx, job.ID, workerID); rerr != nil {
+			log.Printf("⚠️ Worker %s failed to release job #%d: %v", workerID, job.ID, rerr)
+		} else {
+			log.Printf("↩️ Worker %s released job #%d on shutdown", workerID, job.ID)
+		}
+		return
+	}
+	if errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+		// Reddit is unavailable, not the job: requeue it untouched for when the breaker closes.
+		if rerr := c.leaser.Release(ctx, job.ID, workerID); rerr != nil {
+			log.Printf("⚠️ Worker %s failed to release job #%d: %v", workerID, job.ID, rerr)
+		} else {
+			log.Printf("↩️ Worker %s released job #%d: %v", workerID, job.ID, err)
+		}
+		return
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil
+		log.Printf("⚠️ Error processing job #%d: %v", job.ID, err)
+	}
+}
+
+// heartbeatLoop renews the job lease every heartbeat interval until ctx is done.
+func (c *Crawler) heartbeatLoop(ctx context.Context, cancelJob context.CancelFunc, workerID string, jobID int32) {
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := c.leaser.Renew(ctx, jobID, workerID)
+			if err != nil {
+				// Transient DB errors are tolerated; the lease only lapses after the full timeout.
+				if ctx.Err() == nil {
+					log.Printf("⚠️ Worker %s failed to renew lease on job #%d: %v", workerID, jobID, err)
+				}
+				continue
+			}
+			if !ok {
+				log.Printf("⚠️ Worker %s lost lease on job #%d; abandoning it", workerID, jobID)
+				metrics.CrawlerLeasesLost.Inc()
+				cancelJob()
+				return
+			}
 		}
-		return fmt.Errorf("failed to claim next job: %w", err)
 	}
+}
 
-	return handleJob(ctx, c.queries, job)
+// sleepCtx waits for d or until ctx is cancelled, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
 }
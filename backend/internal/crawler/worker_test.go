@@ -0,0 +1,239 @@
+package crawler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/circuitbreaker"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// fakeLeaser is an in-memory jobLeaser that hands out queued jobs in order.
+type fakeLeaser struct {
+	mu       sync.Mutex
+	queue    []Job
+	owners   map[int32]string
+	released []int32
+	renewOK  bool
+}
+
+func newFakeLeaser(n int) *fakeLeaser {
+	l := &fakeLeaser{owners: map[int32]string{}, renewOK: true}
+	for i := 1; i <= n; i++ {
+		l.queue = append(l.queue, Job{CrawlJob: db.CrawlJob{ID: int32(i), SubredditID: int32(i), Status: "queued"}, Type: JobTypeSubreddit})
+	}
+	return l
+}
+
+func (l *fakeLeaser) Claim(ctx context.Context, workerID string) (Job, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue) == 0 {
+		return Job{}, sql.ErrNoRows
+	}
+	j := l.queue[0]
+	l.queue = l.queue[1:]
+	l.owners[j.ID] = workerID
+	j.Status = "crawling"
+	return j, nil
+}
+
+func (l *fakeLeaser) Renew(ctx context.Context, jobID int32, workerID string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.renewOK && l.owners[jobID] == workerID, nil
+}
+
+func (l *fakeLeaser) Release(ctx context.Context, jobID int32, workerID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owners[jobID] == workerID {
+		l.released = append(l.released, jobID)
+	}
+	return nil
+}
+
+func newTestCrawler(l jobLeaser, workers int, handle func(ctx context.Context, job Job) error) *Crawler {
+	return &Crawler{
+		stop:      make(chan struct{}),
+		id:        "test",
+		workers:   workers,
+		heartbeat: 10 * time.Millisecond,
+		poll:      5 * time.Millisecond,
+		leaser:    l,
+		handle:    handle,
+		enabled:   func(context.Context) bool { return true },
+	}
+}
+
+func TestWorkerPool_ProcessesJobsConcurrently(t *testing.T) {
+	leaser := newFakeLeaser(6)
+	var inFlight, maxInFlight, done int32
+	allDone := make(chan struct{})
+	var once sync.Once
+
+	c := newTestCrawler(leaser, 3, func(ctx context.Context, job Job) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		if atomic.AddInt32(&done, 1) == 6 {
+			once.Do(func() { close(allDone) })
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	c.startWorkers(ctx, &wg)
+
+	select {
+	case <-allDone:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out: processed %d/6 jobs", atomic.LoadInt32(&done))
+	}
+	cancel()
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&maxInFlight); got < 2 {
+		t.Errorf("expected jobs to run concurrently, max in flight = %d", got)
+	}
+	if len(leaser.released) != 0 {
+		t.Errorf("expected no released jobs, got %v", leaser.released)
+	}
+}
+
+func TestWorkerPool_ReleasesJobOnShutdown(t *testing.T) {
+	leaser := newFakeLeaser(1)
+	started := make(chan struct{})
+
+	c := newTestCrawler(leaser, 1, func(ctx context.Context, job Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	c.startWorkers(ctx, &wg)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("job never started")
+	}
+	cancel()
+	wg.Wait()
+
+	if len(leaser.released) != 1 || leaser.released[0] != 1 {
+		t.Fatalf("expected job 1 to be released on shutdown, got %v", leaser.released)
+	}
+}
+
+func TestWorkerPool_LostLeaseCancelsJob(t *testing.T) {
+	leaser := newFakeLeaser(1)
+	leaser.renewOK = false
+	cancelled := make(chan struct{})
+
+	c := newTestCrawler(leaser, 1, func(ctx context.Context, job Job) error {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+		case <-time.After(time.Second):
+		}
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	c.startWorkers(ctx, &wg)
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected job context to be cancelled after losing the lease")
+	}
+	cancel()
+	wg.Wait()
+
+	if len(leaser.released) != 0 {
+		t.Errorf("a job with a lost lease must not be released, got %v", leaser.released)
+	}
+}
+
+func TestWorkerPool_PausesClaimingWhileBreakerOpen(t *testing.T) {
+	leaser := newFakeLeaser(2)
+	var open atomic.Bool
+	open.Store(true)
+	var handled int32
+
+	c := newTestCrawler(leaser, 1, func(ctx context.Context, job Job) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	c.openBreakers = func() []string {
+		if open.Load() {
+			return []string{EndpointListing}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	c.startWorkers(ctx, &wg)
+
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&handled); got != 0 {
+		t.Fatalf("expected no jobs claimed while breaker open, got %d", got)
+	}
+
+	open.Store(false)
+	deadline := time.After(2 * time.Second)
+	for atomic.LoadInt32(&handled) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("expected claiming to resume after breaker closed, handled %d", atomic.LoadInt32(&handled))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	wg.Wait()
+}
+
+func TestWorkerPool_ReleasesJobOnOpenBreaker(t *testing.T) {
+	leaser := newFakeLeaser(1)
+	done := make(chan struct{})
+
+	c := newTestCrawler(leaser, 1, func(ctx context.Context, job Job) error {
+		defer close(done)
+		return fmt.Errorf("failed to fetch subreddit: %w", circuitbreaker.ErrCircuitOpen)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	c.startWorkers(ctx, &wg)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job never ran")
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	if len(leaser.released) != 1 || leaser.released[0] != 1 {
+		t.Fatalf("expected job 1 to be released after breaker opened, got %v", leaser.released)
+	}
+}
@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	"github.com/sqlc-dev/pqtype"
 )
@@ -18,6 +19,7 @@ SELECT
   COUNT(*) FILTER (WHERE status = 'crawling') AS running_count,
   COUNT(*) FILTER (WHERE status = 'failed') AS failed_count,
   COUNT(*) FILTER (WHERE status = 'success') AS completed_count,
+  COUNT(*) FILTER (WHERE status = 'terminal') AS terminal_count,
   COUNT(*) AS total_count
 FROM crawl_jobs
 `
@@ -27,6 +29,7 @@ type GetAdminCrawlJobStatsRow struct {
 	RunningCount   int64
 	FailedCount    int64
 	CompletedCount int64
+	TerminalCount  int64
 	TotalCount     int64
 }
 
@@ -38,6 +41,7 @@ func (q *Queries) GetAdminCrawlJobStats(ctx context.Context) (GetAdminCrawlJobSt
 		&i.RunningCount,
 		&i.FailedCount,
 		&i.CompletedCount,
+		&i.TerminalCount,
 		&i.TotalCount,
 	)
 	return i, err
@@ -73,7 +77,9 @@ func (q *Queries) GetAllServiceSettings(ctx context.Context) ([]ServiceSetting,
 const getCrawlJobByID = `-- name: GetCrawlJobByID :one
 SELECT 
   id,
-  subreddit_id,
+  COALESCE(subreddit_id, 0)::int AS subreddit_id,
+  job_type,
+  payload,
   status,
   retries,
   priority,
@@ -88,6 +94,8 @@ WHERE id = $1
 type GetCrawlJobByIDRow struct {
 	ID          int32
 	SubredditID int32
+	JobType     string
+	Payload     json.RawMessage
 	Status      string
 	Retries     sql.NullInt32
 	Priority    sql.NullInt32
@@ -103,6 +111,8 @@ func (q *Queries) GetCrawlJobByID(ctx context.Context, id int32) (GetCrawlJobByI
 	err := row.Scan(
 		&i.ID,
 		&i.SubredditID,
+		&i.JobType,
+		&i.Payload,
 		&i.Status,
 		&i.Retries,
 		&i.Priority,
@@ -169,18 +179,23 @@ func (q *Queries) ListAdminAuditLog(ctx context.Context, arg ListAdminAuditLogPa
 const listCrawlJobsByStatus = `-- name: ListCrawlJobsByStatus :many
 SELECT
   cj.id,
-  cj.subreddit_id,
-  s.name AS subreddit_name,
+  COALESCE(cj.subreddit_id, 0)::int AS subreddit_id,
+  COALESCE(s.name, '')::text AS subreddit_name,
+  cj.job_type,
+  cj.payload,
   cj.status,
   cj.retries,
   cj.priority,
   cj.last_attempt,
   cj.enqueued_by,
   cj.created_at,
-  cj.updated_at
+  cj.updated_at,
+  cj.failure_reason,
+  cj.last_error
 FROM crawl_jobs cj
-JOIN subreddits s ON s.id = cj.subreddit_id
+LEFT JOIN subreddits s ON s.id = cj.subreddit_id
 WHERE cj.status = $1
+  AND ($4::text = '' OR cj.job_type = $4::text)
 ORDER BY cj.priority DESC, cj.created_at DESC
 LIMIT $2::int OFFSET $3::int
 `
@@ -189,12 +204,15 @@ type ListCrawlJobsByStatusParams struct {
 	Status  string
 	Column2 int32
 	Column3 int32
+	Column4 string
 }
 
 type ListCrawlJobsByStatusRow struct {
 	ID            int32
 	SubredditID   int32
 	SubredditName string
+	JobType       string
+	Payload       json.RawMessage
 	Status        string
 	Retries       sql.NullInt32
 	Priority      sql.NullInt32
@@ -202,10 +220,17 @@ type ListCrawlJobsByStatusRow struct {
 	EnqueuedBy    sql.NullString
 	CreatedAt     sql.NullTime
 	UpdatedAt     sql.NullTime
+	FailureReason sql.NullString
+	LastError     sql.NullString
 }
 
 func (q *Queries) ListCrawlJobsByStatus(ctx context.Context, arg ListCrawlJobsByStatusParams) ([]ListCrawlJobsByStatusRow, error) {
-	rows, err := q.db.QueryContext(ctx, listCrawlJobsByStatus, arg.Status, arg.Column2, arg.Column3)
+	rows, err := q.db.QueryContext(ctx, listCrawlJobsByStatus,
+		arg.Status,
+		arg.Column2,
+		arg.Column3,
+		arg.Column4,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +242,8 @@ func (q *Queries) ListCrawlJobsByStatus(ctx context.Context, arg ListCrawlJobsBy
 			&i.ID,
 			&i.SubredditID,
 			&i.SubredditName,
+			&i.JobType,
+			&i.Payload,
 			&i.Status,
 			&i.Retries,
 			&i.Priority,
@@ -224,6 +251,8 @@ func (q *Queries) ListCrawlJobsByStatus(ctx context.Context, arg ListCrawlJobsBy
 			&i.EnqueuedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.FailureReason,
+			&i.LastError,
 		); err != nil {
 			return nil, err
 		}
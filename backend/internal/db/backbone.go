@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// LinkSignificance is the backbone p-value of a graph link.
+type LinkSignificance struct {
+	Source string
+	Target string
+	PValue float64
+	Kept   bool
+}
+
+// UpdateLinkBackbone replaces the backbone p-values of the links of a type. Links
+// of that type missing from items are reset to untested.
+func (q *Queries) UpdateLinkBackbone(ctx context.Context, linkType string, items []LinkSignificance) error {
+	const reset = `UPDATE graph_links SET backbone_pvalue = NULL, backbone_kept = NULL
+                   WHERE link_type = $1 AND backbone_pvalue IS NOT NULL`
+	if _, err := q.db.ExecContext(ctx, reset, linkType); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	sources := make([]string, len(items))
+	targets := make([]string, len(items))
+	pvalues := make([]float64, len(items))
+	kept := make([]bool, len(items))
+	for i, it := range items {
+		sources[i], targets[i], pvalues[i], kept[i] = it.Source, it.Target, it.PValue, it.Kept
+	}
+	const stmt = `UPDATE graph_links gl SET backbone_pvalue = v.p, backbone_kept = v.k
+                  FROM unnest($2::text[], $3::text[], $4::float8[], $5::bool[]) AS v(s, t, p, k)
+                  WHERE gl.link_type = $1 AND gl.source = v.s AND gl.target = v.t`
+	_, err := q.db.ExecContext(ctx, stmt, linkType, pq.Array(sources), pq.Array(targets), pq.Array(pvalues), pq.Array(kept))
+	return err
+}
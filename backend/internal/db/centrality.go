@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// GraphEdge is a graph link as an edge between two node IDs.
+type GraphEdge struct {
+	Source string
+	Target string
+	Weight float64
+}
+
+// NodeCentrality holds the structural importance measures of a graph node.
+type NodeCentrality struct {
+	ID             string
+	PageRank       float64
+	WeightedDegree float64
+	Betweenness    float64
+	KCore          int32
+}
+
+// ListGraphNodeIDs returns the IDs of all graph nodes.
+func (q *Queries) ListGraphNodeIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT id FROM graph_nodes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// ListGraphEdges returns all graph links.
+func (q *Queries) ListGraphEdges(ctx context.Context) ([]GraphEdge, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT source, target, weight FROM graph_links`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GraphEdge
+	for rows.Next() {
+		var e GraphEdge
+		if err := rows.Scan(&e.Source, &e.Target, &e.Weight); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// UpdateNodeCentrality stores the centrality of graph nodes, in batches.
+func (q *Queries) UpdateNodeCentrality(ctx context.Context, items []NodeCentrality) error {
+	const batch = 10000
+	const stmt = `UPDATE graph_nodes gn
+                  SET pagerank = v.pr, weighted_degree = v.wd, betweenness = v.bc, kcore = v.kc
+                  FROM unnest($1::text[], $2::float8[], $3::float8[], $4::float8[], $5::int[]) AS v(id, pr, wd, bc, kc)
+                  WHERE gn.id = v.id`
+	for start := 0; start < len(items); start += batch {
+		end := start + batch
+		if end > len(items) {
+			end = len(items)
+		}
+		n := end - start
+		ids := make([]string, n)
+		pr := make([]float64, n)
+		wd := make([]float64, n)
+		bc := make([]float64, n)
+		kc := make([]int32, n)
+		for i, c := range items[start:end] {
+			ids[i], pr[i], wd[i], bc[i], kc[i] = c.ID, c.PageRank, c.WeightedDegree, c.Betweenness, c.KCore
+		}
+		if _, err := q.db.ExecContext(ctx, stmt, pq.Array(ids), pq.Array(pr), pq.Array(wd), pq.Array(bc), pq.Array(kc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
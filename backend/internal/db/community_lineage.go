@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CommunityEvent is a step in the lineage of a community between two runs.
+type CommunityEvent struct {
+	ID          int64
+	RunID       int32
+	CommunityID int32
+	Event       string
+	RelatedID   sql.NullInt32
+	Jaccard     sql.NullFloat64
+	Size        int32
+	CreatedAt   time.Time
+}
+
+// ListCommunityMemberships returns the members of the current communities by
+// community ID.
+func (q *Queries) ListCommunityMemberships(ctx context.Context) (map[int32][]string, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT community_id, node_id FROM graph_community_members`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int32][]string{}
+	for rows.Next() {
+		var id int32
+		var node string
+		if err := rows.Scan(&id, &node); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], node)
+	}
+	return out, rows.Err()
+}
+
+// CreateCommunityWithID inserts a community under an ID it had in an earlier run.
+func (q *Queries) CreateCommunityWithID(ctx context.Context, id int32, arg CreateCommunityParams) (GraphCommunity, error) {
+	const stmt = `INSERT INTO graph_communities (id, label, size, modularity)
+                  VALUES ($1, $2, $3, $4)
+                  RETURNING id, label, size, modularity, created_at, updated_at`
+	var c GraphCommunity
+	err := q.db.QueryRowContext(ctx, stmt, id, arg.Label, arg.Size, arg.Modularity).
+		Scan(&c.ID, &c.Label, &c.Size, &c.Modularity, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// CreateCommunityEvents stores the lineage events of a run.
+func (q *Queries) CreateCommunityEvents(ctx context.Context, runID int32, events []CommunityEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]int32, len(events))
+	kinds := make([]string, len(events))
+	related := make([]sql.NullInt32, len(events))
+	jaccard := make([]sql.NullFloat64, len(events))
+	sizes := make([]int32, len(events))
+	for i, e := range events {
+		ids[i], kinds[i], related[i], jaccard[i], sizes[i] = e.CommunityID, e.Event, e.RelatedID, e.Jaccard, e.Size
+	}
+	const stmt = `INSERT INTO community_events (run_id, community_id, event, related_id, jaccard, size)
+                  SELECT $1, v.c, v.e, v.r, v.j, v.s
+                  FROM unnest($2::int[], $3::text[], $4::int[], $5::float8[], $6::int[]) AS v(c, e, r, j, s)`
+	_, err := q.db.ExecContext(ctx, stmt, runID, pq.Array(ids), pq.Array(kinds), pq.Array(related), pq.Array(jaccard), pq.Array(sizes))
+	return err
+}
+
+// ListCommunityEvents returns the events that happened to a community or
+// name it as the related community, oldest first.
+func (q *Queries) ListCommunityEvents(ctx context.Context, communityID int32) ([]CommunityEvent, error) {
+	const stmt = `SELECT id, run_id, community_id, event, related_id, jaccard, size, created_at
+                  FROM community_events
+                  WHERE community_id = $1 OR related_id = $1
+                  ORDER BY run_id, id`
+	rows, err := q.db.QueryContext(ctx, stmt, communityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CommunityEvent
+	for rows.Next() {
+		var e CommunityEvent
+		if err := rows.Scan(&e.ID, &e.RunID, &e.CommunityID, &e.Event, &e.RelatedID, &e.Jaccard, &e.Size, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CommunityRun records how a community detection run partitioned the graph.
+type CommunityRun struct {
+	ID          int32
+	Algorithm   string
+	Resolution  float64
+	Seed        sql.NullInt64
+	Modularity  float64 // at resolution 1
+	Quality     float64 // modularity at Resolution
+	CPM         float64 // Constant Potts Model at Resolution
+	Communities int32
+	Nodes       int32
+	CreatedAt   time.Time
+}
+
+// CreateCommunityRun stores a run and returns it with its ID and creation time.
+func (q *Queries) CreateCommunityRun(ctx context.Context, r CommunityRun) (CommunityRun, error) {
+	const stmt = `INSERT INTO community_runs (algorithm, resolution, seed, modularity, quality_at_resolution, cpm, communities, nodes)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+                  RETURNING id, created_at`
+	err := q.db.QueryRowContext(ctx, stmt, r.Algorithm, r.Resolution, r.Seed, r.Modularity, r.Quality, r.CPM, r.Communities, r.Nodes).
+		Scan(&r.ID, &r.CreatedAt)
+	return r, err
+}
+
+// SetCommunitiesRun links the communities stored since the last run to run id.
+func (q *Queries) SetCommunitiesRun(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, `UPDATE graph_communities SET run_id = $1 WHERE run_id IS NULL`, id)
+	return err
+}
+
+// GetLatestCommunityRun returns the run behind the current communities.
+func (q *Queries) GetLatestCommunityRun(ctx context.Context) (CommunityRun, error) {
+	const stmt = `SELECT id, algorithm, resolution, seed, modularity, quality_at_resolution, cpm, communities, nodes, created_at
+                  FROM community_runs ORDER BY id DESC LIMIT 1`
+	var r CommunityRun
+	err := q.db.QueryRowContext(ctx, stmt).
+		Scan(&r.ID, &r.Algorithm, &r.Resolution, &r.Seed, &r.Modularity, &r.Quality, &r.CPM, &r.Communities, &r.Nodes, &r.CreatedAt)
+	return r, err
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RemovedContent lists the posts and comments of a subreddit whose last observed
+// state was deleted or removed.
+type RemovedContent struct {
+	PostIDs    []string
+	CommentIDs []string
+}
+
+// ListRemovedContent returns the removed posts and comments of a subreddit.
+func (q *Queries) ListRemovedContent(ctx context.Context, subredditID int32) (RemovedContent, error) {
+	const stmt = `SELECT 'post', id FROM posts WHERE subreddit_id = $1 AND removed_state IS NOT NULL
+                  UNION ALL
+                  SELECT 'comment', id FROM comments WHERE subreddit_id = $1 AND removed_state IS NOT NULL`
+	var out RemovedContent
+	rows, err := q.db.QueryContext(ctx, stmt, subredditID)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, id string
+		if err := rows.Scan(&kind, &id); err != nil {
+			return out, err
+		}
+		if kind == "post" {
+			out.PostIDs = append(out.PostIDs, id)
+		} else {
+			out.CommentIDs = append(out.CommentIDs, id)
+		}
+	}
+	return out, rows.Err()
+}
+
+// RemovedActivityCount is the number of removed posts and comments an author has
+// in a subreddit.
+type RemovedActivityCount struct {
+	AuthorID    int32
+	SubredditID int32
+	Count       int32
+}
+
+// GetRemovedActivityCounts returns removed content per author and subreddit, the
+// part of GetUserSubredditActivityCount that precalculation can exclude.
+func (q *Queries) GetRemovedActivityCounts(ctx context.Context) ([]RemovedActivityCount, error) {
+	const stmt = `SELECT author_id, subreddit_id, COUNT(*)::int FROM (
+                    SELECT author_id, subreddit_id FROM posts WHERE removed_state IS NOT NULL
+                    UNION ALL
+                    SELECT author_id, subreddit_id FROM comments WHERE removed_state IS NOT NULL
+                  ) removed
+                  GROUP BY author_id, subreddit_id`
+	rows, err := q.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RemovedActivityCount
+	for rows.Next() {
+		var i RemovedActivityCount
+		if err := rows.Scan(&i.AuthorID, &i.SubredditID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// SubredditContentHistory summarizes what recrawls observed in a subreddit.
+type SubredditContentHistory struct {
+	Posts           int64 // stored posts
+	Comments        int64 // stored comments
+	DeletedPosts    int64 // deleted by their authors
+	DeletedComments int64
+	RemovedPosts    int64 // removed by moderators or Reddit
+	RemovedComments int64
+	Edits           int64 // edit events since the cutoff
+	ScoreTrend      []ScoreTrendPoint
+}
+
+// ScoreTrendPoint is the average post score observed on one day.
+type ScoreTrendPoint struct {
+	Day       time.Time
+	AvgScore  float64
+	Snapshots int64
+}
+
+// GetSubredditContentHistory returns the removal counts of a subreddit's content,
+// and the daily average of post score snapshots and the edits observed since.
+func (q *Queries) GetSubredditContentHistory(ctx context.Context, subredditID int32, since time.Time) (SubredditContentHistory, error) {
+	var h SubredditContentHistory
+	const counts = `SELECT
+        (SELECT COUNT(*) FROM posts WHERE subreddit_id = $1),
+        (SELECT COUNT(*) FROM comments WHERE subreddit_id = $1),
+        (SELECT COUNT(*) FROM posts WHERE subreddit_id = $1 AND removed_state = 'deleted'),
+        (SELECT COUNT(*) FROM comments WHERE subreddit_id = $1 AND removed_state = 'deleted'),
+        (SELECT COUNT(*) FROM posts WHERE subreddit_id = $1 AND removed_state = 'removed'),
+        (SELECT COUNT(*) FROM comments WHERE subreddit_id = $1 AND removed_state = 'removed'),
+        (SELECT COUNT(*) FROM content_events WHERE subreddit_id = $1 AND event = 'edited' AND observed_at >= $2)`
+	if err := q.db.QueryRowContext(ctx, counts, subredditID, since).Scan(
+		&h.Posts, &h.Comments, &h.DeletedPosts, &h.DeletedComments, &h.RemovedPosts, &h.RemovedComments, &h.Edits,
+	); err != nil {
+		return h, err
+	}
+
+	const trend = `SELECT date_trunc('day', observed_at) AS day, AVG(score)::float8, COUNT(*)
+                   FROM content_score_snapshots
+                   WHERE subreddit_id = $1 AND content_type = 'post' AND observed_at >= $2
+                   GROUP BY day ORDER BY day`
+	rows, err := q.db.QueryContext(ctx, trend, subredditID, since)
+	if err != nil {
+		return h, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ScoreTrendPoint
+		if err := rows.Scan(&p.Day, &p.AvgScore, &p.Snapshots); err != nil {
+			return h, err
+		}
+		h.ScoreTrend = append(h.ScoreTrend, p)
+	}
+	return h, rows.Err()
+}
@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 )
 
 const ageStarvedJobs = `-- name: AgeStarvedJobs :exec
@@ -100,6 +101,36 @@ func (q *Queries) EnqueueCrawlJob(ctx context.Context, arg EnqueueCrawlJobParams
 	return err
 }
 
+const enqueueTypedCrawlJob = `-- name: EnqueueTypedCrawlJob :one
+INSERT INTO crawl_jobs (job_type, dedupe_key, payload, status, retries, priority, max_retries, enqueued_by)
+VALUES ($1, $2, $3, 'queued', 0, $4, $5, $6)
+ON CONFLICT (job_type, dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('queued', 'crawling') DO NOTHING
+RETURNING id
+`
+
+type EnqueueTypedCrawlJobParams struct {
+	JobType    string
+	DedupeKey  sql.NullString
+	Payload    json.RawMessage
+	Priority   sql.NullInt32
+	MaxRetries sql.NullInt32
+	EnqueuedBy sql.NullString
+}
+
+func (q *Queries) EnqueueTypedCrawlJob(ctx context.Context, arg EnqueueTypedCrawlJobParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, enqueueTypedCrawlJob,
+		arg.JobType,
+		arg.DedupeKey,
+		arg.Payload,
+		arg.Priority,
+		arg.MaxRetries,
+		arg.EnqueuedBy,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getCrawlJobBySubredditID = `-- name: GetCrawlJobBySubredditID :one
 SELECT id, subreddit_id, status, retries, priority, last_attempt, duration_ms, enqueued_by, created_at, updated_at
 FROM crawl_jobs
@@ -140,7 +171,8 @@ func (q *Queries) GetCrawlJobBySubredditID(ctx context.Context, subredditID int3
 const listCrawlJobs = `-- name: ListCrawlJobs :many
 SELECT
   id,
-  subreddit_id,
+  COALESCE(subreddit_id, 0)::int AS subreddit_id,
+  job_type,
   status,
   retries,
   priority,
@@ -161,6 +193,7 @@ type ListCrawlJobsParams struct {
 type ListCrawlJobsRow struct {
 	ID          int32
 	SubredditID int32
+	JobType     string
 	Status      string
 	Retries     sql.NullInt32
 	Priority    sql.NullInt32
@@ -182,6 +215,7 @@ func (q *Queries) ListCrawlJobs(ctx context.Context, arg ListCrawlJobsParams) ([
 		if err := rows.Scan(
 			&i.ID,
 			&i.SubredditID,
+			&i.JobType,
 			&i.Status,
 			&i.Retries,
 			&i.Priority,
@@ -270,13 +304,22 @@ func (q *Queries) MarkCrawlJobStarted(ctx context.Context, id int32) error {
 	return err
 }
 
-const markCrawlJobSuccess = `-- name: MarkCrawlJobSuccess :exec
-UPDATE crawl_jobs SET status = 'success', updated_at = now() WHERE id = $1
+const markCrawlJobSuccess = `-- name: MarkCrawlJobSuccess :execrows
+UPDATE crawl_jobs SET status = 'success', failure_reason = NULL, last_error = NULL, updated_at = now()
+WHERE id = $1 AND lease_owner = $2 AND status = 'crawling'
 `
 
-func (q *Queries) MarkCrawlJobSuccess(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, markCrawlJobSuccess, id)
-	return err
+type MarkCrawlJobSuccessParams struct {
+	ID         int32
+	LeaseOwner sql.NullString
+}
+
+func (q *Queries) MarkCrawlJobSuccess(ctx context.Context, arg MarkCrawlJobSuccessParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markCrawlJobSuccess, arg.ID, arg.LeaseOwner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const markJobFailedWithRetry = `-- name: MarkJobFailedWithRetry :exec
@@ -307,6 +350,12 @@ WHERE status = 'failed'
   AND next_retry_at IS NOT NULL 
   AND next_retry_at <= now()
   AND retry_count < max_retries
+  AND NOT EXISTS (
+    SELECT 1 FROM crawl_jobs newer
+    WHERE newer.job_type = crawl_jobs.job_type
+      AND newer.dedupe_key = crawl_jobs.dedupe_key
+      AND newer.id <> crawl_jobs.id
+      AND (newer.id > crawl_jobs.id OR newer.status IN ('queued', 'crawling')))
 `
 
 func (q *Queries) RequeueRetryableJobs(ctx context.Context) error {
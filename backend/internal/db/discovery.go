@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MarkSubredditSeeds records the named subreddits as discovery seeds (hop 0).
+// Names are matched case-insensitively; subreddits that do not exist yet are ignored.
+func (q *Queries) MarkSubredditSeeds(ctx context.Context, names []string) error {
+	lower := make([]string, 0, len(names))
+	for _, n := range names {
+		if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+			lower = append(lower, n)
+		}
+	}
+	if len(lower) == 0 {
+		return nil
+	}
+	const stmt = `UPDATE subreddits SET hop_distance = 0, discovered_by_job_id = NULL
+                  WHERE lower(name) = ANY($1) AND hop_distance IS DISTINCT FROM 0`
+	_, err := q.db.ExecContext(ctx, stmt, pq.Array(lower))
+	return err
+}
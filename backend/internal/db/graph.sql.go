@@ -14,20 +14,27 @@ import (
 )
 
 const bulkInsertGraphLink = `-- name: BulkInsertGraphLink :exec
-INSERT INTO graph_links (source, target)
-SELECT $1, $2
+INSERT INTO graph_links (source, target, weight, link_type)
+SELECT $1, $2, $3, $4
 WHERE EXISTS (SELECT 1 FROM graph_nodes WHERE id = $1)
     AND EXISTS (SELECT 1 FROM graph_nodes WHERE id = $2)
-ON CONFLICT (source, target) DO NOTHING
+ON CONFLICT (source, target, link_type) DO UPDATE SET weight = EXCLUDED.weight, updated_at = now()
 `
 
 type BulkInsertGraphLinkParams struct {
-	Source string
-	Target string
+	Source   string
+	Target   string
+	Weight   float64
+	LinkType string
 }
 
 func (q *Queries) BulkInsertGraphLink(ctx context.Context, arg BulkInsertGraphLinkParams) error {
-	_, err := q.db.ExecContext(ctx, bulkInsertGraphLink, arg.Source, arg.Target)
+	_, err := q.db.ExecContext(ctx, bulkInsertGraphLink,
+		arg.Source,
+		arg.Target,
+		arg.Weight,
+		arg.LinkType,
+	)
 	return err
 }
 
@@ -390,19 +397,28 @@ func (q *Queries) CreateGraphDiff(ctx context.Context, arg CreateGraphDiffParams
 const createGraphLink = `-- name: CreateGraphLink :one
 INSERT INTO graph_links (
     source,
-    target
+    target,
+    weight,
+    link_type
 ) VALUES (
-    $1, $2
-) RETURNING id, source, target, created_at, updated_at
+    $1, $2, $3, $4
+) RETURNING id, source, target, created_at, updated_at, weight, link_type
 `
 
 type CreateGraphLinkParams struct {
-	Source string
-	Target string
+	Source   string
+	Target   string
+	Weight   float64
+	LinkType string
 }
 
 func (q *Queries) CreateGraphLink(ctx context.Context, arg CreateGraphLinkParams) (GraphLink, error) {
-	row := q.db.QueryRowContext(ctx, createGraphLink, arg.Source, arg.Target)
+	row := q.db.QueryRowContext(ctx, createGraphLink,
+		arg.Source,
+		arg.Target,
+		arg.Weight,
+		arg.LinkType,
+	)
 	var i GraphLink
 	err := row.Scan(
 		&i.ID,
@@ -410,6 +426,8 @@ func (q *Queries) CreateGraphLink(ctx context.Context, arg CreateGraphLinkParams
 		&i.Target,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Weight,
+		&i.LinkType,
 	)
 	return i, err
 }
@@ -464,24 +482,48 @@ const createSubredditRelationship = `-- name: CreateSubredditRelationship :one
 INSERT INTO subreddit_relationships (
     source_subreddit_id,
     target_subreddit_id,
-    overlap_count
+    overlap_count,
+    jaccard,
+    cosine,
+    pmi,
+    npmi,
+    lift
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) ON CONFLICT (source_subreddit_id, target_subreddit_id)
 DO UPDATE SET
     overlap_count = EXCLUDED.overlap_count,
+    jaccard = EXCLUDED.jaccard,
+    cosine = EXCLUDED.cosine,
+    pmi = EXCLUDED.pmi,
+    npmi = EXCLUDED.npmi,
+    lift = EXCLUDED.lift,
     updated_at = now()
-RETURNING id, source_subreddit_id, target_subreddit_id, overlap_count, created_at, updated_at
+RETURNING id, source_subreddit_id, target_subreddit_id, overlap_count, created_at, updated_at, jaccard, cosine, pmi, npmi, lift
 `
 
 type CreateSubredditRelationshipParams struct {
 	SourceSubredditID int32
 	TargetSubredditID int32
 	OverlapCount      int32
+	Jaccard           float64
+	Cosine            float64
+	Pmi               float64
+	Npmi              float64
+	Lift              float64
 }
 
 func (q *Queries) CreateSubredditRelationship(ctx context.Context, arg CreateSubredditRelationshipParams) (SubredditRelationship, error) {
-	row := q.db.QueryRowContext(ctx, createSubredditRelationship, arg.SourceSubredditID, arg.TargetSubredditID, arg.OverlapCount)
+	row := q.db.QueryRowContext(ctx, createSubredditRelationship,
+		arg.SourceSubredditID,
+		arg.TargetSubredditID,
+		arg.OverlapCount,
+		arg.Jaccard,
+		arg.Cosine,
+		arg.Pmi,
+		arg.Npmi,
+		arg.Lift,
+	)
 	var i SubredditRelationship
 	err := row.Scan(
 		&i.ID,
@@ -490,6 +532,11 @@ func (q *Queries) CreateSubredditRelationship(ctx context.Context, arg CreateSub
 		&i.OverlapCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Jaccard,
+		&i.Cosine,
+		&i.Pmi,
+		&i.Npmi,
+		&i.Lift,
 	)
 	return i, err
 }
@@ -746,7 +793,7 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]GetAllPostsRow, error) {
 }
 
 const getAllSubredditRelationships = `-- name: GetAllSubredditRelationships :many
-SELECT source_subreddit_id, target_subreddit_id, overlap_count
+SELECT source_subreddit_id, target_subreddit_id, overlap_count, jaccard, cosine, pmi, npmi, lift
 FROM subreddit_relationships
 `
 
@@ -754,6 +801,11 @@ type GetAllSubredditRelationshipsRow struct {
 	SourceSubredditID int32
 	TargetSubredditID int32
 	OverlapCount      int32
+	Jaccard           float64
+	Cosine            float64
+	Pmi               float64
+	Npmi              float64
+	Lift              float64
 }
 
 func (q *Queries) GetAllSubredditRelationships(ctx context.Context) ([]GetAllSubredditRelationshipsRow, error) {
@@ -765,7 +817,16 @@ func (q *Queries) GetAllSubredditRelationships(ctx context.Context) ([]GetAllSub
 	var items []GetAllSubredditRelationshipsRow
 	for rows.Next() {
 		var i GetAllSubredditRelationshipsRow
-		if err := rows.Scan(&i.SourceSubredditID, &i.TargetSubredditID, &i.OverlapCount); err != nil {
+		if err := rows.Scan(
+			&i.SourceSubredditID,
+			&i.TargetSubredditID,
+			&i.OverlapCount,
+			&i.Jaccard,
+			&i.Cosine,
+			&i.Pmi,
+			&i.Npmi,
+			&i.Lift,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -1680,30 +1741,41 @@ WITH bbox_nodes AS (
       AND pos_y BETWEEN $3 AND $4
       AND pos_z BETWEEN $5 AND $6
 )
-SELECT 
-    gl.id,
-    gl.source,
-    gl.target
-FROM graph_links gl
-WHERE EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.source)
-  AND EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.target)
+SELECT id, source, target, weight, link_type
+FROM (
+    -- One link per node pair: the heaviest of its types that pass the filter
+    SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+    FROM graph_links gl
+    WHERE EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.source)
+      AND EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.target)
+      AND gl.weight >= $8::float8
+      AND (COALESCE(cardinality($9::text[]), 0) = 0 OR gl.link_type = ANY($9::text[]))
+      AND ($10::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $10::float8)
+    ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+) pair_links
+ORDER BY weight DESC, id
 LIMIT $7
 `
 
 type GetLinksForNodesInBoundingBoxParams struct {
-	PosX   sql.NullFloat64
-	PosX_2 sql.NullFloat64
-	PosY   sql.NullFloat64
-	PosY_2 sql.NullFloat64
-	PosZ   sql.NullFloat64
-	PosZ_2 sql.NullFloat64
-	Limit  int32
+	PosX     sql.NullFloat64
+	PosX_2   sql.NullFloat64
+	PosY     sql.NullFloat64
+	PosY_2   sql.NullFloat64
+	PosZ     sql.NullFloat64
+	PosZ_2   sql.NullFloat64
+	Limit    int32
+	Column8  float64
+	Column9  []string
+	Column10 float64
 }
 
 type GetLinksForNodesInBoundingBoxRow struct {
-	ID     int32
-	Source string
-	Target string
+	ID       int32
+	Source   string
+	Target   string
+	Weight   float64
+	LinkType string
 }
 
 // Retrieves links where both source and target nodes are within the bounding box
@@ -1718,6 +1790,9 @@ func (q *Queries) GetLinksForNodesInBoundingBox(ctx context.Context, arg GetLink
 		arg.PosZ,
 		arg.PosZ_2,
 		arg.Limit,
+		arg.Column8,
+		pq.Array(arg.Column9),
+		arg.Column10,
 	)
 	if err != nil {
 		return nil, err
@@ -1726,7 +1801,13 @@ func (q *Queries) GetLinksForNodesInBoundingBox(ctx context.Context, arg GetLink
 	var items []GetLinksForNodesInBoundingBoxRow
 	for rows.Next() {
 		var i GetLinksForNodesInBoundingBoxRow
-		if err := rows.Scan(&i.ID, &i.Source, &i.Target); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Source,
+			&i.Target,
+			&i.Weight,
+			&i.LinkType,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -1741,31 +1822,48 @@ func (q *Queries) GetLinksForNodesInBoundingBox(ctx context.Context, arg GetLink
 }
 
 const getLinksForPaginatedNodes = `-- name: GetLinksForPaginatedNodes :many
-SELECT 
-    id,
-    source,
-    target
-FROM graph_links
-WHERE source = ANY($1::text[]) 
-  AND target = ANY($1::text[])
+SELECT id, source, target, weight, link_type
+FROM (
+    -- One link per node pair: the heaviest of its types that pass the filter
+    SELECT DISTINCT ON (source, target) id, source, target, weight, link_type
+    FROM graph_links
+    WHERE source = ANY($1::text[])
+      AND target = ANY($1::text[])
+      AND weight >= $3::float8
+      AND (COALESCE(cardinality($4::text[]), 0) = 0 OR link_type = ANY($4::text[]))
+      AND ($5::float8 <= 0 OR backbone_pvalue IS NULL OR backbone_pvalue <= $5::float8)
+    ORDER BY source, target, weight DESC, id
+) pair_links
+ORDER BY weight DESC, id
 LIMIT $2
 `
 
 type GetLinksForPaginatedNodesParams struct {
 	Column1 []string
 	Limit   int32
+	Column3 float64
+	Column4 []string
+	Column5 float64
 }
 
 type GetLinksForPaginatedNodesRow struct {
-	ID     int32
-	Source string
-	Target string
+	ID       int32
+	Source   string
+	Target   string
+	Weight   float64
+	LinkType string
 }
 
 // Get links where both source and target are in the provided node ID list
 // Parameters: $1=node_ids (text array)
 func (q *Queries) GetLinksForPaginatedNodes(ctx context.Context, arg GetLinksForPaginatedNodesParams) ([]GetLinksForPaginatedNodesRow, error) {
-	rows, err := q.db.QueryContext(ctx, getLinksForPaginatedNodes, pq.Array(arg.Column1), arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getLinksForPaginatedNodes,
+		pq.Array(arg.Column1),
+		arg.Limit,
+		arg.Column3,
+		pq.Array(arg.Column4),
+		arg.Column5,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -1773,7 +1871,13 @@ func (q *Queries) GetLinksForPaginatedNodes(ctx context.Context, arg GetLinksFor
 	var items []GetLinksForPaginatedNodesRow
 	for rows.Next() {
 		var i GetLinksForPaginatedNodesRow
-		if err := rows.Scan(&i.ID, &i.Source, &i.Target); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Source,
+			&i.Target,
+			&i.Weight,
+			&i.LinkType,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -1796,19 +1900,27 @@ SELECT
     type,
     pos_x,
     pos_y,
-    pos_z
+    pos_z,
+    pagerank,
+    weighted_degree,
+    betweenness,
+    kcore
 FROM graph_nodes
 WHERE id = $1
 `
 
 type GetNodeDetailsRow struct {
-	ID   string
-	Name string
-	Val  interface{}
-	Type sql.NullString
-	PosX sql.NullFloat64
-	PosY sql.NullFloat64
-	PosZ sql.NullFloat64
+	ID             string
+	Name           string
+	Val            interface{}
+	Type           sql.NullString
+	PosX           sql.NullFloat64
+	PosY           sql.NullFloat64
+	PosZ           sql.NullFloat64
+	Pagerank       sql.NullFloat64
+	WeightedDegree sql.NullFloat64
+	Betweenness    sql.NullFloat64
+	Kcore          sql.NullInt32
 }
 
 // ============================================================
@@ -1826,6 +1938,10 @@ func (q *Queries) GetNodeDetails(ctx context.Context, id string) (GetNodeDetails
 		&i.PosX,
 		&i.PosY,
 		&i.PosZ,
+		&i.Pagerank,
+		&i.WeightedDegree,
+		&i.Betweenness,
+		&i.Kcore,
 	)
 	return i, err
 }
@@ -2232,20 +2348,62 @@ func (q *Queries) GetPrecalcState(ctx context.Context) (PrecalcState, error) {
 
 const getPrecalculatedGraphDataCappedAll = `-- name: GetPrecalculatedGraphDataCappedAll :many
 WITH sel_nodes AS (
-    SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z
+    -- Only one branch runs: by val (using idx_graph_nodes_val_numeric) when no
+    -- centrality order is requested, otherwise by that centrality measure, each
+    -- with its own index (idx_graph_nodes_pagerank, ...)
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
     FROM graph_nodes gn
+    WHERE $6::text = ''
     ORDER BY (
         CASE WHEN gn.val ~ '^[0-9]+$' THEN CAST(gn.val AS BIGINT) ELSE 0 END
     ) DESC NULLS LAST, gn.id
-    LIMIT $1
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'pagerank'
+    ORDER BY gn.pagerank DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'weighted_degree'
+    ORDER BY gn.weighted_degree DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'betweenness'
+    ORDER BY gn.betweenness DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'kcore'
+    ORDER BY gn.kcore DESC NULLS LAST, gn.id
+    LIMIT $1)
 ), sel_node_ids AS MATERIALIZED (
     -- Explicitly materialize IDs for efficient hash lookups in EXISTS subqueries
     SELECT id FROM sel_nodes
 ), sel_links AS (
-    SELECT gl.id, gl.source, gl.target
-    FROM graph_links gl
-    WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
-      AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+    SELECT id, source, target, weight, link_type
+    FROM (
+        -- One link per node pair: the heaviest of its types that pass the filter
+        SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+        FROM graph_links gl
+        WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
+          AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+          AND gl.weight >= $3::float8
+          AND (COALESCE(cardinality($4::text[]), 0) = 0 OR gl.link_type = ANY($4::text[]))
+          AND ($5::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $5::float8)
+        ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+    ) pair_links
+    ORDER BY weight DESC, id
     LIMIT $2
 )
 SELECT
@@ -2258,7 +2416,13 @@ SELECT
     n.pos_y,
     n.pos_z,
         NULL AS source,
-        NULL AS target
+        NULL AS target,
+        CAST(NULL AS DOUBLE PRECISION) AS weight,
+        CAST(NULL AS TEXT) AS link_type,
+    n.pagerank,
+    n.weighted_degree,
+    n.betweenness,
+    n.kcore
 FROM sel_nodes n
 UNION ALL
 SELECT
@@ -2271,7 +2435,13 @@ SELECT
     NULL as pos_y,
     NULL as pos_z,
         l.source,
-        l.target
+        l.target,
+        l.weight,
+        l.link_type,
+    CAST(NULL AS DOUBLE PRECISION) AS pagerank,
+    CAST(NULL AS DOUBLE PRECISION) AS weighted_degree,
+    CAST(NULL AS DOUBLE PRECISION) AS betweenness,
+    CAST(NULL AS INTEGER) AS kcore
 FROM sel_links l
 ORDER BY data_type, id
 `
@@ -2279,26 +2449,43 @@ ORDER BY data_type, id
 type GetPrecalculatedGraphDataCappedAllParams struct {
 	Limit   int32
 	Limit_2 int32
+	Column3 float64
+	Column4 []string
+	Column5 float64
+	Column6 string
 }
 
 type GetPrecalculatedGraphDataCappedAllRow struct {
-	DataType string
-	ID       string
-	Name     string
-	Val      string
-	Type     sql.NullString
-	PosX     sql.NullFloat64
-	PosY     sql.NullFloat64
-	PosZ     sql.NullFloat64
-	Source   interface{}
-	Target   interface{}
+	DataType       string
+	ID             string
+	Name           string
+	Val            string
+	Type           sql.NullString
+	PosX           sql.NullFloat64
+	PosY           sql.NullFloat64
+	PosZ           sql.NullFloat64
+	Source         interface{}
+	Target         interface{}
+	Weight         sql.NullFloat64
+	LinkType       sql.NullString
+	Pagerank       sql.NullFloat64
+	WeightedDegree sql.NullFloat64
+	Betweenness    sql.NullFloat64
+	Kcore          sql.NullInt32
 }
 
 // Optimized query with improved link filtering
 // Uses EXISTS subqueries for better performance on large datasets
 // Note: statement_timeout is enforced at application level via context timeout
 func (q *Queries) GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg GetPrecalculatedGraphDataCappedAllParams) ([]GetPrecalculatedGraphDataCappedAllRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPrecalculatedGraphDataCappedAll, arg.Limit, arg.Limit_2)
+	rows, err := q.db.QueryContext(ctx, getPrecalculatedGraphDataCappedAll,
+		arg.Limit,
+		arg.Limit_2,
+		arg.Column3,
+		pq.Array(arg.Column4),
+		arg.Column5,
+		arg.Column6,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -2317,6 +2504,12 @@ func (q *Queries) GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg Ge
 			&i.PosZ,
 			&i.Source,
 			&i.Target,
+			&i.Weight,
+			&i.LinkType,
+			&i.Pagerank,
+			&i.WeightedDegree,
+			&i.Betweenness,
+			&i.Kcore,
 		); err != nil {
 			return nil, err
 		}
@@ -2333,21 +2526,62 @@ func (q *Queries) GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg Ge
 
 const getPrecalculatedGraphDataCappedFiltered = `-- name: GetPrecalculatedGraphDataCappedFiltered :many
 WITH sel_nodes AS (
-    SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z
+    -- Only one branch runs: by val (using idx_graph_nodes_val_numeric) when no
+    -- centrality order is requested, otherwise by that centrality measure, each
+    -- with its own index (idx_graph_nodes_pagerank, ...)
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
     FROM graph_nodes gn
-    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[])
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = ''
     ORDER BY (
         CASE WHEN gn.val ~ '^[0-9]+$' THEN CAST(gn.val AS BIGINT) ELSE 0 END
     ) DESC NULLS LAST, gn.id
-    LIMIT $2
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'pagerank'
+    ORDER BY gn.pagerank DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'weighted_degree'
+    ORDER BY gn.weighted_degree DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'betweenness'
+    ORDER BY gn.betweenness DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'kcore'
+    ORDER BY gn.kcore DESC NULLS LAST, gn.id
+    LIMIT $2)
 ), sel_node_ids AS MATERIALIZED (
     -- Explicitly materialize IDs for efficient hash lookups in EXISTS subqueries
     SELECT id FROM sel_nodes
 ), sel_links AS (
-    SELECT gl.id, gl.source, gl.target
-    FROM graph_links gl
-    WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
-      AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+    SELECT id, source, target, weight, link_type
+    FROM (
+        -- One link per node pair: the heaviest of its types that pass the filter
+        SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+        FROM graph_links gl
+        WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
+          AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+          AND gl.weight >= $4::float8
+          AND (COALESCE(cardinality($5::text[]), 0) = 0 OR gl.link_type = ANY($5::text[]))
+          AND ($6::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $6::float8)
+        ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+    ) pair_links
+    ORDER BY weight DESC, id
     LIMIT $3
 )
 SELECT
@@ -2360,7 +2594,13 @@ SELECT
     n.pos_y,
     n.pos_z,
         NULL AS source,
-        NULL AS target
+        NULL AS target,
+        CAST(NULL AS DOUBLE PRECISION) AS weight,
+        CAST(NULL AS TEXT) AS link_type,
+    n.pagerank,
+    n.weighted_degree,
+    n.betweenness,
+    n.kcore
 FROM sel_nodes n
 UNION ALL
 SELECT
@@ -2373,7 +2613,13 @@ SELECT
     NULL as pos_y,
     NULL as pos_z,
         l.source,
-        l.target
+        l.target,
+        l.weight,
+        l.link_type,
+    CAST(NULL AS DOUBLE PRECISION) AS pagerank,
+    CAST(NULL AS DOUBLE PRECISION) AS weighted_degree,
+    CAST(NULL AS DOUBLE PRECISION) AS betweenness,
+    CAST(NULL AS INTEGER) AS kcore
 FROM sel_links l
 ORDER BY data_type, id
 `
@@ -2382,26 +2628,44 @@ type GetPrecalculatedGraphDataCappedFilteredParams struct {
 	Column1 []string
 	Limit   int32
 	Limit_2 int32
+	Column4 float64
+	Column5 []string
+	Column6 float64
+	Column7 string
 }
 
 type GetPrecalculatedGraphDataCappedFilteredRow struct {
-	DataType string
-	ID       string
-	Name     string
-	Val      string
-	Type     sql.NullString
-	PosX     sql.NullFloat64
-	PosY     sql.NullFloat64
-	PosZ     sql.NullFloat64
-	Source   interface{}
-	Target   interface{}
+	DataType       string
+	ID             string
+	Name           string
+	Val            string
+	Type           sql.NullString
+	PosX           sql.NullFloat64
+	PosY           sql.NullFloat64
+	PosZ           sql.NullFloat64
+	Source         interface{}
+	Target         interface{}
+	Weight         sql.NullFloat64
+	LinkType       sql.NullString
+	Pagerank       sql.NullFloat64
+	WeightedDegree sql.NullFloat64
+	Betweenness    sql.NullFloat64
+	Kcore          sql.NullInt32
 }
 
 // Optimized query with improved link filtering
 // Uses EXISTS subqueries for better performance than IN subqueries
 // Note: statement_timeout is enforced at application level via context timeout
 func (q *Queries) GetPrecalculatedGraphDataCappedFiltered(ctx context.Context, arg GetPrecalculatedGraphDataCappedFilteredParams) ([]GetPrecalculatedGraphDataCappedFilteredRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPrecalculatedGraphDataCappedFiltered, pq.Array(arg.Column1), arg.Limit, arg.Limit_2)
+	rows, err := q.db.QueryContext(ctx, getPrecalculatedGraphDataCappedFiltered,
+		pq.Array(arg.Column1),
+		arg.Limit,
+		arg.Limit_2,
+		arg.Column4,
+		pq.Array(arg.Column5),
+		arg.Column6,
+		arg.Column7,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -2420,6 +2684,12 @@ func (q *Queries) GetPrecalculatedGraphDataCappedFiltered(ctx context.Context, a
 			&i.PosZ,
 			&i.Source,
 			&i.Target,
+			&i.Weight,
+			&i.LinkType,
+			&i.Pagerank,
+			&i.WeightedDegree,
+			&i.Betweenness,
+			&i.Kcore,
 		); err != nil {
 			return nil, err
 		}
@@ -2704,14 +2974,16 @@ func (q *Queries) InsertCommunityHierarchy(ctx context.Context, arg InsertCommun
 }
 
 const listGraphLinksAmong = `-- name: ListGraphLinksAmong :many
-SELECT source, target
+SELECT source, target, weight, link_type
 FROM graph_links
 WHERE source = ANY($1::text[]) AND target = ANY($1::text[])
 `
 
 type ListGraphLinksAmongRow struct {
-	Source string
-	Target string
+	Source   string
+	Target   string
+	Weight   float64
+	LinkType string
 }
 
 func (q *Queries) ListGraphLinksAmong(ctx context.Context, dollar_1 []string) ([]ListGraphLinksAmongRow, error) {
@@ -2723,7 +2995,12 @@ func (q *Queries) ListGraphLinksAmong(ctx context.Context, dollar_1 []string) ([
 	var items []ListGraphLinksAmongRow
 	for rows.Next() {
 		var i ListGraphLinksAmongRow
-		if err := rows.Scan(&i.Source, &i.Target); err != nil {
+		if err := rows.Scan(
+			&i.Source,
+			&i.Target,
+			&i.Weight,
+			&i.LinkType,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
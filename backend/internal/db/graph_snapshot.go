@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// SnapshotNode is a graph node as loaded into the in-memory graph.
+type SnapshotNode struct {
+	ID   string
+	Name string
+	Val  sql.NullString
+	Type sql.NullString
+	PosX sql.NullFloat64
+	PosY sql.NullFloat64
+	PosZ sql.NullFloat64
+}
+
+// SnapshotLink is a graph link as loaded into the in-memory graph.
+type SnapshotLink struct {
+	ID             int32
+	Source         string
+	Target         string
+	Weight         float64
+	LinkType       string
+	BackbonePValue sql.NullFloat64
+}
+
+// ReadGraphSnapshot calls read with queries that all see the database as of
+// their first statement, in a read-only REPEATABLE READ transaction, so that a
+// graph version and the nodes and links read after it belong together even
+// while precalculation rewrites the graph.
+func (q *Queries) ReadGraphSnapshot(ctx context.Context, read func(*Queries) error) error {
+	sqlDB, ok := q.db.(*sql.DB)
+	if !ok {
+		return errors.New("graph snapshot needs a *sql.DB")
+	}
+	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := read(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListGraphSnapshotNodes returns every graph node.
+func (q *Queries) ListGraphSnapshotNodes(ctx context.Context) ([]SnapshotNode, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT id, name, val, type, pos_x, pos_y, pos_z FROM graph_nodes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SnapshotNode
+	for rows.Next() {
+		var n SnapshotNode
+		if err := rows.Scan(&n.ID, &n.Name, &n.Val, &n.Type, &n.PosX, &n.PosY, &n.PosZ); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// ListGraphSnapshotLinks returns every graph link, by ID.
+func (q *Queries) ListGraphSnapshotLinks(ctx context.Context) ([]SnapshotLink, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT id, source, target, weight, link_type, backbone_pvalue FROM graph_links ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SnapshotLink
+	for rows.Next() {
+		var l SnapshotLink
+		if err := rows.Scan(&l.ID, &l.Source, &l.Target, &l.Weight, &l.LinkType, &l.BackbonePValue); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
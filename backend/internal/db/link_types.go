@@ -0,0 +1,37 @@
+package db
+
+// Link types stored in graph_links.link_type.
+const (
+	LinkTypeUserActivity     = "user_activity"     // user -> subreddit, weighted by posts and comments
+	LinkTypeSubredditOverlap = "subreddit_overlap" // subreddit -> subreddit, weighted by shared users
+	LinkTypeMention          = "mention"           // subreddit -> subreddit, r/name mentioned in posts
+	LinkTypeCrosspost        = "crosspost"         // subreddit -> subreddit, posts crossposted from the target
+	LinkTypeAuthored         = "authored"          // user -> post or comment
+	LinkTypeReply            = "reply"             // post or comment -> comment
+	LinkTypeContains         = "contains"          // subreddit -> post
+	LinkTypeSameAuthor       = "same_author"       // content -> content by one author
+	LinkTypeUnknown          = "unknown"           // the column default, for links written without a type
+)
+
+// LinkTypes lists every link type, in the order above.
+var LinkTypes = []string{
+	LinkTypeUserActivity,
+	LinkTypeSubredditOverlap,
+	LinkTypeMention,
+	LinkTypeCrosspost,
+	LinkTypeAuthored,
+	LinkTypeReply,
+	LinkTypeContains,
+	LinkTypeSameAuthor,
+	LinkTypeUnknown,
+}
+
+// IsLinkType reports whether t is a known link type.
+func IsLinkType(t string) bool {
+	for _, lt := range LinkTypes {
+		if lt == t {
+			return true
+		}
+	}
+	return false
+}
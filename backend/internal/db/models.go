@@ -76,6 +76,8 @@ type GraphCommunity struct {
 	Modularity sql.NullFloat64
 	CreatedAt  sql.NullTime
 	UpdatedAt  sql.NullTime
+	// The run that produced the community
+	RunID sql.NullInt32
 }
 
 type GraphCommunityHierarchy struct {
@@ -143,6 +145,8 @@ type GraphLink struct {
 	Target    string
 	CreatedAt sql.NullTime
 	UpdatedAt sql.NullTime
+	Weight    float64
+	LinkType  string
 }
 
 type GraphNode struct {
@@ -155,6 +159,14 @@ type GraphNode struct {
 	PosZ      sql.NullFloat64
 	CreatedAt sql.NullTime
 	UpdatedAt sql.NullTime
+	// PageRank of the node in the precalculated graph
+	Pagerank sql.NullFloat64
+	// Sum of the weights of the node's links
+	WeightedDegree sql.NullFloat64
+	// Approximate normalized betweenness centrality, from sampled sources
+	Betweenness sql.NullFloat64
+	// k-core number: the largest k with the node in a subgraph of minimum degree k
+	Kcore sql.NullInt32
 }
 
 // Tracks graph precalculation versions with timestamps and statistics
@@ -259,6 +271,11 @@ type SubredditRelationship struct {
 	OverlapCount      int32
 	CreatedAt         sql.NullTime
 	UpdatedAt         sql.NullTime
+	Jaccard           float64
+	Cosine            float64
+	Pmi               float64
+	Npmi              float64
+	Lift              float64
 }
 
 type User struct {
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// GraphNeighbor is a link of a node, with the type of the node at its other
+// end.
+type GraphNeighbor struct {
+	Source    string
+	Target    string
+	Weight    float64
+	LinkType  string
+	OtherType string
+}
+
+// ListGraphNeighborsParams selects the links of a set of nodes. NodeTypes
+// restricts the nodes the links lead to (any type when empty), except for the
+// Endpoints; the link conditions are those of the graph link filter.
+type ListGraphNeighborsParams struct {
+	IDs       []string
+	NodeTypes []string
+	Endpoints []string
+	MinWeight float64
+	LinkTypes []string
+	MaxPValue float64
+}
+
+// ListGraphNeighbors returns the links of the given nodes in both directions.
+func (q *Queries) ListGraphNeighbors(ctx context.Context, arg ListGraphNeighborsParams) ([]GraphNeighbor, error) {
+	const stmt = `SELECT l.source, l.target, l.weight, l.link_type, COALESCE(gn.type, '')
+                  FROM (
+                      SELECT gl.source, gl.target, gl.target AS other_id, gl.weight, gl.link_type, gl.backbone_pvalue
+                      FROM graph_links gl WHERE gl.source = ANY($1::text[])
+                      UNION ALL
+                      SELECT gl.source, gl.target, gl.source, gl.weight, gl.link_type, gl.backbone_pvalue
+                      FROM graph_links gl WHERE gl.target = ANY($1::text[])
+                  ) l
+                  JOIN graph_nodes gn ON gn.id = l.other_id
+                  WHERE (COALESCE(cardinality($2::text[]), 0) = 0 OR gn.type = ANY($2::text[]) OR gn.id = ANY($6::text[]))
+                    AND l.weight >= $3::float8
+                    AND (COALESCE(cardinality($4::text[]), 0) = 0 OR l.link_type = ANY($4::text[]))
+                    AND ($5::float8 <= 0 OR l.backbone_pvalue IS NULL OR l.backbone_pvalue <= $5::float8)`
+	rows, err := q.db.QueryContext(ctx, stmt, pq.Array(arg.IDs), pq.Array(arg.NodeTypes), arg.MinWeight, pq.Array(arg.LinkTypes), arg.MaxPValue, pq.Array(arg.Endpoints))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GraphNeighbor
+	for rows.Next() {
+		var n GraphNeighbor
+		if err := rows.Scan(&n.Source, &n.Target, &n.Weight, &n.LinkType, &n.OtherType); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// GraphNodeSummary is the identity, size and stored position of a graph node.
+type GraphNodeSummary struct {
+	ID   string
+	Name string
+	Val  string
+	Type string
+	PosX sql.NullFloat64
+	PosY sql.NullFloat64
+	PosZ sql.NullFloat64
+}
+
+// ListGraphNodesByIDs returns the graph nodes with the given IDs, in no
+// particular order. Unknown IDs are skipped.
+func (q *Queries) ListGraphNodesByIDs(ctx context.Context, ids []string) ([]GraphNodeSummary, error) {
+	const stmt = `SELECT id, name, COALESCE(CAST(val AS TEXT), ''), COALESCE(type, ''), pos_x, pos_y, pos_z
+                  FROM graph_nodes WHERE id = ANY($1::text[])`
+	rows, err := q.db.QueryContext(ctx, stmt, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GraphNodeSummary
+	for rows.Next() {
+		var n GraphNodeSummary
+		if err := rows.Scan(&n.ID, &n.Name, &n.Val, &n.Type, &n.PosX, &n.PosY, &n.PosZ); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// ListGraphLinksAmongFilteredParams selects the links between a set of nodes
+// that pass the graph link filter.
+type ListGraphLinksAmongFilteredParams struct {
+	IDs       []string
+	MinWeight float64
+	LinkTypes []string
+	MaxPValue float64
+}
+
+// ListGraphLinksAmongFiltered is ListGraphLinksAmong with the graph link
+// filter, heaviest links first. Like the graph queries it returns one link per
+// node pair, the heaviest of its types that pass the filter.
+func (q *Queries) ListGraphLinksAmongFiltered(ctx context.Context, arg ListGraphLinksAmongFilteredParams) ([]ListGraphLinksAmongRow, error) {
+	const stmt = `SELECT source, target, weight, link_type
+                  FROM (
+                    SELECT DISTINCT ON (source, target) id, source, target, weight, link_type
+                    FROM graph_links
+                    WHERE source = ANY($1::text[]) AND target = ANY($1::text[])
+                      AND weight >= $2::float8
+                      AND (COALESCE(cardinality($3::text[]), 0) = 0 OR link_type = ANY($3::text[]))
+                      AND ($4::float8 <= 0 OR backbone_pvalue IS NULL OR backbone_pvalue <= $4::float8)
+                    ORDER BY source, target, weight DESC, id
+                  ) pair_links
+                  ORDER BY weight DESC, id`
+	rows, err := q.db.QueryContext(ctx, stmt, pq.Array(arg.IDs), arg.MinWeight, pq.Array(arg.LinkTypes), arg.MaxPValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ListGraphLinksAmongRow
+	for rows.Next() {
+		var l ListGraphLinksAmongRow
+		if err := rows.Scan(&l.Source, &l.Target, &l.Weight, &l.LinkType); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
@@ -41,8 +41,9 @@ func (q *Queries) BatchUpsertGraphNodes(ctx context.Context, nodes []BulkInsertG
 	return nil
 }
 
-// BatchInsertGraphLinks inserts many graph_links rows with ON CONFLICT DO NOTHING semantics in batches.
-// It de-duplicates (source,target) pairs client-side to reduce useless conflict checks.
+// BatchInsertGraphLinks upserts many graph_links rows in batches; an existing link of the
+// same type between the same nodes takes the new weight. It de-duplicates
+// (source,target,link_type) triples client-side, since one statement cannot update a row twice.
 func (q *Queries) BatchInsertGraphLinks(ctx context.Context, links []BulkInsertGraphLinkParams, batchSize int) error {
 	if len(links) == 0 {
 		return nil
@@ -53,7 +54,7 @@ func (q *Queries) BatchInsertGraphLinks(ctx context.Context, links []BulkInsertG
 	// Deduplicate
 	uniq := make(map[string]BulkInsertGraphLinkParams, len(links))
 	for _, l := range links {
-		key := l.Source + "\x00" + l.Target
+		key := l.Source + "\x00" + l.Target + "\x00" + l.LinkType
 		uniq[key] = l
 	}
 	dedup := make([]BulkInsertGraphLinkParams, 0, len(uniq))
@@ -68,21 +69,21 @@ func (q *Queries) BatchInsertGraphLinks(ctx context.Context, links []BulkInsertG
 		}
 		batch := dedup[start:end]
 		var sb strings.Builder
-		sb.WriteString("WITH vals(source, target) AS (VALUES ")
-		args := make([]any, 0, len(batch)*2)
+		sb.WriteString("WITH vals(source, target, weight, link_type) AS (VALUES ")
+		args := make([]any, 0, len(batch)*4)
 		for i, l := range batch {
 			if i > 0 {
 				sb.WriteByte(',')
 			}
-			idx := i*2 + 1
-			sb.WriteString(fmt.Sprintf("($%d,$%d)", idx, idx+1))
-			args = append(args, l.Source, l.Target)
+			idx := i*4 + 1
+			sb.WriteString(fmt.Sprintf("($%d,$%d,$%d::float8,$%d)", idx, idx+1, idx+2, idx+3))
+			args = append(args, l.Source, l.Target, l.Weight, l.LinkType)
 		}
-		sb.WriteString(") INSERT INTO graph_links (source, target) ")
-		sb.WriteString("SELECT v.source, v.target FROM vals v ")
+		sb.WriteString(") INSERT INTO graph_links (source, target, weight, link_type) ")
+		sb.WriteString("SELECT v.source, v.target, v.weight, v.link_type FROM vals v ")
 		sb.WriteString("JOIN graph_nodes s ON s.id = v.source ")
 		sb.WriteString("JOIN graph_nodes t ON t.id = v.target ")
-		sb.WriteString("ON CONFLICT (source, target) DO NOTHING")
+		sb.WriteString("ON CONFLICT (source, target, link_type) DO UPDATE SET weight = EXCLUDED.weight, updated_at = now()")
 		if _, err := q.db.ExecContext(ctx, sb.String(), args...); err != nil {
 			return err
 		}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SubredditSnapshot is the metadata of a subreddit observed by one crawl.
+type SubredditSnapshot struct {
+	SubredditID   int32
+	ObservedAt    time.Time
+	Subscribers   sql.NullInt32
+	ActiveUsers   sql.NullInt32
+	Over18        bool
+	SubredditType sql.NullString
+	CreatedUTC    sql.NullTime
+	Language      sql.NullString
+}
+
+// InsertSubredditSnapshot stores a snapshot observed now.
+func (q *Queries) InsertSubredditSnapshot(ctx context.Context, s SubredditSnapshot) error {
+	const stmt = `INSERT INTO subreddit_snapshots (subreddit_id, subscribers, active_users, over18, subreddit_type, created_utc, language)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7)
+                  ON CONFLICT (subreddit_id, observed_at) DO NOTHING`
+	_, err := q.db.ExecContext(ctx, stmt, s.SubredditID, s.Subscribers, s.ActiveUsers, s.Over18, s.SubredditType, s.CreatedUTC, s.Language)
+	return err
+}
+
+// ListSubredditSnapshots returns a subreddit's snapshots since a time, oldest first.
+func (q *Queries) ListSubredditSnapshots(ctx context.Context, subredditID int32, since time.Time) ([]SubredditSnapshot, error) {
+	const stmt = `SELECT subreddit_id, observed_at, subscribers, active_users, over18, subreddit_type, created_utc, language
+                  FROM subreddit_snapshots
+                  WHERE subreddit_id = $1 AND observed_at >= $2
+                  ORDER BY observed_at`
+	rows, err := q.db.QueryContext(ctx, stmt, subredditID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubredditSnapshot
+	for rows.Next() {
+		var s SubredditSnapshot
+		if err := rows.Scan(&s.SubredditID, &s.ObservedAt, &s.Subscribers, &s.ActiveUsers, &s.Over18, &s.SubredditType, &s.CreatedUTC, &s.Language); err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+	}
+	return items, rows.Err()
+}
+
+// SubredditGrowth is the subscriber change of a subreddit between its first and
+// last snapshot in a window.
+type SubredditGrowth struct {
+	SubredditID int32
+	Name        string
+	From        time.Time
+	To          time.Time
+	Start       int32 // subscribers at From
+	End         int32 // subscribers at To
+}
+
+// Change is the number of subscribers gained (negative when lost).
+func (g SubredditGrowth) Change() int64 { return int64(g.End) - int64(g.Start) }
+
+// Rate is the relative subscriber change, 0.1 for 10% growth. It is 0 when the
+// window starts with no subscribers.
+func (g SubredditGrowth) Rate() float64 {
+	if g.Start <= 0 {
+		return 0
+	}
+	return float64(g.Change()) / float64(g.Start)
+}
+
+// MinGrowthSpan is the shortest span between two snapshots that a growth rate is
+// extrapolated from; the change between crawls a few hours apart is mostly noise.
+const MinGrowthSpan = 24 * time.Hour
+
+// RateOver is Rate scaled linearly from the span between the snapshots to period,
+// so that subreddits observed for different spans compare: a subreddit that grew
+// 10% in the 3.5 days it was observed has a weekly rate of 0.2. ok is false when
+// the span is shorter than MinGrowthSpan.
+func (g SubredditGrowth) RateOver(period time.Duration) (rate float64, ok bool) {
+	span := g.To.Sub(g.From)
+	if span < MinGrowthSpan {
+		return 0, false
+	}
+	return g.Rate() * period.Seconds() / span.Seconds(), true
+}
+
+// growthQuery selects the first and last snapshot with a subscriber count of
+// each subreddit since $1. filter narrows the snapshots read, before they are
+// ranked. Subreddits need two snapshots to have a growth.
+func growthQuery(filter string) string {
+	return `WITH w AS (
+    SELECT subreddit_id, observed_at, subscribers,
+           ROW_NUMBER() OVER (PARTITION BY subreddit_id ORDER BY observed_at) AS first_rank,
+           ROW_NUMBER() OVER (PARTITION BY subreddit_id ORDER BY observed_at DESC) AS last_rank
+    FROM subreddit_snapshots
+    WHERE observed_at >= $1 AND subscribers IS NOT NULL` + filter + `
+)
+SELECT f.subreddit_id, s.name, f.observed_at, l.observed_at, f.subscribers, l.subscribers
+FROM w f
+JOIN w l ON l.subreddit_id = f.subreddit_id AND l.last_rank = 1
+JOIN subreddits s ON s.id = f.subreddit_id
+WHERE f.first_rank = 1 AND l.observed_at > f.observed_at`
+}
+
+// GetSubredditGrowth returns a subreddit's growth since a time. ok is false when
+// fewer than two snapshots fall in the window.
+func (q *Queries) GetSubredditGrowth(ctx context.Context, subredditID int32, since time.Time) (g SubredditGrowth, ok bool, err error) {
+	err = q.db.QueryRowContext(ctx, growthQuery(` AND subreddit_id = $2`), since, subredditID).
+		Scan(&g.SubredditID, &g.Name, &g.From, &g.To, &g.Start, &g.End)
+	if err == sql.ErrNoRows {
+		return g, false, nil
+	}
+	return g, err == nil, err
+}
+
+// ListFastestGrowingSubreddits ranks subreddits by relative subscriber growth
+// per unit of time since a time (see RateOver), ignoring those that started the
+// window below minSubscribers or were observed for less than MinGrowthSpan.
+func (q *Queries) ListFastestGrowingSubreddits(ctx context.Context, since time.Time, minSubscribers int32, limit int32) ([]SubredditGrowth, error) {
+	stmt := growthQuery("") + ` AND f.subscribers >= GREATEST($2, 1)
+  AND l.observed_at - f.observed_at >= $4 * interval '1 second'
+ORDER BY (l.subscribers - f.subscribers)::float8 / f.subscribers / EXTRACT(EPOCH FROM l.observed_at - f.observed_at) DESC,
+         l.subscribers - f.subscribers DESC
+LIMIT $3`
+	rows, err := q.db.QueryContext(ctx, stmt, since, minSubscribers, limit, MinGrowthSpan.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubredditGrowth
+	for rows.Next() {
+		var g SubredditGrowth
+		if err := rows.Scan(&g.SubredditID, &g.Name, &g.From, &g.To, &g.Start, &g.End); err != nil {
+			return nil, err
+		}
+		items = append(items, g)
+	}
+	return items, rows.Err()
+}
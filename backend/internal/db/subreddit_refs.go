@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// PostSubredditRef is a subreddit a post refers to, by lowercase name: one it
+// mentions (LinkTypeMention) or the one it was crossposted from (LinkTypeCrosspost).
+type PostSubredditRef struct {
+	Target string
+	Kind   string
+}
+
+// ReplacePostSubredditRefs replaces the subreddit references stored for a post.
+func (q *Queries) ReplacePostSubredditRefs(ctx context.Context, postID string, subredditID int32, refs []PostSubredditRef) error {
+	if _, err := q.db.ExecContext(ctx, `DELETE FROM post_subreddit_refs WHERE post_id = $1`, postID); err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	targets := make([]string, len(refs))
+	kinds := make([]string, len(refs))
+	for i, r := range refs {
+		targets[i], kinds[i] = r.Target, r.Kind
+	}
+	const stmt = `INSERT INTO post_subreddit_refs (post_id, subreddit_id, target_subreddit, kind)
+                  SELECT $1, $2, t.target, t.kind FROM unnest($3::text[], $4::text[]) AS t(target, kind)
+                  ON CONFLICT DO NOTHING`
+	_, err := q.db.ExecContext(ctx, stmt, postID, subredditID, pq.Array(targets), pq.Array(kinds))
+	return err
+}
+
+// SubredditRefLink counts the posts of a subreddit referring to another stored
+// subreddit in one way.
+type SubredditRefLink struct {
+	SourceSubredditID int32
+	TargetSubredditID int32
+	Kind              string
+	Posts             int32
+}
+
+// ListSubredditRefLinks aggregates post references into subreddit pairs. References
+// to subreddits that are not stored, and a subreddit's references to itself, are
+// left out.
+func (q *Queries) ListSubredditRefLinks(ctx context.Context) ([]SubredditRefLink, error) {
+	const stmt = `SELECT r.subreddit_id, s.id, r.kind, COUNT(*)::int
+                  FROM post_subreddit_refs r
+                  JOIN subreddits s ON lower(s.name) = r.target_subreddit
+                  WHERE s.id <> r.subreddit_id
+                  GROUP BY r.subreddit_id, s.id, r.kind`
+	rows, err := q.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubredditRefLink
+	for rows.Next() {
+		var l SubredditRefLink
+		if err := rows.Scan(&l.SourceSubredditID, &l.TargetSubredditID, &l.Kind, &l.Posts); err != nil {
+			return nil, err
+		}
+		items = append(items, l)
+	}
+	return items, rows.Err()
+}
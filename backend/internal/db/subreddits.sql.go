@@ -40,6 +40,7 @@ func (q *Queries) EnsureSubreddit(ctx context.Context, arg EnsureSubredditParams
 const getStaleSubreddits = `-- name: GetStaleSubreddits :many
 SELECT name FROM subreddits 
 WHERE last_seen < NOW() - INTERVAL '7 days'
+  AND NOT EXISTS (SELECT 1 FROM crawl_jobs cj WHERE cj.subreddit_id = subreddits.id AND cj.status = 'terminal')
 ORDER BY last_seen ASC
 `
 
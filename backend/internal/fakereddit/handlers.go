@@ -0,0 +1,498 @@
+package fakereddit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListingLimit = 25
+	maxListingLimit     = 100
+	defaultCommentLimit = 200
+	maxMoreChildren     = 100
+)
+
+// timeFilters maps Reddit's `t` values to how far back a listing reaches.
+var timeFilters = map[string]time.Duration{
+	"hour":  time.Hour,
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+}
+
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request, postID string) {
+	p := s.world.Post(postID)
+	if p == nil || p.Subreddit.Status != StatusPublic {
+		writeError(w, http.StatusNotFound, "", "Not Found")
+		return
+	}
+	q := r.URL.Query()
+	limit := intParam(q, "limit", defaultCommentLimit, 1, 500)
+	maxDepth := intParam(q, "depth", 10, 0, 10)
+
+	budget := limit
+	tree := commentTree(p.Roots, "t3_"+p.ID, 0, maxDepth, &budget)
+	writeJSON(w, []any{
+		listing([]any{postThing(p)}, "", ""),
+		listing(tree, "", ""),
+	})
+}
+
+// commentTree renders comments with nested replies while the budget lasts.
+// Comments beyond the budget are summarized in a `more` stub listing their IDs;
+// those below maxDepth get a "continue this thread" stub without IDs.
+func commentTree(comments []*Comment, parent string, depth, maxDepth int, budget *int) []any {
+	ordered := byScore(comments)
+	out := make([]any, 0, len(ordered))
+	var rest []*Comment
+	for i, c := range ordered {
+		if depth > maxDepth {
+			rest = ordered[i:]
+			break
+		}
+		if *budget <= 0 {
+			rest = ordered[i:]
+			break
+		}
+		*budget--
+		thing := commentThing(c)
+		data := thing["data"].(map[string]any)
+		if len(c.Children) > 0 {
+			data["replies"] = listing(commentTree(c.Children, "t1_"+c.ID, depth+1, maxDepth, budget), "", "")
+		}
+		out = append(out, thing)
+	}
+	if len(rest) > 0 {
+		ids := make([]string, 0, len(rest))
+		count := 0
+		for _, c := range rest {
+			ids = append(ids, c.ID)
+			count += c.size
+		}
+		if depth > maxDepth {
+			ids = []string{}
+		}
+		out = append(out, moreThing(parent, depth, count, ids))
+	}
+	return out
+}
+
+// handleMoreChildren returns the requested comments and their descendants as a
+// flat list, breadth-first, up to maxMoreChildren things. Descendants that do not
+// fit are summarized in `more` stubs.
+func (s *Server) handleMoreChildren(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p := s.world.Post(strings.TrimPrefix(q.Get("link_id"), "t3_"))
+	if p == nil || p.Subreddit.Status != StatusPublic {
+		writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{{"INVALID_ID", "invalid link_id", "link_id"}}}})
+		return
+	}
+
+	var queue []*Comment
+	for _, id := range strings.Split(q.Get("children"), ",") {
+		if c := s.world.Comment(strings.TrimSpace(id)); c != nil && c.Post == p {
+			queue = append(queue, c)
+		}
+	}
+	things := make([]any, 0, len(queue))
+	included := 0
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if included >= maxMoreChildren {
+			parent := c.ParentFullname()
+			rest := []*Comment{c}
+			for len(queue) > 0 && queue[0].ParentFullname() == parent {
+				rest = append(rest, queue[0])
+				queue = queue[1:]
+			}
+			ids, count := make([]string, 0, len(rest)), 0
+			for _, r := range rest {
+				ids = append(ids, r.ID)
+				count += r.size
+			}
+			things = append(things, moreThing(parent, c.Depth, count, ids))
+			continue
+		}
+		included++
+		things = append(things, commentThing(c))
+		queue = append(queue, byScore(c.Children)...)
+	}
+	writeJSON(w, map[string]any{"json": map[string]any{"errors": []any{}, "data": map[string]any{"things": things}}})
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	var things []any
+	for _, name := range strings.Split(r.URL.Query().Get("id"), ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case strings.HasPrefix(name, "t3_"):
+			if p := s.world.Post(name[3:]); p != nil && p.Subreddit.Status == StatusPublic {
+				things = append(things, postThing(p))
+			}
+		case strings.HasPrefix(name, "t1_"):
+			if c := s.world.Comment(name[3:]); c != nil && c.Post.Subreddit.Status == StatusPublic {
+				things = append(things, commentThing(c))
+			}
+		case strings.HasPrefix(name, "t5_"):
+			for _, sub := range s.world.Subreddits {
+				if subredditFullname(sub) == name && sub.Status == StatusPublic {
+					things = append(things, map[string]any{"kind": "t5", "data": subredditData(sub)})
+				}
+			}
+		}
+	}
+	writeJSON(w, listing(things, "", ""))
+}
+
+// handleUser serves /user/{name}/overview|submitted|comments, newest first.
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, name, kind string) {
+	u := s.world.User(name)
+	if u == nil {
+		writeError(w, http.StatusNotFound, "", "Not Found")
+		return
+	}
+	type item struct {
+		created time.Time
+		name    string
+		thing   map[string]any
+	}
+	var items []item
+	if kind == "overview" || kind == "submitted" {
+		for _, p := range u.Posts {
+			items = append(items, item{p.Created, "t3_" + p.ID, postThing(p)})
+		}
+	}
+	if kind == "overview" || kind == "comments" {
+		for _, c := range u.Comments {
+			items = append(items, item{c.Created, "t1_" + c.ID, commentThing(c)})
+		}
+	}
+	if kind != "overview" && kind != "submitted" && kind != "comments" {
+		writeError(w, http.StatusNotFound, "", "Not Found")
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].created.After(items[j].created) })
+	page, after, before := paginate(items, func(it item) string { return it.name }, r.URL.Query())
+	things := make([]any, len(page))
+	for i, it := range page {
+		things[i] = it.thing
+	}
+	writeJSON(w, listing(things, after, before))
+}
+
+// handleSearch matches posts whose title or body contains every term of q.
+// Terms of the form author:name and subreddit:name filter on those fields.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request, restrict *Subreddit) {
+	q := r.URL.Query()
+	if restrict != nil && q.Get("restrict_sr") != "1" && q.Get("restrict_sr") != "true" && q.Get("restrict_sr") != "on" {
+		restrict = nil
+	}
+	var terms []string
+	var author, subName string
+	for _, f := range strings.Fields(strings.ToLower(q.Get("q"))) {
+		switch {
+		case strings.HasPrefix(f, "author:"):
+			author = strings.TrimPrefix(f, "author:")
+		case strings.HasPrefix(f, "subreddit:"):
+			subName = strings.TrimPrefix(f, "subreddit:")
+		default:
+			terms = append(terms, f)
+		}
+	}
+
+	type hit struct {
+		p     *Post
+		score int
+	}
+	var hits []hit
+	for _, sub := range s.world.Subreddits {
+		if sub.Status != StatusPublic || (restrict != nil && sub != restrict) || (subName != "" && strings.ToLower(sub.Name) != subName) {
+			continue
+		}
+		for _, p := range sub.Posts {
+			if author != "" && strings.ToLower(p.Author) != author {
+				continue
+			}
+			text := strings.ToLower(p.Title + " " + p.Selftext)
+			matched := 0
+			for _, t := range terms {
+				if strings.Contains(text, t) {
+					matched++
+				}
+			}
+			if matched == len(terms) && (len(terms) > 0 || author != "" || subName != "") {
+				hits = append(hits, hit{p, matched + strings.Count(strings.ToLower(p.Title), strings.Join(terms, " "))})
+			}
+		}
+	}
+
+	posts := make([]*Post, len(hits))
+	for i, h := range hits {
+		posts[i] = h.p
+	}
+	switch sort := q.Get("sort"); sort {
+	case "new", "top", "hot":
+		posts = sortPosts(posts, sort, q.Get("t"), s.world.Epoch)
+	case "comments":
+		posts = filterByTime(posts, q.Get("t"), s.world.Epoch)
+		sortStable(posts, func(a, b *Post) bool { return len(a.Comments) > len(b.Comments) })
+	default: // relevance
+		rank := make(map[*Post]int, len(hits))
+		for _, h := range hits {
+			rank[h.p] = h.score
+		}
+		posts = filterByTime(posts, q.Get("t"), s.world.Epoch)
+		sortStable(posts, func(a, b *Post) bool {
+			if rank[a] != rank[b] {
+				return rank[a] > rank[b]
+			}
+			return a.Score > b.Score
+		})
+	}
+	writeJSON(w, postListing(posts, q))
+}
+
+// sortPosts orders a subreddit's posts for a listing.
+func sortPosts(posts []*Post, sortBy, t string, epoch time.Time) []*Post {
+	switch sortBy {
+	case "new":
+		out := append([]*Post(nil), posts...)
+		sortStable(out, func(a, b *Post) bool { return a.Created.After(b.Created) })
+		return out
+	case "top":
+		out := filterByTime(posts, t, epoch)
+		sortStable(out, func(a, b *Post) bool { return a.Score > b.Score })
+		return out
+	case "controversial":
+		out := filterByTime(posts, t, epoch)
+		sortStable(out, func(a, b *Post) bool {
+			return len(a.Comments)-a.Score > len(b.Comments)-b.Score
+		})
+		return out
+	case "rising":
+		out := filterByTime(posts, "day", epoch)
+		sortStable(out, func(a, b *Post) bool { return a.Score > b.Score })
+		return out
+	default: // hot: score decayed by age
+		out := append([]*Post(nil), posts...)
+		hot := func(p *Post) float64 {
+			return float64(p.Score+1) / math.Pow(epoch.Sub(p.Created).Hours()+2, 1.5)
+		}
+		sortStable(out, func(a, b *Post) bool { return hot(a) > hot(b) })
+		return out
+	}
+}
+
+func filterByTime(posts []*Post, t string, epoch time.Time) []*Post {
+	span, ok := timeFilters[t]
+	out := make([]*Post, 0, len(posts))
+	for _, p := range posts {
+		if !ok || epoch.Sub(p.Created) <= span {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func sortStable(posts []*Post, less func(a, b *Post) bool) {
+	sort.SliceStable(posts, func(i, j int) bool { return less(posts[i], posts[j]) })
+}
+
+func byScore(comments []*Comment) []*Comment {
+	out := append([]*Comment(nil), comments...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+func postListing(posts []*Post, q url.Values) map[string]any {
+	page, after, before := paginate(posts, func(p *Post) string { return "t3_" + p.ID }, q)
+	things := make([]any, len(page))
+	for i, p := range page {
+		things[i] = postThing(p)
+	}
+	return listing(things, after, before)
+}
+
+// paginate applies limit, after and before to an ordered list, returning the page
+// and the cursors for the pages after and before it.
+func paginate[T any](items []T, fullname func(T) string, q url.Values) ([]T, string, string) {
+	limit := intParam(q, "limit", defaultListingLimit, 1, maxListingLimit)
+	index := func(name string) int {
+		for i, it := range items {
+			if fullname(it) == name {
+				return i
+			}
+		}
+		return -1
+	}
+	start, end := 0, len(items)
+	switch {
+	case q.Get("after") != "":
+		i := index(q.Get("after"))
+		if i < 0 {
+			return nil, "", ""
+		}
+		start = i + 1
+		end = min(start+limit, len(items))
+	case q.Get("before") != "":
+		i := index(q.Get("before"))
+		if i < 0 {
+			return nil, "", ""
+		}
+		end = i
+		start = max(0, end-limit)
+	default:
+		end = min(limit, len(items))
+	}
+	page := items[start:end]
+	var after, before string
+	if len(page) > 0 && end < len(items) {
+		after = fullname(page[len(page)-1])
+	}
+	if len(page) > 0 && start > 0 {
+		before = fullname(page[0])
+	}
+	return page, after, before
+}
+
+func intParam(q url.Values, name string, def, lo, hi int) int {
+	n, err := strconv.Atoi(q.Get(name))
+	if err != nil {
+		return def
+	}
+	return max(lo, min(hi, n))
+}
+
+func listing(children []any, after, before string) map[string]any {
+	if children == nil {
+		children = []any{}
+	}
+	data := map[string]any{"children": children, "dist": len(children), "after": nil, "before": nil}
+	if after != "" {
+		data["after"] = after
+	}
+	if before != "" {
+		data["before"] = before
+	}
+	return map[string]any{"kind": "Listing", "data": data}
+}
+
+func subredditFullname(sub *Subreddit) string {
+	return "t5_" + strconv.FormatUint(uint64(fnv32(strings.ToLower(sub.Name))), 36)
+}
+
+func subredditData(sub *Subreddit) map[string]any {
+	return map[string]any{
+		"display_name":              sub.Name,
+		"display_name_prefixed":     "r/" + sub.Name,
+		"name":                      subredditFullname(sub),
+		"title":                     sub.Title,
+		"public_description":        sub.Description,
+		"subscribers":               sub.Subscribers,
+		"over18":                    sub.Over18,
+		"active_user_count":         sub.Subscribers / 100,
+		"lang":                      "en",
+		"subreddit_type":            "public",
+		"url":                       "/r/" + sub.Name + "/",
+		"created_utc":               0,
+		"accounts_active_is_fuzzed": false,
+	}
+}
+
+func postThing(p *Post) map[string]any {
+	return map[string]any{"kind": "t3", "data": map[string]any{
+		"id":                      p.ID,
+		"name":                    "t3_" + p.ID,
+		"subreddit":               p.Subreddit.Name,
+		"subreddit_name_prefixed": "r/" + p.Subreddit.Name,
+		"subreddit_id":            subredditFullname(p.Subreddit),
+		"author":                  p.Author,
+		"title":                   p.Title,
+		"selftext":                p.Selftext,
+		"permalink":               permalink(p),
+		"url":                     p.URL,
+		"is_self":                 p.IsSelf,
+		"score":                   p.Score,
+		"num_comments":            len(p.Comments),
+		"link_flair_text":         nullable(p.Flair),
+		"over_18":                 p.Subreddit.Over18,
+		"created_utc":             float64(p.Created.Unix()),
+	}}
+}
+
+func commentThing(c *Comment) map[string]any {
+	return map[string]any{"kind": "t1", "data": map[string]any{
+		"id":          c.ID,
+		"name":        "t1_" + c.ID,
+		"parent_id":   c.ParentFullname(),
+		"link_id":     "t3_" + c.Post.ID,
+		"link_title":  c.Post.Title,
+		"subreddit":   c.Post.Subreddit.Name,
+		"author":      c.Author,
+		"body":        c.Body,
+		"score":       c.Score,
+		"depth":       c.Depth,
+		"permalink":   permalink(c.Post) + c.ID + "/",
+		"created_utc": float64(c.Created.Unix()),
+		"replies":     "",
+	}}
+}
+
+func moreThing(parent string, depth, count int, ids []string) map[string]any {
+	id := "_"
+	if len(ids) > 0 {
+		id = ids[0]
+	}
+	return map[string]any{"kind": "more", "data": map[string]any{
+		"id":        id,
+		"name":      "t1_" + id,
+		"parent_id": parent,
+		"depth":     depth,
+		"count":     count,
+		"children":  ids,
+	}}
+}
+
+func permalink(p *Post) string {
+	return fmt.Sprintf("/r/%s/comments/%s/%s/", p.Subreddit.Name, p.ID, slug(p.Title))
+}
+
+func slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' && b.Len() > 0:
+			b.WriteByte('_')
+		}
+		if b.Len() >= 50 {
+			break
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func fnv32(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,326 @@
+package fakereddit
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerOptions controls authentication, rate limiting and fault injection.
+type ServerOptions struct {
+	ClientID     string // when set, token requests must use this client ID and secret
+	ClientSecret string
+	TokenTTL     time.Duration // lifetime of issued tokens
+	Quota        int           // requests per window and token; 0 disables rate limiting
+	Window       time.Duration // rate-limit window, reported in X-Ratelimit-Reset
+	ErrorRate    float64       // share of API requests answered with a 5xx
+	ThrottleRate float64       // share of API requests answered with a 429
+	Latency      time.Duration // added to every response
+	Seed         int64         // seeds fault injection
+}
+
+// DefaultServerOptions mirrors Reddit's OAuth limits: 600 requests per 10 minutes.
+func DefaultServerOptions() ServerOptions {
+	return ServerOptions{
+		TokenTTL: 24 * time.Hour,
+		Quota:    600,
+		Window:   10 * time.Minute,
+		Seed:     1,
+	}
+}
+
+// Server serves the Reddit API from a World.
+type Server struct {
+	world *World
+	opts  ServerOptions
+	start time.Time
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	tokens   map[string]time.Time // token -> expiry
+	issued   int
+	usage    map[string]*quotaWindow // per token, or "public" for anonymous requests
+	requests map[string]int          // per endpoint, for /_fake/stats
+}
+
+type quotaWindow struct {
+	start time.Time
+	used  int
+}
+
+// NewServer creates a server for w.
+func NewServer(w *World, opts ServerOptions) *Server {
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Minute
+	}
+	return &Server{
+		world:    w,
+		opts:     opts,
+		start:    time.Now(),
+		rng:      rand.New(rand.NewSource(opts.Seed)),
+		tokens:   make(map[string]time.Time),
+		usage:    make(map[string]*quotaWindow),
+		requests: make(map[string]int),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Latency > 0 {
+		time.Sleep(s.opts.Latency)
+	}
+	path := strings.Trim(strings.TrimSuffix(strings.Trim(r.URL.Path, "/"), ".json"), "/")
+	segs := strings.Split(path, "/")
+
+	if path == "api/v1/access_token" {
+		s.count("token")
+		s.handleToken(w, r)
+		return
+	}
+	if path == "_fake/stats" {
+		s.handleStats(w)
+		return
+	}
+
+	key, ok := s.authorize(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "", "Unauthorized")
+		return
+	}
+	if !s.spendQuota(w, key) {
+		return
+	}
+	if status := s.injectFault(); status != 0 {
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeError(w, status, "", http.StatusText(status))
+		return
+	}
+
+	switch {
+	case path == "api/info":
+		s.count("info")
+		s.handleInfo(w, r)
+	case path == "api/morechildren":
+		s.count("morechildren")
+		s.handleMoreChildren(w, r)
+	case path == "search":
+		s.count("search")
+		s.handleSearch(w, r, nil)
+	case segs[0] == "comments" && len(segs) >= 2:
+		s.count("comments")
+		s.handleComments(w, r, segs[1])
+	case (segs[0] == "user" || segs[0] == "u") && len(segs) >= 2:
+		s.count("user")
+		kind := "overview"
+		if len(segs) >= 3 {
+			kind = segs[2]
+		}
+		s.handleUser(w, r, segs[1], kind)
+	case segs[0] == "r" && len(segs) >= 2:
+		s.handleSubreddit(w, r, segs[1:])
+	default:
+		writeError(w, http.StatusNotFound, "", "Not Found")
+	}
+}
+
+func (s *Server) handleSubreddit(w http.ResponseWriter, r *http.Request, segs []string) {
+	sub := s.world.Subreddit(segs[0])
+	if sub == nil {
+		s.count("about")
+		writeError(w, http.StatusNotFound, "", "Not Found")
+		return
+	}
+	action := "hot"
+	if len(segs) >= 2 && segs[1] != "" {
+		action = segs[1]
+	}
+	switch action {
+	case "about":
+		s.count("about")
+	case "comments":
+		s.count("comments")
+	case "search":
+		s.count("search")
+	default:
+		s.count("listing")
+	}
+	switch sub.Status {
+	case StatusPrivate:
+		writeError(w, http.StatusForbidden, "private", "Forbidden")
+		return
+	case StatusBanned:
+		writeError(w, http.StatusNotFound, "banned", "Not Found")
+		return
+	}
+
+	switch action {
+	case "about":
+		writeJSON(w, map[string]any{"kind": "t5", "data": subredditData(sub)})
+	case "comments":
+		if len(segs) < 3 {
+			writeError(w, http.StatusNotFound, "", "Not Found")
+			return
+		}
+		s.handleComments(w, r, segs[2])
+	case "search":
+		s.handleSearch(w, r, sub)
+	case "hot", "new", "top", "rising", "controversial":
+		posts := sortPosts(sub.Posts, action, r.URL.Query().Get("t"), s.world.Epoch)
+		writeJSON(w, postListing(posts, r.URL.Query()))
+	default:
+		writeError(w, http.StatusNotFound, "", "Not Found")
+	}
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "", "Method Not Allowed")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok || id == "" {
+		writeError(w, http.StatusUnauthorized, "", "Unauthorized")
+		return
+	}
+	if s.opts.ClientID != "" && (subtle.ConstantTimeCompare([]byte(id), []byte(s.opts.ClientID)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(secret), []byte(s.opts.ClientSecret)) != 1) {
+		writeError(w, http.StatusUnauthorized, "", "Unauthorized")
+		return
+	}
+	s.mu.Lock()
+	s.issued++
+	token := fmt.Sprintf("fake-token-%d", s.issued)
+	s.tokens[token] = time.Now().Add(s.opts.TokenTTL)
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   int(s.opts.TokenTTL.Seconds()),
+		"scope":        "read",
+	})
+}
+
+// authorize checks the bearer token. Requests without one are served as public
+// traffic, like www/old.reddit.com; an unknown or expired token is rejected.
+func (s *Server) authorize(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "public", true
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.tokens[token]
+	if !ok || time.Now().After(expiry) {
+		return "", false
+	}
+	return token, true
+}
+
+// spendQuota counts a request against key's window and sets the X-Ratelimit-*
+// headers. Over quota it answers 429 and returns false.
+func (s *Server) spendQuota(w http.ResponseWriter, key string) bool {
+	if s.opts.Quota <= 0 {
+		return true
+	}
+	now := time.Now()
+	s.mu.Lock()
+	q := s.usage[key]
+	// Windows are aligned to the server start, like Reddit's fixed windows.
+	windowStart := s.start.Add(now.Sub(s.start).Truncate(s.opts.Window))
+	if q == nil || q.start != windowStart {
+		q = &quotaWindow{start: windowStart}
+		s.usage[key] = q
+	}
+	q.used++
+	used := q.used
+	s.mu.Unlock()
+
+	reset := int(windowStart.Add(s.opts.Window).Sub(now).Seconds())
+	remaining := s.opts.Quota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	h := w.Header()
+	h.Set("X-Ratelimit-Used", strconv.Itoa(used))
+	h.Set("X-Ratelimit-Remaining", fmt.Sprintf("%.1f", float64(remaining)))
+	h.Set("X-Ratelimit-Reset", strconv.Itoa(reset))
+	if used > s.opts.Quota {
+		h.Set("Retry-After", strconv.Itoa(reset+1))
+		writeError(w, http.StatusTooManyRequests, "", "Too Many Requests")
+		return false
+	}
+	return true
+}
+
+// injectFault picks a simulated failure for the request, or 0 for none.
+func (s *Server) injectFault() int {
+	if s.opts.ErrorRate <= 0 && s.opts.ThrottleRate <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := s.rng.Float64()
+	switch {
+	case x < s.opts.ThrottleRate:
+		return http.StatusTooManyRequests
+	case x < s.opts.ThrottleRate+s.opts.ErrorRate:
+		return []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}[s.rng.Intn(3)]
+	default:
+		return 0
+	}
+}
+
+func (s *Server) count(endpoint string) {
+	s.mu.Lock()
+	s.requests[endpoint]++
+	s.mu.Unlock()
+}
+
+// handleStats reports the world's size and the requests served per endpoint.
+func (s *Server) handleStats(w http.ResponseWriter) {
+	posts, comments := s.world.Counts()
+	subs := make([]map[string]any, 0, len(s.world.Subreddits))
+	for _, sub := range s.world.Subreddits {
+		subs = append(subs, map[string]any{"name": sub.Name, "status": sub.Status, "posts": len(sub.Posts)})
+	}
+	s.mu.Lock()
+	requests := make(map[string]int, len(s.requests))
+	for k, v := range s.requests {
+		requests[k] = v
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{
+		"subreddits": subs,
+		"users":      len(s.world.Users),
+		"posts":      posts,
+		"comments":   comments,
+		"requests":   requests,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers in Reddit's error format, e.g. {"reason":"private","message":"Forbidden","error":403}.
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	body := map[string]any{"message": message, "error": status}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
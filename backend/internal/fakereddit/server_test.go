@@ -0,0 +1,367 @@
+package fakereddit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEpoch = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func testWorld() *World {
+	opts := DefaultOptions()
+	opts.Subreddits = 6
+	opts.Users = 80
+	opts.PostsPerSub = 40
+	opts.CommentsPerPost = 30
+	opts.PrivateSubs = 1
+	opts.BannedSubs = 1
+	opts.Epoch = testEpoch
+	return NewWorld(opts)
+}
+
+type listingResp struct {
+	Data struct {
+		After    *string `json:"after"`
+		Before   *string `json:"before"`
+		Children []struct {
+			Kind string         `json:"kind"`
+			Data map[string]any `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+func do(t *testing.T, srv http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode: %v\n%s", err, rec.Body)
+	}
+}
+
+func TestNewWorldIsDeterministic(t *testing.T) {
+	a, b := testWorld(), testWorld()
+	pa, ca := a.Counts()
+	pb, cb := b.Counts()
+	if pa != pb || ca != cb || pa == 0 || ca == 0 {
+		t.Fatalf("counts differ: %d/%d vs %d/%d", pa, ca, pb, cb)
+	}
+	for i, sub := range a.Subreddits {
+		if sub.Name != b.Subreddits[i].Name || len(sub.Posts) != len(b.Subreddits[i].Posts) {
+			t.Fatalf("subreddit %d differs: %s vs %s", i, sub.Name, b.Subreddits[i].Name)
+		}
+	}
+	if a.Subreddit("askreddit") == nil {
+		t.Error("seed subreddits should always exist and be looked up case-insensitively")
+	}
+	for _, sub := range a.Subreddits {
+		for _, p := range sub.Posts {
+			for _, c := range p.Comments {
+				if c.Created.After(testEpoch) {
+					t.Fatalf("comment %s created after the epoch", c.ID)
+				}
+			}
+		}
+	}
+}
+
+func TestTokenAndAuthorization(t *testing.T) {
+	opts := DefaultServerOptions()
+	opts.ClientID, opts.ClientSecret = "id", "secret"
+	srv := NewServer(testWorld(), opts)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/access_token", strings.NewReader("grant_type=client_credentials"))
+	req.SetBasicAuth("id", "wrong")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad secret: status %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/access_token", strings.NewReader("grant_type=client_credentials"))
+	req.SetBasicAuth("id", "secret")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	decode(t, rec, &tok)
+	if tok.AccessToken == "" || tok.ExpiresIn != int((24*time.Hour).Seconds()) {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+
+	if rec := do(t, srv, "GET", "/r/AskReddit/about.json", "bogus"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: status %d", rec.Code)
+	}
+	var about struct {
+		Kind string         `json:"kind"`
+		Data map[string]any `json:"data"`
+	}
+	decode(t, do(t, srv, "GET", "/r/AskReddit/about.json", tok.AccessToken), &about)
+	if about.Kind != "t5" || about.Data["display_name"] != "AskReddit" {
+		t.Errorf("unexpected about %+v", about)
+	}
+}
+
+func TestRateLimitHeadersAndQuota(t *testing.T) {
+	opts := DefaultServerOptions()
+	opts.Quota = 3
+	srv := NewServer(testWorld(), opts)
+
+	for i := 1; i <= 3; i++ {
+		rec := do(t, srv, "GET", "/r/AskReddit/new.json", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("X-Ratelimit-Used"); got != string(rune('0'+i)) {
+			t.Errorf("request %d: X-Ratelimit-Used = %s", i, got)
+		}
+	}
+	rec := do(t, srv, "GET", "/r/AskReddit/new.json", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over quota: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("X-Ratelimit-Remaining") != "0.0" {
+		t.Errorf("X-Ratelimit-Remaining = %s", rec.Header().Get("X-Ratelimit-Remaining"))
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	opts := DefaultServerOptions()
+	opts.Quota = 0
+	opts.ErrorRate = 1
+	srv := NewServer(testWorld(), opts)
+	if rec := do(t, srv, "GET", "/r/AskReddit/new.json", ""); rec.Code < 500 {
+		t.Errorf("expected a 5xx, got %d", rec.Code)
+	}
+
+	opts.ErrorRate, opts.ThrottleRate = 0, 1
+	srv = NewServer(testWorld(), opts)
+	if rec := do(t, srv, "GET", "/r/AskReddit/new.json", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a 429, got %d", rec.Code)
+	}
+}
+
+func TestInaccessibleSubreddits(t *testing.T) {
+	w := testWorld()
+	srv := NewServer(w, DefaultServerOptions())
+	var private, banned *Subreddit
+	for _, sub := range w.Subreddits {
+		switch sub.Status {
+		case StatusPrivate:
+			private = sub
+		case StatusBanned:
+			banned = sub
+		}
+	}
+	if private == nil || banned == nil {
+		t.Fatal("expected a private and a banned subreddit")
+	}
+
+	cases := []struct {
+		path   string
+		status int
+		reason string
+	}{
+		{"/r/" + private.Name + "/about.json", http.StatusForbidden, "private"},
+		{"/r/" + banned.Name + "/new.json", http.StatusNotFound, "banned"},
+		{"/r/doesnotexist/about.json", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rec := do(t, srv, "GET", tc.path, "")
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		reason, _ := body["reason"].(string)
+		if rec.Code != tc.status || reason != tc.reason {
+			t.Errorf("%s: status %d reason %q, want %d %q", tc.path, rec.Code, reason, tc.status, tc.reason)
+		}
+	}
+}
+
+func TestListingPagination(t *testing.T) {
+	w := testWorld()
+	srv := NewServer(w, ServerOptions{})
+	sub := w.Subreddit("AskReddit")
+
+	seen := make(map[string]bool)
+	next := "/r/AskReddit/new.json?limit=7"
+	var prev string
+	for pages := 0; next != ""; pages++ {
+		if pages > len(sub.Posts) {
+			t.Fatal("pagination does not terminate")
+		}
+		var l listingResp
+		decode(t, do(t, srv, "GET", next, ""), &l)
+		if len(l.Data.Children) > 7 {
+			t.Fatalf("page has %d items, limit 7", len(l.Data.Children))
+		}
+		for _, c := range l.Data.Children {
+			id := c.Data["id"].(string)
+			if seen[id] {
+				t.Fatalf("post %s returned twice", id)
+			}
+			seen[id] = true
+			created := c.Data["created_utc"].(float64)
+			if prev != "" && created > w.Post(prev).Created.Sub(time.Unix(0, 0)).Seconds() {
+				t.Fatalf("new listing not sorted by creation time at %s", id)
+			}
+			prev = id
+		}
+		if pages > 0 && l.Data.Before == nil {
+			t.Error("later pages should carry a before cursor")
+		}
+		next = ""
+		if l.Data.After != nil {
+			next = "/r/AskReddit/new.json?limit=7&after=" + *l.Data.After
+		}
+	}
+	if len(seen) != len(sub.Posts) {
+		t.Errorf("paged through %d posts, subreddit has %d", len(seen), len(sub.Posts))
+	}
+
+	var back listingResp
+	decode(t, do(t, srv, "GET", "/r/AskReddit/new.json?limit=5&before=t3_"+sub.Posts[5].ID, ""), &back)
+	if len(back.Data.Children) != 5 || back.Data.Children[0].Data["id"] != sub.Posts[0].ID {
+		t.Errorf("before cursor returned the wrong page")
+	}
+}
+
+func TestCommentTreeAndMoreChildren(t *testing.T) {
+	w := testWorld()
+	srv := NewServer(w, ServerOptions{})
+	var post *Post
+	for _, p := range w.Subreddit("AskReddit").Posts {
+		if post == nil || len(p.Comments) > len(post.Comments) {
+			post = p
+		}
+	}
+
+	var thread []listingResp
+	decode(t, do(t, srv, "GET", "/comments/"+post.ID+".json?limit=5", ""), &thread)
+	if len(thread) != 2 || thread[0].Data.Children[0].Data["id"] != post.ID {
+		t.Fatalf("unexpected thread shape")
+	}
+
+	got := 0
+	var stubs []string
+	var walk func(children []struct {
+		Kind string         `json:"kind"`
+		Data map[string]any `json:"data"`
+	})
+	walk = func(children []struct {
+		Kind string         `json:"kind"`
+		Data map[string]any `json:"data"`
+	}) {
+		for _, c := range children {
+			if c.Kind == "more" {
+				for _, id := range c.Data["children"].([]any) {
+					stubs = append(stubs, id.(string))
+				}
+				continue
+			}
+			got++
+			if replies, ok := c.Data["replies"].(map[string]any); ok {
+				b, _ := json.Marshal(replies)
+				var l listingResp
+				json.Unmarshal(b, &l)
+				walk(l.Data.Children)
+			}
+		}
+	}
+	walk(thread[1].Data.Children)
+	if got != 5 || len(stubs) == 0 {
+		t.Fatalf("got %d comments and %d stub IDs, want 5 and some", got, len(stubs))
+	}
+
+	var more struct {
+		JSON struct {
+			Errors []any `json:"errors"`
+			Data   struct {
+				Things []struct {
+					Kind string         `json:"kind"`
+					Data map[string]any `json:"data"`
+				} `json:"things"`
+			} `json:"data"`
+		} `json:"json"`
+	}
+	target := "/api/morechildren?api_type=json&link_id=t3_" + post.ID + "&children=" + strings.Join(stubs, ",")
+	decode(t, do(t, srv, "GET", target, ""), &more)
+	if len(more.JSON.Errors) != 0 {
+		t.Fatalf("errors: %v", more.JSON.Errors)
+	}
+	expanded := 0
+	for _, th := range more.JSON.Data.Things {
+		if th.Kind == "t1" {
+			expanded++
+		}
+	}
+	if got+expanded != len(post.Comments) {
+		t.Errorf("tree plus morechildren returned %d comments, post has %d", got+expanded, len(post.Comments))
+	}
+}
+
+func TestUserAndSearch(t *testing.T) {
+	w := testWorld()
+	srv := NewServer(w, ServerOptions{})
+	var user *User
+	for _, u := range w.Users {
+		if len(u.Posts) > 0 && len(u.Comments) > 0 {
+			user = u
+			break
+		}
+	}
+
+	var submitted, overview listingResp
+	decode(t, do(t, srv, "GET", "/user/"+user.Name+"/submitted.json?limit=100", ""), &submitted)
+	decode(t, do(t, srv, "GET", "/user/"+user.Name+"/.json?limit=100", ""), &overview)
+	if len(submitted.Data.Children) != min(len(user.Posts), 100) {
+		t.Errorf("submitted has %d posts, want %d", len(submitted.Data.Children), len(user.Posts))
+	}
+	if len(overview.Data.Children) != min(len(user.Posts)+len(user.Comments), 100) {
+		t.Errorf("overview has %d items", len(overview.Data.Children))
+	}
+	if rec := do(t, srv, "GET", "/user/nobody_at_all/comments.json", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status %d", rec.Code)
+	}
+
+	var found listingResp
+	decode(t, do(t, srv, "GET", "/search.json?q=author:"+user.Name+"&sort=new&limit=100", ""), &found)
+	for _, c := range found.Data.Children {
+		if c.Data["author"] != user.Name {
+			t.Fatalf("search returned a post by %v", c.Data["author"])
+		}
+	}
+	public := 0
+	for _, p := range user.Posts {
+		if p.Subreddit.Status == StatusPublic {
+			public++
+		}
+	}
+	if len(found.Data.Children) != min(public, 100) {
+		t.Errorf("author search found %d posts, want %d", len(found.Data.Children), public)
+	}
+
+	post := w.Subreddit("AskReddit").Posts[0]
+	var info listingResp
+	decode(t, do(t, srv, "GET", "/api/info?id=t3_"+post.ID+"&raw_json=1", ""), &info)
+	if len(info.Data.Children) != 1 || info.Data.Children[0].Data["subreddit"] != "AskReddit" {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,356 @@
+// Package fakereddit implements the Reddit endpoints used by the crawler on top
+// of a synthetic, seeded world of subreddits, users, posts and comments. It backs
+// cmd/fakereddit, which lets crawl and precalculation pipelines run locally and in
+// CI without touching the real API.
+package fakereddit
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subreddit access states. Private subreddits answer 403 and banned ones 404,
+// with the reason in the body as Reddit does.
+const (
+	StatusPublic  = "public"
+	StatusPrivate = "private"
+	StatusBanned  = "banned"
+)
+
+// Options sizes the synthetic world. The same options always produce the same
+// world, apart from timestamps, which are relative to Epoch.
+type Options struct {
+	Seed            int64
+	Subreddits      int      // generated subreddits, in addition to SubredditNames
+	SubredditNames  []string // subreddits that always exist, e.g. the crawler's seeds
+	Users           int
+	PostsPerSub     int     // average posts per public subreddit
+	CommentsPerPost int     // average comments per post
+	PrivateSubs     int     // generated subreddits that are private
+	BannedSubs      int     // generated subreddits that are banned
+	NSFWFraction    float64 // share of public subreddits marked over_18
+	Epoch           time.Time
+}
+
+// DefaultOptions returns a small world suitable for local development.
+func DefaultOptions() Options {
+	return Options{
+		Seed:            1,
+		Subreddits:      30,
+		SubredditNames:  []string{"AskReddit", "worldnews", "technology", "funny", "gaming"},
+		Users:           500,
+		PostsPerSub:     60,
+		CommentsPerPost: 12,
+		PrivateSubs:     2,
+		BannedSubs:      2,
+		NSFWFraction:    0.05,
+	}
+}
+
+// postSpan is how far back from the epoch posts are spread.
+const postSpan = 30 * 24 * time.Hour
+
+// Subreddit is a generated community.
+type Subreddit struct {
+	Name        string
+	Title       string
+	Description string
+	Subscribers int
+	Over18      bool
+	Status      string
+	Posts       []*Post // newest first
+}
+
+// Post is a generated submission.
+type Post struct {
+	ID        string
+	Subreddit *Subreddit
+	Author    string
+	Title     string
+	Selftext  string
+	URL       string
+	Flair     string
+	IsSelf    bool
+	Score     int
+	Created   time.Time
+	Comments  []*Comment // every comment, in creation order
+	Roots     []*Comment // top-level comments
+}
+
+// Comment is a generated comment.
+type Comment struct {
+	ID       string
+	Post     *Post
+	Parent   *Comment // nil for top-level comments
+	Author   string
+	Body     string
+	Score    int
+	Depth    int
+	Created  time.Time
+	Children []*Comment
+	size     int // comments in this subtree, including itself
+}
+
+// ParentFullname is the parent's fullname: t1_ for replies, t3_ for top-level comments.
+func (c *Comment) ParentFullname() string {
+	if c.Parent != nil {
+		return "t1_" + c.Parent.ID
+	}
+	return "t3_" + c.Post.ID
+}
+
+// User is a generated account with its history, newest first.
+type User struct {
+	Name     string
+	Posts    []*Post
+	Comments []*Comment
+}
+
+// World holds the generated content and its indexes.
+type World struct {
+	Epoch      time.Time
+	Subreddits []*Subreddit
+	Users      []*User
+
+	subs     map[string]*Subreddit // by lower-case name
+	users    map[string]*User      // by lower-case name
+	posts    map[string]*Post
+	comments map[string]*Comment
+}
+
+var (
+	adjectives = []string{"quiet", "brave", "lucky", "rapid", "silver", "hidden", "cosmic", "gentle", "rusty", "bright",
+		"clever", "dusty", "fuzzy", "golden", "hollow", "icy", "jolly", "lively", "misty", "noble", "odd", "proud", "royal", "sunny"}
+	nouns = []string{"falcon", "otter", "maple", "river", "comet", "garden", "engine", "lantern", "harbor", "meadow",
+		"pixel", "summit", "tundra", "violin", "walrus", "canyon", "badger", "cactus", "dragon", "glacier", "koala", "nebula", "orchid", "quartz"}
+	topics = []string{"gardening", "rust", "golang", "astronomy", "cooking", "cycling", "chess", "photography", "history",
+		"linux", "music", "running", "travel", "woodworking", "coffee", "databases", "graphs", "typescript", "birds", "movies"}
+	flairs = []string{"", "", "discussion", "question", "news", "meta", "showcase"}
+)
+
+// NewWorld generates a world from opts.
+func NewWorld(opts Options) *World {
+	if opts.Epoch.IsZero() {
+		opts.Epoch = time.Now().UTC().Truncate(time.Second)
+	}
+	if opts.Users < 1 {
+		opts.Users = 1
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	w := &World{
+		Epoch:    opts.Epoch,
+		subs:     make(map[string]*Subreddit),
+		users:    make(map[string]*User),
+		posts:    make(map[string]*Post),
+		comments: make(map[string]*Comment),
+	}
+
+	for _, name := range opts.SubredditNames {
+		if name = strings.TrimSpace(name); name != "" {
+			w.addSubreddit(rng, name, StatusPublic, opts.NSFWFraction)
+		}
+	}
+	generated := make([]*Subreddit, 0, opts.Subreddits)
+	for i := 0; i < opts.Subreddits; i++ {
+		name := uniqueName(rng, func(s string) bool { return w.subs[strings.ToLower(s)] != nil }, 21, "")
+		generated = append(generated, w.addSubreddit(rng, name, StatusPublic, opts.NSFWFraction))
+	}
+	// The last generated subreddits are the inaccessible ones.
+	for i := 0; i < opts.PrivateSubs+opts.BannedSubs && i < len(generated); i++ {
+		sub := generated[len(generated)-1-i]
+		sub.Status = StatusPrivate
+		if i >= opts.PrivateSubs {
+			sub.Status = StatusBanned
+		}
+	}
+
+	for i := 0; i < opts.Users; i++ {
+		name := uniqueName(rng, func(s string) bool { return w.users[strings.ToLower(s)] != nil }, 20, "_")
+		u := &User{Name: name}
+		w.Users = append(w.Users, u)
+		w.users[strings.ToLower(name)] = u
+	}
+
+	var postSeq, commentSeq int64
+	for _, sub := range w.Subreddits {
+		if sub.Status != StatusPublic {
+			continue
+		}
+		// Each subreddit has a core of regulars, so users overlap across communities.
+		core := make([]*User, 0, len(w.Users)/10+1)
+		for i := 0; i < cap(core); i++ {
+			core = append(core, w.Users[rng.Intn(len(w.Users))])
+		}
+		pickAuthor := func() *User {
+			if rng.Float64() < 0.8 {
+				return core[rng.Intn(len(core))]
+			}
+			return w.Users[rng.Intn(len(w.Users))]
+		}
+
+		nPosts := varied(rng, opts.PostsPerSub)
+		for i := 0; i < nPosts; i++ {
+			postSeq++
+			author := pickAuthor()
+			p := &Post{
+				ID:        fakeID(postSeq, 0x2000000),
+				Subreddit: sub,
+				Author:    author.Name,
+				Created:   opts.Epoch.Add(-time.Duration(rng.Int63n(int64(postSpan)))),
+				Score:     int(rng.ExpFloat64() * 40),
+				Flair:     flairs[rng.Intn(len(flairs))],
+				IsSelf:    rng.Float64() < 0.7,
+			}
+			p.Title, p.Selftext = w.postText(rng, sub)
+			if p.IsSelf {
+				p.URL = fmt.Sprintf("https://www.reddit.com/r/%s/comments/%s/", sub.Name, p.ID)
+			} else {
+				p.URL = fmt.Sprintf("https://example.com/%s/%s", topics[rng.Intn(len(topics))], p.ID)
+				p.Selftext = ""
+			}
+			sub.Posts = append(sub.Posts, p)
+			author.Posts = append(author.Posts, p)
+			w.posts[p.ID] = p
+
+			nComments := varied(rng, opts.CommentsPerPost)
+			for j := 0; j < nComments; j++ {
+				commentSeq++
+				cAuthor := pickAuthor()
+				c := &Comment{
+					ID:     fakeID(commentSeq, 0x4000000),
+					Post:   p,
+					Author: cAuthor.Name,
+					Body:   w.commentText(rng),
+					Score:  int(rng.ExpFloat64()*8) - 1,
+				}
+				if len(p.Comments) > 0 && rng.Float64() < 0.6 {
+					c.Parent = p.Comments[rng.Intn(len(p.Comments))]
+					c.Depth = c.Parent.Depth + 1
+					c.Parent.Children = append(c.Parent.Children, c)
+					c.Created = c.Parent.Created.Add(time.Duration(1+rng.Intn(120)) * time.Minute)
+				} else {
+					p.Roots = append(p.Roots, c)
+					c.Created = p.Created.Add(time.Duration(1+rng.Intn(240)) * time.Minute)
+				}
+				if c.Created.After(opts.Epoch) {
+					c.Created = opts.Epoch
+				}
+				p.Comments = append(p.Comments, c)
+				cAuthor.Comments = append(cAuthor.Comments, c)
+				w.comments[c.ID] = c
+			}
+			for _, root := range p.Roots {
+				computeSizes(root)
+			}
+		}
+		sort.SliceStable(sub.Posts, func(i, j int) bool { return sub.Posts[i].Created.After(sub.Posts[j].Created) })
+	}
+	for _, u := range w.Users {
+		sort.SliceStable(u.Posts, func(i, j int) bool { return u.Posts[i].Created.After(u.Posts[j].Created) })
+		sort.SliceStable(u.Comments, func(i, j int) bool { return u.Comments[i].Created.After(u.Comments[j].Created) })
+	}
+	return w
+}
+
+func (w *World) addSubreddit(rng *rand.Rand, name, status string, nsfw float64) *Subreddit {
+	topic := topics[rng.Intn(len(topics))]
+	sub := &Subreddit{
+		Name:        name,
+		Title:       fmt.Sprintf("r/%s: all about %s", name, topic),
+		Description: fmt.Sprintf("A community for %s fans.", topic),
+		// Subscriber counts follow a heavy-tailed distribution.
+		Subscribers: int(math.Exp(4 + rng.Float64()*10)),
+		Over18:      rng.Float64() < nsfw,
+		Status:      status,
+	}
+	w.Subreddits = append(w.Subreddits, sub)
+	w.subs[strings.ToLower(name)] = sub
+	return sub
+}
+
+// postText builds a title and body. About one post in ten links another
+// subreddit, which the crawler follows as a mention.
+func (w *World) postText(rng *rand.Rand, sub *Subreddit) (string, string) {
+	a, b := topics[rng.Intn(len(topics))], topics[rng.Intn(len(topics))]
+	title := fmt.Sprintf("What do you think about %s and %s?", a, b)
+	body := fmt.Sprintf("I have been getting into %s lately. Any tips from people who know %s?", a, b)
+	if rng.Float64() < 0.1 {
+		other := w.Subreddits[rng.Intn(len(w.Subreddits))]
+		if other != sub {
+			body += fmt.Sprintf(" Also asked over in r/%s.", other.Name)
+		}
+	}
+	return title, body
+}
+
+func (w *World) commentText(rng *rand.Rand) string {
+	switch rng.Intn(4) {
+	case 0:
+		return fmt.Sprintf("I've had good luck with %s.", topics[rng.Intn(len(topics))])
+	case 1:
+		return "Same here, great question."
+	case 2:
+		return fmt.Sprintf("Have you tried the %s %s approach?", adjectives[rng.Intn(len(adjectives))], nouns[rng.Intn(len(nouns))])
+	default:
+		return "Strongly disagree, but I see where you're coming from."
+	}
+}
+
+// Subreddit looks up a subreddit by name, case-insensitively.
+func (w *World) Subreddit(name string) *Subreddit { return w.subs[strings.ToLower(name)] }
+
+// User looks up a user by name, case-insensitively.
+func (w *World) User(name string) *User { return w.users[strings.ToLower(name)] }
+
+// Post looks up a post by ID.
+func (w *World) Post(id string) *Post { return w.posts[strings.ToLower(id)] }
+
+// Comment looks up a comment by ID.
+func (w *World) Comment(id string) *Comment { return w.comments[strings.ToLower(id)] }
+
+// Counts reports the number of posts and comments in the world.
+func (w *World) Counts() (posts, comments int) { return len(w.posts), len(w.comments) }
+
+// uniqueName draws adjective+noun names until one is free, adding a number once
+// the plain combinations run out.
+func uniqueName(rng *rand.Rand, taken func(string) bool, maxLen int, sep string) string {
+	for attempt := 0; ; attempt++ {
+		name := adjectives[rng.Intn(len(adjectives))] + sep + nouns[rng.Intn(len(nouns))]
+		if attempt > 20 {
+			name += sep + strconv.Itoa(rng.Intn(10000))
+		}
+		if len(name) > maxLen {
+			name = name[:maxLen]
+		}
+		if !taken(name) {
+			return name
+		}
+	}
+}
+
+// varied returns a count around avg, between half and one and a half times it.
+func varied(rng *rand.Rand, avg int) int {
+	if avg <= 0 {
+		return 0
+	}
+	return avg/2 + rng.Intn(avg+1)
+}
+
+// fakeID renders a sequence number as a base36 ID. Posts and comments use
+// different offsets so their IDs look distinct.
+func fakeID(seq, offset int64) string {
+	return strconv.FormatInt(offset+seq, 36)
+}
+
+func computeSizes(c *Comment) int {
+	c.size = 1
+	for _, child := range c.Children {
+		c.size += computeSizes(child)
+	}
+	return c.size
+}
@@ -0,0 +1,206 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Backbone extraction methods, selected with BACKBONE_METHOD.
+const (
+	// BackboneDisparity is the disparity filter (Serrano et al. 2009): a link is
+	// significant when it carries more of either endpoint's weight than a uniform
+	// split of that weight over the endpoint's links would.
+	BackboneDisparity = "disparity"
+	// BackboneHypergeometric tests the overlap count of a pair against drawing
+	// both subreddits' overlaps at random from all overlaps in the graph, which
+	// corrects for the number of users big subreddits share with everything.
+	BackboneHypergeometric = "hypergeometric"
+	BackboneNone           = "none"
+)
+
+// BackboneStore stores the significance of graph links.
+type BackboneStore interface {
+	UpdateLinkBackbone(ctx context.Context, linkType string, items []db.LinkSignificance) error
+}
+
+// BackboneEdge is an undirected subreddit link under test.
+type BackboneEdge struct {
+	A, B    int32
+	Weight  float64 // link weight, for the disparity filter
+	Overlap int64   // shared users, for the hypergeometric test
+}
+
+// backboneMethod returns the configured method and significance level.
+func backboneMethod(cfg *config.Config) (string, float64) {
+	method := cfg.BackboneMethod
+	switch method {
+	case BackboneDisparity, BackboneHypergeometric, BackboneNone:
+	case "":
+		method = BackboneDisparity
+	default:
+		log.Printf("⚠️ Unknown BACKBONE_METHOD %q; using %s", method, BackboneDisparity)
+		method = BackboneDisparity
+	}
+	alpha := cfg.BackboneAlpha
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.05
+	}
+	return method, alpha
+}
+
+// backboneEdges merges the relationship links, stored once per direction, into
+// undirected edges.
+func backboneEdges(links []db.BulkInsertGraphLinkParams, overlaps map[[2]int32]int64) []BackboneEdge {
+	idx := make(map[[2]int32]int, len(links)/2)
+	var edges []BackboneEdge
+	for _, l := range links {
+		var a, b int32
+		if _, err := fmt.Sscanf(l.Source, "subreddit_%d", &a); err != nil {
+			continue
+		}
+		if _, err := fmt.Sscanf(l.Target, "subreddit_%d", &b); err != nil || a == b {
+			continue
+		}
+		k := pairKey(a, b)
+		if i, ok := idx[k]; ok {
+			edges[i].Weight = math.Max(edges[i].Weight, l.Weight)
+			continue
+		}
+		idx[k] = len(edges)
+		edges = append(edges, BackboneEdge{A: k[0], B: k[1], Weight: l.Weight, Overlap: overlaps[k]})
+	}
+	return edges
+}
+
+// pairKey orders a subreddit pair by ID.
+func pairKey(a, b int32) [2]int32 {
+	if a > b {
+		return [2]int32{b, a}
+	}
+	return [2]int32{a, b}
+}
+
+// DisparityPValues returns the disparity filter p-value of each edge: for an
+// endpoint with k links and strength s, (1 - w/s)^(k-1), the lower of both ends.
+func DisparityPValues(edges []BackboneEdge) []float64 {
+	strength := map[int32]float64{}
+	degree := map[int32]int{}
+	for _, e := range edges {
+		strength[e.A] += e.Weight
+		strength[e.B] += e.Weight
+		degree[e.A]++
+		degree[e.B]++
+	}
+	side := func(n int32, w float64) float64 {
+		k, s := degree[n], strength[n]
+		if k <= 1 || s <= 0 {
+			return 1
+		}
+		return math.Pow(1-w/s, float64(k-1))
+	}
+	out := make([]float64, len(edges))
+	for i, e := range edges {
+		out[i] = math.Min(side(e.A, e.Weight), side(e.B, e.Weight))
+	}
+	return out
+}
+
+// HypergeometricPValues returns, for each edge, the probability of an overlap at
+// least as large when drawing the overlaps of one endpoint from all overlaps
+// (counted once per direction), of which the other endpoint's are successes.
+func HypergeometricPValues(edges []BackboneEdge) []float64 {
+	strength := map[int32]int64{}
+	var total int64
+	for _, e := range edges {
+		strength[e.A] += e.Overlap
+		strength[e.B] += e.Overlap
+		total += 2 * e.Overlap
+	}
+	out := make([]float64, len(edges))
+	for i, e := range edges {
+		out[i] = hypergeomSF(e.Overlap, total, strength[e.A], strength[e.B])
+	}
+	return out
+}
+
+// hypergeomSF is P(X >= k) for X ~ Hypergeometric(population n, successes s,
+// draws d).
+func hypergeomSF(k, n, s, d int64) float64 {
+	lo, hi := d+s-n, s
+	if lo < 0 {
+		lo = 0
+	}
+	if d < hi {
+		hi = d
+	}
+	if k <= lo {
+		return 1
+	}
+	if k > hi {
+		return 0
+	}
+	logDenom := logChoose(n, d)
+	mode := (d + 1) * (s + 1) / (n + 2)
+	sum := 0.0
+	for x := k; x <= hi; x++ {
+		term := math.Exp(logChoose(s, x) + logChoose(n-s, d-x) - logDenom)
+		sum += term
+		// Past the mode the terms only shrink
+		if x > mode && term < sum*1e-15 {
+			break
+		}
+	}
+	return math.Min(sum, 1)
+}
+
+func logChoose(n, k int64) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// ExtractBackbone marks the subreddit overlap links as kept or pruned by their
+// significance under method, at level alpha. links are the relationship links
+// of this precalculation and overlaps the shared users of each subreddit pair,
+// keyed by the lower ID first.
+func (s *Service) ExtractBackbone(ctx context.Context, links []db.BulkInsertGraphLinkParams, overlaps map[[2]int32]int64, method string, alpha float64) error {
+	bs, ok := s.store.(BackboneStore)
+	if !ok {
+		return nil
+	}
+	if method == BackboneNone {
+		return bs.UpdateLinkBackbone(ctx, db.LinkTypeSubredditOverlap, nil)
+	}
+	edges := backboneEdges(links, overlaps)
+	var pvalues []float64
+	if method == BackboneHypergeometric {
+		pvalues = HypergeometricPValues(edges)
+	} else {
+		pvalues = DisparityPValues(edges)
+	}
+
+	items := make([]db.LinkSignificance, 0, 2*len(edges))
+	kept := 0
+	for i, e := range edges {
+		p := pvalues[i]
+		if p <= alpha {
+			kept++
+		}
+		a, b := fmt.Sprintf("subreddit_%d", e.A), fmt.Sprintf("subreddit_%d", e.B)
+		items = append(items,
+			db.LinkSignificance{Source: a, Target: b, PValue: p, Kept: p <= alpha},
+			db.LinkSignificance{Source: b, Target: a, PValue: p, Kept: p <= alpha},
+		)
+	}
+	if err := bs.UpdateLinkBackbone(ctx, db.LinkTypeSubredditOverlap, items); err != nil {
+		return err
+	}
+	log.Printf("✅ Backbone (%s, alpha %g) keeps %d of %d subreddit pairs", method, alpha, kept, len(edges))
+	return nil
+}
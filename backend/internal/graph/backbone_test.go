@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestDisparityPValues(t *testing.T) {
+	// A hub with one strong and two weak links; the leaves only have one link each
+	edges := []BackboneEdge{{A: 1, B: 2, Weight: 10}, {A: 1, B: 3, Weight: 1}, {A: 1, B: 4, Weight: 1}}
+	p := DisparityPValues(edges)
+	if !near(p[0], 1.0/36) || !near(p[1], 121.0/144) || !near(p[2], p[1]) {
+		t.Errorf("unexpected p-values %v", p)
+	}
+}
+
+func TestHypergeomSF(t *testing.T) {
+	tests := []struct {
+		k, n, s, d int64
+		want       float64
+	}{
+		{k: 5, n: 10, s: 5, d: 5, want: 1.0 / 252},
+		{k: 3, n: 10, s: 5, d: 5, want: 0.5},
+		{k: 0, n: 10, s: 5, d: 5, want: 1},
+		{k: 6, n: 10, s: 5, d: 5, want: 0},
+		{k: 2, n: 8, s: 3, d: 3, want: 16.0 / 56},
+	}
+	for _, tt := range tests {
+		if got := hypergeomSF(tt.k, tt.n, tt.s, tt.d); !near(got, tt.want) {
+			t.Errorf("P(X >= %d | %d, %d, %d) = %v, want %v", tt.k, tt.n, tt.s, tt.d, got, tt.want)
+		}
+	}
+	// Large counts stay finite and tiny for an overlap far above chance
+	if p := hypergeomSF(5000, 1_000_000, 10_000, 10_000); !(p >= 0 && p < 1e-100) {
+		t.Errorf("expected a vanishing p-value, got %v", p)
+	}
+}
+
+// backboneFakeStore records the backbone p-values written by precalculation.
+type backboneFakeStore struct {
+	*similarityFakeStore
+	linkType string
+	items    []db.LinkSignificance
+}
+
+func (f *backboneFakeStore) UpdateLinkBackbone(ctx context.Context, linkType string, items []db.LinkSignificance) error {
+	f.linkType, f.items = linkType, items
+	return nil
+}
+
+func TestPrecalculateGraphData_Backbone(t *testing.T) {
+	t.Setenv("DETAILED_GRAPH", "false")
+	t.Setenv("BACKBONE_METHOD", "hypergeometric")
+	t.Setenv("BACKBONE_ALPHA", "0.3")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	fs := &backboneFakeStore{similarityFakeStore: &similarityFakeStore{fakeStore: newFakeStore()}}
+	if err := NewService(fs).CalculateSubredditRelationships(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fs.linkType != db.LinkTypeSubredditOverlap || len(fs.items) != 6 {
+		t.Fatalf("expected both directions of 3 pairs, got %q %+v", fs.linkType, fs.items)
+	}
+	for _, it := range fs.items {
+		small := (it.Source == "subreddit_2" && it.Target == "subreddit_3") || (it.Source == "subreddit_3" && it.Target == "subreddit_2")
+		if it.Kept != small {
+			t.Errorf("%s -> %s: kept %v with p %v", it.Source, it.Target, it.Kept, it.PValue)
+		}
+		if small && !near(it.PValue, 16.0/56) {
+			t.Errorf("unexpected p-value %v for the small pair", it.PValue)
+		}
+	}
+
+	t.Setenv("BACKBONE_METHOD", "none")
+	config.ResetForTest()
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.items) != 0 {
+		t.Errorf("method none should clear the p-values, got %+v", fs.items)
+	}
+}
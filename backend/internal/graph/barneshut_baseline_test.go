@@ -1,5 +1,8 @@
 package graph
 
+// The 2D Barnes-Hut quadtree that the octree replaced. It is only built for
+// tests, as the baseline that the octree is benchmarked and checked against.
+
 import "math"
 
 // barnesHutNode represents a quadtree node for Barnes-Hut simulation.
@@ -118,8 +121,9 @@ func (node *barnesHutNode) calculateForce(i int, px, py, theta, repStrength floa
 		if dist < 1e-6 {
 			// Particles too close, add small jitter to prevent collapse
 			dist = 1e-6
-			dx = (randFloat() - 0.5) * 2e-6
-			dy = (randFloat() - 0.5) * 2e-6
+			jx, jy, _ := jitter3(int32(i), int32(node.body))
+			dx = jx * 1e-6
+			dy = jy * 1e-6
 		}
 		// FR-style repulsion: components scale as repStrength * mass / dist^2
 		force := repStrength * node.mass / (dist * dist)
@@ -0,0 +1,299 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// CentralityStore reads the precalculated graph and stores node centrality.
+type CentralityStore interface {
+	ListGraphNodeIDs(ctx context.Context) ([]string, error)
+	ListGraphEdges(ctx context.Context) ([]db.GraphEdge, error)
+	UpdateNodeCentrality(ctx context.Context, items []db.NodeCentrality) error
+}
+
+const (
+	pageRankDamping  = 0.85
+	pageRankMaxIter  = 100
+	pageRankTol      = 1e-10
+	betweennessSeed  = 1
+	defaultBCSamples = 256
+)
+
+// centralityGraph is the precalculated graph as a simple undirected graph:
+// links stored once per direction are merged, keeping the larger weight.
+type centralityGraph struct {
+	ids []string
+	adj [][]int
+	w   [][]float64
+}
+
+func newCentralityGraph(ids []string, edges []db.GraphEdge) *centralityGraph {
+	idx := make(map[string]int, len(ids))
+	for i, id := range ids {
+		idx[id] = i
+	}
+	weights := make(map[[2]int]float64, len(edges))
+	for _, e := range edges {
+		a, okA := idx[e.Source]
+		b, okB := idx[e.Target]
+		if !okA || !okB || a == b {
+			continue
+		}
+		if a > b {
+			a, b = b, a
+		}
+		w := e.Weight
+		if !(w > 0) || math.IsInf(w, 0) {
+			w = 1
+		}
+		if w > weights[[2]int{a, b}] {
+			weights[[2]int{a, b}] = w
+		}
+	}
+	pairs := make([][2]int, 0, len(weights))
+	for p := range weights {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	g := &centralityGraph{ids: ids, adj: make([][]int, len(ids)), w: make([][]float64, len(ids))}
+	for _, p := range pairs {
+		w := weights[p]
+		g.adj[p[0]] = append(g.adj[p[0]], p[1])
+		g.w[p[0]] = append(g.w[p[0]], w)
+		g.adj[p[1]] = append(g.adj[p[1]], p[0])
+		g.w[p[1]] = append(g.w[p[1]], w)
+	}
+	return g
+}
+
+// weightedDegree is the sum of the weights of each node's links.
+func (g *centralityGraph) weightedDegree() []float64 {
+	out := make([]float64, len(g.ids))
+	for i, ws := range g.w {
+		for _, w := range ws {
+			out[i] += w
+		}
+	}
+	return out
+}
+
+// pageRank runs weighted PageRank until the ranks change by less than
+// pageRankTol in total. The rank of nodes without links is spread evenly.
+func (g *centralityGraph) pageRank(strength []float64) []float64 {
+	n := len(g.ids)
+	if n == 0 {
+		return nil
+	}
+	pr := make([]float64, n)
+	next := make([]float64, n)
+	for i := range pr {
+		pr[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < pageRankMaxIter; iter++ {
+		dangling := 0.0
+		for i, s := range strength {
+			if s == 0 {
+				dangling += pr[i]
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, s := range strength {
+			if s == 0 {
+				continue
+			}
+			share := pageRankDamping * pr[i] / s
+			for k, j := range g.adj[i] {
+				next[j] += share * g.w[i][k]
+			}
+		}
+		diff := 0.0
+		for i := range pr {
+			diff += math.Abs(next[i] - pr[i])
+		}
+		pr, next = next, pr
+		if diff < pageRankTol {
+			break
+		}
+	}
+	return pr
+}
+
+// betweenness estimates normalized betweenness centrality with Brandes'
+// algorithm from samples random sources (every node when samples >= n),
+// counting shortest paths by hops. The estimate is scaled up by n/samples.
+func (g *centralityGraph) betweenness(samples int, seed int64) []float64 {
+	n := len(g.ids)
+	bc := make([]float64, n)
+	if n < 3 || samples <= 0 {
+		return bc
+	}
+	sources := make([]int, n)
+	for i := range sources {
+		sources[i] = i
+	}
+	if samples < n {
+		rng := rand.New(rand.NewSource(seed))
+		rng.Shuffle(n, func(i, j int) { sources[i], sources[j] = sources[j], sources[i] })
+		sources = sources[:samples]
+	}
+
+	dist := make([]int, n)
+	sigma := make([]float64, n)
+	delta := make([]float64, n)
+	for i := range dist {
+		dist[i] = -1
+	}
+	order := make([]int, 0, n)
+	for _, s := range sources {
+		order = append(order[:0], s)
+		dist[s], sigma[s] = 0, 1
+		for head := 0; head < len(order); head++ {
+			v := order[head]
+			for _, u := range g.adj[v] {
+				if dist[u] < 0 {
+					dist[u] = dist[v] + 1
+					order = append(order, u)
+				}
+				if dist[u] == dist[v]+1 {
+					sigma[u] += sigma[v]
+				}
+			}
+		}
+		for k := len(order) - 1; k > 0; k-- {
+			v := order[k]
+			for _, u := range g.adj[v] {
+				if dist[u] == dist[v]-1 {
+					delta[u] += sigma[u] / sigma[v] * (1 + delta[v])
+				}
+			}
+			bc[v] += delta[v]
+		}
+		for _, v := range order {
+			dist[v], sigma[v], delta[v] = -1, 0, 0
+		}
+	}
+
+	// Each pair is counted from both ends; normalize by the (n-1)(n-2)/2 pairs
+	// a node can lie between
+	scale := float64(n) / float64(len(sources)) / 2 / (float64(n-1) * float64(n-2) / 2)
+	for i := range bc {
+		bc[i] *= scale
+	}
+	return bc
+}
+
+// kCore returns the core number of each node (Batagelj and Zaversnik): the
+// largest k such that the node belongs to a subgraph where every node has at
+// least k links.
+func (g *centralityGraph) kCore() []int32 {
+	n := len(g.ids)
+	deg := make([]int, n)
+	maxDeg := 0
+	for i, a := range g.adj {
+		deg[i] = len(a)
+		if deg[i] > maxDeg {
+			maxDeg = deg[i]
+		}
+	}
+	// Bucket sort the nodes by degree
+	bin := make([]int, maxDeg+1)
+	for _, d := range deg {
+		bin[d]++
+	}
+	start := 0
+	for d := range bin {
+		bin[d], start = start, start+bin[d]
+	}
+	vert := make([]int, n)
+	pos := make([]int, n)
+	for v, d := range deg {
+		pos[v] = bin[d]
+		vert[pos[v]] = v
+		bin[d]++
+	}
+	for d := maxDeg; d > 0; d-- {
+		bin[d] = bin[d-1]
+	}
+	bin[0] = 0
+	// Peel nodes in order of degree, lowering the degree of their neighbors
+	for i := 0; i < n; i++ {
+		v := vert[i]
+		for _, u := range g.adj[v] {
+			if deg[u] > deg[v] {
+				du, pu := deg[u], pos[u]
+				pw := bin[du]
+				w := vert[pw]
+				if u != w {
+					pos[u], pos[w] = pw, pu
+					vert[pu], vert[pw] = w, u
+				}
+				bin[du]++
+				deg[u]--
+			}
+		}
+	}
+	out := make([]int32, n)
+	for i, d := range deg {
+		out[i] = int32(d)
+	}
+	return out
+}
+
+// computeCentrality computes the centrality of every node.
+func computeCentrality(ids []string, edges []db.GraphEdge, samples int) []db.NodeCentrality {
+	g := newCentralityGraph(ids, edges)
+	strength := g.weightedDegree()
+	pr := g.pageRank(strength)
+	bc := g.betweenness(samples, betweennessSeed)
+	core := g.kCore()
+	out := make([]db.NodeCentrality, len(ids))
+	for i, id := range ids {
+		out[i] = db.NodeCentrality{ID: id, PageRank: pr[i], WeightedDegree: strength[i], Betweenness: bc[i], KCore: core[i]}
+	}
+	return out
+}
+
+// computeAndStoreCentrality computes PageRank, weighted degree, sampled
+// betweenness and k-core number for every graph node.
+func (s *Service) computeAndStoreCentrality(ctx context.Context) error {
+	cs, ok := s.store.(CentralityStore)
+	if !ok {
+		return nil
+	}
+	start := time.Now()
+	ids, err := cs.ListGraphNodeIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("list graph nodes: %w", err)
+	}
+	edges, err := cs.ListGraphEdges(ctx)
+	if err != nil {
+		return fmt.Errorf("list graph links: %w", err)
+	}
+	samples := config.Load().CentralitySamples
+	if samples < 0 {
+		samples = defaultBCSamples
+	}
+	items := computeCentrality(ids, edges, samples)
+	if err := cs.UpdateNodeCentrality(ctx, items); err != nil {
+		return fmt.Errorf("store centrality: %w", err)
+	}
+	log.Printf("✅ Centrality of %d nodes computed in %s (%d betweenness samples)", len(ids), time.Since(start).Round(time.Millisecond), samples)
+	return nil
+}
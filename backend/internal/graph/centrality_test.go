@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func edgeList(pairs ...string) []db.GraphEdge {
+	var out []db.GraphEdge
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out = append(out, db.GraphEdge{Source: pairs[i], Target: pairs[i+1], Weight: 1})
+	}
+	return out
+}
+
+func TestBetweennessPath(t *testing.T) {
+	// a - b - c - d - e: c lies on 4 of the 6 pairs of the other nodes, b on 3
+	g := newCentralityGraph([]string{"a", "b", "c", "d", "e"}, edgeList("a", "b", "b", "c", "c", "d", "d", "e"))
+	bc := g.betweenness(10, 1)
+	want := []float64{0, 0.5, 4.0 / 6, 0.5, 0}
+	for i := range want {
+		if !near(bc[i], want[i]) {
+			t.Errorf("betweenness = %v, want %v", bc, want)
+			break
+		}
+	}
+}
+
+func TestBetweennessSampled(t *testing.T) {
+	// A star: every path between leaves goes through the hub
+	ids := []string{"hub"}
+	var edges []db.GraphEdge
+	for i := 0; i < 50; i++ {
+		leaf := fmt.Sprintf("leaf%d", i)
+		ids = append(ids, leaf)
+		edges = append(edges, db.GraphEdge{Source: "hub", Target: leaf, Weight: 1})
+	}
+	g := newCentralityGraph(ids, edges)
+	bc := g.betweenness(20, 7)
+	if bc[0] < 0.8 || bc[0] > 1.2 {
+		t.Errorf("hub betweenness estimate %v, want about 1", bc[0])
+	}
+	for _, v := range bc[1:] {
+		if v != 0 {
+			t.Fatalf("leaves lie on no shortest path, got %v", v)
+		}
+	}
+	again := g.betweenness(20, 7)
+	if again[0] != bc[0] {
+		t.Errorf("the same seed gave %v and %v", bc[0], again[0])
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	ids := []string{"hub", "a", "b", "c", "alone"}
+	g := newCentralityGraph(ids, []db.GraphEdge{
+		{Source: "hub", Target: "a", Weight: 1},
+		{Source: "a", Target: "hub", Weight: 1}, // the reverse direction is merged
+		{Source: "hub", Target: "b", Weight: 1},
+		{Source: "hub", Target: "c", Weight: 4},
+	})
+	strength := g.weightedDegree()
+	if strength[0] != 6 || strength[1] != 1 || strength[3] != 4 || strength[4] != 0 {
+		t.Errorf("weighted degree = %v", strength)
+	}
+	pr := g.pageRank(strength)
+	sum := 0.0
+	for _, v := range pr {
+		sum += v
+	}
+	if !near(sum, 1) {
+		t.Errorf("ranks sum to %v", sum)
+	}
+	if !(pr[0] > pr[3] && pr[3] > pr[1] && near(pr[1], pr[2]) && pr[4] < pr[1]) {
+		t.Errorf("unexpected ranks %v", pr)
+	}
+}
+
+func TestKCore(t *testing.T) {
+	// A 4-clique with a triangle hanging off it and a pendant node
+	edges := edgeList(
+		"a", "b", "a", "c", "a", "d", "b", "c", "b", "d", "c", "d",
+		"d", "e", "e", "f", "f", "d",
+		"f", "g",
+	)
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	core := newCentralityGraph(ids, edges).kCore()
+	want := []int32{3, 3, 3, 3, 2, 2, 1, 0}
+	for i := range want {
+		if core[i] != want[i] {
+			t.Fatalf("core numbers = %v, want %v", core, want)
+		}
+	}
+}
+
+// centralityFakeStore serves a small graph and records the stored centrality.
+type centralityFakeStore struct {
+	*fakeStore
+	items []db.NodeCentrality
+}
+
+func (f *centralityFakeStore) ListGraphNodeIDs(ctx context.Context) ([]string, error) {
+	return []string{"a", "b", "c"}, nil
+}
+
+func (f *centralityFakeStore) ListGraphEdges(ctx context.Context) ([]db.GraphEdge, error) {
+	return edgeList("a", "b", "b", "c", "x", "a"), nil
+}
+
+func (f *centralityFakeStore) UpdateNodeCentrality(ctx context.Context, items []db.NodeCentrality) error {
+	f.items = items
+	return nil
+}
+
+func TestComputeAndStoreCentrality(t *testing.T) {
+	t.Setenv("CENTRALITY_BETWEENNESS_SAMPLES", "")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	fs := &centralityFakeStore{fakeStore: newFakeStore()}
+	if err := NewService(fs).computeAndStoreCentrality(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.items) != 3 {
+		t.Fatalf("expected every node, got %+v", fs.items)
+	}
+	b := fs.items[1]
+	if b.ID != "b" || b.WeightedDegree != 2 || b.KCore != 1 || !near(b.Betweenness, 1) || b.PageRank <= fs.items[0].PageRank {
+		t.Errorf("unexpected centrality of the middle node %+v", b)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"math"
 	"math/rand"
 	"sort"
+	"strconv"
 
+	"github.com/onnwee/reddit-cluster-map/backend/internal/admin"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
 )
 
@@ -24,6 +27,74 @@ type CommunityResult struct {
 	Communities     []Community
 	NodeToCommunity map[string]int
 	Modularity      float64
+	// Quality at Params.Resolution: modularity with resolution γ, which Leiden
+	// optimizes, and the Constant Potts Model
+	QualityAtResolution float64
+	CPM                 float64
+	Params              CommunityParams
+}
+
+// Community detection algorithms.
+const (
+	AlgorithmLouvain = "louvain"
+	AlgorithmLeiden  = "leiden"
+)
+
+// Admin settings of community detection, stored in service_settings.
+const (
+	SettingCommunityAlgorithm  = "community_algorithm"
+	SettingCommunityResolution = "community_resolution"
+	SettingCommunitySeed       = "community_seed"
+)
+
+// CommunityParams selects the algorithm of flat community detection. Resolution
+// and Seed only apply to Leiden; Louvain runs at resolution 1, unseeded.
+type CommunityParams struct {
+	Algorithm  string
+	Resolution float64 // higher values give more, smaller communities
+	Seed       int64
+}
+
+// defaultCommunityParams returns the parameters set by the environment.
+func defaultCommunityParams(cfg *config.Config) CommunityParams {
+	p := CommunityParams{Algorithm: cfg.CommunityAlgorithm, Resolution: cfg.CommunityResolution, Seed: cfg.CommunitySeed}
+	if p.Algorithm != AlgorithmLeiden {
+		p.Algorithm = AlgorithmLouvain
+	}
+	if p.Resolution <= 0 {
+		p.Resolution = 1
+	}
+	return p
+}
+
+// LoadCommunityParams returns the environment parameters overridden by the
+// settings stored through the admin API. Stored values that cannot be used are
+// ignored.
+func LoadCommunityParams(ctx context.Context, q *db.Queries) CommunityParams {
+	p := defaultCommunityParams(config.Load())
+	if v, _ := admin.Get(ctx, q, SettingCommunityAlgorithm); v == AlgorithmLouvain || v == AlgorithmLeiden {
+		p.Algorithm = v
+	}
+	if v, _ := admin.Get(ctx, q, SettingCommunityResolution); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			p.Resolution = f
+		}
+	}
+	if v, _ := admin.Get(ctx, q, SettingCommunitySeed); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.Seed = n
+		}
+	}
+	return p
+}
+
+// detectCommunitiesWithParams runs flat community detection with the selected
+// algorithm.
+func (s *Service) detectCommunitiesWithParams(nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow, p CommunityParams) (*CommunityResult, error) {
+	if p.Algorithm == AlgorithmLeiden {
+		return s.detectCommunitiesLeiden(nodes, links, p)
+	}
+	return s.detectCommunitiesFromData(nodes, links)
 }
 
 // HierarchyLevel represents one level in the community hierarchy
@@ -35,11 +106,10 @@ type HierarchyLevel struct {
 	Modularity         float64
 }
 
-// detectCommunities performs Louvain community detection on the graph
+// detectCommunities performs community detection on the graph with the algorithm
+// selected in the admin settings
 // Returns the community detection result along with the fetched nodes and links
 func (s *Service) detectCommunities(ctx context.Context, queries *db.Queries) (*CommunityResult, []db.ListGraphNodesByWeightRow, []db.ListGraphLinksAmongRow, error) {
-	log.Printf("🔍 Starting community detection (Louvain algorithm)")
-
 	// Fetch all nodes and links
 	nodes, err := queries.ListGraphNodesByWeight(ctx, 50000) // Cap at 50k nodes for performance
 	if err != nil {
@@ -60,7 +130,7 @@ func (s *Service) detectCommunities(ctx context.Context, queries *db.Queries) (*
 		return nil, nil, nil, fmt.Errorf("fetch links: %w", err)
 	}
 
-	result, err := s.detectCommunitiesFromData(nodes, links)
+	result, err := s.detectCommunitiesWithParams(nodes, links, LoadCommunityParams(ctx, queries))
 	return result, nodes, links, err
 }
 
@@ -69,6 +139,7 @@ func (s *Service) detectCommunitiesFromData(nodes []db.ListGraphNodesByWeightRow
 	if len(nodes) == 0 {
 		return &CommunityResult{Communities: []Community{}, NodeToCommunity: map[string]int{}, Modularity: 0}, nil
 	}
+	log.Printf("🔍 Starting community detection (Louvain algorithm)")
 
 	nodeIDs := make([]string, len(nodes))
 	for i, n := range nodes {
@@ -163,6 +234,14 @@ func (s *Service) detectCommunitiesFromData(nodes []db.ListGraphNodesByWeightRow
 		log.Printf("⏱ Iteration %d: improved=%v", iteration, improved)
 	}
 
+	result := buildCommunityResult(nodes, links, nodeToCommunity, adjacency, degrees, totalWeight, CommunityParams{Algorithm: AlgorithmLouvain, Resolution: 1})
+	log.Printf("✅ Community detection complete: %d communities, modularity=%.3f", len(result.Communities), result.Modularity)
+	return result, nil
+}
+
+// buildCommunityResult numbers the communities of a partition sequentially,
+// labels each after its best connected member and scores the partition.
+func buildCommunityResult(nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow, nodeToCommunity map[string]int, adjacency map[string]map[string]int, degrees map[string]int, totalWeight int, params CommunityParams) *CommunityResult {
 	// Renumber communities to be sequential
 	uniqueCommunities := make(map[int]bool)
 	for _, comm := range nodeToCommunity {
@@ -236,16 +315,14 @@ func (s *Service) detectCommunitiesFromData(nodes []db.ListGraphNodesByWeightRow
 		return len(communities[i].Members) > len(communities[j].Members)
 	})
 
-	// Calculate final modularity
-	modularity := calculateModularity(finalNodeToCommunity, adjacency, degrees, totalWeight)
-
-	log.Printf("✅ Community detection complete: %d communities, modularity=%.3f", len(communities), modularity)
-
 	return &CommunityResult{
-		Communities:     communities,
-		NodeToCommunity: finalNodeToCommunity,
-		Modularity:      modularity,
-	}, nil
+		Communities:         communities,
+		NodeToCommunity:     finalNodeToCommunity,
+		Modularity:          calculateModularity(finalNodeToCommunity, adjacency, degrees, totalWeight),
+		QualityAtResolution: calculateModularityAt(finalNodeToCommunity, adjacency, degrees, totalWeight, params.Resolution),
+		CPM:                 calculateCPM(finalNodeToCommunity, adjacency, totalWeight, params.Resolution),
+		Params:              params,
+	}
 }
 
 // modularityGain calculates the gain in modularity from moving a node between communities
@@ -317,12 +394,79 @@ func calculateModularity(nodeToCommunity map[string]int, adjacency map[string]ma
 	return modularity / m2
 }
 
+// calculateModularityAt calculates the quality Leiden optimizes, modularity at a
+// resolution γ: Σ_c [w_c/m - γ (K_c/2m)²], with w_c the links inside community c
+// and K_c the sum of its degrees. Unlike calculateModularity it also counts the
+// expected links between members that are not linked.
+func calculateModularityAt(nodeToCommunity map[string]int, adjacency map[string]map[string]int, degrees map[string]int, totalWeight int, resolution float64) float64 {
+	if totalWeight == 0 {
+		return 0
+	}
+	inside := make(map[int]int)
+	strength := make(map[int]int)
+	for node, comm := range nodeToCommunity {
+		strength[comm] += degrees[node]
+		for neighbor, weight := range adjacency[node] {
+			if nodeToCommunity[neighbor] == comm {
+				inside[comm] += weight
+			}
+		}
+	}
+	m := float64(totalWeight)
+	quality := 0.0
+	for comm, k := range strength {
+		share := float64(k) / (2 * m)
+		quality += float64(inside[comm])/(2*m) - resolution*share*share
+	}
+	return quality
+}
+
+// calculateCPM calculates the Constant Potts Model quality of a partition per
+// link: Σ_c [w_c - γ n_c(n_c-1)/2] / m, with w_c the links inside community c of
+// n_c nodes. Unlike modularity it compares communities to a fixed density γ.
+func calculateCPM(nodeToCommunity map[string]int, adjacency map[string]map[string]int, totalWeight int, resolution float64) float64 {
+	if totalWeight == 0 {
+		return 0
+	}
+	inside := make(map[int]int)
+	sizes := make(map[int]int)
+	for node, comm := range nodeToCommunity {
+		sizes[comm]++
+		for neighbor, weight := range adjacency[node] {
+			if nodeToCommunity[neighbor] == comm {
+				inside[comm] += weight
+			}
+		}
+	}
+	cpm := 0.0
+	for comm, n := range sizes {
+		cpm += float64(inside[comm])/2 - resolution*float64(n)*float64(n-1)/2
+	}
+	return cpm / float64(totalWeight)
+}
+
 // storeCommunities stores the detected communities in the database
 // Accepts nodes and links fetched during detection to avoid redundant database queries
 // Returns the mapping from node ID to database community ID for use in bundle computation
 func (s *Service) storeCommunities(ctx context.Context, queries *db.Queries, result *CommunityResult, nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow) (map[string]int32, error) {
 	log.Printf("💾 Storing community detection results")
 
+	// Match against the previous run so that continuing communities keep their IDs
+	prev, err := queries.ListCommunityMemberships(ctx)
+	if err != nil {
+		log.Printf("⚠️ failed to load previous communities, all communities get new IDs: %v", err)
+		prev = nil
+	}
+	next := make([][]string, len(result.Communities))
+	for i, comm := range result.Communities {
+		next[i] = comm.Members
+	}
+	threshold := config.Load().CommunityMatchThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.3
+	}
+	lineage := matchCommunities(prev, next, threshold)
+
 	// Clear existing communities
 	if err := queries.ClearCommunityTables(ctx); err != nil {
 		return nil, fmt.Errorf("clear community tables: %w", err)
@@ -330,16 +474,24 @@ func (s *Service) storeCommunities(ctx context.Context, queries *db.Queries, res
 
 	// Insert communities and build mapping from member IDs to database community IDs
 	nodeToDB := make(map[string]int32)
-	for _, comm := range result.Communities {
-		dbComm, err := queries.CreateCommunity(ctx, db.CreateCommunityParams{
+	ids := make([]int32, len(result.Communities))
+	for i, comm := range result.Communities {
+		params := db.CreateCommunityParams{
 			Label:      comm.Label,
 			Size:       int32(len(comm.Members)),
 			Modularity: sql.NullFloat64{Float64: result.Modularity, Valid: true},
-		})
+		}
+		var dbComm db.GraphCommunity
+		if id := lineage.IDs[i]; id != 0 {
+			dbComm, err = queries.CreateCommunityWithID(ctx, id, params)
+		} else {
+			dbComm, err = queries.CreateCommunity(ctx, params)
+		}
 		if err != nil {
 			log.Printf("⚠️ failed to create community %d: %v", comm.ID, err)
 			continue
 		}
+		ids[i] = dbComm.ID
 
 		// Insert members and map them to the database community ID
 		for _, memberID := range comm.Members {
@@ -385,6 +537,27 @@ func (s *Service) storeCommunities(ctx context.Context, queries *db.Queries, res
 		}
 	}
 
+	p := result.Params
+	run := db.CommunityRun{
+		Algorithm:   p.Algorithm,
+		Resolution:  p.Resolution,
+		Modularity:  result.Modularity,
+		Quality:     result.QualityAtResolution,
+		CPM:         result.CPM,
+		Communities: int32(len(result.Communities)),
+		Nodes:       int32(len(result.NodeToCommunity)),
+	}
+	if p.Algorithm == AlgorithmLeiden {
+		run.Seed = sql.NullInt64{Int64: p.Seed, Valid: true}
+	}
+	if run, err = queries.CreateCommunityRun(ctx, run); err != nil {
+		log.Printf("⚠️ failed to record community run: %v", err)
+	} else if err := queries.SetCommunitiesRun(ctx, run.ID); err != nil {
+		log.Printf("⚠️ failed to link communities to run %d: %v", run.ID, err)
+	} else if err := queries.CreateCommunityEvents(ctx, run.ID, lineage.dbEvents(ids)); err != nil {
+		log.Printf("⚠️ failed to record community lineage: %v", err)
+	}
+
 	log.Printf("✅ Stored %d communities with inter-community links", len(result.Communities))
 	return nodeToDB, nil
 }
@@ -516,10 +689,12 @@ func (s *Service) computeAndStoreEdgeBundles(ctx context.Context, queries *db.Qu
 	return nil
 }
 
-// detectHierarchicalCommunities performs multi-level Louvain community detection
+// detectHierarchicalCommunities performs multi-level community detection with the
+// algorithm of p: each level is one Louvain or Leiden level over the communities
+// of the previous one.
 // Returns a hierarchy of levels where level 0 is the original nodes
-func (s *Service) detectHierarchicalCommunities(ctx context.Context, queries *db.Queries, nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow) ([]HierarchyLevel, error) {
-	log.Printf("🔍 Starting hierarchical community detection (multi-level Louvain)")
+func (s *Service) detectHierarchicalCommunities(ctx context.Context, queries *db.Queries, nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow, p CommunityParams) ([]HierarchyLevel, error) {
+	log.Printf("🔍 Starting hierarchical community detection (multi-level %s)", p.Algorithm)
 
 	if len(nodes) == 0 {
 		log.Printf("ℹ️ No nodes found for hierarchical community detection")
@@ -583,11 +758,19 @@ func (s *Service) detectHierarchicalCommunities(ctx context.Context, queries *db
 	currentTotalWeight := totalWeight
 	maxLevels := 4 // Target 3-4 levels (0 is original, 1-3 are hierarchy)
 
+	// One generator for all levels, so the seed alone decides a Leiden hierarchy
+	rng := rand.New(rand.NewSource(p.Seed))
+
 	for level := 1; level < maxLevels; level++ {
 		log.Printf("🔄 Computing hierarchy level %d", level)
 
-		// Run single-pass Louvain on current level
-		metaNodeToCommunity := runSinglePassLouvain(currentNodeIDs, currentAdjacency, currentDegrees, currentTotalWeight)
+		// Run a single level of the selected algorithm on the current graph
+		var metaNodeToCommunity map[string]int
+		if p.Algorithm == AlgorithmLeiden {
+			metaNodeToCommunity = runSingleLevelLeiden(currentNodeIDs, currentAdjacency, p.Resolution, rng)
+		} else {
+			metaNodeToCommunity = runSinglePassLouvain(currentNodeIDs, currentAdjacency, currentDegrees, currentTotalWeight)
+		}
 
 		// Check if we got any clustering (more than 1 community and less than total nodes)
 		uniqueCommunities := make(map[int]bool)
@@ -712,6 +895,14 @@ func (s *Service) detectHierarchicalCommunities(ctx context.Context, queries *db
 			}
 		}
 
+		// Meta-node order follows the community numbers, not map order
+		sort.Slice(metaNodeIDs, func(i, j int) bool {
+			var a, b int
+			fmt.Sscanf(metaNodeIDs[i], "meta_%d", &a)
+			fmt.Sscanf(metaNodeIDs[j], "meta_%d", &b)
+			return a < b
+		})
+
 		// If meta-graph is too small, stop
 		if len(metaNodeIDs) < 3 {
 			log.Printf("ℹ️ Meta-graph too small (%d communities), stopping hierarchy", len(metaNodeIDs))
@@ -729,6 +920,17 @@ func (s *Service) detectHierarchicalCommunities(ctx context.Context, queries *db
 	return hierarchy, nil
 }
 
+// runSingleLevelLeiden performs one level of the Leiden algorithm on the given
+// graph, whose nodes are visited in the order of nodeIDs.
+func runSingleLevelLeiden(nodeIDs []string, adjacency map[string]map[string]int, resolution float64, rng *rand.Rand) map[string]int {
+	membership := leidenLevel(leidenGraphOf(nodeIDs, adjacency), resolution, rng)
+	nodeToCommunity := make(map[string]int, len(nodeIDs))
+	for i, id := range nodeIDs {
+		nodeToCommunity[id] = membership[i]
+	}
+	return nodeToCommunity
+}
+
 // runSinglePassLouvain performs one pass of Louvain algorithm on the given graph
 func runSinglePassLouvain(nodeIDs []string, adjacency map[string]map[string]int, degrees map[string]int, totalWeight int) map[string]int {
 	// Initialize each node to its own community
@@ -39,7 +39,7 @@ func TestHierarchicalCommunityDetection(t *testing.T) {
 	svc := NewService(fs)
 	queries := &db.Queries{} // Will not be used in test, but needed for signature
 
-	hierarchy, err := svc.detectHierarchicalCommunities(context.Background(), queries, nodes, links)
+	hierarchy, err := svc.detectHierarchicalCommunities(context.Background(), queries, nodes, links, CommunityParams{Algorithm: AlgorithmLouvain})
 	if err != nil {
 		t.Fatalf("hierarchical detection failed: %v", err)
 	}
@@ -105,7 +105,7 @@ func TestHierarchyValidation(t *testing.T) {
 	svc := NewService(fs)
 	queries := &db.Queries{}
 
-	hierarchy, err := svc.detectHierarchicalCommunities(context.Background(), queries, nodes, links)
+	hierarchy, err := svc.detectHierarchicalCommunities(context.Background(), queries, nodes, links, CommunityParams{Algorithm: AlgorithmLouvain})
 	if err != nil {
 		t.Fatalf("hierarchical detection failed: %v", err)
 	}
@@ -263,11 +263,11 @@ func TestIntegration_LayoutComputation_ConfigRespect(t *testing.T) {
 	}
 
 	// Add some links between nodes
-	_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target) VALUES ($1, $2) ON CONFLICT DO NOTHING", testNodeIDs[0], testNodeIDs[1])
+	_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target, link_type) VALUES ($1, $2, 'reply') ON CONFLICT DO NOTHING", testNodeIDs[0], testNodeIDs[1])
 	if err != nil {
 		t.Fatalf("failed to insert test link: %v", err)
 	}
-	_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target) VALUES ($1, $2) ON CONFLICT DO NOTHING", testNodeIDs[1], testNodeIDs[2])
+	_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target, link_type) VALUES ($1, $2, 'reply') ON CONFLICT DO NOTHING", testNodeIDs[1], testNodeIDs[2])
 	if err != nil {
 		t.Fatalf("failed to insert test link: %v", err)
 	}
@@ -358,12 +358,12 @@ func TestIntegration_HierarchicalCommunityDetection(t *testing.T) {
 	for _, link := range links {
 		src := fmt.Sprintf("hier_test_%d", link[0])
 		tgt := fmt.Sprintf("hier_test_%d", link[1])
-		_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target) VALUES ($1, $2) ON CONFLICT DO NOTHING", src, tgt)
+		_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target, link_type) VALUES ($1, $2, 'reply') ON CONFLICT DO NOTHING", src, tgt)
 		if err != nil {
 			t.Fatalf("failed to insert test link: %v", err)
 		}
 		// Add reverse link for undirected graph
-		_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target) VALUES ($1, $2) ON CONFLICT DO NOTHING", tgt, src)
+		_, err = conn.ExecContext(ctx, "INSERT INTO graph_links (source, target, link_type) VALUES ($1, $2, 'reply') ON CONFLICT DO NOTHING", tgt, src)
 		if err != nil {
 			t.Fatalf("failed to insert reverse link: %v", err)
 		}
@@ -412,7 +412,7 @@ func TestIntegration_HierarchicalCommunityDetection(t *testing.T) {
 	t.Logf("Running hierarchical detection on %d nodes, %d links", len(testNodes), len(testLinks))
 
 	// Run hierarchical detection
-	hierarchy, err := svc.detectHierarchicalCommunities(ctx, q, testNodes, testLinks)
+	hierarchy, err := svc.detectHierarchicalCommunities(ctx, q, testNodes, testLinks, CommunityParams{Algorithm: AlgorithmLouvain})
 	if err != nil {
 		t.Fatalf("hierarchical detection failed: %v", err)
 	}
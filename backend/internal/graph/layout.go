@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// layoutParams configures forceLayout3D.
+type layoutParams struct {
+	Iterations int
+	Theta      float64 // Barnes-Hut opening angle
+	Seed       int64   // seeds the initial positions
+	Workers    int     // goroutines for the repulsion; GOMAXPROCS when <= 0
+}
+
+// layoutEdge is an undirected edge between node indexes.
+type layoutEdge struct{ a, b int }
+
+// sortLayoutEdges puts edges in a canonical order, a < b and sorted by (a, b).
+// The attraction is summed in edge order, so the order must not depend on
+// how the links were read for a seed to reproduce a layout.
+func sortLayoutEdges(edges []layoutEdge) {
+	for i, e := range edges {
+		if e.a > e.b {
+			edges[i] = layoutEdge{e.b, e.a}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].a != edges[j].a {
+			return edges[i].a < edges[j].a
+		}
+		return edges[i].b < edges[j].b
+	})
+}
+
+// forceLayout3D places n nodes in 3D with Fruchterman-Reingold dynamics:
+// edges pull their ends together and every node repels every other, through
+// the octree. Nodes start scattered in a ball drawn from the seed, and
+// nothing else is random, so a seed always gives the same layout.
+func forceLayout3D(n int, edges []layoutEdge, p layoutParams) (X, Y, Z []float64) {
+	X = make([]float64, n)
+	Y = make([]float64, n)
+	Z = make([]float64, n)
+	R := 200.0 * math.Sqrt(float64(n)/1000.0+1)
+	rng := rand.New(rand.NewSource(p.Seed))
+	for i := 0; i < n; i++ {
+		// A uniform point in the ball of radius R
+		for {
+			x, y, z := 2*rng.Float64()-1, 2*rng.Float64()-1, 2*rng.Float64()-1
+			if x*x+y*y+z*z <= 1 {
+				X[i], Y[i], Z[i] = R*x, R*y, R*z
+				break
+			}
+		}
+	}
+	if n == 0 || p.Iterations <= 0 {
+		return X, Y, Z
+	}
+
+	// The 3D analogue of FR's k = sqrt(area/n)
+	k := math.Cbrt(R * R * R / float64(n))
+	repStrength := k * k
+	cool := R / float64(p.Iterations)
+	dispX := make([]float64, n)
+	dispY := make([]float64, n)
+	dispZ := make([]float64, n)
+	for it := 0; it < p.Iterations; it++ {
+		// O(n log n) repulsion, written straight into the displacements
+		calculateOctreeForces(X, Y, Z, dispX, dispY, dispZ, p.Theta, repStrength, p.Workers)
+
+		// Attraction along edges (O(E))
+		for _, e := range edges {
+			dx, dy, dz := X[e.a]-X[e.b], Y[e.a]-Y[e.b], Z[e.a]-Z[e.b]
+			dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
+			if dist < 1e-6 {
+				dx, dy, dz = jitter3(int32(e.a), int32(e.b))
+				dist = 1
+			}
+			f := dist / k // (dist²/k) / dist
+			dispX[e.a] -= dx * f
+			dispY[e.a] -= dy * f
+			dispZ[e.a] -= dz * f
+			dispX[e.b] += dx * f
+			dispY[e.b] += dy * f
+			dispZ[e.b] += dz * f
+		}
+
+		// Limit the displacement to the temperature
+		temp := R - float64(it)*cool
+		for v := 0; v < n; v++ {
+			disp := math.Sqrt(dispX[v]*dispX[v] + dispY[v]*dispY[v] + dispZ[v]*dispZ[v])
+			if disp > 0 {
+				s := math.Min(disp, temp) / disp
+				X[v] = clampCoord(X[v] + dispX[v]*s)
+				Y[v] = clampCoord(Y[v] + dispY[v]*s)
+				Z[v] = clampCoord(Z[v] + dispZ[v]*s)
+			}
+		}
+	}
+	return X, Y, Z
+}
+
+// clampCoord prevents blow-up.
+func clampCoord(x float64) float64 {
+	return math.Max(-1e6, math.Min(1e6, x))
+}
@@ -3,6 +3,7 @@ package graph
 import (
 	"fmt"
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -161,3 +162,89 @@ func BenchmarkQuadtreeConstruction(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkOctreeVsBruteForce compares one octree repulsion pass against the
+// exact O(n²) sum
+func BenchmarkOctreeVsBruteForce(b *testing.B) {
+	sizes := []int{100, 500, 1000, 2000, 5000}
+
+	for _, N := range sizes {
+		X, Y, Z := randomPoints3D(N, 1)
+		dispX := make([]float64, N)
+		dispY := make([]float64, N)
+		dispZ := make([]float64, N)
+		repStrength := 10000.0
+
+		b.Run(fmt.Sprintf("Octree_N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateOctreeForces(X, Y, Z, dispX, dispY, dispZ, 0.8, repStrength, 1)
+			}
+		})
+
+		b.Run(fmt.Sprintf("BruteForce_N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bruteForceRepulsion3D(X, Y, Z, repStrength)
+			}
+		})
+	}
+}
+
+// BenchmarkOctreeConstruction benchmarks just the tree building
+func BenchmarkOctreeConstruction(b *testing.B) {
+	sizes := []int{100, 500, 1000, 5000, 10000, 100000}
+
+	for _, N := range sizes {
+		X, Y, Z := randomPoints3D(N, 1)
+
+		b.Run(fmt.Sprintf("N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				buildOctree(X, Y, Z)
+			}
+		})
+	}
+}
+
+// BenchmarkOctreeVsQuadtree compares one repulsion pass of the parallel 3D
+// octree with the 2D quadtree it replaced, on graphs of 100k+ nodes. The
+// quadtree sees the same points projected onto the XY plane.
+func BenchmarkOctreeVsQuadtree(b *testing.B) {
+	for _, N := range []int{100000, 250000} {
+		X, Y, Z := randomPoints3D(N, 1)
+		dispX := make([]float64, N)
+		dispY := make([]float64, N)
+		dispZ := make([]float64, N)
+		repStrength := 10000.0
+
+		b.Run(fmt.Sprintf("Quadtree2D_N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateBarnesHutForces(X, Y, dispX, dispY, 0.8, repStrength)
+			}
+		})
+		b.Run(fmt.Sprintf("Octree3D_Workers=1_N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateOctreeForces(X, Y, Z, dispX, dispY, dispZ, 0.8, repStrength, 1)
+			}
+		})
+		b.Run(fmt.Sprintf("Octree3D_N=%d", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateOctreeForces(X, Y, Z, dispX, dispY, dispZ, 0.8, repStrength, 0)
+			}
+		})
+	}
+}
+
+// BenchmarkForceLayout3D runs whole layouts of a sparse random graph.
+func BenchmarkForceLayout3D(b *testing.B) {
+	for _, N := range []int{10000, 100000} {
+		rng := rand.New(rand.NewSource(1))
+		edges := make([]layoutEdge, 0, 2*N)
+		for i := 1; i < N; i++ {
+			edges = append(edges, layoutEdge{i, rng.Intn(i)}, layoutEdge{i, rng.Intn(N)})
+		}
+		b.Run(fmt.Sprintf("N=%d_Iter=10", N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				forceLayout3D(N, edges, layoutParams{Iterations: 10, Theta: 0.8, Seed: 1})
+			}
+		})
+	}
+}
@@ -0,0 +1,496 @@
+package graph
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// leidenGraph is an undirected weighted graph on nodes 0..n-1. Aggregated graphs
+// keep intra-community weight as self-loops.
+type leidenGraph struct {
+	adj      [][]leidenEdge
+	selfLoop []float64 // weight of each node's self-loop
+	strength []float64 // weighted degree, self-loops counted twice
+	size     []int     // original nodes represented by each node
+	total    float64   // sum of strengths, 2m
+}
+
+type leidenEdge struct {
+	to     int
+	weight float64
+}
+
+func newLeidenGraph(n int) *leidenGraph {
+	g := &leidenGraph{
+		adj:      make([][]leidenEdge, n),
+		selfLoop: make([]float64, n),
+		strength: make([]float64, n),
+		size:     make([]int, n),
+	}
+	for i := range g.size {
+		g.size[i] = 1
+	}
+	return g
+}
+
+// addEdge adds an undirected edge; parallel edges are expected to be merged by
+// the caller.
+func (g *leidenGraph) addEdge(a, b int, w float64) {
+	if a == b {
+		g.selfLoop[a] += w
+		g.strength[a] += 2 * w
+		g.total += 2 * w
+		return
+	}
+	g.adj[a] = append(g.adj[a], leidenEdge{to: b, weight: w})
+	g.adj[b] = append(g.adj[b], leidenEdge{to: a, weight: w})
+	g.strength[a] += w
+	g.strength[b] += w
+	g.total += 2 * w
+}
+
+func (g *leidenGraph) n() int { return len(g.adj) }
+
+// leidenRandomness is θ of the refinement phase: how strongly a node prefers the
+// subcommunity with the best gain over the others it may join.
+const leidenRandomness = 0.01
+
+// runLeiden partitions g with the Leiden algorithm (Traag, Waltman & van Eck
+// 2019), maximizing modularity with the given resolution. Unlike Louvain, each
+// aggregation step merges the refined partition, whose subcommunities are
+// guaranteed to be connected, so no community ends up disconnected. The result
+// only depends on g, resolution and seed.
+func runLeiden(g *leidenGraph, resolution float64, seed int64, iterations int) []int {
+	rng := rand.New(rand.NewSource(seed))
+	membership := make([]int, g.n())
+	for i := range membership {
+		membership[i] = i
+	}
+	if g.total == 0 {
+		return membership
+	}
+	best := modularityOf(g, membership, resolution)
+	for it := 0; it < iterations; it++ {
+		next := leidenIteration(g, membership, resolution, rng)
+		q := modularityOf(g, next, resolution)
+		if q <= best+1e-12 {
+			break
+		}
+		membership, best = next, q
+	}
+	return renumber(membership)
+}
+
+// leidenIteration runs one full Leiden pass from an initial partition of g:
+// local moving, refinement and aggregation until the partition is stable.
+func leidenIteration(g0 *leidenGraph, initial []int, resolution float64, rng *rand.Rand) []int {
+	// node of the current graph that each original node belongs to
+	toNode := make([]int, g0.n())
+	for i := range toNode {
+		toNode[i] = i
+	}
+	g := g0
+	part := append([]int(nil), initial...)
+	for level := 0; level < 32; level++ {
+		part = moveNodesFast(g, part, resolution, rng)
+		parts := renumber(part)
+		if maxOf(parts)+1 == g.n() {
+			part = parts
+			break
+		}
+		refined := refinePartition(g, parts, resolution, rng)
+		nRefined := maxOf(refined) + 1
+		if nRefined == g.n() {
+			// Refinement merged nothing; aggregation would not progress
+			part = parts
+			break
+		}
+		agg := aggregate(g, refined, nRefined)
+		aggPart := make([]int, nRefined)
+		for v, r := range refined {
+			aggPart[r] = parts[v]
+		}
+		for i, v := range toNode {
+			toNode[i] = refined[v]
+		}
+		g, part = agg, aggPart
+	}
+	out := make([]int, g0.n())
+	for i, v := range toNode {
+		out[i] = part[v]
+	}
+	return out
+}
+
+// leidenLevel runs one level of the Leiden algorithm on g from singletons: local
+// moving, then refinement, whose subcommunities are connected. It returns the
+// refined partition numbered 0..k-1, or the moved partition when refinement
+// merges nothing. Hierarchical detection stacks levels by aggregating each
+// result, as leidenIteration does.
+func leidenLevel(g *leidenGraph, resolution float64, rng *rand.Rand) []int {
+	part := make([]int, g.n())
+	for i := range part {
+		part[i] = i
+	}
+	if g.total == 0 {
+		return part
+	}
+	parts := renumber(moveNodesFast(g, part, resolution, rng))
+	refined := refinePartition(g, parts, resolution, rng)
+	if maxOf(refined)+1 == g.n() {
+		return parts
+	}
+	return refined
+}
+
+// moveNodesFast is the local moving phase: nodes are visited from a queue and
+// moved to the neighbouring community with the best modularity gain, and the
+// neighbours of moved nodes are queued again.
+func moveNodesFast(g *leidenGraph, part []int, resolution float64, rng *rand.Rand) []int {
+	n := g.n()
+	part = append([]int(nil), part...)
+	commStrength := make([]float64, n)
+	commCount := make([]int, n)
+	for v, c := range part {
+		commStrength[c] += g.strength[v]
+		commCount[c]++
+	}
+	var empty []int
+	for c := n - 1; c >= 0; c-- {
+		if commCount[c] == 0 {
+			empty = append(empty, c)
+		}
+	}
+
+	queue := rng.Perm(n)
+	queued := make([]bool, n)
+	for _, v := range queue {
+		queued[v] = true
+	}
+	weightTo := make([]float64, n)
+	var touched []int
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		queued[v] = false
+
+		cur := part[v]
+		for _, e := range g.adj[v] {
+			c := part[e.to]
+			if weightTo[c] == 0 {
+				touched = append(touched, c)
+			}
+			weightTo[c] += e.weight
+		}
+		k := g.strength[v]
+		commStrength[cur] -= k
+		commCount[cur]--
+
+		bestComm, bestGain := cur, weightTo[cur]-resolution*k*commStrength[cur]/g.total
+		for _, c := range touched {
+			if gain := weightTo[c] - resolution*k*commStrength[c]/g.total; gain > bestGain {
+				bestComm, bestGain = c, gain
+			}
+		}
+		// An empty community gains 0
+		if bestGain < 0 && commCount[cur] > 0 {
+			if len(empty) > 0 {
+				bestComm = empty[len(empty)-1]
+				empty = empty[:len(empty)-1]
+			}
+		}
+		for _, c := range touched {
+			weightTo[c] = 0
+		}
+		touched = touched[:0]
+
+		commStrength[bestComm] += k
+		commCount[bestComm]++
+		if bestComm == cur {
+			continue
+		}
+		if commCount[cur] == 0 {
+			empty = append(empty, cur)
+		}
+		part[v] = bestComm
+		for _, e := range g.adj[v] {
+			if part[e.to] != bestComm && !queued[e.to] {
+				queued[e.to] = true
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return part
+}
+
+// refinePartition splits each community of part into well-connected
+// subcommunities. Every node starts alone; a node still alone may merge into a
+// subcommunity of its community that is well connected to the rest of it,
+// chosen at random with a preference for higher gains.
+func refinePartition(g *leidenGraph, part []int, resolution float64, rng *rand.Rand) []int {
+	n := g.n()
+	refined := make([]int, n)
+	for i := range refined {
+		refined[i] = i
+	}
+	commStrength := make([]float64, n)
+	for v, c := range part {
+		commStrength[c] += g.strength[v]
+	}
+	// Subcommunity strength and weight to the rest of their community
+	subStrength := append([]float64(nil), g.strength...)
+	subExternal := make([]float64, n)
+	subCount := make([]int, n)
+	for v := 0; v < n; v++ {
+		subCount[v] = 1
+		for _, e := range g.adj[v] {
+			if part[e.to] == part[v] {
+				subExternal[v] += e.weight
+			}
+		}
+	}
+	wellConnected := func(external, strength float64, comm int) bool {
+		return external >= resolution*strength*(commStrength[comm]-strength)/g.total
+	}
+
+	weightTo := make([]float64, n)
+	var touched, candidates []int
+	var gains []float64
+	for _, v := range rng.Perm(n) {
+		c := part[v]
+		if subCount[refined[v]] != 1 || !wellConnected(subExternal[v], g.strength[v], c) {
+			continue
+		}
+		for _, e := range g.adj[v] {
+			if part[e.to] != c {
+				continue
+			}
+			r := refined[e.to]
+			if weightTo[r] == 0 {
+				touched = append(touched, r)
+			}
+			weightTo[r] += e.weight
+		}
+
+		// Staying alone is a candidate too, with gain 0
+		k := g.strength[v]
+		candidates := append(candidates[:0], refined[v])
+		gains = append(gains[:0], 0)
+		maxGain := 0.0
+		for _, r := range touched {
+			if !wellConnected(subExternal[r], subStrength[r], c) {
+				continue
+			}
+			gain := weightTo[r] - resolution*k*subStrength[r]/g.total
+			if gain < 0 {
+				continue
+			}
+			candidates = append(candidates, r)
+			gains = append(gains, gain)
+			maxGain = math.Max(maxGain, gain)
+		}
+		// P(r) ∝ exp(ΔQ/θ), shifted by the best gain to stay finite. The gains
+		// are ΔQ·m, as modularity is normalized by the m links of g.
+		sum := 0.0
+		for i, gain := range gains {
+			gains[i] = math.Exp((gain - maxGain) / (leidenRandomness * g.total / 2))
+			sum += gains[i]
+		}
+		pick, x := len(candidates)-1, rng.Float64()*sum
+		for i, p := range gains {
+			if x < p {
+				pick = i
+				break
+			}
+			x -= p
+		}
+		if r, old := candidates[pick], refined[v]; r != old {
+			refined[v] = r
+			subCount[old]--
+			subCount[r]++
+			subStrength[r] += k
+			subExternal[r] += subExternal[v] - 2*weightTo[r]
+		}
+		for _, r := range touched {
+			weightTo[r] = 0
+		}
+		touched = touched[:0]
+	}
+	return renumber(refined)
+}
+
+// aggregate collapses g into one node per community of part, which must be
+// numbered 0..k-1.
+func aggregate(g *leidenGraph, part []int, k int) *leidenGraph {
+	agg := newLeidenGraph(k)
+	for i := range agg.size {
+		agg.size[i] = 0
+	}
+	weights := make([]map[int]float64, k)
+	for v := 0; v < g.n(); v++ {
+		a := part[v]
+		agg.size[a] += g.size[v]
+		if g.selfLoop[v] > 0 {
+			agg.addEdge(a, a, g.selfLoop[v])
+		}
+		for _, e := range g.adj[v] {
+			b := part[e.to]
+			if v > e.to {
+				continue // each undirected edge once
+			}
+			if a == b {
+				agg.addEdge(a, a, e.weight)
+				continue
+			}
+			lo, hi := a, b
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if weights[lo] == nil {
+				weights[lo] = map[int]float64{}
+			}
+			weights[lo][hi] += e.weight
+		}
+	}
+	for a, m := range weights {
+		for b, w := range m {
+			agg.addEdge(a, b, w)
+		}
+	}
+	// Map iteration order must not leak into the result
+	for a := range agg.adj {
+		sortEdges(agg.adj[a])
+	}
+	return agg
+}
+
+func sortEdges(edges []leidenEdge) {
+	sort.Slice(edges, func(i, j int) bool { return edges[i].to < edges[j].to })
+}
+
+// modularityOf is the modularity of part at a resolution:
+// Σ_c [w_c/m - γ (K_c/2m)²], with w_c the weight inside c and K_c its strength.
+func modularityOf(g *leidenGraph, part []int, resolution float64) float64 {
+	if g.total == 0 {
+		return 0
+	}
+	inside := map[int]float64{}
+	strength := map[int]float64{}
+	for v, c := range part {
+		strength[c] += g.strength[v]
+		inside[c] += 2 * g.selfLoop[v]
+		for _, e := range g.adj[v] {
+			if part[e.to] == c {
+				inside[c] += e.weight
+			}
+		}
+	}
+	q := 0.0
+	for c, k := range strength {
+		q += inside[c]/g.total - resolution*(k/g.total)*(k/g.total)
+	}
+	return q
+}
+
+// renumber relabels communities 0..k-1 in order of first appearance.
+func renumber(part []int) []int {
+	ids := map[int]int{}
+	out := make([]int, len(part))
+	for i, c := range part {
+		id, ok := ids[c]
+		if !ok {
+			id = len(ids)
+			ids[c] = id
+		}
+		out[i] = id
+	}
+	return out
+}
+
+func maxOf(xs []int) int {
+	m := -1
+	for _, x := range xs {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
+
+// leidenIterations caps the Leiden passes of a run; each pass starts from the
+// partition of the previous one and runs stop once modularity stops improving.
+const leidenIterations = 10
+
+// detectCommunitiesLeiden performs Leiden community detection on provided nodes
+// and links, which are unweighted like in Louvain.
+func (s *Service) detectCommunitiesLeiden(nodes []db.ListGraphNodesByWeightRow, links []db.ListGraphLinksAmongRow, p CommunityParams) (*CommunityResult, error) {
+	if len(nodes) == 0 {
+		return &CommunityResult{Communities: []Community{}, NodeToCommunity: map[string]int{}, Params: p}, nil
+	}
+	log.Printf("🔍 Starting community detection (Leiden, resolution %g, seed %d): %d nodes, %d links", p.Resolution, p.Seed, len(nodes), len(links))
+
+	nodeIDs := make([]string, len(nodes))
+	adjacency := make(map[string]map[string]int, len(nodes))
+	degrees := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		nodeIDs[i] = n.ID
+		adjacency[n.ID] = make(map[string]int)
+	}
+	totalWeight := 0
+	for _, link := range links {
+		if _, ok := adjacency[link.Source]; !ok {
+			continue
+		}
+		if _, ok := adjacency[link.Target]; !ok {
+			continue
+		}
+		adjacency[link.Source][link.Target]++
+		adjacency[link.Target][link.Source]++
+		degrees[link.Source]++
+		degrees[link.Target]++
+		totalWeight++
+	}
+
+	membership := runLeiden(leidenGraphOf(nodeIDs, adjacency), p.Resolution, p.Seed, leidenIterations)
+	nodeToCommunity := make(map[string]int, len(nodeIDs))
+	for i, id := range nodeIDs {
+		nodeToCommunity[id] = membership[i]
+	}
+	result := buildCommunityResult(nodes, links, nodeToCommunity, adjacency, degrees, totalWeight, p)
+	log.Printf("✅ Community detection complete: %d communities, modularity=%.3f, quality at resolution %g=%.3f, cpm=%.3f",
+		len(result.Communities), result.Modularity, p.Resolution, result.QualityAtResolution, result.CPM)
+	return result, nil
+}
+
+// leidenGraphOf builds the graph of nodeIDs from an adjacency map that counts
+// each link at both ends, self-links included. Edges are added in node order so
+// the seed alone decides the result.
+func leidenGraphOf(nodeIDs []string, adjacency map[string]map[string]int) *leidenGraph {
+	index := make(map[string]int, len(nodeIDs))
+	for i, id := range nodeIDs {
+		index[id] = i
+	}
+	g := newLeidenGraph(len(nodeIDs))
+	for i, id := range nodeIDs {
+		neighbors := make([]int, 0, len(adjacency[id]))
+		for nb := range adjacency[id] {
+			if j, ok := index[nb]; ok && j >= i {
+				neighbors = append(neighbors, j)
+			}
+		}
+		sort.Ints(neighbors)
+		for _, j := range neighbors {
+			w := float64(adjacency[id][nodeIDs[j]])
+			if j == i {
+				// Self-links were counted from both ends
+				w /= 2
+			}
+			g.addEdge(i, j, w)
+		}
+	}
+	return g
+}
@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// plantedGraph returns groups of size nodes, densely linked inside each group
+// and sparsely across groups.
+func plantedGraph(groups, size int, seed int64) ([]db.ListGraphNodesByWeightRow, []db.ListGraphLinksAmongRow) {
+	rng := rand.New(rand.NewSource(seed))
+	var nodes []db.ListGraphNodesByWeightRow
+	var links []db.ListGraphLinksAmongRow
+	n := groups * size
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, db.ListGraphNodesByWeightRow{ID: fmt.Sprintf("n%d", i), Name: fmt.Sprintf("N%d", i)})
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			p := 0.01
+			if i/size == j/size {
+				p = 0.5
+			}
+			if rng.Float64() < p {
+				links = append(links, db.ListGraphLinksAmongRow{Source: nodes[i].ID, Target: nodes[j].ID})
+			}
+		}
+	}
+	return nodes, links
+}
+
+// assertConnectedCommunities fails when a community is not connected through
+// links among its own members.
+func assertConnectedCommunities(t *testing.T, result *CommunityResult, links []db.ListGraphLinksAmongRow) {
+	t.Helper()
+	adj := map[string][]string{}
+	for _, l := range links {
+		adj[l.Source] = append(adj[l.Source], l.Target)
+		adj[l.Target] = append(adj[l.Target], l.Source)
+	}
+	for _, c := range result.Communities {
+		seen := map[string]bool{c.Members[0]: true}
+		stack := []string{c.Members[0]}
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, u := range adj[v] {
+				if !seen[u] && result.NodeToCommunity[u] == c.ID {
+					seen[u] = true
+					stack = append(stack, u)
+				}
+			}
+		}
+		if len(seen) != len(c.Members) {
+			t.Errorf("community %d is disconnected: reached %d of %d members", c.ID, len(seen), len(c.Members))
+		}
+	}
+}
+
+func TestLeidenPlantedPartition(t *testing.T) {
+	nodes, links := plantedGraph(4, 25, 7)
+	svc := NewService(newFakeStore())
+	result, err := svc.detectCommunitiesLeiden(nodes, links, CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 1, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Communities) != 4 {
+		t.Fatalf("expected the 4 planted groups, got %d communities", len(result.Communities))
+	}
+	for i := 0; i < 100; i++ {
+		if a, b := result.NodeToCommunity[fmt.Sprintf("n%d", i)], result.NodeToCommunity[fmt.Sprintf("n%d", i/25*25)]; a != b {
+			t.Fatalf("n%d is not with its group", i)
+		}
+	}
+	if result.Modularity < 0.5 || result.Params.Algorithm != AlgorithmLeiden || result.Params.Seed != 42 {
+		t.Errorf("unexpected result: modularity %.3f, params %+v", result.Modularity, result.Params)
+	}
+	assertConnectedCommunities(t, result, links)
+}
+
+func TestLeidenDeterministic(t *testing.T) {
+	nodes, links := plantedGraph(6, 15, 3)
+	svc := NewService(newFakeStore())
+	p := CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 1, Seed: 9}
+	first, _ := svc.detectCommunitiesLeiden(nodes, links, p)
+	for i := 0; i < 3; i++ {
+		again, _ := svc.detectCommunitiesLeiden(nodes, links, p)
+		if !reflect.DeepEqual(first.NodeToCommunity, again.NodeToCommunity) {
+			t.Fatal("the same seed gave different partitions")
+		}
+	}
+}
+
+func TestLeidenConnectedOnSparseGraph(t *testing.T) {
+	// Sparse random graphs are where Louvain tends to leave communities disconnected
+	rng := rand.New(rand.NewSource(11))
+	var nodes []db.ListGraphNodesByWeightRow
+	var links []db.ListGraphLinksAmongRow
+	for i := 0; i < 300; i++ {
+		nodes = append(nodes, db.ListGraphNodesByWeightRow{ID: fmt.Sprintf("n%d", i)})
+	}
+	for i := 0; i < 600; i++ {
+		a, b := rng.Intn(300), rng.Intn(300)
+		if a != b {
+			links = append(links, db.ListGraphLinksAmongRow{Source: nodes[a].ID, Target: nodes[b].ID})
+		}
+	}
+	svc := NewService(newFakeStore())
+	for seed := int64(1); seed <= 5; seed++ {
+		result, err := svc.detectCommunitiesLeiden(nodes, links, CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 1, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertConnectedCommunities(t, result, links)
+	}
+}
+
+func TestLeidenResolution(t *testing.T) {
+	nodes, links := plantedGraph(4, 25, 5)
+	svc := NewService(newFakeStore())
+	low, _ := svc.detectCommunitiesLeiden(nodes, links, CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 0.05, Seed: 1})
+	high, _ := svc.detectCommunitiesLeiden(nodes, links, CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 5, Seed: 1})
+	if !(len(low.Communities) < 4 && len(high.Communities) > 4) {
+		t.Errorf("expected fewer communities at low resolution and more at high, got %d and %d", len(low.Communities), len(high.Communities))
+	}
+}
+
+func TestCalculateCPM(t *testing.T) {
+	// Two triangles joined by one link, split into the triangles
+	adjacency := map[string]map[string]int{}
+	add := func(a, b string) {
+		for _, n := range []string{a, b} {
+			if adjacency[n] == nil {
+				adjacency[n] = map[string]int{}
+			}
+		}
+		adjacency[a][b]++
+		adjacency[b][a]++
+	}
+	for _, l := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"d", "e"}, {"e", "f"}, {"f", "d"}, {"c", "d"}} {
+		add(l[0], l[1])
+	}
+	part := map[string]int{"a": 0, "b": 0, "c": 0, "d": 1, "e": 1, "f": 1}
+	// (3 - 0.5*3) * 2 communities / 7 links
+	if got := calculateCPM(part, adjacency, 7, 0.5); !near(got, 3.0/7) {
+		t.Errorf("cpm = %v, want %v", got, 3.0/7)
+	}
+
+	// Each triangle holds 3 of 7 links and half the strength: 2 * (3/7 - γ/4)
+	degrees := map[string]int{"a": 2, "b": 2, "c": 3, "d": 3, "e": 2, "f": 2}
+	if got := calculateModularityAt(part, adjacency, degrees, 7, 0.5); !near(got, 6.0/7-0.25) {
+		t.Errorf("quality at resolution 0.5 = %v, want %v", got, 6.0/7-0.25)
+	}
+	ids := []string{"a", "b", "c", "d", "e", "f"}
+	if got, want := calculateModularityAt(part, adjacency, degrees, 7, 0.5), modularityOf(leidenGraphOf(ids, adjacency), []int{0, 0, 0, 1, 1, 1}, 0.5); !near(got, want) {
+		t.Errorf("quality at resolution 0.5 = %v, Leiden's quality = %v", got, want)
+	}
+}
+
+func TestLeidenHierarchy(t *testing.T) {
+	nodes, links := plantedGraph(4, 25, 5)
+	svc := NewService(newFakeStore())
+	p := CommunityParams{Algorithm: AlgorithmLeiden, Resolution: 1, Seed: 3}
+	hierarchy, err := svc.detectHierarchicalCommunities(context.Background(), &db.Queries{}, nodes, links, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hierarchy) < 2 {
+		t.Fatalf("expected at least one level above the nodes, got %d levels", len(hierarchy))
+	}
+	again, _ := svc.detectHierarchicalCommunities(context.Background(), &db.Queries{}, nodes, links, p)
+	if len(again) != len(hierarchy) || !reflect.DeepEqual(again[1].NodeToCommunity, hierarchy[1].NodeToCommunity) {
+		t.Fatal("the same seed gave different hierarchies")
+	}
+
+	// Level 1 is a refined partition: connected communities inside the planted groups
+	level := &CommunityResult{NodeToCommunity: hierarchy[1].NodeToCommunity}
+	members := map[int][]string{}
+	for _, n := range nodes {
+		members[level.NodeToCommunity[n.ID]] = append(members[level.NodeToCommunity[n.ID]], n.ID)
+	}
+	for id, m := range members {
+		level.Communities = append(level.Communities, Community{ID: id, Members: m})
+		var first int
+		fmt.Sscanf(m[0], "n%d", &first)
+		for _, id := range m {
+			var i int
+			fmt.Sscanf(id, "n%d", &i)
+			if i/25 != first/25 {
+				t.Fatalf("level 1 community %v mixes planted groups", m)
+			}
+		}
+	}
+	assertConnectedCommunities(t, level, links)
+}
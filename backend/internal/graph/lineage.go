@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Community lineage events between consecutive detection runs.
+const (
+	CommunityBirth    = "birth"
+	CommunityContinue = "continue"
+	CommunitySplit    = "split"
+	CommunityMerge    = "merge"
+	CommunityDeath    = "death"
+)
+
+// communityLineage is how the communities of a run relate to the previous one.
+type communityLineage struct {
+	// IDs holds, per community of the run, the ID of the previous community it
+	// continues, or 0 when it needs a new ID.
+	IDs []int32
+	// Events refer to communities of the run by index in Next; Next is -1 for
+	// events about previous communities only.
+	Events []lineageEvent
+}
+
+type lineageEvent struct {
+	Event   string
+	Next    int
+	Prev    int32
+	Jaccard float64
+	Size    int
+}
+
+// lineageContainment is the share of a community's members that must come from
+// (split) or go to (merge) one community of the other run for the two to be
+// related when their Jaccard index is too low for a continuation.
+const lineageContainment = 0.5
+
+// matchCommunities matches the communities of a run (members per community)
+// against the previous run's (members by ID). Going from the strongest Jaccard
+// index down, a pair overlapping with at least threshold whose communities are
+// both still unclaimed continues the previous ID. The rest are related by
+// containment, which a small community absorbed by a large one or a large one
+// split into many parts reaches although their Jaccard index stays low: a
+// previous community that did not continue merged into the new community
+// holding most of its members, and a new community without an ID split from
+// the previous community most of its members come from. Each pair gets at most
+// one event. Unrelated previous communities die; new communities that are not
+// continued, split off or merged into are born.
+func matchCommunities(prev map[int32][]string, next [][]string, threshold float64) communityLineage {
+	prevOf := make(map[string]int32)
+	for id, members := range prev {
+		for _, m := range members {
+			prevOf[m] = id
+		}
+	}
+
+	type pair struct {
+		next    int
+		prev    int32
+		overlap int
+		jaccard float64
+	}
+	var pairs []pair
+	for i, members := range next {
+		overlap := map[int32]int{}
+		for _, m := range members {
+			if id, ok := prevOf[m]; ok {
+				overlap[id]++
+			}
+		}
+		for id, ov := range overlap {
+			j := float64(ov) / float64(len(members)+len(prev[id])-ov)
+			pairs = append(pairs, pair{next: i, prev: id, overlap: ov, jaccard: j})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].jaccard != pairs[b].jaccard {
+			return pairs[a].jaccard > pairs[b].jaccard
+		}
+		if pairs[a].prev != pairs[b].prev {
+			return pairs[a].prev < pairs[b].prev
+		}
+		return pairs[a].next < pairs[b].next
+	})
+
+	out := communityLineage{IDs: make([]int32, len(next))}
+	continued := map[int32]bool{}
+	for _, p := range pairs {
+		if p.jaccard < threshold || out.IDs[p.next] != 0 || continued[p.prev] {
+			continue
+		}
+		out.IDs[p.next] = p.prev
+		continued[p.prev] = true
+		out.Events = append(out.Events, lineageEvent{Event: CommunityContinue, Next: p.next, Prev: p.prev, Jaccard: p.jaccard, Size: len(next[p.next])})
+	}
+
+	// Pairs are visited strongest first, so the first match of a community is
+	// its best one.
+	related := map[int32]bool{}
+	formed := make([]bool, len(next)) // split off or merged into
+	mergedInto := map[int32]int{}
+	split := make([]bool, len(next))
+	for _, p := range pairs {
+		if _, ok := mergedInto[p.prev]; ok || continued[p.prev] || float64(p.overlap) < lineageContainment*float64(len(prev[p.prev])) {
+			continue
+		}
+		mergedInto[p.prev], related[p.prev], formed[p.next] = p.next, true, true
+		out.Events = append(out.Events, lineageEvent{Event: CommunityMerge, Next: p.next, Prev: p.prev, Jaccard: p.jaccard, Size: len(prev[p.prev])})
+	}
+	for _, p := range pairs {
+		if out.IDs[p.next] != 0 || split[p.next] || float64(p.overlap) < lineageContainment*float64(len(next[p.next])) {
+			continue
+		}
+		if n, ok := mergedInto[p.prev]; ok && n == p.next {
+			continue // the pair is already a merge
+		}
+		split[p.next], related[p.prev], formed[p.next] = true, true, true
+		out.Events = append(out.Events, lineageEvent{Event: CommunitySplit, Next: p.next, Prev: p.prev, Jaccard: p.jaccard, Size: len(next[p.next])})
+	}
+
+	for i, members := range next {
+		if out.IDs[i] == 0 && !formed[i] {
+			out.Events = append(out.Events, lineageEvent{Event: CommunityBirth, Next: i, Size: len(members)})
+		}
+	}
+	ids := make([]int32, 0, len(prev))
+	for id := range prev {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	for _, id := range ids {
+		if !continued[id] && !related[id] {
+			out.Events = append(out.Events, lineageEvent{Event: CommunityDeath, Next: -1, Prev: id, Size: len(prev[id])})
+		}
+	}
+	return out
+}
+
+// dbEvents resolves the events against the IDs the communities of the run were
+// stored under.
+func (l communityLineage) dbEvents(ids []int32) []db.CommunityEvent {
+	out := make([]db.CommunityEvent, 0, len(l.Events))
+	for _, e := range l.Events {
+		ev := db.CommunityEvent{Event: e.Event, Size: int32(e.Size)}
+		if e.Jaccard > 0 {
+			ev.Jaccard = sql.NullFloat64{Float64: e.Jaccard, Valid: true}
+		}
+		switch e.Event {
+		case CommunityBirth, CommunityContinue:
+			ev.CommunityID = ids[e.Next]
+		case CommunitySplit:
+			ev.CommunityID = ids[e.Next]
+			ev.RelatedID = sql.NullInt32{Int32: e.Prev, Valid: true}
+		case CommunityMerge:
+			if ids[e.Next] == 0 {
+				continue
+			}
+			ev.CommunityID = e.Prev
+			ev.RelatedID = sql.NullInt32{Int32: ids[e.Next], Valid: true}
+		case CommunityDeath:
+			ev.CommunityID = e.Prev
+		}
+		if ev.CommunityID == 0 {
+			// The community failed to store
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out
+}
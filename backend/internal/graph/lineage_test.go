@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// members returns the node IDs n<from>..n<to-1>.
+func members(from, to int) []string {
+	var out []string
+	for i := from; i < to; i++ {
+		out = append(out, fmt.Sprintf("n%d", i))
+	}
+	return out
+}
+
+func eventsByKind(l communityLineage) map[string][]lineageEvent {
+	out := map[string][]lineageEvent{}
+	for _, e := range l.Events {
+		out[e.Event] = append(out[e.Event], e)
+	}
+	return out
+}
+
+func TestMatchCommunities(t *testing.T) {
+	prev := map[int32][]string{
+		1: members(0, 20),  // continues with a few members moved
+		2: members(20, 40), // splits in half
+		3: members(40, 50), // merges into 4
+		4: members(50, 60),
+		5: members(60, 70), // dies
+	}
+	next := [][]string{
+		append(members(0, 18), "n99"),
+		members(20, 30),
+		members(30, 40),
+		members(40, 60),
+		members(100, 110), // born
+	}
+	l := matchCommunities(prev, next, 0.3)
+
+	// Ties go to the lower previous ID, then to the first new community
+	if want := []int32{1, 2, 0, 3, 0}; !reflect.DeepEqual(l.IDs, want) {
+		t.Fatalf("ids = %v, want %v", l.IDs, want)
+	}
+	ev := eventsByKind(l)
+	if len(ev[CommunityContinue]) != 3 || len(ev[CommunitySplit]) != 1 || len(ev[CommunityMerge]) != 1 ||
+		len(ev[CommunityBirth]) != 1 || len(ev[CommunityDeath]) != 1 {
+		t.Fatalf("unexpected events %+v", l.Events)
+	}
+	if s := ev[CommunitySplit][0]; s.Prev != 2 || s.Next != 2 || !near(s.Jaccard, 0.5) {
+		t.Errorf("unexpected split %+v", s)
+	}
+	if m := ev[CommunityMerge][0]; m.Next != 3 || m.Prev != 4 || !near(m.Jaccard, 0.5) {
+		t.Errorf("unexpected merge %+v", m)
+	}
+	if b := ev[CommunityBirth][0]; b.Next != 4 {
+		t.Errorf("unexpected birth %+v", b)
+	}
+	if d := ev[CommunityDeath][0]; d.Prev != 5 || d.Size != 10 {
+		t.Errorf("unexpected death %+v", d)
+	}
+
+	// The new communities are stored under the matched IDs or fresh ones
+	ids := make([]int32, len(next))
+	for i, id := range l.IDs {
+		ids[i] = id
+		if id == 0 {
+			ids[i] = int32(100 + i)
+		}
+	}
+	for _, e := range l.dbEvents(ids) {
+		switch e.Event {
+		case CommunityMerge:
+			if e.CommunityID != 4 || e.RelatedID.Int32 != 3 {
+				t.Errorf("merge %+v should be 4 into 3", e)
+			}
+		case CommunitySplit:
+			if e.CommunityID != 102 || e.RelatedID.Int32 != 2 {
+				t.Errorf("split %+v should be a new community off 2", e)
+			}
+		case CommunityDeath:
+			if e.CommunityID != 5 || e.RelatedID.Valid || e.Jaccard.Valid {
+				t.Errorf("unexpected death %+v", e)
+			}
+		}
+	}
+}
+
+func TestMatchCommunities_Stable(t *testing.T) {
+	prev := map[int32][]string{7: members(0, 10), 12: members(10, 30)}
+	l := matchCommunities(prev, [][]string{members(10, 30), members(0, 10)}, 0.3)
+	if !reflect.DeepEqual(l.IDs, []int32{12, 7}) {
+		t.Errorf("ids = %v", l.IDs)
+	}
+	for _, e := range l.Events {
+		if e.Event != CommunityContinue || e.Jaccard != 1 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+
+	// Without a previous run every community is born
+	l = matchCommunities(nil, [][]string{members(0, 3)}, 0.3)
+	if l.IDs[0] != 0 || len(l.Events) != 1 || l.Events[0].Event != CommunityBirth {
+		t.Errorf("unexpected lineage %+v", l)
+	}
+}
+
+func TestMatchCommunities_Absorption(t *testing.T) {
+	// A small community absorbed by a large one that continues: their Jaccard
+	// index is far below the threshold, but all of the small one moved
+	prev := map[int32][]string{1: members(0, 100), 2: members(100, 110)}
+	l := matchCommunities(prev, [][]string{members(0, 110)}, 0.3)
+	if !reflect.DeepEqual(l.IDs, []int32{1}) {
+		t.Fatalf("ids = %v", l.IDs)
+	}
+	ev := eventsByKind(l)
+	if len(ev[CommunityContinue]) != 1 || len(ev[CommunityMerge]) != 1 || len(l.Events) != 2 {
+		t.Fatalf("unexpected events %+v", l.Events)
+	}
+	if m := ev[CommunityMerge][0]; m.Prev != 2 || m.Next != 0 || m.Size != 10 || !near(m.Jaccard, 10.0/110) {
+		t.Errorf("unexpected merge %+v", m)
+	}
+}
+
+func TestMatchCommunities_SplitIntoMany(t *testing.T) {
+	// Five equal parts each overlap the old community with a Jaccard index of 0.2
+	prev := map[int32][]string{1: members(0, 50)}
+	var next [][]string
+	for i := 0; i < 5; i++ {
+		next = append(next, members(10*i, 10*i+10))
+	}
+	l := matchCommunities(prev, next, 0.3)
+	if !reflect.DeepEqual(l.IDs, []int32{0, 0, 0, 0, 0}) {
+		t.Fatalf("ids = %v", l.IDs)
+	}
+	if len(l.Events) != 5 {
+		t.Fatalf("expected one split per part, got %+v", l.Events)
+	}
+	seen := map[int]bool{}
+	for _, e := range l.Events {
+		if e.Event != CommunitySplit || e.Prev != 1 || e.Size != 10 || seen[e.Next] {
+			t.Errorf("unexpected event %+v", e)
+		}
+		seen[e.Next] = true
+	}
+}
+
+func TestMatchCommunities_MergeIntoNew(t *testing.T) {
+	// Four communities merge into one new community; none of them is similar
+	// enough to continue, and the new one is formed by the merges, not born
+	prev := map[int32][]string{1: members(0, 10), 2: members(10, 20), 3: members(20, 30), 4: members(30, 40)}
+	l := matchCommunities(prev, [][]string{members(0, 40)}, 0.3)
+	if l.IDs[0] != 0 {
+		t.Fatalf("ids = %v", l.IDs)
+	}
+	pairs := map[[2]int]bool{}
+	for _, e := range l.Events {
+		if e.Event != CommunityMerge || e.Next != 0 {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		key := [2]int{int(e.Prev), e.Next}
+		if pairs[key] {
+			t.Fatalf("pair %v has more than one event", key)
+		}
+		pairs[key] = true
+	}
+	if len(pairs) != 4 {
+		t.Errorf("expected 4 merges, got %+v", l.Events)
+	}
+}
@@ -0,0 +1,307 @@
+package graph
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// octreeMaxDepth bounds the subdivision of the octree. Bodies that still
+// share a cell at this depth (coincident or nearly so) share a leaf.
+const octreeMaxDepth = 24
+
+// octreeChunk is the number of bodies a worker claims at a time.
+const octreeChunk = 512
+
+// octNode is a cell of the octree. Cells live in a flat slice and refer to
+// their children by index; 0 means no child, since the root is never one.
+type octNode struct {
+	// Center of mass, mass and cell width
+	mx, my, mz, mass, width float64
+
+	child [8]int32
+	body  int32 // first body of a leaf, -1 when empty
+	leaf  bool
+}
+
+// octree is a Barnes-Hut octree over body positions. Leaves hold one body,
+// except at octreeMaxDepth, where the bodies of a leaf are chained by next.
+type octree struct {
+	nodes []octNode
+	next  []int32
+	order []int32 // bodies in Morton order
+
+	// Cell centers, only needed while building
+	centers [][3]float64
+}
+
+// buildOctree builds the octree of the bodies at X, Y, Z, all of mass 1.
+// Bodies are inserted in Morton order, ties broken by index, so that nearby
+// cells sit close together in memory and the tree only depends on the
+// positions.
+func buildOctree(X, Y, Z []float64) *octree {
+	n := len(X)
+	if n == 0 {
+		return nil
+	}
+	minX, maxX, minY, maxY, minZ, maxZ := X[0], X[0], Y[0], Y[0], Z[0], Z[0]
+	for i := 1; i < n; i++ {
+		minX, maxX = math.Min(minX, X[i]), math.Max(maxX, X[i])
+		minY, maxY = math.Min(minY, Y[i]), math.Max(maxY, Y[i])
+		minZ, maxZ = math.Min(minZ, Z[i]), math.Max(maxZ, Z[i])
+	}
+	// A padded cube around the bodies, as for the quadtree
+	half := math.Max(maxX-minX, math.Max(maxY-minY, maxZ-minZ))/2*1.1 + 1
+
+	cx, cy, cz := (minX+maxX)/2, (minY+maxY)/2, (minZ+maxZ)/2
+
+	t := &octree{nodes: make([]octNode, 0, 2*n), next: make([]int32, n), order: make([]int32, n), centers: make([][3]float64, 0, 2*n)}
+	keys := make([]uint64, n)
+	scale := float64(1<<21) / (2 * half)
+	for i := 0; i < n; i++ {
+		t.order[i] = int32(i)
+		keys[i] = mortonKey((X[i]-cx+half)*scale, (Y[i]-cy+half)*scale, (Z[i]-cz+half)*scale)
+	}
+	sort.Slice(t.order, func(a, b int) bool {
+		ka, kb := keys[t.order[a]], keys[t.order[b]]
+		return ka < kb || ka == kb && t.order[a] < t.order[b]
+	})
+
+	t.nodes = append(t.nodes, octNode{width: 2 * half, body: -1, leaf: true})
+	t.centers = append(t.centers, [3]float64{cx, cy, cz})
+	for _, i := range t.order {
+		t.insert(i, X, Y, Z)
+	}
+	t.summarize(X, Y, Z)
+	t.centers = nil
+	return t
+}
+
+// mortonKey interleaves the bits of three coordinates in [0, 2^21).
+func mortonKey(x, y, z float64) uint64 {
+	spread := func(v float64) uint64 {
+		u := uint64(math.Max(0, math.Min(v, 1<<21-1)))
+		u = (u | u<<32) & 0x1f00000000ffff
+		u = (u | u<<16) & 0x1f0000ff0000ff
+		u = (u | u<<8) & 0x100f00f00f00f00f
+		u = (u | u<<4) & 0x10c30c30c30c30c3
+		u = (u | u<<2) & 0x1249249249249249
+		return u
+	}
+	return spread(x) | spread(y)<<1 | spread(z)<<2
+}
+
+// octant returns the child of cell n that contains (x, y, z).
+func (t *octree) octant(n int32, x, y, z float64) int {
+	c := t.centers[n]
+	o := 0
+	if x >= c[0] {
+		o |= 1
+	}
+	if y >= c[1] {
+		o |= 2
+	}
+	if z >= c[2] {
+		o |= 4
+	}
+	return o
+}
+
+// childOf returns the child o of cell n, creating it as an empty leaf.
+func (t *octree) childOf(n int32, o int) int32 {
+	if c := t.nodes[n].child[o]; c != 0 {
+		return c
+	}
+	w := t.nodes[n].width / 2
+	c := t.centers[n]
+	for a := 0; a < 3; a++ {
+		if o&(1<<a) != 0 {
+			c[a] += w / 2
+		} else {
+			c[a] -= w / 2
+		}
+	}
+	id := int32(len(t.nodes))
+	t.nodes = append(t.nodes, octNode{width: w, body: -1, leaf: true})
+	t.centers = append(t.centers, c)
+	t.nodes[n].child[o] = id
+	return id
+}
+
+func (t *octree) insert(i int32, X, Y, Z []float64) {
+	t.next[i] = -1
+	n := int32(0)
+	for depth := 0; ; depth++ {
+		c := &t.nodes[n]
+		if c.leaf {
+			if c.body < 0 {
+				c.body = i
+				return
+			}
+			if depth >= octreeMaxDepth {
+				t.next[i] = c.body
+				c.body = i
+				return
+			}
+			// Split: move the resident body down a level
+			b := c.body
+			c.body, c.leaf = -1, false
+			bc := t.childOf(n, t.octant(n, X[b], Y[b], Z[b]))
+			t.nodes[bc].body = b
+		}
+		n = t.childOf(n, t.octant(n, X[i], Y[i], Z[i]))
+	}
+}
+
+// summarize computes the mass and center of mass of every cell. Children
+// always come after their parent, so one backward pass suffices.
+func (t *octree) summarize(X, Y, Z []float64) {
+	for n := len(t.nodes) - 1; n >= 0; n-- {
+		c := &t.nodes[n]
+		var mx, my, mz, m float64
+		if c.leaf {
+			for b := c.body; b >= 0; b = t.next[b] {
+				mx, my, mz, m = mx+X[b], my+Y[b], mz+Z[b], m+1
+			}
+		} else {
+			for _, k := range c.child {
+				if k == 0 {
+					continue
+				}
+				ch := &t.nodes[k]
+				mx, my, mz, m = mx+ch.mx*ch.mass, my+ch.my*ch.mass, mz+ch.mz*ch.mass, m+ch.mass
+			}
+		}
+		if m > 0 {
+			c.mx, c.my, c.mz = mx/m, my/m, mz/m
+		}
+		c.mass = m
+	}
+}
+
+// force returns the repulsion on body i, which pushes it away from every
+// other body with strength repStrength/dist². Cells narrower than theta times
+// their distance act as a single body at their center of mass. stack is
+// scratch space, returned for reuse.
+func (t *octree) force(i int32, X, Y, Z []float64, theta2, repStrength float64, stack []int32) (fx, fy, fz float64, _ []int32) {
+	px, py, pz := X[i], Y[i], Z[i]
+	stack = append(stack[:0], 0)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		c := &t.nodes[n]
+		if c.mass == 0 {
+			continue
+		}
+		if c.leaf {
+			if c.mass == 1 && c.body != i {
+				// A lone body sits at the center of mass
+				dx, dy, dz := px-c.mx, py-c.my, pz-c.mz
+				d2 := dx*dx + dy*dy + dz*dz
+				if d2 >= 1e-12 {
+					f := repStrength / (d2 * math.Sqrt(d2))
+					fx, fy, fz = fx+dx*f, fy+dy*f, fz+dz*f
+					continue
+				}
+			}
+			for b := c.body; b >= 0; b = t.next[b] {
+				if b == i {
+					continue
+				}
+				dx, dy, dz := px-X[b], py-Y[b], pz-Z[b]
+				d2 := dx*dx + dy*dy + dz*dz
+				if d2 < 1e-12 {
+					// Coincident bodies: push them apart along a direction
+					// that depends only on the pair
+					dx, dy, dz = jitter3(i, b)
+					dx, dy, dz, d2 = dx*1e-6, dy*1e-6, dz*1e-6, 1e-12
+				}
+				f := repStrength / (d2 * math.Sqrt(d2))
+				fx, fy, fz = fx+dx*f, fy+dy*f, fz+dz*f
+			}
+			continue
+		}
+		dx, dy, dz := px-c.mx, py-c.my, pz-c.mz
+		d2 := dx*dx + dy*dy + dz*dz
+		if c.width*c.width < theta2*d2 {
+			f := repStrength * c.mass / (d2 * math.Sqrt(d2))
+			fx, fy, fz = fx+dx*f, fy+dy*f, fz+dz*f
+			continue
+		}
+		for _, k := range c.child {
+			if k != 0 {
+				stack = append(stack, k)
+			}
+		}
+	}
+	return fx, fy, fz, stack
+}
+
+// calculateOctreeForces computes the Barnes-Hut repulsion on every body into
+// dispX, dispY and dispZ, splitting the bodies between workers goroutines
+// (GOMAXPROCS when workers <= 0). Each body's force is summed in the same
+// order whatever the number of workers, so the result is too.
+func calculateOctreeForces(X, Y, Z, dispX, dispY, dispZ []float64, theta, repStrength float64, workers int) {
+	n := len(X)
+	t := buildOctree(X, Y, Z)
+	if t == nil {
+		return
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if chunks := (n + octreeChunk - 1) / octreeChunk; workers > chunks {
+		workers = chunks
+	}
+	theta2 := theta * theta
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stack := make([]int32, 0, 64)
+			for {
+				start := int(next.Add(octreeChunk)) - octreeChunk
+				if start >= n {
+					return
+				}
+				end := min(start+octreeChunk, n)
+				// Neighbouring bodies walk much the same cells
+				for _, i := range t.order[start:end] {
+					dispX[i], dispY[i], dispZ[i], stack = t.force(i, X, Y, Z, theta2, repStrength, stack)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// jitter3 returns a unit direction for pushing apart bodies a and b when they
+// coincide. It is a hash of the pair, so it is deterministic, and flips sign
+// when a and b swap, so the two bodies move apart.
+func jitter3(a, b int32) (float64, float64, float64) {
+	sign := 1.0
+	if a > b {
+		a, b, sign = b, a, -1
+	}
+	h := uint64(a)<<32 | uint64(uint32(b))
+	u := splitmix64(&h)
+	v := splitmix64(&h)
+	// A uniform direction on the sphere
+	z := 2*float64(u>>11)/(1<<53) - 1
+	phi := 2 * math.Pi * float64(v>>11) / (1 << 53)
+	r := math.Sqrt(1 - z*z)
+	return sign * r * math.Cos(phi), sign * r * math.Sin(phi), sign * z
+}
+
+func splitmix64(s *uint64) uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := *s
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,338 @@
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomPoints3D(n int, seed int64) (X, Y, Z []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	X, Y, Z = make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		X[i], Y[i], Z[i] = rng.NormFloat64()*100, rng.NormFloat64()*100, rng.NormFloat64()*100
+	}
+	return X, Y, Z
+}
+
+// bruteForceRepulsion3D is the exact O(n²) repulsion the octree approximates.
+func bruteForceRepulsion3D(X, Y, Z []float64, repStrength float64) (fx, fy, fz []float64) {
+	n := len(X)
+	fx, fy, fz = make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			dx, dy, dz := X[i]-X[j], Y[i]-Y[j], Z[i]-Z[j]
+			d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+			f := repStrength / (d * d * d)
+			fx[i], fy[i], fz[i] = fx[i]+dx*f, fy[i]+dy*f, fz[i]+dz*f
+		}
+	}
+	return fx, fy, fz
+}
+
+func TestOctreeMass(t *testing.T) {
+	X, Y, Z := randomPoints3D(1000, 1)
+	tree := buildOctree(X, Y, Z)
+	root := tree.nodes[0]
+	if root.mass != 1000 {
+		t.Fatalf("root mass = %v, want 1000", root.mass)
+	}
+	var cx, cy, cz float64
+	for i := range X {
+		cx, cy, cz = cx+X[i], cy+Y[i], cz+Z[i]
+	}
+	if math.Abs(root.mx-cx/1000) > 1e-9 || math.Abs(root.my-cy/1000) > 1e-9 || math.Abs(root.mz-cz/1000) > 1e-9 {
+		t.Errorf("root center of mass = (%v, %v, %v)", root.mx, root.my, root.mz)
+	}
+}
+
+func TestOctreeForcesMatchBruteForce(t *testing.T) {
+	n := 500
+	X, Y, Z := randomPoints3D(n, 2)
+	wantX, wantY, wantZ := bruteForceRepulsion3D(X, Y, Z, 100)
+	fx, fy, fz := make([]float64, n), make([]float64, n), make([]float64, n)
+
+	// theta 0 never approximates
+	calculateOctreeForces(X, Y, Z, fx, fy, fz, 0, 100, 4)
+	for i := 0; i < n; i++ {
+		if math.Abs(fx[i]-wantX[i]) > 1e-9 || math.Abs(fy[i]-wantY[i]) > 1e-9 || math.Abs(fz[i]-wantZ[i]) > 1e-9 {
+			t.Fatalf("body %d: force (%v, %v, %v), want (%v, %v, %v)", i, fx[i], fy[i], fz[i], wantX[i], wantY[i], wantZ[i])
+		}
+	}
+
+	// The standard theta stays within a few percent overall
+	calculateOctreeForces(X, Y, Z, fx, fy, fz, 0.8, 100, 4)
+	var errSum, normSum float64
+	for i := 0; i < n; i++ {
+		errSum += math.Sqrt((fx[i]-wantX[i])*(fx[i]-wantX[i]) + (fy[i]-wantY[i])*(fy[i]-wantY[i]) + (fz[i]-wantZ[i])*(fz[i]-wantZ[i]))
+		normSum += math.Sqrt(wantX[i]*wantX[i] + wantY[i]*wantY[i] + wantZ[i]*wantZ[i])
+	}
+	if rel := errSum / normSum; rel > 0.05 {
+		t.Errorf("relative error at theta 0.8 = %.3f", rel)
+	}
+}
+
+func TestOctreeForcesIndependentOfWorkers(t *testing.T) {
+	n := 5000
+	X, Y, Z := randomPoints3D(n, 3)
+	var first [3][]float64
+	for _, workers := range []int{1, 3, 8} {
+		fx, fy, fz := make([]float64, n), make([]float64, n), make([]float64, n)
+		calculateOctreeForces(X, Y, Z, fx, fy, fz, 0.8, 100, workers)
+		if first[0] == nil {
+			first = [3][]float64{fx, fy, fz}
+			continue
+		}
+		if !reflect.DeepEqual(first, [3][]float64{fx, fy, fz}) {
+			t.Fatalf("forces with %d workers differ from 1 worker", workers)
+		}
+	}
+}
+
+func TestOctreeCoincidentBodies(t *testing.T) {
+	// Coincident bodies share a leaf at the maximum depth instead of
+	// subdividing forever, and are pushed apart in opposite directions
+	X := []float64{5, 5, 5, 40}
+	Y := []float64{5, 5, 5, 40}
+	Z := []float64{5, 5, 5, 40}
+	fx, fy, fz := make([]float64, 4), make([]float64, 4), make([]float64, 4)
+	calculateOctreeForces(X, Y, Z, fx, fy, fz, 0.8, 1, 0)
+	for i := 0; i < 3; i++ {
+		if fx[i] == 0 && fy[i] == 0 && fz[i] == 0 {
+			t.Errorf("coincident body %d has no force", i)
+		}
+	}
+	sx, sy, sz := fx[0]+fx[1]+fx[2], fy[0]+fy[1]+fy[2], fz[0]+fz[1]+fz[2]
+	if math.Abs(sx)+math.Abs(sy)+math.Abs(sz) > 1e-3*math.Abs(fx[0]) {
+		t.Errorf("coincident pushes don't cancel: (%v, %v, %v)", sx, sy, sz)
+	}
+}
+
+func TestForceLayout3DDeterministic(t *testing.T) {
+	// Two rings joined by one edge
+	n := 200
+	var edges []layoutEdge
+	for i := 0; i < n/2; i++ {
+		edges = append(edges, layoutEdge{i, (i + 1) % (n / 2)}, layoutEdge{n/2 + i, n/2 + (i+1)%(n/2)})
+	}
+	edges = append(edges, layoutEdge{0, n / 2})
+
+	p := layoutParams{Iterations: 50, Theta: 0.8, Seed: 42, Workers: 1}
+	X1, Y1, Z1 := forceLayout3D(n, edges, p)
+	p.Workers = 4
+	X2, Y2, Z2 := forceLayout3D(n, edges, p)
+	if !reflect.DeepEqual(X1, X2) || !reflect.DeepEqual(Y1, Y2) || !reflect.DeepEqual(Z1, Z2) {
+		t.Fatal("the same seed gave different layouts")
+	}
+	p.Seed = 43
+	X3, _, _ := forceLayout3D(n, edges, p)
+	if reflect.DeepEqual(X1, X3) {
+		t.Error("different seeds gave the same layout")
+	}
+
+	// The layout uses the third dimension, and linked nodes end up closer
+	// than nodes of different rings
+	var zMin, zMax float64
+	for _, z := range Z1 {
+		zMin, zMax = math.Min(zMin, z), math.Max(zMax, z)
+	}
+	if zMax-zMin < 1 {
+		t.Errorf("z spans only %v", zMax-zMin)
+	}
+	dist := func(a, b int) float64 {
+		return math.Sqrt((X1[a]-X1[b])*(X1[a]-X1[b]) + (Y1[a]-Y1[b])*(Y1[a]-Y1[b]) + (Z1[a]-Z1[b])*(Z1[a]-Z1[b]))
+	}
+	var linked, apart float64
+	for i := 1; i < n/2; i++ {
+		linked += dist(i, i+1)
+		apart += dist(i, n/2+i)
+	}
+	if linked >= apart {
+		t.Errorf("mean linked distance %.1f, mean distance across rings %.1f", linked/float64(n/2-1), apart/float64(n/2-1))
+	}
+}
+
+// edgeStretch is the mean edge length over the mean distance between all
+// pairs of nodes. A good layout keeps linked nodes close, so lower is better.
+func edgeStretch(X, Y, Z []float64, edges []layoutEdge) float64 {
+	dist := func(i, j int) float64 {
+		dx, dy, dz := X[i]-X[j], Y[i]-Y[j], Z[i]-Z[j]
+		return math.Sqrt(dx*dx + dy*dy + dz*dz)
+	}
+	var edgeSum, pairSum float64
+	for _, e := range edges {
+		edgeSum += dist(e.a, e.b)
+	}
+	n := len(X)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			pairSum += dist(i, j)
+		}
+	}
+	return (edgeSum / float64(len(edges))) / (pairSum / float64(n*(n-1)/2))
+}
+
+// plantedClusters returns edges of 4 dense clusters of 25 nodes joined by a
+// few edges.
+func plantedClusters(seed int64) (int, []layoutEdge) {
+	rng := rand.New(rand.NewSource(seed))
+	const clusters, size = 4, 25
+	var edges []layoutEdge
+	for c := 0; c < clusters; c++ {
+		for i := 0; i < size; i++ {
+			for j := i + 1; j < size; j++ {
+				if rng.Float64() < 0.3 {
+					edges = append(edges, layoutEdge{c*size + i, c*size + j})
+				}
+			}
+		}
+		edges = append(edges, layoutEdge{c * size, (c + 1) % clusters * size})
+	}
+	return clusters * size, edges
+}
+
+// TestForceLayout3DQuality verifies that forceLayout3D produces reasonable layouts
+func TestForceLayout3DQuality(t *testing.T) {
+	// Edges forming a square: 0-1, 1-3, 3-2, 2-0
+	N := 4
+	edges := []layoutEdge{{0, 1}, {1, 3}, {3, 2}, {2, 0}}
+	X, Y, Z := forceLayout3D(N, edges, layoutParams{Iterations: 50, Theta: 0.8, Seed: 1})
+
+	// Layout should span a reasonable area (not collapsed, not exploded)
+	var span float64
+	for i := 0; i < N; i++ {
+		for j := i + 1; j < N; j++ {
+			dist := math.Sqrt((X[i]-X[j])*(X[i]-X[j]) + (Y[i]-Y[j])*(Y[i]-Y[j]) + (Z[i]-Z[j])*(Z[i]-Z[j]))
+			if dist < 1 {
+				t.Errorf("nodes %d and %d too close: dist=%.2f", i, j, dist)
+			}
+			span = math.Max(span, dist)
+		}
+	}
+	if span < 10 {
+		t.Errorf("layout collapsed: span=%.2f", span)
+	}
+	if span > 10000 {
+		t.Errorf("layout exploded: span=%.2f", span)
+	}
+
+	// The sides of the square end up shorter than its diagonals
+	if s := edgeStretch(X, Y, Z, edges); s >= 1 {
+		t.Errorf("edges are not shorter than the average pair: stretch=%.2f", s)
+	}
+}
+
+// TestForceLayout3DConvergence verifies that iterating pulls clusters together
+func TestForceLayout3DConvergence(t *testing.T) {
+	N, edges := plantedClusters(1)
+	p := layoutParams{Theta: 0.8, Seed: 1}
+	X, Y, Z := forceLayout3D(N, edges, p)
+	initial := edgeStretch(X, Y, Z, edges)
+
+	p.Iterations = 100
+	X, Y, Z = forceLayout3D(N, edges, p)
+	short := edgeStretch(X, Y, Z, edges)
+	p.Iterations = 300
+	X, Y, Z = forceLayout3D(N, edges, p)
+	long := edgeStretch(X, Y, Z, edges)
+
+	t.Logf("edge stretch: initial=%.3f, 100 iterations=%.3f, 300 iterations=%.3f", initial, short, long)
+	if short > initial/2 {
+		t.Errorf("layout not converging: stretch %.3f after 100 iterations, %.3f initially", short, initial)
+	}
+	if long > short*1.2 {
+		t.Errorf("layout diverges with more iterations: stretch %.3f after 300, %.3f after 100", long, short)
+	}
+}
+
+// TestOctreeVsExactLayoutQuality compares the layout quality of the octree
+// approximation with exact repulsion (theta 0)
+func TestOctreeVsExactLayoutQuality(t *testing.T) {
+	N, edges := plantedClusters(2)
+	p := layoutParams{Iterations: 100, Theta: 0, Seed: 1}
+	X, Y, Z := forceLayout3D(N, edges, p)
+	exact := edgeStretch(X, Y, Z, edges)
+	p.Theta = 0.8
+	X, Y, Z = forceLayout3D(N, edges, p)
+	approx := edgeStretch(X, Y, Z, edges)
+
+	// The final positions differ, but the approximation should not make
+	// the layout noticeably worse
+	if approx > exact*1.2 {
+		t.Errorf("octree layout worse than exact: stretch=%.3f, exact=%.3f", approx, exact)
+	}
+}
+
+// TestOctreeThetaAccuracyTradeoff verifies theta parameter behavior
+func TestOctreeThetaAccuracyTradeoff(t *testing.T) {
+	// A 5x5x2 grid
+	N := 50
+	X := make([]float64, N)
+	Y := make([]float64, N)
+	Z := make([]float64, N)
+	for i := 0; i < N; i++ {
+		X[i] = float64(i%5) * 20
+		Y[i] = float64(i/5%5) * 20
+		Z[i] = float64(i/25) * 20
+	}
+
+	repStrength := 1000.0
+	forces := func(theta float64) []float64 {
+		fx, fy, fz := make([]float64, N), make([]float64, N), make([]float64, N)
+		calculateOctreeForces(X, Y, Z, fx, fy, fz, theta, repStrength, 1)
+		return append(append(fx, fy...), fz...)
+	}
+	exact := forces(0) // Exact
+
+	// Mean error relative to the largest exact force component
+	var maxForce float64
+	for _, f := range exact {
+		maxForce = math.Max(maxForce, math.Abs(f))
+	}
+	relError := func(theta float64) float64 {
+		var total float64
+		for i, f := range forces(theta) {
+			total += math.Abs(f - exact[i])
+		}
+		return total / float64(len(exact)) / maxForce
+	}
+
+	err5, err8, err15 := relError(0.5), relError(0.8), relError(1.5)
+	if err5 > err8 || err8 > err15 {
+		// Note: This might not always hold due to numerical effects
+		t.Logf("theta error progression: 0.5=%.4f, 0.8=%.4f, 1.5=%.4f", err5, err8, err15)
+	}
+	if err8 > 0.2 {
+		t.Errorf("theta=0.8 has high relative error: %.2f%%", err8*100)
+	}
+}
+
+// TestForceLayout3DEdgeOrder verifies that sorted edges give the same layout however
+// the links were read
+func TestForceLayout3DEdgeOrder(t *testing.T) {
+	N, edges := plantedClusters(3)
+	shuffled := append([]layoutEdge(nil), edges...)
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	for i := range shuffled {
+		if rng.Intn(2) == 0 {
+			shuffled[i] = layoutEdge{shuffled[i].b, shuffled[i].a}
+		}
+	}
+	sortLayoutEdges(edges)
+	sortLayoutEdges(shuffled)
+	if !reflect.DeepEqual(edges, shuffled) {
+		t.Fatal("sorting did not give one order")
+	}
+
+	p := layoutParams{Iterations: 50, Theta: 0.8, Seed: 1}
+	X1, Y1, Z1 := forceLayout3D(N, edges, p)
+	X2, Y2, Z2 := forceLayout3D(N, shuffled, p)
+	if !reflect.DeepEqual(X1, X2) || !reflect.DeepEqual(Y1, Y2) || !reflect.DeepEqual(Z1, Z2) {
+		t.Error("the same links in another order gave a different layout")
+	}
+}
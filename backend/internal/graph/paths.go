@@ -0,0 +1,272 @@
+package graph
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"strings"
+)
+
+// PathEdge is the link between two consecutive nodes of a path, in its stored
+// direction.
+type PathEdge struct {
+	Source string
+	Target string
+	Weight float64
+	Type   string
+}
+
+// Path is a simple path between two nodes.
+type Path struct {
+	Nodes []string
+	Edges []PathEdge
+	Cost  float64
+}
+
+// PathOptions configures KShortestPaths. Unweighted searches count hops;
+// weighted ones cost each link 1/weight, so paths follow strong ties.
+type PathOptions struct {
+	K        int
+	MaxHops  int
+	Weighted bool
+}
+
+// PathGraph is an undirected graph for path searches. Links stored in both
+// directions or with several types are merged into the heaviest one.
+type PathGraph struct {
+	adj    map[string]map[string]PathEdge
+	sorted map[string][]string
+}
+
+// NewPathGraph returns an empty path graph.
+func NewPathGraph() *PathGraph {
+	return &PathGraph{adj: map[string]map[string]PathEdge{}}
+}
+
+// AddNode adds a node without links.
+func (g *PathGraph) AddNode(id string) {
+	if g.adj[id] == nil {
+		g.adj[id] = map[string]PathEdge{}
+	}
+}
+
+// AddLink adds a link between two nodes.
+func (g *PathGraph) AddLink(e PathEdge) {
+	if e.Source == e.Target {
+		return
+	}
+	g.AddNode(e.Source)
+	g.AddNode(e.Target)
+	if cur, ok := g.adj[e.Source][e.Target]; ok && cur.Weight >= e.Weight {
+		return
+	}
+	g.adj[e.Source][e.Target] = e
+	g.adj[e.Target][e.Source] = e
+	g.sorted = nil
+}
+
+// Len returns the number of nodes.
+func (g *PathGraph) Len() int { return len(g.adj) }
+
+// Neighbors returns the nodes linked to id, sorted.
+func (g *PathGraph) Neighbors(id string) []string {
+	if g.sorted == nil {
+		g.sorted = make(map[string][]string, len(g.adj))
+	}
+	if ns, ok := g.sorted[id]; ok {
+		return ns
+	}
+	ns := make([]string, 0, len(g.adj[id]))
+	for n := range g.adj[id] {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	g.sorted[id] = ns
+	return ns
+}
+
+func (o PathOptions) cost(e PathEdge) float64 {
+	if !o.Weighted {
+		return 1
+	}
+	if e.Weight > 0 {
+		return 1 / e.Weight
+	}
+	return 1
+}
+
+type pathLabel struct {
+	node   string
+	hops   int
+	cost   float64
+	parent int
+}
+
+type labelHeap struct {
+	labels []pathLabel
+	idx    []int
+}
+
+func (h *labelHeap) Len() int { return len(h.idx) }
+func (h *labelHeap) Less(i, j int) bool {
+	a, b := h.labels[h.idx[i]], h.labels[h.idx[j]]
+	if a.cost != b.cost {
+		return a.cost < b.cost
+	}
+	if a.hops != b.hops {
+		return a.hops < b.hops
+	}
+	if a.node != b.node {
+		return a.node < b.node
+	}
+	return h.idx[i] < h.idx[j]
+}
+func (h *labelHeap) Swap(i, j int) { h.idx[i], h.idx[j] = h.idx[j], h.idx[i] }
+func (h *labelHeap) Push(x any)    { h.idx = append(h.idx, x.(int)) }
+func (h *labelHeap) Pop() any {
+	n := len(h.idx) - 1
+	x := h.idx[n]
+	h.idx = h.idx[:n]
+	return x
+}
+
+// shortestPath finds the cheapest path of at most maxHops links that avoids
+// the banned nodes and links. It runs Dijkstra over (node, hops) labels: a
+// label is dropped when its node was already reached as cheaply in as few
+// hops, so each node is settled at most maxHops times.
+func (g *PathGraph) shortestPath(ctx context.Context, from, to string, o PathOptions, maxHops int, bannedNodes map[string]bool, bannedLinks map[[2]string]bool) (Path, bool, error) {
+	h := &labelHeap{labels: []pathLabel{{node: from, parent: -1}}, idx: []int{0}}
+	settled := map[string]int{} // fewest hops a node was settled with
+	for pops := 0; h.Len() > 0; pops++ {
+		if pops%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return Path{}, false, err
+			}
+		}
+		li := heap.Pop(h).(int)
+		l := h.labels[li]
+		if hops, ok := settled[l.node]; ok && hops <= l.hops {
+			continue
+		}
+		settled[l.node] = l.hops
+		if l.node == to {
+			return g.tracePath(h.labels, li, o), true, nil
+		}
+		if l.hops >= maxHops {
+			continue
+		}
+		for _, n := range g.Neighbors(l.node) {
+			if bannedNodes[n] || bannedLinks[linkKey(l.node, n)] {
+				continue
+			}
+			if hops, ok := settled[n]; ok && hops <= l.hops+1 {
+				continue
+			}
+			h.labels = append(h.labels, pathLabel{node: n, hops: l.hops + 1, cost: l.cost + o.cost(g.adj[l.node][n]), parent: li})
+			heap.Push(h, len(h.labels)-1)
+		}
+	}
+	return Path{}, false, nil
+}
+
+func (g *PathGraph) tracePath(labels []pathLabel, li int, o PathOptions) Path {
+	var nodes []string
+	for ; li >= 0; li = labels[li].parent {
+		nodes = append(nodes, labels[li].node)
+	}
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return g.pathOf(nodes, o)
+}
+
+func (g *PathGraph) pathOf(nodes []string, o PathOptions) Path {
+	p := Path{Nodes: nodes, Edges: make([]PathEdge, 0, len(nodes)-1)}
+	for i := 1; i < len(nodes); i++ {
+		e := g.adj[nodes[i-1]][nodes[i]]
+		p.Edges = append(p.Edges, e)
+		p.Cost += o.cost(e)
+	}
+	return p
+}
+
+func linkKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// KShortestPaths returns up to K simple paths from one node to another, of at
+// most MaxHops links each, cheapest first (Yen's algorithm). Ties go to the
+// path with fewer hops, then to the lexicographically smaller node sequence.
+func (g *PathGraph) KShortestPaths(ctx context.Context, from, to string, o PathOptions) ([]Path, error) {
+	if o.K <= 0 || from == to || g.adj[from] == nil || g.adj[to] == nil {
+		return nil, nil
+	}
+	first, ok, err := g.shortestPath(ctx, from, to, o, o.MaxHops, nil, nil)
+	if err != nil || !ok {
+		return nil, err
+	}
+	paths := []Path{first}
+	seen := map[string]bool{pathKey(first.Nodes): true}
+	var candidates []Path
+	for len(paths) < o.K {
+		last := paths[len(paths)-1]
+		for i := 0; i < len(last.Nodes)-1; i++ {
+			root := last.Nodes[:i+1]
+			bannedLinks := map[[2]string]bool{}
+			for _, p := range paths {
+				if len(p.Nodes) > i+1 && equalNodes(p.Nodes[:i+1], root) {
+					bannedLinks[linkKey(p.Nodes[i], p.Nodes[i+1])] = true
+				}
+			}
+			bannedNodes := make(map[string]bool, i)
+			for _, n := range root[:i] {
+				bannedNodes[n] = true
+			}
+			spur, ok, err := g.shortestPath(ctx, root[i], to, o, o.MaxHops-i, bannedNodes, bannedLinks)
+			if err != nil {
+				return paths, err
+			}
+			if !ok {
+				continue
+			}
+			nodes := append(append([]string{}, root[:i]...), spur.Nodes...)
+			if key := pathKey(nodes); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, g.pathOf(nodes, o))
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			ca, cb := candidates[a], candidates[b]
+			if ca.Cost != cb.Cost {
+				return ca.Cost < cb.Cost
+			}
+			if len(ca.Nodes) != len(cb.Nodes) {
+				return len(ca.Nodes) < len(cb.Nodes)
+			}
+			return pathKey(ca.Nodes) < pathKey(cb.Nodes)
+		})
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+	return paths, nil
+}
+
+func pathKey(nodes []string) string { return strings.Join(nodes, "\x00") }
+
+func equalNodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func pathGraph(links ...PathEdge) *PathGraph {
+	g := NewPathGraph()
+	for _, l := range links {
+		g.AddLink(l)
+	}
+	return g
+}
+
+func pathNodes(paths []Path) [][]string {
+	out := make([][]string, len(paths))
+	for i, p := range paths {
+		out[i] = p.Nodes
+	}
+	return out
+}
+
+func TestKShortestPaths(t *testing.T) {
+	// a-b-d and a-c-d take two hops, a-e-f-d three; d-a is stored reversed
+	g := pathGraph(
+		PathEdge{Source: "a", Target: "b", Weight: 1},
+		PathEdge{Source: "b", Target: "d", Weight: 1},
+		PathEdge{Source: "a", Target: "c", Weight: 10},
+		PathEdge{Source: "c", Target: "d", Weight: 10},
+		PathEdge{Source: "a", Target: "e", Weight: 1},
+		PathEdge{Source: "e", Target: "f", Weight: 1},
+		PathEdge{Source: "d", Target: "f", Weight: 1},
+	)
+	ctx := context.Background()
+
+	paths, err := g.KShortestPaths(ctx, "a", "d", PathOptions{K: 5, MaxHops: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a", "b", "d"}, {"a", "c", "d"}, {"a", "e", "f", "d"}}
+	if got := pathNodes(paths); !reflect.DeepEqual(got, want) {
+		t.Fatalf("paths = %v, want %v", got, want)
+	}
+	if e := paths[2].Edges[2]; e.Source != "d" || e.Target != "f" {
+		t.Errorf("link keeps its stored direction, got %+v", e)
+	}
+
+	// Weighted, the strong a-c-d ties cost 0.2 against 2 for a-b-d
+	paths, _ = g.KShortestPaths(ctx, "a", "d", PathOptions{K: 2, MaxHops: 4, Weighted: true})
+	want = [][]string{{"a", "c", "d"}, {"a", "b", "d"}}
+	if got := pathNodes(paths); !reflect.DeepEqual(got, want) {
+		t.Fatalf("weighted paths = %v, want %v", got, want)
+	}
+	if !near(paths[0].Cost, 0.2) {
+		t.Errorf("cost = %v, want 0.2", paths[0].Cost)
+	}
+
+	// The hop limit drops the three-hop path
+	paths, _ = g.KShortestPaths(ctx, "a", "d", PathOptions{K: 5, MaxHops: 2})
+	if len(paths) != 2 {
+		t.Errorf("got %d paths within 2 hops, want 2", len(paths))
+	}
+}
+
+func TestShortestPathHopLimitWeighted(t *testing.T) {
+	// The cheapest path takes four hops; within three only the weak direct
+	// link is left
+	g := pathGraph(
+		PathEdge{Source: "a", Target: "z", Weight: 0.1},
+		PathEdge{Source: "a", Target: "b", Weight: 10},
+		PathEdge{Source: "b", Target: "c", Weight: 10},
+		PathEdge{Source: "c", Target: "d", Weight: 10},
+		PathEdge{Source: "d", Target: "z", Weight: 10},
+	)
+	paths, err := g.KShortestPaths(context.Background(), "a", "z", PathOptions{K: 1, MaxHops: 3, Weighted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pathNodes(paths); !reflect.DeepEqual(got, [][]string{{"a", "z"}}) {
+		t.Fatalf("paths = %v", got)
+	}
+	paths, _ = g.KShortestPaths(context.Background(), "a", "z", PathOptions{K: 1, MaxHops: 4, Weighted: true})
+	if len(paths) != 1 || len(paths[0].Nodes) != 5 {
+		t.Fatalf("paths = %v", pathNodes(paths))
+	}
+}
+
+func TestKShortestPathsUnreachable(t *testing.T) {
+	g := pathGraph(PathEdge{Source: "a", Target: "b", Weight: 1}, PathEdge{Source: "c", Target: "d", Weight: 1})
+	paths, err := g.KShortestPaths(context.Background(), "a", "d", PathOptions{K: 3, MaxHops: 5})
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("paths = %v, err = %v", pathNodes(paths), err)
+	}
+}
+
+func TestKShortestPathsCanceled(t *testing.T) {
+	g := pathGraph(PathEdge{Source: "a", Target: "b", Weight: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.KShortestPaths(ctx, "a", "b", PathOptions{K: 1, MaxHops: 2}); err == nil {
+		t.Fatal("expected the canceled context to stop the search")
+	}
+}
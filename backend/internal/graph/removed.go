@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// RemovedContentStore is implemented by stores that know which posts and comments
+// were last seen deleted or removed (*db.Queries does). With PRECALC_EXCLUDE_REMOVED
+// set, precalculation uses it to leave that content out of the graph.
+type RemovedContentStore interface {
+	ListRemovedContent(ctx context.Context, subredditID int32) (db.RemovedContent, error)
+	GetRemovedActivityCounts(ctx context.Context) ([]db.RemovedActivityCount, error)
+}
+
+// removedStore returns the store's RemovedContentStore when removed content is
+// to be excluded, or nil.
+func (s *Service) removedStore() RemovedContentStore {
+	if !config.Load().PrecalcExcludeRemoved {
+		return nil
+	}
+	rs, ok := s.store.(RemovedContentStore)
+	if !ok {
+		log.Printf("ℹ️ PRECALC_EXCLUDE_REMOVED is set but the store does not track removed content")
+		return nil
+	}
+	return rs
+}
+
+// removedActivity returns the removed posts and comments per [user, subreddit],
+// to subtract from activity counts. It is nil when nothing is excluded.
+func (s *Service) removedActivity(ctx context.Context) map[[2]int32]int32 {
+	rs := s.removedStore()
+	if rs == nil {
+		return nil
+	}
+	counts, err := rs.GetRemovedActivityCounts(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to load removed activity; counting removed content: %v", err)
+		return nil
+	}
+	out := make(map[[2]int32]int32, len(counts))
+	for _, c := range counts {
+		out[[2]int32{c.AuthorID, c.SubredditID}] = c.Count
+	}
+	log.Printf("🧹 Excluding removed content of %d user/subreddit pairs from activity", len(out))
+	return out
+}
+
+// removedContent returns the IDs of a subreddit's removed posts and comments. Both
+// sets are nil when nothing is excluded.
+func (s *Service) removedContent(ctx context.Context, subredditID int32) (posts, comments map[string]bool) {
+	rs := s.removedStore()
+	if rs == nil {
+		return nil, nil
+	}
+	removed, err := rs.ListRemovedContent(ctx, subredditID)
+	if err != nil {
+		log.Printf("⚠️ Failed to list removed content of subreddit %d: %v", subredditID, err)
+		return nil, nil
+	}
+	posts = make(map[string]bool, len(removed.PostIDs))
+	for _, id := range removed.PostIDs {
+		posts[id] = true
+	}
+	comments = make(map[string]bool, len(removed.CommentIDs))
+	for _, id := range removed.CommentIDs {
+		comments[id] = true
+	}
+	return posts, comments
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// removedFakeStore marks p1 and c2 as removed.
+type removedFakeStore struct {
+	*fakeStore
+	activity []db.CreateUserSubredditActivityParams
+}
+
+func (f *removedFakeStore) ListRemovedContent(ctx context.Context, subredditID int32) (db.RemovedContent, error) {
+	if subredditID == 1 {
+		return db.RemovedContent{PostIDs: []string{"p1"}}, nil
+	}
+	return db.RemovedContent{CommentIDs: []string{"c2"}}, nil
+}
+
+func (f *removedFakeStore) GetRemovedActivityCounts(ctx context.Context) ([]db.RemovedActivityCount, error) {
+	return []db.RemovedActivityCount{{AuthorID: 10, SubredditID: 1, Count: 1}}, nil
+}
+
+func (f *removedFakeStore) ListCommentsByPost(ctx context.Context, postID string) ([]db.Comment, error) {
+	if postID != "p2" {
+		return nil, nil
+	}
+	return []db.Comment{
+		{ID: "c1", PostID: "p2", AuthorID: 10, SubredditID: 2, Body: sql.NullString{String: "kept", Valid: true}},
+		{ID: "c2", PostID: "p2", AuthorID: 10, SubredditID: 2, Body: sql.NullString{String: "removed", Valid: true}},
+	}, nil
+}
+
+func (f *removedFakeStore) CreateUserSubredditActivity(ctx context.Context, arg db.CreateUserSubredditActivityParams) (db.UserSubredditActivity, error) {
+	f.activity = append(f.activity, arg)
+	return db.UserSubredditActivity{}, nil
+}
+
+func TestPrecalculateGraphData_ExcludeRemoved(t *testing.T) {
+	t.Setenv("DETAILED_GRAPH", "true")
+	t.Setenv("MAX_AUTHOR_CONTENT_LINKS", "0")
+	t.Setenv("PRECALC_EXCLUDE_REMOVED", "true")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	fs := &removedFakeStore{fakeStore: newFakeStore()}
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fs.insertedNodes["post_p1"]; ok {
+		t.Error("removed post p1 should not be a node")
+	}
+	if _, ok := fs.insertedNodes["comment_c2"]; ok {
+		t.Error("removed comment c2 should not be a node")
+	}
+	for _, id := range []string{"post_p2", "comment_c1"} {
+		if _, ok := fs.insertedNodes[id]; !ok {
+			t.Errorf("expected node %s", id)
+		}
+	}
+
+	// The fake's single activity item in r/a is the removed one.
+	if err := NewService(fs).CalculateUserActivity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.activity) != 0 {
+		t.Errorf("removed activity should not be counted, got %+v", fs.activity)
+	}
+
+	t.Setenv("PRECALC_EXCLUDE_REMOVED", "false")
+	config.ResetForTest()
+	fs = &removedFakeStore{fakeStore: newFakeStore()}
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fs.insertedNodes["post_p1"]; !ok {
+		t.Error("removed content is kept unless PRECALC_EXCLUDE_REMOVED is set")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"runtime"
 	"strconv"
@@ -103,6 +102,13 @@ type GraphStore interface {
 	GetPrecalculatedGraphDataCappedAll(ctx context.Context, arg db.GetPrecalculatedGraphDataCappedAllParams) ([]db.GetPrecalculatedGraphDataCappedAllRow, error)
 }
 
+// SubredditRefStore is implemented by stores that keep the subreddits posts mention
+// or were crossposted from (*db.Queries does). Precalculation turns them into
+// mention and crosspost links between subreddits.
+type SubredditRefStore interface {
+	ListSubredditRefLinks(ctx context.Context) ([]db.SubredditRefLink, error)
+}
+
 func NewService(store GraphStore) *Service { return &Service{store: store} }
 
 // truncateUTF8 returns a string with at most max runes, preserving valid UTF-8 boundaries.
@@ -120,7 +126,8 @@ func truncateUTF8(s string, max int) string {
 	return s
 }
 
-// CalculateSubredditRelationships via user activity co-occurrence (incremental upsert)
+// CalculateSubredditRelationships via user activity co-occurrence (incremental upsert).
+// Besides the overlap count, each pair gets the normalized scores of Similarity.
 func (s *Service) CalculateSubredditRelationships(ctx context.Context) error {
 	log.Printf("🔄 Starting subreddit relationship calculation (via co-occurrence)")
 
@@ -139,9 +146,10 @@ func (s *Service) CalculateSubredditRelationships(ctx context.Context) error {
 		perUser[a.UserID] = append(perUser[a.UserID], a.SubredditID)
 	}
 
-	// Count unordered pairs
+	// Count unordered pairs, and the users of each subreddit for normalization
 	type pair struct{ a, b int32 }
 	counts := make(map[pair]int32, 4096)
+	subUsers := make(map[int32]int, 1024)
 	for _, subs := range perUser {
 		if len(subs) == 0 {
 			continue
@@ -152,6 +160,7 @@ func (s *Service) CalculateSubredditRelationships(ctx context.Context) error {
 			if _, ok := seen[id]; !ok {
 				seen[id] = struct{}{}
 				uniq = append(uniq, id)
+				subUsers[id]++
 			}
 		}
 		for i := 0; i < len(uniq); i++ {
@@ -170,12 +179,24 @@ func (s *Service) CalculateSubredditRelationships(ctx context.Context) error {
 		if c <= 0 {
 			continue
 		}
-		if _, err := s.store.CreateSubredditRelationship(ctx, db.CreateSubredditRelationshipParams{SourceSubredditID: p.a, TargetSubredditID: p.b, OverlapCount: c}); err != nil {
+		sim := computeSimilarity(int(c), subUsers[p.a], subUsers[p.b], len(perUser))
+		params := db.CreateSubredditRelationshipParams{
+			SourceSubredditID: p.a,
+			TargetSubredditID: p.b,
+			OverlapCount:      c,
+			Jaccard:           sim.Jaccard,
+			Cosine:            sim.Cosine,
+			Pmi:               sim.PMI,
+			Npmi:              sim.NPMI,
+			Lift:              sim.Lift,
+		}
+		if _, err := s.store.CreateSubredditRelationship(ctx, params); err != nil {
 			log.Printf("⚠️ relationship upsert %d->%d failed: %v", p.a, p.b, err)
 		} else {
 			upserts++
 		}
-		if _, err := s.store.CreateSubredditRelationship(ctx, db.CreateSubredditRelationshipParams{SourceSubredditID: p.b, TargetSubredditID: p.a, OverlapCount: c}); err != nil {
+		params.SourceSubredditID, params.TargetSubredditID = p.b, p.a
+		if _, err := s.store.CreateSubredditRelationship(ctx, params); err != nil {
 			log.Printf("⚠️ relationship upsert %d->%d failed: %v", p.b, p.a, err)
 		} else {
 			upserts++
@@ -218,6 +239,7 @@ func (s *Service) CalculateUserActivity(ctx context.Context) error {
 		workers = 1
 	}
 	log.Printf("⚙️ Calculating activity with %d workers", workers)
+	removed := s.removedActivity(ctx)
 
 	var total int64
 	userCh := make(chan db.GetAllUsersRow, workers*2)
@@ -243,6 +265,7 @@ func (s *Service) CalculateUserActivity(ctx context.Context) error {
 						log.Printf("⚠️ GetUserSubredditActivityCount %s r/%s: %v", u.Username, sr.Name, err)
 						continue
 					}
+					act -= removed[[2]int32{u.ID, sr.ID}]
 					if act <= 0 {
 						continue
 					}
@@ -699,7 +722,11 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 				log.Printf("⚠️ list posts r/%s: %v", sr.Name, err)
 				continue
 			}
+			removedPosts, removedComments := s.removedContent(ctx, sr.ID)
 			for _, p := range posts {
+				if removedPosts[p.ID] {
+					continue
+				}
 				title := strings.TrimSpace(p.Title.String)
 				if title == "" {
 					title = fmt.Sprintf("post %s", p.ID)
@@ -712,7 +739,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 					score = sql.NullString{String: strconv.FormatInt(int64(p.Score.Int32), 10), Valid: true}
 				}
 				pendingNodes = append(pendingNodes, db.BulkInsertGraphNodeParams{ID: fmt.Sprintf("post_%s", p.ID), Name: title, Val: score, Type: sql.NullString{String: "post", Valid: true}})
-				pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("subreddit_%d", sr.ID), Target: fmt.Sprintf("post_%s", p.ID)})
+				pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("subreddit_%d", sr.ID), Target: fmt.Sprintf("post_%s", p.ID), Weight: 1, LinkType: db.LinkTypeContains})
 				postToSub[p.ID] = sr.ID
 				authoredPosts = append(authoredPosts, authoredPost{postID: p.ID, authorID: p.AuthorID})
 
@@ -728,6 +755,9 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 					if count >= commentsPerPost {
 						break
 					}
+					if removedComments[c.ID] {
+						continue
+					}
 					cid := c.ID
 					name := strings.TrimSpace(c.Body.String)
 					if name == "" {
@@ -758,9 +788,9 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 						parentID = parentID[3:]
 					}
 					if strings.HasPrefix(parent, "t1_") && inserted[parentID] {
-						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("comment_%s", parentID), Target: fmt.Sprintf("comment_%s", cid)})
+						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("comment_%s", parentID), Target: fmt.Sprintf("comment_%s", cid), Weight: 1, LinkType: db.LinkTypeReply})
 					} else {
-						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("post_%s", p.ID), Target: fmt.Sprintf("comment_%s", cid)})
+						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("post_%s", p.ID), Target: fmt.Sprintf("comment_%s", cid), Weight: 1, LinkType: db.LinkTypeReply})
 					}
 					flushNodes(false)
 				}
@@ -808,7 +838,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 						if seen[key] {
 							continue
 						}
-						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: srcID, Target: dstID})
+						pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: srcID, Target: dstID, Weight: 1, LinkType: db.LinkTypeSameAuthor})
 						seen[key] = true
 						links++
 						made++
@@ -828,16 +858,41 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 		precalcErr = fmt.Errorf("failed to fetch relationships: %w", err)
 		return precalcErr
 	}
-	relLinks := 0
+	metric, minScore := similarityMetric(cfg)
+	var relLinks []db.BulkInsertGraphLinkParams
+	overlaps := make(map[[2]int32]int64, len(relationships)/2)
 	for _, rel := range relationships {
-		pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("subreddit_%d", rel.SourceSubredditID), Target: fmt.Sprintf("subreddit_%d", rel.TargetSubredditID)})
-		relLinks++
+		score := relationshipScore(rel, metric)
+		if score < minScore {
+			continue
+		}
+		l := db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("subreddit_%d", rel.SourceSubredditID), Target: fmt.Sprintf("subreddit_%d", rel.TargetSubredditID), Weight: score, LinkType: db.LinkTypeSubredditOverlap}
+		pendingLinks = append(pendingLinks, l)
+		relLinks = append(relLinks, l)
+		overlaps[pairKey(rel.SourceSubredditID, rel.TargetSubredditID)] = int64(rel.OverlapCount)
 		if len(pendingLinks)%5000 == 0 {
 			flushLinks(false)
 		}
 	}
 	flushLinks(true)
-	log.Printf("✅ Queued %d subreddit relationship links", relLinks)
+	log.Printf("✅ Queued %d of %d subreddit relationship links (%s >= %g)", len(relLinks), len(relationships), metric, minScore)
+	backbone, alpha := backboneMethod(cfg)
+	if err := s.ExtractBackbone(ctx, relLinks, overlaps, backbone, alpha); err != nil {
+		log.Printf("⚠️ backbone extraction failed: %v", err)
+	}
+
+	// Mentions and crossposts between subreddits -> links
+	if rs, ok := s.store.(SubredditRefStore); ok {
+		refs, err := rs.ListSubredditRefLinks(ctx)
+		if err != nil {
+			log.Printf("⚠️ failed to fetch subreddit references: %v", err)
+		}
+		for _, r := range refs {
+			pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("subreddit_%d", r.SourceSubredditID), Target: fmt.Sprintf("subreddit_%d", r.TargetSubredditID), Weight: float64(r.Posts), LinkType: r.Kind})
+		}
+		flushLinks(true)
+		log.Printf("✅ Queued %d mention and crosspost links", len(refs))
+	}
 
 	// User activity -> links (idempotent)
 	acts, err := s.store.GetAllUserSubredditActivity(ctx)
@@ -847,7 +902,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 	}
 	actLinks := 0
 	for _, a := range acts {
-		pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", a.UserID), Target: fmt.Sprintf("subreddit_%d", a.SubredditID)})
+		pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", a.UserID), Target: fmt.Sprintf("subreddit_%d", a.SubredditID), Weight: float64(a.ActivityCount), LinkType: db.LinkTypeUserActivity})
 		actLinks++
 		if len(pendingLinks)%10000 == 0 {
 			flushLinks(false)
@@ -859,7 +914,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 	if detailed {
 		upost := 0
 		for _, ap := range authoredPosts {
-			pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", ap.authorID), Target: fmt.Sprintf("post_%s", ap.postID)})
+			pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", ap.authorID), Target: fmt.Sprintf("post_%s", ap.postID), Weight: 1, LinkType: db.LinkTypeAuthored})
 			upost++
 			if len(pendingLinks)%10000 == 0 {
 				flushLinks(false)
@@ -867,7 +922,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 		}
 		ucom := 0
 		for _, ac := range authoredComments {
-			pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", ac.authorID), Target: fmt.Sprintf("comment_%s", ac.commentID)})
+			pendingLinks = append(pendingLinks, db.BulkInsertGraphLinkParams{Source: fmt.Sprintf("user_%d", ac.authorID), Target: fmt.Sprintf("comment_%s", ac.commentID), Weight: 1, LinkType: db.LinkTypeAuthored})
 			ucom++
 			if len(pendingLinks)%10000 == 0 {
 				flushLinks(false)
@@ -880,6 +935,10 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 
 	log.Printf("🎉 Graph data precalculation completed successfully")
 
+	if err := s.computeAndStoreCentrality(ctx); err != nil {
+		log.Printf("⚠️ centrality computation failed: %v", err)
+	}
+
 	// Run hierarchical community detection and store results
 	if queries, ok := s.store.(*db.Queries); ok {
 		// Fetch nodes and links for community detection
@@ -898,7 +957,8 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 				log.Printf("⚠️ failed to fetch links for community detection: %v", err)
 			} else {
 				// Run hierarchical community detection
-				hierarchy, err := s.detectHierarchicalCommunities(ctx, queries, nodes, links)
+				params := LoadCommunityParams(ctx, queries)
+				hierarchy, err := s.detectHierarchicalCommunities(ctx, queries, nodes, links, params)
 				if err != nil {
 					log.Printf("⚠️ hierarchical community detection failed: %v", err)
 				} else if err := s.storeHierarchy(ctx, queries, hierarchy); err != nil {
@@ -906,7 +966,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 				}
 
 				// Also run flat community detection for backward compatibility (reuse same nodes/links)
-				if result, err := s.detectCommunitiesFromData(nodes, links); err != nil {
+				if result, err := s.detectCommunitiesWithParams(nodes, links, params); err != nil {
 					log.Printf("⚠️ community detection failed: %v", err)
 				} else if nodeToCommunity, err := s.storeCommunities(ctx, queries, result, nodes, links); err != nil {
 					log.Printf("⚠️ failed to store communities: %v", err)
@@ -922,7 +982,7 @@ func (s *Service) PrecalculateGraphDataWithMode(ctx context.Context, fullRebuild
 		log.Printf("ℹ️ community detection skipped: store is not *db.Queries")
 	}
 
-	// Optional: compute and store a simple 2D layout for faster client rendering
+	// Optional: compute and store a 3D layout for faster client rendering
 	if err := s.computeAndStoreLayout(ctx); err != nil {
 		log.Printf("⚠️ layout computation failed: %v", err)
 	}
@@ -1024,9 +1084,9 @@ func (s *Service) checkPositionColumnsExist(ctx context.Context, queries *db.Que
 	return true
 }
 
-// computeAndStoreLayout calculates a simple force-directed 2D layout for a capped set of nodes
-// and persists positions into graph_nodes.pos_x/pos_y (pos_z set to 0). It is best-effort and
-// bounded to avoid heavy CPU load.
+// computeAndStoreLayout calculates a force-directed 3D layout for a capped set of nodes and
+// persists positions into graph_nodes.pos_x/pos_y/pos_z. It is best-effort and bounded to
+// avoid heavy CPU load; the same graph and LAYOUT_SEED always give the same positions.
 func (s *Service) computeAndStoreLayout(ctx context.Context) error {
 	layoutStart := time.Now()
 
@@ -1053,7 +1113,7 @@ func (s *Service) computeAndStoreLayout(ctx context.Context) error {
 	epsilon := cfg.LayoutEpsilon
 	theta := cfg.LayoutTheta
 
-	log.Printf("⚙️ layout configuration: max_nodes=%d, iterations=%d, batch_size=%d, epsilon=%.2f, theta=%.2f", maxNodes, iterations, batchSize, epsilon, theta)
+	log.Printf("⚙️ layout configuration: max_nodes=%d, iterations=%d, batch_size=%d, epsilon=%.2f, theta=%.2f, seed=%d, workers=%d", maxNodes, iterations, batchSize, epsilon, theta, cfg.LayoutSeed, cfg.LayoutWorkers)
 
 	if maxNodes <= 0 || iterations <= 0 {
 		log.Printf("ℹ️ layout computation disabled via configuration")
@@ -1087,21 +1147,9 @@ func (s *Service) computeAndStoreLayout(ctx context.Context) error {
 		idx[n.ID] = i
 	}
 
-	// Initialize positions in a circle to reduce initial clashes
-	N := len(nodes)
-	X := make([]float64, N)
-	Y := make([]float64, N)
-	Z := make([]float64, N)
-	R := 200.0 * math.Sqrt(float64(N)/1000.0+1)
-	for i := 0; i < N; i++ {
-		a := 2 * math.Pi * float64(i) / float64(N)
-		X[i] = R * math.Cos(a)
-		Y[i] = R * math.Sin(a)
-		Z[i] = 0
-	}
 	// Build adjacency
-	type edge struct{ a, b int }
-	E := make([]edge, 0, len(links))
+	N := len(nodes)
+	E := make([]layoutEdge, 0, len(links))
 	seen := make(map[[2]int]struct{}, len(links))
 	for _, l := range links {
 		ia, okA := idx[l.Source]
@@ -1114,77 +1162,17 @@ func (s *Service) computeAndStoreLayout(ctx context.Context) error {
 			continue
 		}
 		seen[key] = struct{}{}
-		E = append(E, edge{a: ia, b: ib})
+		E = append(E, layoutEdge{a: ia, b: ib})
 	}
 	if len(E) == 0 {
 		log.Printf("⚠️ no edges found; skipping force-directed layout")
 		return nil
 	}
-	log.Printf("🌐 initialized layout: %d nodes, %d edges, radius=%.1f", N, len(E), R)
-
-	// Simple FR-like dynamics
-	area := R * R
-	k := math.Sqrt(area / float64(N))
-	cool := R / float64(iterations)
-	dispX := make([]float64, N)
-	dispY := make([]float64, N)
-	repX := make([]float64, N) // Reusable buffer for Barnes-Hut forces
-	repY := make([]float64, N)
-	var attr = func(dist float64) float64 { return (dist * dist) / k }
+	sortLayoutEdges(E)
+	log.Printf("🌐 initialized layout: %d nodes, %d edges", N, len(E))
 
 	layoutComputeStart := time.Now()
-	for it := 0; it < iterations; it++ {
-		for i := 0; i < N; i++ {
-			dispX[i], dispY[i] = 0, 0
-		}
-
-		// Use Barnes-Hut for O(n log n) repulsive forces (writes into repX, repY)
-		repStrength := k * k
-		calculateBarnesHutForces(X, Y, repX, repY, theta, repStrength)
-		for i := 0; i < N; i++ {
-			dispX[i] += repX[i]
-			dispY[i] += repY[i]
-		}
-
-		// Attractive forces along edges (still O(E))
-		for _, e := range E {
-			dx := X[e.a] - X[e.b]
-			dy := Y[e.a] - Y[e.b]
-			dist := math.Hypot(dx, dy)
-			if dist < 1e-6 {
-				dx, dy, dist = (randFloat() - 0.5), (randFloat() - 0.5), 1
-			}
-			force := attr(dist)
-			ax := dx / dist * force
-			ay := dy / dist * force
-			dispX[e.a] -= ax
-			dispY[e.a] -= ay
-			dispX[e.b] += ax
-			dispY[e.b] += ay
-		}
-		// limit max displacement (temperature)
-		temp := R - float64(it)*cool
-		for v := 0; v < N; v++ {
-			dx := dispX[v]
-			dy := dispY[v]
-			disp := math.Hypot(dx, dy)
-			if disp > 0 {
-				X[v] += dx / disp * math.Min(disp, temp)
-				Y[v] += dy / disp * math.Min(disp, temp)
-			}
-			// prevent blow-up
-			if X[v] > 1e6 {
-				X[v] = 1e6
-			} else if X[v] < -1e6 {
-				X[v] = -1e6
-			}
-			if Y[v] > 1e6 {
-				Y[v] = 1e6
-			} else if Y[v] < -1e6 {
-				Y[v] = -1e6
-			}
-		}
-	}
+	X, Y, Z := forceLayout3D(N, E, layoutParams{Iterations: iterations, Theta: theta, Seed: cfg.LayoutSeed, Workers: cfg.LayoutWorkers})
 	layoutComputeDuration := time.Since(layoutComputeStart)
 	log.Printf("⏱️ layout computation completed in %s", layoutComputeDuration.Truncate(time.Millisecond))
 
@@ -1208,13 +1196,3 @@ func max(a, b int) int {
 	}
 	return b
 }
-
-// simple random float in [0,1); no global rng dependency to avoid races
-func randFloat() float64 {
-	// Xorshift-based tiny RNG
-	var x uint64 = uint64(time.Now().UnixNano())
-	x ^= x << 13
-	x ^= x >> 7
-	x ^= x << 17
-	return float64(x%1_000_000) / 1_000_000.0
-}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"log"
+	"math"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Similarity metrics of subreddit relationships, selected with
+// PRECALC_SIMILARITY_METRIC.
+const (
+	MetricOverlap = "overlap" // users active in both subreddits
+	MetricJaccard = "jaccard"
+	MetricCosine  = "cosine"
+	MetricPMI     = "pmi"
+	MetricNPMI    = "npmi"
+	MetricLift    = "lift"
+)
+
+// Similarity holds the normalized scores of a subreddit pair.
+type Similarity struct {
+	Jaccard float64 // share of the union of both user sets that is in both
+	Cosine  float64 // overlap over the geometric mean of the set sizes
+	PMI     float64 // ln(Lift); 0 when the subreddits are independent
+	NPMI    float64 // PMI scaled to [-1, 1]
+	Lift    float64 // observed overlap over the overlap expected by chance
+}
+
+// computeSimilarity scores a pair of subreddits with usersA and usersB active
+// users, overlap of them shared, out of totalUsers users overall.
+func computeSimilarity(overlap, usersA, usersB, totalUsers int) Similarity {
+	if overlap <= 0 || usersA <= 0 || usersB <= 0 || totalUsers <= 0 {
+		return Similarity{}
+	}
+	c, a, b, n := float64(overlap), float64(usersA), float64(usersB), float64(totalUsers)
+	s := Similarity{
+		Jaccard: c / (a + b - c),
+		Cosine:  c / math.Sqrt(a*b),
+		Lift:    c * n / (a * b),
+	}
+	s.PMI = math.Log(s.Lift)
+	if pJoint := c / n; pJoint < 1 {
+		s.NPMI = s.PMI / -math.Log(pJoint)
+	} else {
+		// Every user is in both subreddits: perfect co-occurrence
+		s.NPMI = 1
+	}
+	return s
+}
+
+// relationshipScore is the score of a stored relationship under a metric.
+func relationshipScore(rel db.GetAllSubredditRelationshipsRow, metric string) float64 {
+	switch metric {
+	case MetricJaccard:
+		return rel.Jaccard
+	case MetricCosine:
+		return rel.Cosine
+	case MetricPMI:
+		return rel.Pmi
+	case MetricNPMI:
+		return rel.Npmi
+	case MetricLift:
+		return rel.Lift
+	}
+	return float64(rel.OverlapCount)
+}
+
+// similarityMetric returns the configured metric and the minimum score a
+// relationship needs to become a link. Link weights are non-negative, so the
+// minimum is never below 0.
+func similarityMetric(cfg *config.Config) (string, float64) {
+	metric := cfg.PrecalcSimilarityMetric
+	switch metric {
+	case MetricOverlap, MetricJaccard, MetricCosine, MetricPMI, MetricNPMI, MetricLift:
+	case "":
+		metric = MetricOverlap
+	default:
+		log.Printf("⚠️ Unknown PRECALC_SIMILARITY_METRIC %q; using %s", metric, MetricOverlap)
+		metric = MetricOverlap
+	}
+	return metric, math.Max(cfg.PrecalcSimilarityMin, 0)
+}
@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/config"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestComputeSimilarity(t *testing.T) {
+	// 10 users: 4 in A, 5 in B, 2 in both
+	s := computeSimilarity(2, 4, 5, 10)
+	if !near(s.Jaccard, 2.0/7) || !near(s.Cosine, 2/math.Sqrt(20)) || !near(s.Lift, 1) {
+		t.Errorf("unexpected scores %+v", s)
+	}
+	if !near(s.PMI, 0) || !near(s.NPMI, 0) {
+		t.Errorf("independent subreddits should have zero PMI, got %+v", s)
+	}
+
+	// Always together: NPMI is 1 whatever the share of users
+	s = computeSimilarity(3, 3, 3, 12)
+	if !near(s.Jaccard, 1) || !near(s.Cosine, 1) || !near(s.NPMI, 1) || !near(s.PMI, math.Log(4)) {
+		t.Errorf("unexpected scores for identical user sets %+v", s)
+	}
+	if s = computeSimilarity(1, 1, 1, 1); !near(s.NPMI, 1) || !near(s.PMI, 0) {
+		t.Errorf("single user: %+v", s)
+	}
+	if s = computeSimilarity(0, 4, 5, 10); s != (Similarity{}) {
+		t.Errorf("no overlap should score zero, got %+v", s)
+	}
+}
+
+// similarityFakeStore has a big subreddit 1 that shares a user with both small
+// subreddits 2 and 3, which share all their users with each other.
+type similarityFakeStore struct {
+	*fakeStore
+	relationships []db.CreateSubredditRelationshipParams
+	links         []db.BulkInsertGraphLinkParams
+}
+
+func (f *similarityFakeStore) GetAllUserSubredditActivity(ctx context.Context) ([]db.GetAllUserSubredditActivityRow, error) {
+	rows := []db.GetAllUserSubredditActivityRow{
+		{UserID: 1, SubredditID: 1}, {UserID: 1, SubredditID: 2}, {UserID: 1, SubredditID: 3},
+		{UserID: 2, SubredditID: 2}, {UserID: 2, SubredditID: 3},
+	}
+	for u := int32(3); u <= 10; u++ {
+		rows = append(rows, db.GetAllUserSubredditActivityRow{UserID: u, SubredditID: 1})
+	}
+	return rows, nil
+}
+
+func (f *similarityFakeStore) CreateSubredditRelationship(ctx context.Context, arg db.CreateSubredditRelationshipParams) (db.SubredditRelationship, error) {
+	f.relationships = append(f.relationships, arg)
+	return db.SubredditRelationship{}, nil
+}
+
+func (f *similarityFakeStore) GetAllSubredditRelationships(ctx context.Context) ([]db.GetAllSubredditRelationshipsRow, error) {
+	rows := make([]db.GetAllSubredditRelationshipsRow, 0, len(f.relationships))
+	for _, r := range f.relationships {
+		rows = append(rows, db.GetAllSubredditRelationshipsRow{
+			SourceSubredditID: r.SourceSubredditID, TargetSubredditID: r.TargetSubredditID, OverlapCount: r.OverlapCount,
+			Jaccard: r.Jaccard, Cosine: r.Cosine, Pmi: r.Pmi, Npmi: r.Npmi, Lift: r.Lift,
+		})
+	}
+	return rows, nil
+}
+
+func (f *similarityFakeStore) BulkInsertGraphLink(ctx context.Context, arg db.BulkInsertGraphLinkParams) error {
+	f.links = append(f.links, arg)
+	return f.fakeStore.BulkInsertGraphLink(ctx, arg)
+}
+
+// relationshipLinks returns the weights of the subreddit links by source and target.
+func (f *similarityFakeStore) relationshipLinks() map[[2]string]float64 {
+	out := map[[2]string]float64{}
+	for _, l := range f.links {
+		if l.LinkType == db.LinkTypeSubredditOverlap {
+			out[[2]string{l.Source, l.Target}] = l.Weight
+		}
+	}
+	return out
+}
+
+func TestCalculateSubredditRelationships_Similarity(t *testing.T) {
+	fs := &similarityFakeStore{fakeStore: newFakeStore()}
+	if err := NewService(fs).CalculateSubredditRelationships(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.relationships) != 6 {
+		t.Fatalf("expected 3 pairs in both directions, got %d", len(fs.relationships))
+	}
+	for _, r := range fs.relationships {
+		big := r.SourceSubredditID == 1 || r.TargetSubredditID == 1
+		switch {
+		case big && (r.OverlapCount != 1 || !near(r.Jaccard, 1.0/10) || r.Npmi >= 0):
+			t.Errorf("big subreddit pair should be weak: %+v", r)
+		case !big && (r.OverlapCount != 2 || !near(r.Jaccard, 1) || !near(r.Npmi, 1) || !near(r.Lift, 5)):
+			t.Errorf("small subreddit pair should be strong: %+v", r)
+		}
+	}
+}
+
+func TestPrecalculateGraphData_SimilarityMetric(t *testing.T) {
+	t.Setenv("DETAILED_GRAPH", "false")
+	t.Setenv("PRECALC_SIMILARITY_METRIC", "npmi")
+	t.Setenv("PRECALC_SIMILARITY_MIN", "0.1")
+	config.ResetForTest()
+	t.Cleanup(config.ResetForTest)
+
+	fs := &similarityFakeStore{fakeStore: newFakeStore()}
+	if err := NewService(fs).CalculateSubredditRelationships(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	links := fs.relationshipLinks()
+	if len(links) != 2 || !near(links[[2]string{"subreddit_2", "subreddit_3"}], 1) {
+		t.Errorf("expected only the small pair weighted by NPMI, got %v", links)
+	}
+
+	// The default keeps every pair, weighted by its overlap count
+	t.Setenv("PRECALC_SIMILARITY_METRIC", "")
+	t.Setenv("PRECALC_SIMILARITY_MIN", "")
+	config.ResetForTest()
+	fs.links = nil
+	if err := NewService(fs).PrecalculateGraphData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	links = fs.relationshipLinks()
+	if len(links) != 6 || links[[2]string{"subreddit_1", "subreddit_2"}] != 1 || links[[2]string{"subreddit_3", "subreddit_2"}] != 2 {
+		t.Errorf("unexpected overlap links %v", links)
+	}
+}
@@ -142,8 +142,8 @@ func TestIntegration_SpatialQueries_BoundingBox(t *testing.T) {
 	t.Run("GetLinksForNodesInBoundingBox", func(t *testing.T) {
 		// Create test links between nodes
 		_, err = conn.ExecContext(ctx,
-			`INSERT INTO graph_links (source, target) VALUES ($1, $2), ($3, $4)
-			 ON CONFLICT (source, target) DO NOTHING`,
+			`INSERT INTO graph_links (source, target, weight, link_type) VALUES ($1, $2, 5, 'mention'), ($3, $4, 1, 'reply')
+			 ON CONFLICT (source, target, link_type) DO NOTHING`,
 			"node_1", "node_2", "node_2", "node_3")
 		if err != nil {
 			t.Fatalf("failed to insert test links: %v", err)
@@ -171,6 +171,32 @@ func TestIntegration_SpatialQueries_BoundingBox(t *testing.T) {
 		if len(links) != 2 {
 			t.Errorf("expected 2 links, got %d", len(links))
 		}
+
+		// The weight and type filters keep one link each
+		heavy, err := q.GetLinksForNodesInBoundingBox(ctx, db.GetLinksForNodesInBoundingBoxParams{
+			PosX: sql.NullFloat64{Float64: -1.0, Valid: true}, PosX_2: sql.NullFloat64{Float64: 11.0, Valid: true},
+			PosY: sql.NullFloat64{Float64: -1.0, Valid: true}, PosY_2: sql.NullFloat64{Float64: 11.0, Valid: true},
+			PosZ: sql.NullFloat64{Float64: -1.0, Valid: true}, PosZ_2: sql.NullFloat64{Float64: 1.0, Valid: true},
+			Limit: 100, Column8: 2,
+		})
+		if err != nil {
+			t.Fatalf("GetLinksForNodesInBoundingBox (min weight) failed: %v", err)
+		}
+		if len(heavy) != 1 || heavy[0].LinkType != "mention" || heavy[0].Weight != 5 {
+			t.Errorf("expected the mention link of weight 5, got %+v", heavy)
+		}
+		replies, err := q.GetLinksForNodesInBoundingBox(ctx, db.GetLinksForNodesInBoundingBoxParams{
+			PosX: sql.NullFloat64{Float64: -1.0, Valid: true}, PosX_2: sql.NullFloat64{Float64: 11.0, Valid: true},
+			PosY: sql.NullFloat64{Float64: -1.0, Valid: true}, PosY_2: sql.NullFloat64{Float64: 11.0, Valid: true},
+			PosZ: sql.NullFloat64{Float64: -1.0, Valid: true}, PosZ_2: sql.NullFloat64{Float64: 1.0, Valid: true},
+			Limit: 100, Column9: []string{"reply"},
+		})
+		if err != nil {
+			t.Fatalf("GetLinksForNodesInBoundingBox (link types) failed: %v", err)
+		}
+		if len(replies) != 1 || replies[0].Source != "node_2" {
+			t.Errorf("expected the node_2 reply link, got %+v", replies)
+		}
 	})
 }
 
@@ -0,0 +1,104 @@
+package graphstore
+
+import "math"
+
+// grid is a uniform 3D grid over the positioned nodes, with the nodes of each
+// cell stored contiguously.
+type grid struct {
+	min   [3]float64
+	cell  [3]float64
+	dims  [3]int
+	start []int32 // nodes of cell c are nodes[start[c]:start[c+1]]
+	nodes []int32
+}
+
+// Target number of nodes per cell, and the cap on cells per axis
+const (
+	gridCellNodes = 8
+	gridMaxDim    = 256
+)
+
+func newGrid(hasPos []bool, pos [][3]float64) *grid {
+	g := &grid{}
+	count := 0
+	lo := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	hi := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for i, ok := range hasPos {
+		if !ok {
+			continue
+		}
+		count++
+		for a := 0; a < 3; a++ {
+			lo[a] = math.Min(lo[a], pos[i][a])
+			hi[a] = math.Max(hi[a], pos[i][a])
+		}
+	}
+	if count == 0 {
+		return g
+	}
+	dim := int(math.Ceil(math.Cbrt(float64(count) / gridCellNodes)))
+	dim = max(1, min(dim, gridMaxDim))
+	g.min = lo
+	for a := 0; a < 3; a++ {
+		g.dims[a] = dim
+		g.cell[a] = (hi[a] - lo[a]) / float64(dim)
+		if !(g.cell[a] > 0) || math.IsInf(g.cell[a], 0) {
+			g.dims[a], g.cell[a] = 1, 0
+		}
+	}
+
+	cells := make([]int32, len(hasPos))
+	g.start = make([]int32, g.dims[0]*g.dims[1]*g.dims[2]+1)
+	for i, ok := range hasPos {
+		if ok {
+			cells[i] = int32(g.cellOf(pos[i]))
+			g.start[cells[i]+1]++
+		}
+	}
+	for c := 1; c < len(g.start); c++ {
+		g.start[c] += g.start[c-1]
+	}
+	g.nodes = make([]int32, count)
+	fill := append([]int32(nil), g.start...)
+	for i, ok := range hasPos {
+		if ok {
+			g.nodes[fill[cells[i]]] = int32(i)
+			fill[cells[i]]++
+		}
+	}
+	return g
+}
+
+func (g *grid) axisCell(a int, v float64) int {
+	if g.cell[a] == 0 {
+		return 0
+	}
+	c := int(math.Floor((v - g.min[a]) / g.cell[a]))
+	return max(0, min(c, g.dims[a]-1))
+}
+
+func (g *grid) cellOf(p [3]float64) int {
+	return (g.axisCell(0, p[0])*g.dims[1]+g.axisCell(1, p[1]))*g.dims[2] + g.axisCell(2, p[2])
+}
+
+// query calls fn with the nodes of every cell overlapping the box; callers
+// check the exact bounds.
+func (g *grid) query(lo, hi [3]float64, fn func(i int32)) {
+	if len(g.nodes) == 0 {
+		return
+	}
+	var from, to [3]int
+	for a := 0; a < 3; a++ {
+		from[a], to[a] = g.axisCell(a, lo[a]), g.axisCell(a, hi[a])
+	}
+	for x := from[0]; x <= to[0]; x++ {
+		for y := from[1]; y <= to[1]; y++ {
+			for z := from[2]; z <= to[2]; z++ {
+				c := (x*g.dims[1]+y)*g.dims[2] + z
+				for _, i := range g.nodes[g.start[c]:g.start[c+1]] {
+					fn(i)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package graphstore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// TestIntegration_ReadGraphSnapshot checks that the reads of a refresh do not
+// see nodes committed by another connection after the first read.
+func TestIntegration_ReadGraphSnapshot(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+		return
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ctx := context.Background()
+	const id = "graphstore_snapshot_test"
+	cleanup := func() { _, _ = conn.ExecContext(ctx, `DELETE FROM graph_nodes WHERE id = $1`, id) }
+	cleanup()
+	t.Cleanup(cleanup)
+
+	err = DBSource(db.New(conn)).ReadGraphSnapshot(ctx, func(r SnapshotReader) error {
+		if _, err := r.GetCurrentGraphVersion(ctx); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// A concurrent writer commits a node after the snapshot started
+		if _, err := conn.ExecContext(ctx, `INSERT INTO graph_nodes (id, name, val, type) VALUES ($1, $1, '1', 'subreddit')`, id); err != nil {
+			return err
+		}
+		nodes, err := r.ListGraphSnapshotNodes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			if n.ID == id {
+				t.Error("the snapshot saw a node committed after it started")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
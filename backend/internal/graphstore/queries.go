@@ -0,0 +1,220 @@
+package graphstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// GetNodeNeighbors returns the neighbors of a node with the most links to it,
+// as (*db.Queries).GetNodeNeighbors does.
+func (s *Snapshot) GetNodeNeighbors(ctx context.Context, arg db.GetNodeNeighborsParams) ([]db.GetNodeNeighborsRow, error) {
+	i, ok := s.index[arg.Source]
+	if !ok {
+		return []db.GetNodeNeighborsRow{}, nil
+	}
+	count := map[int32]int32{}
+	for _, li := range s.links(i) {
+		if s.src[li] == i {
+			count[s.dst[li]]++
+		}
+		if s.dst[li] == i {
+			count[s.src[li]]++
+		}
+	}
+	nis := make([]int32, 0, len(count))
+	for n := range count {
+		nis = append(nis, n)
+	}
+	sort.Slice(nis, func(a, b int) bool {
+		if count[nis[a]] != count[nis[b]] {
+			return count[nis[a]] > count[nis[b]]
+		}
+		return s.ids[nis[a]] < s.ids[nis[b]]
+	})
+	if arg.Limit >= 0 && len(nis) > int(arg.Limit) {
+		nis = nis[:arg.Limit]
+	}
+	out := make([]db.GetNodeNeighborsRow, len(nis))
+	for k, n := range nis {
+		out[k] = db.GetNodeNeighborsRow{ID: s.ids[n], Name: s.names[n], Val: s.vals[n].String, Type: s.types[n], Degree: count[n]}
+	}
+	return out, nil
+}
+
+// ListGraphNodesByIDs returns the nodes with the given IDs.
+func (s *Snapshot) ListGraphNodesByIDs(ctx context.Context, ids []string) ([]db.GraphNodeSummary, error) {
+	var out []db.GraphNodeSummary
+	seen := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		i, ok := s.index[id]
+		if !ok || seen[i] {
+			continue
+		}
+		seen[i] = true
+		n := db.GraphNodeSummary{ID: id, Name: s.names[i], Val: s.vals[i].String, Type: s.types[i].String}
+		if s.hasPos[i] {
+			n.PosX, n.PosY, n.PosZ = nullFloat(s.pos[i][0]), nullFloat(s.pos[i][1]), nullFloat(s.pos[i][2])
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// ListGraphNeighbors returns the links of the given nodes in both directions.
+func (s *Snapshot) ListGraphNeighbors(ctx context.Context, arg db.ListGraphNeighborsParams) ([]db.GraphNeighbor, error) {
+	f := s.newLinkFilter(arg.MinWeight, arg.LinkTypes, arg.MaxPValue)
+	var allowed map[string]bool
+	if len(arg.NodeTypes) > 0 {
+		allowed = map[string]bool{}
+		for _, t := range arg.NodeTypes {
+			allowed[t] = true
+		}
+	}
+	endpoints := s.nodeSet(arg.Endpoints)
+	var out []db.GraphNeighbor
+	seen := make(map[int32]bool, len(arg.IDs))
+	for _, id := range arg.IDs {
+		i, ok := s.index[id]
+		if !ok || seen[i] {
+			continue
+		}
+		seen[i] = true
+		for _, li := range s.links(i) {
+			other := s.dst[li]
+			if s.src[li] != i {
+				other = s.src[li]
+			}
+			t := s.types[other]
+			if allowed != nil && !(t.Valid && allowed[t.String]) && !endpoints[other] {
+				continue
+			}
+			if !s.allows(f, li) {
+				continue
+			}
+			out = append(out, db.GraphNeighbor{
+				Source:    s.ids[s.src[li]],
+				Target:    s.ids[s.dst[li]],
+				Weight:    s.weights[li],
+				LinkType:  s.typeNames[s.linkTypes[li]],
+				OtherType: t.String,
+			})
+		}
+	}
+	return out, nil
+}
+
+// ListGraphLinksAmongFiltered returns the heaviest link of each pair of the
+// given nodes that passes the link filter, heaviest first.
+func (s *Snapshot) ListGraphLinksAmongFiltered(ctx context.Context, arg db.ListGraphLinksAmongFilteredParams) ([]db.ListGraphLinksAmongRow, error) {
+	in := s.nodeSet(arg.IDs)
+	f := s.newLinkFilter(arg.MinWeight, arg.LinkTypes, arg.MaxPValue)
+	lis := s.heaviestPerPair(s.linksAmong(in, f))
+	out := make([]db.ListGraphLinksAmongRow, len(lis))
+	for k, li := range lis {
+		out[k] = db.ListGraphLinksAmongRow{Source: s.ids[s.src[li]], Target: s.ids[s.dst[li]], Weight: s.weights[li], LinkType: s.typeNames[s.linkTypes[li]]}
+	}
+	return out, nil
+}
+
+// GetNodesInBoundingBox returns the positioned nodes inside a box, highest val
+// first.
+func (s *Snapshot) GetNodesInBoundingBox(ctx context.Context, arg db.GetNodesInBoundingBoxParams) ([]db.GetNodesInBoundingBoxRow, error) {
+	nis := s.nodesInBox(arg.PosX.Float64, arg.PosX_2.Float64, arg.PosY.Float64, arg.PosY_2.Float64, arg.PosZ.Float64, arg.PosZ_2.Float64)
+	s.sortNodes(nis)
+	if arg.Limit >= 0 && len(nis) > int(arg.Limit) {
+		nis = nis[:arg.Limit]
+	}
+	out := make([]db.GetNodesInBoundingBoxRow, len(nis))
+	for k, i := range nis {
+		out[k] = db.GetNodesInBoundingBoxRow{
+			ID:   s.ids[i],
+			Name: s.names[i],
+			Val:  s.vals[i],
+			Type: s.types[i],
+			PosX: nullFloat(s.pos[i][0]),
+			PosY: nullFloat(s.pos[i][1]),
+			PosZ: nullFloat(s.pos[i][2]),
+		}
+	}
+	return out, nil
+}
+
+// GetLinksForNodesInBoundingBox returns the heaviest link of each node pair
+// with both ends inside a box that passes the link filter, heaviest first.
+func (s *Snapshot) GetLinksForNodesInBoundingBox(ctx context.Context, arg db.GetLinksForNodesInBoundingBoxParams) ([]db.GetLinksForNodesInBoundingBoxRow, error) {
+	nis := s.nodesInBox(arg.PosX.Float64, arg.PosX_2.Float64, arg.PosY.Float64, arg.PosY_2.Float64, arg.PosZ.Float64, arg.PosZ_2.Float64)
+	in := make(map[int32]bool, len(nis))
+	for _, i := range nis {
+		in[i] = true
+	}
+	lis := s.heaviestPerPair(s.linksAmong(in, s.newLinkFilter(arg.Column8, arg.Column9, arg.Column10)))
+	if arg.Limit >= 0 && len(lis) > int(arg.Limit) {
+		lis = lis[:arg.Limit]
+	}
+	out := make([]db.GetLinksForNodesInBoundingBoxRow, len(lis))
+	for k, li := range lis {
+		out[k] = db.GetLinksForNodesInBoundingBoxRow{
+			ID:       s.linkIDs[li],
+			Source:   s.ids[s.src[li]],
+			Target:   s.ids[s.dst[li]],
+			Weight:   s.weights[li],
+			LinkType: s.typeNames[s.linkTypes[li]],
+		}
+	}
+	return out, nil
+}
+
+func (s *Snapshot) nodeSet(ids []string) map[int32]bool {
+	in := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		if i, ok := s.index[id]; ok {
+			in[i] = true
+		}
+	}
+	return in
+}
+
+// linksAmong returns the links between nodes of the set that pass the filter,
+// sorted by sortLinks.
+func (s *Snapshot) linksAmong(in map[int32]bool, f linkFilter) []int32 {
+	var lis []int32
+	for i := range in {
+		for _, li := range s.links(i) {
+			// Each link once, from its source
+			if s.src[li] == i && in[s.dst[li]] && s.allows(f, li) {
+				lis = append(lis, li)
+			}
+		}
+	}
+	s.sortLinks(lis)
+	return lis
+}
+
+// heaviestPerPair keeps the first link of each (source, target) pair of links
+// sorted by sortLinks, which is the heaviest of the pair's types.
+func (s *Snapshot) heaviestPerPair(lis []int32) []int32 {
+	seen := make(map[[2]int32]bool, len(lis))
+	out := lis[:0]
+	for _, li := range lis {
+		pair := [2]int32{s.src[li], s.dst[li]}
+		if !seen[pair] {
+			seen[pair] = true
+			out = append(out, li)
+		}
+	}
+	return out
+}
+
+func (s *Snapshot) nodesInBox(xMin, xMax, yMin, yMax, zMin, zMax float64) []int32 {
+	var nis []int32
+	lo, hi := [3]float64{xMin, yMin, zMin}, [3]float64{xMax, yMax, zMax}
+	s.grid.query(lo, hi, func(i int32) {
+		p := s.pos[i]
+		if p[0] >= xMin && p[0] <= xMax && p[1] >= yMin && p[1] <= yMax && p[2] >= zMin && p[2] <= zMax {
+			nis = append(nis, i)
+		}
+	})
+	return nis
+}
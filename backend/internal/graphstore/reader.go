@@ -0,0 +1,62 @@
+package graphstore
+
+import (
+	"context"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Reader is a *db.Queries whose neighbor, path, ego and region queries are
+// answered from the store's snapshot when one is loaded, and from the database
+// otherwise.
+type Reader struct {
+	*db.Queries
+	store *Store
+}
+
+// NewReader returns a reader over q and the store, which may be nil.
+func NewReader(q *db.Queries, store *Store) *Reader {
+	return &Reader{Queries: q, store: store}
+}
+
+func (r *Reader) GetNodeNeighbors(ctx context.Context, arg db.GetNodeNeighborsParams) ([]db.GetNodeNeighborsRow, error) {
+	if s := r.store.Current(); s != nil {
+		return s.GetNodeNeighbors(ctx, arg)
+	}
+	return r.Queries.GetNodeNeighbors(ctx, arg)
+}
+
+func (r *Reader) ListGraphNodesByIDs(ctx context.Context, ids []string) ([]db.GraphNodeSummary, error) {
+	if s := r.store.Current(); s != nil {
+		return s.ListGraphNodesByIDs(ctx, ids)
+	}
+	return r.Queries.ListGraphNodesByIDs(ctx, ids)
+}
+
+func (r *Reader) ListGraphNeighbors(ctx context.Context, arg db.ListGraphNeighborsParams) ([]db.GraphNeighbor, error) {
+	if s := r.store.Current(); s != nil {
+		return s.ListGraphNeighbors(ctx, arg)
+	}
+	return r.Queries.ListGraphNeighbors(ctx, arg)
+}
+
+func (r *Reader) ListGraphLinksAmongFiltered(ctx context.Context, arg db.ListGraphLinksAmongFilteredParams) ([]db.ListGraphLinksAmongRow, error) {
+	if s := r.store.Current(); s != nil {
+		return s.ListGraphLinksAmongFiltered(ctx, arg)
+	}
+	return r.Queries.ListGraphLinksAmongFiltered(ctx, arg)
+}
+
+func (r *Reader) GetNodesInBoundingBox(ctx context.Context, arg db.GetNodesInBoundingBoxParams) ([]db.GetNodesInBoundingBoxRow, error) {
+	if s := r.store.Current(); s != nil {
+		return s.GetNodesInBoundingBox(ctx, arg)
+	}
+	return r.Queries.GetNodesInBoundingBox(ctx, arg)
+}
+
+func (r *Reader) GetLinksForNodesInBoundingBox(ctx context.Context, arg db.GetLinksForNodesInBoundingBoxParams) ([]db.GetLinksForNodesInBoundingBoxRow, error) {
+	if s := r.store.Current(); s != nil {
+		return s.GetLinksForNodesInBoundingBox(ctx, arg)
+	}
+	return r.Queries.GetLinksForNodesInBoundingBox(ctx, arg)
+}
@@ -0,0 +1,194 @@
+// Package graphstore keeps the precalculated graph in memory for traversal
+// queries: a compressed adjacency structure over graph_nodes and graph_links,
+// with node attributes and a spatial index, reloaded when a new graph version
+// completes.
+package graphstore
+
+import (
+	"database/sql"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Snapshot is an immutable in-memory copy of the graph tables. Its query
+// methods mirror the database queries they replace.
+type Snapshot struct {
+	// Version is the graph version loaded, 0 when there is none yet
+	Version  int64
+	LoadedAt time.Time
+
+	ids    []string
+	index  map[string]int32
+	names  []string
+	vals   []sql.NullString
+	valNum []int64 // vals ordered as the queries do: digits only, else 0
+	types  []sql.NullString
+	hasPos []bool
+	pos    [][3]float64
+
+	// Links of node i are adj[offsets[i]:offsets[i+1]], as link indexes;
+	// self-links appear once
+	offsets []int32
+	adj     []int32
+
+	linkIDs   []int32
+	src       []int32
+	dst       []int32
+	weights   []float64
+	linkTypes []uint8
+	typeNames []string
+	pvalues   []float64 // NaN when untested
+
+	grid *grid
+}
+
+var digitsOnly = regexp.MustCompile(`^[0-9]+$`)
+
+// newSnapshot builds a snapshot; links to unknown nodes are dropped.
+func newSnapshot(version int64, nodes []db.SnapshotNode, links []db.SnapshotLink) *Snapshot {
+	n := len(nodes)
+	s := &Snapshot{
+		Version:  version,
+		LoadedAt: time.Now(),
+		ids:      make([]string, n),
+		index:    make(map[string]int32, n),
+		names:    make([]string, n),
+		vals:     make([]sql.NullString, n),
+		valNum:   make([]int64, n),
+		types:    make([]sql.NullString, n),
+		hasPos:   make([]bool, n),
+		pos:      make([][3]float64, n),
+	}
+	for i, nd := range nodes {
+		s.ids[i], s.names[i], s.vals[i], s.types[i] = nd.ID, nd.Name, nd.Val, nd.Type
+		s.index[nd.ID] = int32(i)
+		if nd.Val.Valid && digitsOnly.MatchString(nd.Val.String) {
+			s.valNum[i], _ = strconv.ParseInt(nd.Val.String, 10, 64)
+		}
+		if nd.PosX.Valid && nd.PosY.Valid && nd.PosZ.Valid {
+			s.hasPos[i] = true
+			s.pos[i] = [3]float64{nd.PosX.Float64, nd.PosY.Float64, nd.PosZ.Float64}
+		}
+	}
+
+	typeIdx := map[string]uint8{}
+	deg := make([]int32, n+1)
+	for _, l := range links {
+		a, okA := s.index[l.Source]
+		b, okB := s.index[l.Target]
+		if !okA || !okB {
+			continue
+		}
+		t, ok := typeIdx[l.LinkType]
+		if !ok {
+			t = uint8(len(s.typeNames))
+			typeIdx[l.LinkType] = t
+			s.typeNames = append(s.typeNames, l.LinkType)
+		}
+		p := math.NaN()
+		if l.BackbonePValue.Valid {
+			p = l.BackbonePValue.Float64
+		}
+		s.linkIDs = append(s.linkIDs, l.ID)
+		s.src = append(s.src, a)
+		s.dst = append(s.dst, b)
+		s.weights = append(s.weights, l.Weight)
+		s.linkTypes = append(s.linkTypes, t)
+		s.pvalues = append(s.pvalues, p)
+		deg[a]++
+		if a != b {
+			deg[b]++
+		}
+	}
+
+	s.offsets = make([]int32, n+1)
+	for i := 0; i < n; i++ {
+		s.offsets[i+1] = s.offsets[i] + deg[i]
+	}
+	s.adj = make([]int32, s.offsets[n])
+	fill := append([]int32(nil), s.offsets[:n]...)
+	for li := range s.src {
+		a, b := s.src[li], s.dst[li]
+		s.adj[fill[a]] = int32(li)
+		fill[a]++
+		if a != b {
+			s.adj[fill[b]] = int32(li)
+			fill[b]++
+		}
+	}
+
+	s.grid = newGrid(s.hasPos, s.pos)
+	return s
+}
+
+// NodeCount returns the number of nodes.
+func (s *Snapshot) NodeCount() int { return len(s.ids) }
+
+// LinkCount returns the number of links.
+func (s *Snapshot) LinkCount() int { return len(s.src) }
+
+func (s *Snapshot) links(i int32) []int32 { return s.adj[s.offsets[i]:s.offsets[i+1]] }
+
+// linkFilter is the graph link filter of the database queries.
+type linkFilter struct {
+	minWeight float64
+	types     map[uint8]bool // nil allows every type
+	maxPValue float64
+}
+
+func (s *Snapshot) newLinkFilter(minWeight float64, linkTypes []string, maxPValue float64) linkFilter {
+	f := linkFilter{minWeight: minWeight, maxPValue: maxPValue}
+	if len(linkTypes) > 0 {
+		f.types = map[uint8]bool{}
+		for _, t := range linkTypes {
+			for i, name := range s.typeNames {
+				if name == t {
+					f.types[uint8(i)] = true
+				}
+			}
+		}
+	}
+	return f
+}
+
+func (s *Snapshot) allows(f linkFilter, li int32) bool {
+	if s.weights[li] < f.minWeight {
+		return false
+	}
+	if f.types != nil && !f.types[s.linkTypes[li]] {
+		return false
+	}
+	if p := s.pvalues[li]; f.maxPValue > 0 && !math.IsNaN(p) && p > f.maxPValue {
+		return false
+	}
+	return true
+}
+
+// sortLinks orders link indexes heaviest first, then by link ID.
+func (s *Snapshot) sortLinks(lis []int32) {
+	sort.Slice(lis, func(a, b int) bool {
+		wa, wb := s.weights[lis[a]], s.weights[lis[b]]
+		if wa != wb {
+			return wa > wb
+		}
+		return s.linkIDs[lis[a]] < s.linkIDs[lis[b]]
+	})
+}
+
+// sortNodes orders node indexes by val, highest first, then by ID.
+func (s *Snapshot) sortNodes(nis []int32) {
+	sort.Slice(nis, func(a, b int) bool {
+		va, vb := s.valNum[nis[a]], s.valNum[nis[b]]
+		if va != vb {
+			return va > vb
+		}
+		return s.ids[nis[a]] < s.ids[nis[b]]
+	})
+}
+
+func nullFloat(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
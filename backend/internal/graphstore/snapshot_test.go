@@ -0,0 +1,222 @@
+package graphstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func str(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+func node(id, typ, val string, pos ...float64) db.SnapshotNode {
+	n := db.SnapshotNode{ID: id, Name: id, Val: str(val), Type: str(typ)}
+	if len(pos) == 3 {
+		n.PosX, n.PosY, n.PosZ = nullFloat(pos[0]), nullFloat(pos[1]), nullFloat(pos[2])
+	}
+	return n
+}
+
+func testSnapshot() *Snapshot {
+	nodes := []db.SnapshotNode{
+		node("subreddit_1", "subreddit", "500", 0, 0, 0),
+		node("subreddit_2", "subreddit", "400", 10, 0, 0),
+		node("subreddit_3", "subreddit", "900", 5, 5, 0),
+		node("user_1", "user", "12", 1, 1, 1),
+		node("user_2", "user", "x"),
+	}
+	links := []db.SnapshotLink{
+		{ID: 1, Source: "user_1", Target: "subreddit_1", Weight: 5, LinkType: "user_activity"},
+		{ID: 2, Source: "user_1", Target: "subreddit_2", Weight: 3, LinkType: "user_activity"},
+		{ID: 3, Source: "subreddit_1", Target: "subreddit_3", Weight: 40, LinkType: "subreddit_overlap", BackbonePValue: sql.NullFloat64{Float64: 0.01, Valid: true}},
+		{ID: 4, Source: "subreddit_3", Target: "subreddit_2", Weight: 30, LinkType: "subreddit_overlap", BackbonePValue: sql.NullFloat64{Float64: 0.2, Valid: true}},
+		{ID: 5, Source: "subreddit_1", Target: "user_1", Weight: 1, LinkType: "mention"},
+		{ID: 6, Source: "user_2", Target: "subreddit_9", Weight: 1, LinkType: "user_activity"},
+	}
+	return newSnapshot(7, nodes, links)
+}
+
+func TestSnapshotBuild(t *testing.T) {
+	s := testSnapshot()
+	if s.NodeCount() != 5 || s.LinkCount() != 5 {
+		t.Fatalf("got %d nodes and %d links, want 5 and 5 (the link to a missing node dropped)", s.NodeCount(), s.LinkCount())
+	}
+	if s.valNum[s.index["user_2"]] != 0 {
+		t.Error("non-numeric val should order as 0")
+	}
+}
+
+func TestSnapshotGetNodeNeighbors(t *testing.T) {
+	s := testSnapshot()
+	rows, _ := s.GetNodeNeighbors(context.Background(), db.GetNodeNeighborsParams{Source: "subreddit_1", Limit: 10})
+	var got []string
+	for _, r := range rows {
+		got = append(got, fmt.Sprintf("%s:%d", r.ID, r.Degree))
+	}
+	// user_1 has two links to subreddit_1, in either direction
+	if want := []string{"user_1:2", "subreddit_3:1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("neighbors = %v, want %v", got, want)
+	}
+	rows, _ = s.GetNodeNeighbors(context.Background(), db.GetNodeNeighborsParams{Source: "subreddit_1", Limit: 1})
+	if len(rows) != 1 || rows[0].Val != "12" {
+		t.Fatalf("limited neighbors = %+v", rows)
+	}
+}
+
+func TestSnapshotListGraphNeighbors(t *testing.T) {
+	s := testSnapshot()
+	ctx := context.Background()
+	rows, _ := s.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{IDs: []string{"subreddit_3"}})
+	if len(rows) != 2 {
+		t.Fatalf("got %d links, want 2", len(rows))
+	}
+	// Links keep their stored direction
+	for _, r := range rows {
+		if r.Source == "subreddit_3" && r.Target != "subreddit_2" || r.Target == "subreddit_3" && r.Source != "subreddit_1" {
+			t.Errorf("unexpected link %+v", r)
+		}
+	}
+
+	// Node types restrict the other end, except for the endpoints
+	rows, _ = s.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{IDs: []string{"subreddit_1"}, NodeTypes: []string{"subreddit"}})
+	if len(rows) != 1 || rows[0].Target != "subreddit_3" {
+		t.Fatalf("subreddit links = %+v", rows)
+	}
+	rows, _ = s.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{IDs: []string{"subreddit_1"}, NodeTypes: []string{"subreddit"}, Endpoints: []string{"user_1"}})
+	if len(rows) != 3 {
+		t.Fatalf("got %d links with the user endpoint, want 3", len(rows))
+	}
+
+	// The link filter of the database queries
+	rows, _ = s.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{IDs: []string{"subreddit_3"}, MaxPValue: 0.05})
+	if len(rows) != 1 || rows[0].Source != "subreddit_1" {
+		t.Fatalf("backbone links = %+v", rows)
+	}
+	rows, _ = s.ListGraphNeighbors(ctx, db.ListGraphNeighborsParams{IDs: []string{"user_1"}, MinWeight: 2, LinkTypes: []string{"user_activity"}})
+	if len(rows) != 2 {
+		t.Fatalf("filtered links = %+v", rows)
+	}
+}
+
+func TestSnapshotListGraphLinksAmongFiltered(t *testing.T) {
+	s := testSnapshot()
+	rows, _ := s.ListGraphLinksAmongFiltered(context.Background(), db.ListGraphLinksAmongFilteredParams{IDs: []string{"subreddit_1", "subreddit_3", "user_1"}})
+	var got []float64
+	for _, r := range rows {
+		got = append(got, r.Weight)
+	}
+	if want := []float64{40, 5, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("weights = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotBoundingBox(t *testing.T) {
+	s := testSnapshot()
+	ctx := context.Background()
+	box := db.GetNodesInBoundingBoxParams{
+		PosX: nullFloat(-1), PosX_2: nullFloat(6),
+		PosY: nullFloat(-1), PosY_2: nullFloat(6),
+		PosZ: nullFloat(-1), PosZ_2: nullFloat(1),
+		Limit: 10,
+	}
+	nodes, _ := s.GetNodesInBoundingBox(ctx, box)
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"subreddit_3", "subreddit_1", "user_1"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("nodes = %v, want %v", ids, want)
+	}
+	links, _ := s.GetLinksForNodesInBoundingBox(ctx, db.GetLinksForNodesInBoundingBoxParams{
+		PosX: box.PosX, PosX_2: box.PosX_2, PosY: box.PosY, PosY_2: box.PosY_2, PosZ: box.PosZ, PosZ_2: box.PosZ_2,
+		Limit: 2, Column9: []string{"user_activity", "subreddit_overlap"},
+	})
+	if len(links) != 2 || links[0].ID != 3 || links[1].ID != 1 {
+		t.Fatalf("links = %+v", links)
+	}
+}
+
+func TestSnapshotBoundingBoxOneLinkPerPair(t *testing.T) {
+	s := newSnapshot(1, []db.SnapshotNode{
+		node("subreddit_1", "subreddit", "1", 0, 0, 0),
+		node("subreddit_2", "subreddit", "1", 1, 0, 0),
+	}, []db.SnapshotLink{
+		{ID: 1, Source: "subreddit_1", Target: "subreddit_2", Weight: 3, LinkType: "mention"},
+		{ID: 2, Source: "subreddit_1", Target: "subreddit_2", Weight: 7, LinkType: "crosspost"},
+		{ID: 3, Source: "subreddit_1", Target: "subreddit_2", Weight: 20, LinkType: "subreddit_overlap"},
+		{ID: 4, Source: "subreddit_2", Target: "subreddit_1", Weight: 2, LinkType: "mention"},
+	})
+	links, _ := s.GetLinksForNodesInBoundingBox(context.Background(), db.GetLinksForNodesInBoundingBoxParams{
+		PosX: nullFloat(-1), PosX_2: nullFloat(2), PosY: nullFloat(-1), PosY_2: nullFloat(1), PosZ: nullFloat(-1), PosZ_2: nullFloat(1),
+		Limit: 10, Column9: []string{"mention", "crosspost"},
+	})
+	var got []string
+	for _, l := range links {
+		got = append(got, fmt.Sprintf("%s>%s:%s", l.Source, l.Target, l.LinkType))
+	}
+	if want := []string{"subreddit_1>subreddit_2:crosspost", "subreddit_2>subreddit_1:mention"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("links = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotLinksAmongOneLinkPerPair(t *testing.T) {
+	s := newSnapshot(1, []db.SnapshotNode{
+		node("subreddit_1", "subreddit", "1", 0, 0, 0),
+		node("subreddit_2", "subreddit", "1", 1, 0, 0),
+	}, []db.SnapshotLink{
+		{ID: 1, Source: "subreddit_1", Target: "subreddit_2", Weight: 3, LinkType: "mention"},
+		{ID: 2, Source: "subreddit_1", Target: "subreddit_2", Weight: 7, LinkType: "crosspost"},
+		{ID: 3, Source: "subreddit_1", Target: "subreddit_2", Weight: 20, LinkType: "subreddit_overlap"},
+		{ID: 4, Source: "subreddit_2", Target: "subreddit_1", Weight: 2, LinkType: "mention"},
+	})
+	links, _ := s.ListGraphLinksAmongFiltered(context.Background(), db.ListGraphLinksAmongFilteredParams{
+		IDs: []string{"subreddit_1", "subreddit_2"}, LinkTypes: []string{"mention", "crosspost"},
+	})
+	var got []string
+	for _, l := range links {
+		got = append(got, fmt.Sprintf("%s>%s:%s", l.Source, l.Target, l.LinkType))
+	}
+	if want := []string{"subreddit_1>subreddit_2:crosspost", "subreddit_2>subreddit_1:mention"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("links = %v, want %v", got, want)
+	}
+}
+
+func TestGridMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n := 2000
+	hasPos := make([]bool, n)
+	pos := make([][3]float64, n)
+	for i := range pos {
+		hasPos[i] = i%10 != 0
+		pos[i] = [3]float64{rng.NormFloat64() * 100, rng.NormFloat64() * 100, rng.Float64()}
+	}
+	g := newGrid(hasPos, pos)
+	for q := 0; q < 50; q++ {
+		var lo, hi [3]float64
+		for a := 0; a < 3; a++ {
+			x, y := rng.NormFloat64()*150, rng.NormFloat64()*150
+			lo[a], hi[a] = min(x, y), max(x, y)
+		}
+		var got, want []int
+		g.query(lo, hi, func(i int32) {
+			p := pos[i]
+			if p[0] >= lo[0] && p[0] <= hi[0] && p[1] >= lo[1] && p[1] <= hi[1] && p[2] >= lo[2] && p[2] <= hi[2] {
+				got = append(got, int(i))
+			}
+		})
+		for i, p := range pos {
+			if hasPos[i] && p[0] >= lo[0] && p[0] <= hi[0] && p[1] >= lo[1] && p[1] <= hi[1] && p[2] >= lo[2] && p[2] <= hi[2] {
+				want = append(want, i)
+			}
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("box %v-%v: grid found %d nodes, scan %d", lo, hi, len(got), len(want))
+		}
+	}
+}
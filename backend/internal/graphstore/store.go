@@ -0,0 +1,131 @@
+package graphstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/logger"
+)
+
+// Source reads the graph consistently: read is called with a SnapshotReader
+// whose reads all see the same committed state, so a version is never loaded
+// with the nodes or links of another.
+type Source interface {
+	ReadGraphSnapshot(ctx context.Context, read func(SnapshotReader) error) error
+}
+
+// SnapshotReader reads the graph tables and the current graph version.
+type SnapshotReader interface {
+	GetCurrentGraphVersion(ctx context.Context) (db.GraphVersion, error)
+	ListGraphSnapshotNodes(ctx context.Context) ([]db.SnapshotNode, error)
+	ListGraphSnapshotLinks(ctx context.Context) ([]db.SnapshotLink, error)
+}
+
+// DBSource reads the graph from the database behind q, in one transaction per
+// refresh.
+func DBSource(q *db.Queries) Source {
+	return dbSource{q}
+}
+
+type dbSource struct{ q *db.Queries }
+
+func (s dbSource) ReadGraphSnapshot(ctx context.Context, read func(SnapshotReader) error) error {
+	return s.q.ReadGraphSnapshot(ctx, func(q *db.Queries) error { return read(q) })
+}
+
+// Store holds the current snapshot of the graph. Reloads build a new snapshot
+// and swap it in atomically, so readers never see a partial graph.
+type Store struct {
+	src  Source
+	cur  atomic.Pointer[Snapshot]
+	load sync.Mutex
+}
+
+// New returns a store with no snapshot loaded.
+func New(src Source) *Store {
+	return &Store{src: src}
+}
+
+// Current returns the loaded snapshot, or nil when none is loaded. It is safe
+// to call on a nil store.
+func (s *Store) Current() *Snapshot {
+	if s == nil {
+		return nil
+	}
+	return s.cur.Load()
+}
+
+// Refresh loads the graph when nothing is loaded yet or the current graph
+// version differs from the loaded one, and reports whether it did. A failed
+// load keeps the previous snapshot.
+func (s *Store) Refresh(ctx context.Context) (bool, error) {
+	s.load.Lock()
+	defer s.load.Unlock()
+
+	var (
+		version  int64
+		nodes    []db.SnapshotNode
+		links    []db.SnapshotLink
+		start    time.Time
+		upToDate bool
+	)
+	err := s.src.ReadGraphSnapshot(ctx, func(r SnapshotReader) error {
+		v, err := r.GetCurrentGraphVersion(ctx)
+		switch {
+		case err == nil:
+			version = v.ID
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("get graph version: %w", err)
+		}
+		if cur := s.cur.Load(); cur != nil && cur.Version == version {
+			upToDate = true
+			return nil
+		}
+
+		start = time.Now()
+		if nodes, err = r.ListGraphSnapshotNodes(ctx); err != nil {
+			return fmt.Errorf("load graph nodes: %w", err)
+		}
+		if links, err = r.ListGraphSnapshotLinks(ctx); err != nil {
+			return fmt.Errorf("load graph links: %w", err)
+		}
+		return nil
+	})
+	if err != nil || upToDate {
+		return false, err
+	}
+	snap := newSnapshot(version, nodes, links)
+	s.cur.Store(snap)
+	logger.Info("In-memory graph loaded", "version", version, "nodes", snap.NodeCount(), "links", snap.LinkCount(), "duration", time.Since(start).Round(time.Millisecond))
+	return true, nil
+}
+
+// Run loads the graph, then checks the graph version every interval until ctx
+// is done.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	refresh := func() {
+		if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("In-memory graph refresh failed", "error", err)
+		}
+	}
+	refresh()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			refresh()
+		}
+	}
+}
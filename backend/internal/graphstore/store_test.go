@@ -0,0 +1,145 @@
+package graphstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// fakeGraph is the state of the graph tables at one moment.
+type fakeGraph struct {
+	version   int64 // 0 means no completed version
+	nodes     []db.SnapshotNode
+	links     []db.SnapshotLink
+	failLinks bool
+}
+
+// fakeSource serves graph tables from memory. Each read sees a copy of the
+// graph taken when it starts, like a repeatable read transaction.
+type fakeSource struct {
+	fakeGraph
+	loadCalled int
+	// afterVersion runs once the version is read, like a concurrent writer
+	afterVersion func()
+}
+
+func (f *fakeSource) ReadGraphSnapshot(ctx context.Context, read func(SnapshotReader) error) error {
+	return read(&fakeReader{fakeGraph: f.fakeGraph, src: f})
+}
+
+type fakeReader struct {
+	fakeGraph
+	src *fakeSource
+}
+
+func (r *fakeReader) GetCurrentGraphVersion(ctx context.Context) (db.GraphVersion, error) {
+	if hook := r.src.afterVersion; hook != nil {
+		defer hook()
+	}
+	if r.version == 0 {
+		return db.GraphVersion{}, sql.ErrNoRows
+	}
+	return db.GraphVersion{ID: r.version, Status: "completed"}, nil
+}
+
+func (r *fakeReader) ListGraphSnapshotNodes(ctx context.Context) ([]db.SnapshotNode, error) {
+	r.src.loadCalled++
+	return r.nodes, nil
+}
+
+func (r *fakeReader) ListGraphSnapshotLinks(ctx context.Context) ([]db.SnapshotLink, error) {
+	if r.failLinks {
+		return nil, errors.New("connection reset")
+	}
+	return r.links, nil
+}
+
+func TestStoreRefresh(t *testing.T) {
+	ctx := context.Background()
+	src := &fakeSource{fakeGraph: fakeGraph{nodes: []db.SnapshotNode{node("a", "subreddit", "1"), node("b", "subreddit", "2")}}}
+	s := New(src)
+	if s.Current() != nil {
+		t.Fatal("new store should have no snapshot")
+	}
+
+	// Without any graph version the graph still loads, once
+	if loaded, err := s.Refresh(ctx); err != nil || !loaded {
+		t.Fatalf("Refresh = %v, %v", loaded, err)
+	}
+	if loaded, _ := s.Refresh(ctx); loaded || src.loadCalled != 1 {
+		t.Fatalf("unchanged version reloaded (loads: %d)", src.loadCalled)
+	}
+	first := s.Current()
+	if first.Version != 0 || first.NodeCount() != 2 {
+		t.Fatalf("snapshot v%d with %d nodes", first.Version, first.NodeCount())
+	}
+
+	// A new version swaps in a new snapshot
+	src.version = 3
+	src.links = []db.SnapshotLink{{ID: 1, Source: "a", Target: "b", Weight: 1, LinkType: "subreddit_overlap"}}
+	if loaded, err := s.Refresh(ctx); err != nil || !loaded {
+		t.Fatalf("Refresh = %v, %v", loaded, err)
+	}
+	if cur := s.Current(); cur == first || cur.Version != 3 || cur.LinkCount() != 1 {
+		t.Fatalf("snapshot v%d with %d links", cur.Version, cur.LinkCount())
+	}
+	if first.LinkCount() != 0 {
+		t.Error("the previous snapshot changed")
+	}
+
+	// A failed load keeps serving the previous snapshot
+	src.version = 4
+	src.failLinks = true
+	if _, err := s.Refresh(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	if s.Current().Version != 3 {
+		t.Fatalf("snapshot v%d after a failed load, want v3", s.Current().Version)
+	}
+}
+
+func TestStoreRefreshConsistent(t *testing.T) {
+	ctx := context.Background()
+	v4 := fakeGraph{version: 4, nodes: []db.SnapshotNode{node("a", "subreddit", "1")}}
+	v5 := fakeGraph{version: 5, nodes: []db.SnapshotNode{node("a", "subreddit", "1"), node("b", "subreddit", "2")},
+		links: []db.SnapshotLink{{ID: 1, Source: "a", Target: "b", Weight: 1, LinkType: "subreddit_overlap"}}}
+	src := &fakeSource{fakeGraph: v4}
+	// Precalculation commits version 5 right after the version is read
+	src.afterVersion = func() {
+		src.fakeGraph = v5
+		src.afterVersion = nil
+	}
+	s := New(src)
+	if _, err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if cur := s.Current(); cur.Version != 4 || cur.NodeCount() != 1 || cur.LinkCount() != 0 {
+		t.Fatalf("snapshot v%d with %d nodes and %d links, want version 4 as read", cur.Version, cur.NodeCount(), cur.LinkCount())
+	}
+	if loaded, err := s.Refresh(ctx); err != nil || !loaded {
+		t.Fatalf("Refresh = %v, %v", loaded, err)
+	}
+	if cur := s.Current(); cur.Version != 5 || cur.NodeCount() != 2 || cur.LinkCount() != 1 {
+		t.Fatalf("snapshot v%d with %d nodes and %d links, want version 5", cur.Version, cur.NodeCount(), cur.LinkCount())
+	}
+}
+
+func TestReaderUsesSnapshot(t *testing.T) {
+	src := &fakeSource{fakeGraph: fakeGraph{version: 1, nodes: []db.SnapshotNode{node("a", "subreddit", "1")}}}
+	s := New(src)
+	if _, err := s.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// No database behind the reader: the queries must come from memory
+	r := NewReader(nil, s)
+	rows, err := r.ListGraphNodesByIDs(context.Background(), []string{"a", "missing"})
+	if err != nil || len(rows) != 1 || rows[0].ID != "a" {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+	if NewReader(nil, nil).store.Current() != nil {
+		t.Fatal("a nil store has no snapshot")
+	}
+}
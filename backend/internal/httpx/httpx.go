@@ -27,6 +27,7 @@ type AttemptInfo struct {
 	Status  int
 	Err     error
 	Wait    time.Duration
+	Header  http.Header // response headers, nil when no response was received
 }
 
 // Observer callback to report attempt telemetry.
@@ -80,7 +81,7 @@ func DoWithRetryFactoryObs(client *http.Client, build func() (*http.Request, err
 					log.Printf("httpx: attempt=%d method=%s url=%s status=%d (success)", attempt, req.Method, req.URL.String(), resp.StatusCode)
 				}
 				if obs != nil {
-					obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode})
+					obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Header: resp.Header})
 				}
 				return resp, nil
 			}
@@ -91,7 +92,7 @@ func DoWithRetryFactoryObs(client *http.Client, build func() (*http.Request, err
 					log.Printf("httpx: attempt=%d method=%s url=%s status=%d (giving up)", attempt, req.Method, req.URL.String(), resp.StatusCode)
 				}
 				if obs != nil {
-					obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode})
+					obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Header: resp.Header})
 				}
 				return resp, nil
 			}
@@ -105,7 +106,7 @@ func DoWithRetryFactoryObs(client *http.Client, build func() (*http.Request, err
 						log.Printf("httpx: attempt=%d 429/5xx Retry-After=%s wait=%s method=%s url=%s", attempt, ra, wait, req.Method, req.URL.String())
 					}
 					if obs != nil {
-						obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Wait: wait})
+						obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Wait: wait, Header: resp.Header})
 					}
 					time.Sleep(wait)
 					continue
@@ -119,7 +120,7 @@ func DoWithRetryFactoryObs(client *http.Client, build func() (*http.Request, err
 							log.Printf("httpx: attempt=%d 429/5xx Retry-After=%s wait=%s method=%s url=%s", attempt, ra, delta, req.Method, req.URL.String())
 						}
 						if obs != nil {
-							obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Wait: delta})
+							obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Wait: delta, Header: resp.Header})
 						}
 						time.Sleep(delta)
 						continue
@@ -136,7 +137,12 @@ func DoWithRetryFactoryObs(client *http.Client, build func() (*http.Request, err
 			log.Printf("httpx: attempt=%d backing off=%s method=%s url=%s", attempt, delay, req.Method, req.URL.String())
 		}
 		if obs != nil {
-			obs(AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Wait: delay})
+			info := AttemptInfo{Attempt: attempt, Method: req.Method, URL: req.URL.String(), Wait: delay}
+			if resp != nil {
+				info.Status = resp.StatusCode
+				info.Header = resp.Header
+			}
+			obs(info)
 		}
 		time.Sleep(delay)
 	}
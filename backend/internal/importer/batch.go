@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// batchPlan is a batch reduced to the rows it writes. A record that appears more
+// than once keeps its last version, since a row can only be upserted once per
+// statement.
+type batchPlan struct {
+	posts    []*dumpRecord
+	comments []plannedComment
+	skipped  int64 // comments whose post is not stored
+}
+
+type plannedComment struct {
+	rec   *dumpRecord
+	depth int
+}
+
+// plan selects the rows a batch writes. Comments are kept only if their post is
+// already stored or part of the batch; import the submissions dump first.
+func (im *Importer) plan(batch []dumpRecord, knownPosts map[string]bool) batchPlan {
+	var p batchPlan
+	postAt := make(map[string]int)
+	commentAt := make(map[string]int)
+	for i := range batch {
+		rec := &batch[i]
+		if rec.Kind() == KindSubmission {
+			knownPosts[rec.ID] = true
+			if j, ok := postAt[rec.ID]; ok {
+				p.posts[j] = rec
+				continue
+			}
+			postAt[rec.ID] = len(p.posts)
+			p.posts = append(p.posts, rec)
+			continue
+		}
+		if !knownPosts[rec.PostID()] {
+			p.skipped++
+			continue
+		}
+		c := plannedComment{rec: rec, depth: im.commentDepth(rec)}
+		if j, ok := commentAt[rec.ID]; ok {
+			p.comments[j] = c
+			continue
+		}
+		commentAt[rec.ID] = len(p.comments)
+		p.comments = append(p.comments, c)
+	}
+	return p
+}
+
+// names returns the subreddits and authors of the planned rows that are not cached.
+func (im *Importer) names(p batchPlan) (subreddits, users []string) {
+	seenSub, seenUser := make(map[string]bool), make(map[string]bool)
+	add := func(rec *dumpRecord) {
+		if _, ok := im.subreddits[rec.Subreddit]; !ok && !seenSub[rec.Subreddit] {
+			seenSub[rec.Subreddit] = true
+			subreddits = append(subreddits, rec.Subreddit)
+		}
+		if _, ok := im.users[rec.Author]; !ok && !seenUser[rec.Author] {
+			seenUser[rec.Author] = true
+			users = append(users, rec.Author)
+		}
+	}
+	for _, rec := range p.posts {
+		add(rec)
+	}
+	for _, c := range p.comments {
+		add(c.rec)
+	}
+	// Sorted names lock rows in the same order in concurrent imports.
+	sort.Strings(subreddits)
+	sort.Strings(users)
+	return subreddits, users
+}
+
+// ensureSubreddits creates the named subreddits with placeholder metadata, like
+// EnsureSubreddit, and returns their IDs.
+func ensureSubreddits(ctx context.Context, tx db.DBTX, names []string) (map[string]int32, error) {
+	const stmt = `INSERT INTO subreddits (name, title, description, subscribers, created_at, last_seen)
+                  SELECT n, n, '', 0, now(), now() FROM unnest($1::text[]) AS n
+                  ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+                  RETURNING name, id`
+	return upsertNames(ctx, tx, stmt, names)
+}
+
+// ensureUsers upserts the named users, like UpsertUser, and returns their IDs.
+func ensureUsers(ctx context.Context, tx db.DBTX, names []string) (map[string]int32, error) {
+	const stmt = `INSERT INTO users (username, created_at, last_seen)
+                  SELECT u, now(), now() FROM unnest($1::text[]) AS u
+                  ON CONFLICT (username) DO UPDATE SET last_seen = now()
+                  RETURNING username, id`
+	return upsertNames(ctx, tx, stmt, names)
+}
+
+func upsertNames(ctx context.Context, tx db.DBTX, stmt string, names []string) (map[string]int32, error) {
+	ids := make(map[string]int32, len(names))
+	if len(names) == 0 {
+		return ids, nil
+	}
+	rows, err := tx.QueryContext(ctx, stmt, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			name string
+			id   int32
+		)
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+		ids[name] = id
+	}
+	return ids, rows.Err()
+}
+
+// upsertPosts writes posts with the semantics of UpsertPost in one statement.
+func upsertPosts(ctx context.Context, tx db.DBTX, posts []db.UpsertPostParams) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	n := len(posts)
+	var (
+		ids                      = make([]string, n)
+		subreddits, authors      = make([]int32, n), make([]int32, n)
+		titles, selftexts        = make([]sql.NullString, n), make([]sql.NullString, n)
+		permalinks, flairs, urls = make([]sql.NullString, n), make([]sql.NullString, n), make([]sql.NullString, n)
+		created                  = make([]sql.NullString, n)
+		scores                   = make([]sql.NullInt32, n)
+		isSelf                   = make([]sql.NullBool, n)
+	)
+	for i, p := range posts {
+		ids[i], subreddits[i], authors[i] = p.ID, p.SubredditID, p.AuthorID
+		titles[i], selftexts[i], permalinks[i] = p.Title, p.Selftext, p.Permalink
+		flairs[i], urls[i] = p.Flair, p.Url
+		created[i] = timestampText(p.CreatedAt)
+		scores[i], isSelf[i] = p.Score, p.IsSelf
+	}
+	const stmt = `INSERT INTO posts (id, subreddit_id, author_id, title, selftext, permalink, created_at, score, flair, url, is_self, last_seen)
+                  SELECT t.*, now() FROM unnest($1::text[], $2::int[], $3::int[], $4::text[], $5::text[], $6::text[],
+                                                $7::timestamptz[], $8::int[], $9::text[], $10::text[], $11::bool[]) AS t
+                  ON CONFLICT (id) DO UPDATE SET
+                    subreddit_id = EXCLUDED.subreddit_id,
+                    author_id = EXCLUDED.author_id,
+                    title = EXCLUDED.title,
+                    selftext = EXCLUDED.selftext,
+                    permalink = EXCLUDED.permalink,
+                    created_at = EXCLUDED.created_at,
+                    score = EXCLUDED.score,
+                    flair = EXCLUDED.flair,
+                    url = EXCLUDED.url,
+                    is_self = EXCLUDED.is_self,
+                    last_seen = now()`
+	_, err := tx.ExecContext(ctx, stmt, pq.Array(ids), pq.Array(subreddits), pq.Array(authors),
+		pq.Array(titles), pq.Array(selftexts), pq.Array(permalinks), pq.Array(created),
+		pq.Array(scores), pq.Array(flairs), pq.Array(urls), pq.Array(isSelf))
+	if err != nil {
+		return fmt.Errorf("upsert %d posts: %w", n, err)
+	}
+	return nil
+}
+
+// upsertComments writes comments with the semantics of UpsertComment in one statement.
+func upsertComments(ctx context.Context, tx db.DBTX, comments []db.UpsertCommentParams) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	n := len(comments)
+	var (
+		ids, postIDs        = make([]string, n), make([]string, n)
+		authors, subreddits = make([]int32, n), make([]int32, n)
+		parents, bodies     = make([]sql.NullString, n), make([]sql.NullString, n)
+		created             = make([]sql.NullString, n)
+		scores, depths      = make([]sql.NullInt32, n), make([]sql.NullInt32, n)
+	)
+	for i, c := range comments {
+		ids[i], postIDs[i], authors[i], subreddits[i] = c.ID, c.PostID, c.AuthorID, c.SubredditID
+		parents[i], bodies[i] = c.ParentID, c.Body
+		created[i] = timestampText(c.CreatedAt)
+		scores[i], depths[i] = c.Score, c.Depth
+	}
+	const stmt = `INSERT INTO comments (id, post_id, author_id, subreddit_id, parent_id, body, created_at, score, depth, last_seen)
+                  SELECT t.*, now() FROM unnest($1::text[], $2::text[], $3::int[], $4::int[], $5::text[], $6::text[],
+                                                $7::timestamptz[], $8::int[], $9::int[]) AS t
+                  ON CONFLICT (id) DO UPDATE SET
+                    post_id = EXCLUDED.post_id,
+                    author_id = EXCLUDED.author_id,
+                    subreddit_id = EXCLUDED.subreddit_id,
+                    parent_id = EXCLUDED.parent_id,
+                    body = EXCLUDED.body,
+                    created_at = EXCLUDED.created_at,
+                    score = EXCLUDED.score,
+                    last_seen = now(),
+                    depth = EXCLUDED.depth`
+	_, err := tx.ExecContext(ctx, stmt, pq.Array(ids), pq.Array(postIDs), pq.Array(authors), pq.Array(subreddits),
+		pq.Array(parents), pq.Array(bodies), pq.Array(created), pq.Array(scores), pq.Array(depths))
+	if err != nil {
+		return fmt.Errorf("upsert %d comments: %w", n, err)
+	}
+	return nil
+}
+
+// timestampText formats a time for a timestamptz[] parameter. Array elements are
+// passed as text, which keeps the zone and sub-second precision.
+func timestampText(t sql.NullTime) sql.NullString {
+	if !t.Valid {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Time.Format(time.RFC3339Nano), Valid: true}
+}
+
+// replaceSubredditRefs replaces the subreddit references of posts, like
+// ReplacePostSubredditRefs, in two statements for the whole batch.
+func replaceSubredditRefs(ctx context.Context, tx db.DBTX, postIDs []string, refs []postRef) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_subreddit_refs WHERE post_id = ANY($1::text[])`, pq.Array(postIDs)); err != nil {
+		return fmt.Errorf("delete subreddit references of %d posts: %w", len(postIDs), err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	n := len(refs)
+	var (
+		posts, targets, kinds = make([]string, n), make([]string, n), make([]string, n)
+		subreddits            = make([]int32, n)
+	)
+	for i, r := range refs {
+		posts[i], subreddits[i], targets[i], kinds[i] = r.postID, r.subredditID, r.Target, r.Kind
+	}
+	const stmt = `INSERT INTO post_subreddit_refs (post_id, subreddit_id, target_subreddit, kind)
+                  SELECT * FROM unnest($1::text[], $2::int[], $3::text[], $4::text[])
+                  ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, stmt, pq.Array(posts), pq.Array(subreddits), pq.Array(targets), pq.Array(kinds))
+	if err != nil {
+		return fmt.Errorf("insert %d subreddit references: %w", n, err)
+	}
+	return nil
+}
+
+// postRef is a subreddit reference of a planned post.
+type postRef struct {
+	postID      string
+	subredditID int32
+	db.PostSubredditRef
+}
+
+// subredditRefs lists the subreddit references of the planned posts, which
+// become mention and crosspost links like those of crawled posts.
+func subredditRefs(p batchPlan, subreddits func(string) int32) (postIDs []string, refs []postRef) {
+	for _, rec := range p.posts {
+		postIDs = append(postIDs, rec.ID)
+		for _, r := range rec.toPost().SubredditRefs() {
+			refs = append(refs, postRef{postID: rec.ID, subredditID: subreddits(rec.Subreddit), PostSubredditRef: r})
+		}
+	}
+	return postIDs, refs
+}
+
+// rowParams converts the planned rows into upsert parameters with the resolved IDs.
+func rowParams(p batchPlan, subreddits, users func(string) int32) ([]db.UpsertPostParams, []db.UpsertCommentParams) {
+	posts := make([]db.UpsertPostParams, len(p.posts))
+	for i, rec := range p.posts {
+		posts[i] = crawler.ToUpsertPostParams(rec.toPost(), subreddits(rec.Subreddit), users(rec.Author))
+	}
+	comments := make([]db.UpsertCommentParams, len(p.comments))
+	for i, c := range p.comments {
+		comments[i] = crawler.ToUpsertCommentParams(c.rec.toComment(c.depth), c.rec.PostID(), subreddits(c.rec.Subreddit), users(c.rec.Author))
+	}
+	return posts, comments
+}
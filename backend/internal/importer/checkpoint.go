@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// checkpoint is a row of import_checkpoints.
+type checkpoint struct {
+	FileName  string
+	FileSize  int64
+	Offset    int64 // decompressed bytes up to the end of the last written line
+	Lines     int64
+	Imported  int64
+	Completed bool
+}
+
+func loadCheckpoint(ctx context.Context, q *db.Queries, name string) (checkpoint, bool, error) {
+	cp := checkpoint{FileName: name}
+	var completedAt sql.NullTime
+	err := q.DB().QueryRowContext(ctx, `
+		SELECT file_size, offset_bytes, lines_read, records_imported, completed_at
+		FROM import_checkpoints WHERE file_name = $1`, name,
+	).Scan(&cp.FileSize, &cp.Offset, &cp.Lines, &cp.Imported, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cp, false, nil
+	}
+	if err != nil {
+		return cp, false, err
+	}
+	cp.Completed = completedAt.Valid
+	return cp, true, nil
+}
+
+func saveCheckpoint(ctx context.Context, tx db.DBTX, cp checkpoint) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO import_checkpoints (file_name, file_size, offset_bytes, lines_read, records_imported, completed_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $6 THEN now() END, now())
+		ON CONFLICT (file_name) DO UPDATE SET
+		  file_size = EXCLUDED.file_size,
+		  offset_bytes = EXCLUDED.offset_bytes,
+		  lines_read = EXCLUDED.lines_read,
+		  records_imported = EXCLUDED.records_imported,
+		  completed_at = EXCLUDED.completed_at,
+		  updated_at = now()`,
+		cp.FileName, cp.FileSize, cp.Offset, cp.Lines, cp.Imported, cp.Completed)
+	return err
+}
+
+// ResetCheckpoint forgets a file's progress so that it is imported from the start.
+func ResetCheckpoint(ctx context.Context, q *db.Queries, name string) error {
+	_, err := q.DB().ExecContext(ctx, `DELETE FROM import_checkpoints WHERE file_name = $1`, name)
+	return err
+}
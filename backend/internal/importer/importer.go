@@ -0,0 +1,344 @@
+// Package importer loads offline Reddit data dumps (zstd-compressed NDJSON
+// submissions and comments) into the tables the crawler fills. Records are mapped
+// through the crawler's upsert parameters, so imported rows are indistinguishable
+// from crawled ones and are picked up by the next precalculation run.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+// Caches are dropped when they reach this many entries to bound memory on
+// multi-gigabyte dumps.
+const maxCacheEntries = 1 << 20
+
+// Options configures an import.
+type Options struct {
+	Filter           Filter
+	BatchSize        int           // lines between checkpoints
+	Resume           bool          // continue from the file's checkpoint
+	ProgressInterval time.Duration // how often to log throughput; 0 disables
+}
+
+// Stats summarizes the work done on one file.
+type Stats struct {
+	Lines     int64 // lines read, including those before a resumed offset
+	Posts     int64
+	Comments  int64
+	Filtered  int64 // outside the subreddit list or date range
+	Skipped   int64 // unparseable, deleted author or missing post
+	Bytes     int64 // decompressed bytes read in this run
+	Elapsed   time.Duration
+	Resumed   bool
+	Completed bool
+}
+
+// Imported is the number of posts and comments written.
+func (s Stats) Imported() int64 { return s.Posts + s.Comments }
+
+// Importer writes dump records into the database.
+type Importer struct {
+	q    *db.Queries
+	opts Options
+
+	subreddits map[string]int32
+	users      map[string]int32
+	depths     map[string]int // comment ID -> depth, for comments seen in this run
+}
+
+// New creates an importer. A non-positive batch size defaults to 1000 lines.
+func New(q *db.Queries, opts Options) *Importer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	return &Importer{
+		q:          q,
+		opts:       opts,
+		subreddits: make(map[string]int32),
+		users:      make(map[string]int32),
+		depths:     make(map[string]int),
+	}
+}
+
+// ImportFile streams one dump file into the database, checkpointing every batch.
+// Each batch is written in the same transaction as its checkpoint, so a failed
+// batch is retried in full by the next run with Resume. If the context is cancelled
+// the pending batch is written and checkpointed before returning, so a rerun with
+// Resume continues where this one stopped.
+func (im *Importer) ImportFile(ctx context.Context, path string) (Stats, error) {
+	var stats Stats
+	info, err := os.Stat(path)
+	if err != nil {
+		return stats, err
+	}
+	name := filepath.Base(path)
+
+	var start checkpoint
+	if im.opts.Resume {
+		cp, found, err := loadCheckpoint(ctx, im.q, name)
+		if err != nil {
+			return stats, fmt.Errorf("load checkpoint: %w", err)
+		}
+		switch {
+		case !found:
+		case cp.FileSize != info.Size():
+			log.Printf("⚠️ %s changed size since its checkpoint (%d -> %d bytes); starting over", name, cp.FileSize, info.Size())
+		case cp.Completed:
+			log.Printf("⏭️ %s already imported (%d records)", name, cp.Imported)
+			stats.Completed = true
+			return stats, nil
+		default:
+			start = cp
+			stats.Resumed = true
+		}
+	}
+	start.FileName, start.FileSize = name, info.Size()
+
+	s, err := openDump(path)
+	if err != nil {
+		return stats, err
+	}
+	defer s.Close()
+	if start.Offset > 0 {
+		if err := s.Skip(start.Offset); err != nil {
+			return stats, err
+		}
+		log.Printf("↪️ Resuming %s at line %d (offset %d)", name, start.Lines, start.Offset)
+	}
+	stats.Lines = start.Lines
+
+	began := time.Now()
+	lastReport := began
+	imported := start.Imported
+	batch := make([]dumpRecord, 0, im.opts.BatchSize)
+	pending := 0
+
+	// Batches are written with a context that outlives cancellation, so that an
+	// interrupted import still stores its last batch and records how far it got.
+	writeCtx := context.WithoutCancel(ctx)
+	save := func(completed bool) error {
+		cp := checkpoint{
+			FileName: name, FileSize: info.Size(), Offset: s.Offset(),
+			Lines: stats.Lines, Imported: imported, Completed: completed,
+		}
+		n, err := im.flush(writeCtx, batch, cp, &stats)
+		batch, pending = batch[:0], 0
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", name, stats.Lines, err)
+		}
+		imported += n
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			if saveErr := save(false); saveErr != nil {
+				log.Printf("⚠️ Failed to checkpoint %s: %v", name, saveErr)
+			}
+			stats.Bytes, stats.Elapsed = s.Offset()-start.Offset, time.Since(began)
+			return stats, err
+		}
+
+		line, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("%s line %d: %w", name, stats.Lines+1, err)
+		}
+		stats.Lines++
+		pending++
+
+		if len(line) > 0 {
+			rec, err := parseRecord(line)
+			switch {
+			case err != nil:
+				stats.Skipped++
+			case !im.opts.Filter.Keep(&rec):
+				stats.Filtered++
+			case !rec.hasAuthor():
+				stats.Skipped++
+			default:
+				batch = append(batch, rec)
+			}
+		}
+
+		if pending >= im.opts.BatchSize {
+			if err := save(false); err != nil {
+				return stats, err
+			}
+		}
+		if im.opts.ProgressInterval > 0 && time.Since(lastReport) >= im.opts.ProgressInterval {
+			im.report(name, s, info.Size(), start.Offset, &stats, began)
+			lastReport = time.Now()
+		}
+	}
+
+	if err := save(true); err != nil {
+		return stats, err
+	}
+	stats.Completed = true
+	stats.Bytes, stats.Elapsed = s.Offset()-start.Offset, time.Since(began)
+	return stats, nil
+}
+
+// report logs throughput since the file was opened.
+func (im *Importer) report(name string, s *dumpStream, size, startOffset int64, stats *Stats, began time.Time) {
+	secs := time.Since(began).Seconds()
+	if secs <= 0 {
+		return
+	}
+	pct := 0.0
+	if size > 0 {
+		pct = 100 * float64(s.CompressedRead()) / float64(size)
+	}
+	log.Printf("📥 %s: %.1f%% | %d lines, %d posts, %d comments, %d filtered, %d skipped | %.0f lines/s, %.0f records/s, %.1f MB/s",
+		name, pct, stats.Lines, stats.Posts, stats.Comments, stats.Filtered, stats.Skipped,
+		float64(stats.Lines)/secs, float64(stats.Imported())/secs,
+		float64(s.Offset()-startOffset)/secs/(1<<20))
+}
+
+// flush writes a batch of records and the checkpoint reached after it in one
+// transaction, so a checkpoint never covers records that failed to store. It
+// returns how many records were stored; cp.Imported is advanced by them.
+func (im *Importer) flush(ctx context.Context, batch []dumpRecord, cp checkpoint, stats *Stats) (int64, error) {
+	sqlDB, ok := im.q.DB().(*sql.DB)
+	if !ok {
+		return 0, fmt.Errorf("underlying DB does not support transactions")
+	}
+	known, err := im.existingPosts(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+	plan := im.plan(batch, known)
+	stored := int64(len(plan.posts) + len(plan.comments))
+	cp.Imported += stored
+
+	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	subNames, userNames := im.names(plan)
+	newSubs, err := ensureSubreddits(ctx, tx, subNames)
+	if err != nil {
+		return 0, fmt.Errorf("ensure subreddits: %w", err)
+	}
+	newUsers, err := ensureUsers(ctx, tx, userNames)
+	if err != nil {
+		return 0, fmt.Errorf("upsert users: %w", err)
+	}
+	subredditID := func(name string) int32 { return cachedOr(im.subreddits, newSubs, name) }
+	posts, comments := rowParams(plan, subredditID,
+		func(name string) int32 { return cachedOr(im.users, newUsers, name) })
+	if err := upsertPosts(ctx, tx, posts); err != nil {
+		return 0, err
+	}
+	postIDs, refs := subredditRefs(plan, subredditID)
+	if err := replaceSubredditRefs(ctx, tx, postIDs, refs); err != nil {
+		return 0, err
+	}
+	if err := upsertComments(ctx, tx, comments); err != nil {
+		return 0, err
+	}
+	if err := saveCheckpoint(ctx, tx, cp); err != nil {
+		return 0, fmt.Errorf("save checkpoint: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	// IDs of rows created by the transaction are only cached once it committed.
+	cacheIDs(im.subreddits, newSubs)
+	cacheIDs(im.users, newUsers)
+	stats.Posts += int64(len(plan.posts))
+	stats.Comments += int64(len(plan.comments))
+	stats.Skipped += plan.skipped
+	return stored, nil
+}
+
+func cachedOr(cache, fresh map[string]int32, name string) int32 {
+	if id, ok := cache[name]; ok {
+		return id
+	}
+	return fresh[name]
+}
+
+// cacheIDs adds fresh IDs to a cache, dropping it first when it is full.
+func cacheIDs(cache, fresh map[string]int32) {
+	if len(cache)+len(fresh) > maxCacheEntries {
+		clear(cache)
+	}
+	for name, id := range fresh {
+		cache[name] = id
+	}
+}
+
+// commentDepth derives a comment's depth from its parent. Top-level comments are
+// depth 0; replies to comments seen earlier in the run are one deeper, and
+// replies to unseen comments are assumed to be depth 1.
+func (im *Importer) commentDepth(rec *dumpRecord) int {
+	depth := 1
+	if len(rec.ParentID) >= 3 && rec.ParentID[:3] == "t3_" {
+		depth = 0
+	} else if d, ok := im.depths[trimFullname(rec.ParentID)]; ok {
+		depth = d + 1
+	}
+	if len(im.depths) >= maxCacheEntries {
+		clear(im.depths)
+	}
+	im.depths[rec.ID] = depth
+	return depth
+}
+
+// existingPosts returns the IDs of posts referenced by the batch's comments that
+// are already stored.
+func (im *Importer) existingPosts(ctx context.Context, batch []dumpRecord) (map[string]bool, error) {
+	known := make(map[string]bool)
+	var ids []string
+	seen := make(map[string]bool)
+	for i := range batch {
+		if batch[i].Kind() != KindComment {
+			continue
+		}
+		if id := batch[i].PostID(); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return known, nil
+	}
+	rows, err := im.q.DB().QueryContext(ctx, `SELECT id FROM posts WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("look up posts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		known[id] = true
+	}
+	return known, rows.Err()
+}
+
+func trimFullname(id string) string {
+	if len(id) > 3 && id[2] == '_' {
+		return id[3:]
+	}
+	return id
+}
@@ -0,0 +1,288 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	_ "github.com/lib/pq"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
+)
+
+func TestParseRecord(t *testing.T) {
+	sub, err := parseRecord([]byte(`{"id":"abc","subreddit":"golang","author":"gopher","created_utc":"1700000000","title":"Hi\u0000","is_self":true,"score":5}`))
+	if err != nil {
+		t.Fatalf("parse submission: %v", err)
+	}
+	if sub.Kind() != KindSubmission || sub.PostID() != "abc" {
+		t.Errorf("unexpected kind %q / post %q", sub.Kind(), sub.PostID())
+	}
+	post := sub.toPost()
+	if !post.CreatedAt.Equal(time.Unix(1700000000, 0)) || post.Title != "Hi" || !post.IsSelf {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if post.Permalink != "/r/golang/comments/abc/" {
+		t.Errorf("fallback permalink = %q", post.Permalink)
+	}
+
+	c, err := parseRecord([]byte(`{"id":"c1","subreddit":"golang","author":"x","created_utc":1700000100.5,"body":"yo","link_id":"t3_abc","parent_id":"t3_abc"}`))
+	if err != nil {
+		t.Fatalf("parse comment: %v", err)
+	}
+	if c.Kind() != KindComment || c.PostID() != "abc" {
+		t.Errorf("unexpected kind %q / post %q", c.Kind(), c.PostID())
+	}
+	if got := c.toComment(0); got.ParentID != "t3_abc" || got.CreatedAt.Unix() != 1700000100 {
+		t.Errorf("unexpected comment %+v", got)
+	}
+
+	if _, err := parseRecord([]byte(`{"id":"x"}`)); err == nil {
+		t.Error("expected error for record without subreddit")
+	}
+	if _, err := parseRecord([]byte(`{"id":"x","subreddit":"a","created_utc":"soon"}`)); err == nil {
+		t.Error("expected error for invalid created_utc")
+	}
+	if deleted := (dumpRecord{Author: "[deleted]"}); deleted.hasAuthor() {
+		t.Error("deleted author should be skipped")
+	}
+}
+
+func TestFilterKeep(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFilter([]string{"r/GoLang", " rust ", ""}, jan, feb)
+
+	rec := func(sub string, at time.Time) *dumpRecord {
+		return &dumpRecord{Subreddit: sub, CreatedUTC: unixTime{at}}
+	}
+	tests := []struct {
+		rec  *dumpRecord
+		want bool
+	}{
+		{rec("golang", jan), true},
+		{rec("Rust", feb.Add(-time.Second)), true},
+		{rec("golang", feb), false},
+		{rec("golang", jan.Add(-time.Second)), false},
+		{rec("python", jan), false},
+	}
+	for _, tt := range tests {
+		if got := f.Keep(tt.rec); got != tt.want {
+			t.Errorf("Keep(%s @ %s) = %v, want %v", tt.rec.Subreddit, tt.rec.CreatedUTC.Time, got, tt.want)
+		}
+	}
+
+	if all := NewFilter([]string{""}, time.Time{}, time.Time{}); !all.Keep(rec("anything", time.Unix(0, 0))) {
+		t.Error("empty filter should keep everything")
+	}
+}
+
+func TestCommentDepth(t *testing.T) {
+	im := New(nil, Options{})
+	top := &dumpRecord{ID: "c1", LinkID: "t3_p", ParentID: "t3_p"}
+	reply := &dumpRecord{ID: "c2", LinkID: "t3_p", ParentID: "t1_c1"}
+	orphan := &dumpRecord{ID: "c3", LinkID: "t3_p", ParentID: "t1_zzz"}
+	if d := im.commentDepth(top); d != 0 {
+		t.Errorf("top-level depth = %d", d)
+	}
+	if d := im.commentDepth(reply); d != 1 {
+		t.Errorf("reply depth = %d", d)
+	}
+	if d := im.commentDepth(&dumpRecord{ID: "c4", ParentID: "t1_c2"}); d != 2 {
+		t.Errorf("nested reply depth = %d", d)
+	}
+	if d := im.commentDepth(orphan); d != 1 {
+		t.Errorf("reply to unseen comment depth = %d", d)
+	}
+}
+
+func TestPlanBatch(t *testing.T) {
+	im := New(nil, Options{})
+	im.users["cached"] = 7
+	batch := []dumpRecord{
+		{ID: "p1", Subreddit: "golang", Author: "zed", Title: "first"},
+		{ID: "c1", Subreddit: "golang", Author: "amy", LinkID: "t3_p1", ParentID: "t3_p1"},
+		{ID: "c2", Subreddit: "golang", Author: "amy", LinkID: "t3_gone", ParentID: "t3_gone"},
+		{ID: "c3", Subreddit: "rust", Author: "cached", LinkID: "t3_old", ParentID: "t1_c1"},
+		{ID: "p1", Subreddit: "golang", Author: "zed", Title: "edited"},
+	}
+	plan := im.plan(batch, map[string]bool{"old": true})
+
+	if len(plan.posts) != 1 || plan.posts[0].Title != "edited" {
+		t.Fatalf("expected one post with its last version, got %+v", plan.posts)
+	}
+	if len(plan.comments) != 2 || plan.comments[0].rec.ID != "c1" || plan.comments[1].rec.ID != "c3" {
+		t.Fatalf("unexpected comments %+v", plan.comments)
+	}
+	if plan.comments[1].depth != 1 {
+		t.Errorf("reply depth = %d, want 1", plan.comments[1].depth)
+	}
+	if plan.skipped != 1 {
+		t.Errorf("skipped = %d, want the comment of the missing post", plan.skipped)
+	}
+
+	subs, users := im.names(plan)
+	if len(subs) != 2 || subs[0] != "golang" || subs[1] != "rust" {
+		t.Errorf("subreddits = %v", subs)
+	}
+	if len(users) != 2 || users[0] != "amy" || users[1] != "zed" {
+		t.Errorf("users = %v, want the uncached authors in order", users)
+	}
+}
+
+func TestSubredditRefs(t *testing.T) {
+	rec, err := parseRecord([]byte(`{"id":"p1","subreddit":"golang","author":"zed","title":"Also on /r/Rust and /r/golang",` +
+		`"crosspost_parent_list":[{"subreddit":"programming"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := dumpRecord{ID: "p2", Subreddit: "golang", Author: "amy", Title: "no links"}
+	plan := batchPlan{posts: []*dumpRecord{&rec, &plain}}
+
+	postIDs, refs := subredditRefs(plan, func(name string) int32 { return map[string]int32{"golang": 3}[name] })
+	if len(postIDs) != 2 || postIDs[0] != "p1" || postIDs[1] != "p2" {
+		t.Errorf("post IDs = %v, want every planned post so stale references are replaced", postIDs)
+	}
+	want := []postRef{
+		{postID: "p1", subredditID: 3, PostSubredditRef: db.PostSubredditRef{Target: "rust", Kind: db.LinkTypeMention}},
+		{postID: "p1", subredditID: 3, PostSubredditRef: db.PostSubredditRef{Target: "programming", Kind: db.LinkTypeCrosspost}},
+	}
+	if len(refs) != len(want) {
+		t.Fatalf("refs = %+v, want %+v", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Errorf("ref %d = %+v, want %+v", i, refs[i], want[i])
+		}
+	}
+}
+
+func TestDumpStreamResumesAtOffset(t *testing.T) {
+	lines := []string{`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`}
+	var raw bytes.Buffer
+	for _, l := range lines {
+		raw.WriteString(l + "\n")
+	}
+	raw.WriteString(`{"id":"d"}`) // no trailing newline
+
+	var compressed bytes.Buffer
+	zw, err := zstd.NewWriter(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.Write(raw.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "RS_2024-01.zst")
+	if err := os.WriteFile(path, compressed.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := openDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := s.Next()
+	second, _ := s.Next()
+	if string(first) != lines[0] || string(second) != lines[1] {
+		t.Fatalf("unexpected lines %q %q", first, second)
+	}
+	offset := s.Offset()
+	s.Close()
+
+	s, err = openDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Skip(offset); err != nil {
+		t.Fatal(err)
+	}
+	var rest []string
+	for {
+		line, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rest = append(rest, string(line))
+	}
+	if len(rest) != 2 || rest[0] != lines[2] || rest[1] != `{"id":"d"}` {
+		t.Errorf("resumed lines = %q", rest)
+	}
+	if s.Offset() != int64(raw.Len()) {
+		t.Errorf("final offset = %d, want %d", s.Offset(), raw.Len())
+	}
+	if s.CompressedRead() != int64(compressed.Len()) {
+		t.Errorf("compressed bytes read = %d, want %d", s.CompressedRead(), compressed.Len())
+	}
+}
+
+func TestIntegration_ImportFile(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	ctx := context.Background()
+
+	dump := strings.Join([]string{
+		`{"id":"imptp1","subreddit":"importer_test_sub","author":"importer_test_user","created_utc":1700000000,"title":"first"}`,
+		`{"id":"imptc1","subreddit":"importer_test_sub","author":"importer_test_user","created_utc":1700000100,"body":"hi","link_id":"t3_imptp1","parent_id":"t3_imptp1"}`,
+		`{"id":"imptp1","subreddit":"importer_test_sub","author":"importer_test_user","created_utc":1700000000,"title":"edited, see /r/importer_test_other"}`,
+		`{"id":"imptc2","subreddit":"importer_test_sub","author":"importer_test_user","created_utc":1700000200,"body":"orphan","link_id":"t3_imptgone","parent_id":"t3_imptgone"}`,
+	}, "\n")
+	var compressed bytes.Buffer
+	zw, err := zstd.NewWriter(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write([]byte(dump))
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "RS_importer_test.zst")
+	if err := os.WriteFile(path, compressed.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM comments WHERE id IN ('imptc1', 'imptc2')`)
+		conn.Exec(`DELETE FROM posts WHERE id = 'imptp1'`)
+		conn.Exec(`DELETE FROM import_checkpoints WHERE file_name = 'RS_importer_test.zst'`)
+	})
+
+	stats, err := New(q, Options{BatchSize: 2}).ImportFile(ctx, path)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if stats.Posts != 2 || stats.Comments != 1 || stats.Skipped != 1 || !stats.Completed {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	var title string
+	if err := conn.QueryRowContext(ctx, `SELECT title FROM posts WHERE id = 'imptp1'`).Scan(&title); err != nil || !strings.HasPrefix(title, "edited") {
+		t.Errorf("post title = %q (%v), want the last version", title, err)
+	}
+	var target, kind string
+	if err := conn.QueryRowContext(ctx, `SELECT target_subreddit, kind FROM post_subreddit_refs WHERE post_id = 'imptp1'`).Scan(&target, &kind); err != nil ||
+		target != "importer_test_other" || kind != db.LinkTypeMention {
+		t.Errorf("post reference = %q %q (%v), want the mention of the last version", target, kind, err)
+	}
+	cp, found, err := loadCheckpoint(ctx, q, "RS_importer_test.zst")
+	if err != nil || !found || !cp.Completed || cp.Imported != stats.Imported() {
+		t.Errorf("checkpoint = %+v (found %v, err %v), want completed with %d records", cp, found, err, stats.Imported())
+	}
+}
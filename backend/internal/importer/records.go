@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
+)
+
+// Record kinds found in dump files. Submission and comment dumps share a format
+// (one JSON object per line); comments are recognized by their link_id.
+const (
+	KindSubmission = "submission"
+	KindComment    = "comment"
+)
+
+// unixTime is a created_utc value. Older dumps store it as a string, newer ones
+// as an integer or float.
+type unixTime struct{ time.Time }
+
+func (t *unixTime) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid created_utc %s", b)
+	}
+	sec, frac := math.Modf(f)
+	t.Time = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	return nil
+}
+
+// dumpRecord is the subset of a dump line the importer uses.
+type dumpRecord struct {
+	ID         string   `json:"id"`
+	Subreddit  string   `json:"subreddit"`
+	Author     string   `json:"author"`
+	CreatedUTC unixTime `json:"created_utc"`
+	Score      int      `json:"score"`
+
+	// Submissions
+	Title     string `json:"title"`
+	Selftext  string `json:"selftext"`
+	Permalink string `json:"permalink"`
+	URL       string `json:"url"`
+	Flair     string `json:"link_flair_text"`
+	IsSelf    bool   `json:"is_self"`
+	// CrosspostParentList holds the original post of a crosspost.
+	CrosspostParentList []struct {
+		Subreddit string `json:"subreddit"`
+	} `json:"crosspost_parent_list"`
+
+	// Comments
+	Body     string `json:"body"`
+	LinkID   string `json:"link_id"`
+	ParentID string `json:"parent_id"`
+}
+
+// Kind reports whether the record is a submission or a comment.
+func (r *dumpRecord) Kind() string {
+	if r.LinkID != "" {
+		return KindComment
+	}
+	return KindSubmission
+}
+
+// PostID is the ID of the submission a record belongs to, without the t3_ prefix.
+func (r *dumpRecord) PostID() string {
+	if r.Kind() == KindComment {
+		return strings.TrimPrefix(r.LinkID, "t3_")
+	}
+	return r.ID
+}
+
+// parseRecord decodes one dump line. Records without an ID or subreddit are
+// rejected.
+func parseRecord(line []byte) (dumpRecord, error) {
+	var r dumpRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return r, err
+	}
+	if r.ID == "" || r.Subreddit == "" {
+		return r, fmt.Errorf("record without id or subreddit")
+	}
+	return r, nil
+}
+
+// hasAuthor reports whether the record's author still exists. Deleted accounts
+// are skipped, as the crawler does.
+func (r *dumpRecord) hasAuthor() bool {
+	return r.Author != "" && r.Author != "[deleted]"
+}
+
+// toPost maps a submission record to the crawler's post type.
+func (r *dumpRecord) toPost() crawler.Post {
+	permalink := r.Permalink
+	if permalink == "" {
+		permalink = fmt.Sprintf("/r/%s/comments/%s/", r.Subreddit, r.ID)
+	}
+	return crawler.Post{
+		ID:         r.ID,
+		Title:      cleanText(r.Title),
+		Subreddit:  r.Subreddit,
+		Author:     r.Author,
+		Permalink:  permalink,
+		Score:      r.Score,
+		URL:        r.URL,
+		Flair:      cleanText(r.Flair),
+		CreatedUTC: float64(r.CreatedUTC.Unix()),
+		CreatedAt:  r.CreatedUTC.Time,
+		IsSelf:     r.IsSelf,
+		Selftext:   cleanText(r.Selftext),
+
+		CrosspostParentList: r.CrosspostParentList,
+	}
+}
+
+// toComment maps a comment record to the crawler's comment type. The parent ID
+// keeps its t1_/t3_ prefix, matching what the crawler stores.
+func (r *dumpRecord) toComment(depth int) crawler.Comment {
+	return crawler.Comment{
+		ID:        r.ID,
+		Author:    r.Author,
+		Body:      cleanText(r.Body),
+		CreatedAt: r.CreatedUTC.Time,
+		ParentID:  r.ParentID,
+		Depth:     depth,
+		Score:     r.Score,
+	}
+}
+
+// cleanText drops NUL characters, which occur in some dumps and which Postgres
+// rejects in text columns.
+func cleanText(s string) string {
+	return strings.ReplaceAll(s, "\x00", "")
+}
+
+// Filter selects the records to import.
+type Filter struct {
+	Subreddits map[string]bool // lower-case names; empty keeps every subreddit
+	After      time.Time       // inclusive lower bound on created_utc; zero for none
+	Before     time.Time       // exclusive upper bound on created_utc; zero for none
+}
+
+// NewFilter builds a filter from a list of subreddit names (with or without the
+// r/ prefix) and a date range.
+func NewFilter(subreddits []string, after, before time.Time) Filter {
+	f := Filter{After: after, Before: before}
+	for _, name := range subreddits {
+		name = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(name), "/"), "r/")
+		if name == "" {
+			continue
+		}
+		if f.Subreddits == nil {
+			f.Subreddits = make(map[string]bool)
+		}
+		f.Subreddits[strings.ToLower(name)] = true
+	}
+	return f
+}
+
+// Keep reports whether a record passes the filter.
+func (f Filter) Keep(r *dumpRecord) bool {
+	if len(f.Subreddits) > 0 && !f.Subreddits[strings.ToLower(r.Subreddit)] {
+		return false
+	}
+	created := r.CreatedUTC.Time
+	if !f.After.IsZero() && created.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !created.Before(f.Before) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Reddit dumps are compressed with a long-distance window, larger than the
+// decoder's default limit.
+const maxZstdWindow = 1 << 31
+
+// countingReader counts the bytes read from the underlying file, so progress can
+// be reported against the compressed file size.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// dumpStream reads the decompressed lines of a dump file.
+type dumpStream struct {
+	file   *os.File
+	raw    *countingReader
+	zr     *zstd.Decoder
+	br     *bufio.Reader
+	offset int64 // decompressed bytes consumed
+}
+
+// openDump opens a dump file. Files ending in .zst are decompressed; anything
+// else is read as plain NDJSON.
+func openDump(path string) (*dumpStream, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &dumpStream{file: f, raw: &countingReader{r: f}}
+	var r io.Reader = s.raw
+	if strings.HasSuffix(path, ".zst") {
+		s.zr, err = zstd.NewReader(s.raw, zstd.WithDecoderMaxWindow(maxZstdWindow), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("open zstd stream: %w", err)
+		}
+		r = s.zr
+	}
+	s.br = bufio.NewReaderSize(r, 1<<20)
+	return s, nil
+}
+
+// Skip discards n decompressed bytes, positioning the stream at a checkpoint.
+func (s *dumpStream) Skip(n int64) error {
+	skipped, err := io.CopyN(io.Discard, s.br, n)
+	s.offset += skipped
+	if err != nil {
+		return fmt.Errorf("skip to offset %d: %w", n, err)
+	}
+	return nil
+}
+
+// Next returns the next line without its trailing newline. It returns io.EOF
+// once the stream is exhausted; a final line without a newline is still returned.
+func (s *dumpStream) Next() ([]byte, error) {
+	line, err := s.br.ReadBytes('\n')
+	s.offset += int64(len(line))
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return trimEOL(line), nil
+}
+
+// Offset is the number of decompressed bytes consumed so far.
+func (s *dumpStream) Offset() int64 { return s.offset }
+
+// CompressedRead is the number of bytes read from the file so far.
+func (s *dumpStream) CompressedRead() int64 { return s.raw.n.Load() }
+
+func (s *dumpStream) Close() error {
+	if s.zr != nil {
+		s.zr.Close()
+	}
+	return s.file.Close()
+}
+
+func trimEOL(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return line
+}
@@ -12,7 +12,15 @@ var (
 			Name: "crawler_jobs_total",
 			Help: "Total number of crawl jobs processed",
 		},
-		[]string{"status"}, // status: success, failed
+		[]string{"type", "status"}, // type: subreddit, user, post, search; status: success, failed, terminal, interrupted, deferred
+	)
+
+	CrawlerJobFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crawler_job_failures_total",
+			Help: "Total number of failed crawl jobs by job type and failure reason",
+		},
+		[]string{"type", "reason"}, // reason: private, banned, quarantined, not_found, rate_limited, server_error, ...
 	)
 
 	CrawlerJobDuration = promauto.NewHistogramVec(
@@ -21,7 +29,15 @@ var (
 			Help:    "Duration of crawl jobs in seconds",
 			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"status"},
+		[]string{"type", "status"},
+	)
+
+	CrawlerDiscoveryExcluded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crawler_discovery_excluded_total",
+			Help: "Total number of discovered subreddits excluded by the discovery policy",
+		},
+		[]string{"reason"}, // reason: max_hops, denied, not_allowed, nsfw, min_subscribers
 	)
 
 	CrawlerHTTPRequests = promauto.NewCounterVec(
@@ -54,6 +70,54 @@ var (
 		},
 	)
 
+	CrawlerRateLimitRPS = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "crawler_rate_limit_rps",
+			Help: "Current request rate allowed by each OAuth credential's Reddit rate limiter",
+		},
+		[]string{"credential"},
+	)
+
+	RedditRateLimitRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "reddit_ratelimit_remaining",
+			Help: "Requests remaining in the current Reddit rate limit window (X-Ratelimit-Remaining), per OAuth credential",
+		},
+		[]string{"credential"},
+	)
+
+	RedditRateLimitUsed = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "reddit_ratelimit_used",
+			Help: "Requests used in the current Reddit rate limit window (X-Ratelimit-Used), per OAuth credential",
+		},
+		[]string{"credential"},
+	)
+
+	RedditRateLimitResetSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "reddit_ratelimit_reset_seconds",
+			Help: "Seconds until the Reddit rate limit window resets (X-Ratelimit-Reset), per OAuth credential",
+		},
+		[]string{"credential"},
+	)
+
+	RedditRateLimitExhausted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reddit_ratelimit_exhausted_total",
+			Help: "Total number of times an OAuth credential's Reddit quota ran out and it paused until reset",
+		},
+		[]string{"credential"},
+	)
+
+	CrawlerCredentialRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crawler_credential_requests_total",
+			Help: "Total number of Reddit API requests scheduled on each OAuth credential",
+		},
+		[]string{"credential"},
+	)
+
 	CrawlerPostsProcessed = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "crawler_posts_processed_total",
@@ -68,6 +132,50 @@ var (
 		},
 	)
 
+	CrawlerContentEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crawler_content_events_total",
+			Help: "Edits, deletions, removals and restorations observed on recrawled posts and comments",
+		},
+		[]string{"content_type", "event"}, // content_type: post, comment; event: edited, deleted, removed, restored
+	)
+
+	CrawlerCommentsExpanded = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "crawler_comments_expanded_total",
+			Help: "Total number of comments fetched by expanding more stubs via /api/morechildren",
+		},
+	)
+
+	CrawlerCommentsSkipped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "crawler_comments_skipped_total",
+			Help: "Total number of comments behind more stubs left unresolved (budget, depth or errors)",
+		},
+	)
+
+	CrawlerCrawlsByMode = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crawler_crawls_total",
+			Help: "Total number of subreddit crawls by mode",
+		},
+		[]string{"mode"}, // mode: full, incremental
+	)
+
+	CrawlerWorkersBusy = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "crawler_workers_busy",
+			Help: "Number of crawler workers currently processing a job",
+		},
+	)
+
+	CrawlerLeasesLost = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "crawler_leases_lost_total",
+			Help: "Total number of job leases lost because a heartbeat found the job reclaimed",
+		},
+	)
+
 	// Crawl job status gauges
 	CrawlJobsPending = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -16,7 +16,9 @@ WHERE id = $1;
 -- name: GetCrawlJobByID :one
 SELECT 
   id,
-  subreddit_id,
+  COALESCE(subreddit_id, 0)::int AS subreddit_id,
+  job_type,
+  payload,
   status,
   retries,
   priority,
@@ -30,18 +32,23 @@ WHERE id = $1;
 -- name: ListCrawlJobsByStatus :many
 SELECT
   cj.id,
-  cj.subreddit_id,
-  s.name AS subreddit_name,
+  COALESCE(cj.subreddit_id, 0)::int AS subreddit_id,
+  COALESCE(s.name, '')::text AS subreddit_name,
+  cj.job_type,
+  cj.payload,
   cj.status,
   cj.retries,
   cj.priority,
   cj.last_attempt,
   cj.enqueued_by,
   cj.created_at,
-  cj.updated_at
+  cj.updated_at,
+  cj.failure_reason,
+  cj.last_error
 FROM crawl_jobs cj
-JOIN subreddits s ON s.id = cj.subreddit_id
+LEFT JOIN subreddits s ON s.id = cj.subreddit_id
 WHERE cj.status = $1
+  AND ($4::text = '' OR cj.job_type = $4::text)
 ORDER BY cj.priority DESC, cj.created_at DESC
 LIMIT $2::int OFFSET $3::int;
 
@@ -51,6 +58,7 @@ SELECT
   COUNT(*) FILTER (WHERE status = 'crawling') AS running_count,
   COUNT(*) FILTER (WHERE status = 'failed') AS failed_count,
   COUNT(*) FILTER (WHERE status = 'success') AS completed_count,
+  COUNT(*) FILTER (WHERE status = 'terminal') AS terminal_count,
   COUNT(*) AS total_count
 FROM crawl_jobs;
 
@@ -3,11 +3,18 @@ INSERT INTO crawl_jobs (subreddit_id, status, retries, enqueued_by)
 SELECT $1, 'queued', 0, $2
 WHERE NOT EXISTS (SELECT 1 FROM crawl_jobs WHERE subreddit_id = $1);
 
+-- name: EnqueueTypedCrawlJob :one
+INSERT INTO crawl_jobs (job_type, dedupe_key, payload, status, retries, priority, max_retries, enqueued_by)
+VALUES ($1, $2, $3, 'queued', 0, $4, $5, $6)
+ON CONFLICT (job_type, dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('queued', 'crawling') DO NOTHING
+RETURNING id;
+
 -- name: MarkCrawlJobStarted :exec
 UPDATE crawl_jobs SET status = 'crawling', last_attempt = now(), updated_at = now() WHERE id = $1;
 
--- name: MarkCrawlJobSuccess :exec
-UPDATE crawl_jobs SET status = 'success', updated_at = now() WHERE id = $1;
+-- name: MarkCrawlJobSuccess :execrows
+UPDATE crawl_jobs SET status = 'success', failure_reason = NULL, last_error = NULL, updated_at = now()
+WHERE id = $1 AND lease_owner = $2 AND status = 'crawling';
 
 -- name: MarkCrawlJobFailed :exec
 UPDATE crawl_jobs SET status = 'failed', retries = retries + 1, updated_at = now() WHERE id = $1;
@@ -15,7 +22,8 @@ UPDATE crawl_jobs SET status = 'failed', retries = retries + 1, updated_at = now
 -- name: ListCrawlJobs :many
 SELECT
   id,
-  subreddit_id,
+  COALESCE(subreddit_id, 0)::int AS subreddit_id,
+  job_type,
   status,
   retries,
   priority,
@@ -70,7 +78,13 @@ SET status = 'queued',
 WHERE status = 'failed' 
   AND next_retry_at IS NOT NULL 
   AND next_retry_at <= now()
-  AND retry_count < max_retries;
+  AND retry_count < max_retries
+  AND NOT EXISTS (
+    SELECT 1 FROM crawl_jobs newer
+    WHERE newer.job_type = crawl_jobs.job_type
+      AND newer.dedupe_key = crawl_jobs.dedupe_key
+      AND newer.id <> crawl_jobs.id
+      AND (newer.id > crawl_jobs.id OR newer.status IN ('queued', 'crawling')));
 
 -- name: AgeStarvedJobs :exec
 UPDATE crawl_jobs
@@ -30,20 +30,62 @@ ORDER BY data_type, id;
 -- Uses EXISTS subqueries for better performance on large datasets
 -- Note: statement_timeout is enforced at application level via context timeout
 WITH sel_nodes AS (
-    SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z
+    -- Only one branch runs: by val (using idx_graph_nodes_val_numeric) when no
+    -- centrality order is requested, otherwise by that centrality measure, each
+    -- with its own index (idx_graph_nodes_pagerank, ...)
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
     FROM graph_nodes gn
+    WHERE $6::text = ''
     ORDER BY (
         CASE WHEN gn.val ~ '^[0-9]+$' THEN CAST(gn.val AS BIGINT) ELSE 0 END
     ) DESC NULLS LAST, gn.id
-    LIMIT $1
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'pagerank'
+    ORDER BY gn.pagerank DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'weighted_degree'
+    ORDER BY gn.weighted_degree DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'betweenness'
+    ORDER BY gn.betweenness DESC NULLS LAST, gn.id
+    LIMIT $1)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE $6::text = 'kcore'
+    ORDER BY gn.kcore DESC NULLS LAST, gn.id
+    LIMIT $1)
 ), sel_node_ids AS MATERIALIZED (
     -- Explicitly materialize IDs for efficient hash lookups in EXISTS subqueries
     SELECT id FROM sel_nodes
 ), sel_links AS (
-    SELECT gl.id, gl.source, gl.target
-    FROM graph_links gl
-    WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
-      AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+    SELECT id, source, target, weight, link_type
+    FROM (
+        -- One link per node pair: the heaviest of its types that pass the filter
+        SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+        FROM graph_links gl
+        WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
+          AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+          AND gl.weight >= $3::float8
+          AND (COALESCE(cardinality($4::text[]), 0) = 0 OR gl.link_type = ANY($4::text[]))
+          AND ($5::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $5::float8)
+        ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+    ) pair_links
+    ORDER BY weight DESC, id
     LIMIT $2
 )
 SELECT
@@ -56,7 +98,13 @@ SELECT
     n.pos_y,
     n.pos_z,
         NULL AS source,
-        NULL AS target
+        NULL AS target,
+        CAST(NULL AS DOUBLE PRECISION) AS weight,
+        CAST(NULL AS TEXT) AS link_type,
+    n.pagerank,
+    n.weighted_degree,
+    n.betweenness,
+    n.kcore
 FROM sel_nodes n
 UNION ALL
 SELECT
@@ -69,7 +117,13 @@ SELECT
     NULL as pos_y,
     NULL as pos_z,
         l.source,
-        l.target
+        l.target,
+        l.weight,
+        l.link_type,
+    CAST(NULL AS DOUBLE PRECISION) AS pagerank,
+    CAST(NULL AS DOUBLE PRECISION) AS weighted_degree,
+    CAST(NULL AS DOUBLE PRECISION) AS betweenness,
+    CAST(NULL AS INTEGER) AS kcore
 FROM sel_links l
 ORDER BY data_type, id;
 
@@ -78,21 +132,62 @@ ORDER BY data_type, id;
 -- Uses EXISTS subqueries for better performance than IN subqueries
 -- Note: statement_timeout is enforced at application level via context timeout
 WITH sel_nodes AS (
-    SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z
+    -- Only one branch runs: by val (using idx_graph_nodes_val_numeric) when no
+    -- centrality order is requested, otherwise by that centrality measure, each
+    -- with its own index (idx_graph_nodes_pagerank, ...)
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
     FROM graph_nodes gn
-    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[])
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = ''
     ORDER BY (
         CASE WHEN gn.val ~ '^[0-9]+$' THEN CAST(gn.val AS BIGINT) ELSE 0 END
     ) DESC NULLS LAST, gn.id
-    LIMIT $2
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'pagerank'
+    ORDER BY gn.pagerank DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'weighted_degree'
+    ORDER BY gn.weighted_degree DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'betweenness'
+    ORDER BY gn.betweenness DESC NULLS LAST, gn.id
+    LIMIT $2)
+    UNION ALL
+    (SELECT gn.id, gn.name, gn.val, gn.type, gn.pos_x, gn.pos_y, gn.pos_z,
+           gn.pagerank, gn.weighted_degree, gn.betweenness, gn.kcore
+    FROM graph_nodes gn
+    WHERE gn.type IS NOT NULL AND gn.type = ANY($1::text[]) AND $7::text = 'kcore'
+    ORDER BY gn.kcore DESC NULLS LAST, gn.id
+    LIMIT $2)
 ), sel_node_ids AS MATERIALIZED (
     -- Explicitly materialize IDs for efficient hash lookups in EXISTS subqueries
     SELECT id FROM sel_nodes
 ), sel_links AS (
-    SELECT gl.id, gl.source, gl.target
-    FROM graph_links gl
-    WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
-      AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+    SELECT id, source, target, weight, link_type
+    FROM (
+        -- One link per node pair: the heaviest of its types that pass the filter
+        SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+        FROM graph_links gl
+        WHERE EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.source)
+          AND EXISTS (SELECT 1 FROM sel_node_ids WHERE id = gl.target)
+          AND gl.weight >= $4::float8
+          AND (COALESCE(cardinality($5::text[]), 0) = 0 OR gl.link_type = ANY($5::text[]))
+          AND ($6::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $6::float8)
+        ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+    ) pair_links
+    ORDER BY weight DESC, id
     LIMIT $3
 )
 SELECT
@@ -105,7 +200,13 @@ SELECT
     n.pos_y,
     n.pos_z,
         NULL AS source,
-        NULL AS target
+        NULL AS target,
+        CAST(NULL AS DOUBLE PRECISION) AS weight,
+        CAST(NULL AS TEXT) AS link_type,
+    n.pagerank,
+    n.weighted_degree,
+    n.betweenness,
+    n.kcore
 FROM sel_nodes n
 UNION ALL
 SELECT
@@ -118,7 +219,13 @@ SELECT
     NULL as pos_y,
     NULL as pos_z,
         l.source,
-        l.target
+        l.target,
+        l.weight,
+        l.link_type,
+    CAST(NULL AS DOUBLE PRECISION) AS pagerank,
+    CAST(NULL AS DOUBLE PRECISION) AS weighted_degree,
+    CAST(NULL AS DOUBLE PRECISION) AS betweenness,
+    CAST(NULL AS INTEGER) AS kcore
 FROM sel_links l
 ORDER BY data_type, id;
 
@@ -172,9 +279,11 @@ SET
 -- name: CreateGraphLink :one
 INSERT INTO graph_links (
     source,
-    target
+    target,
+    weight,
+    link_type
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: ClearGraphTables :exec
@@ -184,11 +293,11 @@ INSERT INTO graph_nodes (id, name, val, type)
 VALUES ($1, $2, $3, $4);
 
 -- name: BulkInsertGraphLink :exec
-INSERT INTO graph_links (source, target)
-SELECT $1, $2
+INSERT INTO graph_links (source, target, weight, link_type)
+SELECT $1, $2, $3, $4
 WHERE EXISTS (SELECT 1 FROM graph_nodes WHERE id = $1)
     AND EXISTS (SELECT 1 FROM graph_nodes WHERE id = $2)
-ON CONFLICT (source, target) DO NOTHING;
+ON CONFLICT (source, target, link_type) DO UPDATE SET weight = EXCLUDED.weight, updated_at = now();
 
 -- name: GetAllSubreddits :many
 SELECT id, name, subscribers
@@ -225,12 +334,22 @@ JOIN other_activity oa ON ua.author_id = oa.author_id;
 INSERT INTO subreddit_relationships (
     source_subreddit_id,
     target_subreddit_id,
-    overlap_count
+    overlap_count,
+    jaccard,
+    cosine,
+    pmi,
+    npmi,
+    lift
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) ON CONFLICT (source_subreddit_id, target_subreddit_id)
 DO UPDATE SET
     overlap_count = EXCLUDED.overlap_count,
+    jaccard = EXCLUDED.jaccard,
+    cosine = EXCLUDED.cosine,
+    pmi = EXCLUDED.pmi,
+    npmi = EXCLUDED.npmi,
+    lift = EXCLUDED.lift,
     updated_at = now()
 RETURNING *;
 
@@ -267,7 +386,7 @@ INSERT INTO user_subreddit_activity (
 TRUNCATE TABLE user_subreddit_activity;
 
 -- name: GetAllSubredditRelationships :many
-SELECT source_subreddit_id, target_subreddit_id, overlap_count
+SELECT source_subreddit_id, target_subreddit_id, overlap_count, jaccard, cosine, pmi, npmi, lift
 FROM subreddit_relationships;
 
 -- name: GetAllUserSubredditActivity :many
@@ -307,7 +426,7 @@ ORDER BY (
 LIMIT $1;
 
 -- name: ListGraphLinksAmong :many
-SELECT source, target
+SELECT source, target, weight, link_type
 FROM graph_links
 WHERE source = ANY($1::text[]) AND target = ANY($1::text[]);
 
@@ -644,13 +763,19 @@ WITH bbox_nodes AS (
       AND pos_y BETWEEN $3 AND $4
       AND pos_z BETWEEN $5 AND $6
 )
-SELECT 
-    gl.id,
-    gl.source,
-    gl.target
-FROM graph_links gl
-WHERE EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.source)
-  AND EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.target)
+SELECT id, source, target, weight, link_type
+FROM (
+    -- One link per node pair: the heaviest of its types that pass the filter
+    SELECT DISTINCT ON (gl.source, gl.target) gl.id, gl.source, gl.target, gl.weight, gl.link_type
+    FROM graph_links gl
+    WHERE EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.source)
+      AND EXISTS (SELECT 1 FROM bbox_nodes WHERE id = gl.target)
+      AND gl.weight >= $8::float8
+      AND (COALESCE(cardinality($9::text[]), 0) = 0 OR gl.link_type = ANY($9::text[]))
+      AND ($10::float8 <= 0 OR gl.backbone_pvalue IS NULL OR gl.backbone_pvalue <= $10::float8)
+    ORDER BY gl.source, gl.target, gl.weight DESC, gl.id
+) pair_links
+ORDER BY weight DESC, id
 LIMIT $7;
 
 -- name: CountNodesInBoundingBox :one
@@ -820,13 +945,19 @@ LIMIT $3;
 -- name: GetLinksForPaginatedNodes :many
 -- Get links where both source and target are in the provided node ID list
 -- Parameters: $1=node_ids (text array)
-SELECT 
-    id,
-    source,
-    target
-FROM graph_links
-WHERE source = ANY($1::text[]) 
-  AND target = ANY($1::text[])
+SELECT id, source, target, weight, link_type
+FROM (
+    -- One link per node pair: the heaviest of its types that pass the filter
+    SELECT DISTINCT ON (source, target) id, source, target, weight, link_type
+    FROM graph_links
+    WHERE source = ANY($1::text[])
+      AND target = ANY($1::text[])
+      AND weight >= $3::float8
+      AND (COALESCE(cardinality($4::text[]), 0) = 0 OR link_type = ANY($4::text[]))
+      AND ($5::float8 <= 0 OR backbone_pvalue IS NULL OR backbone_pvalue <= $5::float8)
+    ORDER BY source, target, weight DESC, id
+) pair_links
+ORDER BY weight DESC, id
 LIMIT $2;
 
 -- ============================================================
@@ -956,7 +1087,11 @@ SELECT
     type,
     pos_x,
     pos_y,
-    pos_z
+    pos_z,
+    pagerank,
+    weighted_degree,
+    betweenness,
+    kcore
 FROM graph_nodes
 WHERE id = $1;
 
@@ -2,6 +2,7 @@ package redditapi
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -23,6 +24,22 @@ const (
 	ErrorQuarantined
 )
 
+// Failure reasons are stable names for error types, suitable for storing on crawl
+// jobs and using as metric labels.
+const (
+	ReasonPrivate      = "private"
+	ReasonBanned       = "banned"
+	ReasonQuarantined  = "quarantined"
+	ReasonNotFound     = "not_found"
+	ReasonRateLimited  = "rate_limited"
+	ReasonServerError  = "server_error"
+	ReasonForbidden    = "forbidden"
+	ReasonUnauthorized = "unauthorized"
+	ReasonBadRequest   = "bad_request"
+	ReasonNetwork      = "network_error"
+	ReasonUnknown      = "unknown"
+)
+
 // APIError represents a Reddit API error with additional context
 type APIError struct {
 	Type       ErrorType
@@ -35,6 +52,41 @@ func (e *APIError) Error() string {
 	return e.Message
 }
 
+// Reason returns the stable failure reason for the error type.
+func (e *APIError) Reason() string {
+	switch e.Type {
+	case ErrorPrivateSubreddit:
+		return ReasonPrivate
+	case ErrorBannedSubreddit:
+		return ReasonBanned
+	case ErrorQuarantined:
+		return ReasonQuarantined
+	case ErrorNotFound:
+		return ReasonNotFound
+	case ErrorRateLimited:
+		return ReasonRateLimited
+	case ErrorServerError:
+		return ReasonServerError
+	case ErrorForbidden:
+		return ReasonForbidden
+	case ErrorUnauthorized:
+		return ReasonUnauthorized
+	case ErrorBadRequest:
+		return ReasonBadRequest
+	default:
+		return ReasonUnknown
+	}
+}
+
+// AsAPIError extracts an *APIError from an error chain, if present.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
 // RedditErrorResponse represents the JSON structure of Reddit error responses
 type RedditErrorResponse struct {
 	Message string `json:"message"`
@@ -96,10 +148,13 @@ func ClassifyError(resp *http.Response) *APIError {
 		apiErr.Message = "forbidden (403)"
 		apiErr.Retryable = false
 
-		// Check for quarantined subreddit
+		// Check for quarantined or private subreddit (Reddit reports both as 403)
 		if strings.Contains(bodyText, "quarantined") || redditErr.Reason == "quarantined" {
 			apiErr.Type = ErrorQuarantined
 			apiErr.Message = "subreddit is quarantined"
+		} else if redditErr.Reason == "private" {
+			apiErr.Type = ErrorPrivateSubreddit
+			apiErr.Message = "subreddit is private"
 		}
 
 	case http.StatusUnauthorized:
@@ -156,3 +211,15 @@ func IsPermanent(err *APIError) bool {
 		err.Type == ErrorBadRequest ||
 		err.Type == ErrorForbidden
 }
+
+// IsUnavailableSubreddit reports whether Reddit said the subreddit itself is
+// private, banned or quarantined. Unlike other 4xx errors, which Reddit's edge
+// also returns transiently, these do not go away on retry.
+func IsUnavailableSubreddit(err *APIError) bool {
+	if err == nil {
+		return false
+	}
+	return err.Type == ErrorPrivateSubreddit ||
+		err.Type == ErrorBannedSubreddit ||
+		err.Type == ErrorQuarantined
+}
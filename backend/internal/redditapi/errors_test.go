@@ -1,6 +1,8 @@
 package redditapi
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -123,3 +125,79 @@ func TestIsPermanent(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyError_PrivateSubredditForbidden(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Body:       io.NopCloser(strings.NewReader(`{"reason": "private", "message": "Forbidden", "error": 403}`)),
+	}
+
+	err := ClassifyError(resp)
+	if err.Type != ErrorPrivateSubreddit {
+		t.Errorf("Expected ErrorPrivateSubreddit, got %v", err.Type)
+	}
+	if !IsPermanent(err) {
+		t.Error("Expected private subreddit error to be permanent")
+	}
+}
+
+func TestAPIError_Reason(t *testing.T) {
+	tests := []struct {
+		errType ErrorType
+		reason  string
+	}{
+		{ErrorPrivateSubreddit, ReasonPrivate},
+		{ErrorBannedSubreddit, ReasonBanned},
+		{ErrorQuarantined, ReasonQuarantined},
+		{ErrorNotFound, ReasonNotFound},
+		{ErrorRateLimited, ReasonRateLimited},
+		{ErrorServerError, ReasonServerError},
+		{ErrorForbidden, ReasonForbidden},
+		{ErrorUnauthorized, ReasonUnauthorized},
+		{ErrorBadRequest, ReasonBadRequest},
+		{ErrorUnknown, ReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			err := &APIError{Type: tt.errType}
+			if got := err.Reason(); got != tt.reason {
+				t.Errorf("Reason() = %q, want %q", got, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAsAPIError_Wrapped(t *testing.T) {
+	apiErr := &APIError{Type: ErrorBannedSubreddit, Message: "subreddit is banned"}
+	wrapped := fmt.Errorf("failed to fetch subreddit: %w", apiErr)
+
+	got, ok := AsAPIError(wrapped)
+	if !ok || got != apiErr {
+		t.Fatalf("expected wrapped APIError to be extracted, got %v (ok=%v)", got, ok)
+	}
+	if _, ok := AsAPIError(errors.New("plain")); ok {
+		t.Error("expected plain error not to be an APIError")
+	}
+}
+
+func TestIsUnavailableSubreddit(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusForbidden, `{"reason": "quarantined"}`, true},
+		{http.StatusForbidden, `{"reason": "private"}`, true},
+		{http.StatusNotFound, `{"reason": "banned"}`, true},
+		// Edge and rate-limit 403s carry no subreddit reason
+		{http.StatusForbidden, `<html>blocked</html>`, false},
+		{http.StatusBadRequest, ``, false},
+		{http.StatusNotFound, `{"message": "Not Found", "error": 404}`, false},
+	} {
+		resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+		if got := IsUnavailableSubreddit(ClassifyError(resp)); got != tt.want {
+			t.Errorf("IsUnavailableSubreddit(%d %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix versions the format of sealed values so the scheme can change later.
+const sealedPrefix = "v1:"
+
+// ErrNoKey is returned when a secret must be sealed or opened but no key is configured.
+var ErrNoKey = errors.New("no encryption key configured")
+
+// ParseKey decodes a base64-encoded AES-256 key, e.g. from `openssl rand -base64 32`.
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, ErrNoKey
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid encryption key: want 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-256-GCM. The context (e.g. the row label) is
+// authenticated but not stored, so a sealed value only opens under the same context
+// and cannot be copied to another row.
+func Seal(key []byte, plaintext, context string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a value produced by Seal with the same key and context.
+func Open(key []byte, sealed, context string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		return "", errors.New("sealed secret has an unknown format")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errors.New("sealed secret is malformed")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(context))
+	if err != nil {
+		return "", errors.New("sealed secret does not match the key")
+	}
+	return string(plain), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrNoKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
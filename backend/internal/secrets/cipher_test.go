@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key := testKey(1)
+	sealed, err := Seal(key, "hunter2-secret", "app-2")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if strings.Contains(sealed, "hunter2") {
+		t.Fatalf("sealed value leaks the plaintext: %q", sealed)
+	}
+	got, err := Open(key, sealed, "app-2")
+	if err != nil || got != "hunter2-secret" {
+		t.Fatalf("Open = %q, %v; want the original secret", got, err)
+	}
+
+	again, _ := Seal(key, "hunter2-secret", "app-2")
+	if again == sealed {
+		t.Error("sealing twice should use fresh nonces")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	key := testKey(1)
+	sealed, _ := Seal(key, "secret", "app-2")
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	raw[len(raw)-1] ^= 1
+	tampered := sealedPrefix + base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name    string
+		key     []byte
+		sealed  string
+		context string
+	}{
+		{"wrong key", testKey(2), sealed, "app-2"},
+		{"other row", key, sealed, "app-3"},
+		{"tampered", key, tampered, "app-2"},
+		{"plaintext", key, "secret", "app-2"},
+		{"truncated", key, sealedPrefix + "AAAA", "app-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Open(tt.key, tt.sealed, tt.context); err == nil {
+				t.Errorf("Open succeeded with %q", got)
+			}
+		})
+	}
+	if _, err := Open(nil, sealed, "app-2"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Open without a key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(testKey(7))
+	if key, err := ParseKey(" " + valid + "\n"); err != nil || !bytes.Equal(key, testKey(7)) {
+		t.Errorf("ParseKey(valid) = %v, %v", key, err)
+	}
+	if _, err := ParseKey(""); !errors.Is(err, ErrNoKey) {
+		t.Errorf("ParseKey(\"\") = %v, want ErrNoKey", err)
+	}
+	for _, bad := range []string{"not base64!", base64.StdEncoding.EncodeToString(testKey(7)[:16])} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", bad)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/onnwee/reddit-cluster-map/backend/internal/crawler"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/db"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/graph"
 	"github.com/onnwee/reddit-cluster-map/backend/internal/metrics"
@@ -113,8 +114,7 @@ func (s *Server) Start(ctx context.Context) error {
 		// Try to fetch pending jobs; if none, enqueue a few defaults
 		jobs, err := s.DB.ListQueueWithNames(ctx)
 		if err == nil && len(jobs) == 0 {
-			defaults := []string{"AskReddit", "worldnews", "technology"}
-			for _, name := range defaults {
+			for _, name := range crawler.DefaultSubs {
 				id, err := s.DB.EnsureSubreddit(ctx, db.EnsureSubredditParams{
 					Name:        name,
 					Title:       sql.NullString{String: name, Valid: true},
@@ -125,6 +125,7 @@ func (s *Server) Start(ctx context.Context) error {
 					_ = s.DB.EnqueueCrawlJob(ctx, db.EnqueueCrawlJobParams{SubredditID: id})
 				}
 			}
+			_ = s.DB.MarkSubredditSeeds(ctx, crawler.DefaultSubs)
 		}
 	}()
 	return nil
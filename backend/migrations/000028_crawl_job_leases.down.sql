@@ -0,0 +1,5 @@
+-- Revert per-worker lease fields
+DROP INDEX IF EXISTS idx_crawl_jobs_lease_expiry;
+
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS lease_owner;
@@ -0,0 +1,12 @@
+-- Per-worker leases for concurrent crawl workers.
+-- visible_at doubles as the lease expiry while a job is 'crawling': the owning
+-- worker pushes it forward with heartbeats, and an expired lease makes the job
+-- claimable again by any worker in any crawler process.
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS lease_owner TEXT;
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ;
+
+-- Index for finding crawling jobs whose lease has expired
+CREATE INDEX IF NOT EXISTS idx_crawl_jobs_lease_expiry ON crawl_jobs(visible_at) WHERE status = 'crawling';
+
+COMMENT ON COLUMN crawl_jobs.lease_owner IS 'Identifier of the crawler worker that last leased the job';
+COMMENT ON COLUMN crawl_jobs.heartbeat_at IS 'Timestamp of the last lease heartbeat from the owning worker';
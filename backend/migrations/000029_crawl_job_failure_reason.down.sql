@@ -0,0 +1,7 @@
+-- Revert structured failure reasons
+DROP INDEX IF EXISTS idx_crawl_jobs_failure_reason;
+
+UPDATE crawl_jobs SET status = 'failed' WHERE status = 'terminal';
+
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS last_error;
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS failure_reason;
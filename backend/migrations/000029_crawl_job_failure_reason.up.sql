@@ -0,0 +1,10 @@
+-- Structured failure reasons for crawl jobs.
+-- Permanent Reddit errors (private, banned, quarantined, not found) move a job to the
+-- 'terminal' status, which is never requeued automatically.
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_crawl_jobs_failure_reason ON crawl_jobs(failure_reason) WHERE failure_reason IS NOT NULL;
+
+COMMENT ON COLUMN crawl_jobs.failure_reason IS 'Reason of the last failed attempt: private, banned, quarantined, not_found, rate_limited, server_error, ...';
+COMMENT ON COLUMN crawl_jobs.last_error IS 'Error message of the last failed attempt';
@@ -0,0 +1,2 @@
+-- Drop reported circuit breaker states
+DROP TABLE IF EXISTS crawler_circuit_breakers;
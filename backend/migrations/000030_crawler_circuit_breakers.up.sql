@@ -0,0 +1,18 @@
+-- Reddit circuit breaker states reported by crawler processes.
+-- Breakers live in memory in each crawler; the crawler upserts a snapshot every
+-- few seconds so the API server can expose them on the admin API.
+CREATE TABLE IF NOT EXISTS crawler_circuit_breakers (
+    instance TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    state TEXT NOT NULL,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ,
+    retry_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (instance, endpoint)
+);
+
+COMMENT ON COLUMN crawler_circuit_breakers.instance IS 'Identifier of the reporting crawler process';
+COMMENT ON COLUMN crawler_circuit_breakers.endpoint IS 'Reddit endpoint class: about, listing, comments, user or token';
+COMMENT ON COLUMN crawler_circuit_breakers.state IS 'Breaker state: closed, open or half-open';
+COMMENT ON COLUMN crawler_circuit_breakers.retry_at IS 'When an open breaker lets the next probe request through';
@@ -0,0 +1,2 @@
+-- Revert per-subreddit listing plans
+ALTER TABLE subreddits DROP COLUMN IF EXISTS listing_plan;
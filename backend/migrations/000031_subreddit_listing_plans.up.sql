@@ -0,0 +1,6 @@
+-- Per-subreddit listing plans.
+-- A plan lists the listings crawled for a subreddit (e.g. 'hot,new,top:month@3,rising').
+-- NULL means the subreddit follows the default plan from the crawler configuration.
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS listing_plan TEXT;
+
+COMMENT ON COLUMN subreddits.listing_plan IS 'Comma-separated sort[:time][@pages] listings to crawl; NULL uses the default plan';
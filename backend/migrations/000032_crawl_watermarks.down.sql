@@ -0,0 +1,4 @@
+-- Revert incremental crawling state
+DROP INDEX IF EXISTS idx_posts_subreddit_created_at;
+DROP TABLE IF EXISTS post_comment_checks;
+DROP TABLE IF EXISTS subreddit_watermarks;
@@ -0,0 +1,25 @@
+-- Incremental crawling state.
+-- Kept out of subreddits/posts so that recording it does not bump their updated_at
+-- columns, which drive incremental precalculation.
+CREATE TABLE IF NOT EXISTS subreddit_watermarks (
+    subreddit_id INTEGER PRIMARY KEY REFERENCES subreddits(id) ON DELETE CASCADE,
+    newest_post_fullname TEXT,
+    newest_post_created_at TIMESTAMPTZ,
+    newest_post_seen_at TIMESTAMPTZ,
+    last_full_crawl_at TIMESTAMPTZ,
+    last_incremental_crawl_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS post_comment_checks (
+    post_id TEXT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    comment_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_subreddit_created_at ON posts(subreddit_id, created_at DESC);
+
+COMMENT ON COLUMN subreddit_watermarks.newest_post_fullname IS 'Fullname (t3_...) of the newest post seen; used as the before cursor of incremental crawls';
+COMMENT ON COLUMN subreddit_watermarks.newest_post_seen_at IS 'When the crawler first saw the newest post';
+COMMENT ON COLUMN subreddit_watermarks.last_full_crawl_at IS 'Last crawl that ran the full listing plan';
+COMMENT ON COLUMN post_comment_checks.checked_at IS 'When the post''s comment tree was last fetched';
@@ -0,0 +1,3 @@
+-- Revert the OAuth credential pool
+DROP TABLE IF EXISTS reddit_credential_states;
+DROP TABLE IF EXISTS reddit_credentials;
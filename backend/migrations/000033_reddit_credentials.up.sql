@@ -0,0 +1,31 @@
+-- Pool of Reddit OAuth apps used by the crawler in addition to REDDIT_CLIENT_ID/SECRET.
+-- Crawlers reload this table periodically, so rows can be added or disabled at runtime.
+CREATE TABLE IF NOT EXISTS reddit_credentials (
+    id SERIAL PRIMARY KEY,
+    label TEXT NOT NULL UNIQUE,
+    client_id TEXT NOT NULL,
+    client_secret_sealed TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Per-process view of each credential, reported by crawlers for the admin API.
+CREATE TABLE IF NOT EXISTS reddit_credential_states (
+    instance TEXT NOT NULL,
+    credential TEXT NOT NULL,
+    status TEXT NOT NULL,
+    in_flight INTEGER NOT NULL DEFAULT 0,
+    requests_total BIGINT NOT NULL DEFAULT 0,
+    quota_remaining DOUBLE PRECISION,
+    quota_used INTEGER,
+    quota_reset_at TIMESTAMPTZ,
+    token_expires_at TIMESTAMPTZ,
+    last_error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (instance, credential)
+);
+
+COMMENT ON COLUMN reddit_credentials.label IS 'Name used in metrics and logs; "env" is reserved for REDDIT_CLIENT_ID';
+COMMENT ON COLUMN reddit_credentials.client_secret_sealed IS 'AES-256-GCM sealed client secret (internal/secrets.Seal with the label as context), keyed by REDDIT_CREDENTIALS_KEY';
+COMMENT ON COLUMN reddit_credential_states.status IS 'active, exhausted (quota paused until reset), draining (disabled, finishing in-flight requests) or revoked';
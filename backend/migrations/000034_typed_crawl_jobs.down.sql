@@ -0,0 +1,12 @@
+-- Revert typed crawl jobs; non-subreddit jobs cannot be represented without a subreddit
+DELETE FROM crawl_jobs WHERE job_type <> 'subreddit';
+
+DROP INDEX IF EXISTS idx_crawl_jobs_type_status;
+DROP INDEX IF EXISTS idx_crawl_jobs_type_dedupe;
+ALTER TABLE crawl_jobs DROP CONSTRAINT IF EXISTS crawl_jobs_target_check;
+ALTER TABLE crawl_jobs DROP CONSTRAINT IF EXISTS crawl_jobs_job_type_check;
+
+ALTER TABLE crawl_jobs ALTER COLUMN subreddit_id SET NOT NULL;
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS dedupe_key;
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS payload;
+ALTER TABLE crawl_jobs DROP COLUMN IF EXISTS job_type;
@@ -0,0 +1,23 @@
+-- Typed crawl jobs: besides subreddits, the queue holds user history, single post
+-- thread and keyword search jobs. Non-subreddit jobs have no subreddit_id and are
+-- identified by (job_type, dedupe_key); their parameters live in payload. Only one
+-- job per identity may be queued or crawling at a time; finished ones can be re-run.
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS job_type TEXT NOT NULL DEFAULT 'subreddit';
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS payload JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE crawl_jobs ADD COLUMN IF NOT EXISTS dedupe_key TEXT;
+ALTER TABLE crawl_jobs ALTER COLUMN subreddit_id DROP NOT NULL;
+
+ALTER TABLE crawl_jobs DROP CONSTRAINT IF EXISTS crawl_jobs_job_type_check;
+ALTER TABLE crawl_jobs ADD CONSTRAINT crawl_jobs_job_type_check
+    CHECK (job_type IN ('subreddit', 'user', 'post', 'search'));
+ALTER TABLE crawl_jobs DROP CONSTRAINT IF EXISTS crawl_jobs_target_check;
+ALTER TABLE crawl_jobs ADD CONSTRAINT crawl_jobs_target_check
+    CHECK ((job_type = 'subreddit' AND subreddit_id IS NOT NULL) OR (job_type <> 'subreddit' AND dedupe_key IS NOT NULL));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crawl_jobs_type_dedupe ON crawl_jobs(job_type, dedupe_key)
+    WHERE dedupe_key IS NOT NULL AND status IN ('queued', 'crawling');
+CREATE INDEX IF NOT EXISTS idx_crawl_jobs_type_status ON crawl_jobs(job_type, status);
+
+COMMENT ON COLUMN crawl_jobs.job_type IS 'subreddit, user, post or search';
+COMMENT ON COLUMN crawl_jobs.payload IS 'Type-specific parameters, e.g. {"username": "..."} or {"query": "...", "subreddit": "..."}';
+COMMENT ON COLUMN crawl_jobs.dedupe_key IS 'Normalized identity of a non-subreddit job (username, post ID or search key); NULL for subreddit jobs';
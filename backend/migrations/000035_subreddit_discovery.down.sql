@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subreddits_hop_distance;
+ALTER TABLE subreddits DROP COLUMN IF EXISTS discovered_by_job_id;
+ALTER TABLE subreddits DROP COLUMN IF EXISTS hop_distance;
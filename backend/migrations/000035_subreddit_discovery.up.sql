@@ -0,0 +1,10 @@
+-- Discovery provenance for subreddits.
+-- hop_distance is the number of discovery steps from a seed subreddit (seeds are 0);
+-- NULL means the subreddit was added before discovery was tracked.
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS hop_distance INT;
+ALTER TABLE subreddits ADD COLUMN IF NOT EXISTS discovered_by_job_id INT REFERENCES crawl_jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subreddits_hop_distance ON subreddits(hop_distance);
+
+COMMENT ON COLUMN subreddits.hop_distance IS 'Discovery steps from a seed subreddit (0 = seed); NULL if unknown';
+COMMENT ON COLUMN subreddits.discovered_by_job_id IS 'Crawl job that discovered the subreddit along its shortest known path';
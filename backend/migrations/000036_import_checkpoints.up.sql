@@ -0,0 +1,14 @@
+-- Progress of offline dump imports (cmd/import).
+-- offset_bytes is the position in the decompressed stream just after the last
+-- line whose records have been written, so an interrupted import resumes there.
+CREATE TABLE IF NOT EXISTS import_checkpoints (
+    file_name TEXT PRIMARY KEY,
+    file_size BIGINT NOT NULL,
+    offset_bytes BIGINT NOT NULL DEFAULT 0,
+    lines_read BIGINT NOT NULL DEFAULT 0,
+    records_imported BIGINT NOT NULL DEFAULT 0,
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE import_checkpoints IS 'Resume points for offline Reddit dump imports, keyed by file base name';
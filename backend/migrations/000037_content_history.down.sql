@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS content_events;
+DROP TABLE IF EXISTS content_score_snapshots;
+DROP INDEX IF EXISTS idx_comments_removed;
+DROP INDEX IF EXISTS idx_posts_removed;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS removed_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS removed_state;
+ALTER TABLE posts DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS removed_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS removed_state;
@@ -0,0 +1,47 @@
+-- Content history: what recrawls observed about posts and comments over time.
+-- UpsertPost/UpsertComment keep only the latest state; these tables keep the changes.
+
+-- removed_state is NULL while the content is live, 'deleted' after its author
+-- deleted it and 'removed' after moderators or Reddit removed it. The stored
+-- title/selftext/body keep the last live text.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS removed_state TEXT CHECK (removed_state IN ('deleted', 'removed'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS removed_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS removed_state TEXT CHECK (removed_state IN ('deleted', 'removed'));
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS removed_at TIMESTAMPTZ;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_posts_removed ON posts(subreddit_id) WHERE removed_state IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_removed ON comments(subreddit_id) WHERE removed_state IS NOT NULL;
+
+-- One row per observed score change (and the first observation).
+CREATE TABLE IF NOT EXISTS content_score_snapshots (
+    content_type TEXT NOT NULL CHECK (content_type IN ('post', 'comment')),
+    content_id TEXT NOT NULL,
+    subreddit_id INT NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    score INT NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (content_type, content_id, observed_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_content_score_snapshots_subreddit ON content_score_snapshots(subreddit_id, observed_at);
+
+-- Edits and deletion/removal transitions.
+CREATE TABLE IF NOT EXISTS content_events (
+    id BIGSERIAL PRIMARY KEY,
+    content_type TEXT NOT NULL CHECK (content_type IN ('post', 'comment')),
+    content_id TEXT NOT NULL,
+    subreddit_id INT NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    event TEXT NOT NULL CHECK (event IN ('edited', 'deleted', 'removed', 'restored')),
+    field TEXT,     -- edited field: title, selftext or body
+    old_value TEXT, -- previous text of an edited field
+    new_value TEXT,
+    diff TEXT,      -- line diff of old_value and new_value
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_content_events_content ON content_events(content_type, content_id, observed_at);
+CREATE INDEX IF NOT EXISTS idx_content_events_subreddit ON content_events(subreddit_id, observed_at);
+
+COMMENT ON COLUMN posts.removed_state IS 'NULL while live; deleted (by the author) or removed (by moderators/Reddit) as last observed';
+COMMENT ON COLUMN comments.removed_state IS 'NULL while live; deleted (by the author) or removed (by moderators/Reddit) as last observed';
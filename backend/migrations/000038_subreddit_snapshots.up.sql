@@ -0,0 +1,17 @@
+-- Subreddit metadata snapshots: UpsertSubreddit keeps only the latest
+-- subscribers/title/description, so each crawl also stores what /about returned.
+CREATE TABLE IF NOT EXISTS subreddit_snapshots (
+    subreddit_id INT NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    subscribers INT,
+    active_users INT,      -- NULL when Reddit does not report it
+    over18 BOOLEAN NOT NULL DEFAULT FALSE,
+    subreddit_type TEXT,   -- public, restricted, private, ...
+    created_utc TIMESTAMPTZ,
+    language TEXT,
+    PRIMARY KEY (subreddit_id, observed_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subreddit_snapshots_observed_at ON subreddit_snapshots(observed_at);
+
+COMMENT ON TABLE subreddit_snapshots IS 'Subreddit metadata observed on each crawl, for subscriber growth series';
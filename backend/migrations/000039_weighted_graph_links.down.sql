@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS post_subreddit_refs;
+
+DROP INDEX IF EXISTS idx_graph_links_type_weight;
+
+-- Keep one link per node pair before restoring the pair constraint.
+DELETE FROM graph_links a
+USING graph_links b
+WHERE a.source = b.source AND a.target = b.target AND a.id > b.id;
+
+ALTER TABLE graph_links DROP CONSTRAINT IF EXISTS graph_links_source_target_type_unique;
+ALTER TABLE graph_links ADD CONSTRAINT graph_links_source_target_unique UNIQUE (source, target);
+ALTER TABLE graph_links DROP COLUMN IF EXISTS link_type;
+ALTER TABLE graph_links DROP COLUMN IF EXISTS weight;
@@ -0,0 +1,56 @@
+-- Weighted, typed graph links.
+-- weight is the strength of the relation (activity count, shared users,
+-- mentions, ...; 1 for structural links). link_type says which relation it is,
+-- so the same pair of nodes can be linked once per type.
+ALTER TABLE graph_links ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 1;
+ALTER TABLE graph_links ADD COLUMN IF NOT EXISTS link_type TEXT;
+
+-- Classify links written before types existed from their node ID prefixes.
+-- The next full precalculation rewrites them anyway.
+UPDATE graph_links SET link_type = CASE
+    WHEN source LIKE 'user\_%' AND target LIKE 'subreddit\_%' THEN 'user_activity'
+    WHEN source LIKE 'subreddit\_%' AND target LIKE 'subreddit\_%' THEN 'subreddit_overlap'
+    WHEN source LIKE 'user\_%' THEN 'authored'
+    WHEN source LIKE 'subreddit\_%' THEN 'contains'
+    WHEN target LIKE 'comment\_%' THEN 'reply'
+    ELSE 'same_author'
+END
+WHERE link_type IS NULL;
+
+UPDATE graph_links gl SET weight = usa.activity_count
+FROM user_subreddit_activity usa
+WHERE gl.link_type = 'user_activity'
+  AND gl.source = 'user_' || usa.user_id
+  AND gl.target = 'subreddit_' || usa.subreddit_id;
+
+UPDATE graph_links gl SET weight = sr.overlap_count
+FROM subreddit_relationships sr
+WHERE gl.link_type = 'subreddit_overlap'
+  AND gl.source = 'subreddit_' || sr.source_subreddit_id
+  AND gl.target = 'subreddit_' || sr.target_subreddit_id;
+
+-- Writers that do not set a type yet (e.g. during a rolling deploy) get
+-- 'unknown' instead of failing; the next full precalculation replaces them.
+ALTER TABLE graph_links ALTER COLUMN link_type SET DEFAULT 'unknown';
+ALTER TABLE graph_links ALTER COLUMN link_type SET NOT NULL;
+
+ALTER TABLE graph_links DROP CONSTRAINT IF EXISTS graph_links_source_target_unique;
+ALTER TABLE graph_links DROP CONSTRAINT IF EXISTS graph_links_source_target_key;
+ALTER TABLE graph_links ADD CONSTRAINT graph_links_source_target_type_unique UNIQUE (source, target, link_type);
+
+CREATE INDEX IF NOT EXISTS idx_graph_links_type_weight ON graph_links(link_type, weight);
+
+COMMENT ON COLUMN graph_links.weight IS 'Strength of the relation: activity count, shared users, mentions, crossposts; 1 for structural links';
+COMMENT ON COLUMN graph_links.link_type IS 'user_activity, subreddit_overlap, mention, crosspost, authored, reply, contains, same_author or unknown';
+
+-- Subreddits that posts point at, for mention and crosspost links.
+-- target_subreddit is a lowercase name because the target may not be crawled yet.
+CREATE TABLE IF NOT EXISTS post_subreddit_refs (
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    subreddit_id INT NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    target_subreddit TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('mention', 'crosspost')),
+    PRIMARY KEY (post_id, target_subreddit, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_subreddit_refs_target ON post_subreddit_refs(target_subreddit);
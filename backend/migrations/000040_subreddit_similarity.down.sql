@@ -0,0 +1,6 @@
+ALTER TABLE subreddit_relationships
+    DROP COLUMN IF EXISTS lift,
+    DROP COLUMN IF EXISTS npmi,
+    DROP COLUMN IF EXISTS pmi,
+    DROP COLUMN IF EXISTS cosine,
+    DROP COLUMN IF EXISTS jaccard;
@@ -0,0 +1,14 @@
+-- Normalized similarity of subreddit pairs, from the users active in each.
+-- overlap_count favours large subreddits; these scores do not.
+--   jaccard = overlap / (users_a + users_b - overlap)
+--   cosine  = overlap / sqrt(users_a * users_b)
+--   lift    = overlap * total_users / (users_a * users_b)
+--   pmi     = ln(lift)
+--   npmi    = pmi / -ln(overlap / total_users), in [-1, 1]
+-- Rows written before this migration keep 0 until the next precalculation.
+ALTER TABLE subreddit_relationships
+    ADD COLUMN IF NOT EXISTS jaccard DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cosine DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pmi DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS npmi DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS lift DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE graph_links
+    DROP COLUMN IF EXISTS backbone_kept,
+    DROP COLUMN IF EXISTS backbone_pvalue;
@@ -0,0 +1,7 @@
+-- Backbone extraction: the significance of subreddit links under the null model
+-- of BACKBONE_METHOD (disparity filter or hypergeometric test). Lower p-values
+-- are more significant; backbone_kept marks links with p <= BACKBONE_ALPHA at
+-- precalculation time. Links that were not tested keep NULL.
+ALTER TABLE graph_links
+    ADD COLUMN IF NOT EXISTS backbone_pvalue DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS backbone_kept BOOLEAN;
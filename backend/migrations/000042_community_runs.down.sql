@@ -0,0 +1,2 @@
+ALTER TABLE graph_communities DROP COLUMN IF EXISTS run_id;
+DROP TABLE IF EXISTS community_runs;
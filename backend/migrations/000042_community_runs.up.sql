@@ -0,0 +1,20 @@
+-- One row per community detection run: the algorithm and parameters that produced
+-- the current graph_communities, and the quality of the partition.
+CREATE TABLE IF NOT EXISTS community_runs (
+    id SERIAL PRIMARY KEY,
+    algorithm TEXT NOT NULL,              -- louvain or leiden
+    resolution DOUBLE PRECISION NOT NULL, -- γ of the quality function
+    seed BIGINT,                          -- NULL when the run was not seeded
+    modularity DOUBLE PRECISION NOT NULL, -- at resolution 1
+    quality_at_resolution DOUBLE PRECISION NOT NULL, -- modularity at the run's resolution
+    cpm DOUBLE PRECISION NOT NULL,        -- Constant Potts Model at the run's resolution
+    communities INTEGER NOT NULL,
+    nodes INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_community_runs_created ON community_runs(created_at DESC);
+
+-- The run that produced each community. Communities are replaced on every run,
+-- so all current rows point at the latest one.
+ALTER TABLE graph_communities ADD COLUMN IF NOT EXISTS run_id INTEGER REFERENCES community_runs(id) ON DELETE SET NULL;
@@ -0,0 +1,16 @@
+-- Lineage of communities across detection runs. Communities that continue keep
+-- their ID in graph_communities; the rest are born, die, merge or split, and
+-- rows here outlive the communities they mention.
+CREATE TABLE IF NOT EXISTS community_events (
+    id BIGSERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES community_runs(id) ON DELETE CASCADE,
+    community_id INTEGER NOT NULL,  -- the community the event happened to
+    event TEXT NOT NULL,            -- birth, continue, split, merge or death
+    related_id INTEGER,             -- split: the community split from; merge: the one merged into
+    jaccard DOUBLE PRECISION,       -- member overlap with the related (or, for continue, previous) community
+    size INTEGER NOT NULL,          -- members of community_id (before a merge or death)
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_community_events_community ON community_events(community_id, run_id);
+CREATE INDEX IF NOT EXISTS idx_community_events_related ON community_events(related_id, run_id) WHERE related_id IS NOT NULL;
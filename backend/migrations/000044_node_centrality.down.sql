@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_graph_nodes_kcore;
+DROP INDEX IF EXISTS idx_graph_nodes_betweenness;
+DROP INDEX IF EXISTS idx_graph_nodes_weighted_degree;
+DROP INDEX IF EXISTS idx_graph_nodes_pagerank;
+
+ALTER TABLE graph_nodes
+    DROP COLUMN IF EXISTS kcore,
+    DROP COLUMN IF EXISTS betweenness,
+    DROP COLUMN IF EXISTS weighted_degree,
+    DROP COLUMN IF EXISTS pagerank;
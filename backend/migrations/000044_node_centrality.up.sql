@@ -0,0 +1,18 @@
+-- Structural importance of graph nodes, computed at the end of each
+-- precalculation over the whole graph. NULL until the first run.
+ALTER TABLE graph_nodes
+    ADD COLUMN IF NOT EXISTS pagerank DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS weighted_degree DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS betweenness DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS kcore INTEGER;
+
+COMMENT ON COLUMN graph_nodes.pagerank IS 'PageRank of the node in the precalculated graph';
+COMMENT ON COLUMN graph_nodes.weighted_degree IS 'Sum of the weights of the node''s links';
+COMMENT ON COLUMN graph_nodes.betweenness IS 'Approximate normalized betweenness centrality, from sampled sources';
+COMMENT ON COLUMN graph_nodes.kcore IS 'k-core number: the largest k with the node in a subgraph of minimum degree k';
+
+-- sort_by keeps the top nodes by one measure; each ordering has its own index.
+CREATE INDEX IF NOT EXISTS idx_graph_nodes_pagerank ON graph_nodes (pagerank DESC NULLS LAST, id);
+CREATE INDEX IF NOT EXISTS idx_graph_nodes_weighted_degree ON graph_nodes (weighted_degree DESC NULLS LAST, id);
+CREATE INDEX IF NOT EXISTS idx_graph_nodes_betweenness ON graph_nodes (betweenness DESC NULLS LAST, id);
+CREATE INDEX IF NOT EXISTS idx_graph_nodes_kcore ON graph_nodes (kcore DESC NULLS LAST, id);
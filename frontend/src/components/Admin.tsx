@@ -5,6 +5,7 @@ interface JobStats {
   running_count: number;
   failed_count: number;
   completed_count: number;
+  terminal_count: number;
   total_count: number;
 }
 
@@ -19,6 +20,8 @@ interface Job {
   enqueued_by: string | null;
   created_at: string | null;
   updated_at: string | null;
+  failure_reason?: string;
+  last_error?: string;
 }
 
 interface Settings {
@@ -333,7 +336,7 @@ function Admin({ onViewMode }: AdminProps) {
           <div>
             {/* Job Stats */}
             {jobStats && (
-              <div className="grid grid-cols-5 gap-4 mb-6">
+              <div className="grid grid-cols-6 gap-4 mb-6">
                 <div className="bg-gray-800 p-4 rounded">
                   <div className="text-gray-400 text-sm">Queued</div>
                   <div className="text-2xl font-bold text-yellow-500">
@@ -358,6 +361,12 @@ function Admin({ onViewMode }: AdminProps) {
                     {jobStats.completed_count}
                   </div>
                 </div>
+                <div className="bg-gray-800 p-4 rounded">
+                  <div className="text-gray-400 text-sm">Terminal</div>
+                  <div className="text-2xl font-bold text-gray-400">
+                    {jobStats.terminal_count}
+                  </div>
+                </div>
                 <div className="bg-gray-800 p-4 rounded">
                   <div className="text-gray-400 text-sm">Total</div>
                   <div className="text-2xl font-bold">{jobStats.total_count}</div>
@@ -377,6 +386,7 @@ function Admin({ onViewMode }: AdminProps) {
                 <option value="crawling">Running</option>
                 <option value="failed">Failed</option>
                 <option value="success">Completed</option>
+                <option value="terminal">Terminal</option>
               </select>
             </div>
 
@@ -421,11 +431,19 @@ function Admin({ onViewMode }: AdminProps) {
                               ? "bg-blue-900 text-blue-200"
                               : job.status === "failed"
                               ? "bg-red-900 text-red-200"
+                              : job.status === "terminal"
+                              ? "bg-gray-700 text-gray-300"
                               : "bg-green-900 text-green-200"
                           }`}
+                          title={job.last_error}
                         >
                           {job.status}
                         </span>
+                        {job.failure_reason && (
+                          <span className="ml-2 text-xs text-gray-400">
+                            {job.failure_reason}
+                          </span>
+                        )}
                       </td>
                       <td className="px-4 py-3 text-sm">
                         <input
@@ -448,7 +466,7 @@ function Admin({ onViewMode }: AdminProps) {
                       </td>
                       <td className="px-4 py-3 text-sm">{job.retries || 0}</td>
                       <td className="px-4 py-3 text-sm space-x-2">
-                        {job.status === "failed" && (
+                        {(job.status === "failed" || job.status === "terminal") && (
                           <button
                             onClick={() => retryJob(job.id)}
                             className="px-3 py-1 bg-blue-600 hover:bg-blue-700 rounded text-xs transition-colors"